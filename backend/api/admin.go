@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/weather"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequireAPIKey rejects requests that don't present the admin API key,
+// either as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+func RequireAPIKey(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasAPIKey(r, key) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasAPIKey reports whether r presents key; never when key is empty.
+func hasAPIKey(r *http.Request, key string) bool {
+	got := r.Header.Get("X-API-Key")
+	if got == "" {
+		got = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return key != "" && subtle.ConstantTimeCompare([]byte(got), []byte(key)) == 1
+}
+
+// RefreshRequest is the body accepted by POST /api/admin/locations/{id}/refresh.
+type RefreshRequest struct {
+	Style        int    `json:"style"`
+	Seed         *int32 `json:"seed,omitempty"`
+	ImageOnly    bool   `json:"image_only,omitempty"`
+	VideoOnly    bool   `json:"video_only,omitempty"`
+	Priority     string `json:"priority,omitempty"`      // interactive, scheduled (default) or batch
+	Grounding    string `json:"grounding,omitempty"`     // search, off or required; the server's GROUNDING_MODE by default
+	OverrideLock bool   `json:"override_lock,omitempty"` // Regenerate a locked location
+
+	// Regenerate a hidden or archived location, keeping its status; without
+	// it such locations get 409
+	OverrideHidden bool `json:"override_hidden,omitempty"`
+
+	// Keep the media when the weather is unchanged since it was generated;
+	// the location is returned with "unchanged": true
+	SkipUnchanged bool `json:"skip_unchanged,omitempty"`
+
+	// Stage the new image of a preset as a pending candidate instead of
+	// replacing its media; the location is returned with "staged" set to
+	// the candidate's ID. See /locations/{id}/candidates/{cid}/approve
+	RequireApproval bool `json:"require_approval,omitempty"`
+}
+
+// VideoPromptRequest is the body accepted by PUT
+// /api/admin/locations/{id}/video-prompt. An empty prompt restores the
+// default.
+type VideoPromptRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+// PromptContextRequest is the body accepted by PUT
+// /api/admin/locations/{id}/prompt-context. An empty context clears it.
+type PromptContextRequest struct {
+	Context string `json:"context"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) HandleAdminStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.DB.GetStats(r.Context())
+	if err != nil {
+		log.Printf("Admin stats failed: %v", err)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *Handler) HandleAdminListLocations(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	status := database.LocationStatus(r.URL.Query().Get("status"))
+	if status != "" && !slices.Contains(database.LocationStatuses, status) {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	locs, err := h.DB.ListLocations(r.Context(), database.ListOptions{
+		Limit:  limit,
+		Type:   r.URL.Query().Get("type"),
+		Sort:   r.URL.Query().Get("sort"),
+		Status: status,
+	})
+	if err != nil {
+		log.Printf("Admin list failed: %v", err)
+		http.Error(w, "Failed to list locations", http.StatusInternalServerError)
+		return
+	}
+	for i := range locs {
+		locs[i].Usage = h.Costs.Summarize(locs[i].Generation)
+	}
+	writeJSON(w, http.StatusOK, locs)
+}
+
+func (h *Handler) HandleAdminRefreshLocation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.ImageOnly && req.VideoOnly {
+		http.Error(w, "image_only and video_only are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+	if req.Priority != "" {
+		if _, err := quota.ParsePriority(req.Priority); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := genai.ParseGroundingMode(req.Grounding); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc, err := h.Weather.RefreshLocation(r.Context(), id, weather.RefreshOptions{
+		Style:           req.Style,
+		Seed:            req.Seed,
+		ImageOnly:       req.ImageOnly,
+		VideoOnly:       req.VideoOnly,
+		Priority:        req.Priority,
+		Grounding:       req.Grounding,
+		OverrideLock:    req.OverrideLock,
+		OverrideHidden:  req.OverrideHidden,
+		SkipUnchanged:   req.SkipUnchanged,
+		RequireApproval: req.RequireApproval,
+	})
+	if errors.Is(err, weather.ErrLocked) || errors.Is(err, weather.ErrWithdrawn) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, weather.ErrHalted) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin refresh of %s failed: %v", id, err)
+		http.Error(w, "Refresh failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if loc.Staged != "" {
+		writeJSON(w, http.StatusOK, loc) // The live media is unchanged until approval
+		return
+	}
+	h.presetsChanged()
+	if !loc.Unchanged {
+		h.mediaRefreshed(r.Context(), loc)
+	}
+	writeJSON(w, http.StatusOK, loc)
+}
+
+// PendingRefresher retries the refreshes of locations left refresh_pending.
+// *jobs.PendingRefreshes implements it.
+type PendingRefresher interface {
+	Run(ctx context.Context) (*jobs.PendingRefreshReport, error)
+}
+
+// HandleAdminRefreshPending runs the pending refresh job, e.g. every few
+// minutes from Cloud Scheduler.
+func (h *Handler) HandleAdminRefreshPending(w http.ResponseWriter, r *http.Request) {
+	if h.RefreshPending == nil {
+		http.Error(w, "Pending refreshes are not configured", http.StatusNotImplemented)
+		return
+	}
+	report, err := h.RefreshPending.Run(r.Context())
+	if err != nil {
+		log.Printf("Pending refreshes failed: %v", err)
+		http.Error(w, "Pending refreshes failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(report.Refreshed) > 0 {
+		h.presetsChanged()
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// GenerationResponse is returned by GET /api/admin/locations/{id}/generation.
+// Generation is null for locations generated before metadata was recorded.
+type GenerationResponse struct {
+	ID          string                       `json:"id"`
+	Name        string                       `json:"name"`
+	ImageURL    string                       `json:"image_url"`
+	LastUpdated time.Time                    `json:"last_updated"`
+	Generation  *database.GenerationMetadata `json:"generation"`
+}
+
+// HandleAdminLocationGeneration returns the model commentary and Google Search
+// citations behind a location's current image, to audit the depicted weather.
+func (h *Handler) HandleAdminLocationGeneration(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin generation lookup of %s failed: %v", id, err)
+		http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, GenerationResponse{
+		ID:          loc.ID,
+		Name:        loc.Name,
+		ImageURL:    loc.ImageURL,
+		LastUpdated: loc.LastUpdated,
+		Generation:  loc.Generation,
+	})
+}
+
+// LocationAsOfResponse is returned by GET /api/admin/locations/{id}?asOf=.
+// Location is the last version written at or before AsOf, at RevisionAt.
+// Audit has the entries recorded for the location from RevisionAt to AsOf,
+// e.g. bulk edits, which change fields without writing a new version.
+type LocationAsOfResponse struct {
+	AsOf       time.Time             `json:"as_of"`
+	RevisionAt time.Time             `json:"revision_at"`
+	Location   database.Location     `json:"location"`
+	Audit      []database.AuditEntry `json:"audit,omitempty"`
+}
+
+// parseAsOf accepts RFC 3339 timestamps and Unix seconds.
+func parseAsOf(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// HandleAdminGetLocation returns a location. With ?asOf (RFC 3339 or Unix
+// seconds) it returns the location as it was then, for reports like "the
+// video changed and got worse yesterday": see LocationAsOfResponse.
+func (h *Handler) HandleAdminGetLocation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	v := r.URL.Query().Get("asOf")
+	if v == "" {
+		loc, err := h.DB.GetLocation(r.Context(), id)
+		if status.Code(err) == codes.NotFound {
+			http.Error(w, "Location not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Admin lookup of %s failed: %v", id, err)
+			http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, loc)
+		return
+	}
+
+	asOf, err := parseAsOf(v)
+	if err != nil {
+		http.Error(w, "Invalid asOf (want RFC 3339 or Unix seconds)", http.StatusBadRequest)
+		return
+	}
+	rev, err := h.DB.GetLocationAsOf(r.Context(), id, asOf)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "No version of the location at that time", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin lookup of %s as of %s failed: %v", id, v, err)
+		http.Error(w, "Failed to fetch location history", http.StatusInternalServerError)
+		return
+	}
+	audit, err := h.DB.ListAuditEntries(r.Context(), id, rev.CreatedAt, asOf)
+	if err != nil {
+		log.Printf("Admin audit lookup of %s failed: %v", id, err)
+		http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, LocationAsOfResponse{
+		AsOf:       asOf,
+		RevisionAt: rev.CreatedAt,
+		Location:   rev.Location,
+		Audit:      audit,
+	})
+}
+
+// HandleAdminSetVideoPrompt sets the Veo motion prompt of a location, used
+// from its next refresh on, for scenes the default prompt animates poorly.
+// It responds with the updated location.
+func (h *Handler) HandleAdminSetVideoPrompt(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req VideoPromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	loc, prompt, ok := h.editLocationField(w, r, id, "video_prompt", req.Prompt)
+	if !ok {
+		return
+	}
+	loc.VideoPrompt = prompt.(string)
+	writeJSON(w, http.StatusOK, loc)
+}
+
+// HandleAdminSetPromptContext sets the extra image prompt context of a
+// location (e.g. "focus on the old town, include the funicular"), used by
+// every regeneration from then on. It responds with the updated location.
+func (h *Handler) HandleAdminSetPromptContext(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req PromptContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	loc, value, ok := h.editLocationField(w, r, id, "prompt_context", req.Context)
+	if !ok {
+		return
+	}
+	loc.PromptContext = value.(string)
+	writeJSON(w, http.StatusOK, loc)
+}
+
+// editLocationField sets an editable field of location id from its text
+// value (see database.ParseEditValue). It returns the location as it was
+// and the value stored, or writes the error response and returns false.
+func (h *Handler) editLocationField(w http.ResponseWriter, r *http.Request, id, field, text string) (*database.Location, any, bool) {
+	value, err := database.ParseEditValue(field, text)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		log.Printf("Admin %s lookup of %s failed: %v", field, id, err)
+		http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	edit := database.LocationEdit{ID: id, Fields: map[string]any{field: value}}
+	if err := h.DB.UpdateLocations(r.Context(), []database.LocationEdit{edit}); err != nil {
+		log.Printf("Admin %s update of %s failed: %v", field, id, err)
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return loc, value, true
+}
+
+func (h *Handler) HandleAdminDeleteLocation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.DB.DeleteLocation(r.Context(), id); err != nil {
+		log.Printf("Admin delete of %s failed: %v", id, err)
+		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		return
+	}
+	h.presetsChanged()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAdminSLO serves GET /api/admin/slo: latency percentiles of image and
+// video generation and the cache hit rate over ?window (default 7d).
+func (h *Handler) HandleAdminSLO(w http.ResponseWriter, r *http.Request) {
+	window := database.DefaultSLOWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		var err error
+		if window, err = database.ParseWindow(v); err != nil {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+	}
+	since := time.Now().Add(-window)
+	samples, err := h.DB.ListLatencySamples(r.Context(), since)
+	if err != nil {
+		log.Printf("Admin SLO report failed: %v", err)
+		http.Error(w, "Failed to fetch latency stats", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, database.SummarizeLatency(samples, since))
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeListDB struct {
+	repo.Repository
+	locs []database.Location
+}
+
+func (f *fakeListDB) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	return f.locs, nil
+}
+
+func TestHandleAdminListLocations_Usage(t *testing.T) {
+	db := &fakeListDB{locs: []database.Location{
+		{ID: "paris", Generation: &database.GenerationMetadata{Model: "image", Usage: []database.ModelUsage{
+			{Model: "image", PromptTokens: 300, OutputTokens: 1290},
+			{Model: "veo", VideoSeconds: 8},
+		}}},
+		{ID: "old"}, // Generated before metadata was recorded
+	}}
+	h := &Handler{DB: db, Costs: costs.Rates{"veo": {Second: 0.5}}}
+
+	rec := httptest.NewRecorder()
+	h.HandleAdminListLocations(rec, httptest.NewRequest(http.MethodGet, "/api/admin/locations", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var got []struct {
+		ID    string                 `json:"id"`
+		Usage *database.UsageSummary `json:"usage"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d locations", len(got))
+	}
+	if u := got[0].Usage; u == nil || *u != (database.UsageSummary{Tokens: 1590, VideoSeconds: 8, CostUSD: 4}) {
+		t.Errorf("paris usage = %+v", u)
+	}
+	if got[1].Usage != nil {
+		t.Errorf("old usage = %+v, want none", got[1].Usage)
+	}
+}
+
+func TestHandleAdminSetVideoPrompt(t *testing.T) {
+	db := mock.NewDB()
+	db.UpsertLocation(context.Background(), database.Location{ID: "venice", Name: "Venice"})
+	r := chi.NewRouter()
+	r.Put("/api/admin/locations/{id}/video-prompt", (&Handler{DB: db}).HandleAdminSetVideoPrompt)
+
+	tests := []struct {
+		id, body string
+		status   int
+		want     string
+	}{
+		{"venice", `{"prompt": " Gondolas glide along the canal "}`, http.StatusOK, "Gondolas glide along the canal"},
+		{"venice", `{"prompt": ""}`, http.StatusOK, ""},
+		{"venice", `{"prompt": "` + strings.Repeat("a", database.MaxVideoPromptLength+1) + `"}`, http.StatusBadRequest, ""},
+		{"venice", `not json`, http.StatusBadRequest, ""},
+		{"nowhere", `{"prompt": "Waves"}`, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/admin/locations/"+tt.id+"/video-prompt", strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %.40s: got %d, want %d", tt.id, tt.body, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		loc, _ := db.GetLocation(context.Background(), tt.id)
+		if loc.VideoPrompt != tt.want {
+			t.Errorf("%.40s: stored prompt %q, want %q", tt.body, loc.VideoPrompt, tt.want)
+		}
+	}
+}
+
+func TestHandleAdminSetPromptContext(t *testing.T) {
+	db := mock.NewDB()
+	db.UpsertLocation(context.Background(), database.Location{ID: "porto", Name: "Porto"})
+	r := chi.NewRouter()
+	r.Put("/api/admin/locations/{id}/prompt-context", (&Handler{DB: db}).HandleAdminSetPromptContext)
+
+	put := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/admin/locations/"+id+"/prompt-context", strings.NewReader(body)))
+		return rec
+	}
+	rec := put("porto", `{"context": " Focus on the Ribeira, include the funicular "}`)
+	var got database.Location
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	want := "Focus on the Ribeira, include the funicular"
+	if loc, _ := db.GetLocation(context.Background(), "porto"); got.PromptContext != want || loc.PromptContext != want {
+		t.Errorf("Expected the context stored and returned, got %q and %q", got.PromptContext, loc.PromptContext)
+	}
+	if rec := put("porto", `{"context": "`+strings.Repeat("a", database.MaxPromptContextLength+1)+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a long context, got %d", rec.Code)
+	}
+	if rec := put("nowhere", `{"context": "Harbour"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown location, got %d", rec.Code)
+	}
+}
+
+func TestHandleAdminGetLocation_AsOf(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+	db.UpsertLocation(ctx, database.Location{ID: "venice", Name: "Venice", VideoURL: "https://example.com/new.mp4"})
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	db.AddRevision(database.LocationRevision{
+		Location:  database.Location{ID: "venice", Name: "Venice", VideoURL: "https://example.com/old.mp4", LastUpdated: monday},
+		CreatedAt: monday,
+	})
+	db.AddRevision(database.LocationRevision{
+		Location:  database.Location{ID: "venice", Name: "Venice", VideoURL: "https://example.com/worse.mp4"},
+		CreatedAt: monday.Add(48 * time.Hour),
+	})
+	db.AddAuditEntry(ctx, database.AuditEntry{Event: "location_edited", Location: "venice", Reason: "category: Europe", CreatedAt: monday.Add(time.Hour)})
+	db.AddAuditEntry(ctx, database.AuditEntry{Event: "location_edited", Location: "venice", Reason: "later", CreatedAt: monday.Add(72 * time.Hour)})
+	r := chi.NewRouter()
+	r.Get("/api/admin/locations/{id}", (&Handler{DB: db}).HandleAdminGetLocation)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/admin/locations/venice?asOf=2026-10-13T12:00:00Z")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body)
+	}
+	var resp LocationAsOfResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Location.VideoURL != "https://example.com/old.mp4" || !resp.RevisionAt.Equal(monday) {
+		t.Errorf("Expected Monday's version, got %s at %v", resp.Location.VideoURL, resp.RevisionAt)
+	}
+	if len(resp.Audit) != 1 || resp.Audit[0].Reason != "category: Europe" {
+		t.Errorf("Expected the edit since Monday's version, got %+v", resp.Audit)
+	}
+
+	// Unix seconds work too
+	rec = get("/api/admin/locations/venice?asOf=" + strconv.FormatInt(monday.Add(49*time.Hour).Unix(), 10))
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Location.VideoURL != "https://example.com/worse.mp4" {
+		t.Errorf("Expected Wednesday's version, got %s", resp.Location.VideoURL)
+	}
+
+	rec = get("/api/admin/locations/venice")
+	var loc database.Location
+	json.NewDecoder(rec.Body).Decode(&loc)
+	if rec.Code != http.StatusOK || loc.VideoURL != "https://example.com/new.mp4" {
+		t.Errorf("Expected the current version, got %d %s", rec.Code, loc.VideoURL)
+	}
+
+	for path, want := range map[string]int{
+		"/api/admin/locations/venice?asOf=2026-10-01T00:00:00Z": http.StatusNotFound,
+		"/api/admin/locations/venice?asOf=yesterday":            http.StatusBadRequest,
+		"/api/admin/locations/nowhere":                          http.StatusNotFound,
+	} {
+		if rec := get(path); rec.Code != want {
+			t.Errorf("%s: got %d, want %d", path, rec.Code, want)
+		}
+	}
+}
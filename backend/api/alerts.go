@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/database"
+)
+
+// AlertEvaluator checks failure rate and latency against the alert
+// thresholds. *jobs.Alerts implements it.
+type AlertEvaluator interface {
+	Evaluate(ctx context.Context) (*database.AlertReport, error)
+}
+
+// HandleAdminEvaluateAlerts runs the alert evaluator, e.g. every few minutes
+// from Cloud Scheduler.
+func (h *Handler) HandleAdminEvaluateAlerts(w http.ResponseWriter, r *http.Request) {
+	if h.Alerts == nil {
+		http.Error(w, "Alerts are not configured", http.StatusNotImplemented)
+		return
+	}
+	report, err := h.Alerts.Evaluate(r.Context())
+	if err != nil {
+		log.Printf("Alert evaluation failed: %v", err)
+		http.Error(w, "Alert evaluation failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+type fakeAlerts struct{}
+
+func (fakeAlerts) Evaluate(ctx context.Context) (*database.AlertReport, error) {
+	return &database.AlertReport{Firing: true, Reasons: []string{"Failure rate 50%"}}, nil
+}
+
+func TestHandleAdminEvaluateAlerts(t *testing.T) {
+	post := func(h *Handler) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleAdminEvaluateAlerts(rec, httptest.NewRequest(http.MethodPost, "/api/admin/alerts/evaluate", nil))
+		return rec
+	}
+	if rec := post(&Handler{}); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without the evaluator, got %d", rec.Code)
+	}
+
+	rec := post(&Handler{Alerts: fakeAlerts{}})
+	var report database.AlertReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil || rec.Code != http.StatusOK || !report.Firing {
+		t.Errorf("Unexpected response %d %+v (%v)", rec.Code, report, err)
+	}
+}
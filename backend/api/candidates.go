@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/weather"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HandleAdminListCandidates returns the candidates of a location, newest
+// first: staged refreshes awaiting approval and recorded comparisons.
+func (h *Handler) HandleAdminListCandidates(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	cands, err := h.DB.ListCandidates(r.Context(), id)
+	if err != nil {
+		log.Printf("Admin candidate list of %s failed: %v", id, err)
+		http.Error(w, "Failed to list candidates", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, cands)
+}
+
+// HandleAdminCandidatePreview renders the location's live image and the
+// candidate's side by side, as PNG, to review a staged refresh before
+// approving it.
+func (h *Handler) HandleAdminCandidatePreview(w http.ResponseWriter, r *http.Request) {
+	if h.Cards == nil {
+		http.Error(w, "Previews are not configured", http.StatusNotImplemented)
+		return
+	}
+	id, cid := chi.URLParam(r, "id"), chi.URLParam(r, "cid")
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin preview lookup of %s failed: %v", id, err)
+		http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+		return
+	}
+	cand, err := h.DB.GetCandidate(r.Context(), cid)
+	if status.Code(err) == codes.NotFound || (err == nil && cand.LocationID != id) {
+		http.Error(w, "Candidate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin preview lookup of candidate %s failed: %v", cid, err)
+		http.Error(w, "Failed to fetch candidate", http.StatusInternalServerError)
+		return
+	}
+
+	sheet, err := h.Cards.SideBySide(r.Context(), []string{"live", "candidate " + cid}, []string{loc.ImageURL, cand.ImageURL})
+	if err != nil {
+		log.Printf("Admin preview of candidate %s failed: %v", cid, err)
+		http.Error(w, "Failed to render preview", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(sheet)
+}
+
+// HandleAdminApproveCandidate swaps a pending candidate in as the location's
+// live media, animating it first (see weather.Service.ApproveCandidate), and
+// returns the location.
+func (h *Handler) HandleAdminApproveCandidate(w http.ResponseWriter, r *http.Request) {
+	id, cid := chi.URLParam(r, "id"), chi.URLParam(r, "cid")
+	loc, err := h.Weather.ApproveCandidate(r.Context(), id, cid)
+	if !h.reviewed(w, cid, err) {
+		return
+	}
+	h.presetsChanged()
+	h.mediaRefreshed(r.Context(), loc)
+	writeJSON(w, http.StatusOK, loc)
+}
+
+// HandleAdminRejectCandidate marks a pending candidate rejected, keeping the
+// live media.
+func (h *Handler) HandleAdminRejectCandidate(w http.ResponseWriter, r *http.Request) {
+	cid := chi.URLParam(r, "cid")
+	err := h.Weather.RejectCandidate(r.Context(), chi.URLParam(r, "id"), cid)
+	if !h.reviewed(w, cid, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviewed writes the error response of a candidate review, if any, and
+// reports whether it succeeded.
+func (h *Handler) reviewed(w http.ResponseWriter, cid string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case status.Code(err) == codes.NotFound:
+		http.Error(w, "Candidate not found", http.StatusNotFound)
+	case errors.Is(err, weather.ErrNotReviewable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, weather.ErrHalted):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		log.Printf("Admin review of candidate %s failed: %v", cid, err)
+		http.Error(w, "Review failed: "+err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
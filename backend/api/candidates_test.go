@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/cards"
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/weather"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAdminCandidates(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+	store, err := mock.NewStorage(t.TempDir(), "http://media.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var art bytes.Buffer
+	png.Encode(&art, image.NewRGBA(image.Rect(0, 0, 64, 64)))
+	live, _ := store.UploadBytes(ctx, art.Bytes(), "lisbon.png", "image/png")
+	staged, _ := store.UploadBytes(ctx, art.Bytes(), "candidates/lisbon.png", "image/png")
+	if err := db.UpsertLocation(ctx, database.Location{ID: "lisbon", CityQuery: "Lisbon", IsPreset: true, ImageURL: live}); err != nil {
+		t.Fatal(err)
+	}
+	cid, err := db.AddCandidate(ctx, database.Candidate{LocationID: "lisbon", Source: "refresh", Status: database.CandidatePending, ImageURL: staged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := db.AddCandidate(ctx, database.Candidate{LocationID: "porto", Status: database.CandidatePending, ImageURL: staged})
+
+	svc := weather.NewService(&mock.Maps{DB: db}, &mock.GenAI{}, store, db)
+	svc.Candidates = db
+	h := &Handler{DB: db, Weather: svc}
+	r := chi.NewRouter()
+	r.Get("/locations/{id}/candidates", h.HandleAdminListCandidates)
+	r.Get("/locations/{id}/candidates/{cid}/preview.png", h.HandleAdminCandidatePreview)
+	r.Post("/locations/{id}/candidates/{cid}/approve", h.HandleAdminApproveCandidate)
+	r.Post("/locations/{id}/candidates/{cid}/reject", h.HandleAdminRejectCandidate)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/locations/lisbon/candidates")
+	var cands []database.Candidate
+	if err := json.NewDecoder(rec.Body).Decode(&cands); err != nil || len(cands) != 1 || cands[0].ID != cid {
+		t.Fatalf("Unexpected candidates %+v (%d, %v)", cands, rec.Code, err)
+	}
+
+	if rec := do(http.MethodGet, "/locations/lisbon/candidates/"+cid+"/preview.png"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without cards, got %d", rec.Code)
+	}
+	h.Cards = &cards.Service{Store: store}
+	rec = do(http.MethodGet, "/locations/lisbon/candidates/"+cid+"/preview.png")
+	if img, err := png.Decode(rec.Body); err != nil || img.Bounds().Dx() <= img.Bounds().Dy() {
+		t.Errorf("Expected a side-by-side PNG, got %d (%v)", rec.Code, err)
+	}
+	if rec := do(http.MethodGet, "/locations/lisbon/candidates/"+other+"/preview.png"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 previewing another location's candidate, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/locations/lisbon/candidates/999/approve"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 approving a missing candidate, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/locations/lisbon/candidates/"+other+"/reject"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 rejecting another location's candidate, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/locations/lisbon/candidates/"+cid+"/reject"); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 rejecting, got %d", rec.Code)
+	}
+	if cand, _ := db.GetCandidate(ctx, cid); cand.Status != database.CandidateRejected {
+		t.Errorf("Expected the candidate rejected, got %q", cand.Status)
+	}
+	if rec := do(http.MethodPost, "/locations/lisbon/candidates/"+cid+"/approve"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 approving a rejected candidate, got %d", rec.Code)
+	}
+	if loc, _ := db.GetLocation(ctx, "lisbon"); loc.ImageURL != live {
+		t.Errorf("Expected the live media kept, got %s", loc.ImageURL)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"banana-weather/internal/cards"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleLocationCard serves GET /api/locations/{id}/card.png: a share card of
+// the location's art with its city, temperature and update time.
+// ?size=landscape (1200x630, the default) or story (1080x1920).
+func (h *Handler) HandleLocationCard(w http.ResponseWriter, r *http.Request) {
+	if h.Cards == nil {
+		http.Error(w, "Share cards are not enabled", http.StatusNotImplemented)
+		return
+	}
+	size, err := cards.ParseSize(r.URL.Query().Get("size"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	loc := h.servableLocation(w, r, chi.URLParam(r, "id"))
+	if loc == nil {
+		return
+	}
+
+	card, err := h.Cards.Card(r.Context(), loc, size)
+	if err != nil {
+		log.Printf("Failed to render %s card for %s: %v", size.Name, loc.ID, err)
+		http.Error(w, "Failed to render card", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Last-Modified", loc.LastUpdated.UTC().Format(http.TimeFormat))
+	// The URL outlives refreshes, so caches must pick up new art soon
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(card)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/cards"
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleLocationCard(t *testing.T) {
+	store, err := mock.NewStorage(t.TempDir(), "http://media.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var art bytes.Buffer
+	png.Encode(&art, image.NewRGBA(image.Rect(0, 0, 90, 160)))
+	artURL, err := store.UploadBytes(context.Background(), art.Bytes(), "paris.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &fakeWalletDB{locs: map[string]*database.Location{
+		"paris":  {ID: "paris", Name: "Paris", ImageURL: artURL},
+		"hidden": {ID: "hidden", ImageURL: artURL, Status: database.StatusHidden},
+	}}
+	r := chi.NewRouter()
+	r.Get("/api/locations/{id}/card.png", (&Handler{DB: db, Cards: &cards.Service{Store: store}}).HandleLocationCard)
+
+	tests := []struct {
+		path   string
+		status int
+		size   image.Point
+	}{
+		{"/api/locations/paris/card.png", http.StatusOK, image.Pt(1200, 630)},
+		{"/api/locations/paris/card.png?size=story", http.StatusOK, image.Pt(1080, 1920)},
+		{"/api/locations/paris/card.png?size=square", http.StatusBadRequest, image.Point{}},
+		{"/api/locations/hidden/card.png", http.StatusNotFound, image.Point{}},
+		{"/api/locations/nowhere/card.png", http.StatusNotFound, image.Point{}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.path, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		img, err := png.Decode(rec.Body)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+		} else if img.Bounds().Size() != tt.size || rec.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("%s: got a %v %s, want a %v PNG", tt.path, img.Bounds().Size(), rec.Header().Get("Content-Type"), tt.size)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	(&Handler{DB: db}).HandleLocationCard(rec, httptest.NewRequest(http.MethodGet, "/api/locations/paris/card.png", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("without cards: got %d, want 501", rec.Code)
+	}
+}
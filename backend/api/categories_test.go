@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+type fakeCategoriesDB struct {
+	repo.Repository
+	categories []database.Category
+}
+
+func (f *fakeCategoriesDB) ListCategories(ctx context.Context) ([]database.Category, error) {
+	return f.categories, nil
+}
+
+func TestHandleGetCategories(t *testing.T) {
+	db := &fakeCategoriesDB{}
+	h := &Handler{DB: db}
+
+	rec := httptest.NewRecorder()
+	h.HandleGetCategories(rec, httptest.NewRequest(http.MethodGet, "/api/categories", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("empty: %d %q", rec.Code, rec.Body.String())
+	}
+
+	db.categories = []database.Category{
+		{Name: "Europe", Order: 0, CoverURL: "https://storage.googleapis.com/b/covers/europe_1.png", CoverCities: []string{"Paris", "Rome"}},
+		{Name: "Fictional", Order: 1},
+	}
+	rec = httptest.NewRecorder()
+	h.HandleGetCategories(rec, httptest.NewRequest(http.MethodGet, "/api/categories", nil))
+	var got []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["cover_url"] != db.categories[0].CoverURL {
+		t.Fatalf("got %v", got)
+	}
+	if _, ok := got[1]["cover_url"]; ok {
+		t.Errorf("Fictional has no cover, got %v", got[1])
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/genai"
+	"banana-weather/internal/query"
+	"banana-weather/internal/weather"
+)
+
+// CompareRequest is the body accepted by POST /api/admin/compare.
+type CompareRequest struct {
+	City   string   `json:"city"`
+	Styles []string `json:"styles"`           // Style names or modes, see genai.ParseStyle
+	ID     string   `json:"id,omitempty"`     // Location the candidates are recorded under; derived from city by default
+	Seed   *int32   `json:"seed,omitempty"`   // Shared by every style; random by default
+	Record bool     `json:"record,omitempty"` // Upload the images and record them as candidates
+}
+
+// HandleAdminCompare generates the city once per style, concurrently, and
+// returns the images inline (see weather.Comparison), for prompt and style
+// tuning. With record the images are also stored as candidates of the
+// location; its media is never replaced.
+func (h *Handler) HandleAdminCompare(w http.ResponseWriter, r *http.Request) {
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	city, err := query.Normalize(req.City)
+	if err != nil {
+		http.Error(w, "Invalid city: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if city == "" {
+		http.Error(w, "city is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Styles) == 0 {
+		req.Styles = genai.Styles[1:]
+	}
+	styles := make([]int, len(req.Styles))
+	for i, s := range req.Styles {
+		if styles[i], err = genai.ParseStyle(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Record && h.Weather.Candidates == nil {
+		http.Error(w, "Candidate recording is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	out, err := h.Weather.Compare(r.Context(), weather.CompareRequest{
+		City:       city,
+		LocationID: req.ID,
+		Styles:     styles,
+		Seed:       req.Seed,
+		Record:     req.Record,
+	})
+	if errors.Is(err, weather.ErrHalted) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("Admin compare of %q failed: %v", city, err)
+		http.Error(w, "Compare failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
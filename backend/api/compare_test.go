@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/weather"
+)
+
+func TestHandleAdminCompare(t *testing.T) {
+	db := mock.NewDB()
+	store, err := mock.NewStorage(t.TempDir(), "http://media.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := weather.NewService(&mock.Maps{DB: db}, &mock.GenAI{}, store, db)
+	h := &Handler{DB: db, Weather: svc}
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleAdminCompare(rec, httptest.NewRequest(http.MethodPost, "/api/admin/compare", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"city":"Istanbul","styles":["classic","drink"],"seed":9}`)
+	var out weather.Comparison
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if out.LocationID != "istanbul" || out.Seed != 9 || len(out.Results) != 2 || out.Results[0].Style != "classic" || out.Results[1].Style != "drink" {
+		t.Fatalf("Unexpected comparison: %+v", out)
+	}
+	for _, r := range out.Results {
+		if r.ImageBase64 == "" || r.ImageURL != "" || r.Error != "" {
+			t.Errorf("Expected an inline, unrecorded image, got %+v", r)
+		}
+	}
+
+	if rec := post(`{"city":"Istanbul","styles":["classic"],"record":true}`); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 recording without a candidate store, got %d", rec.Code)
+	}
+	svc.Candidates = db
+	rec = post(`{"city":"Istanbul","styles":["drink"],"id":"istanbul_tr","record":true}`)
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if r := out.Results[0]; r.Candidate == "" || !strings.HasPrefix(r.ImageURL, "http://media.test/candidates/istanbul_tr_drink_") {
+		t.Errorf("Expected the image recorded as a candidate, got %+v", r)
+	}
+	if cands, _ := db.ListCandidates(context.Background(), "istanbul_tr"); len(cands) != 1 || cands[0].Style != "drink" {
+		t.Errorf("Unexpected candidates: %+v", cands)
+	}
+
+	for _, body := range []string{
+		`{"city":"Istanbul","styles":["snowglobe"]}`,
+		`{"styles":["classic"]}`,
+		`{"city":"https://example.com","styles":["classic"]}`,
+		`not json`,
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	svc.Runtime = db
+	db.UpdateRuntimeSettings(context.Background(), func(s *database.RuntimeSettings) {
+		s.KillSwitch = database.KillSwitch{On: true, Reason: "veo quota incident"}
+	})
+	if rec := post(`{"city":"Istanbul","styles":["classic"]}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with the kill switch on, got %d", rec.Code)
+	}
+}
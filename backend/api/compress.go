@@ -0,0 +1,42 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// compressibleTypes are the response types worth compressing. SSE
+// (text/event-stream) is deliberately left out: the stream is mostly one
+// base64 image that barely compresses, and an encoder would hold back the
+// small status events.
+var compressibleTypes = []string{
+	"application/json",
+	"application/geo+json",
+	"application/javascript",
+	"application/wasm",
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"image/svg+xml",
+}
+
+// Compress gzip/deflate-compresses responses of compressibleTypes, and also
+// offers brotli (preferred by clients that accept it) when withBrotli is set.
+// level is the flate level (1-9), also used as the brotli quality; 0 disables
+// compression entirely.
+func Compress(level int, withBrotli bool) func(http.Handler) http.Handler {
+	if level <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	c := middleware.NewCompressor(level, compressibleTypes...)
+	if withBrotli {
+		c.SetEncoder("br", func(w io.Writer, level int) io.Writer {
+			return brotli.NewWriterLevel(w, level)
+		})
+	}
+	return c.Handler
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+var payload = strings.Repeat(`{"id":"london","image_url":"https://storage.googleapis.com/bucket/image.png"}`, 50)
+
+func serve(h http.Handler, contentType, acceptEncoding string) *httptest.ResponseRecorder {
+	handler := h
+	if handler == nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, payload)
+		})
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	rec := httptest.NewRecorder()
+	Compress(5, true)(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompress(t *testing.T) {
+	rec := serve(nil, "application/json", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != payload {
+		t.Error("Decompressed body doesn't match")
+	}
+
+	rec = serve(nil, "application/json", "gzip, deflate, br")
+	if rec.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("Expected br to be preferred, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	if body, _ := io.ReadAll(brotli.NewReader(rec.Body)); string(body) != payload {
+		t.Error("Decompressed brotli body doesn't match")
+	}
+}
+
+func TestCompress_SkipsSSE(t *testing.T) {
+	rec := serve(nil, "text/event-stream", "gzip, br")
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("SSE must not be compressed, got %q", enc)
+	}
+	if rec.Body.String() != payload {
+		t.Error("SSE body was altered")
+	}
+}
+
+func TestCompress_Disabled(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, payload)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	Compress(0, true)(handler).ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != payload {
+		t.Error("Expected an uncompressed passthrough at level 0")
+	}
+}
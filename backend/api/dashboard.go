@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"banana-weather/internal/query"
+)
+
+// MaxDashboardCities caps the cities of GET /api/dashboard; each one is a web
+// flow, possibly generating.
+const MaxDashboardCities = 8
+
+// DashboardEvent is the data of every GET /api/dashboard event: the city key
+// (as given in ?cities=) and the data of that city's web flow event.
+type DashboardEvent struct {
+	City string `json:"city"`
+	Data string `json:"data"`
+}
+
+// dashboardCity is one panel of a dashboard request.
+type dashboardCity struct {
+	Key   string // As given, e.g. "nyc"
+	Query string // Normalized for the web flow
+}
+
+// HandleDashboard runs the web flow for each of ?cities= (comma-separated)
+// concurrently over one SSE stream, so a multi-panel dashboard needs one
+// connection. Events keep the web flow's names; their data is a
+// DashboardEvent tagging the flow's data with the city key. Cities with fresh
+// media get their "result" (and "video") as soon as they're resolved, while
+// others generate. Each city ends with a "done" event, and the stream with
+// "end".
+func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	cities, err := parseDashboardCities(r.URL.Query().Get("cities"))
+	var qe *query.Error
+	if errors.As(err, &qe) {
+		log.Printf("Rejected dashboard city (%s): %q", qe.Code, r.URL.Query().Get("cities"))
+		writeJSON(w, http.StatusBadRequest, qe)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// The flows run concurrently; events go out one at a time
+	var mu sync.Mutex
+	send := func(key, event, data string) {
+		b, _ := json.Marshal(DashboardEvent{City: key, Data: data})
+		mu.Lock()
+		defer mu.Unlock()
+		writeSSE(w, event, string(b))
+		flusher.Flush()
+	}
+
+	ctx := r.Context()
+	var wg sync.WaitGroup
+	for _, c := range cities {
+		wg.Go(func() {
+			err := h.Weather.GetWeatherFlow(ctx, c.Query, "", "", func(event, data string) {
+				send(c.Key, event, data)
+			})
+			if err != nil {
+				// The flow has sent its "error" event
+				log.Printf("Dashboard flow for %q finished with error: %v", c.Key, err)
+			}
+			send(c.Key, "done", "")
+		})
+	}
+	wg.Wait()
+	send("", "end", "")
+}
+
+// parseDashboardCities splits ?cities= into up to MaxDashboardCities cities,
+// skipping empty entries and repeated keys. A city that isn't a valid query
+// fails with its *query.Error.
+func parseDashboardCities(s string) ([]dashboardCity, error) {
+	var cities []dashboardCity
+	seen := map[string]bool{}
+	for _, key := range strings.Split(s, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[strings.ToLower(key)] {
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+		q, err := query.Normalize(key)
+		if err != nil {
+			return nil, err
+		}
+		cities = append(cities, dashboardCity{Key: key, Query: q})
+	}
+	switch {
+	case len(cities) == 0:
+		return nil, fmt.Errorf("cities is required, e.g. ?cities=paris,tokyo")
+	case len(cities) > MaxDashboardCities:
+		return nil, fmt.Errorf("at most %d cities are allowed, got %d", MaxDashboardCities, len(cities))
+	}
+	return cities, nil
+}
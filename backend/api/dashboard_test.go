@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/mock"
+	"banana-weather/internal/weather"
+)
+
+func TestHandleDashboard(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+	if _, err := db.LoadDefault(); err != nil {
+		t.Fatal(err)
+	}
+	store, err := mock.NewStorage(t.TempDir(), "http://media.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &mock.GenAI{ImageLatency: 200 * time.Millisecond}
+	if err := mock.Seed(ctx, db, gen, store); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{DB: db, Weather: weather.NewService(&mock.Maps{DB: db}, gen, store, db)}
+
+	rec := httptest.NewRecorder()
+	h.HandleDashboard(rec, httptest.NewRequest(http.MethodGet, "/api/dashboard?cities=Lisbon,Paris,paris,", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body)
+	}
+
+	// Event order per city, and the order of the results across cities
+	events := map[string][]string{}
+	var results []string
+	var last string
+	scanner := bufio.NewScanner(rec.Body)
+	scanner.Buffer(nil, 10<<20)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var e DashboardEvent
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("Event %s isn't a DashboardEvent: %v", event, err)
+		}
+		events[e.City] = append(events[e.City], event)
+		if event == "result" {
+			results = append(results, e.City)
+		}
+		last = event
+	}
+
+	if len(events) != 3 || len(events["paris"]) > 0 {
+		t.Errorf("Expected Lisbon, Paris and the end, got %v", events)
+	}
+	for _, city := range []string{"Lisbon", "Paris"} {
+		ev := events[city]
+		if len(ev) == 0 || ev[len(ev)-1] != "done" || !slices.Contains(ev, "result") {
+			t.Errorf("Expected %s to get a result and end with done, got %v", city, ev)
+		}
+	}
+	if len(results) != 2 || results[0] != "Paris" {
+		t.Errorf("Expected the cached Paris result before the generated Lisbon one, got %v", results)
+	}
+	if last != "end" {
+		t.Errorf("Expected the stream to end with end, got %s", last)
+	}
+}
+
+func TestParseDashboardCities(t *testing.T) {
+	cities, err := parseDashboardCities(" nyc , Tokyo,,NYC")
+	if err != nil || len(cities) != 2 || cities[0] != (dashboardCity{Key: "nyc", Query: "nyc"}) || cities[1].Key != "Tokyo" {
+		t.Errorf("Unexpected cities %+v (%v)", cities, err)
+	}
+	if _, err := parseDashboardCities(""); err == nil {
+		t.Error("Expected an error without cities")
+	}
+	if _, err := parseDashboardCities(strings.Repeat("a,b,c,", 3)); err != nil {
+		t.Errorf("Expected repeated cities collapsed, got %v", err)
+	}
+	if _, err := parseDashboardCities("a,b,c,d,e,f,g,h,i"); err == nil {
+		t.Error("Expected an error for too many cities")
+	}
+	if _, err := parseDashboardCities("paris,https://example.com"); err == nil {
+		t.Error("Expected an invalid city rejected")
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/push"
+)
+
+// maxDeviceTopics caps the topics changed by one POST /api/devices.
+const maxDeviceTopics = 50
+
+// DeviceRegistrar manages the topics a device token follows. *push.FCM
+// implements it.
+type DeviceRegistrar interface {
+	Subscribe(ctx context.Context, token string, topics []string) error
+	Unsubscribe(ctx context.Context, token string, topics []string) error
+}
+
+// RefreshNotifier tells followers that a location's media changed.
+// *jobs.Fanout implements it.
+type RefreshNotifier interface {
+	Refreshed(ctx context.Context, loc *database.Location) error
+}
+
+// DeviceRequest is the body accepted by POST /api/devices. Topics are
+// "preset:<id>", "category:<name>" or "city_of_the_day".
+type DeviceRequest struct {
+	Token       string   `json:"token"` // FCM registration token
+	Subscribe   []string `json:"subscribe,omitempty"`
+	Unsubscribe []string `json:"unsubscribe,omitempty"`
+}
+
+// DeviceResponse is returned by POST /api/devices, with the FCM topic names.
+type DeviceResponse struct {
+	Subscribed   []string `json:"subscribed"`
+	Unsubscribed []string `json:"unsubscribed"`
+}
+
+// HandleRegisterDevice subscribes a device token to, or unsubscribes it from,
+// push notification topics. Tokens are kept by FCM, not stored here.
+func (h *Handler) HandleRegisterDevice(w http.ResponseWriter, r *http.Request) {
+	if h.Devices == nil {
+		http.Error(w, "Push notifications are not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	var req DeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Subscribe)+len(req.Unsubscribe) == 0 || len(req.Subscribe)+len(req.Unsubscribe) > maxDeviceTopics {
+		http.Error(w, "subscribe and unsubscribe must list 1 to 50 topics", http.StatusBadRequest)
+		return
+	}
+
+	resp := DeviceResponse{Subscribed: []string{}, Unsubscribed: []string{}}
+	for _, list := range []struct {
+		in  []string
+		out *[]string
+	}{{req.Subscribe, &resp.Subscribed}, {req.Unsubscribe, &resp.Unsubscribed}} {
+		for _, s := range list.in {
+			topic, err := push.ParseTopic(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			*list.out = append(*list.out, topic)
+		}
+	}
+
+	if len(resp.Subscribed) > 0 {
+		if err := h.Devices.Subscribe(r.Context(), req.Token, resp.Subscribed); err != nil {
+			log.Printf("Failed to subscribe device: %v", err)
+			http.Error(w, "Failed to subscribe", http.StatusBadGateway)
+			return
+		}
+	}
+	if len(resp.Unsubscribed) > 0 {
+		if err := h.Devices.Unsubscribe(r.Context(), req.Token, resp.Unsubscribed); err != nil {
+			log.Printf("Failed to unsubscribe device: %v", err)
+			http.Error(w, "Failed to unsubscribe", http.StatusBadGateway)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeDevices struct {
+	subscribed, unsubscribed []string
+}
+
+func (f *fakeDevices) Subscribe(ctx context.Context, token string, topics []string) error {
+	f.subscribed = append(f.subscribed, topics...)
+	return nil
+}
+func (f *fakeDevices) Unsubscribe(ctx context.Context, token string, topics []string) error {
+	f.unsubscribed = append(f.unsubscribed, topics...)
+	return nil
+}
+
+func TestHandleRegisterDevice(t *testing.T) {
+	post := func(h *Handler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleRegisterDevice(rec, httptest.NewRequest(http.MethodPost, "/api/devices", strings.NewReader(body)))
+		return rec
+	}
+	if rec := post(&Handler{}, `{}`); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without FCM, got %d", rec.Code)
+	}
+
+	devices := &fakeDevices{}
+	h := &Handler{Devices: devices}
+	for _, body := range []string{
+		`{"subscribe":["city_of_the_day"]}`,
+		`{"token":"tok"}`,
+		`{"token":"tok","subscribe":["weather:paris"]}`,
+	} {
+		if rec := post(h, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if len(devices.subscribed) != 0 {
+		t.Errorf("Invalid requests shouldn't subscribe, got %v", devices.subscribed)
+	}
+
+	rec := post(h, `{"token":"tok","subscribe":["preset:paris","category:Dune Universe"],"unsubscribe":["city_of_the_day"]}`)
+	var resp DeviceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if strings.Join(devices.subscribed, ",") != "preset_paris,category_dune_universe" || strings.Join(devices.unsubscribed, ",") != "city_of_the_day" {
+		t.Errorf("Unexpected topics %v / %v", devices.subscribed, devices.unsubscribed)
+	}
+	if len(resp.Subscribed) != 2 || resp.Unsubscribed[0] != "city_of_the_day" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
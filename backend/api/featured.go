@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/database"
+)
+
+// CityOfTheDayRunner runs the city of the day job. *jobs.CityOfTheDay implements it.
+type CityOfTheDayRunner interface {
+	Run(ctx context.Context, id string) (*database.FeaturedCity, error)
+}
+
+// CityOfTheDayRequest is the body accepted by POST /api/admin/city-of-the-day.
+type CityOfTheDayRequest struct {
+	ID string `json:"id,omitempty"` // Location to feature; picked by the job when empty
+}
+
+// HandleCityOfTheDay serves GET /api/city-of-the-day: the latest selection,
+// with the media generated for it.
+func (h *Handler) HandleCityOfTheDay(w http.ResponseWriter, r *http.Request) {
+	history, err := h.DB.ListCityOfTheDay(r.Context(), 1)
+	if err != nil {
+		log.Printf("Failed to get city of the day: %v", err)
+		http.Error(w, "Failed to fetch city of the day", http.StatusInternalServerError)
+		return
+	}
+	if len(history) == 0 {
+		http.Error(w, "No city of the day yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, history[0])
+}
+
+// HandleAdminCityOfTheDay runs the city of the day job, e.g. from Cloud
+// Scheduler. Today's existing selection is returned unless an id is given.
+func (h *Handler) HandleAdminCityOfTheDay(w http.ResponseWriter, r *http.Request) {
+	if h.CityOfTheDay == nil {
+		http.Error(w, "City of the day is not configured", http.StatusNotImplemented)
+		return
+	}
+	var req CityOfTheDayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	f, err := h.CityOfTheDay.Run(r.Context(), req.ID)
+	if err != nil {
+		log.Printf("City of the day failed: %v", err)
+		http.Error(w, "City of the day failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.presetsChanged()
+	writeJSON(w, http.StatusOK, f)
+}
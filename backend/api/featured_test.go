@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+type fakeFeaturedDB struct {
+	repo.Repository
+	history []database.FeaturedCity
+}
+
+func (f *fakeFeaturedDB) ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error) {
+	return f.history, nil
+}
+
+type fakeCityOfTheDay struct{ id string }
+
+func (f *fakeCityOfTheDay) Run(ctx context.Context, id string) (*database.FeaturedCity, error) {
+	f.id = id
+	return &database.FeaturedCity{Date: "2026-10-15", LocationID: "paris"}, nil
+}
+
+func TestHandleCityOfTheDay(t *testing.T) {
+	db := &fakeFeaturedDB{}
+	h := &Handler{DB: db}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleCityOfTheDay(rec, httptest.NewRequest(http.MethodGet, "/api/city-of-the-day", nil))
+		return rec
+	}
+
+	if rec := get(); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before any selection, got %d", rec.Code)
+	}
+
+	db.history = []database.FeaturedCity{{Date: "2026-10-15", LocationID: "paris", Name: "Paris"}}
+	rec := get()
+	var f database.FeaturedCity
+	if err := json.NewDecoder(rec.Body).Decode(&f); err != nil || rec.Code != http.StatusOK || f.LocationID != "paris" {
+		t.Errorf("Unexpected response %d %+v (%v)", rec.Code, f, err)
+	}
+}
+
+func TestHandleAdminCityOfTheDay(t *testing.T) {
+	post := func(h *Handler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleAdminCityOfTheDay(rec, httptest.NewRequest(http.MethodPost, "/api/admin/city-of-the-day", strings.NewReader(body)))
+		return rec
+	}
+	if rec := post(&Handler{}, ""); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without the job, got %d", rec.Code)
+	}
+
+	job := &fakeCityOfTheDay{}
+	if rec := post(&Handler{CityOfTheDay: job}, `{"id":"tokyo"}`); rec.Code != http.StatusOK || job.id != "tokyo" {
+		t.Errorf("Expected the given id to be featured, got %d, %q", rec.Code, job.id)
+	}
+}
@@ -1,34 +1,260 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
-	"banana-weather/pkg/database"
-	"banana-weather/pkg/weather"
+	"banana-weather/internal/cards"
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/httppool"
+	"banana-weather/internal/notify"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/query"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+	"banana-weather/internal/wallet"
+	"banana-weather/internal/weather"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Handler struct {
-	DB      *database.Client
+	DB      repo.Repository
 	Weather *weather.Service
+
+	ReportThreshold int             // Reports before a location is hidden pending review
+	Notifier        notify.Notifier // Admin notifications (takedowns)
+	PreloadImages   int             // Presets whose images are announced via Link: preload
+	Presets         *PresetCache    // Optional: cache for GET /api/presets
+	Provenance      *provenance.Signer
+	Uploads         UploadSigner                   // Optional: enables POST /api/uploads
+	CityOfTheDay    CityOfTheDayRunner             // Optional: enables POST /api/admin/city-of-the-day
+	Alerts          AlertEvaluator                 // Optional: enables POST /api/admin/alerts/evaluate
+	RefreshPending  PendingRefresher               // Optional: enables POST /api/admin/refresh-pending
+	Quota           *quota.Manager                 // Optional: enables GET /api/admin/quota
+	Pools           *httppool.Pools                // Optional: enables GET /api/admin/pools
+	Devices         DeviceRegistrar                // Optional: enables POST /api/devices
+	Push            RefreshNotifier                // Optional: notifies followers after admin refreshes
+	Wallet          *wallet.Service                // Optional: enables wallet passes and the PassKit web service
+	Cards           *cards.Service                 // Optional: enables GET /api/locations/{id}/card.png
+	Media           map[storage.Kind]storage.Store // Optional: image and video stores, enables the media proxy
+	AdminAPIKey     string                         // Lets the media proxy serve hidden locations to admins
+	TraceSample     float64                        // Fraction of weather flows recorded in flow_traces
+	Costs           costs.Rates                    // Optional: prices for the usage estimates of admin listings
+	Flows           *FlowBuffer                    // Optional: enables GET /api/weather/poll
+	Ready           *Readiness                     // Optional: startup warm-up reported by GET /readyz
+	TrustedProxies  int                            // Reverse proxies whose X-Forwarded-For entries identify clients, see clientAddr
+}
+
+// getPresets reads presets through the cache when one is configured. The
+// result may be shared and must not be modified.
+func (h *Handler) getPresets(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	if h.Presets != nil {
+		return h.Presets.Get(ctx, opts)
+	}
+	return h.DB.GetPresetSummaries(ctx, opts)
+}
+
+// mediaRefreshed tells followers and wallet passes that loc has new media.
+// Failures are logged; the refresh itself succeeded.
+func (h *Handler) mediaRefreshed(ctx context.Context, loc *database.Location) {
+	if h.Push != nil {
+		if err := h.Push.Refreshed(ctx, loc); err != nil {
+			log.Printf("Failed to notify followers of %s: %v", loc.ID, err)
+		}
+	}
+	if err := h.Wallet.Refreshed(ctx, loc); err != nil {
+		log.Printf("Failed to update wallet passes of %s: %v", loc.ID, err)
+	}
+}
+
+// presetsChanged drops cached presets after a write that affects the gallery.
+func (h *Handler) presetsChanged() {
+	if h.Presets != nil {
+		h.Presets.Invalidate()
+	}
 }
 
 func (h *Handler) HandleGetPresets(w http.ResponseWriter, r *http.Request) {
-	// Fetch from Firestore
-	presets, err := h.DB.GetPresets(r.Context())
+	// ?sort=category|name|updated&order=asc|desc
+	var opts database.PresetOptions
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "", database.SortCategory, database.SortName, database.SortUpdated:
+		opts.Sort = sort
+	default:
+		http.Error(w, "sort must be category, name or updated", http.StatusBadRequest)
+		return
+	}
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	// Only the gallery fields are read (see database.PresetSummary)
+	presets, err := h.getPresets(r.Context(), opts)
 	if err != nil {
 		log.Printf("Failed to get presets from DB: %v", err)
 		http.Error(w, "Failed to fetch presets", http.StatusInternalServerError)
 		return
 	}
 
+	// ?lang=ja swaps in localized display names (see `banana admin localize`)
+	// and narrated forecasts (NARRATION_LANGS)
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		presets = slices.Clone(presets) // Cached slices are shared
+		for i := range presets {
+			presets[i].Name = presets[i].LocalizedName(lang)
+			presets[i].AudioURL = presets[i].LocalizedAudioURL(lang)
+		}
+	}
+
+	if r.URL.Query().Get("groupBy") == "continent" {
+		groups := groupByContinent(presets)
+		var ordered []database.PresetSummary
+		for _, g := range groups {
+			ordered = append(ordered, g.Presets...)
+		}
+		preloadImages(w, ordered, h.PreloadImages)
+		writeJSON(w, http.StatusOK, groups)
+		return
+	}
+
+	preloadImages(w, presets, h.PreloadImages)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(presets)
 }
 
+// HandleGetCategories returns the gallery categories in display order, with
+// their cover art (see jobs.CategoryCovers) for section headers.
+func (h *Handler) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.DB.ListCategories(r.Context())
+	if err != nil {
+		log.Printf("Failed to get categories from DB: %v", err)
+		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+	if categories == nil {
+		categories = []database.Category{}
+	}
+	writeJSON(w, http.StatusOK, categories)
+}
+
+// preloadImages adds a Link: rel=preload header for the first n preset images
+// (in gallery order) so browsers start fetching them while the JSON is still
+// being parsed. Server push isn't used: the images live on the bucket's
+// origin, and browsers have dropped support for push anyway.
+func preloadImages(w http.ResponseWriter, presets []database.PresetSummary, n int) {
+	for _, p := range presets {
+		if n <= 0 {
+			return
+		}
+		if p.ImageURL == "" || strings.ContainsAny(p.ImageURL, "<>,") {
+			continue
+		}
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=preload; as=image", p.ImageURL))
+		n--
+	}
+}
+
+// ContinentGroup is one entry of GET /api/presets?groupBy=continent.
+type ContinentGroup struct {
+	Continent string                   `json:"continent"` // "" for fictional or not yet geocoded presets
+	Presets   []database.PresetSummary `json:"presets"`
+}
+
+// groupByContinent groups presets alphabetically by continent, with the
+// unknown group last so fictional worlds don't lead the list.
+func groupByContinent(presets []database.PresetSummary) []ContinentGroup {
+	byContinent := make(map[string][]database.PresetSummary)
+	for _, p := range presets {
+		byContinent[p.Continent] = append(byContinent[p.Continent], p)
+	}
+
+	groups := make([]ContinentGroup, 0, len(byContinent))
+	for c, locs := range byContinent {
+		groups = append(groups, ContinentGroup{Continent: c, Presets: locs})
+	}
+	slices.SortFunc(groups, func(a, b ContinentGroup) int {
+		if (a.Continent == "") != (b.Continent == "") {
+			if a.Continent == "" {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Continent, b.Continent)
+	})
+	return groups
+}
+
+func (h *Handler) HandleLocationsByCountry(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if len(code) != 2 {
+		http.Error(w, "country code must be ISO 3166-1 alpha-2, e.g. US", http.StatusBadRequest)
+		return
+	}
+
+	locs, err := h.DB.GetLocationsByCountry(r.Context(), code, 100)
+	if err != nil {
+		log.Printf("Failed to get locations for country %s: %v", code, err)
+		http.Error(w, "Failed to fetch locations", http.StatusInternalServerError)
+		return
+	}
+	if locs == nil {
+		locs = []database.Location{}
+	}
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		for i := range locs {
+			locs[i].Name = locs[i].LocalizedName(lang)
+			locs[i].AudioURL = locs[i].LocalizedAudioURL(lang)
+		}
+	}
+	writeJSON(w, http.StatusOK, locs)
+}
+
 func (h *Handler) HandleGetWeather(w http.ResponseWriter, r *http.Request) {
+	// Reject bad queries with a plain 400 before the event stream starts
+	city, err := query.Normalize(r.URL.Query().Get("city"))
+	var qe *query.Error
+	if errors.As(err, &qe) {
+		log.Printf("Rejected city query (%s): %q", qe.Code, r.URL.Query().Get("city"))
+		writeJSON(w, http.StatusBadRequest, qe)
+		return
+	}
+
+	ctx := r.Context()
+	if g := r.URL.Query().Get("grounding"); g != "" {
+		mode, err := genai.ParseGroundingMode(g)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = genai.WithGrounding(ctx, mode)
+	}
+	lang, err := requestLanguage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx = genai.WithLanguage(ctx, lang)
+
 	// Check for SSE support
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -41,22 +267,157 @@ func (h *Handler) HandleGetWeather(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	latStr := r.URL.Query().Get("lat")
+	lngStr := r.URL.Query().Get("lng")
+	ref := r.URL.Query().Get("reference")
+	ctx = startFlow(ctx, w)
+	trace := h.startTrace(ctx, city, latStr, lngStr, ref)
+
 	// Helper to send SSE events
 	sendEvent := func(event string, data string) {
-		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		writeSSE(w, event, data)
 		flusher.Flush()
 	}
+	h.runWeatherFlow(ctx, city, latStr, lngStr, ref, trace, sendEvent)
+}
 
-	city := r.URL.Query().Get("city")
-	latStr := r.URL.Query().Get("lat")
-	lngStr := r.URL.Query().Get("lng")
+// runWeatherFlow runs the web flow for a query, sending its events (and
+// recording them in trace, when sampled) through send. It's shared by the
+// SSE and long-poll endpoints; ctx carries the flow's ID, see startFlow.
+func (h *Handler) runWeatherFlow(ctx context.Context, city, lat, lng, ref string, trace *database.FlowTrace, send func(event, data string)) {
+	sendEvent := func(event string, data string) {
+		if trace != nil {
+			trace.Add(event, data, time.Now())
+		}
+		send(event, data)
+	}
 
-	// Call Service Flow
-	err := h.Weather.GetWeatherFlow(r.Context(), city, latStr, lngStr, sendEvent)
+	// Call Service Flow; ?reference= is an object from POST /api/uploads
+	var err error
+	if ref != "" {
+		err = h.Weather.GetReferenceFlow(ctx, city, lat, lng, ref, sendEvent)
+	} else {
+		err = h.Weather.GetWeatherFlow(ctx, city, lat, lng, sendEvent)
+	}
 	if err != nil {
 		// Error is already logged and sent via SSE inside the service if needed,
 		// or we can catch generic errors here.
 		// The service sends "error" events for user-facing issues.
-		log.Printf("Weather flow finished with error: %v", err)
+		progress.Logf(ctx, "Weather flow finished with error: %v", err)
+	}
+	h.saveTrace(ctx, trace, err)
+}
+
+// writeSSE writes one server-sent event. data must not contain newlines;
+// the weather flow only sends single-line JSON and status text.
+func writeSSE(w io.Writer, event, data string) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// FeedbackRequest is the body accepted by POST /api/locations/{id}/feedback.
+type FeedbackRequest struct {
+	Vote   string `json:"vote"`             // "up" or "down"
+	Reason string `json:"reason,omitempty"` // One of database.FeedbackReasons
+}
+
+func (h *Handler) HandleLocationFeedback(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Vote != "up" && req.Vote != "down" {
+		http.Error(w, "vote must be 'up' or 'down'", http.StatusBadRequest)
+		return
+	}
+	if req.Reason != "" && !slices.Contains(database.FeedbackReasons, req.Reason) {
+		http.Error(w, "Unknown reason", http.StatusBadRequest)
+		return
+	}
+
+	// Tie the vote to the media it was cast on
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load %s for feedback: %v", id, err)
+		http.Error(w, "Failed to record feedback", http.StatusInternalServerError)
+		return
+	}
+
+	fb := database.Feedback{
+		Up:       req.Vote == "up",
+		Reason:   req.Reason,
+		ImageURL: loc.ImageURL,
+	}
+	if err := h.DB.AddFeedback(r.Context(), id, fb); err != nil {
+		log.Printf("Failed to record feedback for %s: %v", id, err)
+		http.Error(w, "Failed to record feedback", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReportRequest is the body accepted by POST /api/locations/{id}/report.
+type ReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+// maxReportReason is the longest report reason kept, in characters.
+const maxReportReason = 500
+
+// ReportsPerHour is how many reports a client may send per hour, to any
+// locations.
+const ReportsPerHour = 10
+
+// HandleLocationReport serves POST /api/locations/{id}/report. Anyone may
+// report; each client counts once per location (see database.Report), and
+// the route is rate limited (see RateLimit).
+func (h *Handler) HandleLocationReport(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if reason := []rune(req.Reason); len(reason) > maxReportReason {
+		req.Reason = string(reason[:maxReportReason])
+	}
+
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load %s for report: %v", id, err)
+		http.Error(w, "Failed to record report", http.StatusInternalServerError)
+		return
+	}
+
+	// Each client's report counts once, so a takedown needs several clients
+	report := database.Report{Reporter: clientID(r, h.TrustedProxies), Reason: req.Reason, ImageURL: loc.ImageURL}
+	hidden, err := h.DB.AddReport(r.Context(), id, report, h.ReportThreshold)
+	if err != nil {
+		log.Printf("Failed to record report for %s: %v", id, err)
+		http.Error(w, "Failed to record report", http.StatusInternalServerError)
+		return
+	}
+
+	if hidden {
+		h.presetsChanged()
+		log.Printf("Location %s hidden pending review after %d reports", id, h.ReportThreshold)
+		if h.Notifier != nil {
+			msg := fmt.Sprintf("%s (%s) was hidden after %d reports. Latest reason: %q\nReview with: banana admin review --id %s", loc.Name, id, h.ReportThreshold, req.Reason, id)
+			if err := h.Notifier.Notify(r.Context(), "Location taken down", msg); err != nil {
+				log.Printf("Failed to notify admins about %s: %v", id, err)
+			}
+		}
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+)
+
+func TestWriteSSE(t *testing.T) {
+	var sb strings.Builder
+	writeSSE(&sb, "status", "Identifying location...")
+	if got, want := sb.String(), "event: status\ndata: Identifying location...\n\n"; got != want {
+		t.Errorf("writeSSE() = %q, want %q", got, want)
+	}
+}
+
+// BenchmarkWriteSSE_Result measures the result event, which carries the
+// whole base64 image.
+func BenchmarkWriteSSE_Result(b *testing.B) {
+	img := base64.StdEncoding.EncodeToString(make([]byte, 1536*1024))
+	resp := weather.WeatherResponse{
+		ID:          "london_uk",
+		City:        "London, UK",
+		ImageBase64: img,
+		LastUpdated: time.Now(),
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			b.Fatal(err)
+		}
+		writeSSE(io.Discard, "result", string(data))
+	}
+}
+
+func samplePresets(n int) []database.PresetSummary {
+	continents := []string{"Africa", "Asia", "Europe", "North America", "Oceania", "South America", ""}
+	presets := make([]database.PresetSummary, n)
+	for i := range presets {
+		presets[i] = database.PresetSummary{
+			ID:          fmt.Sprintf("preset_%d", i),
+			Name:        fmt.Sprintf("City %d", i),
+			NameI18n:    map[string]string{"ja": fmt.Sprintf("都市 %d", i), "de": fmt.Sprintf("Stadt %d", i)},
+			Category:    "General",
+			ImageURL:    fmt.Sprintf("https://storage.googleapis.com/bucket/preset_%d_image.png", i),
+			VideoURL:    fmt.Sprintf("https://storage.googleapis.com/bucket/videos/preset_%d.mp4", i),
+			Continent:   continents[i%len(continents)],
+			LastUpdated: time.Now(),
+		}
+	}
+	return presets
+}
+
+func BenchmarkPresetsJSON(b *testing.B) {
+	presets := samplePresets(200)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := json.NewEncoder(io.Discard).Encode(presets); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPresetsJSON_GroupByContinent(b *testing.B) {
+	presets := samplePresets(200)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := json.NewEncoder(io.Discard).Encode(groupByContinent(presets)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPreloadImages(t *testing.T) {
+	presets := samplePresets(4)
+	presets[1].ImageURL = ""
+	rec := httptest.NewRecorder()
+	preloadImages(rec, presets, 2)
+
+	links := rec.Header().Values("Link")
+	want := []string{
+		"<https://storage.googleapis.com/bucket/preset_0_image.png>; rel=preload; as=image",
+		"<https://storage.googleapis.com/bucket/preset_2_image.png>; rel=preload; as=image",
+	}
+	if !slices.Equal(links, want) {
+		t.Errorf("Expected %v, got %v", want, links)
+	}
+}
+
+func TestHandleGetPresets_BadParams(t *testing.T) {
+	h := &Handler{}
+	for _, q := range []string{"sort=popular", "order=up", "sort=name&order=DESC"} {
+		rec := httptest.NewRecorder()
+		h.HandleGetPresets(rec, httptest.NewRequest(http.MethodGet, "/api/presets?"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"banana-weather/internal/genai"
+)
+
+// requestLanguage returns the language a weather image's text is rendered
+// in: ?lang= when given (an invalid one is an error), else the client's
+// preferred Accept-Language. "" is the city's native language.
+func requestLanguage(r *http.Request) (string, error) {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return genai.ParseLanguage(lang)
+	}
+	return parseAcceptLanguage(r.Header.Get("Accept-Language")), nil
+}
+
+// parseAcceptLanguage returns the highest-weighted valid tag of an
+// Accept-Language header, the first on ties. Wildcards, q=0 and malformed
+// entries are skipped; "" if none is left.
+func parseAcceptLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		lang, err := genai.ParseLanguage(tag)
+		if err != nil || lang == "" || q <= bestQ {
+			continue
+		}
+		best, bestQ = lang, q
+	}
+	return best
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                               "",
+		"*":                              "",
+		"ja":                             "ja",
+		"pt-br,pt;q=0.8,en;q=0.5":        "pt-BR",
+		"en;q=0.5, fr-CA;q=0.9, de":      "de",
+		"fr;q=0, es;q=0.3":               "es",
+		"*;q=1, x_y_z!, zh-hant-tw;q=.7": "zh-Hant-TW",
+		"en;q=abc":                       "",
+	} {
+		if got := parseAcceptLanguage(header); got != want {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRequestLanguage(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/weather?city=Lisbon&lang=ja", nil)
+	r.Header.Set("Accept-Language", "pt-PT")
+	if lang, err := requestLanguage(r); err != nil || lang != "ja" {
+		t.Errorf("Expected ?lang= to win, got %q, %v", lang, err)
+	}
+	r = httptest.NewRequest("GET", "/api/weather?city=Lisbon", nil)
+	r.Header.Set("Accept-Language", "pt-PT,en;q=0.5")
+	if lang, err := requestLanguage(r); err != nil || lang != "pt-PT" {
+		t.Errorf("Expected the Accept-Language default, got %q, %v", lang, err)
+	}
+	r = httptest.NewRequest("GET", "/api/weather?city=Lisbon&lang=not-a-language!", nil)
+	if _, err := requestLanguage(r); err == nil {
+		t.Error("Expected an error for an invalid ?lang=")
+	}
+}
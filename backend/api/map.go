@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/geojson"
+)
+
+// mapMaxLocations caps how many documents one map request reads.
+const mapMaxLocations = 5000
+
+// HandleMapGeoJSON serves GET /api/map.geojson: every visible location with
+// coordinates as a GeoJSON FeatureCollection. With ?zoom=N (0-20) nearby
+// points are merged into cluster features for that zoom level.
+func (h *Handler) HandleMapGeoJSON(w http.ResponseWriter, r *http.Request) {
+	var zoom int
+	cluster := false
+	if z := r.URL.Query().Get("zoom"); z != "" {
+		n, err := strconv.Atoi(z)
+		if err != nil || n < 0 || n > 20 {
+			http.Error(w, "zoom must be an integer 0-20", http.StatusBadRequest)
+			return
+		}
+		zoom, cluster = n, true
+	}
+
+	locs, err := h.DB.ListLocations(r.Context(), database.ListOptions{Limit: mapMaxLocations})
+	if err != nil {
+		log.Printf("Failed to list locations for map: %v", err)
+		http.Error(w, "Failed to fetch locations", http.StatusInternalServerError)
+		return
+	}
+
+	features := make([]geojson.Feature, 0, len(locs))
+	for _, l := range locs {
+		if l.Geo == nil || l.IsHidden() || l.Status == database.StatusArchived {
+			continue
+		}
+		features = append(features, geojson.NewPoint(l.Geo.Latitude, l.Geo.Longitude, map[string]any{
+			"id":            l.ID,
+			"name":          l.Name,
+			"thumbnail_url": l.ImageURL, // No separate thumbnails yet
+			"status":        l.EffectiveStatus(),
+			"is_preset":     l.IsPreset,
+		}))
+	}
+	if cluster {
+		features = geojson.Cluster(features, zoom)
+	}
+
+	// Locations change at most every few minutes; let browsers and CDNs absorb map panning
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(geojson.NewCollection(features))
+}
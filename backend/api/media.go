@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mediaURL returns the URL of one kind of media of loc ("image", "poster" or
+// "video") and the store it's in.
+func (h *Handler) mediaURL(loc *database.Location, kind string) (string, storage.Store) {
+	switch kind {
+	case "image":
+		return loc.ImageURL, h.Media[storage.KindImage]
+	case "poster":
+		return loc.PosterURL, h.Media[storage.KindImage]
+	case "video":
+		return loc.VideoURL, h.Media[storage.KindVideo]
+	}
+	return "", nil
+}
+
+// HandleMediaProxy serves GET /media/proxy/{locationID}/{kind}: the location's
+// current image, poster or video, streamed from the bucket with Range and
+// conditional request support. The URL is stable across refreshes and never
+// expires, unlike signed URLs, so long-running players keep working while the
+// bucket stays private. Hidden locations are only served with the admin API
+// key.
+func (h *Handler) HandleMediaProxy(w http.ResponseWriter, r *http.Request) {
+	if h.Media == nil {
+		http.Error(w, "Media proxy is not enabled", http.StatusNotImplemented)
+		return
+	}
+	id := chi.URLParam(r, "locationID")
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound || (err == nil && loc.IsHidden() && !hasAPIKey(r, h.AdminAPIKey)) {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load %s: %v", id, err)
+		http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+		return
+	}
+
+	url, store := h.mediaURL(loc, chi.URLParam(r, "kind"))
+	if url == "" || store == nil {
+		http.Error(w, "No such media", http.StatusNotFound)
+		return
+	}
+	name := store.ObjectName(url)
+	if name == "" {
+		http.Error(w, "Media is not in our bucket", http.StatusNotFound)
+		return
+	}
+	obj, err := storage.OpenObject(r.Context(), store, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "No such media", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open %s for the media proxy: %v", name, err)
+		http.Error(w, "Failed to read media", http.StatusBadGateway)
+		return
+	}
+	defer obj.Close()
+
+	if obj.ContentType != "" {
+		w.Header().Set("Content-Type", obj.ContentType)
+	}
+	if obj.ETag != "" {
+		w.Header().Set("ETag", `"`+obj.ETag+`"`)
+	}
+	// The URL outlives the media behind it, so clients revalidate (cheaply, by ETag)
+	if loc.IsHidden() {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "public, no-cache")
+	}
+	http.ServeContent(w, r, "", obj.Updated, obj)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeMediaStore serves in-memory objects under a public-looking URL.
+type fakeMediaStore struct {
+	storage.Store
+	objects map[string]string
+}
+
+func (f *fakeMediaStore) ObjectName(url string) string {
+	name, _ := strings.CutPrefix(url, "https://storage.googleapis.com/private/")
+	if name == url {
+		return ""
+	}
+	return name
+}
+func (f *fakeMediaStore) Stat(ctx context.Context, name string) (*storage.ObjectInfo, error) {
+	data, ok := f.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, storage.ErrNotFound)
+	}
+	return &storage.ObjectInfo{Size: int64(len(data)), ContentType: "video/mp4", ETag: "abc", Updated: time.Unix(1700000000, 0)}, nil
+}
+func (f *fakeMediaStore) NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.objects[name][offset:])), nil
+}
+
+func TestHandleMediaProxy(t *testing.T) {
+	store := &fakeMediaStore{objects: map[string]string{"paris.mp4": "0123456789"}}
+	db := &fakeWalletDB{locs: map[string]*database.Location{
+		"paris":  {ID: "paris", VideoURL: "https://storage.googleapis.com/private/paris.mp4"},
+		"hidden": {ID: "hidden", VideoURL: "https://storage.googleapis.com/private/paris.mp4", Status: database.StatusHidden},
+		"gone":   {ID: "gone", VideoURL: "https://storage.googleapis.com/private/gone.mp4"},
+	}}
+	h := &Handler{DB: db, AdminAPIKey: "key", Media: map[storage.Kind]storage.Store{storage.KindImage: store, storage.KindVideo: store}}
+	r := chi.NewRouter()
+	r.Get("/media/proxy/{locationID}/{kind}", h.HandleMediaProxy)
+
+	tests := []struct {
+		name, path string
+		header     map[string]string
+		status     int
+		body       string
+	}{
+		{"whole", "/media/proxy/paris/video", nil, http.StatusOK, "0123456789"},
+		{"range", "/media/proxy/paris/video", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234"},
+		{"revalidate", "/media/proxy/paris/video", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotModified, ""},
+		{"no poster", "/media/proxy/paris/poster", nil, http.StatusNotFound, ""},
+		{"unknown kind", "/media/proxy/paris/original", nil, http.StatusNotFound, ""},
+		{"hidden", "/media/proxy/hidden/video", nil, http.StatusNotFound, ""},
+		{"hidden as admin", "/media/proxy/hidden/video", map[string]string{"X-API-Key": "key"}, http.StatusOK, "0123456789"},
+		{"missing object", "/media/proxy/gone/video", nil, http.StatusNotFound, ""},
+		{"unknown location", "/media/proxy/nowhere/video", nil, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.status || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, rec.Code, rec.Body.String(), tt.status, tt.body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	(&Handler{DB: db}).HandleMediaProxy(rec, httptest.NewRequest(http.MethodGet, "/media/proxy/paris/video", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without stores, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// prefersHLS reports whether the client asked for, or can play, HLS:
+// ?format=hls (or mp4 to opt out), an Accept header listing an HLS playlist
+// type, or Apple's media framework, which plays HLS natively.
+func prefersHLS(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "hls":
+		return true
+	case "mp4":
+		return false
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "mpegurl") ||
+		strings.Contains(r.UserAgent(), "AppleCoreMedia")
+}
+
+// HandleLocationPlaylist serves GET /api/locations/{id}/playlist by
+// redirecting to the location's video: the HLS master playlist when there is
+// one and the client supports it, otherwise the MP4.
+func (h *Handler) HandleLocationPlaylist(w http.ResponseWriter, r *http.Request) {
+	loc := h.servableLocation(w, r, chi.URLParam(r, "id"))
+	if loc == nil {
+		return
+	}
+	target := loc.VideoURL
+	if loc.StreamURL != "" && prefersHLS(r) {
+		target = loc.StreamURL
+	}
+	if target == "" {
+		http.Error(w, "Location has no video", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Vary", "Accept, User-Agent")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	http.Redirect(w, r, target, http.StatusFound)
+}
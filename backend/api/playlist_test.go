@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleLocationPlaylist(t *testing.T) {
+	db := &fakeWalletDB{locs: map[string]*database.Location{
+		"paris": {ID: "paris", ImageURL: "https://example.com/p.png", VideoURL: "https://example.com/p.mp4", StreamURL: "https://example.com/p_hls/master.m3u8"},
+		"rome":  {ID: "rome", ImageURL: "https://example.com/r.png", VideoURL: "https://example.com/r.mp4"},
+		"still": {ID: "still", ImageURL: "https://example.com/s.png"},
+	}}
+	r := chi.NewRouter()
+	r.Get("/api/locations/{id}/playlist", (&Handler{DB: db}).HandleLocationPlaylist)
+
+	tests := []struct {
+		name, path, accept string
+		status             int
+		location           string
+	}{
+		{"mp4 by default", "/api/locations/paris/playlist", "", http.StatusFound, "https://example.com/p.mp4"},
+		{"hls when accepted", "/api/locations/paris/playlist", "application/vnd.apple.mpegurl, */*", http.StatusFound, "https://example.com/p_hls/master.m3u8"},
+		{"hls on request", "/api/locations/paris/playlist?format=hls", "", http.StatusFound, "https://example.com/p_hls/master.m3u8"},
+		{"mp4 on request", "/api/locations/paris/playlist?format=mp4", "application/x-mpegURL", http.StatusFound, "https://example.com/p.mp4"},
+		{"mp4 without stream", "/api/locations/rome/playlist?format=hls", "", http.StatusFound, "https://example.com/r.mp4"},
+		{"no video", "/api/locations/still/playlist", "", http.StatusNotFound, ""},
+		{"unknown", "/api/locations/nowhere/playlist", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, rec.Code, rec.Header().Get("Location"), tt.status, tt.location)
+		}
+	}
+}
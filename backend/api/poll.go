@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/query"
+)
+
+// FlowEvent is one event of a weather flow: the SSE event name and data,
+// numbered from 1 in the order sent.
+type FlowEvent struct {
+	Seq   int    `json:"seq"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+}
+
+// PollResponse is the body of GET /api/weather/poll: the events after the
+// cursor, and the cursor for the next poll (the last event's seq).
+type PollResponse struct {
+	Flow   string      `json:"flow"`
+	Events []FlowEvent `json:"events"`
+	Next   int         `json:"next"`
+	Done   bool        `json:"done"` // No events will follow
+}
+
+// FlowBuffer runs weather flows for clients that can't hold an SSE stream
+// (some webviews and proxies) and keeps each flow's events, so the client
+// fetches them in batches by cursor, and a poll whose response was lost is
+// simply repeated. A flow runs detached from the request that started it;
+// one nobody polls for Abandon is cancelled, and a finished one is dropped
+// TTL after its end, or earlier, oldest first, when the buffered events
+// (results carry the image in base64) outgrow MaxBytes. Starts beyond
+// MaxRunning running flows, or while the running ones alone fill MaxBytes,
+// are refused with errFlowsFull.
+type FlowBuffer struct {
+	Wait       time.Duration // How long a poll waits for new events
+	TTL        time.Duration // How long a finished flow's events are kept
+	Abandon    time.Duration // Unpolled time after which a flow is cancelled
+	MaxRunning int           // Flows running at once
+	MaxBytes   int           // Event data kept across flows
+
+	clock   clock.Clock
+	mu      sync.Mutex
+	flows   map[string]*bufferedFlow
+	running int // Flows not done
+	bytes   int // Event data of all flows
+}
+
+type bufferedFlow struct {
+	events   []FlowEvent
+	bytes    int // Of the events' data
+	done     bool
+	finished time.Time
+	changed  chan struct{} // Closed, and replaced, when events arrive or the flow ends
+	abandon  *time.Timer
+}
+
+// Flow buffer defaults: a poll returns before common 30s proxy timeouts.
+const (
+	DefaultPollWait      = 25 * time.Second
+	DefaultFlowTTL       = 10 * time.Minute
+	DefaultFlowAbandon   = 2 * time.Minute
+	DefaultMaxFlows      = 32
+	DefaultMaxFlowBytes  = 64 << 20
+	maxFlowEventsPerPoll = 100
+)
+
+// errFlowsFull refuses a flow start when the buffer is at capacity.
+var errFlowsFull = errors.New("too many polled flows")
+
+// NewFlowBuffer returns a FlowBuffer with the default timings.
+func NewFlowBuffer() *FlowBuffer {
+	return &FlowBuffer{
+		Wait:       DefaultPollWait,
+		TTL:        DefaultFlowTTL,
+		Abandon:    DefaultFlowAbandon,
+		MaxRunning: DefaultMaxFlows,
+		MaxBytes:   DefaultMaxFlowBytes,
+		clock:      clock.Real{},
+		flows:      make(map[string]*bufferedFlow),
+	}
+}
+
+// start registers a new flow and returns its ID (the weather flow's, when
+// ctx has one), a context cancelled when the flow is abandoned, the function
+// recording its events, and the one marking it done. It returns
+// errFlowsFull when the buffer is at capacity.
+func (b *FlowBuffer) start(ctx context.Context) (string, context.Context, func(event, data string), func(), error) {
+	flowID := progress.FlowID(ctx)
+	if flowID == "" {
+		flowID = progress.NewFlowID()
+	}
+
+	b.mu.Lock()
+	b.sweep()
+	b.evict()
+	if b.running >= b.MaxRunning || b.bytes > b.MaxBytes {
+		b.mu.Unlock()
+		return "", nil, nil, nil, errFlowsFull
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &bufferedFlow{changed: make(chan struct{}), abandon: time.AfterFunc(b.Abandon, cancel)}
+	b.flows[flowID] = f
+	b.running++
+	b.mu.Unlock()
+
+	send := func(event, data string) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if f.done {
+			return
+		}
+		f.events = append(f.events, FlowEvent{Seq: len(f.events) + 1, Event: event, Data: data})
+		f.bytes += len(data)
+		b.bytes += len(data)
+		b.evict()
+		f.notify()
+	}
+	finish := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		f.abandon.Stop()
+		cancel()
+		if !f.done {
+			f.done = true
+			f.finished = b.clock.Now()
+			b.running--
+			f.notify()
+		}
+	}
+	return flowID, ctx, send, finish, nil
+}
+
+func (f *bufferedFlow) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// since returns the events of flow id after seq after, waiting up to Wait
+// (or until ctx ends) for some when there are none yet. ok is false when
+// the flow is unknown or expired.
+func (b *FlowBuffer) since(ctx context.Context, id string, after int) (events []FlowEvent, done, ok bool) {
+	timeout := time.NewTimer(b.Wait)
+	defer timeout.Stop()
+	for {
+		b.mu.Lock()
+		b.sweep()
+		f, ok := b.flows[id]
+		if !ok {
+			b.mu.Unlock()
+			return nil, false, false
+		}
+		if !f.done {
+			f.abandon.Reset(b.Abandon)
+		}
+		if after < len(f.events) || f.done {
+			events = f.events[min(after, len(f.events)):]
+			if len(events) > maxFlowEventsPerPoll {
+				events = events[:maxFlowEventsPerPoll]
+			}
+			done = f.done && after+len(events) == len(f.events)
+			b.mu.Unlock()
+			return events, done, true
+		}
+		changed := f.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return nil, false, true
+		case <-ctx.Done():
+			return nil, false, true
+		}
+	}
+}
+
+// sweep drops the flows that finished more than TTL ago. b.mu must be held.
+func (b *FlowBuffer) sweep() {
+	now := b.clock.Now()
+	for id, f := range b.flows {
+		if f.done && now.Sub(f.finished) > b.TTL {
+			b.drop(id)
+		}
+	}
+}
+
+// evict drops finished flows, oldest first, while the buffered events
+// outgrow MaxBytes. b.mu must be held.
+func (b *FlowBuffer) evict() {
+	for b.bytes > b.MaxBytes {
+		oldest := ""
+		for id, f := range b.flows {
+			if f.done && (oldest == "" || f.finished.Before(b.flows[oldest].finished)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		b.drop(oldest)
+	}
+}
+
+// drop forgets flow id. b.mu must be held.
+func (b *FlowBuffer) drop(id string) {
+	b.bytes -= b.flows[id].bytes
+	delete(b.flows, id)
+}
+
+// HandleWeatherPoll is the long-poll form of GET /api/weather. Without
+// ?flow= it starts a flow (same query parameters as /api/weather) and
+// returns its ID with the first events; then ?flow=<id>&after=<seq> returns
+// the events after the cursor, waiting for some when there are none yet.
+// An empty batch that isn't done means "poll again".
+func (h *Handler) HandleWeatherPoll(w http.ResponseWriter, r *http.Request) {
+	if h.Flows == nil {
+		http.Error(w, "Polling is not enabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flowID := r.URL.Query().Get("flow")
+	after := 0
+	if flowID == "" {
+		var ok bool
+		if flowID, ok = h.startPolledFlow(w, r); !ok {
+			return
+		}
+	} else if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "after must be a non-negative event seq", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+
+	events, done, ok := h.Flows.since(r.Context(), flowID, after)
+	if !ok {
+		http.Error(w, "Flow not found or expired", http.StatusNotFound)
+		return
+	}
+	next := after
+	if len(events) > 0 {
+		next = events[len(events)-1].Seq
+	}
+	if events == nil {
+		events = []FlowEvent{}
+	}
+	writeJSON(w, http.StatusOK, PollResponse{Flow: flowID, Events: events, Next: next, Done: done})
+}
+
+// startPolledFlow validates a /api/weather query like HandleGetWeather and
+// starts its flow in h.Flows. It writes the error response and returns false
+// for a bad query, or 503 when the buffer is full.
+func (h *Handler) startPolledFlow(w http.ResponseWriter, r *http.Request) (string, bool) {
+	q := r.URL.Query()
+	city, err := query.Normalize(q.Get("city"))
+	var qe *query.Error
+	if errors.As(err, &qe) {
+		log.Printf("Rejected city query (%s): %q", qe.Code, q.Get("city"))
+		writeJSON(w, http.StatusBadRequest, qe)
+		return "", false
+	}
+	ctx := r.Context()
+	if g := q.Get("grounding"); g != "" {
+		mode, err := genai.ParseGroundingMode(g)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return "", false
+		}
+		ctx = genai.WithGrounding(ctx, mode)
+	}
+	lang, err := requestLanguage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	ctx = genai.WithLanguage(ctx, lang)
+
+	ctx = startFlow(ctx, w)
+	id, ctx, send, finish, err := h.Flows.start(ctx)
+	if err != nil {
+		log.Printf("Refused polled flow for %q: %v", city, err)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Too many flows in progress, try again shortly", http.StatusServiceUnavailable)
+		return "", false
+	}
+	lat, lng, ref := q.Get("lat"), q.Get("lng"), q.Get("reference")
+	trace := h.startTrace(ctx, city, lat, lng, ref)
+	go func() {
+		defer finish()
+		h.runWeatherFlow(ctx, city, lat, lng, ref, trace, send)
+	}()
+	return id, true
+}
+
+// PolledFlowsPerHour is how many flows a client may start per hour through
+// GET /api/weather/poll.
+const PolledFlowsPerHour = 30
+
+// RateLimitFlowStarts is RateLimit for the GET /api/weather/poll requests
+// that start a flow; polls of a started one (?flow=) aren't counted.
+func RateLimitFlowStarts(n int, window time.Duration, trustedProxies int) func(http.Handler) http.Handler {
+	limit := RateLimit(n, window, trustedProxies)
+	return func(next http.Handler) http.Handler {
+		limited := limit(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("flow") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/progress"
+)
+
+func TestHandleWeatherPoll(t *testing.T) {
+	h := &Handler{Flows: NewFlowBuffer()}
+	h.Flows.Wait = 50 * time.Millisecond
+	id, _, send, finish, _ := h.Flows.start(context.Background())
+
+	poll := func(query string) (*httptest.ResponseRecorder, PollResponse) {
+		rec := httptest.NewRecorder()
+		h.HandleWeatherPoll(rec, httptest.NewRequest(http.MethodGet, "/api/weather/poll?"+query, nil))
+		var resp PollResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, resp
+	}
+
+	// Nothing yet: the poll times out empty, with the same cursor
+	if _, resp := poll("flow=" + id); len(resp.Events) != 0 || resp.Next != 0 || resp.Done {
+		t.Errorf("Expected an empty batch, got %+v", resp)
+	}
+
+	send("status", "Checking the weather")
+	send("weather", `{"temp":12}`)
+	_, resp := poll("flow=" + id)
+	if len(resp.Events) != 2 || resp.Events[1] != (FlowEvent{Seq: 2, Event: "weather", Data: `{"temp":12}`}) || resp.Next != 2 {
+		t.Fatalf("Expected both events, got %+v", resp)
+	}
+	// A lost response is repeated with the same cursor
+	if _, again := poll("flow=" + id); len(again.Events) != 2 {
+		t.Errorf("Expected the batch again, got %+v", again)
+	}
+
+	// A waiting poll returns as soon as an event arrives
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		send("image", "https://example.com/a.png")
+		finish()
+	}()
+	h.Flows.Wait = time.Second
+	if _, resp = poll(fmt.Sprintf("flow=%s&after=%d", id, resp.Next)); len(resp.Events) != 1 || resp.Events[0].Seq != 3 {
+		t.Fatalf("Expected the third event, got %+v", resp)
+	}
+	if _, resp = poll(fmt.Sprintf("flow=%s&after=%d", id, resp.Next)); !resp.Done || len(resp.Events) != 0 {
+		t.Errorf("Expected the flow done, got %+v", resp)
+	}
+
+	if rec, _ := poll("flow=" + id + "&after=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad cursor, got %d", rec.Code)
+	}
+	if rec, _ := poll("flow=nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown flow, got %d", rec.Code)
+	}
+	if rec, _ := poll("city=https://example.com"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid query rejected, got %d", rec.Code)
+	}
+}
+
+func TestFlowBuffer_Expiry(t *testing.T) {
+	b := NewFlowBuffer()
+	clk := clock.NewFake(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	b.clock = clk
+	b.Abandon = 20 * time.Millisecond
+
+	// A flow nobody polls is cancelled
+	_, ctx, _, _, _ := b.start(context.Background())
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected an abandoned flow cancelled")
+	}
+
+	id, _, send, finish, _ := b.start(context.Background())
+	send("status", "done")
+	finish()
+	if _, done, ok := b.since(context.Background(), id, 0); !ok || !done {
+		t.Fatalf("Expected the finished flow's events, got done=%v ok=%v", done, ok)
+	}
+	clk.Advance(b.TTL + time.Second)
+	if _, _, ok := b.since(context.Background(), id, 0); ok {
+		t.Error("Expected the flow dropped after its TTL")
+	}
+
+	// A weather flow is polled under its own ID
+	if id, _, _, finish, _ := b.start(progress.WithFlowID(context.Background(), "f00d")); id != "f00d" {
+		t.Errorf("Expected the weather flow's ID, got %q", id)
+	} else {
+		finish()
+	}
+}
+
+func TestFlowBuffer_Capacity(t *testing.T) {
+	b := NewFlowBuffer()
+	clk := clock.NewFake(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	b.clock = clk
+	b.MaxRunning = 2
+	b.MaxBytes = 10
+
+	first, _, send1, finish1, _ := b.start(context.Background())
+	_, _, send2, finish2, _ := b.start(context.Background())
+	if _, _, _, _, err := b.start(context.Background()); err != errFlowsFull {
+		t.Fatalf("Expected a third running flow refused, got %v", err)
+	}
+
+	// Finished flows make room, oldest first, once the events outgrow MaxBytes
+	send1("result", "123456")
+	finish1()
+	clk.Advance(time.Second)
+	send2("result", "1234")
+	finish2()
+	third, _, send3, finish3, err := b.start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	send3("result", "12345")
+	if _, _, ok := b.since(context.Background(), first, 0); ok {
+		t.Error("Expected the oldest finished flow evicted")
+	}
+	if _, _, ok := b.since(context.Background(), third, 0); !ok {
+		t.Error("Expected the running flow kept")
+	}
+
+	// Running flows alone filling MaxBytes refuse new ones
+	send3("result", "123456")
+	if _, _, _, _, err := b.start(context.Background()); err != errFlowsFull {
+		t.Errorf("Expected a start refused while running flows fill the buffer, got %v", err)
+	}
+	finish3()
+}
+
+func TestHandleWeatherPoll_Full(t *testing.T) {
+	h := &Handler{Flows: NewFlowBuffer()}
+	h.Flows.MaxRunning = 0
+	rec := httptest.NewRecorder()
+	h.HandleWeatherPoll(rec, httptest.NewRequest(http.MethodGet, "/api/weather/poll?city=Paris", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitFlowStarts(t *testing.T) {
+	h := RateLimitFlowStarts(1, time.Hour, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(query string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/weather/poll?"+query, nil))
+		return rec.Code
+	}
+	if code := serve("city=Paris"); code != http.StatusOK {
+		t.Fatalf("Expected the first start allowed, got %d", code)
+	}
+	if code := serve("city=Lyon"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the second start limited, got %d", code)
+	}
+	for range 3 {
+		if code := serve("flow=abc&after=1"); code != http.StatusOK {
+			t.Errorf("Expected polls not limited, got %d", code)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+)
+
+// HandleAdminPools reports the use of this instance's upstream connection
+// pools (Maps, GCS, Vertex AI), to size HTTP_MAX_CONNS_PER_HOST against the
+// Cloud Run concurrency.
+func (h *Handler) HandleAdminPools(w http.ResponseWriter, r *http.Request) {
+	if h.Pools == nil {
+		http.Error(w, "Connection pools are not configured", http.StatusNotImplemented)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Pools.Stats())
+}
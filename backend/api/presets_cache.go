@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// PresetCache keeps recent GetPresetSummaries results per sort order and
+// coalesces concurrent misses, so a burst of /api/presets requests after
+// expiry costs one backend read. There are only a handful of sort orders, so
+// entries are never evicted, just refreshed.
+type PresetCache struct {
+	db    repo.LocationStore
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[database.PresetOptions]presetCacheEntry
+	gen     int // Bumped by Invalidate, so reads started before it aren't cached
+	group   singleflight.Group
+
+	// Optional, see SignURLs
+	signers map[storage.Kind]signingStore
+	expiry  time.Duration
+	signed  map[string]signedURL // By stored URL
+}
+
+// signingStore is a media store that can sign its objects' URLs.
+type signingStore struct {
+	store  storage.Store
+	signer storage.URLSigner
+}
+
+type signedURL struct {
+	url     string
+	expires time.Time
+}
+
+type presetCacheEntry struct {
+	presets []database.PresetSummary
+	expires time.Time
+}
+
+// NewPresetCache caches db's presets for ttl.
+func NewPresetCache(db repo.LocationStore, ttl time.Duration) *PresetCache {
+	return &PresetCache{db: db, ttl: ttl, clock: clock.Real{}, entries: make(map[database.PresetOptions]presetCacheEntry)}
+}
+
+// SignURLs makes the cache serve signed URLs, valid for expiry, for the
+// images, posters and videos in stores, so clients can read private buckets
+// directly. Signed URLs are reused until half their lifetime is left, and
+// media outside the stores or in stores that can't sign keeps its stored URL.
+func (c *PresetCache) SignURLs(stores map[storage.Kind]storage.Store, expiry time.Duration) {
+	c.signers = map[storage.Kind]signingStore{}
+	for kind, s := range stores {
+		if signer := storage.AsURLSigner(s); signer != nil {
+			c.signers[kind] = signingStore{store: s, signer: signer}
+		}
+	}
+	c.expiry = expiry
+	c.signed = map[string]signedURL{}
+}
+
+// Get returns the presets for opts. The slice is shared: callers must not
+// modify it.
+func (c *PresetCache) Get(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	c.mu.Lock()
+	e, ok := c.entries[opts]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok && c.clock.Now().Before(e.expires) {
+		return e.presets, nil
+	}
+
+	// Keyed by generation too, so requests after an Invalidate don't wait
+	// for a read that started before it
+	v, err, _ := c.group.Do(fmt.Sprintf("%s/%t/%d", opts.Sort, opts.Desc, gen), func() (any, error) {
+		// Detached so one client disconnecting doesn't fail everyone waiting
+		presets, err := c.db.GetPresetSummaries(context.WithoutCancel(ctx), opts)
+		if err != nil {
+			return nil, err
+		}
+		presets = c.sign(context.WithoutCancel(ctx), presets)
+		c.mu.Lock()
+		if c.gen == gen {
+			c.entries[opts] = presetCacheEntry{presets: presets, expires: c.clock.Now().Add(c.ttl)}
+		}
+		c.mu.Unlock()
+		return presets, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]database.PresetSummary), nil
+}
+
+// Warm loads the presets in every order GET /api/presets serves, so the first
+// requests after startup are hits (and with SignURLs, their URLs are signed
+// ahead of time). It returns the number of presets.
+func (c *PresetCache) Warm(ctx context.Context) (int, error) {
+	n := 0
+	for _, sort := range []string{"", database.SortCategory, database.SortName, database.SortUpdated} {
+		for _, desc := range []bool{false, true} {
+			presets, err := c.Get(ctx, database.PresetOptions{Sort: sort, Desc: desc})
+			if err != nil {
+				return n, err
+			}
+			n = len(presets)
+		}
+	}
+	return n, nil
+}
+
+// SignedURLs returns how many signed URLs are held for reuse.
+func (c *PresetCache) SignedURLs() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.signed)
+}
+
+// sign returns a copy of presets with their media URLs signed. Media that fails to sign keeps its stored URL.
+func (c *PresetCache) sign(ctx context.Context, presets []database.PresetSummary) []database.PresetSummary {
+	if len(c.signers) == 0 {
+		return presets
+	}
+	// Drop expired URLs, e.g. of media replaced since
+	now := c.clock.Now()
+	c.mu.Lock()
+	for url, s := range c.signed {
+		if !now.Before(s.expires) {
+			delete(c.signed, url)
+		}
+	}
+	c.mu.Unlock()
+
+	presets = slices.Clone(presets)
+	for i := range presets {
+		p := &presets[i]
+		p.ImageURL = c.signURL(ctx, storage.KindImage, p.ImageURL)
+		p.PosterURL = c.signURL(ctx, storage.KindImage, p.PosterURL)
+		p.VideoURL = c.signURL(ctx, storage.KindVideo, p.VideoURL)
+	}
+	return presets
+}
+
+// signChanges returns a copy of changes with their presets' media URLs
+// signed like sign's, for GET /api/presets/stream.
+func (c *PresetCache) signChanges(ctx context.Context, changes []database.PresetChange) []database.PresetChange {
+	if len(c.signers) == 0 {
+		return changes
+	}
+	presets := make([]database.PresetSummary, len(changes))
+	for i, ch := range changes {
+		presets[i] = ch.Preset
+	}
+	presets = c.sign(ctx, presets)
+	changes = slices.Clone(changes)
+	for i := range changes {
+		changes[i].Preset = presets[i]
+	}
+	return changes
+}
+
+func (c *PresetCache) signURL(ctx context.Context, kind storage.Kind, url string) string {
+	s, ok := c.signers[kind]
+	if !ok || url == "" {
+		return url
+	}
+	now := c.clock.Now()
+	c.mu.Lock()
+	cached, ok := c.signed[url]
+	c.mu.Unlock()
+	if ok && cached.expires.Sub(now) > c.expiry/2 {
+		return cached.url
+	}
+
+	name := s.store.ObjectName(url)
+	if name == "" {
+		return url
+	}
+	signed, err := s.signer.SignedURL(ctx, name, c.expiry)
+	if err != nil {
+		log.Printf("Failed to sign %s, serving its stored URL: %v", name, err)
+		return url
+	}
+	c.mu.Lock()
+	c.signed[url] = signedURL{url: signed, expires: now.Add(c.expiry)}
+	c.mu.Unlock()
+	return signed
+}
+
+// Invalidate drops all cached presets, e.g. after an admin edit. Reads in
+// flight still return to their callers but aren't cached, as they may
+// predate the edit.
+func (c *PresetCache) Invalidate() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.gen++
+	c.mu.Unlock()
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+)
+
+// countingStore counts preset reads, blocking each until release is closed.
+type countingStore struct {
+	repo.LocationStore
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *countingStore) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	s.calls.Add(1)
+	<-s.release
+	return []database.PresetSummary{{ID: "paris"}}, nil
+}
+
+func TestPresetCache_CoalescesBurst(t *testing.T) {
+	store := &countingStore{release: make(chan struct{})}
+	cache := NewPresetCache(store, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			presets, err := cache.Get(context.Background(), database.PresetOptions{})
+			if err != nil || len(presets) != 1 {
+				t.Errorf("Unexpected result: %v, %v", presets, err)
+			}
+		})
+	}
+	time.Sleep(50 * time.Millisecond) // Let the burst pile up behind the first read
+	close(store.release)
+	wg.Wait()
+
+	if n := store.calls.Load(); n != 1 {
+		t.Errorf("Expected 1 backend read for the burst, got %d", n)
+	}
+
+	cache.Get(context.Background(), database.PresetOptions{})
+	if n := store.calls.Load(); n != 1 {
+		t.Errorf("Expected a cache hit, got %d reads", n)
+	}
+	cache.Get(context.Background(), database.PresetOptions{Sort: database.SortName})
+	cache.Invalidate()
+	cache.Get(context.Background(), database.PresetOptions{})
+	if n := store.calls.Load(); n != 3 {
+		t.Errorf("Expected a read per sort order and after Invalidate, got %d", n)
+	}
+}
+
+// editedStore serves name, signalling started when a read begins and
+// blocking it until release is closed.
+type editedStore struct {
+	repo.LocationStore
+	name    atomic.Value
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *editedStore) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	name := s.name.Load().(string)
+	s.started <- struct{}{}
+	<-s.release
+	return []database.PresetSummary{{ID: "paris", Name: name}}, nil
+}
+
+func TestPresetCache_InvalidateDuringRead(t *testing.T) {
+	store := &editedStore{started: make(chan struct{}, 2), release: make(chan struct{})}
+	store.name.Store("Paris")
+	cache := NewPresetCache(store, time.Minute)
+
+	done := make(chan []database.PresetSummary)
+	go func() {
+		presets, _ := cache.Get(context.Background(), database.PresetOptions{})
+		done <- presets
+	}()
+	<-store.started
+	store.name.Store("Paris, France") // The admin edit lands while the read is in flight
+	cache.Invalidate()
+	close(store.release)
+	if presets := <-done; len(presets) != 1 || presets[0].Name != "Paris" {
+		t.Fatalf("Expected the read in flight to return what it read, got %+v", presets)
+	}
+
+	presets, err := cache.Get(context.Background(), database.PresetOptions{})
+	if err != nil || len(presets) != 1 || presets[0].Name != "Paris, France" {
+		t.Errorf("Expected the edit after Invalidate, got %+v, %v", presets, err)
+	}
+}
+
+func TestPresetCache_Expiry(t *testing.T) {
+	store := &countingStore{release: make(chan struct{})}
+	close(store.release)
+	cache := NewPresetCache(store, 30*time.Second)
+	clk := clock.NewFake(time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC))
+	cache.clock = clk
+
+	cache.Get(context.Background(), database.PresetOptions{})
+	clk.Advance(29 * time.Second)
+	cache.Get(context.Background(), database.PresetOptions{})
+	if n := store.calls.Load(); n != 1 {
+		t.Errorf("Expected a hit before the TTL, got %d reads", n)
+	}
+	clk.Advance(time.Second)
+	cache.Get(context.Background(), database.PresetOptions{})
+	if n := store.calls.Load(); n != 2 {
+		t.Errorf("Expected a refresh at the TTL, got %d reads", n)
+	}
+}
+
+// signingMediaStore signs URLs by appending a counter.
+type signingMediaStore struct {
+	fakeMediaStore
+	signs int
+}
+
+func (s *signingMediaStore) SignedURL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	s.signs++
+	return fmt.Sprintf("https://signed.test/%s?sig=%d&ttl=%s", name, s.signs, expiry), nil
+}
+
+type presetListStore struct {
+	repo.LocationStore
+	presets []database.PresetSummary
+	calls   int
+}
+
+func (s *presetListStore) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	s.calls++
+	return s.presets, nil
+}
+
+func TestPresetCache_SignURLs(t *testing.T) {
+	db := &presetListStore{presets: []database.PresetSummary{
+		{ID: "paris", ImageURL: "https://storage.googleapis.com/private/paris.png", VideoURL: "https://storage.googleapis.com/private/paris.mp4"},
+		{ID: "elsewhere", ImageURL: "https://cdn.test/elsewhere.png"},
+	}}
+	store := &signingMediaStore{}
+	cache := NewPresetCache(db, 30*time.Second)
+	clk := clock.NewFake(time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC))
+	cache.clock = clk
+	cache.SignURLs(map[storage.Kind]storage.Store{storage.KindImage: store, storage.KindVideo: store}, time.Hour)
+
+	n, err := cache.Warm(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("Warm = %d, %v", n, err)
+	}
+	if db.calls != 8 {
+		t.Errorf("Expected a read per sort order and direction, got %d", db.calls)
+	}
+	presets, _ := cache.Get(context.Background(), database.PresetOptions{Sort: database.SortName})
+	if presets[0].ImageURL != "https://signed.test/paris.png?sig=1&ttl=1h0m0s" || presets[0].VideoURL != "https://signed.test/paris.mp4?sig=2&ttl=1h0m0s" ||
+		presets[1].ImageURL != "https://cdn.test/elsewhere.png" {
+		t.Errorf("Unexpected URLs: %+v", presets)
+	}
+	if db.presets[0].ImageURL != "https://storage.googleapis.com/private/paris.png" {
+		t.Error("Expected the backend's presets left alone")
+	}
+	if store.signs != 2 || cache.SignedURLs() != 2 {
+		t.Errorf("Expected each URL signed once across orders, got %d signatures, %d held", store.signs, cache.SignedURLs())
+	}
+
+	// Reloads reuse signatures until half their lifetime is left
+	clk.Advance(29 * time.Minute)
+	cache.Invalidate()
+	presets, _ = cache.Get(context.Background(), database.PresetOptions{})
+	if store.signs != 2 || presets[0].ImageURL != "https://signed.test/paris.png?sig=1&ttl=1h0m0s" {
+		t.Errorf("Expected the signed URLs reused, got %d signatures", store.signs)
+	}
+	clk.Advance(2 * time.Minute)
+	cache.Invalidate()
+	presets, _ = cache.Get(context.Background(), database.PresetOptions{})
+	if store.signs != 4 || presets[0].ImageURL != "https://signed.test/paris.png?sig=3&ttl=1h0m0s" {
+		t.Errorf("Expected the URLs re-signed, got %d signatures: %+v", store.signs, presets[0])
+	}
+}
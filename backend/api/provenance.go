@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/provenance"
+)
+
+// maxVerifyBytes caps uploads to POST /api/provenance/verify.
+const maxVerifyBytes = 20 << 20
+
+// ProvenanceResponse is returned by POST /api/provenance/verify.
+type ProvenanceResponse struct {
+	HasCredentials bool                     `json:"has_credentials"`
+	Verification   *provenance.Verification `json:"verification,omitempty"`
+	KnownLocation  bool                     `json:"known_location"` // The location ID exists in this deployment
+}
+
+// HandleVerifyProvenance reads the content credentials of an image posted as
+// the request body (PNG or JPEG) and reports whether it's an unmodified image
+// generated, and when signed, signed by this deployment.
+func (h *Handler) HandleVerifyProvenance(w http.ResponseWriter, r *http.Request) {
+	img, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxVerifyBytes))
+	if err != nil {
+		http.Error(w, "Image too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	signer := h.Provenance
+	if signer == nil {
+		signer = &provenance.Signer{}
+	}
+	v, err := signer.Verify(img)
+	if errors.Is(err, provenance.ErrNoCredentials) {
+		writeJSON(w, http.StatusOK, ProvenanceResponse{})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid image: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ProvenanceResponse{HasCredentials: true, Verification: v}
+	if v.Info.LocationID != "" && h.DB != nil {
+		if _, err := h.DB.GetLocation(r.Context(), v.Info.LocationID); err == nil {
+			resp.KnownLocation = true
+		} else {
+			log.Printf("Provenance lookup of %s: %v", v.Info.LocationID, err)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/repo"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLocationDB knows a single location.
+type fakeLocationDB struct {
+	repo.Repository
+	id string
+}
+
+func (f *fakeLocationDB) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	if id != f.id {
+		return nil, status.Errorf(codes.NotFound, "location %s not found", id)
+	}
+	return &database.Location{ID: id}, nil
+}
+
+func TestHandleVerifyProvenance(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	signer := provenance.NewSigner("key")
+	stamped, err := signer.Embed(buf.Bytes(), "test-model", "paris", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{DB: &fakeLocationDB{id: "paris"}, Provenance: signer}
+
+	verify := func(body []byte) ProvenanceResponse {
+		rec := httptest.NewRecorder()
+		h.HandleVerifyProvenance(rec, httptest.NewRequest(http.MethodPost, "/api/provenance/verify", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var resp ProvenanceResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp
+	}
+
+	resp := verify(stamped)
+	if !resp.HasCredentials || !resp.KnownLocation || !resp.Verification.SignatureValid || !resp.Verification.AIGenerated {
+		t.Errorf("Expected verified credentials for a known location, got %+v", resp)
+	}
+	if resp := verify(buf.Bytes()); resp.HasCredentials {
+		t.Errorf("Expected no credentials on the original, got %+v", resp)
+	}
+}
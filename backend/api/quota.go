@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+)
+
+// HandleAdminQuota reports in-flight, per-minute and rejected requests for
+// each quota partition of this instance.
+func (h *Handler) HandleAdminQuota(w http.ResponseWriter, r *http.Request) {
+	if h.Quota == nil {
+		http.Error(w, "Quota limits are not configured", http.StatusNotImplemented)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Quota.Usage())
+}
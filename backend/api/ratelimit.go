@@ -0,0 +1,89 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"banana-weather/internal/clock"
+)
+
+// clientAddr is the IP address of the client of r behind trustedProxies
+// reverse proxies that each append their peer to X-Forwarded-For: the entry
+// the outermost one appended (earlier ones are the client's to forge), e.g.
+// the last one behind Cloud Run's front end alone and the second to last
+// behind an external load balancer too. Without trusted proxies, or without
+// the header, it's the connection's peer.
+func clientAddr(r *http.Request, trustedProxies int) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" && trustedProxies > 0 {
+		parts := strings.Split(xff, ",")
+		if addr := strings.TrimSpace(parts[max(len(parts)-trustedProxies, 0)]); addr != "" {
+			return addr
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientID is a stable pseudonym of the client of r, so its reports can be
+// told apart without storing its address.
+func clientID(r *http.Request, trustedProxies int) string {
+	sum := sha256.Sum256([]byte("client:" + clientAddr(r, trustedProxies)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// RateLimit allows each client (by address, behind trustedProxies, see
+// Handler.TrustedProxies) n requests per window and rejects the rest with
+// 429, for routes anyone can call that write, like abuse reports. Counts are
+// per instance.
+func RateLimit(n int, window time.Duration, trustedProxies int) func(http.Handler) http.Handler {
+	l := &rateLimiter{clock: clock.Real{}, n: n, window: window, trustedProxies: trustedProxies, counts: map[string]int{}}
+	return l.middleware
+}
+
+// rateLimiter counts requests per client in fixed windows.
+type rateLimiter struct {
+	clock          clock.Clock
+	n              int
+	window         time.Duration
+	trustedProxies int
+
+	mu     sync.Mutex
+	start  time.Time      // Of the current window
+	counts map[string]int // Requests per client in the current window
+}
+
+// allow counts a request of client and reports whether it's within the
+// limit, or else how long until the next window.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if now.Sub(l.start) >= l.window {
+		l.start, l.counts = now.Truncate(l.window), map[string]int{}
+	}
+	if l.counts[client] >= l.n {
+		return false, l.start.Add(l.window).Sub(now)
+	}
+	l.counts[client]++
+	return true, 0
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retry := l.allow(clientAddr(r, l.trustedProxies)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestClientAddr(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "10.0.0.7:4321"
+	if got := clientAddr(r, 1); got != "10.0.0.7" {
+		t.Errorf("clientAddr() = %q, want the peer without X-Forwarded-For", got)
+	}
+
+	// Forged entry, client, load balancer
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.9, 130.211.0.1")
+	for _, tc := range []struct {
+		proxies int
+		want    string
+	}{
+		{0, "10.0.0.7"},    // Not behind proxies: the header is the client's
+		{1, "130.211.0.1"}, // Cloud Run's front end alone
+		{2, "203.0.113.9"}, // Behind an external load balancer too
+		{5, "1.2.3.4"},     // Fewer entries than proxies
+	} {
+		if got := clientAddr(r, tc.proxies); got != tc.want {
+			t.Errorf("clientAddr() behind %d proxies = %q, want %q", tc.proxies, got, tc.want)
+		}
+	}
+	if id := clientID(r, 2); id == "" || strings.Contains(id, "203.0.113.9") || id == clientID(r, 1) {
+		t.Errorf("clientID() = %q", id)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC))
+	l := &rateLimiter{clock: fake, n: 2, window: time.Hour, counts: map[string]int{}}
+	h := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+	send := func(addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = addr + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := send("198.51.100.1"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status %d", i+1, rec.Code)
+		}
+	}
+	rec := send("198.51.100.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1801" {
+		t.Errorf("Expected 429 until the next hour, got %d (Retry-After %q)", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("198.51.100.2"); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected other clients unaffected, got %d", rec.Code)
+	}
+	fake.Advance(30 * time.Minute)
+	if rec := send("198.51.100.1"); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected the limit reset in the next window, got %d", rec.Code)
+	}
+}
+
+func TestHandleLocationReport(t *testing.T) {
+	db := mock.NewDB()
+	db.PutLocation(context.Background(), database.Location{ID: "paris", Name: "Paris, France", Status: database.StatusReady})
+	h := &Handler{DB: db, ReportThreshold: 3}
+	r := chi.NewRouter()
+	r.Post("/api/locations/{id}/report", h.HandleLocationReport)
+	forged := 0
+	report := func(addr, reason string) {
+		req := httptest.NewRequest(http.MethodPost, "/api/locations/paris/report", strings.NewReader(`{"reason":"`+reason+`"}`))
+		req.RemoteAddr = addr + ":1234"
+		forged++
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", forged)) // Forged: no trusted proxies
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	}
+
+	// One client counts once, however often it reports and whatever it
+	// claims to forward for
+	for range 5 {
+		report("198.51.100.1", "spam")
+	}
+	if loc, _ := db.GetLocation(context.Background(), "paris"); loc.Reports != 1 || loc.IsHidden() {
+		t.Fatalf("After one client's reports: %d reports, status %q", loc.Reports, loc.Status)
+	}
+
+	report("198.51.100.2", strings.Repeat("é", 600))
+	report("198.51.100.3", "offensive")
+	if loc, _ := db.GetLocation(context.Background(), "paris"); loc.Reports != 3 || !loc.IsHidden() {
+		t.Errorf("After three clients' reports: %d reports, status %q", loc.Reports, loc.Status)
+	}
+}
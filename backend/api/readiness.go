@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"banana-weather/internal/clock"
+)
+
+// Readiness tracks the startup warm-up GET /readyz reports, so Cloud Run's
+// startup probe holds traffic until the presets are in memory.
+type Readiness struct {
+	Timeout time.Duration // After which the warm-up is given up and the server is ready anyway; 0 waits for it
+
+	clock clock.Clock
+
+	mu      sync.Mutex
+	started time.Time
+	report  *WarmupReport // nil while warming
+}
+
+// WarmupReport is the outcome of the startup warm-up.
+type WarmupReport struct {
+	Presets    int    `json:"presets"`
+	SignedURLs int    `json:"signed_urls"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"` // The server still serves, reading presets from the backend
+}
+
+// ReadyResponse is the body of GET /readyz.
+type ReadyResponse struct {
+	Ready     bool          `json:"ready"`
+	WarmingMS int64         `json:"warming_ms,omitempty"` // How long the warm-up has run, while not ready
+	Warmup    *WarmupReport `json:"warmup,omitempty"`
+}
+
+// NewReadiness returns a Readiness that is warming from now on.
+func NewReadiness() *Readiness {
+	return &Readiness{clock: clock.Real{}, started: time.Now()}
+}
+
+// Warm loads presets into cache and records how long it took. A failed
+// warm-up, or one that runs past Timeout (and is then cancelled), is logged
+// and reported, but still makes the server ready: requests then read
+// through the cache as usual.
+func (r *Readiness) Warm(ctx context.Context, cache *PresetCache) {
+	r.mu.Lock()
+	r.started = r.clock.Now()
+	r.mu.Unlock()
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := cache.Warm(ctx)
+		done <- result{n, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res.err = fmt.Errorf("warm-up timed out after %s", r.Timeout)
+	}
+	report := &WarmupReport{Presets: res.n, SignedURLs: cache.SignedURLs()}
+	if res.err != nil {
+		log.Printf("Warning: preset warm-up failed, the first requests read presets from the backend: %v", res.err)
+		report.Error = res.err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report.DurationMS = r.clock.Now().Sub(r.started).Milliseconds()
+	r.report = report
+	log.Printf("Warmed %d presets (%d signed URLs) in %dms", report.Presets, report.SignedURLs, report.DurationMS)
+}
+
+// Status returns the readiness as served by GET /readyz.
+func (r *Readiness) Status() ReadyResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report == nil {
+		return ReadyResponse{WarmingMS: r.clock.Now().Sub(r.started).Milliseconds()}
+	}
+	return ReadyResponse{Ready: true, Warmup: r.report}
+}
+
+// HandleReadyz serves GET /readyz: 200 once startup warm-up is done (with its
+// duration), 503 while it runs. Without a warm-up the server is always ready.
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if h.Ready == nil {
+		writeJSON(w, http.StatusOK, ReadyResponse{Ready: true})
+		return
+	}
+	status := h.Ready.Status()
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, status)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+// slowPresetStore takes a second of fake time per read.
+type slowPresetStore struct {
+	repo.LocationStore
+	clock *clock.Fake
+	err   error
+}
+
+func (s *slowPresetStore) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	s.clock.Advance(time.Second)
+	return []database.PresetSummary{{ID: "paris"}, {ID: "oslo"}}, s.err
+}
+
+func TestHandleReadyz(t *testing.T) {
+	get := func(h *Handler) (int, ReadyResponse) {
+		rec := httptest.NewRecorder()
+		h.HandleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body ReadyResponse
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	if code, body := get(&Handler{}); code != http.StatusOK || !body.Ready {
+		t.Errorf("Expected ready without a warm-up, got %d %+v", code, body)
+	}
+
+	clk := clock.NewFake(time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC))
+	ready := NewReadiness()
+	ready.clock = clk
+	ready.started = clk.Now()
+	h := &Handler{Ready: ready}
+	clk.Advance(1500 * time.Millisecond)
+	if code, body := get(h); code != http.StatusServiceUnavailable || body.Ready || body.WarmingMS != 1500 {
+		t.Errorf("Expected 503 while warming, got %d %+v", code, body)
+	}
+
+	ready.Warm(context.Background(), NewPresetCache(&slowPresetStore{clock: clk}, time.Minute))
+	code, body := get(h)
+	if code != http.StatusOK || !body.Ready || *body.Warmup != (WarmupReport{Presets: 2, DurationMS: 8000}) {
+		t.Errorf("Expected ready with the warm-up's duration, got %d %+v", code, body.Warmup)
+	}
+
+	// A failed warm-up is reported, but doesn't hold traffic back
+	ready.Warm(context.Background(), NewPresetCache(&slowPresetStore{clock: clk, err: errors.New("firestore unavailable")}, time.Minute))
+	if code, body := get(h); code != http.StatusOK || body.Warmup.Error != "firestore unavailable" {
+		t.Errorf("Expected the error reported, got %d %+v", code, body.Warmup)
+	}
+}
+
+// stuckPresetStore never answers until released, whatever its context.
+type stuckPresetStore struct {
+	repo.LocationStore
+	release chan struct{}
+}
+
+func (s *stuckPresetStore) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	<-s.release
+	return nil, ctx.Err()
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	store := &stuckPresetStore{release: make(chan struct{})}
+	defer close(store.release)
+	ready := NewReadiness()
+	ready.Timeout = 20 * time.Millisecond
+
+	// A warm-up stuck past the timeout still makes the server ready
+	ready.Warm(context.Background(), NewPresetCache(store, time.Minute))
+	if status := ready.Status(); !status.Ready || !strings.Contains(status.Warmup.Error, "timed out after 20ms") {
+		t.Errorf("Expected ready with the timeout reported, got %+v", status.Warmup)
+	}
+}
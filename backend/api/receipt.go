@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleLocationReceipt serves GET /api/locations/{id}/receipt: the recipe
+// behind the location's current media (see model.Receipt), to share a great
+// output or reproduce it with `banana generate --from-receipt`.
+func (h *Handler) HandleLocationReceipt(w http.ResponseWriter, r *http.Request) {
+	loc := h.servableLocation(w, r, chi.URLParam(r, "id"))
+	if loc == nil {
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, loc.Receipt())
+}
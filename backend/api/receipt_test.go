@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleLocationReceipt(t *testing.T) {
+	seed := int32(9)
+	db := &fakeWalletDB{locs: map[string]*database.Location{
+		"quito":  {ID: "quito", Name: "Quito", CityQuery: "Quito", ImageURL: "img", Seed: &seed, Generation: &database.GenerationMetadata{Model: "m", Style: "classic"}},
+		"hidden": {ID: "hidden", ImageURL: "img", Status: database.StatusHidden},
+	}}
+	r := chi.NewRouter()
+	r.Get("/api/locations/{id}/receipt", (&Handler{DB: db}).HandleLocationReceipt)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/locations/quito/receipt", nil))
+	var receipt database.Receipt
+	if err := json.NewDecoder(rec.Body).Decode(&receipt); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if receipt.LocationID != "quito" || receipt.City != "Quito" || receipt.Style != "classic" || receipt.ImageModel != "m" || *receipt.Seed != 9 {
+		t.Errorf("Unexpected receipt %+v", receipt)
+	}
+
+	for _, id := range []string{"hidden", "nowhere"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/locations/"+id+"/receipt", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: got %d, want 404", id, rec.Code)
+		}
+	}
+}
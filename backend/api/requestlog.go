@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"banana-weather/internal/progress"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestLogger puts a logger prefixed with the request ID (set by
+// middleware.RequestID, which must run first) in each request's context, so
+// the lines a request logs in deeper layers can be told apart, see
+// progress.Logf.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(progress.WithLogger(r.Context(), progress.NewLogger(id))))
+	})
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/progress"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	h := middleware.RequestID(RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		progress.Logf(r.Context(), "Still polling Veo...")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/api/weather", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), "[req-42] Still polling Veo...") {
+		t.Errorf("Expected the line prefixed with the request ID, got %q", buf.String())
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+type fakeLatencyDB struct {
+	repo.Repository
+	since time.Time
+}
+
+func (f *fakeLatencyDB) ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error) {
+	f.since = since
+	return []database.LatencySample{
+		{Stage: database.LatencyImage, Outcome: database.LatencyOK, DurationMS: 9000},
+		{Stage: database.LatencyCache, Outcome: database.LatencyHit},
+	}, nil
+}
+
+func TestHandleAdminSLO(t *testing.T) {
+	db := &fakeLatencyDB{}
+	h := &Handler{DB: db}
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleAdminSLO(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	if rec := get("/api/admin/slo?window=soon"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid window, got %d", rec.Code)
+	}
+
+	rec := get("/api/admin/slo?window=1d")
+	var report database.SLOReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if age := time.Since(db.since); age < 23*time.Hour || age > 25*time.Hour {
+		t.Errorf("Expected a one day window, got %v", age)
+	}
+	if report.CacheHitRate != 1 || report.Stages[0].P50 != 9 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+// streamKeepAlive is how often an idle preset stream sends an SSE comment, so
+// proxies and load balancers don't close the connection.
+const streamKeepAlive = 30 * time.Second
+
+// HandlePresetStream serves GET /api/presets/stream: an SSE connection that
+// sends every visible preset as an "added" event, a "ready" event, and then
+// "added", "modified" and "removed" events as presets change. Each event's
+// data is a database.PresetSummary (removed events only need the id).
+// ?lang= localizes names and narration, and media URLs are signed (see
+// PresetCache.SignURLs), as in GET /api/presets.
+func (h *Handler) HandlePresetStream(w http.ResponseWriter, r *http.Request) {
+	watcher, ok := h.DB.(repo.PresetWatcher)
+	if !ok {
+		http.Error(w, "Preset streaming requires the Firestore backend", http.StatusNotImplemented)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	flusher.Flush()
+
+	// The listener runs in its own goroutine; writes stay on this one
+	ctx := r.Context()
+	updates := make(chan []database.PresetChange)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.WatchPresets(ctx, func(changes []database.PresetChange) error {
+			select {
+			case updates <- changes:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	lang := r.URL.Query().Get("lang")
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+	ready := false
+	for {
+		select {
+		case changes := <-updates:
+			if h.Presets != nil {
+				changes = h.Presets.signChanges(ctx, changes) // With PRESETS_SIGN_URLS, as GET /api/presets
+			}
+			for _, c := range changes {
+				if lang != "" {
+					c.Preset.Name = c.Preset.LocalizedName(lang)
+					c.Preset.AudioURL = c.Preset.LocalizedAudioURL(lang)
+				}
+				data, _ := json.Marshal(c.Preset)
+				writeSSE(w, c.Type, string(data))
+			}
+			if !ready {
+				writeSSE(w, "ready", "{}")
+				ready = true
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		case err := <-done:
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Preset stream ended: %v", err)
+				writeSSE(w, "error", "Preset stream interrupted")
+				flusher.Flush()
+			}
+			return
+		}
+	}
+}
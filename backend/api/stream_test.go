@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+)
+
+// fakeWatcher replays batches of changes. Only WatchPresets is implemented.
+type fakeWatcher struct {
+	repo.Repository
+	batches [][]database.PresetChange
+}
+
+func (f *fakeWatcher) WatchPresets(ctx context.Context, fn func([]database.PresetChange) error) error {
+	for _, b := range f.batches {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestHandlePresetStream(t *testing.T) {
+	paris := database.PresetSummary{ID: "paris", Name: "Paris", NameI18n: map[string]string{"ja": "パリ"}, Category: "Europe",
+		AudioURL: "https://example.com/paris_en.wav", AudioI18n: map[string]string{"ja": "https://example.com/paris_ja.wav"}}
+	h := &Handler{DB: &fakeWatcher{batches: [][]database.PresetChange{
+		{{Type: database.PresetAdded, Preset: paris}},
+		{{Type: database.PresetRemoved, Preset: database.PresetSummary{ID: "paris"}}},
+	}}}
+
+	rec := httptest.NewRecorder()
+	h.HandlePresetStream(rec, httptest.NewRequest(http.MethodGet, "/api/presets/stream?lang=ja", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"event: added\ndata: {\"id\":\"paris\",\"name\":\"パリ\"",
+		`"audio_url":"https://example.com/paris_ja.wav"`,
+		"event: ready\ndata: {}\n\n",
+		"event: removed\ndata: {\"id\":\"paris\"",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in stream:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "event: ready") > strings.Index(body, "event: removed") {
+		t.Error("ready must follow the initial snapshot")
+	}
+}
+
+func TestHandlePresetStream_SignedURLs(t *testing.T) {
+	paris := database.PresetSummary{ID: "paris", ImageURL: "https://storage.googleapis.com/private/paris.png"}
+	h := &Handler{
+		DB:      &fakeWatcher{batches: [][]database.PresetChange{{{Type: database.PresetAdded, Preset: paris}}}},
+		Presets: NewPresetCache(&presetListStore{}, time.Minute),
+	}
+	h.Presets.SignURLs(map[storage.Kind]storage.Store{storage.KindImage: &signingMediaStore{}}, time.Hour)
+
+	rec := httptest.NewRecorder()
+	h.HandlePresetStream(rec, httptest.NewRequest(http.MethodGet, "/api/presets/stream", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"image_url":"https://signed.test/paris.png?sig=1\u0026ttl=1h0m0s"`) {
+		t.Errorf("Expected the streamed preset's URL signed:\n%s", body)
+	}
+}
+
+func TestHandlePresetStream_Unsupported(t *testing.T) {
+	h := &Handler{DB: struct{ repo.Repository }{}}
+	rec := httptest.NewRecorder()
+	h.HandlePresetStream(rec, httptest.NewRequest(http.MethodGet, "/api/presets/stream", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	randv2 "math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startFlow gives the weather flow of a request its ID, announced in the
+// X-Flow-ID header (and by the flow's first "flow" event), so a report can
+// name it. The returned context carries the ID for the flow's logs and
+// generation metadata, and, when sampled, its trace.
+func startFlow(ctx context.Context, w http.ResponseWriter) context.Context {
+	id := progress.NewFlowID()
+	w.Header().Set("X-Flow-ID", id)
+	return progress.WithFlowID(ctx, id)
+}
+
+// startTrace samples the weather flow of ctx (see startFlow) for
+// flow_traces, TraceSample of them, under the flow's ID; nil when not
+// sampled.
+func (h *Handler) startTrace(ctx context.Context, city, lat, lng, ref string) *database.FlowTrace {
+	if h.TraceSample <= 0 || randv2.Float64() >= h.TraceSample {
+		return nil
+	}
+	t := &database.FlowTrace{
+		ID:        progress.FlowID(ctx),
+		Query:     city,
+		Lat:       lat,
+		Lng:       lng,
+		Reference: ref,
+		StartedAt: time.Now(),
+	}
+	progress.Logf(ctx, "Tracing weather flow")
+	return t
+}
+
+// saveTrace stores a finished flow's trace. It runs after the client may have
+// gone, so it doesn't use the request's cancellation.
+func (h *Handler) saveTrace(ctx context.Context, t *database.FlowTrace, flowErr error) {
+	if t == nil {
+		return
+	}
+	t.FinishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		t.Error = "client disconnected"
+	case flowErr != nil:
+		t.Error = flowErr.Error()
+	}
+	if err := h.DB.SaveFlowTrace(context.WithoutCancel(ctx), *t); err != nil {
+		progress.Logf(ctx, "Failed to save flow trace %s: %v", t.ID, err)
+	}
+}
+
+// HandleAdminFlowTrace returns a recorded flow trace.
+func (h *Handler) HandleAdminFlowTrace(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	t, err := h.DB.GetFlowTrace(r.Context(), id)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "Flow trace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read flow trace: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// HandleAdminListFlowTraces lists the latest flow traces (?limit=, default
+// 20), without their events.
+func (h *Handler) HandleAdminListFlowTraces(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	traces, err := h.DB.ListFlowTraces(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Failed to list flow traces: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range traces {
+		traces[i].Events = nil
+	}
+	if traces == nil {
+		traces = []database.FlowTrace{}
+	}
+	writeJSON(w, http.StatusOK, traces)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTraceDB struct {
+	repo.Repository
+	traces map[string]database.FlowTrace
+}
+
+func (f *fakeTraceDB) SaveFlowTrace(ctx context.Context, t database.FlowTrace) error {
+	f.traces[t.ID] = t
+	return nil
+}
+
+func (f *fakeTraceDB) GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error) {
+	t, ok := f.traces[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "flow trace %s not found", id)
+	}
+	return &t, nil
+}
+
+func TestFlowTraceRoundTrip(t *testing.T) {
+	db := &fakeTraceDB{traces: map[string]database.FlowTrace{}}
+	h := &Handler{DB: db, TraceSample: 1}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/weather?city=Paris", nil)
+	ctx := startFlow(req.Context(), rec)
+	tr := h.startTrace(ctx, "Paris", "", "", "")
+	if tr == nil || tr.ID == "" || rec.Header().Get("X-Flow-ID") != tr.ID || progress.FlowID(ctx) != tr.ID {
+		t.Fatalf("Expected the flow traced under the ID announced in X-Flow-ID, got %v", tr)
+	}
+	tr.Add("status", "Identifying location...", tr.StartedAt)
+	h.saveTrace(req.Context(), tr, nil)
+
+	r := chi.NewRouter()
+	r.Get("/traces/{id}", h.HandleAdminFlowTrace)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	if rec := get("/traces/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown flow, got %d", rec.Code)
+	}
+	rec = get("/traces/" + tr.ID)
+	var got database.FlowTrace
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d (%v)", rec.Code, err)
+	}
+	if got.Query != "Paris" || len(got.Events) != 1 || got.Error != "" || got.FinishedAt.IsZero() {
+		t.Errorf("Unexpected trace %+v", got)
+	}
+}
+
+func TestStartTraceUnsampled(t *testing.T) {
+	h := &Handler{}
+	if tr := h.startTrace(progress.WithFlowID(context.Background(), "f00d"), "Paris", "", "", ""); tr != nil {
+		t.Errorf("Expected no trace without TraceSample, got %+v", tr)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"banana-weather/internal/storage"
+	"banana-weather/internal/weather"
+)
+
+// uploadURLExpiry is how long a signed upload URL stays valid.
+const uploadURLExpiry = 15 * time.Minute
+
+// UploadSigner issues direct-to-bucket upload URLs (storage.Store).
+type UploadSigner interface {
+	SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*storage.SignedUpload, error)
+}
+
+// UploadRequest is the body accepted by POST /api/uploads.
+type UploadRequest struct {
+	ContentType string `json:"content_type"` // One of weather.ReferenceTypes
+}
+
+// UploadResponse is returned by POST /api/uploads. After uploading, the
+// client passes Object as ?reference= to GET /api/weather.
+type UploadResponse struct {
+	Object string `json:"object"`
+	*storage.SignedUpload
+}
+
+var uploadExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// HandleCreateUpload returns a signed URL for uploading one reference photo
+// to the private uploads bucket.
+func (h *Handler) HandleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	if h.Uploads == nil {
+		http.Error(w, "Reference uploads are not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	var req UploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(weather.ReferenceTypes, req.ContentType) {
+		http.Error(w, "content_type must be image/jpeg, image/png or image/webp", http.StatusBadRequest)
+		return
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	object := weather.ReferencePrefix + hex.EncodeToString(id) + uploadExtensions[req.ContentType]
+
+	upload, err := h.Uploads.SignedUploadURL(r.Context(), object, req.ContentType, weather.MaxReferenceBytes, uploadURLExpiry)
+	if err != nil {
+		log.Printf("Failed to sign upload: %v", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, UploadResponse{Object: object, SignedUpload: upload})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/storage"
+)
+
+type fakeSigner struct {
+	object string
+}
+
+func (f *fakeSigner) SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*storage.SignedUpload, error) {
+	f.object = fileName
+	return &storage.SignedUpload{URL: "https://bucket/" + fileName, Method: http.MethodPut}, nil
+}
+
+func TestHandleCreateUpload(t *testing.T) {
+	post := func(h *Handler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleCreateUpload(rec, httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(&Handler{}, `{"content_type":"image/jpeg"}`); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without an uploads bucket, got %d", rec.Code)
+	}
+
+	signer := &fakeSigner{}
+	h := &Handler{Uploads: signer}
+	if rec := post(h, `{"content_type":"image/gif"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for image/gif, got %d", rec.Code)
+	}
+
+	rec := post(h, `{"content_type":"image/jpeg"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp UploadResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if !strings.HasPrefix(resp.Object, "uploads/") || !strings.HasSuffix(resp.Object, ".jpg") || resp.Object != signer.object {
+		t.Errorf("Unexpected object %q (signed %q)", resp.Object, signer.object)
+	}
+	if resp.SignedUpload == nil || resp.URL == "" || resp.Method != http.MethodPut {
+		t.Errorf("Expected the signed upload in the response, got %+v", resp.SignedUpload)
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"banana-weather/internal/database"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PassSerials is returned by the PassKit "updatable passes" endpoint.
+type PassSerials struct {
+	SerialNumbers []string `json:"serialNumbers"`
+	LastUpdated   string   `json:"lastUpdated"` // Unix seconds, echoed back as passesUpdatedSince
+}
+
+// servableLocation loads a visible location with media, for passes and
+// playback, writing the error response and returning nil otherwise.
+func (h *Handler) servableLocation(w http.ResponseWriter, r *http.Request, id string) *database.Location {
+	loc, err := h.DB.GetLocation(r.Context(), id)
+	if status.Code(err) == codes.NotFound || (err == nil && (loc.IsHidden() || loc.ImageURL == "")) {
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to load %s: %v", id, err)
+		http.Error(w, "Failed to fetch location", http.StatusInternalServerError)
+		return nil
+	}
+	return loc
+}
+
+// writePass sends the .pkpass of loc.
+func (h *Handler) writePass(w http.ResponseWriter, r *http.Request, loc *database.Location) {
+	b, err := h.Wallet.ApplePass(r.Context(), loc)
+	if err != nil {
+		log.Printf("Failed to build pass for %s: %v", loc.ID, err)
+		http.Error(w, "Failed to build pass", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.pkpass")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+loc.ID+`.pkpass"`)
+	w.Header().Set("Last-Modified", loc.LastUpdated.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b)
+}
+
+// HandleApplePass serves GET /api/locations/{id}/pass.pkpass.
+func (h *Handler) HandleApplePass(w http.ResponseWriter, r *http.Request) {
+	if h.Wallet == nil || h.Wallet.Apple == nil {
+		http.Error(w, "Apple Wallet passes are not enabled", http.StatusNotImplemented)
+		return
+	}
+	if loc := h.servableLocation(w, r, chi.URLParam(r, "id")); loc != nil {
+		h.writePass(w, r, loc)
+	}
+}
+
+// HandleGoogleWalletPass serves GET /api/locations/{id}/wallet/google by
+// redirecting to the "Add to Google Wallet" page.
+func (h *Handler) HandleGoogleWalletPass(w http.ResponseWriter, r *http.Request) {
+	if h.Wallet == nil || h.Wallet.Google == nil {
+		http.Error(w, "Google Wallet passes are not enabled", http.StatusNotImplemented)
+		return
+	}
+	loc := h.servableLocation(w, r, chi.URLParam(r, "id"))
+	if loc == nil {
+		return
+	}
+	url, err := h.Wallet.GoogleSaveURL(r.Context(), loc)
+	if err != nil {
+		log.Printf("Failed to sign Google Wallet pass for %s: %v", loc.ID, err)
+		http.Error(w, "Failed to build pass", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// The PassKit web service (/api/wallet/v1/...) lets devices holding a pass
+// register for updates and fetch the latest version. Apple defines the
+// paths, status codes and JSON.
+
+// passKit checks that Apple Wallet is on and the pass type is ours.
+func (h *Handler) passKit(w http.ResponseWriter, r *http.Request) bool {
+	if h.Wallet == nil || h.Wallet.Apple == nil || chi.URLParam(r, "passType") != h.Wallet.Apple.PassTypeID {
+		http.NotFound(w, r)
+		return false
+	}
+	return true
+}
+
+// passAuth additionally checks the pass's authentication token.
+func (h *Handler) passAuth(w http.ResponseWriter, r *http.Request) bool {
+	if !h.passKit(w, r) {
+		return false
+	}
+	if !h.Wallet.Apple.CheckAuth(r.Header.Get("Authorization"), chi.URLParam(r, "serial")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// HandlePassRegister registers a device for updates to a pass.
+func (h *Handler) HandlePassRegister(w http.ResponseWriter, r *http.Request) {
+	if !h.passAuth(w, r) {
+		return
+	}
+	var req struct {
+		PushToken string `json:"pushToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PushToken == "" {
+		http.Error(w, "pushToken is required", http.StatusBadRequest)
+		return
+	}
+	created, err := h.DB.RegisterPass(r.Context(), database.PassRegistration{
+		DeviceID:  chi.URLParam(r, "device"),
+		Serial:    chi.URLParam(r, "serial"),
+		PushToken: req.PushToken,
+	})
+	if err != nil {
+		log.Printf("Failed to register pass: %v", err)
+		http.Error(w, "Failed to register", http.StatusInternalServerError)
+		return
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// HandlePassUnregister stops updates to a pass on a device.
+func (h *Handler) HandlePassUnregister(w http.ResponseWriter, r *http.Request) {
+	if !h.passAuth(w, r) {
+		return
+	}
+	if err := h.DB.UnregisterPass(r.Context(), chi.URLParam(r, "device"), chi.URLParam(r, "serial")); err != nil {
+		log.Printf("Failed to unregister pass: %v", err)
+		http.Error(w, "Failed to unregister", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandlePassSerials lists the passes on a device whose art changed since
+// ?passesUpdatedSince (a previous lastUpdated), or 204 when none did.
+func (h *Handler) HandlePassSerials(w http.ResponseWriter, r *http.Request) {
+	if !h.passKit(w, r) {
+		return
+	}
+	var since int64
+	if s := r.URL.Query().Get("passesUpdatedSince"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil {
+			http.Error(w, "Invalid passesUpdatedSince", http.StatusBadRequest)
+			return
+		}
+	}
+	regs, err := h.DB.ListPassRegistrations(r.Context(), "device_id", chi.URLParam(r, "device"))
+	if err != nil {
+		log.Printf("Failed to list pass registrations: %v", err)
+		http.Error(w, "Failed to list passes", http.StatusInternalServerError)
+		return
+	}
+
+	resp := PassSerials{SerialNumbers: []string{}}
+	var latest int64
+	for _, reg := range regs {
+		loc, err := h.DB.GetLocation(r.Context(), reg.Serial)
+		if err != nil {
+			continue // Deleted since; the pass keeps its last version
+		}
+		if updated := loc.LastUpdated.Unix(); updated > since {
+			resp.SerialNumbers = append(resp.SerialNumbers, reg.Serial)
+			latest = max(latest, updated)
+		}
+	}
+	if len(resp.SerialNumbers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp.LastUpdated = strconv.FormatInt(latest, 10)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandlePassLatest serves the current version of a pass, or 304 when it
+// hasn't changed since If-Modified-Since.
+func (h *Handler) HandlePassLatest(w http.ResponseWriter, r *http.Request) {
+	if !h.passAuth(w, r) {
+		return
+	}
+	loc := h.servableLocation(w, r, chi.URLParam(r, "serial"))
+	if loc == nil {
+		return
+	}
+	if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !loc.LastUpdated.Truncate(time.Second).After(t) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.writePass(w, r, loc)
+}
+
+// HandlePassLog records errors devices report about passes.
+func (h *Handler) HandlePassLog(w http.ResponseWriter, r *http.Request) {
+	if h.Wallet == nil || h.Wallet.Apple == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Logs []string `json:"logs"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, l := range req.Logs {
+		log.Printf("Wallet device log: %s", l)
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/wallet"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeWalletDB struct {
+	repo.Repository
+	locs map[string]*database.Location
+	regs []database.PassRegistration
+}
+
+func (f *fakeWalletDB) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	if loc, ok := f.locs[id]; ok {
+		return loc, nil
+	}
+	return nil, status.Error(codes.NotFound, "not found")
+}
+func (f *fakeWalletDB) RegisterPass(ctx context.Context, r database.PassRegistration) (bool, error) {
+	for i, reg := range f.regs {
+		if reg.DeviceID == r.DeviceID && reg.Serial == r.Serial {
+			f.regs[i].PushToken = r.PushToken
+			return false, nil
+		}
+	}
+	f.regs = append(f.regs, r)
+	return true, nil
+}
+func (f *fakeWalletDB) ListPassRegistrations(ctx context.Context, field, value string) ([]database.PassRegistration, error) {
+	var out []database.PassRegistration
+	for _, r := range f.regs {
+		if (field == "device_id" && r.DeviceID == value) || (field == "serial" && r.Serial == value) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// testWallet writes a throwaway pass certificate and opens Apple Wallet with it.
+func testWallet(t *testing.T) *wallet.Service {
+	t.Helper()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "pass.test"}, NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)
+
+	apple, err := wallet.NewApple(config.WalletConfig{
+		BaseURL:         "https://example.com",
+		ApplePassTypeID: "pass.test",
+		AppleTeamID:     "TEAM123",
+		AppleCert:       certPath,
+		AppleKey:        keyPath,
+		AppleWWDRCert:   certPath,
+		AuthSecret:      "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wallet.Service{Apple: apple}
+}
+
+func TestPassKitWebService(t *testing.T) {
+	updated := time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)
+	db := &fakeWalletDB{locs: map[string]*database.Location{
+		"paris": {ID: "paris", Name: "Paris", ImageURL: "https://example.com/paris.png", LastUpdated: updated},
+	}}
+	art := httptest.NewServer(http.NotFoundHandler())
+	defer art.Close()
+	db.locs["paris"].ImageURL = art.URL + "/paris.png"
+	h := &Handler{DB: db, Wallet: testWallet(t)}
+	h.Wallet.DB, h.Wallet.HTTP = db, art.Client()
+	r := chi.NewRouter()
+	r.Post("/v1/devices/{device}/registrations/{passType}/{serial}", h.HandlePassRegister)
+	r.Get("/v1/devices/{device}/registrations/{passType}", h.HandlePassSerials)
+	r.Get("/v1/passes/{passType}/{serial}", h.HandlePassLatest)
+	auth := "ApplePass " + h.Wallet.Apple.AuthToken("paris")
+
+	do := func(method, path, authz, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	register := "/v1/devices/dev1/registrations/pass.test/paris"
+	if rec := do(http.MethodPost, register, "ApplePass wrong", `{"pushToken":"tok"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/devices/dev1/registrations/pass.other/paris", auth, `{"pushToken":"tok"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another pass type, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, register, auth, `{"pushToken":"tok"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected 201 for a new registration, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, register, auth, `{"pushToken":"tok2"}`); rec.Code != http.StatusOK || db.regs[0].PushToken != "tok2" {
+		t.Errorf("Expected 200 and an updated token, got %d %+v", rec.Code, db.regs)
+	}
+
+	rec := do(http.MethodGet, "/v1/devices/dev1/registrations/pass.test?passesUpdatedSince=0", "", "")
+	var serials PassSerials
+	if err := json.NewDecoder(rec.Body).Decode(&serials); err != nil || len(serials.SerialNumbers) != 1 || serials.LastUpdated != "1792044000" {
+		t.Errorf("Unexpected serials %d %+v (%v)", rec.Code, serials, err)
+	}
+	if rec := do(http.MethodGet, "/v1/devices/dev1/registrations/pass.test?passesUpdatedSince="+serials.LastUpdated, "", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 with nothing newer, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodGet, "/v1/passes/pass.test/paris", auth, "", "If-Modified-Since", updated.Format(http.TimeFormat)); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged pass, got %d", rec.Code)
+	}
+	// The art is gone, so the pass falls back to a plain thumbnail
+	rec = do(http.MethodGet, "/v1/passes/pass.test/paris", auth, "", "If-Modified-Since", updated.Add(-time.Hour).Format(http.TimeFormat))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/vnd.apple.pkpass" || rec.Body.Len() == 0 {
+		t.Errorf("Expected the updated pass, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"runtime"
+	"slices"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	"banana-weather/pkg/config"
-	"banana-weather/pkg/database"
-	"banana-weather/pkg/genai"
-	"banana-weather/pkg/storage"
+	"banana-weather/internal/config"
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/push"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+	"banana-weather/internal/wallet"
+	"banana-weather/internal/weather"
 
 	"github.com/spf13/cobra"
 )
@@ -23,106 +34,653 @@ var adminCmd = &cobra.Command{
 	Long:  "Commands for managing the database, presets, and media.",
 }
 
+// adminBackend is implemented by both the local (direct GCP) and remote
+// (admin HTTP API) modes so commands don't care which one they talk to.
+type adminBackend interface {
+	GetStats(ctx context.Context) (*database.Stats, error)
+	ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error)
+	RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error)
+	DeleteLocation(ctx context.Context, id string) error
+	SetVideoPrompt(ctx context.Context, id, prompt string) error
+	SetPromptContext(ctx context.Context, id, promptContext string) error
+	RunCityOfTheDay(ctx context.Context, id string) (*database.FeaturedCity, error)
+	Compare(ctx context.Context, req weather.CompareRequest) (*weather.Comparison, error)
+	ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error)
+	ApproveCandidate(ctx context.Context, locationID, candidateID string) (*database.Location, error)
+	RejectCandidate(ctx context.Context, locationID, candidateID string) error
+	SLOReport(ctx context.Context, window time.Duration) (*database.SLOReport, error)
+	EvaluateAlerts(ctx context.Context) (*database.AlertReport, error)
+	GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error)
+	ListFlowTraces(ctx context.Context, limit int) ([]database.FlowTrace, error)
+}
+
+// localAdmin talks to the repository directly and builds the media services on demand.
+type localAdmin struct {
+	repo.Repository
+	cfg *config.Config
+}
+
+// ListLocations adds usage summaries, priced like the server's (COST_RATES).
+func (l *localAdmin) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	rates, err := costs.Open(l.cfg)
+	if err != nil {
+		return nil, err
+	}
+	locs, err := l.Repository.ListLocations(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range locs {
+		locs[i].Usage = rates.Summarize(locs[i].Generation)
+	}
+	return locs, nil
+}
+
+func (l *localAdmin) RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error) {
+	svc, err := l.weatherService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := svc.RefreshLocation(ctx, id, opts)
+	if err != nil || loc.Unchanged || loc.Staged != "" {
+		return loc, err
+	}
+	l.refreshed(ctx, loc)
+	return loc, nil
+}
+
+// refreshed notifies followers and wallet passes of new media, like the
+// server does.
+func (l *localAdmin) refreshed(ctx context.Context, loc *database.Location) {
+	if err := l.fanout(ctx).Refreshed(ctx, loc); err != nil {
+		log.Printf("Failed to notify followers of %s: %v", loc.ID, err)
+	}
+	if passes, err := wallet.Open(ctx, l.cfg, l.Repository); err != nil {
+		log.Printf("Warning: wallet passes not updated: %v", err)
+	} else if err := passes.Refreshed(ctx, loc); err != nil {
+		log.Printf("Failed to update wallet passes of %s: %v", loc.ID, err)
+	}
+}
+
+func (l *localAdmin) RunCityOfTheDay(ctx context.Context, id string) (*database.FeaturedCity, error) {
+	svc, err := l.weatherService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job := &jobs.CityOfTheDay{DB: l.Repository, Refresher: svc, AvoidDays: l.cfg.CityOfTheDayDays, Push: l.fanout(ctx)}
+	return job.Run(ctx, id)
+}
+
+// fanout returns the push notification fan-out, or nil when PUSH_NOTIFICATIONS
+// is off or FCM is unavailable.
+func (l *localAdmin) fanout(ctx context.Context) *jobs.Fanout {
+	fcm, err := push.Open(ctx, l.cfg)
+	if err != nil {
+		log.Printf("Warning: FCM unavailable, push notifications disabled: %v", err)
+		return nil
+	}
+	if fcm == nil {
+		return nil
+	}
+	return &jobs.Fanout{Sender: fcm}
+}
+
+// weatherService builds the generation services, configured like the server's.
+func (l *localAdmin) weatherService(ctx context.Context) (*weather.Service, error) {
+	genaiService, err := genai.NewService(ctx, l.cfg.ProjectID, l.cfg.Location, l.cfg.BucketName, l.cfg.GeminiImageModel)
+	if err != nil { return nil, fmt.Errorf("GenAI init failed: %w", err) }
+	storageService, err := storage.Open(ctx, l.cfg)
+	if err != nil { return nil, fmt.Errorf("Storage init failed: %w", err) }
+	configureGenAI(ctx, l.cfg, genaiService, l.Repository, storageService)
+
+	svc := weather.NewService(nil, weather.WithChaos(genaiService, l.cfg.ChaosImageFail, l.cfg.ChaosVeoDelay), storageService, l.Repository)
+	configureWeatherCheck(l.cfg, svc, genaiService)
+	svc.Provenance = l.cfg.Signer()
+	svc.Hooks = openHooks(l.cfg)
+	svc.Events = openEvents(ctx, l.cfg)
+	svc.DefaultCity = l.cfg.DefaultCity
+	svc.Candidates = l.Repository
+	svc.Runtime, svc.Tenant = l.Repository, l.cfg.TenantID
+	if m := openMaps(l.cfg); m != nil {
+		svc.Maps = m
+		if l.cfg.MapFallback {
+			svc.StaticMaps = m
+		}
+	}
+	if orig := openOriginals(ctx, l.cfg); orig != nil {
+		svc.Originals = orig
+	}
+	if posters := openPosters(l.cfg); posters != nil {
+		svc.Posters = posters
+	}
+	if streams := openStreams(ctx, l.cfg); streams != nil {
+		svc.Streams = streams
+	}
+	if l.cfg.AltText {
+		svc.Describer = genaiService
+	}
+	if l.cfg.Narration {
+		svc.Narrator, svc.Audio, svc.NarrationLangs = genaiService, storageService, l.cfg.NarrationLangs
+	}
+	return svc, nil
+}
+
+// openAdminBackend returns the remote client when --remote is set, otherwise a
+// local backend. The returned func releases any held clients.
+func openAdminBackend(ctx context.Context, cmd *cobra.Command) (adminBackend, func()) {
+	if rc := remoteFromFlags(cmd); rc != nil {
+		return rc, func() {}
+	}
+
+	cfg, _ := config.Load()
+	if cfg == nil { log.Fatal("Config load failed") }
+
+	db, err := repo.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to init DB: %v", err)
+	}
+	return &localAdmin{Repository: db, cfg: cfg}, func() { db.Close() }
+}
+
 var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show database statistics",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		output, _ := cmd.Flags().GetString("output")
+		runStats(ctx, backend, output)
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List locations",
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		filterType, _ := cmd.Flags().GetString("type")
+		sortBy, _ := cmd.Flags().GetString("sort")
+		status, _ := cmd.Flags().GetString("status")
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		output, _ := cmd.Flags().GetString("output")
+		runList(ctx, backend, database.ListOptions{
+			Limit:  limit,
+			Type:   filterType,
+			Sort:   sortBy,
+			Status: database.LocationStatus(status),
+		}, output)
+	},
+}
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Refresh a location's media",
+	Long: `Regenerate a location's media. The new image of a preset is staged as a pending candidate
+instead of replacing the live media; review it with "banana admin candidates" and approve it
+to swap it in, or pass --auto-approve to replace the media right away. Video-only refreshes
+are never staged.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		style, _ := cmd.Flags().GetInt("style")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+		var seed *int32
+		if cmd.Flags().Changed("seed") {
+			v, _ := cmd.Flags().GetInt32("seed")
+			seed = &v
+		}
+		imageOnly, _ := cmd.Flags().GetBool("image-only")
+		videoOnly, _ := cmd.Flags().GetBool("video-only")
+		if imageOnly && videoOnly {
+			log.Fatal("use only one of --image-only or --video-only")
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		priority, _ := cmd.Flags().GetString("priority")
+		grounding, _ := cmd.Flags().GetString("grounding")
+		overrideLock, _ := cmd.Flags().GetBool("override-lock")
+		overrideHidden, _ := cmd.Flags().GetBool("override-hidden")
+		skipUnchanged, _ := cmd.Flags().GetBool("skip-unchanged")
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+		opts := weather.RefreshOptions{Style: style, Seed: seed, ImageOnly: imageOnly, VideoOnly: videoOnly, Priority: priority, Grounding: grounding, OverrideLock: overrideLock, OverrideHidden: overrideHidden, SkipUnchanged: skipUnchanged}
+		opts.RequireApproval = !autoApprove && !videoOnly
+		loc, err := backend.RefreshLocation(ctx, id, opts)
+		if err != nil {
+			log.Fatalf("Refresh failed: %v", err)
+		}
+		if loc.Unchanged {
+			log.Println("Weather unchanged; kept the current media.")
+			return
+		}
+		if loc.Staged != "" {
+			log.Printf("Preset image staged as candidate %s; the live media is unchanged.", loc.Staged)
+			log.Printf("Review it, then: banana admin candidates --id %s --approve %s", id, loc.Staged)
+			return
+		}
+		log.Println("Refresh Complete.")
+	},
+}
+
+var brandingCmd = &cobra.Command{
+	Use:   "branding",
+	Short: "Show or update branding settings",
+	Long:  "Show the branding settings doc, or update it when any setting flag is given.",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
 		cfg, _ := config.Load()
 		if cfg == nil { log.Fatal("Config load failed") }
 
-		db, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		tenant := cfg.TenantID
+		if cmd.Flags().Changed("tenant") {
+			tenant, _ = cmd.Flags().GetString("tenant")
+		}
+
+		db, err := repo.Open(ctx, cfg)
 		if err != nil {
 			log.Fatalf("Failed to init DB: %v", err)
 		}
 		defer db.Close()
-		runStats(ctx, db)
+
+		b, err := db.GetBranding(ctx, tenant)
+		if err != nil {
+			b = &database.Branding{}
+		}
+
+		changed := false
+		if cmd.Flags().Changed("palette") {
+			b.Palette, _ = cmd.Flags().GetStringSlice("palette")
+			changed = true
+		}
+		if cmd.Flags().Changed("prompt-suffix") {
+			b.PromptSuffix, _ = cmd.Flags().GetString("prompt-suffix")
+			changed = true
+		}
+		if cmd.Flags().Changed("watermark-url") {
+			b.WatermarkURL, _ = cmd.Flags().GetString("watermark-url")
+			changed = true
+		}
+		if cmd.Flags().Changed("watermark-scale") {
+			b.WatermarkScale, _ = cmd.Flags().GetFloat64("watermark-scale")
+			changed = true
+		}
+		if cmd.Flags().Changed("watermark-opacity") {
+			b.WatermarkOpacity, _ = cmd.Flags().GetFloat64("watermark-opacity")
+			changed = true
+		}
+		if cmd.Flags().Changed("ai-badge") {
+			b.AIBadge, _ = cmd.Flags().GetBool("ai-badge")
+			changed = true
+		}
+
+		if changed {
+			if err := db.SetBranding(ctx, tenant, *b); err != nil {
+				log.Fatalf("Failed to save branding: %v", err)
+			}
+			log.Printf("Branding updated (tenant: %q).", tenant)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, b, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Setting\tValue")
+			fmt.Fprintln(w, "-------\t-----")
+			fmt.Fprintf(w, "Palette\t%s\n", strings.Join(b.Palette, ", "))
+			fmt.Fprintf(w, "Prompt Suffix\t%s\n", b.PromptSuffix)
+			fmt.Fprintf(w, "Watermark URL\t%s\n", b.WatermarkURL)
+			fmt.Fprintf(w, "Watermark Scale\t%.2f\n", b.WatermarkScale)
+			fmt.Fprintf(w, "Watermark Opacity\t%.2f\n", b.WatermarkOpacity)
+			fmt.Fprintf(w, "AI Badge\t%t\n", b.AIBadge)
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
-var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List locations",
+var categoriesCmd = &cobra.Command{
+	Use:   "categories",
+	Short: "Show or set the gallery category order and covers",
+	Long: `Show the category display order used by GET /api/presets, or replace it with --order. Categories not listed sort last, alphabetically.
+With --covers, generate each category's cover art (a collage of its presets' cities, served by GET /api/categories),
+or only that of the --category ones.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		limit, _ := cmd.Flags().GetInt("limit")
-		filterType, _ := cmd.Flags().GetString("type")
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil { log.Fatal("Config load failed") }
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		if cmd.Flags().Changed("order") {
+			names, _ := cmd.Flags().GetStringSlice("order")
+			existing, err := db.ListCategories(ctx)
+			if err != nil {
+				log.Fatalf("Failed to list categories: %v", err)
+			}
+			categories := make([]database.Category, len(names))
+			for i, n := range names {
+				categories[i] = database.Category{Name: strings.TrimSpace(n)}
+				// Reordering keeps the covers
+				for _, c := range existing {
+					if c.Name == categories[i].Name {
+						categories[i] = c
+					}
+				}
+				categories[i].Order = i
+			}
+			if err := db.SetCategories(ctx, categories); err != nil {
+				log.Fatalf("Failed to save categories: %v", err)
+			}
+			log.Printf("Category order updated (%d categories).", len(categories))
+		}
+		if covers, _ := cmd.Flags().GetBool("covers"); covers {
+			names, _ := cmd.Flags().GetStringSlice("category")
+			genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+			if err != nil { log.Fatalf("GenAI init failed: %v", err) }
+			images, err := storage.OpenKind(ctx, cfg, storage.KindImage)
+			if err != nil { log.Fatalf("Storage init failed: %v", err) }
+			configureGenAI(ctx, cfg, genaiService, db, images)
+
+			job := &jobs.CategoryCovers{DB: db, GenAI: genaiService, Storage: images}
+			if _, err := job.Run(ctx, names...); err != nil {
+				log.Printf("Some covers failed: %v", err)
+			}
+		}
+
+		categories, err := db.ListCategories(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list categories: %v", err)
+		}
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, categories, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Order\tCategory\tCover")
+			fmt.Fprintln(w, "-----\t--------\t-----")
+			for _, c := range categories {
+				cover := "-"
+				if c.CoverURL != "" {
+					cover = fmt.Sprintf("%s (%s)", c.CoverURL, c.CoverUpdated.Format("02 Jan 15:04"))
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", c.Order, c.Name, cover)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review locations hidden by user reports",
+	Long:  "List locations hidden pending review, or approve (restore) or purge (delete doc and media) one.",
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		approve, _ := cmd.Flags().GetBool("approve")
+		purge, _ := cmd.Flags().GetBool("purge")
+		if (approve || purge) && id == "" {
+			log.Fatal("id is required with --approve or --purge (use --id)")
+		}
+		if approve && purge {
+			log.Fatal("use only one of --approve or --purge")
+		}
 
 		ctx := context.Background()
 		cfg, _ := config.Load()
 		if cfg == nil { log.Fatal("Config load failed") }
 
-		db, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		db, err := repo.Open(ctx, cfg)
 		if err != nil {
 			log.Fatalf("Failed to init DB: %v", err)
 		}
 		defer db.Close()
-		runList(ctx, db, limit, filterType)
+
+		switch {
+		case approve:
+			if err := db.ResolveReview(ctx, id); err != nil {
+				log.Fatalf("Approve failed: %v", err)
+			}
+			log.Printf("Approved %s; it is visible again.", id)
+		case purge:
+			runPurge(ctx, cfg, db, id)
+		default:
+			output, _ := cmd.Flags().GetString("output")
+			runList(ctx, &localAdmin{Repository: db, cfg: cfg}, database.ListOptions{Limit: 100, Type: "hidden"}, output)
+		}
 	},
 }
 
-var refreshCmd = &cobra.Command{
-	Use:   "refresh",
-	Short: "Refresh a location's media",
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Set a location's lifecycle status",
+	Long:  "Manually set a location's status, e.g. archive it or queue it for refresh.",
 	Run: func(cmd *cobra.Command, args []string) {
 		id, _ := cmd.Flags().GetString("id")
-		style, _ := cmd.Flags().GetInt("style")
-		if id == "" {
-			log.Fatal("id is required (use --id)")
+		set, _ := cmd.Flags().GetString("set")
+		if id == "" || set == "" {
+			log.Fatal("id and status are required (use --id and --set)")
+		}
+		status := database.LocationStatus(set)
+		if !slices.Contains(database.LocationStatuses, status) {
+			log.Fatalf("unknown status %q", set)
 		}
 
 		ctx := context.Background()
 		cfg, _ := config.Load()
 		if cfg == nil { log.Fatal("Config load failed") }
 
-		db, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		db, err := repo.Open(ctx, cfg)
 		if err != nil {
 			log.Fatalf("Failed to init DB: %v", err)
 		}
 		defer db.Close()
-		runRefresh(ctx, db, id, style, cfg)
+
+		if _, err := db.GetLocation(ctx, id); err != nil {
+			log.Fatalf("Location not found: %v", err)
+		}
+		if err := db.SetStatus(ctx, id, status); err != nil {
+			log.Fatalf("Failed to set status: %v", err)
+		}
+		log.Printf("%s is now %s.", id, status)
+	},
+}
+
+func completeStatus(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var out []string
+	for _, s := range database.LocationStatuses {
+		out = append(out, string(s))
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a location",
+	Long:  "Delete a location document. Media in GCS is left untouched.",
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		if err := backend.DeleteLocation(ctx, id); err != nil {
+			log.Fatalf("Delete failed: %v", err)
+		}
+		log.Printf("Deleted %s.", id)
+	},
+}
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Generate a preview image without saving",
+	Long:  "Generate an image only (no video, no Firestore or GCS writes) for prompt tuning.",
+	Run: func(cmd *cobra.Command, args []string) {
+		city, _ := cmd.Flags().GetString("city")
+		extra, _ := cmd.Flags().GetString("context")
+		styleStr, _ := cmd.Flags().GetString("style")
+		out, _ := cmd.Flags().GetString("out")
+		open, _ := cmd.Flags().GetBool("open")
+		if city == "" {
+			log.Fatal("city is required (use --city)")
+		}
+		style, err := genai.ParseStyle(styleStr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var seed *int32
+		if cmd.Flags().Changed("seed") {
+			v, _ := cmd.Flags().GetInt32("seed")
+			seed = &v
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil { log.Fatal("Config load failed") }
+
+		runPreview(ctx, cfg, city, extra, style, seed, out, open)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(adminCmd)
 	adminCmd.AddCommand(statsCmd)
+	supportsRemote(statsCmd)
 	adminCmd.AddCommand(listCmd)
+	supportsRemote(listCmd)
 	adminCmd.AddCommand(refreshCmd)
+	supportsRemote(refreshCmd)
+	adminCmd.AddCommand(previewCmd)
+	adminCmd.AddCommand(deleteCmd)
+	supportsRemote(deleteCmd)
+	adminCmd.AddCommand(brandingCmd)
+	adminCmd.AddCommand(categoriesCmd)
+	adminCmd.AddCommand(reviewCmd)
+	adminCmd.AddCommand(statusCmd)
+
+	// Commands without remote support refuse --remote, see supportsRemote
+	adminCmd.PersistentPreRunE = checkRemote
+	adminCmd.PersistentFlags().String("remote", "", "Admin API base URL (e.g. https://api.example.com); env BANANA_REMOTE")
+	adminCmd.PersistentFlags().String("api-key", "", "Admin API key for --remote; env BANANA_API_KEY")
 
 	listCmd.Flags().Int("limit", 20, "Max number of results")
-	listCmd.Flags().String("type", "all", "Filter by type: all, preset, user")
+	listCmd.Flags().String("type", "all", "Filter by type: all, preset, user, hidden")
+	listCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"all", "preset", "user", "hidden"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	listCmd.Flags().String("status", "", "Filter by status: generating, ready, refresh_pending, failed, hidden, archived, hidden_pending_review")
+	listCmd.RegisterFlagCompletionFunc("status", completeStatus)
+	listCmd.Flags().String("sort", "updated", "Sort by: updated, feedback (lowest score first)")
+	listCmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"updated", "feedback"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	addOutputFlag(statsCmd)
+	addOutputFlag(listCmd)
 
 	refreshCmd.Flags().String("id", "", "Location ID to refresh")
 	refreshCmd.Flags().Int("style", 0, "Prompt Style: 0=Random, 1=Classic, 2=Drink")
+	refreshCmd.Flags().Int32("seed", 0, "Override the stored generation seed")
+	refreshCmd.Flags().Bool("image-only", false, "Regenerate only the image, keep the current video")
+	refreshCmd.Flags().Bool("video-only", false, "Regenerate only the video, animating the stored image")
+	refreshCmd.Flags().String("priority", "", "Quota priority: interactive, scheduled or batch (default scheduled)")
+	refreshCmd.Flags().String("grounding", "", "GoogleSearch mode: search, off or required (default GROUNDING_MODE)")
+	refreshCmd.Flags().Bool("override-lock", false, "Regenerate the location even if it's locked")
+	refreshCmd.Flags().Bool("override-hidden", false, "Regenerate a hidden or archived location, keeping it hidden or archived")
+	refreshCmd.Flags().Bool("skip-unchanged", false, "Keep the media if the weather is unchanged since it was generated")
+	refreshCmd.Flags().Bool("auto-approve", false, "Replace a preset's media right away instead of staging the new image for approval")
+
+	deleteCmd.Flags().String("id", "", "Location ID to delete")
+
+	statusCmd.Flags().String("id", "", "Location ID")
+	statusCmd.Flags().String("set", "", "New status")
+	statusCmd.RegisterFlagCompletionFunc("set", completeStatus)
+
+	reviewCmd.Flags().String("id", "", "Location ID to approve or purge")
+	reviewCmd.Flags().Bool("approve", false, "Restore the location and clear its reports")
+	reviewCmd.Flags().Bool("purge", false, "Delete the location and its media")
+	addOutputFlag(reviewCmd)
+
+	brandingCmd.Flags().String("tenant", "", "Tenant ID (default: TENANT_ID)")
+	brandingCmd.Flags().StringSlice("palette", nil, "Brand colors, e.g. \"#FFD400,#1A1A1A\"")
+	brandingCmd.Flags().String("prompt-suffix", "", "Text appended to every image prompt")
+	brandingCmd.Flags().String("watermark-url", "", "PNG logo overlaid on images (https:// or gs://)")
+	brandingCmd.Flags().Float64("watermark-scale", 0, "Logo width as a fraction of image width (default 0.2)")
+	brandingCmd.Flags().Float64("watermark-opacity", 0, "Logo opacity 0-1 (default 0.8)")
+	brandingCmd.Flags().Bool("ai-badge", false, "Overlay a visible \"AI GENERATED\" badge on images")
+	addOutputFlag(brandingCmd)
+
+	categoriesCmd.Flags().StringSlice("order", nil, "Categories in display order, e.g. \"Featured,Europe,Fictional\"")
+	categoriesCmd.Flags().Bool("covers", false, "Generate category cover art")
+	categoriesCmd.Flags().StringSlice("category", nil, "With --covers, only these categories (default: every category with presets)")
+	addOutputFlag(categoriesCmd)
+
+	previewCmd.Flags().String("city", "", "City name")
+	previewCmd.Flags().String("context", "", "Extra prompt context")
+	previewCmd.Flags().String("style", "random", "Prompt Style: random, classic, drink (or 0, 1, 2)")
+	previewCmd.Flags().Int32("seed", 0, "Generation seed (default: random)")
+	previewCmd.Flags().String("out", "", "Output path (default: temp file)")
+	previewCmd.Flags().Bool("open", false, "Open the image in the default viewer")
 }
 
-func runStats(ctx context.Context, db *database.Client) {
-	fmt.Println("Fetching stats...")
+func runStats(ctx context.Context, db adminBackend, output string) {
 	stats, err := db.GetStats(ctx)
 	if err != nil {
 		log.Fatalf("Error getting stats: %v", err)
 	}
-	
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Metric\tValue")
-	fmt.Fprintln(w, "------\t-----")
-	fmt.Fprintf(w, "Total Locations\t%d\n", stats.TotalLocations)
-	fmt.Fprintf(w, "Presets\t%d\n", stats.Presets)
-	fmt.Fprintf(w, "User Generated\t%d\n", stats.UserGenerated)
-	fmt.Fprintf(w, "Last Activity\t%s (%s ago)\n", stats.LastUpdated.Format(time.RFC822), time.Since(stats.LastUpdated).Round(time.Second))
-	w.Flush()
+
+	err = writeOutput(output, stats, func(out io.Writer) {
+		fmt.Fprintln(out, "Fetching stats...")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Metric\tValue")
+		fmt.Fprintln(w, "------\t-----")
+		fmt.Fprintf(w, "Total Locations\t%d\n", stats.TotalLocations)
+		fmt.Fprintf(w, "Presets\t%d\n", stats.Presets)
+		fmt.Fprintf(w, "User Generated\t%d\n", stats.UserGenerated)
+		fmt.Fprintf(w, "Weather Mismatches\t%d\n", stats.WeatherMismatches)
+		fmt.Fprintf(w, "Last Activity\t%s (%s ago)\n", stats.LastUpdated.Format(time.RFC822), time.Since(stats.LastUpdated).Round(time.Second))
+		w.Flush()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func runList(ctx context.Context, db *database.Client, limit int, filterType string) {
-	fmt.Printf("Listing top %d locations (type: %s)...\n", limit, filterType)
-	locs, err := db.ListLocations(ctx, limit, filterType)
+func runList(ctx context.Context, db adminBackend, opts database.ListOptions, output string) {
+	locs, err := db.ListLocations(ctx, opts)
 	if err != nil {
 		log.Fatalf("Error listing locations: %v", err)
 	}
+	if locs == nil {
+		locs = []database.Location{}
+	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tName\tType\tCity\tUpdated")
-	fmt.Fprintln(w, "--\t----\t----\t----\t-------")
+	err = writeOutput(output, locs, func(out io.Writer) {
+		fmt.Fprintf(out, "Listing top %d locations (type: %s)...\n", opts.Limit, opts.Type)
+		printLocationTable(out, locs)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printLocationTable(out io.Writer, locs []database.Location) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tName\tType\tStatus\tCity\tFeedback\tUsage\tUpdated")
+	fmt.Fprintln(w, "--\t----\t----\t------\t----\t--------\t-----\t-------")
 	for _, l := range locs {
 		sType := "User"
 		if l.IsPreset { sType = "Preset" }
@@ -130,53 +688,140 @@ func runList(ctx context.Context, db *database.Client, limit int, filterType str
 		city := l.CityQuery
 		if len(city) > 30 { city = city[:27] + "..." }
 		
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", l.ID, l.Name, sType, city, l.LastUpdated.Format("02 Jan 15:04"))
+		feedback := fmt.Sprintf("+%d/-%d", l.FeedbackUp, l.FeedbackDown)
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.ID, l.Name, sType, l.EffectiveStatus(), city, feedback, formatUsage(l.Usage), l.LastUpdated.Format("02 Jan 15:04"))
 	}
 	w.Flush()
 }
 
-func runRefresh(ctx context.Context, db *database.Client, id string, style int, cfg *config.Config) {
-	log.Printf("Refreshing location: %s (Style: %d)", id, style)
+// formatUsage shortens a usage summary for the table, e.g. "1.6k tok, 8s, $0.43".
+// The cost is left out without rates.
+func formatUsage(u *database.UsageSummary) string {
+	if u == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%.1fk tok", float64(u.Tokens)/1000)
+	if u.VideoSeconds > 0 {
+		s += fmt.Sprintf(", %ds", u.VideoSeconds)
+	}
+	if u.CostUSD > 0 {
+		s += fmt.Sprintf(", $%.2f", u.CostUSD)
+	}
+	return s
+}
+
+func runPurge(ctx context.Context, cfg *config.Config, db repo.Repository, id string) {
 	loc, err := db.GetLocation(ctx, id)
 	if err != nil {
 		log.Fatalf("Location not found: %v", err)
 	}
+	images, err := storage.OpenKind(ctx, cfg, storage.KindImage)
+	if err != nil { log.Fatalf("Storage init failed: %v", err) }
+	videos, err := storage.OpenKind(ctx, cfg, storage.KindVideo)
+	if err != nil { log.Fatalf("Storage init failed: %v", err) }
 
+	for _, m := range []struct {
+		store storage.Store
+		url   string
+	}{{images, loc.ImageURL}, {videos, loc.VideoURL}} {
+		name := m.store.ObjectName(m.url)
+		if name == "" {
+			continue
+		}
+		if err := m.store.DeleteObject(ctx, name); err != nil {
+			log.Printf("Failed to delete %s: %v", name, err)
+		} else {
+			log.Printf("Deleted media: %s", name)
+		}
+	}
+	if err := db.DeleteLocation(ctx, id); err != nil {
+		log.Fatalf("Failed to delete %s: %v", id, err)
+	}
+	log.Printf("Purged %s.", id)
+}
+
+func runPreview(ctx context.Context, cfg *config.Config, city, extra string, style int, seed *int32, out string, open bool) {
 	genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
 	if err != nil { log.Fatalf("GenAI init failed: %v", err) }
-	storageService, err := storage.NewService(ctx, cfg.BucketName)
-	if err != nil { log.Fatalf("Storage init failed: %v", err) }
+	genaiService.SetTransport(cfg.GenAITransport)
+
+	// Branding is read-only here; nothing is written to Firestore or GCS.
+	if db, err := repo.Open(ctx, cfg); err == nil {
+		ss, _ := storage.Open(ctx, cfg)
+		configureGenAI(ctx, cfg, genaiService, db, ss)
+		genaiService.SetImageCache(nil) // Cache writes would touch the bucket
+		db.Close()
+	} else {
+		log.Printf("Warning: DB unavailable, previewing without branding: %v", err)
+	}
 
-	log.Printf("Generating image for '%s'...", loc.CityQuery)
-	imgBase64, err := genaiService.GenerateImage(ctx, loc.CityQuery, "", style)
+	log.Printf("Generating preview for '%s' (Style: %d)...", city, style)
+	img, err := genaiService.GenerateImage(ctx, city, extra, style, seed)
 	if err != nil {
 		log.Fatalf("Image gen failed: %v", err)
 	}
-
-	imgFileName := fmt.Sprintf("refresh_%s_image_%d.png", id, time.Now().Unix())
-	gsImageURI, publicImageURL, err := storageService.UploadImage(ctx, imgBase64, imgFileName)
+	printGeneration(img)
+	data, err := base64.StdEncoding.DecodeString(img.Image())
 	if err != nil {
-		log.Fatalf("Image upload failed: %v", err)
+		log.Fatalf("Invalid image data: %v", err)
 	}
-	log.Printf("Image uploaded: %s", publicImageURL)
 
-	log.Printf("Generating video (Veo)...")
-	videoGsURI, err := genaiService.GenerateVideo(ctx, gsImageURI, "")
-	if err != nil {
-		log.Fatalf("Video gen failed: %v", err)
-	}
-	
-bucketName := os.Getenv("GENMEDIA_BUCKET")
-	publicVideoURL := strings.Replace(videoGsURI, "gs://"+bucketName, "https://storage.googleapis.com/"+bucketName, 1)
-	log.Printf("Video generated: %s", publicVideoURL)
-
-	// Update DB
-	loc.ImageURL = publicImageURL
-	loc.VideoURL = publicVideoURL
-	loc.LastUpdated = time.Now()
-	
-	if err := db.UpsertLocation(ctx, *loc); err != nil {
-		log.Fatalf("Failed to update DB: %v", err)
-	}
-	log.Println("Refresh Complete.")
+	if out == "" {
+		f, err := os.CreateTemp("", "banana_preview_*.png")
+		if err != nil {
+			log.Fatalf("Failed to create temp file: %v", err)
+		}
+		out = f.Name()
+		f.Close()
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		log.Fatalf("Failed to write preview: %v", err)
+	}
+	fmt.Printf("Preview saved to: %s\n", out)
+
+	if open {
+		if err := openFile(out); err != nil {
+			log.Printf("Failed to open preview: %v", err)
+		}
+	}
+}
+
+// printGeneration shows what the model said alongside the image, which is
+// where it explains the weather it looked up.
+func printGeneration(img *genai.ImageResult) {
+	if img.Cached {
+		fmt.Println("(Served from the prompt cache)")
+		return
+	}
+	if img.Text != "" {
+		fmt.Printf("Model commentary:\n%s\n", img.Text)
+	}
+	if len(img.Grounding.SearchQueries) > 0 {
+		fmt.Printf("Searches: %s\n", strings.Join(img.Grounding.SearchQueries, "; "))
+	}
+	for _, src := range img.Grounding.Sources {
+		fmt.Printf("Source: %s (%s)\n", src.Title, src.URI)
+		for _, snippet := range src.Snippets {
+			fmt.Printf("  - %s\n", snippet)
+		}
+	}
+	if len(img.Images) > 1 {
+		fmt.Printf("The model returned %d images; saving the first.\n", len(img.Images))
+	}
+	fmt.Printf("Tokens: %d prompt, %d output, %d total\n", img.Usage.Prompt, img.Usage.Output, img.Usage.Total)
+}
+
+// openFile opens a path with the platform's default handler.
+func openFile(path string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.Command("open", path)
+	case "windows":
+		c = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		c = exec.Command("xdg-open", path)
+	}
+	return c.Start()
 }
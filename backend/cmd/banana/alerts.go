@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/notify"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Check generation failure rate and latency against the alert thresholds",
+	Long:  "Evaluate the alert thresholds of `banana admin runtime` over their window, notifying ADMIN_WEBHOOK_URL and ADMIN_EMAILS when an alert starts or resolves.",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+
+		report, err := backend.EvaluateAlerts(ctx)
+		if err != nil {
+			log.Fatalf("Alert evaluation failed: %v", err)
+		}
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, report, func(out io.Writer) {
+			state := "OK"
+			if report.Firing {
+				state = "FIRING"
+			}
+			fmt.Fprintf(out, "%s: %.1f%% of %d generations failed", state, report.FailureRate*100, report.Generations)
+			if report.Notified {
+				fmt.Fprint(out, " (notified)")
+			}
+			fmt.Fprintln(out)
+			for _, r := range report.Reasons {
+				fmt.Fprintf(out, "  - %s\n", r)
+			}
+			if report.Degraded {
+				fmt.Fprintln(out, "Image-only mode is on.")
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func (l *localAdmin) EvaluateAlerts(ctx context.Context) (*database.AlertReport, error) {
+	job := &jobs.Alerts{DB: l.Repository, Notifier: notify.Open(l.cfg)}
+	return job.Evaluate(ctx)
+}
+
+func (c *remoteClient) EvaluateAlerts(ctx context.Context) (*database.AlertReport, error) {
+	var report database.AlertReport
+	if err := c.do(ctx, http.MethodPost, "/alerts/evaluate", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+var runtimeCmd = &cobra.Command{
+	Use:   "runtime",
+	Short: "Show or update runtime settings (degrade mode, alert thresholds)",
+	Long:  "Show the runtime settings doc, or update it when any setting flag is given. Changes apply to running servers without a redeploy.",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		// The flags' changes, applied to the settings as saved so the kill
+		// switch and the evaluator's alert state are kept
+		var edits []func(s *database.RuntimeSettings)
+		if cmd.Flags().Changed("degrade-image-only") {
+			on, _ := cmd.Flags().GetBool("degrade-image-only")
+			edits = append(edits, func(s *database.RuntimeSettings) {
+				s.DegradeImageOnly, s.DegradeReason = on, ""
+				if on {
+					s.DegradeReason = "manual"
+				}
+			})
+		}
+		if cmd.Flags().Changed("alert-window") {
+			w, _ := cmd.Flags().GetString("alert-window")
+			d, err := database.ParseWindow(w)
+			if err != nil {
+				log.Fatalf("Invalid --alert-window %q: %v", w, err)
+			}
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.WindowMinutes = int(d.Minutes()) })
+		}
+		if cmd.Flags().Changed("alert-min-samples") {
+			n, _ := cmd.Flags().GetInt("alert-min-samples")
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.MinSamples = n })
+		}
+		if cmd.Flags().Changed("alert-failure-rate") {
+			rate, _ := cmd.Flags().GetFloat64("alert-failure-rate")
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.MaxFailureRate = rate })
+		}
+		if cmd.Flags().Changed("alert-image-p95") {
+			p95, _ := cmd.Flags().GetFloat64("alert-image-p95")
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.MaxImageP95 = p95 })
+		}
+		if cmd.Flags().Changed("alert-video-p95") {
+			p95, _ := cmd.Flags().GetFloat64("alert-video-p95")
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.MaxVideoP95 = p95 })
+		}
+		if cmd.Flags().Changed("alert-auto-degrade") {
+			on, _ := cmd.Flags().GetBool("alert-auto-degrade")
+			edits = append(edits, func(s *database.RuntimeSettings) { s.Alerts.AutoDegrade = on })
+		}
+
+		var s *database.RuntimeSettings
+		if len(edits) > 0 {
+			s, err = db.UpdateRuntimeSettings(ctx, func(s *database.RuntimeSettings) {
+				for _, edit := range edits {
+					edit(s)
+				}
+			})
+			if err != nil {
+				log.Fatalf("Failed to save runtime settings: %v", err)
+			}
+			log.Println("Runtime settings updated.")
+		} else if s, err = db.GetRuntimeSettings(ctx); err != nil {
+			log.Fatalf("Failed to read runtime settings: %v", err)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, s, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Setting\tValue")
+			fmt.Fprintln(w, "-------\t-----")
+			fmt.Fprintf(w, "Image-Only Mode\t%t\n", s.DegradeImageOnly)
+			fmt.Fprintf(w, "Degrade Reason\t%s\n", s.DegradeReason)
+			fmt.Fprintf(w, "Alert Window\t%s\n", s.Alerts.Window())
+			fmt.Fprintf(w, "Alert Min Samples\t%d\n", s.Alerts.Samples())
+			fmt.Fprintf(w, "Max Failure Rate\t%.2f\n", s.Alerts.MaxFailureRate)
+			fmt.Fprintf(w, "Max Image p95\t%.0fs\n", s.Alerts.MaxImageP95)
+			fmt.Fprintf(w, "Max Video p95\t%.0fs\n", s.Alerts.MaxVideoP95)
+			fmt.Fprintf(w, "Auto Degrade\t%t\n", s.Alerts.AutoDegrade)
+			fmt.Fprintf(w, "Alert Firing\t%t\n", s.AlertFiring)
+			fmt.Fprintf(w, "Kill Switch\t%t\n", s.Halted(cfg.TenantID, time.Now()) != nil)
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(alertsCmd)
+	supportsRemote(alertsCmd)
+	adminCmd.AddCommand(runtimeCmd)
+	addOutputFlag(alertsCmd)
+
+	runtimeCmd.Flags().Bool("degrade-image-only", false, "Skip video generation in the web flow")
+	runtimeCmd.Flags().String("alert-window", "", "Sliding window of alert checks, e.g. 1h or 1d (default 1h)")
+	runtimeCmd.Flags().Int("alert-min-samples", 0, "Generations in the window before alerting (default 10)")
+	runtimeCmd.Flags().Float64("alert-failure-rate", 0, "Alert when more than this share of generations fail, 0-1 (0 = off)")
+	runtimeCmd.Flags().Float64("alert-image-p95", 0, "Alert when image generation p95 exceeds this many seconds (0 = off)")
+	runtimeCmd.Flags().Float64("alert-video-p95", 0, "Alert when video generation p95 exceeds this many seconds (0 = off)")
+	runtimeCmd.Flags().Bool("alert-auto-degrade", false, "Turn on image-only mode when an alert fires")
+	addOutputFlag(runtimeCmd)
+}
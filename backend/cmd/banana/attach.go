@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// Largest objects attach accepts
+const (
+	maxAttachImageBytes = 20 << 20
+	maxAttachVideoBytes = 500 << 20
+)
+
+// attachTypes are the content types attach accepts, with the extension of
+// the copy, by kind.
+var attachTypes = map[string]map[string]string{
+	"image": {"image/png": ".png", "image/jpeg": ".jpg", "image/webp": ".webp"},
+	"video": {"video/mp4": ".mp4"},
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach hand-made media to a location",
+	Long: `Attach an image and/or video produced outside the pipeline (gs:// URIs in any bucket the
+service account can read). The objects are checked (they exist, their content type and size),
+copied into the media bucket under curated/, and set on the location, which is marked
+manually_curated: refresh-stale, cache warm-up, the city of the day and the web flow leave it
+alone. An explicit "banana admin refresh" replaces the media and clears the mark.`,
+	Example: `  banana admin attach --id tokyo --image gs://design/tokyo.png --video gs://design/tokyo.mp4`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		image, _ := cmd.Flags().GetString("image")
+		video, _ := cmd.Flags().GetString("video")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+		if image == "" && video == "" {
+			log.Fatal("nothing to attach (use --image and/or --video)")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+		store, err := storage.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init storage: %v", err)
+		}
+		copier := storage.AsCopier(store)
+		if copier == nil {
+			log.Fatalf("STORAGE_BACKEND %q can't copy gs:// objects", cfg.StorageBackend)
+		}
+
+		loc, err := attachMedia(ctx, db, copier, id, image, video, time.Now())
+		if err != nil {
+			log.Fatalf("Attach failed: %v", err)
+		}
+		if image != "" {
+			log.Printf("Image: %s", loc.ImageURL)
+		}
+		if video != "" {
+			log.Printf("Video: %s", loc.VideoURL)
+		}
+		log.Printf("Attached to %s; it won't be regenerated automatically.", id)
+	},
+}
+
+// attachDB is the part of the repository attach needs.
+type attachDB interface {
+	GetLocation(ctx context.Context, id string) (*database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	AddAuditEntry(ctx context.Context, e database.AuditEntry) error
+}
+
+// attachMedia checks the given gs:// objects (either may be empty), copies
+// them to curated/ and saves them on the location as manually curated.
+// Nothing is copied unless every object passes. Fields describing the
+// generated media they replace (generation, weather check, mood, alt text,
+// poster and stream) are cleared, as is the feedback on it.
+func attachMedia(ctx context.Context, db attachDB, c storage.Copier, id, imageURI, videoURI string, now time.Time) (*database.Location, error) {
+	loc, err := db.GetLocation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("location not found: %w", err)
+	}
+
+	var image, video *storage.ObjectInfo
+	var imageExt, videoExt string
+	if imageURI != "" {
+		if image, imageExt, err = checkAttachment(ctx, c, "image", imageURI, maxAttachImageBytes); err != nil {
+			return nil, err
+		}
+	}
+	if videoURI != "" {
+		if video, videoExt, err = checkAttachment(ctx, c, "video", videoURI, maxAttachVideoBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	var attached []string
+	sums := map[string]string{} // A copy has the checksum of its source
+	if imageURI != "" {
+		_, url, err := c.CopyFrom(ctx, imageURI, fmt.Sprintf("curated/%s_image_%d%s", id, now.Unix(), imageExt))
+		if err != nil {
+			return nil, err
+		}
+		loc.ImageURL, loc.AltText = url, ""
+		loc.Generation, loc.WeatherCheck, loc.WeatherMismatch = nil, nil, false
+		loc.Mood, loc.TemperatureC = "", nil
+		sums[url] = image.CRC32C
+		attached = append(attached, "image "+imageURI)
+	}
+	if videoURI != "" {
+		_, url, err := c.CopyFrom(ctx, videoURI, fmt.Sprintf("curated/%s_video_%d%s", id, now.Unix(), videoExt))
+		if err != nil {
+			return nil, err
+		}
+		loc.VideoURL, loc.PosterURL, loc.StreamURL = url, "", ""
+		sums[url] = video.CRC32C
+		attached = append(attached, "video "+videoURI)
+	}
+	loc.RecordChecksums(sums)
+
+	loc.ManuallyCurated = true
+	loc.Status = database.StatusReady
+	loc.LastUpdated = now
+	// Feedback applied to the old media
+	loc.FeedbackUp, loc.FeedbackDown, loc.FeedbackScore, loc.FeedbackReasons = 0, 0, 0, nil
+	if err := db.UpsertLocation(ctx, *loc); err != nil {
+		return nil, fmt.Errorf("failed to update DB: %w", err)
+	}
+	entry := database.AuditEntry{Event: "media_attached", Location: id, Reason: strings.Join(attached, ", "), CreatedAt: now}
+	if err := db.AddAuditEntry(ctx, entry); err != nil {
+		log.Printf("Failed to write audit entry: %v", err)
+	}
+	return loc, nil
+}
+
+// checkAttachment checks that the object at uri exists, has a content type
+// accepted for kind and is at most maxBytes. It returns the object's
+// attributes and the extension of the copy.
+func checkAttachment(ctx context.Context, c storage.Copier, kind, uri string, maxBytes int64) (*storage.ObjectInfo, string, error) {
+	info, err := c.StatURI(ctx, uri)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", kind, err)
+	}
+	contentType, _, _ := strings.Cut(info.ContentType, ";")
+	ext, ok := attachTypes[kind][strings.TrimSpace(contentType)]
+	switch {
+	case !ok:
+		return nil, "", fmt.Errorf("%s %s has content type %q", kind, uri, info.ContentType)
+	case info.Size == 0:
+		return nil, "", fmt.Errorf("%s %s is empty", kind, uri)
+	case info.Size > maxBytes:
+		return nil, "", fmt.Errorf("%s %s is %d MB, the limit is %d MB", kind, uri, info.Size>>20, maxBytes>>20)
+	}
+	return info, ext, nil
+}
+
+func init() {
+	adminCmd.AddCommand(attachCmd)
+	attachCmd.Flags().String("id", "", "Location ID")
+	attachCmd.Flags().String("image", "", "gs:// URI of the image (PNG, JPEG or WebP)")
+	attachCmd.Flags().String("video", "", "gs:// URI of the video (MP4)")
+}
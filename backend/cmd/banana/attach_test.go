@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/storage"
+)
+
+// fakeCopier serves objects by gs:// URI and records copies.
+type fakeCopier struct {
+	objects map[string]storage.ObjectInfo
+	copied  []string
+}
+
+func (f *fakeCopier) StatURI(ctx context.Context, uri string) (*storage.ObjectInfo, error) {
+	info, ok := f.objects[uri]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &info, nil
+}
+
+func (f *fakeCopier) CopyFrom(ctx context.Context, uri, fileName string) (string, string, error) {
+	f.copied = append(f.copied, fileName)
+	return "gs://media/" + fileName, "https://storage.googleapis.com/media/" + fileName, nil
+}
+
+func TestAttachMedia(t *testing.T) {
+	ctx := context.Background()
+	temp := 21.5
+	db := mock.NewDB()
+	db.UpsertLocation(ctx, database.Location{
+		ID: "tokyo", Name: "Tokyo", IsPreset: true,
+		ImageURL: "https://storage.googleapis.com/media/old.png", AltText: "Old image", Mood: "cozy-rain", TemperatureC: &temp,
+		VideoURL: "https://storage.googleapis.com/media/old.mp4", PosterURL: "https://storage.googleapis.com/media/old.jpg",
+		Generation: &database.GenerationMetadata{Model: "image"}, FeedbackDown: 3, FeedbackScore: -3,
+	})
+	c := &fakeCopier{objects: map[string]storage.ObjectInfo{
+		"gs://design/tokyo.png":   {Size: 1 << 20, ContentType: "image/png"},
+		"gs://design/tokyo.mp4":   {Size: 30 << 20, ContentType: "video/mp4"},
+		"gs://design/notes.txt":   {Size: 10, ContentType: "text/plain"},
+		"gs://design/huge.mp4":    {Size: 600 << 20, ContentType: "video/mp4"},
+		"gs://design/empty.jpg":   {Size: 0, ContentType: "image/jpeg"},
+		"gs://design/tokyo2.jpeg": {Size: 1 << 20, ContentType: "image/jpeg; charset=binary"},
+	}}
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct{ image, video, want string }{
+		{"gs://design/missing.png", "", "not found"},
+		{"gs://design/notes.txt", "", "content type"},
+		{"gs://design/tokyo.png", "gs://design/huge.mp4", "limit"},
+		{"gs://design/empty.jpg", "", "empty"},
+		{"gs://design/tokyo.mp4", "", "content type"},
+	} {
+		if _, err := attachMedia(ctx, db, c, "tokyo", tt.image, tt.video, now); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("attach %s %s: got %v, want an error with %q", tt.image, tt.video, err, tt.want)
+		}
+	}
+	if len(c.copied) != 0 {
+		t.Fatalf("Expected nothing copied for invalid objects, got %v", c.copied)
+	}
+	if _, err := attachMedia(ctx, db, c, "nowhere", "gs://design/tokyo.png", "", now); err == nil {
+		t.Error("Expected an unknown location to fail")
+	}
+
+	loc, err := attachMedia(ctx, db, c, "tokyo", "gs://design/tokyo.png", "gs://design/tokyo.mp4", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{fmt.Sprintf("curated/tokyo_image_%d.png", now.Unix()), fmt.Sprintf("curated/tokyo_video_%d.mp4", now.Unix())}
+	if len(c.copied) != 2 || c.copied[0] != want[0] || c.copied[1] != want[1] {
+		t.Errorf("copied %v, want %v", c.copied, want)
+	}
+	stored, _ := db.GetLocation(ctx, "tokyo")
+	switch {
+	case !stored.ManuallyCurated || stored.EffectiveStatus() != database.StatusReady:
+		t.Errorf("Expected a ready, curated location, got %+v", stored)
+	case stored.ImageURL != "https://storage.googleapis.com/media/"+want[0] || stored.VideoURL != "https://storage.googleapis.com/media/"+want[1]:
+		t.Errorf("Unexpected media %s, %s", stored.ImageURL, stored.VideoURL)
+	case stored.AltText != "" || stored.Mood != "" || stored.TemperatureC != nil || stored.Generation != nil || stored.PosterURL != "":
+		t.Errorf("Expected the generated media's details cleared, got %+v", stored)
+	case stored.FeedbackDown != 0 || stored.FeedbackScore != 0:
+		t.Errorf("Expected the old media's feedback reset, got %d", stored.FeedbackScore)
+	case !stored.IsPreset || stored.Name != "Tokyo" || loc.ID != "tokyo":
+		t.Errorf("Expected the rest of the location kept, got %+v", stored)
+	}
+
+	// A new image alone keeps the video
+	if _, err := attachMedia(ctx, db, c, "tokyo", "gs://design/tokyo2.jpeg", "", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = db.GetLocation(ctx, "tokyo")
+	if !strings.HasSuffix(stored.ImageURL, ".jpg") || stored.VideoURL != "https://storage.googleapis.com/media/"+want[1] {
+		t.Errorf("Unexpected media %s, %s", stored.ImageURL, stored.VideoURL)
+	}
+}
+
+func TestPlanStaleRefresh_SkipsCurated(t *testing.T) {
+	now := time.Now()
+	locs := []database.Location{
+		{ID: "paris", IsPreset: true, LastUpdated: now.Add(-48 * time.Hour)},
+		{ID: "tokyo", IsPreset: true, ManuallyCurated: true, LastUpdated: now.Add(-48 * time.Hour)},
+	}
+	plan, err := planStaleRefresh(locs, now, time.Hour, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].ID != "paris" {
+		t.Errorf("Expected only paris planned, got %+v", plan)
+	}
+}
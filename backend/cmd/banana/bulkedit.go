@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var bulkEditCmd = &cobra.Command{
+	Use:   "bulk-edit",
+	Short: "Edit metadata of many locations at once",
+	Long: `Set fields on every location matching --filter, or on the locations listed in a CSV
+(--from-csv, an id column plus one column per field; empty cells are left alone).
+Editable fields: ` + strings.Join(database.EditableFields, ", ") + `.
+The affected documents are previewed first; --dry-run stops there. Each edited location
+gets an audit_log entry.`,
+	Example: `  banana admin bulk-edit --filter category=General --set category=Cities --dry-run
+  banana admin bulk-edit --filter country_code=JP --filter is_preset=false --set status=archived
+  banana admin bulk-edit --from-csv edits.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filters, _ := cmd.Flags().GetStringArray("filter")
+		sets, _ := cmd.Flags().GetStringArray("set")
+		csvPath, _ := cmd.Flags().GetString("from-csv")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if (csvPath == "") == (len(sets) == 0) {
+			log.Fatal("use either --set (with optional --filter) or --from-csv")
+		}
+		if csvPath != "" && len(filters) > 0 {
+			log.Fatal("--filter can't be combined with --from-csv")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		locs, err := db.ListLocations(ctx, database.ListOptions{Type: "all"})
+		if err != nil {
+			log.Fatalf("Error listing locations: %v", err)
+		}
+
+		var changes []bulkChange
+		var source string
+		if csvPath != "" {
+			f, err := os.Open(csvPath)
+			if err != nil {
+				log.Fatalf("Failed to open CSV: %v", err)
+			}
+			rows, err := readEditsCSV(f)
+			f.Close()
+			if err != nil {
+				log.Fatalf("Invalid CSV: %v", err)
+			}
+			changes, err = planCSVEdits(locs, rows)
+			if err != nil {
+				log.Fatal(err)
+			}
+			source = "csv " + csvPath
+		} else {
+			where, err := parseAssignments(filters, true)
+			if err != nil {
+				log.Fatalf("Invalid --filter: %v", err)
+			}
+			set, err := parseAssignments(sets, false)
+			if err != nil {
+				log.Fatalf("Invalid --set: %v", err)
+			}
+			changes = planBulkEdit(locs, where, set)
+			source = "filter " + strings.Join(filters, " ")
+		}
+
+		edits, err := bulkEdits(changes)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, changes, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tName\tField\tOld\tNew")
+			fmt.Fprintln(w, "--\t----\t-----\t---\t---")
+			for _, c := range changes {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID, c.Name, c.Field, c.Old, c.New)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if dryRun || len(edits) == 0 {
+			log.Printf("%d locations would change; nothing written.", len(edits))
+			return
+		}
+		if err := db.UpdateLocations(ctx, edits); err != nil {
+			log.Fatalf("Bulk edit failed: %v", err)
+		}
+		for _, e := range auditBulkEdits(changes, source) {
+			if err := db.AddAuditEntry(ctx, e); err != nil {
+				log.Printf("Failed to write audit entry: %v", err)
+			}
+		}
+		log.Printf("Updated %d locations.", len(edits))
+	},
+}
+
+// bulkChange is one field of one location changing, as previewed.
+type bulkChange struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// parseAssignments parses field=value pairs. Filters may also use id.
+func parseAssignments(pairs []string, filter bool) (map[string]string, error) {
+	out := map[string]string{}
+	for _, p := range pairs {
+		field, value, ok := strings.Cut(p, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("%q: want field=value", p)
+		}
+		switch {
+		case filter:
+			if field != "id" && !slices.Contains(database.EditableFields, field) {
+				return nil, fmt.Errorf("can't filter on %q (use id or: %s)", field, strings.Join(database.EditableFields, ", "))
+			}
+		default:
+			if _, err := database.ParseEditValue(field, value); err != nil {
+				return nil, err
+			}
+		}
+		out[field] = value
+	}
+	return out, nil
+}
+
+// planBulkEdit returns the changes setting set on every location matching all
+// of where. Fields already at their new value are left out.
+func planBulkEdit(locs []database.Location, where, set map[string]string) []bulkChange {
+	var changes []bulkChange
+	for i := range locs {
+		loc := &locs[i]
+		match := true
+		for field, want := range where {
+			if got, _ := loc.Field(field); got != want {
+				match = false
+				break
+			}
+		}
+		if match {
+			changes = append(changes, changesFor(loc, set)...)
+		}
+	}
+	return changes
+}
+
+// planCSVEdits returns the changes for rows (id -> field -> value). Unknown
+// IDs are an error, so a typo doesn't go unnoticed.
+func planCSVEdits(locs []database.Location, rows map[string]map[string]string) ([]bulkChange, error) {
+	byID := make(map[string]*database.Location, len(locs))
+	for i := range locs {
+		byID[locs[i].ID] = &locs[i]
+	}
+	ids := make([]string, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var missing []string
+	var changes []bulkChange
+	for _, id := range ids {
+		loc, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		changes = append(changes, changesFor(loc, rows[id])...)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("unknown location IDs in CSV: %s", strings.Join(missing, ", "))
+	}
+	return changes, nil
+}
+
+func changesFor(loc *database.Location, set map[string]string) []bulkChange {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var changes []bulkChange
+	for _, field := range fields {
+		old, _ := loc.Field(field)
+		if old == set[field] {
+			continue
+		}
+		changes = append(changes, bulkChange{ID: loc.ID, Name: loc.Name, Field: field, Old: old, New: set[field]})
+	}
+	return changes
+}
+
+// readEditsCSV reads a CSV whose header is id followed by editable fields.
+func readEditsCSV(r io.Reader) (map[string]map[string]string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || strings.TrimSpace(records[0][0]) != "id" {
+		return nil, fmt.Errorf("the first column must be id")
+	}
+	header := records[0]
+	for _, field := range header[1:] {
+		if !slices.Contains(database.EditableFields, strings.TrimSpace(field)) {
+			return nil, fmt.Errorf("column %q is not an editable field (editable: %s)", field, strings.Join(database.EditableFields, ", "))
+		}
+	}
+
+	rows := map[string]map[string]string{}
+	for n, rec := range records[1:] {
+		id := strings.TrimSpace(rec[0])
+		if id == "" {
+			return nil, fmt.Errorf("line %d: empty id", n+2)
+		}
+		set := map[string]string{}
+		for i, value := range rec[1:] {
+			if value = strings.TrimSpace(value); value != "" {
+				set[strings.TrimSpace(header[i+1])] = value
+			}
+		}
+		if len(set) > 0 {
+			rows[id] = set
+		}
+	}
+	return rows, nil
+}
+
+// bulkEdits groups changes per location, converting values to their stored types.
+func bulkEdits(changes []bulkChange) ([]database.LocationEdit, error) {
+	var edits []database.LocationEdit
+	index := map[string]int{}
+	for _, c := range changes {
+		value, err := database.ParseEditValue(c.Field, c.New)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		i, ok := index[c.ID]
+		if !ok {
+			i = len(edits)
+			index[c.ID] = i
+			edits = append(edits, database.LocationEdit{ID: c.ID, Fields: map[string]any{}})
+		}
+		edits[i].Fields[c.Field] = value
+	}
+	return edits, nil
+}
+
+// auditBulkEdits returns one audit entry per edited location.
+func auditBulkEdits(changes []bulkChange, source string) []database.AuditEntry {
+	var entries []database.AuditEntry
+	index := map[string]int{}
+	for _, c := range changes {
+		diff := fmt.Sprintf("%s: %q -> %q", c.Field, c.Old, c.New)
+		if i, ok := index[c.ID]; ok {
+			entries[i].Reason += "; " + diff
+			continue
+		}
+		index[c.ID] = len(entries)
+		entries = append(entries, database.AuditEntry{Event: "location_edited", Query: source, Location: c.ID, Reason: diff})
+	}
+	return entries
+}
+
+func init() {
+	adminCmd.AddCommand(bulkEditCmd)
+
+	bulkEditCmd.Flags().StringArray("filter", nil, "Only edit locations where field=value (repeatable, all must match; id is allowed)")
+	bulkEditCmd.Flags().StringArray("set", nil, "field=value to set (repeatable)")
+	bulkEditCmd.Flags().String("from-csv", "", "CSV of edits: an id column, then one column per field")
+	bulkEditCmd.Flags().Bool("dry-run", false, "Preview the affected documents without writing")
+	bulkEditCmd.RegisterFlagCompletionFunc("set", completeEditableFields)
+	bulkEditCmd.RegisterFlagCompletionFunc("filter", completeEditableFields)
+	addOutputFlag(bulkEditCmd)
+}
+
+func completeEditableFields(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var out []string
+	for _, f := range database.EditableFields {
+		out = append(out, f+"=")
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
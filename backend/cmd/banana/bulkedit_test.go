@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+func TestPlanBulkEdit(t *testing.T) {
+	locs := []database.Location{
+		{ID: "paris", Name: "Paris", Category: "General", IsPreset: true},
+		{ID: "tokyo", Name: "Tokyo", Category: "General"},
+		{ID: "oslo", Name: "Oslo", Category: "Nordic", IsPreset: true},
+	}
+	where, err := parseAssignments([]string{"category=General", "is_preset=true"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := parseAssignments([]string{"category=Cities", "status=archived"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := planBulkEdit(locs, where, set)
+	if len(changes) != 2 || changes[0].ID != "paris" || changes[0].Field != "category" || changes[0].Old != "General" || changes[1].Field != "status" {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+
+	edits, err := bulkEdits(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].Fields["status"] != database.StatusArchived || edits[0].Fields["category"] != "Cities" {
+		t.Errorf("Expected one typed edit for paris, got %+v", edits)
+	}
+
+	entries := auditBulkEdits(changes, "filter category=General")
+	if len(entries) != 1 || entries[0].Location != "paris" || !strings.Contains(entries[0].Reason, `category: "General" -> "Cities"`) {
+		t.Errorf("Unexpected audit entries %+v", entries)
+	}
+}
+
+func TestParseAssignments_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		pair   string
+		filter bool
+	}{
+		{"category", false},
+		{"image_url=x.png", false},
+		{"status=gone", false},
+		{"is_preset=maybe", false},
+		{"id=paris", false},
+		{"feedback_score=1", true},
+	} {
+		if _, err := parseAssignments([]string{tt.pair}, tt.filter); err == nil {
+			t.Errorf("Expected %q (filter %v) to be rejected", tt.pair, tt.filter)
+		}
+	}
+}
+
+func TestCSVEdits(t *testing.T) {
+	rows, err := readEditsCSV(strings.NewReader("id,category,name\nparis,Cities,\ntokyo,,Tōkyō\nlima,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows["paris"]["category"] != "Cities" || len(rows["paris"]) != 1 || rows["tokyo"]["name"] != "Tōkyō" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	locs := []database.Location{{ID: "paris", Category: "General"}, {ID: "tokyo", Name: "Tokyo"}}
+	changes, err := planCSVEdits(locs, rows)
+	if err != nil || len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %+v, %v", changes, err)
+	}
+
+	rows["atlantis"] = map[string]string{"category": "Myths"}
+	if _, err := planCSVEdits(locs, rows); err == nil || !strings.Contains(err.Error(), "atlantis") {
+		t.Errorf("Expected unknown IDs to be reported, got %v", err)
+	}
+
+	if _, err := readEditsCSV(strings.NewReader("id,image_url\nparis,x.png\n")); err == nil {
+		t.Error("Expected a non-editable column to be rejected")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"text/tabwriter"
+
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var candidatesCmd = &cobra.Command{
+	Use:   "candidates",
+	Short: "Review the candidate images of a location",
+	Long: `List a location's candidate images (preset refreshes staged for approval and recorded
+comparisons), or approve or reject a pending one. Approving animates the candidate and swaps
+it in as the live media; the live media is only replaced once the video is ready. Remotely,
+GET /api/admin/locations/{id}/candidates/{cid}/preview.png shows the live image and the
+candidate side by side.`,
+	Example: `  banana admin candidates --id tokyo
+  banana admin candidates --id tokyo --approve 12
+  banana admin candidates --id tokyo --reject 12`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		approve, _ := cmd.Flags().GetString("approve")
+		reject, _ := cmd.Flags().GetString("reject")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+		if approve != "" && reject != "" {
+			log.Fatal("use only one of --approve or --reject")
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		switch {
+		case approve != "":
+			log.Printf("Approving candidate %s of %s (generating its video)...", approve, id)
+			loc, err := backend.ApproveCandidate(ctx, id, approve)
+			if err != nil {
+				log.Fatalf("Approve failed: %v", err)
+			}
+			log.Printf("Approved; %s now shows %s", id, loc.ImageURL)
+		case reject != "":
+			if err := backend.RejectCandidate(ctx, id, reject); err != nil {
+				log.Fatalf("Reject failed: %v", err)
+			}
+			log.Printf("Rejected candidate %s; the live media of %s is unchanged.", reject, id)
+		default:
+			cands, err := backend.ListCandidates(ctx, id)
+			if err != nil {
+				log.Fatalf("Failed to list candidates: %v", err)
+			}
+			output, _ := cmd.Flags().GetString("output")
+			err = writeOutput(output, cands, func(out io.Writer) {
+				w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tSource\tStatus\tStyle\tCreated\tImage")
+				fmt.Fprintln(w, "--\t------\t------\t-----\t-------\t-----")
+				for _, c := range cands {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Source, candidateStatus(c), c.Style, c.CreatedAt.Format("2006-01-02 15:04"), c.ImageURL)
+				}
+				w.Flush()
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// candidateStatus labels a candidate's review status; comparisons recorded
+// before review existed have none.
+func candidateStatus(c database.Candidate) string {
+	if c.Status == "" {
+		return "-"
+	}
+	return c.Status
+}
+
+func (l *localAdmin) ApproveCandidate(ctx context.Context, locationID, candidateID string) (*database.Location, error) {
+	svc, err := l.weatherService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := svc.ApproveCandidate(ctx, locationID, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	l.refreshed(ctx, loc)
+	return loc, nil
+}
+
+func (l *localAdmin) RejectCandidate(ctx context.Context, locationID, candidateID string) error {
+	svc, err := l.weatherService(ctx)
+	if err != nil {
+		return err
+	}
+	return svc.RejectCandidate(ctx, locationID, candidateID)
+}
+
+func (c *remoteClient) ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error) {
+	var cands []database.Candidate
+	if err := c.do(ctx, http.MethodGet, "/locations/"+url.PathEscape(locationID)+"/candidates", nil, &cands); err != nil {
+		return nil, err
+	}
+	return cands, nil
+}
+
+func (c *remoteClient) ApproveCandidate(ctx context.Context, locationID, candidateID string) (*database.Location, error) {
+	var loc database.Location
+	path := "/locations/" + url.PathEscape(locationID) + "/candidates/" + url.PathEscape(candidateID) + "/approve"
+	if err := c.do(ctx, http.MethodPost, path, nil, &loc); err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+func (c *remoteClient) RejectCandidate(ctx context.Context, locationID, candidateID string) error {
+	path := "/locations/" + url.PathEscape(locationID) + "/candidates/" + url.PathEscape(candidateID) + "/reject"
+	return c.do(ctx, http.MethodPost, path, nil, nil)
+}
+
+func init() {
+	adminCmd.AddCommand(candidatesCmd)
+	supportsRemote(candidatesCmd)
+	candidatesCmd.Flags().String("id", "", "Location ID")
+	candidatesCmd.Flags().String("approve", "", "Candidate ID to approve, swapping it in as the live media")
+	candidatesCmd.Flags().String("reject", "", "Candidate ID to reject")
+	addOutputFlag(candidatesCmd)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"banana-weather/internal/cards"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/query"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Generate a city in several styles side by side",
+	Long: `Generates one image of the city per style, concurrently and with the same seed, and writes
+each to --out as <id>_<style>.png along with contact_sheet.png showing them side by side, for
+prompt and style tuning. No video is generated and the location's media is left alone; with
+--record the images are also uploaded and stored as candidates of the location.`,
+	Example: `  banana admin compare --city "Istanbul" --styles classic,drink --out ./compare/
+  banana admin compare --city "Lagos" --seed 42 --record`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cityFlag, _ := cmd.Flags().GetString("city")
+		stylesStr, _ := cmd.Flags().GetString("styles")
+		outDir, _ := cmd.Flags().GetString("out")
+		record, _ := cmd.Flags().GetBool("record")
+		id, _ := cmd.Flags().GetString("id")
+		city, err := query.Normalize(cityFlag)
+		if err != nil {
+			log.Fatalf("Invalid city: %v", err)
+		}
+		if city == "" {
+			log.Fatal("city is required (use --city)")
+		}
+		styles, err := parseStyles(stylesStr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req := weather.CompareRequest{City: city, LocationID: id, Styles: styles, Record: record}
+		if cmd.Flags().Changed("seed") {
+			v, _ := cmd.Flags().GetInt32("seed")
+			req.Seed = &v
+		}
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		log.Printf("Generating %s in %d styles (seed %s)...", city, len(styles), seedLabel(req.Seed))
+		comparison, err := backend.Compare(ctx, req)
+		if err != nil {
+			log.Fatalf("Compare failed: %v", err)
+		}
+		files, err := writeComparison(outDir, comparison)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for i := range comparison.Results {
+			comparison.Results[i].ImageBase64 = "" // Written to files
+		}
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, comparison, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Style\tFile\tCandidate\tError")
+			fmt.Fprintln(w, "-----\t----\t---------\t-----")
+			for i, r := range comparison.Results {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Style, files[i], r.Candidate, r.Error)
+			}
+			w.Flush()
+			fmt.Fprintf(out, "Seed %d. Contact sheet: %s\n", comparison.Seed, filepath.Join(outDir, "contact_sheet.png"))
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// parseStyles parses a comma-separated list of styles (see genai.ParseStyle),
+// skipping repeats.
+func parseStyles(s string) ([]int, error) {
+	var styles []int
+	seen := map[int]bool{}
+	for _, name := range strings.Split(s, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		style, err := genai.ParseStyle(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[style] {
+			seen[style] = true
+			styles = append(styles, style)
+		}
+	}
+	if len(styles) == 0 {
+		return nil, fmt.Errorf("at least one style is required (use --styles)")
+	}
+	return styles, nil
+}
+
+func seedLabel(seed *int32) string {
+	if seed == nil {
+		return "random"
+	}
+	return fmt.Sprint(*seed)
+}
+
+// writeComparison writes each result's image to dir as <id>_<style>.png and
+// all of them, labelled, to contact_sheet.png. It returns the image files in
+// result order; a failed style has none and a blank tile on the sheet.
+func writeComparison(dir string, c *weather.Comparison) ([]string, error) {
+	files := make([]string, len(c.Results))
+	panels := make([]cards.Panel, len(c.Results))
+	for i, r := range c.Results {
+		panels[i].Label = r.Style
+		if r.ImageBase64 == "" {
+			panels[i].Label += " (failed)"
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(r.ImageBase64)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s image: %w", r.Style, err)
+		}
+		files[i] = filepath.Join(dir, fmt.Sprintf("%s_%s.png", c.LocationID, r.Style))
+		if err := os.WriteFile(files[i], data, 0o644); err != nil {
+			return nil, err
+		}
+		if panels[i].Image, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+			log.Printf("Warning: %s image can't be decoded for the contact sheet: %v", r.Style, err)
+		}
+	}
+	sheet, err := cards.ContactSheet(panels)
+	if err != nil {
+		return nil, err
+	}
+	return files, os.WriteFile(filepath.Join(dir, "contact_sheet.png"), sheet, 0o644)
+}
+
+func (l *localAdmin) Compare(ctx context.Context, req weather.CompareRequest) (*weather.Comparison, error) {
+	svc, err := l.weatherService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Compare(ctx, req)
+}
+
+func (c *remoteClient) Compare(ctx context.Context, req weather.CompareRequest) (*weather.Comparison, error) {
+	styles := make([]string, len(req.Styles))
+	for i, s := range req.Styles {
+		styles[i] = genai.StyleName(s)
+	}
+	body := map[string]any{"city": req.City, "styles": styles, "id": req.LocationID, "record": req.Record}
+	if req.Seed != nil {
+		body["seed"] = *req.Seed
+	}
+	var out weather.Comparison
+	if err := c.do(ctx, http.MethodPost, "/compare", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func init() {
+	adminCmd.AddCommand(compareCmd)
+	supportsRemote(compareCmd)
+	compareCmd.Flags().String("city", "", "City to generate, as typed in the web app")
+	compareCmd.Flags().String("styles", "classic,drink", "Comma-separated styles: random, classic, drink (or 0, 1, 2)")
+	compareCmd.Flags().String("out", "./compare", "Directory the images and contact sheet are written to")
+	compareCmd.Flags().Int32("seed", 0, "Generation seed shared by every style (default: random)")
+	compareCmd.Flags().Bool("record", false, "Also upload the images and record them as candidates of the location")
+	compareCmd.Flags().String("id", "", "Location ID the candidates are recorded under (default: derived from the city)")
+	addOutputFlag(compareCmd)
+}
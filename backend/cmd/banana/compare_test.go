@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"banana-weather/internal/weather"
+)
+
+func TestParseStyles(t *testing.T) {
+	if styles, err := parseStyles("classic, drink,1,"); err != nil || !slices.Equal(styles, []int{1, 2}) {
+		t.Errorf("parseStyles = %v, %v", styles, err)
+	}
+	if _, err := parseStyles("classic,snowglobe"); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+	if _, err := parseStyles(" , "); err == nil {
+		t.Error("Expected an error without styles")
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	img := base64.StdEncoding.EncodeToString(buf.Bytes())
+	dir := t.TempDir()
+	files, err := writeComparison(dir, &weather.Comparison{LocationID: "istanbul", Results: []weather.CompareResult{
+		{Style: "classic", ImageBase64: img},
+		{Style: "drink", Error: "image gen failed"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(files, []string{filepath.Join(dir, "istanbul_classic.png"), ""}) {
+		t.Errorf("Unexpected files: %v", files)
+	}
+	for _, name := range []string{"istanbul_classic.png", "contact_sheet.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
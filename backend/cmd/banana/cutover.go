@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var cutoverCmd = &cobra.Command{
+	Use:   "cutover",
+	Short: "Copy locations into a new collection and verify it for cutover",
+	Long: `Copy the locations collection (FIRESTORE_LOCATIONS_COLLECTION) into the --to collection,
+rewriting each document with the current schema, and verify that both match. Documents only in
+--to are deleted. Run it while the servers run in shadow mode (FIRESTORE_SHADOW_COLLECTION set to
+the --to collection), which keeps the copy current: rerun until it reports no differences, then
+switch FIRESTORE_LOCATIONS_COLLECTION to the new collection and FIRESTORE_SHADOW_COLLECTION to the
+old one, which stays current for a rollback until shadow mode is turned off.
+The feedback and reports subcollections aren't copied; the counters on the locations are.`,
+	Example: `  banana migrate cutover --to locations_v2 --dry-run
+  banana migrate cutover --to locations_v2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		to, _ := cmd.Flags().GetString("to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if to == "" {
+			log.Fatal("target collection is required (use --to)")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+		if cfg.DBBackend != "firestore" {
+			log.Fatal("cutover requires DB_BACKEND=firestore")
+		}
+		if to == cfg.LocationsCollection {
+			log.Fatalf("%s is already the locations collection", to)
+		}
+
+		src, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer src.Close()
+		src.SetLocationsCollection(cfg.LocationsCollection)
+		dst, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer dst.Close()
+		dst.SetLocationsCollection(to)
+
+		if cfg.ShadowCollection != to {
+			log.Printf("Warning: FIRESTORE_SHADOW_COLLECTION isn't %s; writes during and after the copy won't reach it.", to)
+		}
+
+		diffs, err := syncCollection(ctx, src, dst, dryRun)
+		if err != nil {
+			log.Fatalf("Copy failed: %v", err)
+		}
+		for _, d := range diffs {
+			log.Printf("  %s", d)
+		}
+		if dryRun {
+			log.Printf("Dry run: %d locations would be copied or deleted.", len(diffs))
+			return
+		}
+		log.Printf("Copied or deleted %d locations.", len(diffs))
+
+		remaining, err := verifyCollection(ctx, src, dst)
+		if err != nil {
+			log.Fatalf("Verify failed: %v", err)
+		}
+		if len(remaining) > 0 {
+			for _, d := range remaining {
+				log.Printf("  %s", d)
+			}
+			log.Fatalf("%d locations changed during the copy; run cutover again.", len(remaining))
+		}
+
+		entry := database.AuditEntry{Event: "collection_cutover", Reason: fmt.Sprintf("%s verified against %s", to, cfg.LocationsCollection), CreatedAt: time.Now()}
+		if err := src.AddAuditEntry(ctx, entry); err != nil {
+			log.Printf("Failed to write audit entry: %v", err)
+		}
+		log.Printf("%s matches %s. To cut over, deploy with:", to, cfg.LocationsCollection)
+		log.Printf("  FIRESTORE_LOCATIONS_COLLECTION=%s FIRESTORE_SHADOW_COLLECTION=%s", to, cfg.LocationsCollection)
+	},
+}
+
+// syncCollection makes dst match src: locations missing or different in dst
+// are copied as they are in src, and those only in dst are deleted. It
+// returns the differences it found (and, unless dryRun, fixed).
+func syncCollection(ctx context.Context, src repo.LocationStore, dst repo.ShadowStore, dryRun bool) ([]repo.ShadowDiff, error) {
+	srcLocs, err := src.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source locations: %w", err)
+	}
+	dstLocs, err := dst.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target locations: %w", err)
+	}
+	diffs := repo.CompareLocations(srcLocs, dstLocs)
+	if dryRun {
+		return diffs, nil
+	}
+
+	byID := make(map[string]database.Location, len(srcLocs))
+	for _, l := range srcLocs {
+		byID[l.ID] = l
+	}
+	for _, d := range diffs {
+		if d.Extra {
+			err = dst.DeleteLocation(ctx, d.ID)
+		} else {
+			err = dst.PutLocation(ctx, byID[d.ID])
+		}
+		if err != nil {
+			return diffs, fmt.Errorf("%s: %w", d.ID, err)
+		}
+	}
+	return diffs, nil
+}
+
+// verifyCollection returns how dst still differs from src.
+func verifyCollection(ctx context.Context, src, dst repo.LocationStore) ([]repo.ShadowDiff, error) {
+	srcLocs, err := src.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	dstLocs, err := dst.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return repo.CompareLocations(srcLocs, dstLocs), nil
+}
+
+func init() {
+	migrateCmd.AddCommand(cutoverCmd)
+	cutoverCmd.Flags().String("to", "", "Collection to copy locations into (e.g. locations_v2)")
+	cutoverCmd.Flags().Bool("dry-run", false, "Only report the differences")
+}
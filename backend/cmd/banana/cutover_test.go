@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+)
+
+func TestSyncCollection(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	src, dst := mock.NewDB(), mock.NewDB()
+	src.PutLocation(ctx, database.Location{ID: "paris", Name: "Paris", LastUpdated: updated})
+	src.PutLocation(ctx, database.Location{ID: "tokyo", Name: "Tokyo", Mood: "rain", LastUpdated: updated})
+	dst.PutLocation(ctx, database.Location{ID: "tokyo", Name: "Tokyo", LastUpdated: updated})
+	dst.PutLocation(ctx, database.Location{ID: "oslo", Name: "Oslo", LastUpdated: updated})
+
+	diffs, err := syncCollection(ctx, src, dst, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("Expected 3 differences, got %v", diffs)
+	}
+	if remaining, _ := verifyCollection(ctx, src, dst); len(remaining) != 3 {
+		t.Errorf("Expected a dry run to change nothing, got %v", remaining)
+	}
+
+	if _, err := syncCollection(ctx, src, dst, false); err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := verifyCollection(ctx, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected the collections to match, got %v", remaining)
+	}
+	if loc, _ := dst.GetLocation(ctx, "paris"); !loc.LastUpdated.Equal(updated) {
+		t.Errorf("Expected last_updated kept, got %v", loc.LastUpdated)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/query"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Reproduce server behavior from the command line",
+}
+
+var debugFlowCmd = &cobra.Command{
+	Use:   "flow",
+	Short: "Run the web flow (GET /api/weather) and print its events",
+	Long: `Runs GetWeatherFlow, the code path behind GET /api/weather, with the services configured
+from the environment like the server's, and prints the events a browser would be sent as they
+arrive, then how long each stage took. It writes to the database and buckets like a real request:
+a fresh cached location is served from the cache, otherwise the location is regenerated.
+Without --emit-events only the flow ID, status and error events are printed.`,
+	Example: `  banana debug flow --city "Lagos" --style classic --emit-events
+  banana debug flow --lat 6.45 --lng 3.39 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cityFlag, _ := cmd.Flags().GetString("city")
+		lat, _ := cmd.Flags().GetString("lat")
+		lng, _ := cmd.Flags().GetString("lng")
+		styleStr, _ := cmd.Flags().GetString("style")
+		groundingStr, _ := cmd.Flags().GetString("grounding")
+		emit, _ := cmd.Flags().GetBool("emit-events")
+		output, _ := cmd.Flags().GetString("output")
+		if cityFlag == "" && (lat == "" || lng == "") {
+			log.Fatal("a city or coordinates are required (use --city, or --lat and --lng)")
+		}
+		city, err := query.Normalize(cityFlag)
+		if err != nil {
+			log.Fatalf("Invalid city: %v", err)
+		}
+		style, err := genai.ParseStyle(styleStr)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := weather.WithStyle(context.Background(), style)
+		if groundingStr != "" {
+			mode, err := genai.ParseGroundingMode(groundingStr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			ctx = genai.WithGrounding(ctx, mode)
+		}
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+		svc, err := (&localAdmin{Repository: db, cfg: cfg}).weatherService(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var live io.Writer = os.Stdout
+		if output != "table" {
+			live = io.Discard // Only the report
+		}
+		rec := newFlowRecorder(city, lat, lng, live, emit)
+		flowErr := svc.GetWeatherFlow(ctx, city, lat, lng, rec.Send)
+		report := rec.Finish(flowErr)
+
+		err = writeOutput(output, report, func(out io.Writer) {
+			fmt.Fprintln(out)
+			printFlowStages(out, report)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if flowErr != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+// FlowReport is the outcome of banana debug flow: the events sent, as a
+// flow trace, and when each stage ended.
+type FlowReport struct {
+	Trace  database.FlowTrace `json:"trace"`
+	Stages []FlowStage        `json:"stages"`
+}
+
+// FlowStage is a stage of the web flow, ended by its first event.
+type FlowStage struct {
+	Name   string `json:"name"`
+	Event  string `json:"event"`
+	AtMS   int64  `json:"at_ms"`   // Since the flow started
+	TookMS int64  `json:"took_ms"` // Since the previous stage ended
+}
+
+// flowStages maps the events that end a stage of the web flow to the stage's
+// name. The caption stage runs alongside the image stage.
+var flowStages = map[string]string{
+	"forecast": "weather",
+	"caption":  "caption",
+	"result":   "image",
+	"alt_text": "alt text",
+	"video":    "video",
+	"poster":   "poster",
+	"stream":   "stream",
+	"error":    "error",
+}
+
+// flowRecorder records the events of a flow as a trace, under the flow's ID,
+// printing them as they arrive: all of them when emit is set, otherwise the
+// flow ID, status and errors.
+type flowRecorder struct {
+	trace database.FlowTrace
+	out   io.Writer
+	emit  bool
+	now   func() time.Time
+}
+
+func newFlowRecorder(city, lat, lng string, out io.Writer, emit bool) *flowRecorder {
+	return &flowRecorder{
+		trace: database.FlowTrace{Query: city, Lat: lat, Lng: lng, StartedAt: time.Now()},
+		out:   out,
+		emit:  emit,
+		now:   time.Now,
+	}
+}
+
+// Send is the flow's weather.StatusCallback.
+func (r *flowRecorder) Send(event, data string) {
+	at := r.now()
+	r.trace.Add(event, data, at)
+	if event == "flow" {
+		r.trace.ID = data
+	}
+	if !r.emit && event != "flow" && event != "status" && event != "error" {
+		return
+	}
+	e := r.trace.Events[len(r.trace.Events)-1]
+	fmt.Fprintf(r.out, "%s %+8.1fs  %-8s %s\n", at.Format("15:04:05.000"), float64(e.OffsetMS)/1000, event, traceData(e))
+}
+
+// Finish ends the trace and returns the report.
+func (r *flowRecorder) Finish(flowErr error) FlowReport {
+	r.trace.FinishedAt = r.now()
+	if flowErr != nil {
+		r.trace.Error = flowErr.Error()
+	}
+	return FlowReport{Trace: r.trace, Stages: stagesOf(r.trace.Events)}
+}
+
+// stagesOf returns the stages ended by events, in order; a stage whose
+// event is sent again (e.g. "video" on a cache hit) counts once.
+func stagesOf(events []database.FlowEvent) []FlowStage {
+	var stages []FlowStage
+	seen := map[string]bool{}
+	var last int64
+	for _, e := range events {
+		name, ok := flowStages[e.Event]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		stages = append(stages, FlowStage{Name: name, Event: e.Event, AtMS: e.OffsetMS, TookMS: e.OffsetMS - last})
+		last = e.OffsetMS
+	}
+	return stages
+}
+
+func printFlowStages(out io.Writer, report FlowReport) {
+	t := report.Trace
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Stage\tEvent\tAt\tTook")
+	fmt.Fprintln(w, "-----\t-----\t--\t----")
+	for _, s := range report.Stages {
+		fmt.Fprintf(w, "%s\t%s\t%.1fs\t%.1fs\n", s.Name, s.Event, float64(s.AtMS)/1000, float64(s.TookMS)/1000)
+	}
+	w.Flush()
+	if t.ID != "" {
+		fmt.Fprintf(out, "Flow %s finished", t.ID)
+	} else {
+		fmt.Fprint(out, "Finished")
+	}
+	fmt.Fprintf(out, " in %.1fs", t.FinishedAt.Sub(t.StartedAt).Seconds())
+	if t.Error != "" {
+		fmt.Fprintf(out, ": %s", t.Error)
+	}
+	fmt.Fprintln(out)
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugFlowCmd)
+	debugFlowCmd.Flags().String("city", "", "Place to look up, as typed in the web app")
+	debugFlowCmd.Flags().String("lat", "", "Latitude, for a lookup by coordinates")
+	debugFlowCmd.Flags().String("lng", "", "Longitude, for a lookup by coordinates")
+	debugFlowCmd.Flags().String("style", "random", "Prompt Style: random, classic, drink (or 0, 1, 2)")
+	debugFlowCmd.Flags().String("grounding", "", "Grounding mode, like ?grounding= (default GROUNDING_MODE)")
+	debugFlowCmd.Flags().Bool("emit-events", false, "Print every event, not only status and errors")
+	addOutputFlag(debugFlowCmd)
+}
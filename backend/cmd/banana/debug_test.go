@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlowRecorder(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	rec := newFlowRecorder("Lagos", "", "", &out, false)
+	rec.trace.StartedAt = start
+	at := start
+	send := func(after time.Duration, event, data string) {
+		at = at.Add(after)
+		rec.now = func() time.Time { return at }
+		rec.Send(event, data)
+	}
+
+	send(0, "flow", "f00d")
+	send(500*time.Millisecond, "status", "Getting a banana image of the weather for Lagos, Nigeria...")
+	send(time.Second, "forecast", `{"temp":29}`)
+	send(2*time.Second, "caption", "Humid and bright")
+	send(8*time.Second, "result", `{"image_base64":"..."}`)
+	send(time.Second, "status", "Animating (Veo 3.1)... this may take a minute.")
+	send(40*time.Second, "video", "https://example.com/lagos.mp4")
+	send(time.Second, "error", "Failed to extract poster")
+	report := rec.Finish(errors.New("poster failed"))
+
+	if lines := strings.Count(out.String(), "\n"); lines != 4 {
+		t.Errorf("Expected only the flow ID, status and error events printed, got:\n%s", out.String())
+	}
+	want := []FlowStage{
+		{Name: "weather", Event: "forecast", AtMS: 1500, TookMS: 1500},
+		{Name: "caption", Event: "caption", AtMS: 3500, TookMS: 2000},
+		{Name: "image", Event: "result", AtMS: 11500, TookMS: 8000},
+		{Name: "video", Event: "video", AtMS: 52500, TookMS: 41000},
+		{Name: "error", Event: "error", AtMS: 53500, TookMS: 1000},
+	}
+	if len(report.Stages) != len(want) {
+		t.Fatalf("Expected %d stages, got %+v", len(want), report.Stages)
+	}
+	for i := range want {
+		if report.Stages[i] != want[i] {
+			t.Errorf("Stage %d = %+v, want %+v", i, report.Stages[i], want[i])
+		}
+	}
+	if report.Trace.ID != "f00d" || report.Trace.Error != "poster failed" || len(report.Trace.Events) != 8 {
+		t.Errorf("Unexpected trace %+v", report.Trace)
+	}
+
+	var table bytes.Buffer
+	printFlowStages(&table, report)
+	if !strings.Contains(table.String(), "Flow f00d finished in 53.5s: poster failed") {
+		t.Errorf("Unexpected summary:\n%s", table.String())
+	}
+}
+
+func TestFlowRecorder_EmitEvents(t *testing.T) {
+	var out bytes.Buffer
+	rec := newFlowRecorder("Lagos", "", "", &out, true)
+	rec.Send("forecast", `{"temp":29}`)
+	rec.Send("result", strings.Repeat("x", 500))
+	if !strings.Contains(out.String(), "forecast") || !strings.Contains(out.String(), "(500 bytes)") {
+		t.Errorf("Expected every event, shortened, got:\n%s", out.String())
+	}
+}
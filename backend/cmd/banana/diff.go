@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+// Differences reported by diffLocations
+const (
+	diffMissing = "missing"   // In source, not in target
+	diffExtra   = "extra"     // In target only
+	diffImage   = "image_url" // Media URLs differ
+	diffVideo   = "video_url"
+	diffStale   = "stale" // Target was updated before source
+)
+
+// LocationDiff is one difference between the source and target environments.
+type LocationDiff struct {
+	ID            string    `json:"id"`
+	Issue         string    `json:"issue"`
+	Preset        bool      `json:"preset"`
+	Source        string    `json:"source,omitempty"` // The differing value, when there is one
+	Target        string    `json:"target,omitempty"`
+	SourceUpdated time.Time `json:"source_updated,omitzero"`
+	TargetUpdated time.Time `json:"target_updated,omitzero"`
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare locations between two environments",
+	Long: `Compare the locations of two config profiles (.env.<name> files, e.g. .env.prod and .env.staging):
+IDs missing on either side, differing media URLs, and target locations older than the source.
+With --sync-presets, presets that are missing or differ in the target are copied from the source;
+media URLs are copied as-is, so the target serves the source's media.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		source, _ := cmd.Flags().GetString("source")
+		target, _ := cmd.Flags().GetString("target")
+		if source == "" || target == "" {
+			log.Fatal("Error: --source and --target are required")
+		}
+
+		srcDB, closeSrc := openProfile(ctx, source)
+		defer closeSrc()
+		dstDB, closeDst := openProfile(ctx, target)
+		defer closeDst()
+
+		srcLocs, err := srcDB.ListLocations(ctx, database.ListOptions{})
+		if err != nil {
+			log.Fatalf("Failed to list %s locations: %v", source, err)
+		}
+		dstLocs, err := dstDB.ListLocations(ctx, database.ListOptions{})
+		if err != nil {
+			log.Fatalf("Failed to list %s locations: %v", target, err)
+		}
+		diffs := diffLocations(srcLocs, dstLocs)
+
+		if sync, _ := cmd.Flags().GetBool("sync-presets"); sync {
+			n, err := syncPresets(ctx, dstDB, srcLocs, diffs)
+			if err != nil {
+				log.Fatalf("Sync failed after %d presets: %v", n, err)
+			}
+			log.Printf("Synced %d presets from %s to %s.", n, source, target)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, diffs, func(out io.Writer) {
+			if len(diffs) == 0 {
+				fmt.Fprintf(out, "%s and %s match (%d locations).\n", source, target, len(srcLocs))
+				return
+			}
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "ID\tIssue\tPreset\t%s\t%s\n", source, target)
+			fmt.Fprintln(w, "--\t-----\t------\t------\t------")
+			for _, d := range diffs {
+				src, dst := d.Source, d.Target
+				if d.Issue == diffStale {
+					src, dst = d.SourceUpdated.Format(time.RFC3339), d.TargetUpdated.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%v\t%s\t%s\n", d.ID, d.Issue, d.Preset, src, dst)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// openProfile opens the repository configured by a .env.<name> profile.
+func openProfile(ctx context.Context, name string) (repo.Repository, func()) {
+	cfg, err := config.LoadProfile(name)
+	if err != nil {
+		log.Fatalf("Config load failed for %s: %v", name, err)
+	}
+	db, err := repo.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to init DB for %s: %v", name, err)
+	}
+	return db, func() { db.Close() }
+}
+
+// diffLocations compares source and target locations by ID, sorted by ID.
+func diffLocations(src, dst []database.Location) []LocationDiff {
+	byID := make(map[string]database.Location, len(dst))
+	for _, l := range dst {
+		byID[l.ID] = l
+	}
+
+	var diffs []LocationDiff
+	for _, s := range src {
+		d, ok := byID[s.ID]
+		if !ok {
+			diffs = append(diffs, LocationDiff{ID: s.ID, Issue: diffMissing, Preset: s.IsPreset, SourceUpdated: s.LastUpdated})
+			continue
+		}
+		delete(byID, s.ID)
+		if s.ImageURL != d.ImageURL {
+			diffs = append(diffs, LocationDiff{ID: s.ID, Issue: diffImage, Preset: s.IsPreset, Source: s.ImageURL, Target: d.ImageURL})
+		}
+		if s.VideoURL != d.VideoURL {
+			diffs = append(diffs, LocationDiff{ID: s.ID, Issue: diffVideo, Preset: s.IsPreset, Source: s.VideoURL, Target: d.VideoURL})
+		}
+		if d.LastUpdated.Before(s.LastUpdated) {
+			diffs = append(diffs, LocationDiff{ID: s.ID, Issue: diffStale, Preset: s.IsPreset, SourceUpdated: s.LastUpdated, TargetUpdated: d.LastUpdated})
+		}
+	}
+	for _, d := range byID {
+		diffs = append(diffs, LocationDiff{ID: d.ID, Issue: diffExtra, Preset: d.IsPreset, TargetUpdated: d.LastUpdated})
+	}
+
+	slices.SortStableFunc(diffs, func(a, b LocationDiff) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return diffs
+}
+
+// syncPresets upserts the source presets that are missing or differ in the
+// target, returning how many were written. Target-only presets are left alone.
+func syncPresets(ctx context.Context, dst repo.LocationStore, src []database.Location, diffs []LocationDiff) (int, error) {
+	outOfSync := make(map[string]bool)
+	for _, d := range diffs {
+		if d.Preset && d.Issue != diffExtra {
+			outOfSync[d.ID] = true
+		}
+	}
+
+	n := 0
+	for _, l := range src {
+		if !l.IsPreset || !outOfSync[l.ID] {
+			continue
+		}
+		if err := dst.UpsertLocation(ctx, l); err != nil {
+			return n, fmt.Errorf("%s: %w", l.ID, err)
+		}
+		log.Printf("Synced %s", l.ID)
+		n++
+	}
+	return n, nil
+}
+
+func init() {
+	adminCmd.AddCommand(diffCmd)
+	diffCmd.Flags().String("source", "", "Source profile, read from .env.<source> (e.g. prod)")
+	diffCmd.Flags().String("target", "", "Target profile, read from .env.<target> (e.g. staging)")
+	diffCmd.Flags().Bool("sync-presets", false, "Copy presets that are missing or differ from source to target")
+	addOutputFlag(diffCmd)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+// fakeUpserts records UpsertLocation calls.
+type fakeUpserts struct {
+	repo.LocationStore
+	upserted []string
+}
+
+func (f *fakeUpserts) UpsertLocation(ctx context.Context, loc database.Location) error {
+	f.upserted = append(f.upserted, loc.ID)
+	return nil
+}
+
+func TestDiffLocations(t *testing.T) {
+	now := time.Now()
+	src := []database.Location{
+		{ID: "paris", IsPreset: true, ImageURL: "prod/paris.png", LastUpdated: now},
+		{ID: "tokyo", IsPreset: true, ImageURL: "prod/tokyo.png", VideoURL: "prod/tokyo.mp4", LastUpdated: now},
+		{ID: "oslo", ImageURL: "prod/oslo.png", LastUpdated: now},
+		{ID: "lima", IsPreset: true, ImageURL: "lima.png", LastUpdated: now},
+	}
+	dst := []database.Location{
+		{ID: "tokyo", IsPreset: true, ImageURL: "staging/tokyo.png", VideoURL: "prod/tokyo.mp4", LastUpdated: now},
+		{ID: "oslo", ImageURL: "prod/oslo.png", LastUpdated: now.Add(-time.Hour)},
+		{ID: "lima", IsPreset: true, ImageURL: "lima.png", LastUpdated: now},
+		{ID: "demo", IsPreset: true},
+	}
+
+	diffs := diffLocations(src, dst)
+	want := []struct{ id, issue string }{
+		{"demo", diffExtra},
+		{"oslo", diffStale},
+		{"paris", diffMissing},
+		{"tokyo", diffImage},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Expected %d diffs, got %+v", len(want), diffs)
+	}
+	for i, w := range want {
+		if diffs[i].ID != w.id || diffs[i].Issue != w.issue {
+			t.Errorf("diff %d: expected %s %s, got %s %s", i, w.id, w.issue, diffs[i].ID, diffs[i].Issue)
+		}
+	}
+
+	// Only source presets are synced: not the user location, not the target-only demo
+	store := &fakeUpserts{}
+	n, err := syncPresets(context.Background(), store, src, diffs)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 synced presets, got %d (%v)", n, err)
+	}
+	if store.upserted[0] != "paris" || store.upserted[1] != "tokyo" {
+		t.Errorf("Expected paris and tokyo to be synced, got %v", store.upserted)
+	}
+}
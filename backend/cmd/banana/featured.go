@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var cityOfTheDayCmd = &cobra.Command{
+	Use:   "city-of-the-day",
+	Short: "Pick and regenerate the city of the day",
+	Long: `Pick a ready preset not featured in the last CITY_OF_THE_DAY_AVOID_DAYS days (or the one
+given with --id), regenerate it with the classic style, mark it featured and record it in
+the city_of_the_day history served by GET /api/city-of-the-day. Without --id, a day that
+already has a city is left as is, so the command is safe to schedule daily.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		f, err := backend.RunCityOfTheDay(ctx, id)
+		if err != nil {
+			log.Fatalf("City of the day failed: %v", err)
+		}
+		fmt.Printf("City of the day for %s: %s (%s)\n", f.Date, f.Name, f.LocationID)
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(cityOfTheDayCmd)
+	supportsRemote(cityOfTheDayCmd)
+	cityOfTheDayCmd.Flags().String("id", "", "Location to feature (default: picked from the presets)")
+}
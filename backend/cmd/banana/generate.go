@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
-	"banana-weather/pkg/config"
-	"banana-weather/pkg/database"
-	"banana-weather/pkg/genai"
-	"banana-weather/pkg/storage"
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
 
 	"github.com/spf13/cobra"
 )
@@ -20,7 +23,7 @@ import (
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate presets or single locations",
-	Long:  "Generate weather presets from a CSV file or a single location via flags.",
+	Long:  "Generate weather presets from a CSV file, a single location via flags, or a receipt (--from-receipt).",
 	Run:   runGenerate,
 }
 
@@ -29,6 +32,8 @@ func init() {
 
 	generateCmd.Flags().String("csv", "", "Path to CSV file (format: id,name,city,category,context)")
 	generateCmd.Flags().Bool("force", false, "Force overwrite existing presets")
+	generateCmd.Flags().Bool("override-lock", false, "With --force, overwrite locked presets too")
+	generateCmd.Flags().Bool("interactive", false, "Walk through preset creation step by step")
 
 	// Single mode flags
 	generateCmd.Flags().String("city", "", "City name")
@@ -37,11 +42,17 @@ func init() {
 	generateCmd.Flags().String("category", "General", "Category name")
 	generateCmd.Flags().String("id", "", "Unique ID")
 	generateCmd.Flags().Int("style", 0, "Prompt Style: 0=Random, 1=Classic, 2=Drink")
+	generateCmd.Flags().Int32("seed", 0, "Generation seed for reproducible output (default: random)")
+	generateCmd.Flags().String("from-receipt", "", "Regenerate from a receipt saved from GET /api/locations/{id}/receipt (its location, or --id)")
+	generateCmd.Flags().String("priority", "", "Quota priority: interactive, scheduled or batch (default: batch with --csv, else scheduled)")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) {
 	csvPath, _ := cmd.Flags().GetString("csv")
 	force, _ := cmd.Flags().GetBool("force")
+	overrideLock, _ := cmd.Flags().GetBool("override-lock")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	receiptPath, _ := cmd.Flags().GetString("from-receipt")
 	
 	ctx := context.Background()
 
@@ -51,31 +62,67 @@ func runGenerate(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Init Services
+	genaiService, p, dbService, m := openGenerator(ctx, cfg)
+	defer dbService.Close()
+
+	if csvPath != "" && !interactive {
+		ctx = withPriority(ctx, cmd, quota.PriorityBatch)
+	} else {
+		ctx = withPriority(ctx, cmd, quota.PriorityScheduled)
+	}
+
+	if receiptPath != "" {
+		runReceiptMode(ctx, cmd, receiptPath, force, overrideLock, cfg.GeminiImageModel, p, dbService, m)
+	} else if interactive {
+		runInteractiveMode(ctx, force, overrideLock, genaiService, p, dbService, m)
+	} else if csvPath != "" {
+		runBatchMode(ctx, csvPath, force, overrideLock, p, dbService, m)
+	} else {
+		runSingleMode(ctx, cmd, force, overrideLock, p, dbService, m)
+	}
+
+	log.Println("Done.")
+}
+
+// openGenerator initializes the services presets are generated with. The
+// caller closes the repository.
+func openGenerator(ctx context.Context, cfg *config.Config) (*genai.Service, *pipeline.Pipeline, repo.Repository, *maps.Service) {
 	genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
 	if err != nil {
 		log.Fatalf("Failed to init GenAI: %v", err)
 	}
-	storageService, err := storage.NewService(ctx, cfg.BucketName)
+	storageService, err := storage.Open(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to init Storage: %v", err)
 	}
-	dbService, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+	dbService, err := repo.Open(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to init DB: %v", err)
 	}
-	defer dbService.Close()
-
-	if csvPath != "" {
-		runBatchMode(ctx, csvPath, force, genaiService, storageService, dbService)
-	} else {
-		runSingleMode(ctx, cmd, force, genaiService, storageService, dbService)
+	configureGenAI(ctx, cfg, genaiService, dbService, storageService)
+	p := &pipeline.Pipeline{
+		GenAI:      genaiService,
+		Storage:    storageService,
+		Provenance: cfg.Signer(),
+		Hooks:      openHooks(cfg),
+		Events:     openEvents(ctx, cfg),
 	}
-
-	log.Println("Done.")
+	if orig := openOriginals(ctx, cfg); orig != nil {
+		p.Originals = orig
+	}
+	if posters := openPosters(cfg); posters != nil {
+		p.Posters = posters
+	}
+	if streams := openStreams(ctx, cfg); streams != nil {
+		p.Streams = streams
+	}
+	if cfg.AltText {
+		p.Describer = genaiService
+	}
+	return genaiService, p, dbService, openMaps(cfg)
 }
 
-func runBatchMode(ctx context.Context, csvPath string, force bool, gs *genai.Service, ss *storage.Service, db *database.Client) {
+func runBatchMode(ctx context.Context, csvPath string, force, overrideLock bool, p *pipeline.Pipeline, db repo.Repository, m *maps.Service) {
 	log.Printf("Running in Batch Mode from %s (Force: %v)", csvPath, force)
 	f, err := os.Open(csvPath)
 	if err != nil {
@@ -88,7 +135,13 @@ func runBatchMode(ctx context.Context, csvPath string, force bool, gs *genai.Ser
 	if err != nil {
 		log.Fatalf("Failed to read CSV: %v", err)
 	}
+	generatePresets(ctx, records, force, overrideLock, p, db, m)
+}
 
+// generatePresets generates the presets of records, rows of a preset CSV
+// (with its header): id,name,city,category[,context]. Existing presets only
+// get their metadata updated, unless force.
+func generatePresets(ctx context.Context, records [][]string, force, overrideLock bool, p *pipeline.Pipeline, db repo.Repository, m *maps.Service) {
 	for i, row := range records {
 		if i == 0 { continue } // Skip Header
 		if len(row) < 4 { continue }
@@ -104,11 +157,20 @@ func runBatchMode(ctx context.Context, csvPath string, force bool, gs *genai.Ser
 		existing, err := db.GetLocation(ctx, pID)
 		exists := err == nil && existing != nil
 
-		if exists && !force {
+		if pCtx == "" && exists {
+			pCtx = existing.PromptContext
+		}
+
+		if exists && !canOverwrite(existing, force, overrideLock) {
 			log.Printf("Skipping generation for [%s], updating metadata only.", pID)
 			existing.Name = pName
+			existing.PromptContext = pCtx
 			existing.Category = pCat
 			existing.IsPreset = true
+			if existing.Geo == nil {
+				existing.CityQuery = pCity
+				setGeo(ctx, m, existing)
+			}
 			if err := db.UpsertLocation(ctx, *existing); err != nil {
 				log.Printf("Failed to patch %s: %v", pID, err)
 			}
@@ -117,34 +179,52 @@ func runBatchMode(ctx context.Context, csvPath string, force bool, gs *genai.Ser
 
 		log.Printf("Processing [%d/%d]: %s (%s)", i, len(records)-1, pName, pID)
 		// Batch mode defaults to Random (0) unless we add a column later
-		imgURL, vidURL, err := processPreset(ctx, gs, ss, pID, pCity, pCtx, 0)
+		res, err := processPreset(ctx, p, pID, pCity, pCtx, 0, nil)
 		if err != nil {
 			log.Printf("Error processing %s: %v", pID, err)
+			if exists {
+				db.SetStatus(ctx, pID, database.StatusFailed)
+			}
 			continue
 		}
 
 		loc := database.Location{
-			ID:        pID,
-			Name:      pName,
-			Category:  pCat,
-			CityQuery: pCity,
-			ImageURL:  imgURL,
-			VideoURL:  vidURL,
-			IsPreset:  true,
+			ID:            pID,
+			Name:          pName,
+			Category:      pCat,
+			CityQuery:     pCity,
+			ImageURL:      res.ImageURL,
+			VideoURL:      res.VideoURL,
+			PosterURL:     res.PosterURL,
+			StreamURL:     res.StreamURL,
+			Checksums:     res.Checksums,
+			AltText:       res.AltText,
+			PromptContext: pCtx,
+			IsPreset:      true,
+			Seed:          &res.Seed,
+			Generation:    res.Metadata(),
+			Status:        database.StatusReady,
+			Locked:        exists && existing.Locked,
 		}
+		setGeo(ctx, m, &loc)
 		if err := db.UpsertLocation(ctx, loc); err != nil {
 			log.Printf("Failed to save %s: %v", pID, err)
 		}
 	}
 }
 
-func runSingleMode(ctx context.Context, cmd *cobra.Command, force bool, gs *genai.Service, ss *storage.Service, db *database.Client) {
+func runSingleMode(ctx context.Context, cmd *cobra.Command, force, overrideLock bool, p *pipeline.Pipeline, db repo.Repository, m *maps.Service) {
 	city, _ := cmd.Flags().GetString("city")
 	ctxPrompt, _ := cmd.Flags().GetString("context")
 	name, _ := cmd.Flags().GetString("name")
 	category, _ := cmd.Flags().GetString("category")
 	id, _ := cmd.Flags().GetString("id")
 	style, _ := cmd.Flags().GetInt("style")
+	var seed *int32 // Picked by the pipeline unless set
+	if cmd.Flags().Changed("seed") {
+		v, _ := cmd.Flags().GetInt32("seed")
+		seed = &v
+	}
 
 	if city == "" || name == "" || id == "" {
 		fmt.Println("Usage: banana generate [flags]")
@@ -156,69 +236,108 @@ func runSingleMode(ctx context.Context, cmd *cobra.Command, force bool, gs *gena
 		fmt.Println("  --category Grouping category (default: 'General')")
 		fmt.Println("  --context  Visual description for fictional places")
 		fmt.Println("  --style    Prompt Style: 0=Random, 1=Classic, 2=Drink (default: 0)")
+		fmt.Println("  --seed     Generation seed for reproducible output (default: random)")
 		fmt.Println("  --force    Overwrite existing preset media")
+		fmt.Println("  --override-lock  With --force, overwrite locked presets too")
 		fmt.Println("\nOr use batch mode:")
 		fmt.Println("  --csv      Path to CSV file")
+		fmt.Println("\nOr the step-by-step wizard:")
+		fmt.Println("  --interactive")
 		os.Exit(1)
 	}
 
 	existing, err := db.GetLocation(ctx, id)
 	exists := err == nil && existing != nil
 
-	if exists && !force {
+	if ctxPrompt == "" && exists {
+		ctxPrompt = existing.PromptContext
+	}
+
+	if exists && !canOverwrite(existing, force, overrideLock) {
 		log.Printf("Skipping generation for [%s], updating metadata only.", id)
 		existing.Name = name
+		existing.PromptContext = ctxPrompt
 		existing.Category = category
 		existing.IsPreset = true
+		if existing.Geo == nil {
+			existing.CityQuery = city
+			setGeo(ctx, m, existing)
+		}
 		if err := db.UpsertLocation(ctx, *existing); err != nil {
 			log.Fatalf("Failed to patch %s: %v", id, err)
 		}
 	} else {
-		imgURL, vidURL, err := processPreset(ctx, gs, ss, id, city, ctxPrompt, style)
+		res, err := processPreset(ctx, p, id, city, ctxPrompt, style, seed)
 		if err != nil {
+			if exists {
+				db.SetStatus(ctx, id, database.StatusFailed)
+			}
 			log.Fatalf("Error: %v", err)
 		}
 		loc := database.Location{
-			ID:        id,
-			Name:      name,
-			Category:  category,
-			CityQuery: city,
-			ImageURL:  imgURL,
-			VideoURL:  vidURL,
-			IsPreset:  true,
+			ID:            id,
+			Name:          name,
+			Category:      category,
+			CityQuery:     city,
+			ImageURL:      res.ImageURL,
+			VideoURL:      res.VideoURL,
+			PosterURL:     res.PosterURL,
+			StreamURL:     res.StreamURL,
+			Checksums:     res.Checksums,
+			AltText:       res.AltText,
+			PromptContext: ctxPrompt,
+			IsPreset:      true,
+			Seed:          &res.Seed,
+			Generation:    res.Metadata(),
+			Status:        database.StatusReady,
+			Locked:        exists && existing.Locked,
 		}
+		setGeo(ctx, m, &loc)
 		if err := db.UpsertLocation(ctx, loc); err != nil {
 			log.Fatalf("Failed to save: %v", err)
 		}
 	}
 }
 
-func processPreset(ctx context.Context, gs *genai.Service, ss *storage.Service, id, city, promptCtx string, style int) (string, string, error) {
-	// 1. Generate Image
-	log.Printf("Generating image for '%s' (Style: %d)...", city, style)
-	imgBase64, err := gs.GenerateImage(ctx, city, promptCtx, style)
-	if err != nil {
-		return "", "", fmt.Errorf("image gen failed: %w", err)
+// canOverwrite reports whether generate may replace the media of an existing
+// location: only with --force, and for a locked one with --override-lock too.
+func canOverwrite(existing *database.Location, force, overrideLock bool) bool {
+	if existing.Locked && force && !overrideLock {
+		log.Printf("%s is locked; use --override-lock with --force to overwrite its media.", existing.ID)
+		return false
 	}
+	return force
+}
 
-	// 2. Upload Image
-	imgFileName := fmt.Sprintf("preset_%s_image_%d.png", id, time.Now().Unix())
-	gsImageURI, publicImageURL, err := ss.UploadImage(ctx, imgBase64, imgFileName)
-	if err != nil {
-		return "", "", fmt.Errorf("image upload failed: %w", err)
+// setGeo geocodes the city query of loc for the map view and region lists,
+// as `banana migrate --backfill-geo` does. Places that don't geocode to a
+// country, like fictional ones, are left without.
+func setGeo(ctx context.Context, m *maps.Service, loc *database.Location) {
+	if m == nil {
+		return
 	}
-	log.Printf("Image uploaded: %s", publicImageURL)
+	place, err := m.GetCityLocation(ctx, loc.CityQuery)
+	if err != nil || place.CountryCode == "" {
+		log.Printf("No coordinates for %s: no country for %q", loc.ID, loc.CityQuery)
+		return
+	}
+	loc.Geo, loc.CountryCode, loc.Continent = place.LatLng(), place.CountryCode, place.Continent
+}
 
-	// 3. Generate Video
-	log.Printf("Generating video (Veo)...")
-	videoGsURI, err := gs.GenerateVideo(ctx, gsImageURI, "")
+func processPreset(ctx context.Context, p *pipeline.Pipeline, id, city, promptCtx string, style int, seed *int32) (*pipeline.Result, error) {
+	req := pipeline.Request{
+		ID:       id,
+		City:     city,
+		Context:  promptCtx,
+		FileName: fmt.Sprintf("preset_%s_image_%d.png", id, time.Now().Unix()),
+	}
+	opts := []pipeline.Option{pipeline.WithStyle(style)}
+	if seed != nil {
+		opts = append(opts, pipeline.WithSeed(*seed))
+	}
+	res, err := p.Generate(ctx, req, opts...)
 	if err != nil {
-		return "", "", fmt.Errorf("video gen failed: %w", err)
+		return nil, err
 	}
-
-	bucketName := os.Getenv("GENMEDIA_BUCKET")
-	publicVideoURL := strings.Replace(videoGsURI, "gs://"+bucketName, "https://storage.googleapis.com/"+bucketName, 1)
-	log.Printf("Video generated: %s", publicVideoURL)
-
-	return publicImageURL, publicVideoURL, nil
+	return res, nil
 }
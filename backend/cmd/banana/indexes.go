@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var indexesCmd = &cobra.Command{
+	Use:   "indexes",
+	Short: "Manage the Firestore composite indexes the queries need",
+}
+
+var indexesGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write firestore.indexes.json for the queries the code issues",
+	Long:  "Write the required composite indexes in Firebase CLI format. Deploy with `firebase deploy --only firestore:indexes`.",
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString("out")
+
+		data, err := json.MarshalIndent(database.NewIndexFile(), "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode indexes: %v", err)
+		}
+		data = append(data, '\n')
+
+		if out == "-" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", out, err)
+		}
+		log.Printf("Wrote %d indexes to %s", len(database.RequiredIndexes), out)
+	},
+}
+
+var indexesCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Verify the database has every required composite index",
+	Long:  "Compare the required composite indexes with the database, printing gcloud commands for any that are missing. Exits non-zero if any are missing.",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		statuses, err := database.CheckIndexes(ctx, cfg.ProjectID, cfg.DatabaseID)
+		if err != nil {
+			log.Fatalf("Index check failed: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "State\tIndex\tUsed By")
+		fmt.Fprintln(w, "-----\t-----\t-------")
+		var missing []database.IndexStatus
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.State, s.Index, s.Query)
+			if s.State == database.IndexMissing {
+				missing = append(missing, s)
+			}
+		}
+		w.Flush()
+
+		if len(missing) > 0 {
+			fmt.Printf("\n%d missing. Create them with:\n", len(missing))
+			for _, s := range missing {
+				fmt.Println("  " + s.GcloudCommand(cfg.DatabaseID))
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(indexesCmd)
+	indexesCmd.AddCommand(indexesGenerateCmd)
+	indexesCmd.AddCommand(indexesCheckCmd)
+
+	indexesGenerateCmd.Flags().String("out", "firestore.indexes.json", "Output file ('-' for stdout)")
+}
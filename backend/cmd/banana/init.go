@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+// bundles are curated preset sets for new deployments, in the preset CSV
+// format of generate --csv.
+//
+//go:embed bundles/*.csv
+var bundles embed.FS
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Seed a fresh deployment with a bundle of presets",
+	Long: `Generates the presets of a bundle built into the CLI, like generate --csv with the bundle's
+manifest, so a new deployment has presets to show. Presets that already exist are left alone
+(their metadata is updated), so an interrupted run can simply be repeated. The bundle's
+categories are added after any existing ones. --dry-run lists the bundle without generating.
+Available bundles: ` + strings.Join(bundleNames(), ", ") + `.`,
+	Example: `  banana init --bundle starter --dry-run
+  banana init --bundle starter`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("bundle")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		records, err := loadBundle(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if dryRun {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tName\tCity\tCategory")
+			fmt.Fprintln(w, "--\t----\t----\t--------")
+			for _, row := range records[1:] {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row[0], row[1], row[2], row[3])
+			}
+			w.Flush()
+			fmt.Printf("Bundle %s: %d presets.\n", name, len(records)-1)
+			return
+		}
+
+		ctx := withPriority(context.Background(), cmd, quota.PriorityBatch)
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		_, p, db, m := openGenerator(ctx, cfg)
+		defer db.Close()
+
+		added, err := seedCategories(ctx, db, records)
+		if err != nil {
+			log.Fatalf("Failed to save categories: %v", err)
+		}
+		log.Printf("Seeding bundle %s: %d presets, %d new categories.", name, len(records)-1, added)
+		generatePresets(ctx, records, false, false, p, db, m)
+		log.Println("Done.")
+	},
+}
+
+// bundleNames lists the embedded bundles.
+func bundleNames() []string {
+	files, _ := bundles.ReadDir("bundles")
+	var names []string
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f.Name(), ".csv"))
+	}
+	return names
+}
+
+// loadBundle returns the rows of bundle name, header included.
+func loadBundle(name string) ([][]string, error) {
+	data, err := bundles.ReadFile(path.Join("bundles", name+".csv"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown bundle %q (available: %s)", name, strings.Join(bundleNames(), ", "))
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s: %w", name, err)
+	}
+	return records, nil
+}
+
+// seedCategories adds the categories of records (in order of appearance)
+// that don't exist yet after the existing ones, and returns how many it
+// added.
+func seedCategories(ctx context.Context, db repo.SettingsStore, records [][]string) (int, error) {
+	categories, err := db.ListCategories(ctx)
+	if err != nil {
+		return 0, err
+	}
+	known := map[string]bool{}
+	for _, c := range categories {
+		known[c.Name] = true
+	}
+	added := 0
+	for _, row := range records[1:] {
+		if len(row) < 4 || row[3] == "" || known[row[3]] {
+			continue
+		}
+		known[row[3]] = true
+		categories = append(categories, database.Category{Name: row[3]})
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	for i := range categories {
+		categories[i].Order = i
+	}
+	return added, db.SetCategories(ctx, categories)
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().String("bundle", "starter", "Preset bundle to seed")
+	initCmd.Flags().Bool("dry-run", false, "List the bundle's presets without generating them")
+	initCmd.Flags().String("priority", "", "Quota priority: interactive, scheduled or batch (default: batch)")
+}
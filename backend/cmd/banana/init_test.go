@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+)
+
+func TestLoadBundle(t *testing.T) {
+	records, err := loadBundle("starter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(records) - 1; n < 15 || n > 25 {
+		t.Errorf("Expected about 20 starter presets, got %d", n)
+	}
+	ids := map[string]bool{}
+	for _, row := range records[1:] {
+		if len(row) < 4 || row[0] == "" || row[1] == "" || row[2] == "" || row[3] == "" {
+			t.Errorf("Incomplete preset %v", row)
+		}
+		if ids[row[0]] {
+			t.Errorf("Duplicate preset ID %s", row[0])
+		}
+		ids[row[0]] = true
+	}
+
+	if _, err := loadBundle("nope"); err == nil {
+		t.Error("Expected an error for an unknown bundle")
+	}
+}
+
+func TestSeedCategories(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+	db.SetCategories(ctx, []database.Category{{Name: "Landmarks", Order: 0, CoverURL: "cover.png"}, {Name: "Europe", Order: 1}})
+	records := [][]string{
+		{"id", "name", "city", "category", "context"},
+		{"paris", "Paris", "Paris, France", "Europe", ""},
+		{"lagos", "Lagos", "Lagos, Nigeria", "Africa", ""},
+		{"cairo", "Cairo", "Cairo, Egypt", "Africa", ""},
+		{"tokyo", "Tokyo", "Tokyo, Japan", "Asia", ""},
+	}
+
+	added, err := seedCategories(ctx, db, records)
+	if err != nil || added != 2 {
+		t.Fatalf("Expected 2 categories added, got %d (%v)", added, err)
+	}
+	categories, _ := db.ListCategories(ctx)
+	want := []string{"Landmarks", "Europe", "Africa", "Asia"}
+	if len(categories) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, categories)
+	}
+	for i, c := range categories {
+		if c.Name != want[i] || c.Order != i {
+			t.Errorf("Category %d = %+v, want %s", i, c, want[i])
+		}
+	}
+	if categories[0].CoverURL != "cover.png" {
+		t.Error("Expected existing categories kept as they are")
+	}
+
+	if added, _ := seedCategories(ctx, db, records); added != 0 {
+		t.Errorf("Expected nothing added on a rerun, got %d", added)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/repo"
+)
+
+// wizard reads answers from stdin for the interactive generate flow.
+type wizard struct {
+	in *bufio.Reader
+}
+
+// ask prompts for a value, falling back to def on empty input.
+// When required is set, it keeps asking until a non-empty value is given.
+func (wz *wizard) ask(label, def string, required bool) string {
+	for {
+		if def != "" {
+			fmt.Printf("%s [%s]: ", label, def)
+		} else {
+			fmt.Printf("%s: ", label)
+		}
+		line, err := wz.in.ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Input closed: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = def
+		}
+		if line == "" && required {
+			fmt.Println("  A value is required.")
+			continue
+		}
+		return line
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func (wz *wizard) confirm(label string) bool {
+	answer := strings.ToLower(wz.ask(label+" [y/N]", "", false))
+	return answer == "y" || answer == "yes"
+}
+
+// validID reports whether id only uses the characters the web flow produces
+// for location IDs (lowercase letters, digits and underscores).
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func runInteractiveMode(ctx context.Context, force, overrideLock bool, gs *genai.Service, p *pipeline.Pipeline, db repo.Repository, m *maps.Service) {
+	wz := &wizard{in: bufio.NewReader(os.Stdin)}
+
+	fmt.Println("Create a preset. Press Enter to accept [defaults].")
+
+	var id string
+	var locked bool          // Kept when its media is overwritten
+	var promptContext string // Offered as the default
+	for {
+		id = wz.ask("ID (lowercase, digits, underscores)", "", true)
+		if !validID(id) {
+			fmt.Println("  Invalid ID. Use only a-z, 0-9 and _.")
+			continue
+		}
+		if existing, err := db.GetLocation(ctx, id); err == nil && existing != nil {
+			if !canOverwrite(existing, force, overrideLock) {
+				fmt.Printf("  %s already exists (%s). Use --force to overwrite its media.\n", id, existing.Name)
+				continue
+			}
+			locked, promptContext = existing.Locked, existing.PromptContext
+		}
+		break
+	}
+
+	name := wz.ask("Display name", "", true)
+	city := wz.ask("City query", name, true)
+	category := wz.ask("Category", "General", false)
+	ctxPrompt := wz.ask("Extra context (optional)", promptContext, false)
+
+	var style int
+	for {
+		var err error
+		style, err = genai.ParseStyle(wz.ask("Style (random, classic, drink)", "random", false))
+		if err == nil {
+			break
+		}
+		fmt.Printf("  %v\n", err)
+	}
+
+	seed := genai.NewSeed()
+
+	fmt.Println("\n--- Rendered Prompt ---")
+	fmt.Println(gs.RenderPrompt(city, ctxPrompt, style, &seed))
+	fmt.Println("-----------------------")
+	if !wz.confirm("Generate image with this prompt?") {
+		fmt.Println("Aborted.")
+		return
+	}
+
+	// Rejected images are billed too, so track them with the video
+	ctx, _ = costs.Track(ctx)
+	var img *genai.ImageResult
+	for {
+		log.Printf("Generating image for '%s' (Style: %d, Seed: %d)...", city, style, seed)
+		var err error
+		img, err = gs.GenerateImage(ctx, city, ctxPrompt, style, &seed)
+		if err != nil {
+			log.Fatalf("Image gen failed: %v", err)
+		}
+
+		if data, err := base64.StdEncoding.DecodeString(img.Image()); err == nil {
+			preview := fmt.Sprintf("%s/banana_preview_%s.png", os.TempDir(), id)
+			if err := os.WriteFile(preview, data, 0o644); err == nil {
+				fmt.Printf("Preview saved to: %s\n", preview)
+				_ = openFile(preview)
+			}
+		}
+
+		if wz.confirm("Happy with the image?") {
+			break
+		}
+		if !wz.confirm("Try again with a new seed?") {
+			fmt.Println("Aborted. Nothing was saved.")
+			return
+		}
+		seed = genai.NewSeed()
+	}
+
+	if !wz.confirm("Generate video and save preset?") {
+		fmt.Println("Aborted. Nothing was saved.")
+		return
+	}
+
+	req := pipeline.Request{
+		ID:       id,
+		City:     city,
+		Context:  ctxPrompt,
+		FileName: fmt.Sprintf("preset_%s_image_%d.png", id, time.Now().Unix()),
+	}
+	res, err := p.Generate(ctx, req, pipeline.WithImage(img), pipeline.WithSeed(seed))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	loc := database.Location{
+		ID:            id,
+		Name:          name,
+		Category:      category,
+		CityQuery:     city,
+		ImageURL:      res.ImageURL,
+		VideoURL:      res.VideoURL,
+		PosterURL:     res.PosterURL,
+		StreamURL:     res.StreamURL,
+		Checksums:     res.Checksums,
+		AltText:       res.AltText,
+		PromptContext: ctxPrompt,
+		IsPreset:      true,
+		Seed:          &seed,
+		Generation:    res.Metadata(),
+		Status:        database.StatusReady,
+		Locked:        locked,
+	}
+	setGeo(ctx, m, &loc)
+	if err := db.UpsertLocation(ctx, loc); err != nil {
+		log.Fatalf("Failed to save: %v", err)
+	}
+	fmt.Printf("Saved preset %s.\n", id)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var killswitchCmd = &cobra.Command{
+	Use:   "killswitch [on|off]",
+	Short: "Stop or resume all generation (emergency kill switch)",
+	Long: `Turns the kill switch in the runtime settings on or off, or shows it without an argument.
+While it's on, web flows serve cached, stale or fallback media only, with a maintenance status
+line, and refreshes, approvals, comparisons and warm-ups fail (503 from the admin API). It applies
+to running servers without a redeploy and turns itself off after --for (default KILL_SWITCH_TTL, 2h).
+--tenant stops one tenant's deployments (TENANT_ID, or "default" without one) instead of all.
+It writes the runtime settings of the project of your local credentials; --remote is refused.`,
+	Example: `  banana admin killswitch on --reason "veo quota incident"
+  banana admin killswitch on --reason "brand review" --tenant acme --for 24h
+  banana admin killswitch off`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"on", "off"},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+		tenant, _ := cmd.Flags().GetString("tenant")
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		var s *database.RuntimeSettings
+		if len(args) == 1 {
+			k := database.KillSwitch{}
+			if args[0] == "on" {
+				reason, _ := cmd.Flags().GetString("reason")
+				if reason == "" {
+					log.Fatal("reason is required (use --reason)")
+				}
+				ttl := cfg.KillSwitchTTL
+				if cmd.Flags().Changed("for") {
+					ttl, _ = cmd.Flags().GetDuration("for")
+				}
+				k = newKillSwitch(reason, time.Now(), ttl)
+			}
+			// Only the switch changes, on the settings as saved
+			s, err = db.UpdateRuntimeSettings(ctx, func(s *database.RuntimeSettings) { s.SetKillSwitch(tenant, k) })
+			if err != nil {
+				log.Fatalf("Failed to save runtime settings: %v", err)
+			}
+			log.Printf("Kill switch of %s turned %s.", killSwitchScope(tenant), args[0])
+		} else if s, err = db.GetRuntimeSettings(ctx); err != nil {
+			log.Fatalf("Failed to read runtime settings: %v", err)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, killSwitches(s), func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Scope\tActive\tReason\tSince\tExpires")
+			fmt.Fprintln(w, "-----\t------\t------\t-----\t-------")
+			for _, ks := range killSwitches(s) {
+				since, expires := "-", "never"
+				if !ks.Since.IsZero() {
+					since = ks.Since.Local().Format(time.DateTime)
+				}
+				if ks.ExpiresAt != nil {
+					expires = ks.ExpiresAt.Local().Format(time.DateTime)
+				}
+				fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", ks.Scope, ks.Active, ks.Reason, since, expires)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// newKillSwitch returns a switch turned on at now, expiring after ttl; 0 or
+// less never expires.
+func newKillSwitch(reason string, now time.Time, ttl time.Duration) database.KillSwitch {
+	k := database.KillSwitch{On: true, Reason: reason, Since: now}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		k.ExpiresAt = &expires
+	}
+	return k
+}
+
+func killSwitchScope(tenant string) string {
+	if tenant == "" {
+		return "all tenants"
+	}
+	return "tenant " + tenant
+}
+
+// scopedKillSwitch is a kill switch as listed by banana admin killswitch.
+type scopedKillSwitch struct {
+	Scope  string `json:"scope"` // "all tenants" or "tenant <id>"
+	Active bool   `json:"active"`
+	database.KillSwitch
+}
+
+// killSwitches lists the global kill switch, then the tenants' by ID.
+func killSwitches(s *database.RuntimeSettings) []scopedKillSwitch {
+	now := time.Now()
+	out := []scopedKillSwitch{{Scope: killSwitchScope(""), Active: s.KillSwitch.Active(now), KillSwitch: s.KillSwitch}}
+	tenants := make([]string, 0, len(s.TenantKillSwitches))
+	for tenant := range s.TenantKillSwitches {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	for _, tenant := range tenants {
+		k := s.TenantKillSwitches[tenant]
+		out = append(out, scopedKillSwitch{Scope: killSwitchScope(tenant), Active: k.Active(now), KillSwitch: k})
+	}
+	return out
+}
+
+func init() {
+	adminCmd.AddCommand(killswitchCmd)
+	killswitchCmd.Flags().String("reason", "", "Why generation is stopped, for operators (required with on)")
+	killswitchCmd.Flags().Duration("for", 0, "Turn the switch off automatically after this long, 0 = never (default KILL_SWITCH_TTL)")
+	killswitchCmd.Flags().String("tenant", "", "Tenant ID whose generation is stopped (default: all tenants)")
+	addOutputFlag(killswitchCmd)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+func TestNewKillSwitch(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	k := newKillSwitch("veo quota incident", now, 2*time.Hour)
+	if !k.On || k.Reason != "veo quota incident" || !k.Since.Equal(now) || k.ExpiresAt == nil || !k.ExpiresAt.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Unexpected switch: %+v", k)
+	}
+	if k := newKillSwitch("indefinite", now, 0); k.ExpiresAt != nil || !k.Active(now.Add(1000*time.Hour)) {
+		t.Errorf("Expected a switch without expiry, got %+v", k)
+	}
+}
+
+func TestKillSwitches(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	s := &database.RuntimeSettings{}
+	s.SetKillSwitch("globex", newKillSwitch("b", time.Now(), time.Hour))
+	s.SetKillSwitch("acme", database.KillSwitch{On: true, Reason: "a", ExpiresAt: &expired})
+
+	list := killSwitches(s)
+	if len(list) != 3 || list[0].Scope != "all tenants" || list[0].Active ||
+		list[1].Scope != "tenant acme" || list[1].Active || list[2].Scope != "tenant globex" || !list[2].Active {
+		t.Errorf("Unexpected switches: %+v", list)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+// Names per Gemini call; keeps responses well under output limits
+const localizeBatchSize = 50
+
+var localizeCmd = &cobra.Command{
+	Use:   "localize",
+	Short: "Translate preset display names",
+	Long: `Translate preset names with Gemini and store them in each preset's name_i18n map,
+served by GET /api/presets?lang=<code>. Presets already translated for a language
+are skipped unless --force is set.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		langs, _ := cmd.Flags().GetStringSlice("langs")
+		force, _ := cmd.Flags().GetBool("force")
+		model, _ := cmd.Flags().GetString("model")
+		if len(langs) == 0 {
+			log.Fatal("--langs is required")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		gs, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+		if err != nil {
+			log.Fatalf("GenAI init failed: %v", err)
+		}
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("DB init failed: %v", err)
+		}
+		defer db.Close()
+
+		presets, err := db.GetPresets(ctx)
+		if err != nil {
+			log.Fatalf("Failed to get presets: %v", err)
+		}
+
+		for _, lang := range langs {
+			lang = strings.TrimSpace(lang)
+			var todo []database.Location
+			for _, p := range presets {
+				if force || p.NameI18n[lang] == "" {
+					todo = append(todo, p)
+				}
+			}
+			log.Printf("[%s] %d of %d presets need translating", lang, len(todo), len(presets))
+
+			for start := 0; start < len(todo); start += localizeBatchSize {
+				batch := todo[start:min(start+localizeBatchSize, len(todo))]
+				names := make([]string, len(batch))
+				for i, p := range batch {
+					names[i] = p.Name
+				}
+
+				translated, err := gs.TranslateNames(ctx, model, names, lang)
+				if err != nil {
+					log.Printf("[%s] Translation failed for batch at %d: %v", lang, start, err)
+					continue
+				}
+				for i, p := range batch {
+					if err := db.SetNameI18n(ctx, p.ID, map[string]string{lang: translated[i]}); err != nil {
+						log.Printf("[%s] Failed to save %s: %v", lang, p.ID, err)
+						continue
+					}
+					log.Printf("[%s] %s: %s -> %s", lang, p.ID, p.Name, translated[i])
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(localizeCmd)
+
+	localizeCmd.Flags().StringSlice("langs", nil, "Language codes to translate into, e.g. fr,ja,es")
+	localizeCmd.Flags().Bool("force", false, "Re-translate names that already exist")
+	localizeCmd.Flags().String("model", genai.DefaultTextModel, "Gemini model used for translation")
+}
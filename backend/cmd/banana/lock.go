@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock a location's media against regeneration",
+	Long: `Lock a location whose media was approved by hand, e.g. before a demo. Locked locations
+aren't regenerated by refresh-stale, warm-up, the city of the day or the web flow, and refresh,
+generate --force and the admin refresh API reject them unless --override-lock (override_lock)
+is given. --unlock removes the lock.`,
+	Example: `  banana admin lock --id venice
+  banana admin lock --id venice --unlock`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		unlock, _ := cmd.Flags().GetBool("unlock")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.GetLocation(ctx, id); err != nil {
+			log.Fatalf("Location not found: %v", err)
+		}
+		edit := database.LocationEdit{ID: id, Fields: map[string]any{"locked": !unlock}}
+		if err := db.UpdateLocations(ctx, []database.LocationEdit{edit}); err != nil {
+			log.Fatalf("Failed to update %s: %v", id, err)
+		}
+		if unlock {
+			log.Printf("%s is unlocked.", id)
+			return
+		}
+		log.Printf("%s is locked.", id)
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(lockCmd)
+	lockCmd.Flags().String("id", "", "Location ID")
+	lockCmd.Flags().Bool("unlock", false, "Remove the lock")
+}
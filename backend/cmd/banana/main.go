@@ -1,9 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
 
+	"banana-weather/internal/branding"
+	"banana-weather/internal/config"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/media"
+	"banana-weather/internal/promptcache"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+	"banana-weather/internal/weather"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
 )
@@ -29,3 +44,126 @@ func main() {
 
 	Execute()
 }
+
+// configureWeatherCheck applies GROUNDING_MODE, WEATHER_PROVIDER and
+// WEATHER_IN_PROMPT, and enables the depicted-vs-observed weather check when
+// WEATHER_CHECK is set.
+func configureWeatherCheck(cfg *config.Config, ws *weather.Service, gs *genai.Service) {
+	ws.Grounding = genai.GroundingMode(cfg.GroundingMode)
+	ws.Conditions = cfg.Weather() // Also injected into prompts, see WEATHER_IN_PROMPT
+	ws.WeatherInPrompt = cfg.WeatherInPrompt
+	if !cfg.WeatherCheck {
+		return
+	}
+	ws.Verifier = gs
+	ws.RegenerateOnMismatch = cfg.WeatherRegen
+}
+
+// openOriginals opens the private ORIGINALS_BUCKET store; nil when unset.
+// Failing to open it is logged, not fatal: the public media is unaffected.
+func openOriginals(ctx context.Context, cfg *config.Config) storage.Store {
+	orig, err := storage.OpenOriginals(ctx, cfg)
+	if err != nil {
+		log.Printf("Warning: originals bucket unavailable, unbadged images won't be kept: %v", err)
+		return nil
+	}
+	return orig
+}
+
+// openPosters returns the poster extractor for POSTER_FRAME; nil when off or
+// ffmpeg is missing, in which case videos simply have no poster.
+func openPosters(cfg *config.Config) *media.Posters {
+	posters, err := media.NewPosters(cfg.PosterFrame)
+	if err != nil {
+		log.Printf("Warning: video posters disabled: %v", err)
+		return nil
+	}
+	return posters
+}
+
+// openStreams returns the HLS transcoder when HLS_TRANSCODE is on; nil when
+// off or unavailable, in which case videos are served as MP4 only.
+func openStreams(ctx context.Context, cfg *config.Config) *media.HLS {
+	streams, err := media.OpenHLS(ctx, cfg)
+	if err != nil {
+		log.Printf("Warning: HLS transcoding disabled: %v", err)
+		return nil
+	}
+	return streams
+}
+
+// openMaps returns the geocoder for GOOGLE_MAPS_API_KEY; nil when it's
+// unavailable, in which case locations are saved without coordinates.
+func openMaps(cfg *config.Config) *maps.Service {
+	m, err := maps.NewService(cfg.GoogleMapsKey)
+	if err != nil {
+		log.Printf("Warning: Maps unavailable, locations won't be geocoded: %v", err)
+		return nil
+	}
+	return m
+}
+
+// openHooks returns the generation hooks of HOOK_PLUGINS and HOOK_WEBHOOKS,
+// or nil when there are none. A hook that fails to load is fatal, as
+// generating without e.g. a required watermark would publish unmarked media.
+func openHooks(cfg *config.Config) *hooks.Registry {
+	h, err := hooks.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load hooks: %v", err)
+	}
+	return h
+}
+
+// openEvents returns the lifecycle event bus of EVENT_TOPICS, or nil when
+// there are no topics. Events only inform downstream consumers, so a bus that
+// fails to open is a warning.
+func openEvents(ctx context.Context, cfg *config.Config) *eventbus.Bus {
+	b, err := eventbus.Open(ctx, cfg)
+	if err != nil {
+		log.Printf("Warning: generation events disabled: %v", err)
+		return nil
+	}
+	return b
+}
+
+// withPriority returns ctx at the quota priority set by --priority, or at def.
+func withPriority(ctx context.Context, cmd *cobra.Command, def quota.Priority) context.Context {
+	s, _ := cmd.Flags().GetString("priority")
+	if s == "" {
+		return quota.WithPriority(ctx, def)
+	}
+	p, err := quota.ParsePriority(s)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return quota.WithPriority(ctx, p)
+}
+
+// configureGenAI applies the tenant's branding settings and Veo operation
+// tracking to the GenAI service so CLI-generated media matches what the server produces.
+func configureGenAI(ctx context.Context, cfg *config.Config, gs *genai.Service, db repo.Repository, ss storage.Store) {
+	gs.SetOperationStore(db)
+	gs.SetTransport(cfg.GenAITransport)
+	gs.SetVideoOutput(storage.Routes(cfg)[storage.KindVideo].GSURI())
+	q, err := quota.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load quota limits: %v", err)
+	}
+	gs.SetQuota(q)
+	if cfg.PromptCache && ss != nil {
+		gs.SetImageCache(promptcache.New(db, ss))
+	}
+
+	var objects branding.ObjectReader
+	if ss != nil {
+		objects = ss
+	}
+	b, logo, err := branding.Load(ctx, db, objects, cfg.TenantID)
+	if err != nil {
+		log.Printf("Warning: Branding failed to load, continuing without it: %v", err)
+		return
+	}
+	if b != nil {
+		gs.SetBranding(b, logo)
+	}
+}
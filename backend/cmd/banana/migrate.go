@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"log"
 
-	"banana-weather/pkg/config"
-	"banana-weather/pkg/database"
-	"banana-weather/pkg/storage"
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
 
 	"github.com/spf13/cobra"
 )
@@ -21,6 +23,9 @@ var migrateCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().Bool("backfill-status", false, "Set status=ready on locations that have no status (instead of migrating presets.json)")
+	migrateCmd.Flags().Bool("backfill-geo", false, "Geocode locations that have no geo/country_code/continent (instead of migrating presets.json)")
 }
 
 // LegacyPreset matches the JSON structure in presets.json
@@ -39,17 +44,31 @@ func runMigrate(cmd *cobra.Command, args []string) {
 	if cfg == nil { log.Fatal("Config load failed") }
 
 	// Init Services
-	storageService, err := storage.NewService(ctx, cfg.BucketName)
+	storageService, err := storage.Open(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to init Storage: %v", err)
 	}
 	
-	dbService, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+	dbService, err := repo.Open(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to init DB: %v", err)
 	}
 	defer dbService.Close()
 
+	if backfill, _ := cmd.Flags().GetBool("backfill-status"); backfill {
+		runBackfillStatus(ctx, dbService)
+		return
+	}
+
+	if backfill, _ := cmd.Flags().GetBool("backfill-geo"); backfill {
+		mapsService, err := maps.NewService(cfg.GoogleMapsKey)
+		if err != nil {
+			log.Fatalf("Failed to init Maps: %v", err)
+		}
+		runBackfillGeo(ctx, dbService, mapsService)
+		return
+	}
+
 	log.Println("Reading presets.json from GCS...")
 	data, err := storageService.ReadObject(ctx, "presets.json")
 	if err != nil {
@@ -72,6 +91,7 @@ func runMigrate(cmd *cobra.Command, args []string) {
 			ImageURL:  p.ImageURL,
 			VideoURL:  p.VideoURL,
 			IsPreset:  true,
+			Status:    database.StatusReady,
 		}
 		
 		// Fallback category if empty (older presets)
@@ -88,3 +108,50 @@ func runMigrate(cmd *cobra.Command, args []string) {
 
 	log.Println("Migration Complete.")
 }
+
+// runBackfillStatus gives legacy documents an explicit status so status
+// filters (which can't match a missing field) include them.
+func runBackfillStatus(ctx context.Context, db repo.LocationStore) {
+	locs, err := db.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		log.Fatalf("Failed to list locations: %v", err)
+	}
+	updated := 0
+	for _, l := range locs {
+		if l.Status != "" {
+			continue
+		}
+		if err := db.SetStatus(ctx, l.ID, database.StatusReady); err != nil {
+			log.Printf("Failed to backfill %s: %v", l.ID, err)
+			continue
+		}
+		updated++
+	}
+	log.Printf("Backfilled status on %d of %d locations.", updated, len(locs))
+}
+
+// runBackfillGeo derives coordinates, country and continent for locations stored before
+// they were recorded. Fictional presets don't geocode and are left without.
+func runBackfillGeo(ctx context.Context, db repo.LocationStore, m *maps.Service) {
+	locs, err := db.ListLocations(ctx, database.ListOptions{})
+	if err != nil {
+		log.Fatalf("Failed to list locations: %v", err)
+	}
+	updated := 0
+	for _, l := range locs {
+		if (l.CountryCode != "" && l.Geo != nil) || l.CityQuery == "" {
+			continue
+		}
+		place, err := m.GetCityLocation(ctx, l.CityQuery)
+		if err != nil || place.CountryCode == "" {
+			log.Printf("Skipping %s: no country for %q", l.ID, l.CityQuery)
+			continue
+		}
+		if err := db.SetGeo(ctx, l.ID, place.LatLng(), place.CountryCode, place.Continent); err != nil {
+			log.Printf("Failed to backfill %s: %v", l.ID, err)
+			continue
+		}
+		updated++
+	}
+	log.Printf("Backfilled geo on %d of %d locations.", updated, len(locs))
+}
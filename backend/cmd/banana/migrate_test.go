@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+)
+
+// fakeLocations implements the parts of repo.LocationStore the backfill uses;
+// any other call panics on the nil embedded interface.
+type fakeLocations struct {
+	repo.LocationStore
+	locs    []database.Location
+	updated map[string]database.LocationStatus
+}
+
+func (f *fakeLocations) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	return f.locs, nil
+}
+
+func (f *fakeLocations) SetStatus(ctx context.Context, id string, status database.LocationStatus) error {
+	f.updated[id] = status
+	return nil
+}
+
+func TestRunBackfillStatus(t *testing.T) {
+	db := &fakeLocations{
+		locs: []database.Location{
+			{ID: "legacy"},
+			{ID: "failed", Status: database.StatusFailed},
+		},
+		updated: map[string]database.LocationStatus{},
+	}
+
+	runBackfillStatus(context.Background(), db)
+
+	if len(db.updated) != 1 || db.updated["legacy"] != database.StatusReady {
+		t.Errorf("Expected only 'legacy' backfilled to ready, got %v", db.updated)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"banana-weather/api"
+	"banana-weather/internal/cards"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/weather"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/spf13/cobra"
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Local stand-ins for frontend development",
+}
+
+var mockServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the public API against fake GenAI, local storage and an in-memory store",
+	Long: `Runs the public API (weather stream, presets, feedback, map, city of the day)
+with no cloud dependencies: images are generated gradients, the video is the
+--video clip, geocoding resolves against the fixture, media is written to
+--media-dir and served under /media/, and locations live in memory, seeded
+from --fixture (or a built-in set of presets). Latencies are adjustable so
+the SSE flow looks like the real one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		port, _ := cmd.Flags().GetInt("port")
+		fixture, _ := cmd.Flags().GetString("fixture")
+		dir, _ := cmd.Flags().GetString("media-dir")
+		video, _ := cmd.Flags().GetString("video")
+		web, _ := cmd.Flags().GetString("web")
+		imageLatency, _ := cmd.Flags().GetDuration("image-latency")
+		videoLatency, _ := cmd.Flags().GetDuration("video-latency")
+		geocodeLatency, _ := cmd.Flags().GetDuration("geocode-latency")
+		failRate, _ := cmd.Flags().GetFloat64("image-fail-rate")
+
+		db := mock.NewDB()
+		var err error
+		if fixture == "" {
+			_, err = db.LoadDefault()
+		} else {
+			err = loadFixture(db, fixture)
+		}
+		if err != nil {
+			log.Fatalf("Failed to load fixture: %v", err)
+		}
+
+		if dir == "" {
+			if dir, err = os.MkdirTemp("", "banana-mock-"); err != nil {
+				log.Fatalf("Failed to create media dir: %v", err)
+			}
+		}
+		store, err := mock.NewStorage(dir, fmt.Sprintf("http://localhost:%d/media", port))
+		if err != nil {
+			log.Fatalf("Failed to open media dir: %v", err)
+		}
+
+		gen := &mock.GenAI{ImageLatency: imageLatency, VideoLatency: videoLatency}
+		if video != "" {
+			if gen.Video, err = mockVideo(ctx, store, video); err != nil {
+				log.Fatalf("Failed to load --video: %v", err)
+			}
+		}
+		if err := mock.Seed(ctx, db, gen, store); err != nil {
+			log.Fatalf("Failed to seed media: %v", err)
+		}
+
+		svc := weather.NewService(&mock.Maps{DB: db, Latency: geocodeLatency}, weather.WithChaos(gen, failRate, 0), store, db)
+		svc.Latency = db
+		svc.Runtime = db
+		svc.Candidates = db
+		handler := &api.Handler{
+			DB:              db,
+			Weather:         svc,
+			ReportThreshold: 3,
+			Provenance:      provenance.NewSigner(""),
+			Cards:           &cards.Service{Store: store},
+		}
+
+		r := chi.NewRouter()
+		r.Use(middleware.RequestID)
+		r.Use(middleware.Logger)
+		r.Use(middleware.Recoverer)
+		r.Use(api.RequestLogger)
+		r.Use(allowAnyOrigin) // The Flutter dev server runs on another port
+
+		r.Handle("/media/*", http.StripPrefix("/media/", http.FileServer(http.Dir(dir))))
+		r.Route("/api", func(r chi.Router) {
+			r.Get("/weather", handler.HandleGetWeather)
+			r.Get("/dashboard", handler.HandleDashboard)
+			r.Get("/presets", handler.HandleGetPresets)
+			r.Get("/categories", handler.HandleGetCategories)
+			r.Post("/locations/{id}/feedback", handler.HandleLocationFeedback)
+			r.Post("/locations/{id}/report", handler.HandleLocationReport)
+			r.Get("/locations/by-country/{code}", handler.HandleLocationsByCountry)
+			r.Get("/map.geojson", handler.HandleMapGeoJSON)
+			r.Post("/provenance/verify", handler.HandleVerifyProvenance)
+			r.Get("/city-of-the-day", handler.HandleCityOfTheDay)
+			r.Get("/locations/{id}/playlist", handler.HandleLocationPlaylist)
+			r.Get("/locations/{id}/card.png", handler.HandleLocationCard)
+			r.Get("/locations/{id}/receipt", handler.HandleLocationReceipt)
+		})
+		if web != "" {
+			r.Handle("/*", http.FileServer(http.Dir(web)))
+		}
+
+		log.Printf("Mock API on http://localhost:%d (media in %s)", port, dir)
+		if gen.Video == "" {
+			log.Printf("No --video: generated videos will fail, as if Veo were down")
+		}
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", port), r); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func loadFixture(db *mock.DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = db.Load(f)
+	return err
+}
+
+// mockVideo returns the clip the mock GenAI serves: a URL as is, or a local
+// file copied into the media store.
+func mockVideo(ctx context.Context, store *mock.Storage, video string) (string, error) {
+	if strings.Contains(video, "://") {
+		return video, nil
+	}
+	data, err := os.ReadFile(video)
+	if err != nil {
+		return "", err
+	}
+	name := "videos/" + filepath.Base(video)
+	if _, err := store.UploadBytes(ctx, data, name, "video/mp4"); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// allowAnyOrigin adds CORS headers, answering preflights itself.
+func allowAnyOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+	mockCmd.AddCommand(mockServeCmd)
+	mockServeCmd.Flags().Int("port", 8080, "Port to listen on (the frontend's debug builds call localhost:8080)")
+	mockServeCmd.Flags().String("fixture", "", "JSON fixture with locations and categories (default: built-in presets)")
+	mockServeCmd.Flags().String("media-dir", "", "Directory for generated media (default: a new temporary directory)")
+	mockServeCmd.Flags().String("video", "", "MP4 file or URL returned for every generated video")
+	mockServeCmd.Flags().String("web", "", "Optional: built frontend to serve at / (e.g. ../frontend/build/web)")
+	mockServeCmd.Flags().Duration("image-latency", 3*time.Second, "Time each image generation takes")
+	mockServeCmd.Flags().Duration("video-latency", 20*time.Second, "Time each video generation takes")
+	mockServeCmd.Flags().Duration("geocode-latency", 300*time.Millisecond, "Time each geocoding lookup takes")
+	mockServeCmd.Flags().Float64("image-fail-rate", 0, "Fraction of image generations that fail (0-1)")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var opsCmd = &cobra.Command{
+	Use:   "ops",
+	Short: "Inspect and cancel in-flight Veo operations",
+}
+
+var opsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List in-flight Veo operations",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		ops, err := db.ListOperations(ctx)
+		if err != nil {
+			log.Fatalf("Error listing operations: %v", err)
+		}
+		if ops == nil {
+			ops = []database.PendingOperation{}
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, ops, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Name\tModel\tInput\tAge")
+			fmt.Fprintln(w, "----\t-----\t-----\t---")
+			for _, op := range ops {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", op.Name, op.Model, op.InputImage, time.Since(op.StartedAt).Round(time.Second))
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var opsCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel a Veo operation",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			log.Fatal("name is required (use --name, see `banana admin ops list`)")
+		}
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+		if err != nil {
+			log.Fatalf("GenAI init failed: %v", err)
+		}
+		genaiService.SetOperationStore(db)
+
+		if err := genaiService.CancelOperation(ctx, name); err != nil {
+			log.Fatalf("Cancel failed: %v", err)
+		}
+		log.Printf("Cancel requested for %s.", name)
+	},
+}
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Show Vertex AI quota limits",
+	Long:  "Show Vertex AI quota limits from the Cloud Quotas API. Current usage is not exposed there; check Cloud Monitoring for usage.",
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, _ := cmd.Flags().GetString("filter")
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+		if err != nil {
+			log.Fatalf("GenAI init failed: %v", err)
+		}
+
+		quotas, err := genaiService.GetQuotas(ctx, filter)
+		if err != nil {
+			log.Fatalf("Error getting quotas: %v", err)
+		}
+		if quotas == nil {
+			quotas = []genai.QuotaInfo{}
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, quotas, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Quota\tDimensions\tLimit")
+			fmt.Fprintln(w, "-----\t----------\t-----")
+			for _, q := range quotas {
+				fmt.Fprintf(w, "%s\t%v\t%s\n", q.QuotaID, q.Dimensions, q.Limit)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(opsCmd)
+	adminCmd.AddCommand(quotaCmd)
+	opsCmd.AddCommand(opsListCmd)
+	opsCmd.AddCommand(opsCancelCmd)
+
+	addOutputFlag(opsListCmd)
+	opsCancelCmd.Flags().String("name", "", "Full operation name (projects/.../operations/...)")
+
+	quotaCmd.Flags().String("filter", "veo", "Only show quotas whose ID or metric contains this (empty = all)")
+	addOutputFlag(quotaCmd)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Show or update the location blocklist/allowlist",
+	Long: `Show the location policy doc, or update it when any setting flag is given.
+Entries match a whole formatted address ("Paris, France") or one of its
+components ("France"). The server reads the policy at startup.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		tenant := cfg.TenantID
+		if cmd.Flags().Changed("tenant") {
+			tenant, _ = cmd.Flags().GetString("tenant")
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		p, err := db.GetLocationPolicy(ctx, tenant)
+		if err != nil {
+			p = &database.LocationPolicy{}
+		}
+
+		changed := false
+		edit := func(flag string, list *[]string, add bool) {
+			if !cmd.Flags().Changed(flag) {
+				return
+			}
+			values, _ := cmd.Flags().GetStringArray(flag)
+			for _, v := range values {
+				if add {
+					*list = appendUnique(*list, v)
+				} else {
+					*list = removeFold(*list, v)
+				}
+			}
+			changed = true
+		}
+		edit("block", &p.Blocked, true)
+		edit("unblock", &p.Blocked, false)
+		edit("allow", &p.Allowed, true)
+		edit("unallow", &p.Allowed, false)
+		if cmd.Flags().Changed("allowlist-only") {
+			p.AllowlistOnly, _ = cmd.Flags().GetBool("allowlist-only")
+			changed = true
+		}
+
+		if changed {
+			if err := db.SetLocationPolicy(ctx, tenant, *p); err != nil {
+				log.Fatalf("Failed to save location policy: %v", err)
+			}
+			log.Printf("Location policy updated (tenant: %q). Restart the server to apply it.", tenant)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, p, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Setting\tValue")
+			fmt.Fprintln(w, "-------\t-----")
+			fmt.Fprintf(w, "Blocked\t%s\n", strings.Join(p.Blocked, "; "))
+			fmt.Fprintf(w, "Allowed\t%s\n", strings.Join(p.Allowed, "; "))
+			fmt.Fprintf(w, "Allowlist Only\t%v\n", p.AllowlistOnly)
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if strings.EqualFold(existing, v) {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+func removeFold(list []string, v string) []string {
+	var out []string
+	for _, existing := range list {
+		if !strings.EqualFold(existing, v) {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
+
+func init() {
+	adminCmd.AddCommand(policyCmd)
+
+	policyCmd.Flags().String("tenant", "", "Tenant ID (default: TENANT_ID)")
+	policyCmd.Flags().StringArray("block", nil, "Add a blocked location (repeatable)")
+	policyCmd.Flags().StringArray("unblock", nil, "Remove a blocked location (repeatable)")
+	policyCmd.Flags().StringArray("allow", nil, "Add an allowed location (repeatable)")
+	policyCmd.Flags().StringArray("unallow", nil, "Remove an allowed location (repeatable)")
+	policyCmd.Flags().Bool("allowlist-only", false, "Reject every location not in the allowlist (kiosk mode)")
+	addOutputFlag(policyCmd)
+}
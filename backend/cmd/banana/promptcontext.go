@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var setPromptContextCmd = &cobra.Command{
+	Use:   "set-prompt-context",
+	Short: "Set the extra image prompt context of a location",
+	Long: `Set the extra context added to a location's image prompt, used by every regeneration from
+its next refresh on (admin refreshes, stale web requests and generate without --context). An empty
+--context clears it. generate --context sets it too.
+
+  banana admin set-prompt-context --id porto --context "Focus on the Ribeira, include the funicular"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+		if !cmd.Flags().Changed("context") {
+			log.Fatal("context is required (use --context, or --context \"\" to clear it)")
+		}
+		promptContext, _ := cmd.Flags().GetString("context")
+		if _, err := database.ParseEditValue("prompt_context", promptContext); err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		if err := backend.SetPromptContext(ctx, id, promptContext); err != nil {
+			log.Fatalf("Failed to set the prompt context: %v", err)
+		}
+		if promptContext == "" {
+			log.Printf("Cleared the prompt context of %s.", id)
+			return
+		}
+		log.Printf("Set the prompt context of %s; it applies from the next refresh.", id)
+	},
+}
+
+func (l *localAdmin) SetPromptContext(ctx context.Context, id, promptContext string) error {
+	value, err := database.ParseEditValue("prompt_context", promptContext)
+	if err != nil {
+		return err
+	}
+	if _, err := l.GetLocation(ctx, id); err != nil {
+		return err
+	}
+	return l.UpdateLocations(ctx, []database.LocationEdit{{ID: id, Fields: map[string]any{"prompt_context": value}}})
+}
+
+func (c *remoteClient) SetPromptContext(ctx context.Context, id, promptContext string) error {
+	return c.do(ctx, http.MethodPut, "/locations/"+url.PathEscape(id)+"/prompt-context", map[string]any{"context": promptContext}, nil)
+}
+
+func init() {
+	adminCmd.AddCommand(setPromptContextCmd)
+	supportsRemote(setPromptContextCmd)
+	setPromptContextCmd.Flags().String("id", "", "Location ID")
+	setPromptContextCmd.Flags().String("context", "", "Extra image prompt context (empty to clear it)")
+}
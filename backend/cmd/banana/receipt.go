@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+// loadReceipt reads a receipt saved from GET /api/locations/{id}/receipt.
+func loadReceipt(path string) (*database.Receipt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r database.Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid receipt %s: %w", path, err)
+	}
+	if r.City == "" {
+		return nil, fmt.Errorf("invalid receipt %s: no city", path)
+	}
+	if r.Seed == nil {
+		return nil, fmt.Errorf("receipt %s has no seed, so it can't be reproduced", path)
+	}
+	return &r, nil
+}
+
+// receiptRun is how a receipt is replayed.
+type receiptRun struct {
+	Style     int
+	Context   string // Prompt context as sent: the receipt's, plus its weather when it was generated without search
+	Grounding genai.GroundingMode
+	Warnings  []string // Differences from the original generation
+}
+
+// planReceipt works out how to replay r with this build, which generates
+// images with imageModel, noting what can't be reproduced exactly.
+func planReceipt(r *database.Receipt, imageModel string) (*receiptRun, error) {
+	style, err := genai.ParseStyle(r.Style)
+	if err != nil {
+		return nil, err
+	}
+	grounding, err := genai.ParseGroundingMode(r.GroundingMode)
+	if err != nil {
+		return nil, err
+	}
+	run := &receiptRun{Style: style, Context: r.PromptContext, Grounding: grounding}
+	if r.PromptVersion != "" && r.PromptVersion != genai.PromptVersion {
+		run.Warnings = append(run.Warnings, fmt.Sprintf("the prompts changed since (version %s, now %s)", r.PromptVersion, genai.PromptVersion))
+	}
+	if r.ImageModel != "" && r.ImageModel != imageModel {
+		run.Warnings = append(run.Warnings, fmt.Sprintf("the image model is %s, not %s (set GEMINI_IMAGE)", imageModel, r.ImageModel))
+	}
+	if r.VideoModel != "" && r.VideoModel != genai.VideoModel {
+		run.Warnings = append(run.Warnings, fmt.Sprintf("the video model is %s, not %s", genai.VideoModel, r.VideoModel))
+	}
+	switch {
+	case grounding == genai.GroundingOff && r.Conditions != nil && r.TemperatureC != nil:
+		// The observed weather was in the prompt; send the same
+		run.Context = weather.WithObservedWeather(run.Context, &openmeteo.Current{
+			Condition:    openmeteo.Condition(r.Conditions.Condition),
+			TemperatureC: *r.TemperatureC,
+			IsDay:        r.Conditions.IsDay,
+		})
+	case grounding != genai.GroundingOff:
+		run.Warnings = append(run.Warnings, "the model searches for the current weather, which may differ from the receipt's")
+	}
+	return run, nil
+}
+
+// runReceiptMode regenerates the location of a receipt (under --id when
+// given) with the receipt's settings, like single mode does with flags.
+func runReceiptMode(ctx context.Context, cmd *cobra.Command, path string, force, overrideLock bool, imageModel string, p *pipeline.Pipeline, db repo.Repository, m *maps.Service) {
+	r, err := loadReceipt(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	run, err := planReceipt(r, imageModel)
+	if err != nil {
+		log.Fatalf("Invalid receipt: %v", err)
+	}
+	id := r.LocationID
+	if cmd.Flags().Changed("id") {
+		id, _ = cmd.Flags().GetString("id")
+	}
+	if id == "" {
+		log.Fatal("the receipt has no location ID (use --id)")
+	}
+	for _, w := range run.Warnings {
+		log.Printf("Warning: not an exact replay: %s", w)
+	}
+
+	existing, err := db.GetLocation(ctx, id)
+	exists := err == nil && existing != nil
+	if exists && !canOverwrite(existing, force, overrideLock) {
+		log.Fatalf("%s exists; use --force to replace its media with the receipt's", id)
+	}
+
+	ctx = genai.WithGrounding(genai.WithLanguage(ctx, r.Lang), run.Grounding)
+	log.Printf("Replaying the receipt of %s as %s (%s, seed %d)...", r.LocationID, id, genai.StyleName(run.Style), *r.Seed)
+	res, err := p.Generate(ctx, pipeline.Request{
+		ID:       id,
+		City:     r.City,
+		Context:  run.Context,
+		FileName: fmt.Sprintf("preset_%s_image_%d.png", id, time.Now().Unix()),
+	}, pipeline.WithStyle(run.Style), pipeline.WithSeed(*r.Seed), pipeline.WithVideoPrompt(r.VideoPrompt))
+	if err != nil {
+		if exists {
+			db.SetStatus(ctx, id, database.StatusFailed)
+		}
+		log.Fatalf("Error: %v", err)
+	}
+	loc := database.Location{
+		ID:            id,
+		Name:          r.Name,
+		Category:      r.Category,
+		CityQuery:     r.City,
+		ImageURL:      res.ImageURL,
+		VideoURL:      res.VideoURL,
+		PosterURL:     res.PosterURL,
+		StreamURL:     res.StreamURL,
+		Checksums:     res.Checksums,
+		AltText:       res.AltText,
+		PromptContext: r.PromptContext,
+		VideoPrompt:   r.VideoPrompt,
+		IsPreset:      true,
+		Seed:          &res.Seed,
+		Generation:    res.Metadata(),
+		Status:        database.StatusReady,
+		Locked:        exists && existing.Locked,
+	}
+	if loc.Name == "" {
+		loc.Name = r.City
+	}
+	if loc.Category == "" {
+		loc.Category = "General"
+	}
+	setGeo(ctx, m, &loc)
+	if err := db.UpsertLocation(ctx, loc); err != nil {
+		log.Fatalf("Failed to save: %v", err)
+	}
+	log.Printf("Saved %s: %s", id, res.ImageURL)
+}
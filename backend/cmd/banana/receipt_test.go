@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+)
+
+func TestLoadReceipt(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	r, err := loadReceipt(write("ok.json", `{"location_id":"lima","city":"Lima","seed":42,"style":"drink"}`))
+	if err != nil || r.LocationID != "lima" || *r.Seed != 42 || r.Style != "drink" {
+		t.Errorf("loadReceipt = %+v, %v", r, err)
+	}
+	for name, body := range map[string]string{
+		"noseed.json": `{"location_id":"lima","city":"Lima"}`,
+		"nocity.json": `{"location_id":"lima","seed":1}`,
+		"bad.json":    `not json`,
+	} {
+		if _, err := loadReceipt(write(name, body)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestPlanReceipt(t *testing.T) {
+	seed, temp := int32(7), 12.4
+	r := &database.Receipt{
+		City: "Oslo", PromptContext: "Opera house", Seed: &seed, Style: "classic", PromptVersion: genai.PromptVersion,
+		ImageModel: "image-a", GroundingMode: "off",
+		Conditions: &database.Conditions{Condition: "rain", TempBandC: 10}, TemperatureC: &temp,
+	}
+	run, err := planReceipt(r, "image-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Style != 1 || run.Grounding != genai.GroundingOff || len(run.Warnings) != 0 {
+		t.Errorf("Unexpected plan %+v", run)
+	}
+	if !strings.HasPrefix(run.Context, "Opera house. Current weather: rain, 12°C, night") {
+		t.Errorf("Expected the receipt's weather in the prompt context, got %q", run.Context)
+	}
+
+	// Searching, with another model and older prompts: replayed with warnings
+	r.GroundingMode, r.ImageModel, r.PromptVersion = "", "image-b", "000000000000"
+	if run, err = planReceipt(r, "image-a"); err != nil {
+		t.Fatal(err)
+	}
+	if run.Grounding != genai.GroundingSearch || run.Context != "Opera house" || len(run.Warnings) != 3 {
+		t.Errorf("Unexpected plan %+v", run)
+	}
+
+	r.Style = "snowglobe"
+	if _, err := planReceipt(r, "image-a"); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+// Actions of a staleRefresh plan entry
+const (
+	staleRefreshRun    = "refresh"
+	staleRefreshMax    = "skip_max"    // Beyond --max
+	staleRefreshBudget = "skip_budget" // Its estimate doesn't fit in what's left of --budget
+)
+
+// staleRefresh is one preset in the refresh-stale plan, oldest first.
+type staleRefresh struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Updated time.Time `json:"updated"`
+	CostUSD float64   `json:"cost_usd"`          // Estimate: the last generation's cost, or the average of the others
+	Guessed bool      `json:"guessed,omitempty"` // The preset had no cost of its own
+	Action  string    `json:"action"`
+}
+
+var refreshStaleCmd = &cobra.Command{
+	Use:   "refresh-stale",
+	Short: "Regenerate presets whose media is older than a TTL",
+	Long: `Find presets whose media is older than --ttl and regenerate them, oldest first, up to --max
+presets and --budget USD. Each preset's cost is estimated from its last generation (the usage
+summary of admin list, priced with COST_RATES); presets without one are estimated at the average
+of the others. The plan is printed first; nothing is regenerated without --yes.
+Hidden, archived and generating presets are left alone, as are presets with media attached by
+banana admin attach and, without --override-lock, locked presets.
+Presets whose observed weather is in the same bucket as when their media was generated (condition,
+day or night, and 5°C temperature band) keep their media and only get the new temperature and
+timestamp; --skip-unchanged=false regenerates them anyway.`,
+	Example: `  banana admin refresh-stale --ttl 6h --max 50 --budget 20
+  banana admin refresh-stale --ttl 6h --max 50 --budget 20 --yes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		maxRefresh, _ := cmd.Flags().GetInt("max")
+		budgetFlag, _ := cmd.Flags().GetString("budget")
+		yes, _ := cmd.Flags().GetBool("yes")
+		priority, _ := cmd.Flags().GetString("priority")
+		overrideLock, _ := cmd.Flags().GetBool("override-lock")
+		skipUnchanged, _ := cmd.Flags().GetBool("skip-unchanged")
+		if ttl <= 0 {
+			log.Fatal("--ttl must be positive")
+		}
+		budget, err := parseBudget(budgetFlag)
+		if err != nil {
+			log.Fatalf("Invalid --budget: %v", err)
+		}
+		if _, err := quota.ParsePriority(priority); err != nil {
+			log.Fatalf("Invalid --priority: %v", err)
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+
+		locs, err := backend.ListLocations(ctx, database.ListOptions{Type: "preset"})
+		if err != nil {
+			log.Fatalf("Error listing presets: %v", err)
+		}
+		plan, err := planStaleRefresh(locs, time.Now(), ttl, maxRefresh, budget, overrideLock)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, plan, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tName\tAge\tEst. Cost\tAction")
+			fmt.Fprintln(w, "--\t----\t---\t---------\t------")
+			for _, p := range plan {
+				cost := fmt.Sprintf("$%.2f", p.CostUSD)
+				if p.Guessed {
+					cost += "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.ID, p.Name, time.Since(p.Updated).Round(time.Minute), cost, p.Action)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		n, total := staleRefreshTotal(plan)
+		if !yes || n == 0 {
+			log.Printf("%d of %d stale presets would be regenerated (est. $%.2f). Run with --yes to regenerate them.", n, len(plan), total)
+			return
+		}
+
+		var failed, unchanged int
+		for _, p := range plan {
+			if p.Action != staleRefreshRun {
+				continue
+			}
+			log.Printf("Refreshing %s (%s, est. $%.2f)...", p.ID, p.Name, p.CostUSD)
+			loc, err := backend.RefreshLocation(ctx, p.ID, weather.RefreshOptions{Priority: priority, OverrideLock: overrideLock, SkipUnchanged: skipUnchanged})
+			if err != nil {
+				log.Printf("Refresh of %s failed: %v", p.ID, err)
+				failed++
+			} else if loc.Unchanged {
+				log.Printf("Weather unchanged for %s; kept its media.", p.ID)
+				unchanged++
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("Refreshed %d presets (%d with unchanged weather kept), %d failed.", n-failed, unchanged, failed)
+		}
+		log.Printf("Refreshed %d presets (%d with unchanged weather kept).", n, unchanged)
+	},
+}
+
+// parseBudget reads a USD amount, with or without a leading $. Empty is no
+// budget.
+func parseBudget(s string) (float64, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "$")
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("negative budget %s", s)
+	}
+	return v, nil
+}
+
+// planStaleRefresh picks the presets last updated more than ttl before now,
+// oldest first (locked ones only with overrideLock), and marks which fit within maxRefresh (0 is no cap) and
+// budget (0 is none). A preset over what's left of the budget is skipped,
+// but cheaper ones after it may still fit. A budget needs cost estimates,
+// so it's an error when no candidate has one.
+func planStaleRefresh(locs []database.Location, now time.Time, ttl time.Duration, maxRefresh int, budget float64, overrideLock bool) ([]staleRefresh, error) {
+	var plan []staleRefresh
+	var known float64
+	var nKnown int
+	for _, l := range locs {
+		switch l.EffectiveStatus() {
+		case database.StatusHidden, database.StatusArchived, database.StatusHiddenPendingReview, database.StatusGenerating:
+			continue
+		}
+		if !l.IsPreset || l.ManuallyCurated || (l.Locked && !overrideLock) || now.Sub(l.LastUpdated) < ttl {
+			continue
+		}
+		p := staleRefresh{ID: l.ID, Name: l.Name, Updated: l.LastUpdated}
+		if l.Usage != nil && l.Usage.CostUSD > 0 {
+			p.CostUSD = l.Usage.CostUSD
+			known += p.CostUSD
+			nKnown++
+		}
+		plan = append(plan, p)
+	}
+	if budget > 0 && len(plan) > 0 && nKnown == 0 {
+		return nil, fmt.Errorf("--budget needs cost estimates, but no stale preset has one (set COST_RATES)")
+	}
+	slices.SortStableFunc(plan, func(a, b staleRefresh) int { return a.Updated.Compare(b.Updated) })
+
+	var n int
+	var spent float64
+	for i := range plan {
+		p := &plan[i]
+		if p.CostUSD == 0 && nKnown > 0 {
+			p.CostUSD, p.Guessed = known/float64(nKnown), true
+		}
+		switch {
+		case maxRefresh > 0 && n >= maxRefresh:
+			p.Action = staleRefreshMax
+		case budget > 0 && spent+p.CostUSD > budget:
+			p.Action = staleRefreshBudget
+		default:
+			p.Action = staleRefreshRun
+			n++
+			spent += p.CostUSD
+		}
+	}
+	return plan, nil
+}
+
+// staleRefreshTotal counts the presets the plan regenerates and their estimated cost.
+func staleRefreshTotal(plan []staleRefresh) (n int, costUSD float64) {
+	for _, p := range plan {
+		if p.Action == staleRefreshRun {
+			n++
+			costUSD += p.CostUSD
+		}
+	}
+	return n, costUSD
+}
+
+func init() {
+	adminCmd.AddCommand(refreshStaleCmd)
+	supportsRemote(refreshStaleCmd)
+
+	refreshStaleCmd.Flags().Duration("ttl", 6*time.Hour, "Regenerate presets whose media is older than this")
+	refreshStaleCmd.Flags().Int("max", 50, "Most presets to regenerate (0: no cap)")
+	refreshStaleCmd.Flags().String("budget", "", "Most estimated USD to spend, e.g. 20 (quote a leading $ from the shell); needs COST_RATES")
+	refreshStaleCmd.Flags().Bool("yes", false, "Regenerate the planned presets instead of only printing the plan")
+	refreshStaleCmd.Flags().String("priority", "batch", "Quota priority: interactive, scheduled or batch")
+	refreshStaleCmd.Flags().Bool("override-lock", false, "Include locked presets")
+	refreshStaleCmd.Flags().Bool("skip-unchanged", true, "Keep the media of presets whose weather is unchanged since it was generated")
+	addOutputFlag(refreshStaleCmd)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+func TestPlanStaleRefresh(t *testing.T) {
+	now := time.Now()
+	cost := func(usd float64) *database.UsageSummary { return &database.UsageSummary{CostUSD: usd} }
+	locs := []database.Location{
+		{ID: "fresh", IsPreset: true, LastUpdated: now.Add(-time.Hour), Usage: cost(1)},
+		{ID: "paris", IsPreset: true, LastUpdated: now.Add(-10 * time.Hour), Usage: cost(3)},
+		{ID: "tokyo", IsPreset: true, LastUpdated: now.Add(-30 * time.Hour), Usage: cost(4)},
+		{ID: "lima", IsPreset: true, LastUpdated: now.Add(-20 * time.Hour)}, // No usage recorded
+		{ID: "oslo", IsPreset: true, LastUpdated: now.Add(-8 * time.Hour), Usage: cost(0.5)},
+		{ID: "user", LastUpdated: now.Add(-40 * time.Hour), Usage: cost(1)},
+		{ID: "hidden", IsPreset: true, Status: database.StatusHidden, LastUpdated: now.Add(-40 * time.Hour)},
+		{ID: "busy", IsPreset: true, Status: database.StatusGenerating, LastUpdated: now.Add(-40 * time.Hour)},
+	}
+
+	plan, err := planStaleRefresh(locs, now, 6*time.Hour, 0, 8, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id      string
+		cost    float64
+		guessed bool
+		action  string
+	}{
+		{"tokyo", 4, false, staleRefreshRun},
+		{"lima", 2.5, true, staleRefreshRun}, // Average of the others
+		{"paris", 3, false, staleRefreshBudget},
+		{"oslo", 0.5, false, staleRefreshRun}, // Still fits after paris didn't
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("plan = %+v", plan)
+	}
+	for i, w := range want {
+		p := plan[i]
+		if p.ID != w.id || p.CostUSD != w.cost || p.Guessed != w.guessed || p.Action != w.action {
+			t.Errorf("plan[%d] = %+v, want %+v", i, p, w)
+		}
+	}
+	if n, total := staleRefreshTotal(plan); n != 3 || total != 7 {
+		t.Errorf("total = %d, $%v", n, total)
+	}
+
+	// --max stops after the oldest ones; without a budget costs don't matter
+	plan, err = planStaleRefresh(locs, now, 6*time.Hour, 2, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := staleRefreshTotal(plan); n != 2 || plan[2].Action != staleRefreshMax {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	// A budget can't be applied without any estimate
+	unpriced := []database.Location{{ID: "lima", IsPreset: true, LastUpdated: now.Add(-20 * time.Hour)}}
+	if _, err := planStaleRefresh(unpriced, now, 6*time.Hour, 0, 8, false); err == nil {
+		t.Error("expected an error for a budget without estimates")
+	}
+	if plan, err := planStaleRefresh(unpriced, now, 6*time.Hour, 0, 0, false); err != nil || len(plan) != 1 {
+		t.Errorf("plan = %+v, %v", plan, err)
+	}
+}
+
+func TestParseBudget(t *testing.T) {
+	for in, want := range map[string]float64{"": 0, "20": 20, "$20": 20, " $7.5 ": 7.5} {
+		if got, err := parseBudget(in); err != nil || got != want {
+			t.Errorf("parseBudget(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"twenty", "-5"} {
+		if _, err := parseBudget(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestPlanStaleRefresh_Locked(t *testing.T) {
+	now := time.Now()
+	locs := []database.Location{
+		{ID: "paris", IsPreset: true, LastUpdated: now.Add(-48 * time.Hour)},
+		{ID: "venice", IsPreset: true, Locked: true, LastUpdated: now.Add(-72 * time.Hour)},
+	}
+	if plan, _ := planStaleRefresh(locs, now, time.Hour, 0, 0, false); len(plan) != 1 || plan[0].ID != "paris" {
+		t.Errorf("Expected the locked preset left out, got %+v", plan)
+	}
+	if plan, _ := planStaleRefresh(locs, now, time.Hour, 0, 0, true); len(plan) != 2 || plan[0].ID != "venice" {
+		t.Errorf("Expected the locked preset planned with the override, got %+v", plan)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+// remoteClient calls the server's /api/admin endpoints instead of talking to
+// Firestore and GCS directly, so operators only need an API key.
+type remoteClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// remoteFromFlags returns a client when --remote (or BANANA_REMOTE) is set, nil otherwise.
+func remoteFromFlags(cmd *cobra.Command) *remoteClient {
+	base, _ := cmd.Flags().GetString("remote")
+	if base == "" {
+		base = os.Getenv("BANANA_REMOTE")
+	}
+	if base == "" {
+		return nil
+	}
+	key, _ := cmd.Flags().GetString("api-key")
+	if key == "" {
+		key = os.Getenv("BANANA_API_KEY")
+	}
+	return &remoteClient{
+		baseURL: strings.TrimSuffix(base, "/"),
+		apiKey:  key,
+		// Refresh waits for Veo, so allow plenty of time.
+		http: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// remoteAnnotation marks the admin commands that run against --remote.
+const remoteAnnotation = "banana/remote"
+
+// supportsRemote marks cmd as running against --remote when it's set.
+func supportsRemote(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[remoteAnnotation] = "true"
+}
+
+// checkRemote refuses --remote (or BANANA_REMOTE) for admin commands that
+// only run locally, which would otherwise act on the project of the local
+// credentials.
+func checkRemote(cmd *cobra.Command, args []string) error {
+	if cmd.Annotations[remoteAnnotation] != "" || remoteFromFlags(cmd) == nil {
+		return nil
+	}
+	return fmt.Errorf("%s doesn't support --remote (or BANANA_REMOTE); unset it to run against the project of your local credentials", cmd.CommandPath())
+}
+
+func (c *remoteClient) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/admin"+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *remoteClient) GetStats(ctx context.Context) (*database.Stats, error) {
+	var stats database.Stats
+	if err := c.do(ctx, http.MethodGet, "/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (c *remoteClient) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(opts.Limit))
+	q.Set("type", opts.Type)
+	q.Set("sort", opts.Sort)
+	q.Set("status", string(opts.Status))
+	var locs []database.Location
+	if err := c.do(ctx, http.MethodGet, "/locations?"+q.Encode(), nil, &locs); err != nil {
+		return nil, err
+	}
+	return locs, nil
+}
+
+func (c *remoteClient) RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error) {
+	body := map[string]any{
+		"style":      opts.Style,
+		"image_only": opts.ImageOnly,
+		"video_only": opts.VideoOnly,
+		"priority":   opts.Priority,
+		"grounding":  opts.Grounding,
+	}
+	if opts.OverrideLock {
+		body["override_lock"] = true
+	}
+	if opts.OverrideHidden {
+		body["override_hidden"] = true
+	}
+	if opts.SkipUnchanged {
+		body["skip_unchanged"] = true
+	}
+	if opts.RequireApproval {
+		body["require_approval"] = true
+	}
+	if opts.Seed != nil {
+		body["seed"] = *opts.Seed
+	}
+	var loc database.Location
+	if err := c.do(ctx, http.MethodPost, "/locations/"+url.PathEscape(id)+"/refresh", body, &loc); err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+func (c *remoteClient) DeleteLocation(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/locations/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *remoteClient) RunCityOfTheDay(ctx context.Context, id string) (*database.FeaturedCity, error) {
+	var f database.FeaturedCity
+	if err := c.do(ctx, http.MethodPost, "/city-of-the-day", map[string]any{"id": id}, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (c *remoteClient) SLOReport(ctx context.Context, window time.Duration) (*database.SLOReport, error) {
+	var report database.SLOReport
+	if err := c.do(ctx, http.MethodGet, "/slo?window="+url.QueryEscape(window.String()), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *remoteClient) GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error) {
+	var t database.FlowTrace
+	if err := c.do(ctx, http.MethodGet, "/traces/"+url.PathEscape(id), nil, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (c *remoteClient) ListFlowTraces(ctx context.Context, limit int) ([]database.FlowTrace, error) {
+	var traces []database.FlowTrace
+	if err := c.do(ctx, http.MethodGet, "/traces?limit="+strconv.Itoa(limit), nil, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
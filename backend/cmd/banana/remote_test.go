@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRemote(t *testing.T) {
+	t.Setenv("BANANA_REMOTE", "")
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"admin", "list"}, true},
+		{[]string{"admin", "refresh"}, true},
+		{[]string{"admin", "lock"}, false},
+		{[]string{"admin", "killswitch"}, false},
+		{[]string{"admin", "ops", "cancel"}, false},
+	} {
+		cmd, _, err := rootCmd.Find(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags([]string{"--remote", "https://api.example.com"}); err != nil {
+			t.Fatal(err)
+		}
+		err = checkRemote(cmd, nil)
+		if tc.ok && err != nil {
+			t.Errorf("%v: expected --remote to be supported, got %v", tc.args, err)
+		}
+		if !tc.ok && (err == nil || !strings.Contains(err.Error(), "doesn't support --remote")) {
+			t.Errorf("%v: expected --remote to be refused, got %v", tc.args, err)
+		}
+		cmd.Flags().Set("remote", "")
+		if err := checkRemote(cmd, nil); err != nil {
+			t.Errorf("%v: expected local runs to be allowed, got %v", tc.args, err)
+		}
+	}
+
+	t.Setenv("BANANA_REMOTE", "https://api.example.com")
+	cmd, _, _ := rootCmd.Find([]string{"admin", "lock"})
+	if err := checkRemote(cmd, nil); err == nil {
+		t.Error("Expected BANANA_REMOTE to be refused too")
+	}
+}
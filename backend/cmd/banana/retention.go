@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Apply the retention policy to user-generated media",
+}
+
+var retentionRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Move or delete media of old user locations",
+	Long: `Apply RETENTION_POLICY (or --policy) to user-generated locations: media of locations
+not updated within a rule's age is moved to Coldline or deleted. Deleting an image purges
+the location, as does an expire_at (Firestore TTL) that has passed; the expire_at of the
+others is set to match the policy. Presets are never touched. Use --dry-run to list the
+steps first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		policy, _ := cmd.Flags().GetString("policy")
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+		if policy == "" {
+			policy = cfg.RetentionPolicy
+		}
+		rules, err := jobs.ParseRetentionPolicy(policy)
+		if err != nil {
+			log.Fatalf("Invalid retention policy: %v", err)
+		}
+
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+
+		media := map[storage.Kind]storage.Store{}
+		for _, kind := range []storage.Kind{storage.KindImage, storage.KindVideo, storage.KindOriginal} {
+			s, err := storage.OpenKind(ctx, cfg, kind)
+			if err != nil {
+				log.Fatalf("Storage init failed: %v", err)
+			}
+			if s != nil {
+				media[kind] = s
+			}
+		}
+
+		job := &jobs.Retention{DB: db, Media: media, Rules: rules, DryRun: dryRun}
+		steps, err := job.Run(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if steps == nil {
+			steps = []jobs.RetentionStep{}
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, steps, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Location\tKind\tAction\tObject\tAge\tError")
+			fmt.Fprintln(w, "--------\t----\t------\t------\t---\t-----")
+			for _, s := range steps {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%dd\t%s\n", s.Location, s.Kind, s.Action, s.Object, int(s.Age/(24*time.Hour)), s.Error)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if dryRun {
+			log.Printf("Dry run: %d steps planned, nothing changed.", len(steps))
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(retentionCmd)
+	retentionCmd.AddCommand(retentionRunCmd)
+
+	retentionRunCmd.Flags().Bool("dry-run", false, "List the steps without moving or deleting anything")
+	retentionRunCmd.Flags().String("policy", "", "Rules overriding RETENTION_POLICY, e.g. image:coldline:30d,video:delete:90d")
+	addOutputFlag(retentionRunCmd)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var sloCmd = &cobra.Command{
+	Use:   "slo",
+	Short: "Report generation latency percentiles and the cache hit rate",
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := cmd.Flags().GetString("window")
+		window, err := database.ParseWindow(w)
+		if err != nil {
+			log.Fatalf("Invalid --window %q: %v", w, err)
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		output, _ := cmd.Flags().GetString("output")
+		runSLO(ctx, backend, window, output)
+	},
+}
+
+func (l *localAdmin) SLOReport(ctx context.Context, window time.Duration) (*database.SLOReport, error) {
+	since := time.Now().Add(-window)
+	samples, err := l.ListLatencySamples(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	return database.SummarizeLatency(samples, since), nil
+}
+
+func runSLO(ctx context.Context, db adminBackend, window time.Duration, output string) {
+	report, err := db.SLOReport(ctx, window)
+	if err != nil {
+		log.Fatalf("Error getting SLO report: %v", err)
+	}
+
+	err = writeOutput(output, report, func(out io.Writer) {
+		fmt.Fprintf(out, "Latency since %s\n", report.Since.Format(time.RFC822))
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Stage\tCount\tErrors\tp50\tp95\tp99")
+		fmt.Fprintln(w, "-----\t-----\t------\t---\t---\t---")
+		for _, s := range report.Stages {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.1fs\t%.1fs\t%.1fs\n", s.Stage, s.Count, s.Errors, s.P50, s.P95, s.P99)
+		}
+		w.Flush()
+		fmt.Fprintf(out, "Cache hit rate: %.1f%% of %d lookups\n", report.CacheHitRate*100, report.CacheLookups)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	adminCmd.AddCommand(sloCmd)
+	supportsRemote(sloCmd)
+	sloCmd.Flags().String("window", "7d", "Report window: days (7d) or a duration (12h)")
+	addOutputFlag(sloCmd)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Inspect where each kind of media is stored",
+}
+
+var storagePlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Print the bucket routing and suggested lifecycle rules",
+	Long: `Show which bucket/prefix each kind of media is routed to (GENMEDIA_BUCKET, VIDEO_BUCKET,
+THUMBNAILS_BUCKET, ORIGINALS_BUCKET, UPLOADS_BUCKET, EXPORTS_BUCKET) and print a suggested
+lifecycle configuration per bucket. Nothing is changed; apply a rule file with
+` + "`gcloud storage buckets update gs://BUCKET --lifecycle-file=FILE`" + `.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		routes := storage.Routes(cfg)
+		plans := storage.Plan(routes)
+
+		output, _ := cmd.Flags().GetString("output")
+		err := writeOutput(output, plans, func(out io.Writer) {
+			for _, kind := range storage.Kinds {
+				r, ok := routes[kind]
+				if !ok {
+					fmt.Fprintf(out, "%-10s (not configured)\n", kind)
+					continue
+				}
+				fmt.Fprintf(out, "%-10s %s\n", kind, r.GSURI())
+			}
+			for _, p := range plans {
+				access := "private"
+				if p.Public {
+					access = "public"
+				}
+				kinds := make([]string, len(p.Kinds))
+				for i, k := range p.Kinds {
+					kinds[i] = string(k)
+				}
+				fmt.Fprintf(out, "\n# gs://%s (%s: %s)\n", p.Bucket, access, strings.Join(kinds, ", "))
+				for _, w := range p.Warnings {
+					fmt.Fprintf(out, "# WARNING: %s\n", w)
+				}
+				data, _ := json.MarshalIndent(p.Lifecycle, "", "  ")
+				fmt.Fprintln(out, string(data))
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storagePlanCmd)
+	addOutputFlag(storagePlanCmd)
+}
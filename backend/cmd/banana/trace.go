@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "Look up a weather flow by ID, or list recent traced ones",
+	Long: `Looks up one weather flow by the ID every client is sent in the X-Flow-ID
+header and the flow's first "flow" event (also in the server's log lines and
+the generation metadata of the media it made), so a user report can be tied
+to the server side. Prints the locations whose media the flow generated and,
+for flows sampled by FLOW_TRACE_SAMPLE, replays the events the client was
+sent, as kept in flow_traces.
+
+Without --flow, lists the latest traces.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		output, _ := cmd.Flags().GetString("output")
+
+		id, _ := cmd.Flags().GetString("flow")
+		if id == "" {
+			limit, _ := cmd.Flags().GetInt("limit")
+			runListTraces(ctx, backend, limit, output)
+			return
+		}
+		realtime, _ := cmd.Flags().GetBool("realtime")
+		runTrace(ctx, backend, id, realtime, output)
+	},
+}
+
+func runListTraces(ctx context.Context, db adminBackend, limit int, output string) {
+	traces, err := db.ListFlowTraces(ctx, limit)
+	if err != nil {
+		log.Fatalf("Error listing flow traces: %v", err)
+	}
+
+	err = writeOutput(output, traces, func(out io.Writer) {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Flow\tStarted\tDuration\tQuery\tError")
+		fmt.Fprintln(w, "----\t-------\t--------\t-----\t-----")
+		for _, t := range traces {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.StartedAt.Format(time.RFC822), t.FinishedAt.Sub(t.StartedAt).Round(time.Second), t.Query, t.Error)
+		}
+		w.Flush()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// FlowLookup is what banana admin trace knows of a flow: its trace, when it
+// was sampled, and the locations whose current media it generated.
+type FlowLookup struct {
+	Trace     *database.FlowTrace `json:"trace,omitempty"`
+	Locations []database.Location `json:"locations"`
+}
+
+// lookupFlow finds flow id's trace and the locations it generated. Most
+// flows aren't traced, so a missing trace is only an error when the flow
+// generated nothing either.
+func lookupFlow(ctx context.Context, db adminBackend, id string) (*FlowLookup, error) {
+	t, traceErr := db.GetFlowTrace(ctx, id)
+	locs, err := db.ListLocations(ctx, database.ListOptions{Type: "all"})
+	if err != nil {
+		return nil, err
+	}
+	lookup := &FlowLookup{Trace: t, Locations: []database.Location{}}
+	for _, loc := range locs {
+		if loc.Generation != nil && loc.Generation.FlowID == id {
+			lookup.Locations = append(lookup.Locations, loc)
+		}
+	}
+	if traceErr != nil && len(lookup.Locations) == 0 {
+		return nil, fmt.Errorf("no trace or generated media for flow %s: %w", id, traceErr)
+	}
+	return lookup, nil
+}
+
+// runTrace prints the locations a flow generated, then its events with their
+// offsets. With realtime, events are printed with the delays the client saw,
+// which makes a stall obvious.
+func runTrace(ctx context.Context, db adminBackend, id string, realtime bool, output string) {
+	lookup, err := lookupFlow(ctx, db, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = writeOutput(output, lookup, func(out io.Writer) {
+		for _, loc := range lookup.Locations {
+			fmt.Fprintf(out, "Generated %s (%s), updated %s: %s\n", loc.ID, loc.Status, loc.LastUpdated.Format(time.RFC3339), loc.ImageURL)
+		}
+		t := lookup.Trace
+		if t == nil {
+			fmt.Fprintf(out, "Flow %s wasn't traced (see FLOW_TRACE_SAMPLE).\n", id)
+			return
+		}
+		fmt.Fprintf(out, "Flow %s: %q started %s\n", t.ID, t.Query, t.StartedAt.Format(time.RFC3339))
+		var last int64
+		for _, e := range t.Events {
+			if realtime {
+				time.Sleep(time.Duration(e.OffsetMS-last) * time.Millisecond)
+				last = e.OffsetMS
+			}
+			fmt.Fprintf(out, "%+8.1fs  %-8s %s\n", float64(e.OffsetMS)/1000, e.Event, traceData(e))
+		}
+		fmt.Fprintf(out, "%+8.1fs  finished", t.FinishedAt.Sub(t.StartedAt).Seconds())
+		if t.Error != "" {
+			fmt.Fprintf(out, ": %s", t.Error)
+		}
+		fmt.Fprintln(out)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// traceData shortens an event's data for the terminal.
+func traceData(e database.FlowEvent) string {
+	const max = 120
+	if len(e.Data) > max {
+		size := e.Size
+		if size == 0 {
+			size = len(e.Data)
+		}
+		return fmt.Sprintf("%s... (%d bytes)", e.Data[:max], size)
+	}
+	return e.Data
+}
+
+func init() {
+	adminCmd.AddCommand(traceCmd)
+	supportsRemote(traceCmd)
+	traceCmd.Flags().String("flow", "", "Flow ID to look up (from X-Flow-ID, the \"flow\" event or a log line)")
+	traceCmd.Flags().Int("limit", 20, "Traces to list without --flow")
+	traceCmd.Flags().Bool("realtime", false, "Replay events with their original delays")
+	addOutputFlag(traceCmd)
+}
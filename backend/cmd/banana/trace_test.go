@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+type fakeFlowBackend struct {
+	adminBackend
+	traces map[string]database.FlowTrace
+	locs   []database.Location
+}
+
+func (f *fakeFlowBackend) GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error) {
+	t, ok := f.traces[id]
+	if !ok {
+		return nil, fmt.Errorf("flow trace %s not found", id)
+	}
+	return &t, nil
+}
+
+func (f *fakeFlowBackend) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	return f.locs, nil
+}
+
+func TestLookupFlow(t *testing.T) {
+	db := &fakeFlowBackend{
+		traces: map[string]database.FlowTrace{"beef": {ID: "beef", Query: "Oslo"}},
+		locs: []database.Location{
+			{ID: "paris__france", Generation: &database.GenerationMetadata{FlowID: "f00d"}},
+			{ID: "oslo__norway", Generation: &database.GenerationMetadata{FlowID: "beef"}},
+			{ID: "tokyo__japan"},
+		},
+	}
+
+	// Untraced flows are found by their media
+	lookup, err := lookupFlow(context.Background(), db, "f00d")
+	if err != nil || lookup.Trace != nil || len(lookup.Locations) != 1 || lookup.Locations[0].ID != "paris__france" {
+		t.Fatalf("Unexpected lookup %+v (%v)", lookup, err)
+	}
+	lookup, err = lookupFlow(context.Background(), db, "beef")
+	if err != nil || lookup.Trace == nil || len(lookup.Locations) != 1 {
+		t.Errorf("Expected the trace and the location, got %+v (%v)", lookup, err)
+	}
+	if _, err := lookupFlow(context.Background(), db, "nope"); err == nil {
+		t.Error("Expected an error for an unknown flow")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// Problems reported by verifyMedia
+const (
+	mediaMissing    = "missing"    // The object is gone
+	mediaMismatch   = "mismatch"   // Its CRC32C isn't the one recorded
+	mediaUnreadable = "unreadable" // Its metadata couldn't be read
+)
+
+// MediaIssue is one media object of a location that failed verification.
+type MediaIssue struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Issue    string `json:"issue"`
+	Recorded string `json:"recorded,omitempty"` // CRC32C on the location
+	Actual   string `json:"actual,omitempty"`   // CRC32C in GCS, or the read error
+}
+
+// mediaStatter reads object metadata by gs:// URI; storage.Copier has it.
+type mediaStatter interface {
+	StatURI(ctx context.Context, uri string) (*storage.ObjectInfo, error)
+}
+
+var verifyMediaCmd = &cobra.Command{
+	Use:   "verify-media",
+	Short: "Check every location's media against its recorded checksums",
+	Long: `Read the GCS metadata of every location's image, video, poster and narration, and report
+objects that are missing or whose CRC32C differs from the one recorded on the location when it was
+uploaded (Location.Checksums). Media outside GCS is skipped. Media without a recorded checksum
+(e.g. Veo videos, or anything uploaded before checksums were kept) is only checked for existence;
+--record stores its current checksum, so later runs catch changes. Exits 1 when there are issues.`,
+	Example: `  banana admin verify-media
+  banana admin verify-media --record -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		record, _ := cmd.Flags().GetBool("record")
+
+		ctx := context.Background()
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init DB: %v", err)
+		}
+		defer db.Close()
+		store, err := storage.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to init storage: %v", err)
+		}
+		statter := storage.AsCopier(store)
+		if statter == nil {
+			log.Fatalf("STORAGE_BACKEND %q can't read object metadata by gs:// URI", cfg.StorageBackend)
+		}
+
+		locs, err := db.ListLocations(ctx, database.ListOptions{})
+		if err != nil {
+			log.Fatalf("Failed to list locations: %v", err)
+		}
+		issues, recorded, err := verifyMedia(ctx, db, statter, locs, record)
+		if err != nil {
+			log.Fatalf("Verify failed: %v", err)
+		}
+		if record {
+			log.Printf("Recorded %d checksums.", recorded)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		err = writeOutput(output, issues, func(out io.Writer) {
+			if len(issues) == 0 {
+				fmt.Fprintf(out, "Media of %d locations verified.\n", len(locs))
+				return
+			}
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tIssue\tURL\tRecorded\tActual")
+			fmt.Fprintln(w, "--\t-----\t---\t--------\t------")
+			for _, i := range issues {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", i.ID, i.Issue, i.URL, i.Recorded, i.Actual)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(issues) > 0 {
+			log.Fatalf("%d media issues found.", len(issues))
+		}
+	},
+}
+
+// verifyMedia stats the GCS media of locs and returns the objects that are
+// missing, unreadable or don't match their recorded checksum. With record,
+// the checksums of media that had none are saved on the locations, and how
+// many were is returned.
+func verifyMedia(ctx context.Context, db repo.LocationStore, s mediaStatter, locs []database.Location, record bool) ([]MediaIssue, int, error) {
+	var issues []MediaIssue
+	recorded := 0
+	for _, loc := range locs {
+		found := map[string]string{}
+		for _, url := range loc.MediaURLs() {
+			uri, err := storage.GSURI(url)
+			if err != nil {
+				continue // Not in GCS
+			}
+			want := loc.Checksums[url]
+			info, err := s.StatURI(ctx, uri)
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				issues = append(issues, MediaIssue{ID: loc.ID, URL: url, Issue: mediaMissing, Recorded: want})
+			case err != nil:
+				issues = append(issues, MediaIssue{ID: loc.ID, URL: url, Issue: mediaUnreadable, Actual: err.Error()})
+			case want != "" && info.CRC32C != want:
+				issues = append(issues, MediaIssue{ID: loc.ID, URL: url, Issue: mediaMismatch, Recorded: want, Actual: info.CRC32C})
+			case want == "" && info.CRC32C != "":
+				found[url] = info.CRC32C
+			}
+		}
+		if !record || len(found) == 0 {
+			continue
+		}
+		loc.RecordChecksums(found)
+		if err := db.SetChecksums(ctx, loc.ID, loc.Checksums); err != nil {
+			return issues, recorded, fmt.Errorf("%s: %w", loc.ID, err)
+		}
+		recorded += len(found)
+	}
+	return issues, recorded, nil
+}
+
+func init() {
+	adminCmd.AddCommand(verifyMediaCmd)
+	verifyMediaCmd.Flags().Bool("record", false, "Record the current checksum of media that has none")
+	addOutputFlag(verifyMediaCmd)
+}
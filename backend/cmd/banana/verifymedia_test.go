@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/storage"
+)
+
+// fakeStatter serves object checksums by gs:// URI; other URIs are missing.
+type fakeStatter map[string]string
+
+func (f fakeStatter) StatURI(ctx context.Context, uri string) (*storage.ObjectInfo, error) {
+	if uri == "gs://media/broken.png" {
+		return nil, errors.New("permission denied")
+	}
+	sum, ok := f[uri]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &storage.ObjectInfo{CRC32C: sum}, nil
+}
+
+func TestVerifyMedia(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+	locs := []database.Location{
+		{ID: "paris", ImageURL: "https://storage.googleapis.com/media/paris.png", VideoURL: "gs://media/paris.mp4",
+			Checksums: map[string]string{"https://storage.googleapis.com/media/paris.png": "AAAAAA=="}},
+		{ID: "tokyo", ImageURL: "https://storage.googleapis.com/media/tokyo.png", PosterURL: "https://example.com/poster.jpg",
+			Checksums: map[string]string{"https://storage.googleapis.com/media/tokyo.png": "BBBBBB=="}},
+		{ID: "oslo", ImageURL: "gs://media/broken.png", VideoURL: "gs://media/oslo.mp4"},
+	}
+	for _, l := range locs {
+		db.PutLocation(ctx, l)
+	}
+	s := fakeStatter{
+		"gs://media/paris.png": "CCCCCC==",
+		"gs://media/paris.mp4": "DDDDDD==",
+		"gs://media/tokyo.png": "BBBBBB==",
+	}
+
+	issues, recorded, err := verifyMedia(ctx, db, s, locs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MediaIssue{
+		{ID: "paris", URL: "https://storage.googleapis.com/media/paris.png", Issue: mediaMismatch, Recorded: "AAAAAA==", Actual: "CCCCCC=="},
+		{ID: "oslo", URL: "gs://media/broken.png", Issue: mediaUnreadable, Actual: "permission denied"},
+		{ID: "oslo", URL: "gs://media/oslo.mp4", Issue: mediaMissing},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("Issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+	if recorded != 0 {
+		t.Errorf("Expected nothing recorded without --record, got %d", recorded)
+	}
+
+	if _, recorded, err = verifyMedia(ctx, db, s, locs, true); err != nil || recorded != 1 {
+		t.Fatalf("Expected the paris video recorded, got %d, %v", recorded, err)
+	}
+	loc, _ := db.GetLocation(ctx, "paris")
+	if loc.Checksums["gs://media/paris.mp4"] != "DDDDDD==" || loc.Checksums["https://storage.googleapis.com/media/paris.png"] != "AAAAAA==" {
+		t.Errorf("Expected the video's checksum added and the image's kept, got %v", loc.Checksums)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+
+	"banana-weather/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+var setVideoPromptCmd = &cobra.Command{
+	Use:   "set-video-prompt",
+	Short: "Set the Veo motion prompt of a location",
+	Long: `Set the prompt Veo animates a location's image with, used from its next refresh on,
+for scenes the default prompt fits poorly. An empty --prompt restores the default.
+
+  banana admin set-video-prompt --id venice --prompt "Gondolas glide along the canal as the water shimmers"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+		if id == "" {
+			log.Fatal("id is required (use --id)")
+		}
+		if !cmd.Flags().Changed("prompt") {
+			log.Fatal("prompt is required (use --prompt, or --prompt \"\" for the default)")
+		}
+		prompt, _ := cmd.Flags().GetString("prompt")
+		if _, err := database.ParseEditValue("video_prompt", prompt); err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := context.Background()
+		backend, closeFn := openAdminBackend(ctx, cmd)
+		defer closeFn()
+		if err := backend.SetVideoPrompt(ctx, id, prompt); err != nil {
+			log.Fatalf("Failed to set the video prompt: %v", err)
+		}
+		if prompt == "" {
+			log.Printf("%s uses the default video prompt from its next refresh.", id)
+			return
+		}
+		log.Printf("Set the video prompt of %s; it applies from the next refresh.", id)
+	},
+}
+
+func (l *localAdmin) SetVideoPrompt(ctx context.Context, id, prompt string) error {
+	value, err := database.ParseEditValue("video_prompt", prompt)
+	if err != nil {
+		return err
+	}
+	if _, err := l.GetLocation(ctx, id); err != nil {
+		return err
+	}
+	return l.UpdateLocations(ctx, []database.LocationEdit{{ID: id, Fields: map[string]any{"video_prompt": value}}})
+}
+
+func (c *remoteClient) SetVideoPrompt(ctx context.Context, id, prompt string) error {
+	return c.do(ctx, http.MethodPut, "/locations/"+url.PathEscape(id)+"/video-prompt", map[string]any{"prompt": prompt}, nil)
+}
+
+func init() {
+	adminCmd.AddCommand(setVideoPromptCmd)
+	supportsRemote(setVideoPromptCmd)
+	setVideoPromptCmd.Flags().String("id", "", "Location ID")
+	setVideoPromptCmd.Flags().String("prompt", "", "Veo motion prompt (empty for the default)")
+}
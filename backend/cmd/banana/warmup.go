@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+	"banana-weather/internal/weather"
+
+	"github.com/spf13/cobra"
+)
+
+var warmupCmd = &cobra.Command{
+	Use:   "warmup",
+	Short: "Pre-populate the cache for a list of cities",
+	Long: `Generate media for every city in a list ahead of a launch or demo, so the
+first visitors get cache hits. Cities whose entries are still fresh, hidden, or
+presets are skipped, and at most --max-generations new entries are generated.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listPath, _ := cmd.Flags().GetString("list")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		imageOnly, _ := cmd.Flags().GetBool("image-only")
+		maxGen, _ := cmd.Flags().GetInt("max-generations")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if listPath == "" {
+			log.Fatal("--list is required")
+		}
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		cities, err := readCityList(listPath)
+		if err != nil {
+			log.Fatalf("Failed to read list: %v", err)
+		}
+		if len(cities) == 0 {
+			log.Fatal("No cities in list")
+		}
+
+		ctx := withPriority(context.Background(), cmd, quota.PriorityBatch)
+		cfg, _ := config.Load()
+		if cfg == nil {
+			log.Fatal("Config load failed")
+		}
+
+		mapsService, err := maps.NewService(cfg.GoogleMapsKey)
+		if err != nil {
+			log.Fatalf("Maps init failed: %v", err)
+		}
+		genaiService, err := genai.NewService(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+		if err != nil {
+			log.Fatalf("GenAI init failed: %v", err)
+		}
+		storageService, err := storage.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Storage init failed: %v", err)
+		}
+		db, err := repo.Open(ctx, cfg)
+		if err != nil {
+			log.Fatalf("DB init failed: %v", err)
+		}
+		defer db.Close()
+		configureGenAI(ctx, cfg, genaiService, db, storageService)
+
+		svc := weather.NewService(mapsService, weather.WithChaos(genaiService, cfg.ChaosImageFail, cfg.ChaosVeoDelay), storageService, db)
+		configureWeatherCheck(cfg, svc, genaiService)
+		svc.Provenance = cfg.Signer()
+		svc.Hooks = openHooks(cfg)
+		svc.Events = openEvents(ctx, cfg)
+		if orig := openOriginals(ctx, cfg); orig != nil {
+			svc.Originals = orig
+		}
+		if posters := openPosters(cfg); posters != nil {
+			svc.Posters = posters
+		}
+		if streams := openStreams(ctx, cfg); streams != nil {
+			svc.Streams = streams
+		}
+		if cfg.AltText {
+			svc.Describer = genaiService
+		}
+		if cfg.Narration {
+			svc.Narrator, svc.Audio, svc.NarrationLangs = genaiService, storageService, cfg.NarrationLangs
+		}
+		svc.Runtime, svc.Tenant = db, cfg.TenantID
+		svc.Policy, err = weather.LoadLocationPolicy(ctx, db, cfg.TenantID, cfg.LocationPolicy())
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		// Budget is reserved before generating, so concurrent workers can't overshoot it
+		var budget atomic.Int64
+		budget.Store(int64(maxGen))
+		var generated, skipped, failed atomic.Int64
+
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for city := range jobs {
+					t, err := svc.PlanWarm(ctx, city)
+					if err != nil {
+						log.Printf("[%s] %v", city, err)
+						failed.Add(1)
+						continue
+					}
+					if t.Skip != "" {
+						log.Printf("[%s] Skipping %s (%s)", city, t.ID, t.Skip)
+						skipped.Add(1)
+						continue
+					}
+					if maxGen > 0 && budget.Add(-1) < 0 {
+						log.Printf("[%s] Skipping %s (budget exhausted)", city, t.ID)
+						skipped.Add(1)
+						continue
+					}
+					if dryRun {
+						log.Printf("[%s] Would generate %s", city, t.ID)
+						generated.Add(1)
+						continue
+					}
+
+					log.Printf("[%s] Generating %s...", city, t.ID)
+					loc, err := svc.Warm(ctx, t, imageOnly)
+					if err != nil {
+						log.Printf("[%s] Failed: %v", city, err)
+						failed.Add(1)
+						continue
+					}
+					log.Printf("[%s] Done: %s", city, loc.ImageURL)
+					generated.Add(1)
+				}
+			}()
+		}
+
+		for _, c := range cities {
+			jobs <- c
+		}
+		close(jobs)
+		wg.Wait()
+
+		verb := "Generated"
+		if dryRun {
+			verb = "Would generate"
+		}
+		fmt.Printf("%s: %d, Skipped: %d, Failed: %d\n", verb, generated.Load(), skipped.Load(), failed.Load())
+	},
+}
+
+// readCityList reads one city per line, ignoring blank lines and # comments.
+func readCityList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cities []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cities = append(cities, line)
+	}
+	return cities, scanner.Err()
+}
+
+func init() {
+	rootCmd.AddCommand(warmupCmd)
+
+	warmupCmd.Flags().String("list", "", "File with one city per line")
+	warmupCmd.Flags().Int("concurrency", 4, "Number of cities generated in parallel")
+	warmupCmd.Flags().Bool("image-only", false, "Skip Veo, only generate images")
+	warmupCmd.Flags().Int("max-generations", 100, "Stop generating after this many new entries (0 = no limit)")
+	warmupCmd.Flags().Bool("dry-run", false, "Resolve cities and report what would be generated")
+	warmupCmd.Flags().String("priority", "", "Quota priority: interactive, scheduled or batch (default batch)")
+}
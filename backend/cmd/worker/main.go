@@ -0,0 +1,307 @@
+// Command worker runs a batch of preset generations as a task of a Cloud Run
+// Jobs job (or an indexed GKE Job). The run spec comes from flags or the
+// environment; each preset's progress is checkpointed in the run's items,
+// so retried or re-executed tasks resume, and the exit status tells the
+// orchestrator whether a retry can help.
+//
+//	worker -run nightly-2026-10-15 -source gs://bucket/presets.csv
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"banana-weather/internal/branding"
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/httppool"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/media"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/promptcache"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Exit statuses.
+const (
+	exitOK     = 0
+	exitFailed = 1 // Some presets failed or the task was interrupted; a retry resumes
+	exitSpec   = 2 // Bad run spec or configuration; a retry won't help
+)
+
+// spec is what a task runs.
+type spec struct {
+	RunID  string
+	Source string // Preset CSV: gs:// URI or file path; empty to take the run's
+	Force  bool
+	Task   int
+	Tasks  int
+}
+
+// parseSpec reads the spec from args, falling back to the environment:
+// RUN_ID (or the Cloud Run execution name), RUN_SOURCE and RUN_FORCE, and the
+// task index and count Cloud Run Jobs (or an indexed GKE Job) set.
+func parseSpec(args []string, getenv func(string) string) (spec, error) {
+	var s spec
+	envInt := func(names ...string) int {
+		for _, name := range names {
+			if n, err := strconv.Atoi(getenv(name)); err == nil {
+				return n
+			}
+		}
+		return 0
+	}
+	runID := getenv("RUN_ID")
+	if runID == "" {
+		runID = getenv("CLOUD_RUN_EXECUTION")
+	}
+	force, _ := strconv.ParseBool(getenv("RUN_FORCE"))
+
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&s.RunID, "run", runID, "Run ID; a run with this ID resumes (env RUN_ID, default the Cloud Run execution)")
+	fs.StringVar(&s.Source, "source", getenv("RUN_SOURCE"), "Preset CSV (id,name,city,category[,context]): gs:// URI or file path (env RUN_SOURCE)")
+	fs.BoolVar(&s.Force, "force", force, "Overwrite existing presets' media, except locked ones (env RUN_FORCE)")
+	fs.IntVar(&s.Task, "task", envInt("CLOUD_RUN_TASK_INDEX", "JOB_COMPLETION_INDEX"), "Index of this task")
+	fs.IntVar(&s.Tasks, "tasks", max(envInt("CLOUD_RUN_TASK_COUNT"), 1), "Number of tasks the presets are split between")
+	if err := fs.Parse(args); err != nil {
+		return s, err
+	}
+	if fs.NArg() > 0 {
+		return s, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if s.RunID == "" {
+		return s, errors.New("run ID is required (use -run or RUN_ID)")
+	}
+	if s.Tasks < 1 || s.Task < 0 || s.Task >= s.Tasks {
+		return s, fmt.Errorf("task %d is out of range for %d tasks", s.Task, s.Tasks)
+	}
+	return s, nil
+}
+
+// specError marks errors a retry won't fix.
+type specError struct{ err error }
+
+func (e specError) Error() string { return e.err.Error() }
+func (e specError) Unwrap() error { return e.err }
+
+// exitStatus maps the outcome of a task to its exit status.
+func exitStatus(err error) int {
+	var se specError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &se):
+		return exitSpec
+	default:
+		return exitFailed
+	}
+}
+
+// resolveRun returns the run of s: the stored one when it exists, so every
+// task and retry of an execution runs the same source, or a new one.
+func resolveRun(ctx context.Context, db repo.RunStore, s spec) (database.Run, bool, error) {
+	run, err := db.GetRun(ctx, s.RunID)
+	switch {
+	case err == nil:
+		if s.Source != "" && s.Source != run.Source {
+			return *run, false, specError{fmt.Errorf("run %s reads %s, not %s; use a new run ID", run.ID, run.Source, s.Source)}
+		}
+		return *run, true, nil
+	case status.Code(err) != codes.NotFound:
+		return database.Run{}, false, fmt.Errorf("failed to read run %s: %w", s.RunID, err)
+	case s.Source == "":
+		return database.Run{}, false, specError{fmt.Errorf("run %s doesn't exist and no source is set (use -source or RUN_SOURCE)", s.RunID)}
+	}
+	return database.Run{ID: s.RunID, Source: s.Source, Force: s.Force}, false, nil
+}
+
+// uriReader reads objects by gs:// URI. *storage.Service implements it.
+type uriReader interface {
+	ReadURI(ctx context.Context, uri string) ([]byte, error)
+}
+
+// loadPresets reads and parses the preset CSV at source; objects reads
+// gs:// sources and may be nil.
+func loadPresets(ctx context.Context, source string, objects uriReader) ([]jobs.Preset, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "gs://") {
+		if objects == nil {
+			return nil, specError{fmt.Errorf("can't read %s: the storage backend doesn't read gs:// URIs", source)}
+		}
+		data, err = objects.ReadURI(ctx, source)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return nil, specError{err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	presets, err := jobs.ParsePresetCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, specError{fmt.Errorf("%s: %w", source, err)}
+	}
+	return presets, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := exitStatus(execute(ctx, os.Args[1:]))
+	stop()
+	os.Exit(code)
+}
+
+// execute runs the task of args, logging why it failed.
+func execute(ctx context.Context, args []string) error {
+	s, err := parseSpec(args, os.Getenv)
+	if err != nil {
+		log.Printf("Invalid run spec: %v", err)
+		return specError{err}
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return specError{err}
+	}
+	db, err := repo.Open(ctx, cfg)
+	if err != nil {
+		log.Printf("Failed to init DB: %v", err)
+		return err
+	}
+	defer db.Close()
+	pools := httppool.Open(cfg)
+	p, err := openPipeline(ctx, cfg, db, pools)
+	if err != nil {
+		log.Printf("Failed to init the pipeline: %v", err)
+		return err
+	}
+
+	run, existed, err := resolveRun(ctx, db, s)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	objects, _ := p.Storage.(uriReader)
+	presets, err := loadPresets(ctx, run.Source, objects)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	if !existed || run.Total != len(presets) {
+		run.Total = len(presets)
+		if err := db.SaveRun(ctx, run); err != nil {
+			log.Printf("Failed to save run %s: %v", run.ID, err)
+			return err
+		}
+	}
+	log.Printf("Run %s: task %d of %d, %d presets from %s (Force: %v)", run.ID, s.Task, s.Tasks, len(presets), run.Source, run.Force)
+
+	job := &jobs.Batch{DB: db, Generator: p, Runtime: db, Tenant: cfg.TenantID, Task: s.Task, Tasks: s.Tasks}
+	if m, err := maps.NewServiceWithHTTPClient(cfg.GoogleMapsKey, pools.Get(httppool.Maps).Client()); err == nil {
+		job.Maps = m
+	} else {
+		log.Printf("Warning: Maps unavailable, locations won't be geocoded: %v", err)
+	}
+	report, err := job.Run(quota.WithPriority(ctx, quota.PriorityBatch), run, presets)
+	if report != nil {
+		log.Printf("Run %s task %d: %d generated, %d updated, %d already done, %d failed",
+			run.ID, s.Task, report.Generated, report.Updated, report.Resumed, report.Failed)
+	}
+	if err != nil {
+		log.Printf("Run %s task %d failed: %v", run.ID, s.Task, err)
+	}
+	return err
+}
+
+// openPipeline builds the generation pipeline as banana generate does, so
+// worker-generated presets match the CLI's and the server's. Vertex AI and
+// GCS calls go through pools, like in the server.
+func openPipeline(ctx context.Context, cfg *config.Config, db repo.Repository, pools *httppool.Pools) (*pipeline.Pipeline, error) {
+	vertexHTTP, err := pools.Get(httppool.Vertex).GoogleClient(ctx)
+	if err != nil {
+		log.Printf("Warning: Vertex AI calls won't use the shared connection pool: %v", err)
+	}
+	gs, err := genai.NewServiceWithHTTPClient(ctx, cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel, vertexHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("GenAI: %w", err)
+	}
+	if gcsHTTP, err := pools.Get(httppool.GCS).GoogleClient(ctx); err != nil {
+		log.Printf("Warning: GCS calls won't use the shared connection pool: %v", err)
+	} else {
+		storage.SetHTTPClient(gcsHTTP)
+	}
+	ss, err := storage.Open(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	gs.SetOperationStore(db)
+	gs.SetTransport(cfg.GenAITransport)
+	gs.SetVideoOutput(storage.Routes(cfg)[storage.KindVideo].GSURI())
+	q, err := quota.Open(cfg)
+	if err != nil {
+		return nil, specError{fmt.Errorf("quota limits: %w", err)}
+	}
+	gs.SetQuota(q)
+	if cfg.PromptCache {
+		gs.SetImageCache(promptcache.New(db, ss))
+	}
+	if b, logo, err := branding.Load(ctx, db, ss, cfg.TenantID); err != nil {
+		log.Printf("Warning: Branding failed to load, continuing without it: %v", err)
+	} else if b != nil {
+		gs.SetBranding(b, logo)
+	}
+
+	// A hook that fails to load would publish media without e.g. a required
+	// watermark, so it stops the task like in the CLI.
+	h, err := hooks.Open(cfg)
+	if err != nil {
+		return nil, specError{fmt.Errorf("hooks: %w", err)}
+	}
+	p := &pipeline.Pipeline{GenAI: gs, Storage: ss, Provenance: cfg.Signer(), Hooks: h, Quota: q}
+	if bus, err := eventbus.Open(ctx, cfg); err != nil {
+		log.Printf("Warning: generation events disabled: %v", err)
+	} else {
+		p.Events = bus
+	}
+	if orig, err := storage.OpenOriginals(ctx, cfg); err != nil {
+		log.Printf("Warning: originals bucket unavailable, unbadged images won't be kept: %v", err)
+	} else if orig != nil {
+		p.Originals = orig
+	}
+	if posters, err := media.NewPosters(cfg.PosterFrame); err != nil {
+		log.Printf("Warning: video posters disabled: %v", err)
+	} else if posters != nil {
+		p.Posters = posters
+	}
+	if streams, err := media.OpenHLS(ctx, cfg); err != nil {
+		log.Printf("Warning: HLS transcoding disabled: %v", err)
+	} else if streams != nil {
+		p.Streams = streams
+	}
+	if cfg.AltText {
+		p.Describer = gs
+	}
+	return p, nil
+}
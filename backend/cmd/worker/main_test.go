@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+)
+
+func TestParseSpec(t *testing.T) {
+	env := map[string]string{
+		"CLOUD_RUN_EXECUTION":  "presets-abc12",
+		"CLOUD_RUN_TASK_INDEX": "2",
+		"CLOUD_RUN_TASK_COUNT": "4",
+		"RUN_SOURCE":           "gs://bucket/presets.csv",
+		"RUN_FORCE":            "true",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	s, err := parseSpec(nil, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != (spec{RunID: "presets-abc12", Source: "gs://bucket/presets.csv", Force: true, Task: 2, Tasks: 4}) {
+		t.Errorf("Unexpected spec from the environment: %+v", s)
+	}
+	s, err = parseSpec([]string{"-run", "nightly", "-source", "presets.csv", "-force=false", "-task", "0", "-tasks", "1"}, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != (spec{RunID: "nightly", Source: "presets.csv", Tasks: 1}) {
+		t.Errorf("Expected flags to override the environment, got %+v", s)
+	}
+
+	for _, args := range [][]string{{"-task", "4"}, {"-tasks", "0"}, {"extra"}, {"-unknown"}} {
+		if _, err := parseSpec(args, getenv); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+	if _, err := parseSpec(nil, func(string) string { return "" }); err == nil {
+		t.Error("Expected an error without a run ID")
+	}
+}
+
+func TestResolveRun(t *testing.T) {
+	ctx := context.Background()
+	db := mock.NewDB()
+
+	if _, _, err := resolveRun(ctx, db, spec{RunID: "r1"}); exitStatus(err) != exitSpec {
+		t.Errorf("Expected a spec error for a new run without a source, got %v", err)
+	}
+	run, existed, err := resolveRun(ctx, db, spec{RunID: "r1", Source: "a.csv", Force: true})
+	if err != nil || existed || run != (database.Run{ID: "r1", Source: "a.csv", Force: true}) {
+		t.Fatalf("Unexpected new run %+v (existed %v, %v)", run, existed, err)
+	}
+	if err := db.SaveRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+
+	// Retries and other tasks take the stored run's source and force.
+	run, existed, err = resolveRun(ctx, db, spec{RunID: "r1"})
+	if err != nil || !existed || run.Source != "a.csv" || !run.Force {
+		t.Errorf("Unexpected stored run %+v (existed %v, %v)", run, existed, err)
+	}
+	if _, _, err := resolveRun(ctx, db, spec{RunID: "r1", Source: "b.csv"}); exitStatus(err) != exitSpec {
+		t.Errorf("Expected a spec error for a different source, got %v", err)
+	}
+}
+
+func TestLoadPresets(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "presets.csv")
+	if err := os.WriteFile(path, []byte("id,name,city,category\nporto,Porto,Porto,Europe\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	presets, err := loadPresets(ctx, path, nil)
+	if err != nil || len(presets) != 1 || presets[0].ID != "porto" {
+		t.Errorf("Unexpected presets %+v (%v)", presets, err)
+	}
+	if _, err := loadPresets(ctx, path+".missing", nil); exitStatus(err) != exitSpec {
+		t.Errorf("Expected a spec error for a missing file, got %v", err)
+	}
+	if _, err := loadPresets(ctx, "gs://bucket/presets.csv", nil); exitStatus(err) != exitSpec {
+		t.Errorf("Expected a spec error without a gs:// reader, got %v", err)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	if exitStatus(nil) != exitOK || exitStatus(errors.New("2 presets failed")) != exitFailed ||
+		exitStatus(context.Canceled) != exitFailed || exitStatus(specError{errors.New("bad")}) != exitSpec {
+		t.Error("Unexpected exit statuses")
+	}
+}
@@ -0,0 +1,43 @@
+package branding
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// BadgeText is the label drawn by Badge.
+const BadgeText = "AI GENERATED"
+
+// Badge draws a small "AI GENERATED" label on a translucent dark pill in the
+// top-left corner of img (opposite the logo watermark) and returns PNG.
+func Badge(img []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := src.Bounds()
+
+	// One glyph pixel is ~1/300 of the image width: ~2px on a 768px image
+	unit := max(1, bounds.Dx()/300)
+	pad := 2 * unit
+	textW := TextWidth(BadgeText, unit)
+	textH := GlyphHeight * unit
+	inset := int(float64(bounds.Dx()) * margin)
+	box := image.Rect(0, 0, textW+2*pad, textH+2*pad).Add(bounds.Min).Add(image.Pt(inset, inset))
+
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, src, bounds.Min, draw.Src)
+	draw.Draw(out, box, image.NewUniform(color.NRGBA{A: 160}), image.Point{}, draw.Over)
+
+	DrawText(out, box.Min.Add(image.Pt(pad, pad)), BadgeText, unit, color.White)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+package branding
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Register JPEG decoding for model output
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultScale   = 0.2
+	defaultOpacity = 0.8
+	margin         = 0.03 // Fraction of image width kept clear around the logo
+)
+
+// Repo is the subset of the database client needed to load branding.
+type Repo interface {
+	GetBranding(ctx context.Context, tenant string) (*database.Branding, error)
+}
+
+// ObjectReader reads objects from the media bucket (for gs:// logo URLs).
+type ObjectReader interface {
+	ReadObject(ctx context.Context, fileName string) ([]byte, error)
+}
+
+// Load fetches the branding settings for a tenant along with the watermark logo.
+// A missing settings doc is not an error; it returns (nil, nil, nil).
+func Load(ctx context.Context, repo Repo, objects ObjectReader, tenant string) (*database.Branding, []byte, error) {
+	b, err := repo.GetBranding(ctx, tenant)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load branding: %w", err)
+	}
+	if b.WatermarkURL == "" {
+		return b, nil, nil
+	}
+
+	logo, err := fetchLogo(ctx, objects, b.WatermarkURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch watermark %s: %w", b.WatermarkURL, err)
+	}
+	log.Printf("Loaded branding (tenant: %q, watermark: %d bytes)", tenant, len(logo))
+	return b, logo, nil
+}
+
+func fetchLogo(ctx context.Context, objects ObjectReader, url string) ([]byte, error) {
+	if strings.HasPrefix(url, "gs://") {
+		if objects == nil {
+			return nil, fmt.Errorf("storage not available")
+		}
+		// gs://bucket/path -> path (logos must live in the media bucket)
+		_, object, err := storage.ParseGSURI(url)
+		if err != nil {
+			return nil, err
+		}
+		return objects.ReadObject(ctx, object)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ApplyPrompt appends the brand palette and prompt suffix to a rendered prompt.
+func ApplyPrompt(prompt string, b *database.Branding) string {
+	if b == nil {
+		return prompt
+	}
+	if len(b.Palette) > 0 {
+		prompt += fmt.Sprintf("\n\nColor palette: %s", strings.Join(b.Palette, ", "))
+	}
+	if b.PromptSuffix != "" {
+		prompt += "\n\n" + b.PromptSuffix
+	}
+	return prompt
+}
+
+// Watermark overlays logo (PNG) onto the bottom-right corner of img and
+// returns the result as PNG. The logo is scaled to b.WatermarkScale of the
+// image width and blended at b.WatermarkOpacity.
+func Watermark(img, logo []byte, b *database.Branding) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	mark, _, err := image.Decode(bytes.NewReader(logo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode watermark: %w", err)
+	}
+
+	scale, opacity := defaultScale, defaultOpacity
+	if b != nil && b.WatermarkScale > 0 {
+		scale = b.WatermarkScale
+	}
+	if b != nil && b.WatermarkOpacity > 0 {
+		opacity = b.WatermarkOpacity
+	}
+
+	bounds := src.Bounds()
+	w := int(float64(bounds.Dx()) * scale)
+	if w < 1 {
+		w = 1
+	}
+	mb := mark.Bounds()
+	h := w * mb.Dy() / mb.Dx()
+	if h < 1 {
+		h = 1
+	}
+	scaled := resize(mark, w, h)
+
+	pad := int(float64(bounds.Dx()) * margin)
+	at := image.Rect(bounds.Max.X-pad-w, bounds.Max.Y-pad-h, bounds.Max.X-pad, bounds.Max.Y-pad)
+
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, src, bounds.Min, draw.Src)
+	alpha := image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+	draw.DrawMask(out, at, scaled, image.Point{}, alpha, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales img to w x h using nearest-neighbour sampling.
+// Logos are small, so quality is adequate without pulling in x/image.
+func resize(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + y*b.Dy()/h
+		for x := 0; x < w; x++ {
+			sx := b.Min.X + x*b.Dx()/w
+			out.Set(x, y, img.At(sx, sy))
+		}
+	}
+	return out
+}
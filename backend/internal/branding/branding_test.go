@@ -0,0 +1,109 @@
+package branding
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestApplyPrompt(t *testing.T) {
+	b := &database.Branding{Palette: []string{"#FFD400", "#1A1A1A"}, PromptSuffix: "in the style of Acme"}
+	got := ApplyPrompt("base", b)
+	if !strings.Contains(got, "#FFD400, #1A1A1A") || !strings.HasSuffix(got, "in the style of Acme") {
+		t.Errorf("Unexpected prompt: %q", got)
+	}
+	if ApplyPrompt("base", nil) != "base" {
+		t.Error("Expected nil branding to leave the prompt unchanged")
+	}
+}
+
+func TestWatermark(t *testing.T) {
+	img := solidPNG(t, 90, 160, color.White)
+	logo := solidPNG(t, 10, 10, color.Black)
+
+	out, err := Watermark(img, logo, &database.Branding{WatermarkScale: 0.5, WatermarkOpacity: 1})
+	if err != nil {
+		t.Fatalf("Watermark failed: %v", err)
+	}
+	res, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Bounds() != image.Rect(0, 0, 90, 160) {
+		t.Errorf("Expected size to be preserved, got %v", res.Bounds())
+	}
+	// Top-left stays untouched, bottom-right (inside the margin) is covered.
+	if r, _, _, _ := res.At(0, 0).RGBA(); r != 0xffff {
+		t.Error("Expected top-left pixel to stay white")
+	}
+	if r, _, _, _ := res.At(80, 150).RGBA(); r != 0 {
+		t.Error("Expected bottom-right pixel to be covered by the logo")
+	}
+}
+
+func TestBadge(t *testing.T) {
+	img := solidPNG(t, 600, 1000, color.White)
+	out, err := Badge(img)
+	if err != nil {
+		t.Fatalf("Badge failed: %v", err)
+	}
+	res, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Bounds() != image.Rect(0, 0, 600, 1000) {
+		t.Errorf("Expected size to be preserved, got %v", res.Bounds())
+	}
+
+	// unit = 2, inset = 18: the pill starts at (18,18), the first glyph row at y=22
+	if r, _, _, _ := res.At(19, 19).RGBA(); r == 0xffff {
+		t.Error("Expected the badge background to darken the top-left corner")
+	}
+	if r, _, _, _ := res.At(22+2, 22).RGBA(); r != 0xffff {
+		t.Error("Expected the top of the 'A' to be white")
+	}
+	if r, _, _, _ := res.At(300, 500).RGBA(); r != 0xffff {
+		t.Error("Expected the rest of the image to stay untouched")
+	}
+}
+
+func TestFoldText(t *testing.T) {
+	tests := map[string]string{
+		"São Paulo":        "SAO PAULO",
+		"Zürich, 12°C":     "ZURICH, 12°C",
+		"東京 Tokyo 🌧":       "TOKYO",
+		"  Rain  ·  9:05 ": "RAIN · 9:05",
+	}
+	for in, want := range tests {
+		if got := FoldText(in); got != want {
+			t.Errorf("FoldText(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if w := TextWidth("São", 2); w != (3*6-1)*2 {
+		t.Errorf("TextWidth = %d", w)
+	}
+	if w := TextWidth("東京", 2); w != 0 {
+		t.Errorf("TextWidth of unsupported text = %d, want 0", w)
+	}
+}
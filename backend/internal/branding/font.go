@@ -0,0 +1,119 @@
+package branding
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// glyphs is a 5x7 bitmap font: uppercase Latin letters, digits and the
+// punctuation of city names, temperatures and dates. Rows go top to bottom,
+// '#' for a lit pixel. There's no font in the standard library, and labels
+// drawn on images are short, so this avoids a dependency.
+var glyphs = map[rune][7]string{
+	'A':  {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B':  {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C':  {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D':  {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E':  {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F':  {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G':  {".####", "#....", "#....", "#.###", "#...#", "#...#", ".###."},
+	'H':  {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "#####"},
+	'J':  {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K':  {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L':  {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N':  {"#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#", "#...#"},
+	'O':  {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P':  {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q':  {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R':  {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S':  {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V':  {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X':  {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y':  {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z':  {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0':  {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1':  {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2':  {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3':  {"####.", "....#", "....#", ".###.", "....#", "....#", "####."},
+	'4':  {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5':  {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6':  {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7':  {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8':  {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9':  {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	' ':  {".....", ".....", ".....", ".....", ".....", ".....", "....."},
+	'.':  {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	',':  {".....", ".....", ".....", ".....", ".##..", ".##..", ".#..."},
+	'-':  {".....", ".....", ".....", ".###.", ".....", ".....", "....."},
+	':':  {".....", ".##..", ".##..", ".....", ".##..", ".##..", "....."},
+	'\'': {"..#..", "..#..", ".#...", ".....", ".....", ".....", "....."},
+	'°':  {".##..", "#..#.", "#..#.", ".##..", ".....", ".....", "....."},
+	'/':  {"....#", "...#.", "...#.", "..#..", ".#...", ".#...", "#...."},
+	'(':  {"...#.", "..#..", ".#...", ".#...", ".#...", "..#..", "...#."},
+	')':  {".#...", "..#..", "...#.", "...#.", "...#.", "..#..", ".#..."},
+	'&':  {".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#"},
+	'!':  {"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#.."},
+	'?':  {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+	'·':  {".....", ".....", ".....", ".##..", ".##..", ".....", "....."},
+}
+
+// glyphAdvance is the width of a glyph plus the gap after it, in font pixels.
+const glyphAdvance = 6
+
+// GlyphHeight is the height of text in font pixels; DrawText scales it by
+// its unit.
+const GlyphHeight = 7
+
+// FoldText prepares text for the bitmap font: it's uppercased, accents are
+// dropped ("São Paulo" -> "SAO PAULO"), and characters the font lacks
+// (other scripts, emoji) are removed.
+func FoldText(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToUpper(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if _, ok := glyphs[r]; ok {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// TextWidth is the width in pixels of text drawn by DrawText at unit.
+func TextWidth(text string, unit int) int {
+	n := len([]rune(FoldText(text)))
+	if n == 0 {
+		return 0
+	}
+	return (n*glyphAdvance - 1) * unit
+}
+
+// DrawText draws text (see FoldText) with its top-left corner at pt, each
+// font pixel a unit x unit square of c.
+func DrawText(dst draw.Image, pt image.Point, text string, unit int, c color.Color) {
+	src := image.NewUniform(c)
+	x := pt.X
+	for _, r := range FoldText(text) {
+		for row, line := range glyphs[r] {
+			for col, p := range line {
+				if p != '#' {
+					continue
+				}
+				px := image.Rect(0, 0, unit, unit).Add(image.Pt(x+col*unit, pt.Y+row*unit))
+				draw.Draw(dst, px, src, image.Point{}, draw.Over)
+			}
+		}
+		x += glyphAdvance * unit
+	}
+}
@@ -0,0 +1,231 @@
+// Package cards renders share cards: a location's art with an info bar (city,
+// temperature and update time) at a fixed size, for newsletters, link
+// previews and story-format sharing. Cards are cached in the media bucket.
+package cards
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+
+	"banana-weather/internal/branding"
+	"banana-weather/internal/database"
+)
+
+// maxImageBytes caps the art downloaded for a card.
+const maxImageBytes = 20 << 20
+
+// Size is a card format. Title and Detail are the font units (see
+// branding.DrawText) of the city and of the line below it.
+type Size struct {
+	Name          string
+	Width, Height int
+	Title, Detail int
+}
+
+var (
+	Landscape = Size{Name: "landscape", Width: 1200, Height: 630, Title: 8, Detail: 4} // Link previews and newsletters
+	Story     = Size{Name: "story", Width: 1080, Height: 1920, Title: 12, Detail: 6}   // Instagram/WhatsApp stories
+)
+
+// Sizes lists every Size, for validation.
+var Sizes = []Size{Landscape, Story}
+
+// ParseSize returns the Size called name; "" is Landscape.
+func ParseSize(name string) (Size, error) {
+	if name == "" {
+		return Landscape, nil
+	}
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Size{}, fmt.Errorf("unknown card size %q (want landscape or story)", name)
+}
+
+// Store caches cards and holds the art. storage.Store implements it.
+type Store interface {
+	ReadObject(ctx context.Context, fileName string) ([]byte, error)
+	UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error)
+	ObjectName(url string) string
+}
+
+// Service renders cards and caches them in Store.
+type Service struct {
+	Store Store
+	HTTP  *http.Client // Downloads art that isn't in Store; http.DefaultClient when nil
+}
+
+// ObjectName is where the card of loc is cached. It changes whenever the
+// media is regenerated, so stale cards are never served.
+func ObjectName(loc *database.Location, size Size) string {
+	return fmt.Sprintf("cards/%s_%s_%d.png", loc.ID, size.Name, loc.LastUpdated.Unix())
+}
+
+// Card returns the PNG card of loc, rendering and caching it on first use.
+// A card that can't be cached is still returned.
+func (s *Service) Card(ctx context.Context, loc *database.Location, size Size) ([]byte, error) {
+	name := ObjectName(loc, size)
+	if data, err := s.Store.ReadObject(ctx, name); err == nil && len(data) > 0 {
+		return data, nil
+	}
+
+	art, err := s.art(ctx, loc.ImageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load art: %w", err)
+	}
+	card, err := Render(art, loc, size)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Store.UploadBytes(ctx, card, name, "image/png"); err != nil {
+		log.Printf("Failed to cache card %s: %v", name, err)
+	}
+	return card, nil
+}
+
+// art reads the image at url from Store when it's there, and over HTTP
+// otherwise.
+func (s *Service) art(ctx context.Context, url string) (image.Image, error) {
+	if name := s.Store.ObjectName(url); name != "" {
+		data, err := s.Store.ReadObject(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		return img, err
+	}
+
+	client := s.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	img, _, err := image.Decode(http.MaxBytesReader(nil, resp.Body, maxImageBytes))
+	return img, err
+}
+
+var (
+	barColor    = color.NRGBA{R: 16, G: 16, B: 28, A: 200}
+	accentColor = color.RGBA{R: 255, G: 214, B: 10, A: 255} // Banana yellow, as on wallet passes
+	detailColor = color.RGBA{R: 220, G: 220, B: 230, A: 255}
+)
+
+// Render draws the card of loc over art, which is scaled and cropped to
+// fill it, and returns PNG. The info bar along the bottom has the city
+// name and a detail line with the temperature observed when the art was
+// generated (when known) and the update time in UTC.
+func Render(art image.Image, loc *database.Location, size Size) ([]byte, error) {
+	out := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	fill(out, art)
+
+	pad := 6 * size.Detail
+	titleH, detailH := branding.GlyphHeight*size.Title, branding.GlyphHeight*size.Detail
+	bar := image.Rect(0, size.Height-(pad+titleH+pad/2+detailH+pad), size.Width, size.Height)
+	draw.Draw(out, bar, image.NewUniform(barColor), image.Point{}, draw.Over)
+	draw.Draw(out, image.Rect(bar.Min.X, bar.Min.Y, bar.Max.X, bar.Min.Y+size.Detail), image.NewUniform(accentColor), image.Point{}, draw.Src)
+
+	title, unit := fitText(loc.Name, size.Title, size.Detail, size.Width-2*pad)
+	y := bar.Min.Y + pad + (titleH-branding.GlyphHeight*unit)/2
+	branding.DrawText(out, image.Pt(pad, y), title, unit, color.White)
+	branding.DrawText(out, image.Pt(pad, bar.Min.Y+pad+titleH+pad/2), detailLine(loc), size.Detail, detailColor)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// detailLine is e.g. "24°C · Updated 15 Oct 2026 14:05 UTC".
+func detailLine(loc *database.Location) string {
+	line := "Updated " + loc.LastUpdated.UTC().Format("2 Jan 2006 15:04") + " UTC"
+	if loc.LastUpdated.IsZero() {
+		line = ""
+	}
+	if loc.TemperatureC != nil {
+		temp := fmt.Sprintf("%d°C", int(math.Round(*loc.TemperatureC)))
+		if line == "" {
+			return temp
+		}
+		return temp + " · " + line
+	}
+	return line
+}
+
+// fitText shrinks text from unit down to minUnit until it fits width, then
+// cuts it with "...". It returns the text (see branding.FoldText) and unit.
+func fitText(text string, unit, minUnit, width int) (string, int) {
+	text = branding.FoldText(text)
+	for unit > minUnit && branding.TextWidth(text, unit) > width {
+		unit--
+	}
+	for r := []rune(text); branding.TextWidth(text, unit) > width && len(r) > 1; {
+		r = r[:len(r)-1]
+		text = string(r) + "..."
+	}
+	return text, unit
+}
+
+// fill scales src to cover dst, cropping the overflow evenly, with bilinear
+// sampling: art is upscaled for story cards, where nearest-neighbour would
+// show blocks.
+func fill(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, sb.Dx(), sb.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, sb.Min, draw.Src)
+	}
+	sw, sh := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	dw, dh := dst.Bounds().Dx(), dst.Bounds().Dy()
+	if sw == 0 || sh == 0 {
+		return
+	}
+	scale := max(float64(dw)/float64(sw), float64(dh)/float64(sh))
+	offX := (float64(sw) - float64(dw)/scale) / 2
+	offY := (float64(sh) - float64(dh)/scale) / 2
+
+	at := func(x, y int) []uint8 {
+		x, y = min(max(x, 0), sw-1), min(max(y, 0), sh-1)
+		i := rgba.PixOffset(rgba.Rect.Min.X+x, rgba.Rect.Min.Y+y)
+		return rgba.Pix[i : i+4]
+	}
+	for y := range dh {
+		fy := offY + (float64(y)+0.5)/scale - 0.5
+		y0 := int(math.Floor(fy))
+		wy := fy - float64(y0)
+		for x := range dw {
+			fx := offX + (float64(x)+0.5)/scale - 0.5
+			x0 := int(math.Floor(fx))
+			wx := fx - float64(x0)
+			p00, p10, p01, p11 := at(x0, y0), at(x0+1, y0), at(x0, y0+1), at(x0+1, y0+1)
+			o := dst.PixOffset(x, y)
+			for c := range 4 {
+				top := float64(p00[c])*(1-wx) + float64(p10[c])*wx
+				bottom := float64(p01[c])*(1-wx) + float64(p11[c])*wx
+				dst.Pix[o+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package cards
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type fakeStore struct {
+	objects map[string][]byte
+	reads   []string
+}
+
+func (f *fakeStore) ReadObject(ctx context.Context, fileName string) ([]byte, error) {
+	f.reads = append(f.reads, fileName)
+	data, ok := f.objects[fileName]
+	if !ok {
+		return nil, errors.New("object doesn't exist")
+	}
+	return data, nil
+}
+func (f *fakeStore) UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error) {
+	f.objects[fileName] = data
+	return "https://storage.googleapis.com/bucket/" + fileName, nil
+}
+func (f *fakeStore) ObjectName(url string) string {
+	name, _ := strings.CutPrefix(url, "https://storage.googleapis.com/bucket/")
+	if name == url {
+		return ""
+	}
+	return name
+}
+
+func TestCard(t *testing.T) {
+	temp := 23.6
+	loc := &database.Location{
+		ID:           "sao_paulo",
+		Name:         "São Paulo",
+		ImageURL:     "https://storage.googleapis.com/bucket/sao_paulo.png",
+		TemperatureC: &temp,
+		LastUpdated:  time.Date(2026, 10, 15, 14, 5, 0, 0, time.UTC),
+	}
+	store := &fakeStore{objects: map[string][]byte{"sao_paulo.png": solidPNG(t, 768, 1376, color.RGBA{B: 255, A: 255})}}
+	s := &Service{Store: store}
+
+	for _, size := range Sizes {
+		data, err := s.Card(context.Background(), loc, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != image.Rect(0, 0, size.Width, size.Height) {
+			t.Errorf("%s card is %v", size.Name, img.Bounds())
+		}
+		// The art fills the top, the info bar darkens the bottom
+		if r, g, b, _ := img.At(size.Width/2, 10).RGBA(); r != 0 || g != 0 || b != 0xffff {
+			t.Errorf("%s card top = %v, want the art", size.Name, img.At(size.Width/2, 10))
+		}
+		if _, _, b, _ := img.At(size.Width-2, size.Height-2).RGBA(); b > 0x8000 {
+			t.Errorf("%s card bottom = %v, want the info bar", size.Name, img.At(size.Width-2, size.Height-2))
+		}
+		if _, ok := store.objects[ObjectName(loc, size)]; !ok {
+			t.Errorf("%s card wasn't cached", size.Name)
+		}
+	}
+
+	// Cached cards are served without reading the art
+	store.reads = nil
+	if _, err := s.Card(context.Background(), loc, Story); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.reads) != 1 || store.reads[0] != ObjectName(loc, Story) {
+		t.Errorf("read %v, want only the cached card", store.reads)
+	}
+}
+
+func TestDetailLine(t *testing.T) {
+	temp := -0.4
+	updated := time.Date(2026, 1, 2, 3, 4, 0, 0, time.FixedZone("JST", 9*3600))
+	tests := []struct {
+		loc  database.Location
+		want string
+	}{
+		{database.Location{TemperatureC: &temp, LastUpdated: updated}, "0°C · Updated 1 Jan 2026 18:04 UTC"},
+		{database.Location{LastUpdated: updated}, "Updated 1 Jan 2026 18:04 UTC"},
+		{database.Location{TemperatureC: &temp}, "0°C"},
+	}
+	for _, tt := range tests {
+		if got := detailLine(&tt.loc); got != tt.want {
+			t.Errorf("detailLine = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	if text, unit := fitText("Paris", 8, 4, 1000); text != "PARIS" || unit != 8 {
+		t.Errorf("short name = %q at %d", text, unit)
+	}
+	// 20 glyphs are 119 font pixels wide: 714px at 6, too wide for 600 at 8
+	if text, unit := fitText(strings.Repeat("A", 20), 8, 4, 600); text != strings.Repeat("A", 20) || unit != 5 {
+		t.Errorf("long name = %q at %d", text, unit)
+	}
+	text, unit := fitText(strings.Repeat("B", 40), 8, 4, 600)
+	if unit != 4 || !strings.HasSuffix(text, "...") || len(text) >= 40 {
+		t.Errorf("very long name = %q at %d", text, unit)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if s, err := ParseSize(""); err != nil || s != Landscape {
+		t.Errorf("default size = %v, %v", s, err)
+	}
+	if s, err := ParseSize("story"); err != nil || s.Width != 1080 || s.Height != 1920 {
+		t.Errorf("story = %v, %v", s, err)
+	}
+	if _, err := ParseSize("square"); err == nil {
+		t.Error("expected an error for an unknown size")
+	}
+}
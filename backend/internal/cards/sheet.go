@@ -0,0 +1,82 @@
+package cards
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+
+	"banana-weather/internal/branding"
+)
+
+// Contact sheet layout: square tiles, up to sheetColumns a row, each with
+// its label in a bar below.
+const (
+	sheetTile    = 512
+	sheetColumns = 4
+	sheetUnit    = 3 // Label font unit
+	sheetGap     = 8
+)
+
+// Panel is one tile of a contact sheet. A nil Image leaves the tile blank,
+// e.g. for a generation that failed.
+type Panel struct {
+	Label string
+	Image image.Image
+}
+
+// ContactSheet lays panels out side by side in a grid, each image scaled and
+// cropped to a square tile with its label below, and returns PNG. It's for
+// comparing generations, e.g. the same city in every style.
+func ContactSheet(panels []Panel) ([]byte, error) {
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("no panels")
+	}
+	cols := min(len(panels), sheetColumns)
+	rows := (len(panels) + cols - 1) / cols
+	pad := 2 * sheetUnit
+	labelH := pad + branding.GlyphHeight*sheetUnit + pad
+	cellW, cellH := sheetTile+sheetGap, sheetTile+labelH+sheetGap
+
+	out := image.NewRGBA(image.Rect(0, 0, sheetGap+cols*cellW, sheetGap+rows*cellH))
+	draw.Draw(out, out.Bounds(), image.NewUniform(barColor), image.Point{}, draw.Src)
+	tile := image.NewRGBA(image.Rect(0, 0, sheetTile, sheetTile))
+	for i, p := range panels {
+		at := image.Pt(sheetGap+(i%cols)*cellW, sheetGap+(i/cols)*cellH)
+		if p.Image != nil {
+			fill(tile, p.Image)
+			draw.Draw(out, tile.Bounds().Add(at), tile, image.Point{}, draw.Src)
+		}
+		label, unit := fitText(p.Label, sheetUnit, 1, sheetTile-2*pad)
+		y := at.Y + sheetTile + pad + (branding.GlyphHeight*sheetUnit-branding.GlyphHeight*unit)/2
+		branding.DrawText(out, image.Pt(at.X+pad, y), label, unit, color.White)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode contact sheet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SideBySide returns the contact sheet of the images at urls, labelled by
+// labels, e.g. a location's live image next to a candidate replacing it. An
+// image that can't be loaded leaves its tile blank.
+func (s *Service) SideBySide(ctx context.Context, labels, urls []string) ([]byte, error) {
+	panels := make([]Panel, len(urls))
+	for i, url := range urls {
+		panels[i].Label = labels[i]
+		img, err := s.art(ctx, url)
+		if err != nil {
+			log.Printf("Failed to load %s for the contact sheet: %v", url, err)
+			panels[i].Label += " (missing)"
+			continue
+		}
+		panels[i].Image = img
+	}
+	return ContactSheet(panels)
+}
@@ -0,0 +1,40 @@
+package cards
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestContactSheet(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	art, _, err := image.Decode(bytes.NewReader(solidPNG(t, 300, 200, red)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ContactSheet([]Panel{{Label: "classic", Image: art}, {Label: "drink", Image: art}, {Label: "failed"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Three tiles in one row
+	b := img.Bounds()
+	if b.Dx() != sheetGap+3*(sheetTile+sheetGap) || b.Dy() <= sheetTile {
+		t.Errorf("sheet is %v", b)
+	}
+	if r, g, _, _ := img.At(sheetGap+sheetTile/2, sheetGap+sheetTile/2).RGBA(); r>>8 != 255 || g != 0 {
+		t.Error("expected the first tile filled with its art")
+	}
+	if r, _, _, _ := img.At(sheetGap+2*(sheetTile+sheetGap)+sheetTile/2, sheetGap+sheetTile/2).RGBA(); r>>8 == 255 {
+		t.Error("expected the failed tile left blank")
+	}
+
+	if _, err := ContactSheet(nil); err == nil {
+		t.Error("expected an error for no panels")
+	}
+}
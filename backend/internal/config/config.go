@@ -0,0 +1,390 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/provenance"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/weatherdata"
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	ProjectID               string
+	Location                string
+	BucketName              string
+	DatabaseID              string
+	GoogleMapsKey           string
+	Port                    string
+	GeminiImageModel        string
+	GenAITransport          string   // "sdk" (default) or "rest", see genai.SetTransport
+	AdminAPIKey             string   // Enables /api/admin when set
+	TenantID                string   // Selects the branding doc; empty = deployment default
+	ReportThreshold         int      // Abuse reports before a location is hidden
+	TrustedProxies          int      // Reverse proxies in front of the server that append X-Forwarded-For
+	AdminWebhookURL         string   // Optional: where admin notifications are posted
+	PromptCache             bool     // Reuse images for identical prompts within the hour
+	BlockedLocations        []string // Fallback location policy when settings/location_policy is missing
+	AllowedLocations        []string
+	AllowlistOnly           bool
+	IndexCheck              bool          // Verify Firestore composite indexes at startup
+	WeatherCheck            bool          // Check generated images against the observed weather (WEATHER_PROVIDER)
+	WeatherRegen            bool          // Regenerate once when the weather check finds a mismatch
+	GroundingMode           string        // GoogleSearch tool for images: "search" (default), "off" or "required", see genai.GroundingMode
+	WeatherProviders        []string      // Observed weather and forecasts, by preference: "open-meteo" (default) and/or "nws", see weatherdata.Open
+	WeatherInPrompt         bool          // Put the observed weather and today's forecast in every image prompt, not just GroundingOff ones
+	Captions                bool          // Caption web flow locations with a Gemini nickname or fun fact
+	AltText                 bool          // Describe each generated image for screen readers
+	Narration               bool          // Narrate the forecast of refreshed and warmed locations (Location.AudioURL)
+	NarrationLangs          []string      // Narration languages; the first is AudioURL's, the others go in audio_i18n
+	ChaosImageFail          float64       // Development only: fraction of image generations to fail
+	ChaosVeoDelay           time.Duration // Development only: latency added before each Veo call
+	CompressLevel           int           // Response compression level (1-9), 0 disables it
+	CompressBrotli          bool          // Offer brotli alongside gzip
+	HTTP2                   bool          // Serve cleartext HTTP/2 (h2c) alongside HTTP/1.1
+	PreloadImages           int           // Preset images announced via Link: preload on /api/presets
+	PresetsCacheTTL         time.Duration // How long /api/presets results are cached in memory, 0 disables
+	PresetsWarmup           bool          // Load presets into that cache on startup; GET /readyz waits for it
+	PresetsWarmupTimeout    time.Duration // After which GET /readyz reports ready anyway, with the warm-up's error; 0 waits
+	PresetsSignURLs         time.Duration // Lifetime of signed media URLs served by /api/presets (private buckets), 0 serves stored URLs
+	Provenance              bool          // Embed AI-generation credentials (XMP) in generated images
+	ProvenanceKey           string        // Optional: HMAC key signing those credentials
+	OriginalsBucket         string        // Optional: private bucket keeping images before watermark/AI badge
+	UploadsBucket           string        // Optional: private bucket for user reference photos (POST /api/uploads)
+	VideoBucket             string        // Optional: bucket[/prefix] for Veo output (default GENMEDIA_BUCKET/videos/)
+	ThumbnailsBucket        string        // Optional: bucket[/prefix] for thumbnails (default GENMEDIA_BUCKET/thumbnails/)
+	ExportsBucket           string        // Optional: private bucket[/prefix] for exports
+	RetentionPolicy         string        // Rules for user-generated media, see jobs.ParseRetentionPolicy
+	CityOfTheDayDays        int           // City of the day picks aren't repeated within this many days
+	DefaultCity             string        // Shown when the web app sends neither a city nor coordinates
+	PushNotifications       bool          // Send FCM notifications and accept device registrations (POST /api/devices)
+	FCMProjectID            string        // Firebase project for FCM, defaults to ProjectID
+	PosterFrame             string        // Video frame used as the poster: "first", "best" or "off"
+	HLSTranscode            bool          // Transcode videos to multi-bitrate HLS (Location.StreamURL)
+	MediaProxy              bool          // Serve media at /media/proxy/{locationID}/{kind} from private buckets
+	DetachVideo             bool          // Web flow videos finish after the client disconnects
+	MapFallback             bool          // Show a static map when the web flow can't generate an image
+	FallbackMedia           bool          // Show nearby cached art when the web flow can't generate an image
+	FallbackImageURL        string        // Optional: last-resort placeholder image
+	FallbackVideoURL        string        // Optional: last-resort placeholder animation
+	HookPlugins             []string      // Go plugins registering generation hooks, see hooks.Open
+	HookWebhooks            []string      // stage=url webhooks run as generation hooks
+	EventTopics             []string      // Pub/Sub topics for generation lifecycle events, see eventbus.Open
+	QuotaLimits             []string      // Per-model capacity limits, see quota.Parse
+	MaxGenerations          int           // Concurrent generations per instance (quota.KindGeneration), 0 for unlimited
+	HTTPMaxConnsPerHost     int           // Connections per upstream host (Maps, GCS, Vertex AI), 0 for unlimited
+	HTTPMaxIdleConnsPerHost int           // Idle connections kept per upstream host
+	HTTPIdleConnTimeout     time.Duration // How long idle upstream connections are kept
+	CostRates               []string      // Per-model prices for usage estimates, see costs.Parse
+	TraceSample             float64       // Fraction of web flows whose events are kept in flow_traces
+	KillSwitchTTL           time.Duration // Default time until banana admin killswitch on expires
+	DBBackend               string        // "firestore" (default) or "postgres"
+	DatabaseURL             string        // Postgres connection URL when DBBackend is "postgres"
+	LocationsCollection     string        // Firestore collection holding locations ("locations")
+	ShadowCollection        string        // Optional: Firestore collection mirroring locations during a migration, see repo.Shadow
+	StorageBackend          string        // "gcs" (default) or "s3"
+	S3                      S3Config
+	Wallet                  WalletConfig
+	Email                   EmailConfig
+}
+
+// S3Config configures the S3-compatible media store (AWS S3, MinIO, R2).
+// The bucket is GENMEDIA_BUCKET.
+type S3Config struct {
+	Endpoint        string // host[:port], e.g. "localhost:9000" or "s3.amazonaws.com"
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	PublicURL       string // Optional: base URL serving the bucket publicly; presigned URLs are used when empty
+}
+
+// WalletConfig configures wallet passes for locations. Apple Wallet is on when
+// ApplePassTypeID is set, Google Wallet when GoogleIssuerID is.
+type WalletConfig struct {
+	BaseURL         string // Public URL of this server, for the PassKit web service
+	ApplePassTypeID string // e.g. "pass.com.example.bananaweather"
+	AppleTeamID     string
+	AppleCert       string // Pass Type ID certificate (PEM file), also used for APNs
+	AppleKey        string // Its private key (PEM file)
+	AppleWWDRCert   string // Apple WWDR intermediate certificate (PEM or DER file)
+	AuthSecret      string // HMAC key deriving each pass's web service token
+	GoogleIssuerID  string
+	GoogleKeyFile   string // Service account JSON key with Google Wallet API access
+}
+
+// EmailConfig configures admin notifications by email, sent besides the
+// ADMIN_WEBHOOK_URL ones when SMTPAddr and To are set.
+type EmailConfig struct {
+	SMTPAddr string   // host:port of the SMTP server, e.g. "smtp.sendgrid.net:587"
+	From     string   // Sender address
+	Username string   // Optional: SMTP auth
+	Password string   // Optional: SMTP auth
+	To       []string // Recipients
+}
+
+// Load reads .env files and environment variables, validating required fields.
+func Load() (*Config, error) {
+	// Try loading .env files from various locations (root, parent, etc)
+	_ = godotenv.Load(".env")
+	_ = godotenv.Load("../.env")
+	_ = godotenv.Load("../../.env")
+
+	cfg := &Config{
+		ProjectID:               getEnvOr("GOOGLE_CLOUD_PROJECT", os.Getenv("PROJECT_ID")),
+		Location:                getEnvOr("GOOGLE_CLOUD_LOCATION", "us-central1"),
+		BucketName:              os.Getenv("GENMEDIA_BUCKET"),
+		DatabaseID:              getEnvOr("FIRESTORE_DATABASE", "(default)"),
+		GoogleMapsKey:           os.Getenv("GOOGLE_MAPS_API_KEY"),
+		Port:                    getEnvOr("PORT", "8080"),
+		GeminiImageModel:        getEnvOr("GEMINI_IMAGE", "gemini-3.1-flash-image-preview"),
+		GenAITransport:          getEnvOr("GENAI_TRANSPORT", "sdk"),
+		AdminAPIKey:             os.Getenv("ADMIN_API_KEY"),
+		TenantID:                os.Getenv("TENANT_ID"),
+		ReportThreshold:         getEnvIntOr("REPORT_THRESHOLD", 3),
+		TrustedProxies:          getEnvIntOr("TRUSTED_PROXIES", 0),
+		AdminWebhookURL:         os.Getenv("ADMIN_WEBHOOK_URL"),
+		PromptCache:             getEnvOr("PROMPT_CACHE", "true") == "true",
+		BlockedLocations:        getEnvList("BLOCKED_LOCATIONS"),
+		AllowedLocations:        getEnvList("ALLOWED_LOCATIONS"),
+		AllowlistOnly:           os.Getenv("ALLOWLIST_ONLY") == "true",
+		IndexCheck:              getEnvOr("INDEX_CHECK", "true") == "true",
+		WeatherCheck:            os.Getenv("WEATHER_CHECK") == "true",
+		WeatherRegen:            os.Getenv("WEATHER_CHECK_REGENERATE") == "true",
+		GroundingMode:           getEnvOr("GROUNDING_MODE", "search"),
+		WeatherProviders:        getEnvList("WEATHER_PROVIDER"),
+		WeatherInPrompt:         getEnvOr("WEATHER_IN_PROMPT", "true") == "true",
+		Captions:                getEnvOr("CAPTIONS", "true") == "true",
+		AltText:                 getEnvOr("ALT_TEXT", "true") == "true",
+		Narration:               os.Getenv("NARRATION") == "true",
+		NarrationLangs:          getEnvList("NARRATION_LANGS"),
+		ChaosImageFail:          getEnvFloatOr("CHAOS_IMAGE_FAIL_RATE", 0),
+		ChaosVeoDelay:           getEnvDurationOr("CHAOS_VEO_DELAY", 0),
+		CompressLevel:           getEnvIntOr("COMPRESS_LEVEL", 5),
+		CompressBrotli:          getEnvOr("COMPRESS_BROTLI", "true") == "true",
+		HTTP2:                   getEnvOr("HTTP2", "true") == "true",
+		PreloadImages:           getEnvIntOr("PRELOAD_IMAGES", 6),
+		PresetsCacheTTL:         getEnvDurationOr("PRESETS_CACHE_TTL", 30*time.Second),
+		PresetsWarmup:           getEnvOr("PRESETS_WARMUP", "true") == "true",
+		PresetsWarmupTimeout:    getEnvDurationOr("PRESETS_WARMUP_TIMEOUT", 30*time.Second),
+		PresetsSignURLs:         getEnvDurationOr("PRESETS_SIGN_URLS", 0),
+		Provenance:              getEnvOr("PROVENANCE", "true") == "true",
+		ProvenanceKey:           os.Getenv("PROVENANCE_KEY"),
+		OriginalsBucket:         os.Getenv("ORIGINALS_BUCKET"),
+		UploadsBucket:           os.Getenv("UPLOADS_BUCKET"),
+		VideoBucket:             os.Getenv("VIDEO_BUCKET"),
+		ThumbnailsBucket:        os.Getenv("THUMBNAILS_BUCKET"),
+		ExportsBucket:           os.Getenv("EXPORTS_BUCKET"),
+		RetentionPolicy:         getEnvOr("RETENTION_POLICY", "image:coldline:30d,video:coldline:30d"),
+		CityOfTheDayDays:        getEnvIntOr("CITY_OF_THE_DAY_AVOID_DAYS", 30),
+		DefaultCity:             getEnvOr("DEFAULT_CITY", "San Francisco"),
+		PushNotifications:       os.Getenv("PUSH_NOTIFICATIONS") == "true",
+		FCMProjectID:            getEnvOr("FCM_PROJECT_ID", getEnvOr("GOOGLE_CLOUD_PROJECT", os.Getenv("PROJECT_ID"))),
+		PosterFrame:             getEnvOr("POSTER_FRAME", "first"),
+		HLSTranscode:            os.Getenv("HLS_TRANSCODE") == "true",
+		MediaProxy:              os.Getenv("MEDIA_PROXY") == "true",
+		DetachVideo:             os.Getenv("DETACH_VIDEO") == "true",
+		MapFallback:             os.Getenv("STATIC_MAP_FALLBACK") == "true",
+		FallbackMedia:           getEnvOr("FALLBACK_MEDIA", "true") == "true",
+		FallbackImageURL:        os.Getenv("FALLBACK_IMAGE_URL"),
+		FallbackVideoURL:        os.Getenv("FALLBACK_VIDEO_URL"),
+		HookPlugins:             getEnvList("HOOK_PLUGINS"),
+		HookWebhooks:            getEnvList("HOOK_WEBHOOKS"),
+		EventTopics:             getEnvList("EVENT_TOPICS"),
+		QuotaLimits:             getEnvList("QUOTA_LIMITS"),
+		MaxGenerations:          getEnvIntOr("MAX_CONCURRENT_GENERATIONS", 0),
+		HTTPMaxConnsPerHost:     getEnvIntOr("HTTP_MAX_CONNS_PER_HOST", 0),
+		HTTPMaxIdleConnsPerHost: getEnvIntOr("HTTP_MAX_IDLE_CONNS_PER_HOST", 32),
+		HTTPIdleConnTimeout:     getEnvDurationOr("HTTP_IDLE_CONN_TIMEOUT", 90*time.Second),
+		CostRates:               getEnvList("COST_RATES"),
+		TraceSample:             getEnvFloatOr("FLOW_TRACE_SAMPLE", 0),
+		KillSwitchTTL:           getEnvDurationOr("KILL_SWITCH_TTL", 2*time.Hour),
+		DBBackend:               getEnvOr("DB_BACKEND", "firestore"),
+		DatabaseURL:             os.Getenv("DATABASE_URL"),
+		LocationsCollection:     getEnvOr("FIRESTORE_LOCATIONS_COLLECTION", "locations"),
+		ShadowCollection:        os.Getenv("FIRESTORE_SHADOW_COLLECTION"),
+		StorageBackend:          getEnvOr("STORAGE_BACKEND", "gcs"),
+		S3: S3Config{
+			Endpoint:        getEnvOr("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UseSSL:          getEnvOr("S3_USE_SSL", "true") == "true",
+			PublicURL:       strings.TrimSuffix(os.Getenv("S3_PUBLIC_URL"), "/"),
+		},
+		Wallet: WalletConfig{
+			BaseURL:         strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/"),
+			ApplePassTypeID: os.Getenv("APPLE_PASS_TYPE_ID"),
+			AppleTeamID:     os.Getenv("APPLE_TEAM_ID"),
+			AppleCert:       os.Getenv("APPLE_PASS_CERT"),
+			AppleKey:        os.Getenv("APPLE_PASS_KEY"),
+			AppleWWDRCert:   os.Getenv("APPLE_WWDR_CERT"),
+			AuthSecret:      os.Getenv("WALLET_AUTH_SECRET"),
+			GoogleIssuerID:  os.Getenv("GOOGLE_WALLET_ISSUER_ID"),
+			GoogleKeyFile:   os.Getenv("GOOGLE_WALLET_KEY_FILE"),
+		},
+		Email: EmailConfig{
+			SMTPAddr: os.Getenv("SMTP_ADDR"),
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			To:       getEnvList("ADMIN_EMAILS"),
+		},
+	}
+
+	if cfg.ProjectID == "" {
+		return nil, fmt.Errorf("GOOGLE_CLOUD_PROJECT or PROJECT_ID is required")
+	}
+	if cfg.BucketName == "" {
+		return nil, fmt.Errorf("GENMEDIA_BUCKET is required")
+	}
+	if cfg.GoogleMapsKey == "" {
+		return nil, fmt.Errorf("GOOGLE_MAPS_API_KEY is required")
+	}
+	if cfg.DBBackend != "firestore" && cfg.DBBackend != "postgres" {
+		return nil, fmt.Errorf("DB_BACKEND must be firestore or postgres, got %q", cfg.DBBackend)
+	}
+	if cfg.DBBackend == "postgres" && cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required when DB_BACKEND=postgres")
+	}
+	if cfg.ShadowCollection != "" && cfg.DBBackend != "firestore" {
+		return nil, fmt.Errorf("FIRESTORE_SHADOW_COLLECTION requires DB_BACKEND=firestore")
+	}
+	if cfg.ShadowCollection == cfg.LocationsCollection {
+		return nil, fmt.Errorf("FIRESTORE_SHADOW_COLLECTION must differ from FIRESTORE_LOCATIONS_COLLECTION (%q)", cfg.LocationsCollection)
+	}
+	if cfg.ChaosImageFail < 0 || cfg.ChaosImageFail > 1 {
+		return nil, fmt.Errorf("CHAOS_IMAGE_FAIL_RATE must be between 0 and 1, got %v", cfg.ChaosImageFail)
+	}
+	if cfg.GenAITransport != "sdk" && cfg.GenAITransport != "rest" {
+		return nil, fmt.Errorf("GENAI_TRANSPORT must be sdk or rest, got %q", cfg.GenAITransport)
+	}
+	if cfg.GroundingMode != "search" && cfg.GroundingMode != "off" && cfg.GroundingMode != "required" {
+		return nil, fmt.Errorf("GROUNDING_MODE must be search, off or required, got %q", cfg.GroundingMode)
+	}
+	if len(cfg.WeatherProviders) == 0 {
+		cfg.WeatherProviders = []string{weatherdata.NameOpenMeteo}
+	}
+	if _, err := weatherdata.Open(cfg.WeatherProviders, nil); err != nil {
+		return nil, fmt.Errorf("WEATHER_PROVIDER: %w", err)
+	}
+	if cfg.StorageBackend != "gcs" && cfg.StorageBackend != "s3" {
+		return nil, fmt.Errorf("STORAGE_BACKEND must be gcs or s3, got %q", cfg.StorageBackend)
+	}
+
+	return cfg, nil
+}
+
+// getEnvList splits a semicolon-separated variable. Semicolons, because
+// location entries like "Paris, France" contain commas.
+// Signer returns the provenance signer for generated images, or nil when
+// PROVENANCE is off.
+func (c *Config) Signer() *provenance.Signer {
+	if !c.Provenance {
+		return nil
+	}
+	return provenance.NewSigner(c.ProvenanceKey)
+}
+
+// Weather returns the weather provider of WEATHER_PROVIDER: a chain when it
+// lists several, e.g. "nws;open-meteo" for NWS in the US and Open-Meteo
+// elsewhere.
+func (c *Config) Weather() weatherdata.Provider {
+	p, err := weatherdata.Open(c.WeatherProviders, nil)
+	if err != nil {
+		return weatherdata.NewOpenMeteo(nil) // Load validated the list; a hand-built Config may not have
+	}
+	return p
+}
+
+// LoadProfile is Load with the variables in .env.<name> (looked up where Load
+// looks for .env) taking precedence over the environment, e.g. "staging" reads
+// .env.staging. The environment is restored afterwards so several profiles can
+// be loaded one after another; it is not safe to call concurrently.
+func LoadProfile(name string) (*Config, error) {
+	var vars map[string]string
+	for _, dir := range []string{".", "..", "../.."} {
+		v, err := godotenv.Read(filepath.Join(dir, ".env."+name))
+		if err == nil {
+			vars = v
+			break
+		}
+	}
+	if vars == nil {
+		return nil, fmt.Errorf("profile %q not found (expected a .env.%s file)", name, name)
+	}
+
+	for k, v := range vars {
+		prev, had := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if had {
+			defer os.Setenv(k, prev)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+	return Load()
+}
+
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// LocationPolicy returns the env-configured location policy, or nil if none is set.
+func (c *Config) LocationPolicy() *database.LocationPolicy {
+	if len(c.BlockedLocations) == 0 && !c.AllowlistOnly {
+		return nil
+	}
+	return &database.LocationPolicy{
+		Blocked:       c.BlockedLocations,
+		Allowed:       c.AllowedLocations,
+		AllowlistOnly: c.AllowlistOnly,
+	}
+}
+
+func getEnvIntOr(key string, defaultVal int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
+
+func getEnvFloatOr(key string, defaultVal float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+// getEnvDurationOr parses a Go duration such as "30s" or "2m".
+func getEnvDurationOr(key string, defaultVal time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return defaultVal
+}
+
+func getEnvOr(key, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	// Setup Environment
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	os.Setenv("PORT", "9090")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.ProjectID != "test-project" {
+		t.Errorf("Expected ProjectID 'test-project', got '%s'", cfg.ProjectID)
+	}
+	if cfg.BucketName != "test-bucket" {
+		t.Errorf("Expected BucketName 'test-bucket', got '%s'", cfg.BucketName)
+	}
+	if cfg.GoogleMapsKey != "test-key" {
+		t.Errorf("Expected GoogleMapsKey 'test-key', got '%s'", cfg.GoogleMapsKey)
+	}
+	if cfg.Port != "9090" {
+		t.Errorf("Expected Port '9090', got '%s'", cfg.Port)
+	}
+	if cfg.DefaultCity != "San Francisco" {
+		t.Errorf("Expected DefaultCity 'San Francisco', got '%s'", cfg.DefaultCity)
+	}
+
+	os.Setenv("DEFAULT_CITY", "Lagos")
+	if cfg, _ := Load(); cfg.DefaultCity != "Lagos" {
+		t.Errorf("Expected DefaultCity 'Lagos', got '%s'", cfg.DefaultCity)
+	}
+}
+
+func TestLoadMissingRequired(t *testing.T) {
+	os.Clearenv()
+	// Set only one
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Expected error when missing required fields, got nil")
+	}
+}
+
+func TestLoadLocationLists(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	os.Setenv("BLOCKED_LOCATIONS", "Area 51, NV ; ;Pyongyang, North Korea")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if len(cfg.BlockedLocations) != 2 || cfg.BlockedLocations[0] != "Area 51, NV" || cfg.BlockedLocations[1] != "Pyongyang, North Korea" {
+		t.Errorf("Unexpected BlockedLocations: %q", cfg.BlockedLocations)
+	}
+	if cfg.AllowedLocations != nil || cfg.AllowlistOnly {
+		t.Errorf("Expected no allowlist, got %q (only: %v)", cfg.AllowedLocations, cfg.AllowlistOnly)
+	}
+}
+
+func TestLoadStorageBackend(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	os.Setenv("STORAGE_BACKEND", "s3")
+	os.Setenv("S3_ENDPOINT", "localhost:9000")
+	os.Setenv("S3_USE_SSL", "false")
+	os.Setenv("S3_PUBLIC_URL", "http://localhost:9000/test-bucket/")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.S3.Endpoint != "localhost:9000" || cfg.S3.UseSSL {
+		t.Errorf("Unexpected S3 config: %+v", cfg.S3)
+	}
+	if cfg.S3.PublicURL != "http://localhost:9000/test-bucket" {
+		t.Errorf("Expected trailing slash trimmed, got %q", cfg.S3.PublicURL)
+	}
+
+	os.Setenv("STORAGE_BACKEND", "azure")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for unknown STORAGE_BACKEND, got nil")
+	}
+}
+
+func TestLoadChaos(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	os.Setenv("CHAOS_IMAGE_FAIL_RATE", "0.25")
+	os.Setenv("CHAOS_VEO_DELAY", "90s")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.ChaosImageFail != 0.25 || cfg.ChaosVeoDelay != 90*time.Second {
+		t.Errorf("Unexpected chaos config: %v, %v", cfg.ChaosImageFail, cfg.ChaosVeoDelay)
+	}
+
+	os.Setenv("CHAOS_IMAGE_FAIL_RATE", "1.5")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for out-of-range CHAOS_IMAGE_FAIL_RATE, got nil")
+	}
+}
+
+func TestLoadGroundingMode(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.GroundingMode != "search" {
+		t.Errorf("Expected default grounding mode search, got %q", cfg.GroundingMode)
+	}
+
+	os.Setenv("GROUNDING_MODE", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for unknown GROUNDING_MODE, got nil")
+	}
+}
+
+func TestLoadWeatherProvider(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.WeatherProviders) != 1 || cfg.WeatherProviders[0] != "open-meteo" || !cfg.WeatherInPrompt {
+		t.Errorf("Expected Open-Meteo in prompts by default, got %v (in prompt: %v)", cfg.WeatherProviders, cfg.WeatherInPrompt)
+	}
+
+	os.Setenv("WEATHER_PROVIDER", "nws; open-meteo")
+	if cfg, err = Load(); err != nil || len(cfg.WeatherProviders) != 2 || cfg.Weather() == nil {
+		t.Errorf("Expected a chain of two providers, got %v, %v", cfg.WeatherProviders, err)
+	}
+
+	os.Setenv("WEATHER_PROVIDER", "met-office")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for unknown WEATHER_PROVIDER, got nil")
+	}
+}
+
+func TestLoadShadowCollection(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	os.Setenv("GENMEDIA_BUCKET", "test-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	os.Setenv("FIRESTORE_SHADOW_COLLECTION", "locations_v2")
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.LocationsCollection != "locations" || cfg.ShadowCollection != "locations_v2" {
+		t.Errorf("Unexpected collections: %q, %q", cfg.LocationsCollection, cfg.ShadowCollection)
+	}
+
+	os.Setenv("FIRESTORE_LOCATIONS_COLLECTION", "locations_v2")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for a shadow collection equal to the locations collection, got nil")
+	}
+	os.Setenv("FIRESTORE_LOCATIONS_COLLECTION", "locations")
+	os.Setenv("DB_BACKEND", "postgres")
+	os.Setenv("DATABASE_URL", "postgres://localhost/banana")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for a shadow collection with postgres, got nil")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "prod-project")
+	os.Setenv("GENMEDIA_BUCKET", "prod-bucket")
+	os.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	defer os.Clearenv()
+
+	t.Chdir(t.TempDir())
+	os.WriteFile(".env.staging", []byte("GOOGLE_CLOUD_PROJECT=staging-project\nFIRESTORE_DATABASE=staging\n"), 0644)
+
+	cfg, err := LoadProfile("staging")
+	if err != nil {
+		t.Fatalf("LoadProfile() failed: %v", err)
+	}
+	if cfg.ProjectID != "staging-project" || cfg.DatabaseID != "staging" {
+		t.Errorf("Expected profile values to win, got project %q database %q", cfg.ProjectID, cfg.DatabaseID)
+	}
+	if cfg.BucketName != "prod-bucket" {
+		t.Errorf("Expected unset profile values to fall back to the environment, got %q", cfg.BucketName)
+	}
+	if os.Getenv("GOOGLE_CLOUD_PROJECT") != "prod-project" || os.Getenv("FIRESTORE_DATABASE") != "" {
+		t.Error("Expected the environment to be restored")
+	}
+
+	if _, err := LoadProfile("missing"); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
@@ -0,0 +1,143 @@
+// Package costs records the billing dimensions of model calls (Gemini
+// tokens, seconds of Veo video) per generation, and estimates what they
+// cost from per-model rates, so expensive prompts can be found.
+package costs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+)
+
+// Tracker collects the model calls made under a context, see Track.
+type Tracker struct {
+	mu    sync.Mutex
+	calls []database.ModelUsage
+}
+
+type trackerKey struct{}
+
+// Track returns a context whose model calls are recorded by the returned
+// Tracker. If ctx already has a Tracker it's reused, so the stages of one
+// generation add to the same record.
+func Track(ctx context.Context) (context.Context, *Tracker) {
+	if t, ok := ctx.Value(trackerKey{}).(*Tracker); ok {
+		return ctx, t
+	}
+	t := &Tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// Record adds a model call to the Tracker of ctx. Calls made without one
+// aren't recorded.
+func Record(ctx context.Context, u database.ModelUsage) {
+	t, ok := ctx.Value(trackerKey{}).(*Tracker)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, u)
+}
+
+// Calls returns the calls recorded so far, oldest first. A nil Tracker has
+// none.
+func (t *Tracker) Calls() []database.ModelUsage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]database.ModelUsage(nil), t.calls...)
+}
+
+// Rate is the price of a model in USD. Zero values are free.
+type Rate struct {
+	Input  float64 // Per million prompt tokens
+	Output float64 // Per million output tokens
+	Second float64 // Per second of generated video
+}
+
+// Rates holds the rates by model name.
+type Rates map[string]Rate
+
+// Open returns the rates of COST_RATES, or nil when none are set.
+func Open(cfg *config.Config) (Rates, error) {
+	if len(cfg.CostRates) == 0 {
+		return nil, nil
+	}
+	rates, err := Parse(cfg.CostRates)
+	if err != nil {
+		return nil, fmt.Errorf("COST_RATES: %w", err)
+	}
+	return rates, nil
+}
+
+// Parse reads rates written as model=setting,..., where settings are
+// input:USD and output:USD per million tokens and second:USD, e.g.
+// "veo-3.1-lite-generate-001=second:0.05".
+func Parse(entries []string) (Rates, error) {
+	rates := Rates{}
+	for _, e := range entries {
+		model, settings, ok := strings.Cut(e, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("want model=settings, got %q", e)
+		}
+		var r Rate
+		for _, s := range strings.Split(settings, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(s), ":")
+			v, err := strconv.ParseFloat(value, 64)
+			if err == nil && v < 0 {
+				err = fmt.Errorf("negative rate %s", value)
+			}
+			switch name {
+			case "input":
+				r.Input = v
+			case "output":
+				r.Output = v
+			case "second":
+				r.Second = v
+			default:
+				err = fmt.Errorf("unknown setting %q (use input, output or second)", name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", model, err)
+			}
+		}
+		rates[model] = r
+	}
+	return rates, nil
+}
+
+// Cost estimates what a call cost, 0 if its model has no rate.
+func (r Rates) Cost(u database.ModelUsage) float64 {
+	rate := r[u.Model]
+	return (float64(u.PromptTokens)*rate.Input+float64(u.OutputTokens)*rate.Output)/1e6 +
+		float64(u.VideoSeconds)*rate.Second
+}
+
+// Summarize totals the usage of m. Generations recorded before Usage only
+// have the tokens of their image, which are used instead. It returns nil
+// for a nil m.
+func (r Rates) Summarize(m *database.GenerationMetadata) *database.UsageSummary {
+	if m == nil {
+		return nil
+	}
+	calls := m.Usage
+	if len(calls) == 0 && !m.Cached {
+		calls = []database.ModelUsage{{Model: m.Model, PromptTokens: m.PromptTokens, OutputTokens: m.OutputTokens}}
+	}
+	s := &database.UsageSummary{}
+	for _, c := range calls {
+		s.Tokens += c.PromptTokens + c.OutputTokens
+		s.VideoSeconds += c.VideoSeconds
+		s.CostUSD += r.Cost(c)
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package costs
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+func TestParse(t *testing.T) {
+	rates, err := Parse([]string{"gemini-3.1-flash-image-preview=input:0.5,output:30", "veo-3.1-lite-generate-001=second:0.05"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rates["gemini-3.1-flash-image-preview"]; r != (Rate{Input: 0.5, Output: 30}) {
+		t.Errorf("Unexpected image rate %+v", r)
+	}
+	if r := rates["veo-3.1-lite-generate-001"]; r.Second != 0.05 {
+		t.Errorf("Unexpected video rate %+v", r)
+	}
+	for _, bad := range []string{"veo", "=second:1", "veo=minute:1", "veo=second:x", "veo=second:-1"} {
+		if _, err := Parse([]string{bad}); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestTrack(t *testing.T) {
+	// Without a tracker, calls are dropped
+	Record(context.Background(), database.ModelUsage{Model: "m"})
+
+	ctx, tr := Track(context.Background())
+	Record(ctx, database.ModelUsage{Model: "image", PromptTokens: 10})
+	inner, again := Track(ctx)
+	if again != tr {
+		t.Fatal("Track replaced the tracker of the context")
+	}
+	Record(inner, database.ModelUsage{Model: "veo", VideoSeconds: 8})
+
+	calls := tr.Calls()
+	if len(calls) != 2 || calls[0].Model != "image" || calls[1].Model != "veo" {
+		t.Errorf("calls = %+v", calls)
+	}
+	if (*Tracker)(nil).Calls() != nil {
+		t.Error("nil tracker has calls")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	rates := Rates{
+		"image": {Input: 1, Output: 100},
+		"veo":   {Second: 0.5},
+	}
+	m := &database.GenerationMetadata{
+		Model: "image",
+		Usage: []database.ModelUsage{
+			{Model: "image", PromptTokens: 1000, OutputTokens: 2000}, // Rejected by the weather check
+			{Model: "image", PromptTokens: 1000, OutputTokens: 2000},
+			{Model: "checker", PromptTokens: 500, OutputTokens: 20}, // No rate
+			{Model: "veo", VideoSeconds: 8},
+		},
+	}
+	s := rates.Summarize(m)
+	if s.Tokens != 6520 || s.VideoSeconds != 8 {
+		t.Errorf("summary = %+v", s)
+	}
+	if want := 2*(0.001+0.2) + 4; math.Abs(s.CostUSD-want) > 1e-9 {
+		t.Errorf("cost = %v, want %v", s.CostUSD, want)
+	}
+
+	// Before Usage was recorded, only the image's tokens are known
+	old := Rates(nil).Summarize(&database.GenerationMetadata{Model: "image", PromptTokens: 300, OutputTokens: 1290})
+	if old.Tokens != 1590 || old.CostUSD != 0 {
+		t.Errorf("old summary = %+v", old)
+	}
+	if cached := rates.Summarize(&database.GenerationMetadata{Model: "image", Cached: true}); cached.Tokens != 0 || cached.CostUSD != 0 {
+		t.Errorf("cached summary = %+v", cached)
+	}
+	if rates.Summarize(nil) != nil {
+		t.Error("summary without metadata")
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Candidate review states. Candidates recorded for reference (compare) have
+// none until they're reviewed.
+const (
+	CandidatePending   = "pending"   // Staged by a preset refresh, awaiting approval
+	CandidateApproving = "approving" // Claimed by an approval, its video being generated
+	CandidateApproved  = "approved"  // Swapped in as the location's live image
+	CandidateRejected  = "rejected"
+)
+
+// Candidate is media generated for a location without replacing its live
+// media, e.g. one style of banana admin compare or a preset refresh awaiting
+// approval, kept in candidates for an admin to review.
+type Candidate struct {
+	ID             string              `firestore:"-" json:"id"`
+	LocationID     string              `firestore:"location_id" json:"location_id"`
+	City           string              `firestore:"city" json:"city"`
+	Source         string              `firestore:"source" json:"source"` // What generated it: "compare" or "refresh"
+	Style          string              `firestore:"style" json:"style"`   // Prompt style, see genai.Styles
+	Seed           int32               `firestore:"seed" json:"seed"`
+	ImageURL       string              `firestore:"image_url" json:"image_url"`
+	Generation     *GenerationMetadata `firestore:"generation,omitempty" json:"generation,omitempty"`
+	Status         string              `firestore:"status,omitempty" json:"status,omitempty"` // Review state, e.g. CandidatePending
+	AltText        string              `firestore:"alt_text,omitempty" json:"alt_text,omitempty"`
+	Mood           string              `firestore:"mood,omitempty" json:"mood,omitempty"`                       // Of the observed weather it was generated for
+	TemperatureC   *float64            `firestore:"temperature_c,omitempty" json:"temperature_c,omitempty"`     // Observed when generated
+	LastConditions *Conditions         `firestore:"last_conditions,omitempty" json:"last_conditions,omitempty"` // Observed when generated, bucketed
+	CreatedAt      time.Time           `firestore:"created_at" json:"created_at"`
+	ReviewedAt     *time.Time          `firestore:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+}
+
+// Reviewable reports whether the candidate can still be approved or
+// rejected.
+func (c *Candidate) Reviewable() bool {
+	return c.Status == "" || c.Status == CandidatePending
+}
+
+// AddCandidate stores cand under a new ID, which it returns.
+func (c *Client) AddCandidate(ctx context.Context, cand Candidate) (string, error) {
+	if cand.LocationID == "" {
+		return "", fmt.Errorf("location ID is required")
+	}
+	if cand.CreatedAt.IsZero() {
+		cand.CreatedAt = c.clock.Now()
+	}
+	ref := c.fs.Collection("candidates").NewDoc()
+	if _, err := ref.Create(ctx, cand); err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+// ListCandidates returns the candidates of a location, newest first.
+func (c *Client) ListCandidates(ctx context.Context, locationID string) ([]Candidate, error) {
+	iter := c.fs.Collection("candidates").Where("location_id", "==", locationID).OrderBy("created_at", firestore.Desc).Documents(ctx)
+	defer iter.Stop()
+
+	var out []Candidate
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var cand Candidate
+		if err := doc.DataTo(&cand); err != nil {
+			return nil, err
+		}
+		cand.ID = doc.Ref.ID
+		out = append(out, cand)
+	}
+	return out, nil
+}
+
+// GetCandidate returns a candidate by ID.
+func (c *Client) GetCandidate(ctx context.Context, id string) (*Candidate, error) {
+	doc, err := c.fs.Collection("candidates").Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var cand Candidate
+	if err := doc.DataTo(&cand); err != nil {
+		return nil, err
+	}
+	cand.ID = doc.Ref.ID
+	return &cand, nil
+}
+
+// SetCandidateStatus records the review of a candidate.
+func (c *Client) SetCandidateStatus(ctx context.Context, id, status string) error {
+	_, err := c.fs.Collection("candidates").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: status},
+		{Path: "reviewed_at", Value: c.clock.Now()},
+	})
+	return err
+}
+
+// TransitionCandidate changes the status of a candidate to to, in a
+// transaction, if it's one of from, and returns the candidate as it was. It
+// fails with FailedPrecondition otherwise, e.g. when a concurrent review
+// claimed it first.
+func (c *Client) TransitionCandidate(ctx context.Context, id string, from []string, to string) (*Candidate, error) {
+	ref := c.fs.Collection("candidates").Doc(id)
+	var cand Candidate
+	err := c.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&cand); err != nil {
+			return err
+		}
+		if !slices.Contains(from, cand.Status) {
+			return status.Errorf(codes.FailedPrecondition, "candidate %q is %s", id, cand.Status)
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: to},
+			{Path: "reviewed_at", Value: c.clock.Now()},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	cand.ID = id
+	return &cand, nil
+}
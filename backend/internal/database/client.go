@@ -0,0 +1,1012 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/ghchinoy/banana-weather/backend/pkg/model"
+	"google.golang.org/api/iterator"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Client struct {
+	fs        *firestore.Client
+	clock     clock.Clock
+	locations string        // Collection holding locations, see SetLocationsCollection
+	ttl       time.Duration // Expiry of user locations, see SetLocationTTL
+}
+
+func NewClient(ctx context.Context, projectID, databaseID string) (*Client, error) {
+	log.Printf("Initializing Firestore. Project: %s, Database: %s", projectID, databaseID)
+
+	// Create client with specific database ID
+	client, err := firestore.NewClientWithDatabase(ctx, projectID, databaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+	}
+
+	return &Client{fs: client, clock: clock.Real{}, locations: DefaultLocationsCollection}, nil
+}
+
+// DefaultLocationsCollection is where locations are kept unless
+// FIRESTORE_LOCATIONS_COLLECTION names another collection.
+const DefaultLocationsCollection = "locations"
+
+// SetLocationsCollection moves the client to another locations collection,
+// e.g. the new side of a migration (see repo.Shadow).
+func (c *Client) SetLocationsCollection(name string) {
+	c.locations = name
+}
+
+// SetLocationTTL makes UpsertLocation set expire_at on user locations, ttl
+// after the write, for Firestore TTL to delete them (see Location.Expire). 0,
+// the default, writes no expiry.
+func (c *Client) SetLocationTTL(ttl time.Duration) {
+	c.ttl = ttl
+}
+
+// SetLocationExpiry sets (or, when at is nil, removes) expire_at on location
+// id, leaving last_updated alone.
+func (c *Client) SetLocationExpiry(ctx context.Context, id string, at *time.Time) error {
+	var value any = firestore.Delete
+	if at != nil {
+		value = *at
+	}
+	_, err := c.fs.Collection(c.locations).Doc(id).Update(ctx, []firestore.Update{{Path: "expire_at", Value: value}})
+	return err
+}
+
+// LocationsCollection returns the name of the locations collection.
+func (c *Client) LocationsCollection() string {
+	return c.locations
+}
+
+// SetClock replaces the clock used for write timestamps (tests).
+func (c *Client) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Close closes the Firestore client.
+func (c *Client) Close() error {
+	return c.fs.Close()
+}
+
+// -- Models --
+
+// The location model is shared with API clients, in package model; these
+// aliases keep the server's code in terms of this package.
+type (
+	Location           = model.Location
+	GenerationMetadata = model.GenerationMetadata
+	ModelUsage         = model.ModelUsage
+	UsageSummary       = model.UsageSummary
+	WeatherCheck       = model.WeatherCheck
+	Conditions         = model.Conditions
+	GroundingSource    = model.GroundingSource
+	LocationStatus     = model.LocationStatus
+	Receipt            = model.Receipt
+)
+
+const (
+	StatusGenerating          = model.StatusGenerating
+	StatusReady               = model.StatusReady
+	StatusRefreshPending      = model.StatusRefreshPending
+	StatusFailed              = model.StatusFailed
+	StatusHidden              = model.StatusHidden
+	StatusArchived            = model.StatusArchived
+	StatusHiddenPendingReview = model.StatusHiddenPendingReview
+)
+
+// LocationStatuses lists every valid status, for validation and completion.
+var LocationStatuses = model.LocationStatuses
+
+// Branding holds per-tenant customization applied to generated images.
+// Stored in settings/branding (or settings/branding_<tenant>).
+type Branding struct {
+	Palette          []string `firestore:"palette" json:"palette"`                     // Hex colors, e.g. "#FFD400"
+	PromptSuffix     string   `firestore:"prompt_suffix" json:"prompt_suffix"`         // e.g. "in the style of X brand"
+	WatermarkURL     string   `firestore:"watermark_url" json:"watermark_url"`         // PNG logo, https:// or gs://
+	WatermarkScale   float64  `firestore:"watermark_scale" json:"watermark_scale"`     // Fraction of image width (default 0.2)
+	WatermarkOpacity float64  `firestore:"watermark_opacity" json:"watermark_opacity"` // 0-1 (default 0.8)
+	AIBadge          bool     `firestore:"ai_badge" json:"ai_badge"`                   // Visible "AI GENERATED" label on images
+}
+
+// LocationPolicy restricts which geocoded locations may be generated.
+// Stored in settings/location_policy (or settings/location_policy_<tenant>).
+// Entries match a whole formatted address ("Paris, France") or any one of its
+// comma-separated components ("France"), case-insensitively.
+type LocationPolicy struct {
+	Blocked       []string `firestore:"blocked" json:"blocked"`               // Never generated
+	Allowed       []string `firestore:"allowed" json:"allowed"`               // Only consulted in allowlist-only mode
+	AllowlistOnly bool     `firestore:"allowlist_only" json:"allowlist_only"` // Kiosk mode: reject anything not allowed
+}
+
+// AuditEntry records a policy decision, kept in the audit_log collection.
+type AuditEntry struct {
+	Event     string    `firestore:"event" json:"event"` // e.g. "location_blocked"
+	Query     string    `firestore:"query" json:"query"`
+	Location  string    `firestore:"location" json:"location"` // Formatted address
+	Reason    string    `firestore:"reason" json:"reason"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// PresetSummary is the slice of a preset the gallery renders. GetPresetSummaries
+// reads only these fields, keeping Firestore reads and the /api/presets payload
+// small.
+type PresetSummary struct {
+	ID          string            `firestore:"id" json:"id"`
+	Name        string            `firestore:"name" json:"name"`
+	NameI18n    map[string]string `firestore:"name_i18n,omitempty" json:"-"` // Only used to localize Name
+	Category    string            `firestore:"category" json:"category"`
+	ImageURL    string            `firestore:"image_url" json:"image_url"`
+	VideoURL    string            `firestore:"video_url" json:"video_url"`
+	PosterURL   string            `firestore:"poster_url,omitempty" json:"poster_url,omitempty"`
+	StreamURL   string            `firestore:"stream_url,omitempty" json:"stream_url,omitempty"`
+	AltText     string            `firestore:"alt_text,omitempty" json:"alt_text,omitempty"`
+	AudioURL    string            `firestore:"audio_url,omitempty" json:"audio_url,omitempty"`
+	AudioI18n   map[string]string `firestore:"audio_i18n,omitempty" json:"-"` // Only used to localize AudioURL
+	Continent   string            `firestore:"continent,omitempty" json:"continent,omitempty"`
+	Status      LocationStatus    `firestore:"status,omitempty" json:"-"` // Only used to filter
+	LastUpdated time.Time         `firestore:"last_updated" json:"last_updated"`
+}
+
+// presetSummaryFields is the Firestore projection for PresetSummary.
+var presetSummaryFields = []string{"id", "name", "name_i18n", "category", "image_url", "video_url", "poster_url", "stream_url", "alt_text", "audio_url", "audio_i18n", "continent", "status", "last_updated"}
+
+// LocalizedName is Location.LocalizedName for summaries.
+func (p *PresetSummary) LocalizedName(lang string) string {
+	return model.Localize(p.Name, p.NameI18n, lang)
+}
+
+// LocalizedAudioURL is Location.LocalizedAudioURL for summaries.
+func (p *PresetSummary) LocalizedAudioURL(lang string) string {
+	return model.Localize(p.AudioURL, p.AudioI18n, lang)
+}
+
+// Preset change types, see PresetChange
+const (
+	PresetAdded    = "added"
+	PresetModified = "modified"
+	PresetRemoved  = "removed" // Deleted, no longer a preset, or hidden
+)
+
+// PresetChange is one gallery update delivered by WatchPresets.
+type PresetChange struct {
+	Type   string        `json:"type"`
+	Preset PresetSummary `json:"preset"`
+}
+
+// Preset sort keys for PresetOptions.Sort
+const (
+	SortCategory = "category" // Category display order (see Category), then name
+	SortName     = "name"
+	SortUpdated  = "updated"
+)
+
+// PresetOptions controls the order of GetPresetSummaries.
+type PresetOptions struct {
+	Sort string // SortCategory (default), SortName or SortUpdated
+	Desc bool
+}
+
+// Category is an entry of the categories collection, which orders the
+// gallery's category groups and holds their cover art. Categories without
+// an entry sort after the
+// listed ones, alphabetically.
+type Category struct {
+	Name         string    `firestore:"name" json:"name"`
+	Order        int       `firestore:"order" json:"order"`                                     // Ascending display position
+	CoverURL     string    `firestore:"cover_url,omitempty" json:"cover_url,omitempty"`         // Collage of member cities, a section header
+	CoverCities  []string  `firestore:"cover_cities,omitempty" json:"cover_cities,omitempty"`   // Cities the cover shows
+	CoverUpdated time.Time `firestore:"cover_updated,omitempty" json:"cover_updated,omitzero"`
+}
+
+// SortByCategoryOrder stably reorders presets by their category's display
+// position (reversed when desc), keeping the existing order within a category
+// and among unlisted categories.
+func SortByCategoryOrder(presets []PresetSummary, categories []Category, desc bool) {
+	rank := make(map[string]int, len(categories))
+	for _, c := range categories {
+		rank[c.Name] = c.Order
+	}
+	key := func(p PresetSummary) (int, bool) {
+		r, ok := rank[p.Category]
+		return r, ok
+	}
+	slices.SortStableFunc(presets, func(a, b PresetSummary) int {
+		ra, oka := key(a)
+		rb, okb := key(b)
+		if oka != okb {
+			// Listed categories lead in ascending order and trail in descending
+			if oka != desc {
+				return -1
+			}
+			return 1
+		}
+		if desc {
+			return cmp.Compare(rb, ra)
+		}
+		return cmp.Compare(ra, rb)
+	})
+}
+
+// Visible reports whether the preset may be shown in the gallery.
+func (p *PresetSummary) Visible() bool {
+	switch p.Status {
+	case StatusHidden, StatusHiddenPendingReview, StatusArchived:
+		return false
+	}
+	return true
+}
+
+// ListOptions controls ListLocations.
+type ListOptions struct {
+	Limit  int
+	Type   string         // "all", "preset", "user", "hidden"
+	Sort   string         // "updated" (default) or "feedback"
+	Status LocationStatus // Optional exact status filter
+}
+
+// Report is a single abuse report, kept in locations/{id}/reports.
+type Report struct {
+	Reporter  string    `firestore:"reporter,omitempty" json:"reporter,omitempty"` // Hashed client, one report each; empty for reports counted every time
+	Reason    string    `firestore:"reason" json:"reason"`
+	ImageURL  string    `firestore:"image_url" json:"image_url"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// Feedback reasons accepted alongside a thumbs down.
+var FeedbackReasons = []string{"inaccurate_landmark", "illegible_text", "wrong_weather", "other"}
+
+// Feedback is a single user vote, kept in locations/{id}/feedback for the quality scorer.
+type Feedback struct {
+	Up        bool      `firestore:"up" json:"up"`
+	Reason    string    `firestore:"reason,omitempty" json:"reason,omitempty"`
+	ImageURL  string    `firestore:"image_url" json:"image_url"` // Media the vote applies to
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// PendingOperation is an in-flight Veo operation, kept in pending_operations
+// while GenerateVideo polls it.
+type PendingOperation struct {
+	Name       string    `firestore:"name" json:"name"` // Full Vertex AI operation name
+	Model      string    `firestore:"model" json:"model"`
+	InputImage string    `firestore:"input_image" json:"input_image"`
+	StartedAt  time.Time `firestore:"started_at" json:"started_at"`
+}
+
+// MaxModelTimings is how many recent durations ModelTimings keeps.
+const MaxModelTimings = 20
+
+// ModelTimings holds the recent Veo durations of a model, to estimate the
+// progress of running operations.
+type ModelTimings struct {
+	Model     string    `firestore:"model" json:"model"`
+	Durations []float64 `firestore:"durations" json:"durations"` // Seconds, oldest first
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// PromptCacheEntry maps a rendered-prompt hash to a cached image in GCS.
+// expires_at doubles as the Firestore TTL field for the prompt_cache collection.
+type PromptCacheEntry struct {
+	Key       string    `firestore:"key"`
+	Object    string    `firestore:"object"` // GCS object name
+	CreatedAt time.Time `firestore:"created_at"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// -- Methods --
+
+func settingsDocID(name, tenant string) string {
+	if tenant == "" {
+		return name
+	}
+	return name + "_" + tenant
+}
+
+func brandingDocID(tenant string) string {
+	return settingsDocID("branding", tenant)
+}
+
+// GetBranding returns the branding settings for a tenant ("" = deployment default).
+func (c *Client) GetBranding(ctx context.Context, tenant string) (*Branding, error) {
+	doc, err := c.fs.Collection("settings").Doc(brandingDocID(tenant)).Get(ctx)
+	if err != nil {
+		return nil, err // Returns NotFound status code if missing
+	}
+	var b Branding
+	if err := doc.DataTo(&b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SetBranding replaces the branding settings for a tenant.
+func (c *Client) SetBranding(ctx context.Context, tenant string, b Branding) error {
+	_, err := c.fs.Collection("settings").Doc(brandingDocID(tenant)).Set(ctx, b)
+	return err
+}
+
+// GetLocationPolicy returns the location policy for a tenant ("" = deployment default).
+func (c *Client) GetLocationPolicy(ctx context.Context, tenant string) (*LocationPolicy, error) {
+	doc, err := c.fs.Collection("settings").Doc(settingsDocID("location_policy", tenant)).Get(ctx)
+	if err != nil {
+		return nil, err // Returns NotFound status code if missing
+	}
+	var p LocationPolicy
+	if err := doc.DataTo(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetLocationPolicy replaces the location policy for a tenant.
+func (c *Client) SetLocationPolicy(ctx context.Context, tenant string, p LocationPolicy) error {
+	_, err := c.fs.Collection("settings").Doc(settingsDocID("location_policy", tenant)).Set(ctx, p)
+	return err
+}
+
+// AddAuditEntry appends an entry to the audit log.
+func (c *Client) AddAuditEntry(ctx context.Context, e AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = c.clock.Now()
+	}
+	_, _, err := c.fs.Collection("audit_log").Add(ctx, e)
+	return err
+}
+
+// SetChecksums replaces the media checksums of a location (see
+// Location.Checksums), leaving last_updated alone.
+func (c *Client) SetChecksums(ctx context.Context, id string, sums map[string]string) error {
+	_, err := c.fs.Collection(c.locations).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "checksums", Value: sums},
+	})
+	return err
+}
+
+// SetNameI18n merges localized display names (language code -> name) into a location.
+func (c *Client) SetNameI18n(ctx context.Context, id string, names map[string]string) error {
+	_, err := c.fs.Collection(c.locations).Doc(id).Set(ctx, map[string]interface{}{
+		"name_i18n": names,
+	}, firestore.MergeAll)
+	return err
+}
+
+// GetPresets returns all locations where is_preset = true.
+func (c *Client) GetPresets(ctx context.Context) ([]Location, error) {
+	var presets []Location
+	iter := c.fs.Collection(c.locations).Where("is_preset", "==", true).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var loc Location
+		if err := doc.DataTo(&loc); err != nil {
+			log.Printf("Failed to parse preset doc %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if loc.IsHidden() || loc.Status == StatusArchived {
+			continue
+		}
+		presets = append(presets, loc)
+	}
+	return presets, nil
+}
+
+// GetPresetSummaries is GetPresets for the gallery: the query projects only
+// the PresetSummary fields instead of reading whole documents, in the order
+// given by opts.
+func (c *Client) GetPresetSummaries(ctx context.Context, opts PresetOptions) ([]PresetSummary, error) {
+	dir := firestore.Asc
+	if opts.Desc {
+		dir = firestore.Desc
+	}
+	q := c.fs.Collection(c.locations).Where("is_preset", "==", true)
+	switch opts.Sort {
+	case SortName:
+		q = q.OrderBy("name", dir)
+	case SortUpdated:
+		q = q.OrderBy("last_updated", dir)
+	default:
+		// Alphabetical within and between unlisted categories; the display
+		// order is applied below since it lives in another collection
+		q = q.OrderBy("category", dir).OrderBy("name", firestore.Asc)
+	}
+
+	var presets []PresetSummary
+	iter := q.Select(presetSummaryFields...).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var p PresetSummary
+		if err := doc.DataTo(&p); err != nil {
+			log.Printf("Failed to parse preset doc %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if !p.Visible() {
+			continue
+		}
+		presets = append(presets, p)
+	}
+
+	if opts.Sort == "" || opts.Sort == SortCategory {
+		categories, err := c.ListCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		SortByCategoryOrder(presets, categories, opts.Desc)
+	}
+	return presets, nil
+}
+
+// WatchPresets listens to the presets with a Firestore snapshot listener and
+// calls fn with the changes of each snapshot. The first call carries every
+// visible preset as PresetAdded; a preset that becomes hidden is reported as
+// PresetRemoved, and as PresetAdded again once visible. It returns when ctx
+// is done or fn returns an error.
+func (c *Client) WatchPresets(ctx context.Context, fn func([]PresetChange) error) error {
+	// Listeners don't support projections, so full documents are read here
+	iter := c.fs.Collection(c.locations).Where("is_preset", "==", true).Snapshots(ctx)
+	defer iter.Stop()
+	sent := presetStream{}
+
+	for {
+		snap, err := iter.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		changes := make([]PresetChange, 0, len(snap.Changes))
+		for _, ch := range snap.Changes {
+			var p PresetSummary
+			if err := ch.Doc.DataTo(&p); err != nil {
+				log.Printf("Failed to parse preset doc %s: %v", ch.Doc.Ref.ID, err)
+				continue
+			}
+			p.ID = ch.Doc.Ref.ID
+
+			if change, ok := sent.change(p, ch.Kind == firestore.DocumentRemoved); ok {
+				changes = append(changes, change)
+			}
+		}
+		if err := fn(changes); err != nil {
+			return err
+		}
+	}
+}
+
+// presetStream holds the IDs of the presets a WatchPresets stream has sent as
+// visible, so it can tell presets new to the client from updates.
+type presetStream map[string]bool
+
+// change returns what the client sees of an update of p (removed when p was
+// deleted or is no longer a preset), if anything: a preset shown again after
+// being hidden is added again, and presets the client never got aren't
+// removed.
+func (s presetStream) change(p PresetSummary, removed bool) (PresetChange, bool) {
+	change := PresetChange{Preset: p}
+	switch {
+	case removed || !p.Visible():
+		if !s[p.ID] {
+			return change, false
+		}
+		delete(s, p.ID)
+		change.Type = PresetRemoved
+	case s[p.ID]:
+		change.Type = PresetModified
+	default:
+		s[p.ID] = true
+		change.Type = PresetAdded
+	}
+	return change, true
+}
+
+// ListCategories returns the categories collection in display order.
+func (c *Client) ListCategories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	iter := c.fs.Collection("categories").OrderBy("order", firestore.Asc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var cat Category
+		if err := doc.DataTo(&cat); err != nil {
+			log.Printf("Failed to parse category doc %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		categories = append(categories, cat)
+	}
+	return categories, nil
+}
+
+// SetCategories replaces the categories collection.
+func (c *Client) SetCategories(ctx context.Context, categories []Category) error {
+	coll := c.fs.Collection("categories")
+	refs, err := coll.DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if _, err := ref.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	for _, cat := range categories {
+		if _, err := coll.Doc(categoryDocID(cat.Name)).Set(ctx, cat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// categoryDocID makes a category name usable as a document ID.
+func categoryDocID(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// GetLocationsByCountry returns visible locations (presets and user) in a
+// country, most recently updated first. code is an ISO 3166-1 alpha-2 code.
+func (c *Client) GetLocationsByCountry(ctx context.Context, code string, limit int) ([]Location, error) {
+	q := c.fs.Collection(c.locations).
+		Where("country_code", "==", strings.ToUpper(code)).
+		OrderBy("last_updated", firestore.Desc)
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	var locs []Location
+	iter := q.Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var loc Location
+		if err := doc.DataTo(&loc); err != nil {
+			log.Printf("Failed to parse location doc %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if loc.IsHidden() || loc.Status == StatusArchived {
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// SetGeo stores the coordinates, country and continent derived from geocoding.
+func (c *Client) SetGeo(ctx context.Context, id string, geo *latlng.LatLng, countryCode, continent string) error {
+	_, err := c.fs.Collection(c.locations).Doc(id).Set(ctx, map[string]interface{}{
+		"geo":          geo,
+		"country_code": countryCode,
+		"continent":    continent,
+	}, firestore.MergeAll)
+	return err
+}
+
+// UpsertLocation creates or updates a location document, and records the
+// version written as a revision (see LocationRevision).
+func (c *Client) UpsertLocation(ctx context.Context, loc Location) error {
+	// Use ID as document ID if possible, ensuring uniqueness.
+	// If ID is empty (new user search), maybe hash the city query?
+	// For presets, ID is set.
+	
+	if loc.ID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+
+	loc.LastUpdated = c.clock.Now()
+	loc.Expire(c.ttl)
+	ref := c.fs.Collection(c.locations).Doc(loc.ID)
+	// The revision is written with the document, see GetLocationAsOf
+	return c.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(ref, loc); err != nil {
+			return err
+		}
+		return tx.Create(ref.Collection("history").NewDoc(), LocationRevision{Location: loc, CreatedAt: loc.LastUpdated, ExpireAt: loc.ExpireAt})
+	})
+}
+
+// PutLocation writes loc as given, keeping its last_updated: it copies
+// locations between collections.
+func (c *Client) PutLocation(ctx context.Context, loc Location) error {
+	if loc.ID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	_, err := c.fs.Collection(c.locations).Doc(loc.ID).Set(ctx, loc)
+	return err
+}
+
+// AddFeedback records a vote and atomically updates the aggregates on the location.
+// Returns a NotFound status error if the location doesn't exist.
+func (c *Client) AddFeedback(ctx context.Context, id string, fb Feedback) error {
+	ref := c.fs.Collection(c.locations).Doc(id)
+
+	score := 1
+	field := "feedback_up"
+	if !fb.Up {
+		score = -1
+		field = "feedback_down"
+	}
+	updates := []firestore.Update{
+		{Path: field, Value: firestore.Increment(1)},
+		{Path: "feedback_score", Value: firestore.Increment(score)},
+	}
+	if fb.Reason != "" {
+		updates = append(updates, firestore.Update{
+			FieldPath: firestore.FieldPath{"feedback_reasons", fb.Reason},
+			Value:     firestore.Increment(1),
+		})
+	}
+	if _, err := ref.Update(ctx, updates); err != nil {
+		return err
+	}
+
+	if fb.CreatedAt.IsZero() {
+		fb.CreatedAt = c.clock.Now()
+	}
+	if _, _, err := ref.Collection("feedback").Add(ctx, fb); err != nil {
+		// Aggregates are already updated; the raw record is best-effort.
+		log.Printf("Failed to store raw feedback for %s: %v", id, err)
+	}
+	return nil
+}
+
+// AddReport records an abuse report. Once reports reach threshold the location
+// is hidden pending review. hidden is true only for the report that caused the
+// takedown, so callers notify admins exactly once. A reporter's report is
+// kept under its Reporter ID and counted once; later ones are ignored.
+func (c *Client) AddReport(ctx context.Context, id string, r Report, threshold int) (hidden bool, err error) {
+	ref := c.fs.Collection(c.locations).Doc(id)
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = c.clock.Now()
+	}
+	reportRef := ref.Collection("reports").NewDoc()
+	if r.Reporter != "" {
+		reportRef = ref.Collection("reports").Doc(r.Reporter)
+	}
+
+	err = c.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		hidden = false
+		if r.Reporter != "" {
+			_, err := tx.Get(reportRef)
+			if err == nil {
+				return nil // Already reported by this client
+			}
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+		}
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var loc Location
+		if err := doc.DataTo(&loc); err != nil {
+			return err
+		}
+
+		updates := []firestore.Update{{Path: "reports", Value: firestore.Increment(1)}}
+		if !loc.IsHidden() && threshold > 0 && loc.Reports+1 >= threshold {
+			updates = append(updates, firestore.Update{Path: "status", Value: StatusHiddenPendingReview})
+			hidden = true
+		}
+		if err := tx.Update(ref, updates); err != nil {
+			return err
+		}
+		return tx.Create(reportRef, r)
+	})
+	return hidden, err
+}
+
+// SetStatus updates only the status of a location, creating a stub document
+// (id + status) if it doesn't exist yet.
+func (c *Client) SetStatus(ctx context.Context, id string, status LocationStatus) error {
+	if id == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	_, err := c.fs.Collection(c.locations).Doc(id).Set(ctx, map[string]interface{}{
+		"id":     id,
+		"status": status,
+	}, firestore.MergeAll)
+	return err
+}
+
+// ResolveReview clears a takedown after an admin approves the location.
+func (c *Client) ResolveReview(ctx context.Context, id string) error {
+	_, err := c.fs.Collection(c.locations).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: StatusReady},
+		{Path: "reports", Value: 0},
+	})
+	return err
+}
+
+// GetLocation retrieves a location by ID.
+func (c *Client) GetLocation(ctx context.Context, id string) (*Location, error) {
+	doc, err := c.fs.Collection(c.locations).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err // Returns NotFound status code if missing
+	}
+	var loc Location
+	if err := doc.DataTo(&loc); err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+// -- Prompt Cache --
+
+// GetPromptCache returns the cache entry for a prompt hash.
+func (c *Client) GetPromptCache(ctx context.Context, key string) (*PromptCacheEntry, error) {
+	doc, err := c.fs.Collection("prompt_cache").Doc(key).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var e PromptCacheEntry
+	if err := doc.DataTo(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// PutPromptCache stores a cache entry.
+func (c *Client) PutPromptCache(ctx context.Context, e PromptCacheEntry) error {
+	_, err := c.fs.Collection("prompt_cache").Doc(e.Key).Set(ctx, e)
+	return err
+}
+
+// -- Operation Tracking --
+
+// Operation names contain slashes, which Firestore doc IDs can't.
+func operationDocID(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// TrackOperation records an in-flight Veo operation.
+func (c *Client) TrackOperation(ctx context.Context, op PendingOperation) error {
+	_, err := c.fs.Collection("pending_operations").Doc(operationDocID(op.Name)).Set(ctx, op)
+	return err
+}
+
+// ClearOperation removes a finished or cancelled operation.
+func (c *Client) ClearOperation(ctx context.Context, name string) error {
+	_, err := c.fs.Collection("pending_operations").Doc(operationDocID(name)).Delete(ctx)
+	return err
+}
+
+// RecordModelTiming adds a finished operation's duration to the model's
+// timings, keeping the last MaxModelTimings.
+func (c *Client) RecordModelTiming(ctx context.Context, model string, d time.Duration) error {
+	ref := c.fs.Collection("model_timings").Doc(model)
+	return c.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		t := ModelTimings{Model: model}
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&t); err != nil {
+				return err
+			}
+		}
+		t.Durations = append(t.Durations, d.Seconds())
+		if n := len(t.Durations); n > MaxModelTimings {
+			t.Durations = t.Durations[n-MaxModelTimings:]
+		}
+		t.UpdatedAt = c.clock.Now()
+		return tx.Set(ref, t)
+	})
+}
+
+// GetModelTimings returns the model's recent durations; empty for models
+// without any yet.
+func (c *Client) GetModelTimings(ctx context.Context, model string) (*ModelTimings, error) {
+	doc, err := c.fs.Collection("model_timings").Doc(model).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return &ModelTimings{Model: model}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t ModelTimings
+	if err := doc.DataTo(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListOperations returns tracked operations, oldest first.
+func (c *Client) ListOperations(ctx context.Context) ([]PendingOperation, error) {
+	iter := c.fs.Collection("pending_operations").OrderBy("started_at", firestore.Asc).Documents(ctx)
+	var ops []PendingOperation
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var op PendingOperation
+		if err := doc.DataTo(&op); err != nil {
+			log.Printf("Skipping unparseable operation %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// -- Admin Methods --
+
+// DeleteLocation removes a location document. Media in GCS is left untouched.
+func (c *Client) DeleteLocation(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	_, err := c.fs.Collection(c.locations).Doc(id).Delete(ctx)
+	return err
+}
+
+type Stats struct {
+	TotalLocations    int64     `json:"total_locations"`
+	Presets           int64     `json:"presets"`
+	UserGenerated     int64     `json:"user_generated"`
+	WeatherMismatches int64     `json:"weather_mismatches"` // Locations whose image contradicts the observed weather
+	LastUpdated       time.Time `json:"last_updated"`
+}
+
+// GetStats returns aggregate statistics about the locations collection.
+func (c *Client) GetStats(ctx context.Context) (*Stats, error) {
+	coll := c.fs.Collection(c.locations)
+
+	// 1. Total Count
+	// NewAggregationQuery needs addressable Query
+	qTotal := coll.Query
+	aggTotal := qTotal.NewAggregationQuery().WithCount("total")
+	resTotal, err := aggTotal.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count total: %w", err)
+	}
+	var total int64
+	if val, ok := resTotal["total"]; ok {
+		if v, ok := val.(*firestorepb.Value); ok {
+			total = v.GetIntegerValue()
+		} else if v, ok := val.(int64); ok {
+			total = v
+		}
+	}
+
+	// 2. Preset Count
+	qPresets := coll.Where("is_preset", "==", true)
+	aggPresets := qPresets.NewAggregationQuery().WithCount("count")
+	resPresets, err := aggPresets.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count presets: %w", err)
+	}
+	
+	var presets int64
+	if val, ok := resPresets["count"]; ok {
+		if v, ok := val.(*firestorepb.Value); ok {
+			presets = v.GetIntegerValue()
+		} else if v, ok := val.(int64); ok {
+			presets = v
+		}
+	}
+
+	// 3. Weather Mismatches
+	qMismatch := coll.Where("weather_mismatch", "==", true)
+	aggMismatch := qMismatch.NewAggregationQuery().WithCount("count")
+	resMismatch, err := aggMismatch.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count weather mismatches: %w", err)
+	}
+	var mismatches int64
+	if val, ok := resMismatch["count"]; ok {
+		if v, ok := val.(*firestorepb.Value); ok {
+			mismatches = v.GetIntegerValue()
+		} else if v, ok := val.(int64); ok {
+			mismatches = v
+		}
+	}
+
+	// 4. Most Recent Update
+	var last time.Time
+	iter := coll.OrderBy("last_updated", firestore.Desc).Limit(1).Documents(ctx)
+	doc, err := iter.Next()
+	if err == nil {
+		var loc Location
+		if err := doc.DataTo(&loc); err == nil {
+			last = loc.LastUpdated
+		}
+	} else if err != iterator.Done {
+		log.Printf("Warning: failed to get last updated: %v", err)
+	}
+
+	return &Stats{
+		TotalLocations:    total,
+		Presets:           presets,
+		UserGenerated:     total - presets,
+		WeatherMismatches: mismatches,
+		LastUpdated:       last,
+	}, nil
+}
+
+// ListLocations returns a list of locations, optionally filtered and limited.
+// Sorting by "feedback" puts the lowest score first so problem media surfaces
+// at the top; docs written before feedback existed are omitted.
+func (c *Client) ListLocations(ctx context.Context, opts ListOptions) ([]Location, error) {
+	var query firestore.Query
+	switch opts.Sort {
+	case "feedback":
+		query = c.fs.Collection(c.locations).OrderBy("feedback_score", firestore.Asc)
+	default:
+		query = c.fs.Collection(c.locations).OrderBy("last_updated", firestore.Desc)
+	}
+
+	switch opts.Type {
+	case "preset":
+		query = query.Where("is_preset", "==", true)
+	case "user":
+		query = query.Where("is_preset", "==", false)
+	case "hidden":
+		query = query.Where("status", "in", []LocationStatus{StatusHidden, StatusHiddenPendingReview})
+	}
+
+	if opts.Status != "" {
+		query = query.Where("status", "==", opts.Status)
+	}
+
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	iter := query.Documents(ctx)
+	var locs []Location
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var l Location
+		if err := doc.DataTo(&l); err != nil {
+			log.Printf("Skipping unparseable doc %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		locs = append(locs, l)
+	}
+	return locs, nil
+}
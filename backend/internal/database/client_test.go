@@ -0,0 +1,67 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortByCategoryOrder(t *testing.T) {
+	// As returned by Firestore: category, then name
+	presets := func() []PresetSummary {
+		return []PresetSummary{
+			{ID: "kyoto", Category: "Asia"},
+			{ID: "berlin", Category: "Europe"},
+			{ID: "paris", Category: "Europe"},
+			{ID: "gotham", Category: "Fictional"},
+			{ID: "mordor", Category: "Fictional"},
+			{ID: "home", Category: "Misc"},
+		}
+	}
+	categories := []Category{{Name: "Fictional", Order: 0}, {Name: "Europe", Order: 1}}
+	ids := func(ps []PresetSummary) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	asc := presets()
+	SortByCategoryOrder(asc, categories, false)
+	if want := []string{"gotham", "mordor", "berlin", "paris", "kyoto", "home"}; !slices.Equal(ids(asc), want) {
+		t.Errorf("asc: expected %v, got %v", want, ids(asc))
+	}
+
+	// Descending: Firestore returns categories Z-A (names still A-Z), listed categories trail
+	p := presets()
+	desc := []PresetSummary{p[5], p[3], p[4], p[1], p[2], p[0]}
+	SortByCategoryOrder(desc, categories, true)
+	if want := []string{"home", "kyoto", "berlin", "paris", "gotham", "mordor"}; !slices.Equal(ids(desc), want) {
+		t.Errorf("desc: expected %v, got %v", want, ids(desc))
+	}
+}
+
+func TestPresetStream(t *testing.T) {
+	s := presetStream{}
+	var got []string
+	update := func(id string, status LocationStatus, removed bool) {
+		if change, ok := s.change(PresetSummary{ID: id, Status: status}, removed); ok {
+			got = append(got, change.Type+" "+change.Preset.ID)
+		}
+	}
+
+	update("paris", StatusReady, false)               // Initial snapshot
+	update("oslo", StatusHiddenPendingReview, false)  // Hidden from the start
+	update("paris", StatusGenerating, false)          // Refreshed
+	update("paris", StatusHiddenPendingReview, false) // Reported
+	update("paris", StatusHiddenPendingReview, false) // Edited while hidden
+	update("paris", StatusReady, false)               // Reviewed and shown again
+	update("oslo", StatusReady, false)                // Shown for the first time
+	update("oslo", "", true)                          // Deleted
+	update("lima", StatusHidden, true)                // Deleted, never sent
+
+	want := []string{"added paris", "modified paris", "removed paris", "added paris", "added oslo", "removed oslo"}
+	if !slices.Equal(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/firestore"
+)
+
+// EditableFields are the location fields UpdateLocations can set, by their
+// stored names. Media, feedback and generation fields are maintained by the
+// pipeline and aren't editable.
+var EditableFields = []string{"name", "category", "city_query", "status", "is_preset", "country_code", "continent", "video_prompt", "prompt_context", "locked"}
+
+// MaxVideoPromptLength caps admin-set Veo prompts, in runes.
+const MaxVideoPromptLength = 1000
+
+// MaxPromptContextLength caps the extra image prompt context of a location,
+// in runes.
+const MaxPromptContextLength = 1000
+
+// LocationEdit sets some fields of one location.
+type LocationEdit struct {
+	ID     string         `json:"id"`
+	Fields map[string]any `json:"fields"` // Stored field name -> value, see ParseEditValue
+}
+
+// ParseEditValue validates a field name and converts a text value to the type
+// stored for it.
+func ParseEditValue(field, value string) (any, error) {
+	switch field {
+	case "is_preset", "locked":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", field, value)
+		}
+		return b, nil
+	case "status":
+		st := LocationStatus(value)
+		if !slices.Contains(LocationStatuses, st) {
+			return nil, fmt.Errorf("unknown status %q", value)
+		}
+		return st, nil
+	case "video_prompt":
+		if n := utf8.RuneCountInString(value); n > MaxVideoPromptLength {
+			return nil, fmt.Errorf("video_prompt is %d characters, the limit is %d", n, MaxVideoPromptLength)
+		}
+		return strings.TrimSpace(value), nil
+	case "prompt_context":
+		if n := utf8.RuneCountInString(value); n > MaxPromptContextLength {
+			return nil, fmt.Errorf("prompt_context is %d characters, the limit is %d", n, MaxPromptContextLength)
+		}
+		return strings.TrimSpace(value), nil
+	}
+	if !slices.Contains(EditableFields, field) {
+		return nil, fmt.Errorf("field %q is not editable (editable: %v)", field, EditableFields)
+	}
+	return value, nil
+}
+
+// UpdateLocations applies edits through a BulkWriter, which batches and
+// retries the writes. last_updated is left alone, since it dates the media.
+// Locations that don't exist fail; the first error is returned after all
+// writes have been attempted.
+func (c *Client) UpdateLocations(ctx context.Context, edits []LocationEdit) error {
+	bw := c.fs.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(edits))
+	for _, e := range edits {
+		if e.ID == "" {
+			bw.End()
+			return fmt.Errorf("location ID is required")
+		}
+		var updates []firestore.Update
+		for field, value := range e.Fields {
+			updates = append(updates, firestore.Update{Path: field, Value: value})
+		}
+		if e.Fields["is_preset"] == true {
+			// Presets never expire, see Location.Expire
+			updates = append(updates, firestore.Update{Path: "expire_at", Value: firestore.Delete})
+		}
+		job, err := bw.Update(c.fs.Collection(c.locations).Doc(e.ID), updates)
+		if err != nil {
+			bw.End()
+			return fmt.Errorf("failed to queue update of %s: %w", e.ID, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	var firstErr error
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to update %s: %w", edits[i].ID, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// FeaturedCity is one day's city of the day, kept in city_of_the_day/{date}
+// as the selection history.
+type FeaturedCity struct {
+	Date       string    `firestore:"date" json:"date"` // YYYY-MM-DD (UTC)
+	LocationID string    `firestore:"location_id" json:"location_id"`
+	Name       string    `firestore:"name" json:"name"`
+	ImageURL   string    `firestore:"image_url" json:"image_url"` // Media as regenerated for the day
+	VideoURL   string    `firestore:"video_url" json:"video_url"`
+	SelectedAt time.Time `firestore:"selected_at" json:"selected_at"`
+}
+
+// FeaturedDate formats t as a city of the day date.
+func FeaturedDate(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// SetCityOfTheDay records the city of the day, replacing an earlier pick for
+// the same date.
+func (c *Client) SetCityOfTheDay(ctx context.Context, f FeaturedCity) error {
+	if f.Date == "" {
+		return fmt.Errorf("date is required")
+	}
+	if f.SelectedAt.IsZero() {
+		f.SelectedAt = c.clock.Now()
+	}
+	_, err := c.fs.Collection("city_of_the_day").Doc(f.Date).Set(ctx, f)
+	return err
+}
+
+// ListCityOfTheDay returns past selections, newest first.
+func (c *Client) ListCityOfTheDay(ctx context.Context, limit int) ([]FeaturedCity, error) {
+	query := c.fs.Collection("city_of_the_day").OrderBy("date", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var out []FeaturedCity
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var f FeaturedCity
+		if err := doc.DataTo(&f); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
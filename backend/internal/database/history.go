@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocationRevision is a location as one UpsertLocation wrote it, kept in
+// locations/{id}/history so admins can see what a location (and its media)
+// looked like at a given time. Partial updates (status, feedback, edits)
+// don't add revisions; edits are in the audit log.
+type LocationRevision struct {
+	Location  Location   `firestore:"location" json:"location"`
+	CreatedAt time.Time  `firestore:"created_at" json:"created_at"`
+	ExpireAt  *time.Time `firestore:"expire_at,omitempty" json:"-"` // The location's, so TTL deletes its history with it
+}
+
+// GetLocationAsOf returns the latest revision of location id written at or
+// before at, or a NotFound error when there's none.
+func (c *Client) GetLocationAsOf(ctx context.Context, id string, at time.Time) (*LocationRevision, error) {
+	iter := c.fs.Collection(c.locations).Doc(id).Collection("history").
+		Where("created_at", "<=", at).
+		OrderBy("created_at", firestore.Desc).
+		Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, status.Errorf(codes.NotFound, "no revision of %q at %s", id, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rev LocationRevision
+	if err := doc.DataTo(&rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// ListAuditEntries returns the audit entries recorded for location (an ID,
+// or the formatted address of entries about places) between since and until,
+// oldest first. Only the location is queried, so no composite index is
+// needed; the range is applied here.
+func (c *Client) ListAuditEntries(ctx context.Context, location string, since, until time.Time) ([]AuditEntry, error) {
+	iter := c.fs.Collection("audit_log").Where("location", "==", location).Documents(ctx)
+	defer iter.Stop()
+
+	var out []AuditEntry
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e AuditEntry
+		if err := doc.DataTo(&e); err != nil {
+			return nil, err
+		}
+		if e.CreatedAt.Before(since) || e.CreatedAt.After(until) {
+			continue
+		}
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b AuditEntry) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/api/iterator"
+)
+
+// IndexField is one field of a composite index, in firestore.indexes.json form.
+type IndexField struct {
+	FieldPath string `json:"fieldPath"`
+	Order     string `json:"order"` // "ASCENDING" or "DESCENDING"
+}
+
+// Index is a composite index required by a query in this package.
+type Index struct {
+	CollectionGroup string       `json:"collectionGroup"`
+	QueryScope      string       `json:"queryScope"`
+	Fields          []IndexField `json:"fields"`
+	Query           string       `json:"-"` // Which code path needs it
+}
+
+func (i Index) String() string {
+	fields := make([]string, len(i.Fields))
+	for n, f := range i.Fields {
+		dir := "ASC"
+		if f.Order == "DESCENDING" {
+			dir = "DESC"
+		}
+		fields[n] = f.FieldPath + " " + dir
+	}
+	return fmt.Sprintf("%s(%s)", i.CollectionGroup, strings.Join(fields, ", "))
+}
+
+// GcloudCommand returns the gcloud invocation that creates the index.
+func (i Index) GcloudCommand(databaseID string) string {
+	args := []string{"gcloud firestore indexes composite create",
+		"--collection-group=" + i.CollectionGroup,
+		"--database=" + databaseID}
+	for _, f := range i.Fields {
+		args = append(args, fmt.Sprintf("--field-config=field-path=%s,order=%s", f.FieldPath, strings.ToLower(f.Order)))
+	}
+	return strings.Join(args, " ")
+}
+
+func compositeIndex(query string, fields ...IndexField) Index {
+	return Index{CollectionGroup: "locations", QueryScope: "COLLECTION", Fields: fields, Query: query}
+}
+
+var (
+	byPreset       = IndexField{"is_preset", "ASCENDING"}
+	byStatus       = IndexField{"status", "ASCENDING"}
+	byCountry      = IndexField{"country_code", "ASCENDING"}
+	byUpdatedDesc  = IndexField{"last_updated", "DESCENDING"}
+	byFeedback     = IndexField{"feedback_score", "ASCENDING"}
+	byUpdatedAsc   = IndexField{"last_updated", "ASCENDING"}
+	byName         = IndexField{"name", "ASCENDING"}
+	byNameDesc     = IndexField{"name", "DESCENDING"}
+	byCategory     = IndexField{"category", "ASCENDING"}
+	byCategoryDesc = IndexField{"category", "DESCENDING"}
+)
+
+// RequiredIndexes lists the composite indexes behind the queries this package
+// issues. Equality filters combined with an OrderBy on another field need one;
+// single-field queries are covered by Firestore's automatic indexes.
+// Keep this in sync when adding a filter or sort to a query.
+var RequiredIndexes = []Index{
+	compositeIndex("ListLocations type=preset|user, sort=updated", byPreset, byUpdatedDesc),
+	compositeIndex("ListLocations type=preset|user, sort=feedback", byPreset, byFeedback),
+	compositeIndex("ListLocations type=hidden or status, sort=updated", byStatus, byUpdatedDesc),
+	compositeIndex("ListLocations type=hidden or status, sort=feedback", byStatus, byFeedback),
+	compositeIndex("ListLocations type=preset|user + status, sort=updated", byPreset, byStatus, byUpdatedDesc),
+	compositeIndex("ListLocations type=preset|user + status, sort=feedback", byPreset, byStatus, byFeedback),
+	compositeIndex("GetLocationsByCountry", byCountry, byUpdatedDesc),
+	compositeIndex("GetPresetSummaries sort=category", byPreset, byCategory, byName),
+	compositeIndex("GetPresetSummaries sort=category, order=desc", byPreset, byCategoryDesc, byName),
+	compositeIndex("GetPresetSummaries sort=name", byPreset, byName),
+	compositeIndex("GetPresetSummaries sort=name, order=desc", byPreset, byNameDesc),
+	compositeIndex("GetPresetSummaries sort=updated", byPreset, byUpdatedAsc),
+	// sort=updated, order=desc shares the ListLocations type=preset index
+	{CollectionGroup: "candidates", QueryScope: "COLLECTION", Query: "ListCandidates",
+		Fields: []IndexField{{"location_id", "ASCENDING"}, {"created_at", "DESCENDING"}}},
+}
+
+// IndexFile is the firestore.indexes.json layout read by the Firebase CLI.
+type IndexFile struct {
+	Indexes        []Index `json:"indexes"`
+	FieldOverrides []any   `json:"fieldOverrides"`
+}
+
+// NewIndexFile returns the index file for RequiredIndexes.
+func NewIndexFile() IndexFile {
+	return IndexFile{Indexes: RequiredIndexes, FieldOverrides: []any{}}
+}
+
+// Index states reported by CheckIndexes.
+const (
+	IndexReady    = "ready"
+	IndexBuilding = "building"
+	IndexMissing  = "missing"
+)
+
+// IndexStatus is a required index and whether the database has it.
+type IndexStatus struct {
+	Index
+	State string
+}
+
+// CheckIndexes compares RequiredIndexes with the database's composite indexes.
+// It needs the datastore.indexes.list permission.
+func CheckIndexes(ctx context.Context, projectID, databaseID string) ([]IndexStatus, error) {
+	client, err := admin.NewFirestoreAdminClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore admin client: %w", err)
+	}
+	defer client.Close()
+
+	// Existing composite indexes keyed by collection + field list
+	existing := make(map[string]adminpb.Index_State)
+	groups := make(map[string]bool)
+	for _, idx := range RequiredIndexes {
+		if groups[idx.CollectionGroup] {
+			continue
+		}
+		groups[idx.CollectionGroup] = true
+
+		parent := fmt.Sprintf("projects/%s/databases/%s/collectionGroups/%s", projectID, databaseID, idx.CollectionGroup)
+		it := client.ListIndexes(ctx, &adminpb.ListIndexesRequest{Parent: parent})
+		for {
+			ix, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list indexes: %w", err)
+			}
+			if ix.GetQueryScope() != adminpb.Index_COLLECTION {
+				continue
+			}
+			var fields []IndexField
+			for _, f := range ix.GetFields() {
+				if f.GetFieldPath() == "__name__" { // Appended automatically
+					continue
+				}
+				fields = append(fields, IndexField{f.GetFieldPath(), f.GetOrder().String()})
+			}
+			existing[indexKey(idx.CollectionGroup, fields)] = ix.GetState()
+		}
+	}
+
+	statuses := make([]IndexStatus, len(RequiredIndexes))
+	for n, idx := range RequiredIndexes {
+		state, ok := existing[indexKey(idx.CollectionGroup, idx.Fields)]
+		switch {
+		case !ok:
+			statuses[n] = IndexStatus{idx, IndexMissing}
+		case state == adminpb.Index_READY:
+			statuses[n] = IndexStatus{idx, IndexReady}
+		default:
+			statuses[n] = IndexStatus{idx, IndexBuilding}
+		}
+	}
+	return statuses, nil
+}
+
+func indexKey(collection string, fields []IndexField) string {
+	parts := []string{collection}
+	for _, f := range fields {
+		parts = append(parts, f.FieldPath+":"+f.Order)
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
+
+// Stages of the web flow timed in latency_stats.
+const (
+	LatencyCache = "cache" // Outcome LatencyHit or LatencyMiss
+	LatencyImage = "image"
+	LatencyVideo = "video"
+)
+
+// Outcomes of a LatencySample.
+const (
+	LatencyOK    = "ok"
+	LatencyError = "error"
+	LatencyHit   = "hit"
+	LatencyMiss  = "miss"
+)
+
+// LatencySample is how long one stage of a generation took, kept in
+// latency_stats for SLO reports.
+type LatencySample struct {
+	Stage      string    `firestore:"stage" json:"stage"`
+	Outcome    string    `firestore:"outcome" json:"outcome"`
+	DurationMS int64     `firestore:"duration_ms" json:"duration_ms"`
+	LocationID string    `firestore:"location_id" json:"location_id"`
+	CreatedAt  time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// StageLatency is the latency distribution of a stage's successful runs, in
+// seconds.
+type StageLatency struct {
+	Stage  string  `json:"stage"`
+	Count  int     `json:"count"`
+	Errors int     `json:"errors"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// SLOReport summarizes latency_stats over a window.
+type SLOReport struct {
+	Since        time.Time      `json:"since"`
+	Stages       []StageLatency `json:"stages"` // Image, then video
+	CacheLookups int            `json:"cache_lookups"`
+	CacheHitRate float64        `json:"cache_hit_rate"` // 0-1
+}
+
+// DefaultSLOWindow is the window SLO reports cover unless asked otherwise.
+const DefaultSLOWindow = 7 * 24 * time.Hour
+
+// ParseWindow parses an SLO report window: days ("7d") or a Go duration
+// ("12h").
+func ParseWindow(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	}
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("window must be positive")
+	}
+	return d, err
+}
+
+// SummarizeLatency builds the SLO report of samples taken since since.
+func SummarizeLatency(samples []LatencySample, since time.Time) *SLOReport {
+	r := &SLOReport{Since: since, Stages: []StageLatency{}}
+	durations := map[string][]float64{}
+	errors := map[string]int{}
+	hits := 0
+	for _, s := range samples {
+		switch {
+		case s.Stage == LatencyCache:
+			r.CacheLookups++
+			if s.Outcome == LatencyHit {
+				hits++
+			}
+		case s.Outcome == LatencyError:
+			errors[s.Stage]++
+		default:
+			durations[s.Stage] = append(durations[s.Stage], float64(s.DurationMS)/1000)
+		}
+	}
+	if r.CacheLookups > 0 {
+		r.CacheHitRate = float64(hits) / float64(r.CacheLookups)
+	}
+	for _, stage := range []string{LatencyImage, LatencyVideo} {
+		d := durations[stage]
+		slices.Sort(d)
+		r.Stages = append(r.Stages, StageLatency{
+			Stage:  stage,
+			Count:  len(d),
+			Errors: errors[stage],
+			P50:    percentile(d, 50),
+			P95:    percentile(d, 95),
+			P99:    percentile(d, 99),
+		})
+	}
+	return r
+}
+
+// percentile returns the nearest-rank percentile p of sorted, or 0 when empty.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// AddLatencySample records a stage duration.
+func (c *Client) AddLatencySample(ctx context.Context, s LatencySample) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = c.clock.Now()
+	}
+	_, _, err := c.fs.Collection("latency_stats").Add(ctx, s)
+	return err
+}
+
+// ListLatencySamples returns the samples recorded since since.
+func (c *Client) ListLatencySamples(ctx context.Context, since time.Time) ([]LatencySample, error) {
+	iter := c.fs.Collection("latency_stats").Where("created_at", ">=", since).Documents(ctx)
+	defer iter.Stop()
+
+	var out []LatencySample
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var s LatencySample
+		if err := doc.DataTo(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
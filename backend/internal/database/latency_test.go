@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatency(t *testing.T) {
+	var samples []LatencySample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, LatencySample{Stage: LatencyVideo, Outcome: LatencyOK, DurationMS: int64(i) * 1000})
+	}
+	samples = append(samples,
+		LatencySample{Stage: LatencyVideo, Outcome: LatencyError, DurationMS: 500_000},
+		LatencySample{Stage: LatencyImage, Outcome: LatencyOK, DurationMS: 8000},
+		LatencySample{Stage: LatencyCache, Outcome: LatencyHit},
+		LatencySample{Stage: LatencyCache, Outcome: LatencyHit},
+		LatencySample{Stage: LatencyCache, Outcome: LatencyHit},
+		LatencySample{Stage: LatencyCache, Outcome: LatencyMiss},
+	)
+
+	r := SummarizeLatency(samples, time.Time{})
+	if len(r.Stages) != 2 || r.Stages[0].Stage != LatencyImage || r.Stages[1].Stage != LatencyVideo {
+		t.Fatalf("Expected image then video, got %+v", r.Stages)
+	}
+	if img := r.Stages[0]; img.Count != 1 || img.P50 != 8 || img.P99 != 8 {
+		t.Errorf("Unexpected image stats %+v", img)
+	}
+	if v := r.Stages[1]; v.Count != 100 || v.Errors != 1 || v.P50 != 50 || v.P95 != 95 || v.P99 != 99 {
+		t.Errorf("Unexpected video stats %+v", v)
+	}
+	if r.CacheLookups != 4 || r.CacheHitRate != 0.75 {
+		t.Errorf("Expected a 75%% hit rate of 4 lookups, got %v of %d", r.CacheHitRate, r.CacheLookups)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "12h": 12 * time.Hour} {
+		if got, err := ParseWindow(in); err != nil || got != want {
+			t.Errorf("ParseWindow(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "0h", "week"} {
+		if _, err := ParseWindow(in); err == nil {
+			t.Errorf("Expected ParseWindow(%q) to fail", in)
+		}
+	}
+}
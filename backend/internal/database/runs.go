@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
+
+// Run item states.
+const (
+	RunItemRunning = "running"
+	RunItemDone    = "done"   // Generated, or updated without generating when it existed
+	RunItemFailed  = "failed" // Retried by the next attempt of the run
+)
+
+// Run is a batch generation run of cmd/worker, e.g. one Cloud Run Jobs
+// execution. Its items checkpoint each preset, so a retried task resumes
+// where it stopped.
+type Run struct {
+	ID        string    `firestore:"-" json:"id"`
+	Source    string    `firestore:"source" json:"source"` // Preset CSV: gs:// URI or file path
+	Force     bool      `firestore:"force" json:"force"`   // Overwrite existing presets' media
+	Total     int       `firestore:"total" json:"total"`   // Presets in the source
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// RunItem is the progress of one preset of a run, by preset ID.
+type RunItem struct {
+	ID        string    `firestore:"-" json:"id"`
+	Status    string    `firestore:"status" json:"status"` // e.g. RunItemDone
+	Task      int       `firestore:"task" json:"task"`     // Index of the task that handled it last
+	Attempts  int       `firestore:"attempts" json:"attempts"`
+	Error     string    `firestore:"error,omitempty" json:"error,omitempty"`
+	ImageURL  string    `firestore:"image_url,omitempty" json:"image_url,omitempty"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// GetRun returns a run by ID.
+func (c *Client) GetRun(ctx context.Context, id string) (*Run, error) {
+	doc, err := c.fs.Collection("runs").Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := doc.DataTo(&run); err != nil {
+		return nil, err
+	}
+	run.ID = doc.Ref.ID
+	return &run, nil
+}
+
+// SaveRun creates or replaces a run, stamping its update time. Its items
+// are kept.
+func (c *Client) SaveRun(ctx context.Context, run Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("run ID is required")
+	}
+	run.UpdatedAt = c.clock.Now()
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = run.UpdatedAt
+	}
+	_, err := c.fs.Collection("runs").Doc(run.ID).Set(ctx, run)
+	return err
+}
+
+// SetRunItem records the progress of a preset of a run, in
+// runs/{id}/items/{preset}.
+func (c *Client) SetRunItem(ctx context.Context, runID string, item RunItem) error {
+	if item.ID == "" {
+		return fmt.Errorf("item ID is required")
+	}
+	item.UpdatedAt = c.clock.Now()
+	_, err := c.fs.Collection("runs").Doc(runID).Collection("items").Doc(item.ID).Set(ctx, item)
+	return err
+}
+
+// ListRunItems returns the items of a run.
+func (c *Client) ListRunItems(ctx context.Context, runID string) ([]RunItem, error) {
+	iter := c.fs.Collection("runs").Doc(runID).Collection("items").Documents(ctx)
+	defer iter.Stop()
+
+	var out []RunItem
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var item RunItem
+		if err := doc.DataTo(&item); err != nil {
+			return nil, err
+		}
+		item.ID = doc.Ref.ID
+		out = append(out, item)
+	}
+	return out, nil
+}
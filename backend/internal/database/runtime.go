@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RuntimeSettings are deployment-wide switches that take effect without a
+// redeploy, flipped by operators or the alert evaluator. Stored in
+// settings/runtime.
+type RuntimeSettings struct {
+	DegradeImageOnly bool            `firestore:"degrade_image_only" json:"degrade_image_only"` // The web flow skips video generation
+	DegradeReason    string          `firestore:"degrade_reason" json:"degrade_reason"`
+	Alerts           AlertThresholds `firestore:"alerts" json:"alerts"`
+	AlertFiring      bool            `firestore:"alert_firing" json:"alert_firing"` // Kept by the evaluator, so each incident notifies once
+
+	// KillSwitch stops generation for every tenant; TenantKillSwitches for
+	// one, by tenant ID ("default" for deployments without TENANT_ID).
+	KillSwitch         KillSwitch            `firestore:"kill_switch" json:"kill_switch"`
+	TenantKillSwitches map[string]KillSwitch `firestore:"tenant_kill_switches,omitempty" json:"tenant_kill_switches,omitempty"`
+}
+
+// DefaultTenant keys the kill switch of deployments without a tenant ID.
+const DefaultTenant = "default"
+
+// KillSwitch is an emergency stop of generation: while it's active, the web
+// flow serves cached, stale or fallback media only and other generations
+// fail. It turns itself off at ExpiresAt, so a forgotten switch doesn't keep
+// a deployment frozen.
+type KillSwitch struct {
+	On        bool       `firestore:"on" json:"on"`
+	Reason    string     `firestore:"reason" json:"reason"` // For operators, e.g. "veo quota incident"; not shown to users
+	Since     time.Time  `firestore:"since" json:"since"`
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"` // Never when nil
+}
+
+// Active reports whether the switch is on and not expired at now.
+func (k KillSwitch) Active(now time.Time) bool {
+	return k.On && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Halted returns the active kill switch of tenant at now, the global one
+// first, or nil when generation may run.
+func (s *RuntimeSettings) Halted(tenant string, now time.Time) *KillSwitch {
+	if s.KillSwitch.Active(now) {
+		return &s.KillSwitch
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	if k, ok := s.TenantKillSwitches[tenant]; ok && k.Active(now) {
+		return &k
+	}
+	return nil
+}
+
+// SetKillSwitch replaces the kill switch of tenant, or the global one when
+// tenant is empty. A switch turned off is removed from the tenants.
+func (s *RuntimeSettings) SetKillSwitch(tenant string, k KillSwitch) {
+	if tenant == "" {
+		s.KillSwitch = k
+		return
+	}
+	if !k.On {
+		delete(s.TenantKillSwitches, tenant)
+		return
+	}
+	if s.TenantKillSwitches == nil {
+		s.TenantKillSwitches = map[string]KillSwitch{}
+	}
+	s.TenantKillSwitches[tenant] = k
+}
+
+// AlertThresholds configure the generation alert evaluator. A zero threshold
+// is off.
+type AlertThresholds struct {
+	WindowMinutes  int     `firestore:"window_minutes" json:"window_minutes"`     // Sliding window (default 60)
+	MinSamples     int     `firestore:"min_samples" json:"min_samples"`           // Generations in the window before alerting (default 10)
+	MaxFailureRate float64 `firestore:"max_failure_rate" json:"max_failure_rate"` // 0-1, of image and video generations
+	MaxImageP95    float64 `firestore:"max_image_p95" json:"max_image_p95"`       // Seconds
+	MaxVideoP95    float64 `firestore:"max_video_p95" json:"max_video_p95"`       // Seconds
+	AutoDegrade    bool    `firestore:"auto_degrade" json:"auto_degrade"`         // Turn on DegradeImageOnly when an alert fires
+}
+
+// Enabled reports whether any threshold is set.
+func (a AlertThresholds) Enabled() bool {
+	return a.MaxFailureRate > 0 || a.MaxImageP95 > 0 || a.MaxVideoP95 > 0
+}
+
+// Window returns the sliding window alerts are evaluated over.
+func (a AlertThresholds) Window() time.Duration {
+	if a.WindowMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(a.WindowMinutes) * time.Minute
+}
+
+// Samples returns the number of generations needed before alerting, so a
+// single failure at night doesn't page anyone.
+func (a AlertThresholds) Samples() int {
+	if a.MinSamples <= 0 {
+		return 10
+	}
+	return a.MinSamples
+}
+
+// AlertReport is the outcome of an alert evaluation.
+type AlertReport struct {
+	Firing      bool       `json:"firing"`
+	Reasons     []string   `json:"reasons"`      // Thresholds exceeded
+	Generations int        `json:"generations"`  // Image and video generations in the window
+	FailureRate float64    `json:"failure_rate"` // 0-1
+	Notified    bool       `json:"notified"`     // The alert (or its resolution) was sent by this evaluation
+	Degraded    bool       `json:"degraded"`     // Image-only mode is on
+	SLO         *SLOReport `json:"slo,omitempty"`
+}
+
+// GetRuntimeSettings returns the runtime settings, all off when never set.
+func (c *Client) GetRuntimeSettings(ctx context.Context) (*RuntimeSettings, error) {
+	doc, err := c.fs.Collection("settings").Doc("runtime").Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return &RuntimeSettings{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s RuntimeSettings
+	if err := doc.DataTo(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateRuntimeSettings applies update to the current runtime settings and
+// saves them in a transaction, so concurrent writers (the alert evaluator,
+// banana admin runtime and killswitch) don't undo each other's changes, and
+// returns the settings saved. update runs again when the transaction is
+// retried.
+func (c *Client) UpdateRuntimeSettings(ctx context.Context, update func(s *RuntimeSettings)) (*RuntimeSettings, error) {
+	ref := c.fs.Collection("settings").Doc("runtime")
+	var s RuntimeSettings
+	err := c.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		s = RuntimeSettings{}
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&s); err != nil {
+				return err
+			}
+		}
+		update(&s)
+		return tx.Set(ref, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKillSwitch(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	var s RuntimeSettings
+	if s.Halted("", now) != nil {
+		t.Fatal("Expected generation to run without a kill switch")
+	}
+
+	s.SetKillSwitch("acme", KillSwitch{On: true, Reason: "acme incident", ExpiresAt: &later})
+	if k := s.Halted("acme", now); k == nil || k.Reason != "acme incident" {
+		t.Errorf("Expected acme halted, got %+v", k)
+	}
+	if s.Halted("", now) != nil || s.Halted("globex", now) != nil {
+		t.Error("Expected other tenants to keep generating")
+	}
+	if s.Halted("acme", later) != nil {
+		t.Error("Expected the switch to expire")
+	}
+
+	s.SetKillSwitch(DefaultTenant, KillSwitch{On: true, Reason: "default tenant"})
+	if k := s.Halted("", now); k == nil || k.Reason != "default tenant" {
+		t.Errorf("Expected the default tenant halted, got %+v", k)
+	}
+	s.SetKillSwitch("", KillSwitch{On: true, Reason: "veo quota incident"})
+	if k := s.Halted("acme", now); k == nil || k.Reason != "veo quota incident" {
+		t.Errorf("Expected the global switch first, got %+v", k)
+	}
+
+	s.SetKillSwitch("", KillSwitch{})
+	s.SetKillSwitch("acme", KillSwitch{})
+	if s.Halted("acme", now) != nil || len(s.TenantKillSwitches) != 1 {
+		t.Errorf("Expected acme's switch removed, got %+v", s.TenantKillSwitches)
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// MaxTraceData is how much of an event's data a flow trace keeps. Result
+// events carry the whole image as base64, which would blow past Firestore's
+// document size limit.
+const MaxTraceData = 2048
+
+// FlowEvent is one server-sent event of a weather flow.
+type FlowEvent struct {
+	Event    string `firestore:"event" json:"event"`
+	Data     string `firestore:"data" json:"data"`
+	OffsetMS int64  `firestore:"offset_ms" json:"offset_ms"`           // Since the flow started
+	Size     int    `firestore:"size,omitempty" json:"size,omitempty"` // Of the data, when truncated
+}
+
+// FlowTrace is the ordered event stream a client was sent for one weather
+// flow, kept in flow_traces for debugging reports like "it showed X and then
+// hung".
+type FlowTrace struct {
+	ID         string      `firestore:"-" json:"id"`
+	Query      string      `firestore:"query" json:"query"`
+	Lat        string      `firestore:"lat,omitempty" json:"lat,omitempty"`
+	Lng        string      `firestore:"lng,omitempty" json:"lng,omitempty"`
+	Reference  string      `firestore:"reference,omitempty" json:"reference,omitempty"`
+	StartedAt  time.Time   `firestore:"started_at" json:"started_at"`
+	FinishedAt time.Time   `firestore:"finished_at" json:"finished_at"`
+	Error      string      `firestore:"error,omitempty" json:"error,omitempty"` // Why the flow ended early, incl. client disconnects
+	Events     []FlowEvent `firestore:"events" json:"events"`
+}
+
+// Add appends an event sent at, truncating long data.
+func (t *FlowTrace) Add(event, data string, at time.Time) {
+	e := FlowEvent{Event: event, Data: data, OffsetMS: at.Sub(t.StartedAt).Milliseconds()}
+	if len(data) > MaxTraceData {
+		e.Data = data[:MaxTraceData]
+		e.Size = len(data)
+	}
+	t.Events = append(t.Events, e)
+}
+
+// SaveFlowTrace stores a trace under its ID.
+func (c *Client) SaveFlowTrace(ctx context.Context, t FlowTrace) error {
+	if t.ID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	_, err := c.fs.Collection("flow_traces").Doc(t.ID).Set(ctx, t)
+	return err
+}
+
+// GetFlowTrace returns a trace, or a NotFound error.
+func (c *Client) GetFlowTrace(ctx context.Context, id string) (*FlowTrace, error) {
+	doc, err := c.fs.Collection("flow_traces").Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var t FlowTrace
+	if err := doc.DataTo(&t); err != nil {
+		return nil, err
+	}
+	t.ID = doc.Ref.ID
+	return &t, nil
+}
+
+// ListFlowTraces returns the latest traces, newest first.
+func (c *Client) ListFlowTraces(ctx context.Context, limit int) ([]FlowTrace, error) {
+	iter := c.fs.Collection("flow_traces").OrderBy("started_at", firestore.Desc).Limit(limit).Documents(ctx)
+	defer iter.Stop()
+
+	var out []FlowTrace
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var t FlowTrace
+		if err := doc.DataTo(&t); err != nil {
+			return nil, err
+		}
+		t.ID = doc.Ref.ID
+		out = append(out, t)
+	}
+	return out, nil
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlowTraceAdd(t *testing.T) {
+	start := time.Now()
+	tr := &FlowTrace{StartedAt: start}
+	tr.Add("status", "Identifying location...", start.Add(250*time.Millisecond))
+	image := strings.Repeat("A", MaxTraceData*3)
+	tr.Add("result", image, start.Add(9*time.Second))
+
+	if len(tr.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(tr.Events))
+	}
+	if e := tr.Events[0]; e.Event != "status" || e.OffsetMS != 250 || e.Size != 0 {
+		t.Errorf("Unexpected status event %+v", e)
+	}
+	if e := tr.Events[1]; len(e.Data) != MaxTraceData || e.Size != len(image) || e.OffsetMS != 9000 {
+		t.Errorf("Expected result data truncated to %d of %d bytes at 9s, got %d of %d at %dms", MaxTraceData, len(image), len(e.Data), e.Size, e.OffsetMS)
+	}
+}
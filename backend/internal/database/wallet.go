@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PassRegistration is an Apple Wallet device that added the pass of a
+// location, kept in wallet_registrations/{device_id}:{serial} so the device
+// can be pushed when the location's media changes.
+type PassRegistration struct {
+	DeviceID  string    `firestore:"device_id" json:"device_id"` // deviceLibraryIdentifier
+	Serial    string    `firestore:"serial" json:"serial"`       // Pass serial number, the location ID
+	PushToken string    `firestore:"push_token" json:"push_token"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
+
+func passRegistrationID(deviceID, serial string) string {
+	return deviceID + ":" + serial
+}
+
+// RegisterPass records a registration. created is false when the device
+// already had one for the pass (its push token is updated).
+func (c *Client) RegisterPass(ctx context.Context, r PassRegistration) (created bool, err error) {
+	if r.DeviceID == "" || r.Serial == "" {
+		return false, fmt.Errorf("device ID and serial are required")
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = c.clock.Now()
+	}
+	ref := c.fs.Collection("wallet_registrations").Doc(passRegistrationID(r.DeviceID, r.Serial))
+	if _, err := ref.Create(ctx, r); err == nil {
+		return true, nil
+	} else if status.Code(err) != codes.AlreadyExists {
+		return false, err
+	}
+	_, err = ref.Set(ctx, map[string]any{"push_token": r.PushToken}, firestore.MergeAll)
+	return false, err
+}
+
+// UnregisterPass removes a registration; a missing one is not an error.
+func (c *Client) UnregisterPass(ctx context.Context, deviceID, serial string) error {
+	_, err := c.fs.Collection("wallet_registrations").Doc(passRegistrationID(deviceID, serial)).Delete(ctx)
+	return err
+}
+
+// ListPassRegistrations returns the registrations of a device (field
+// "device_id") or of a pass (field "serial").
+func (c *Client) ListPassRegistrations(ctx context.Context, field, value string) ([]PassRegistration, error) {
+	if field != "device_id" && field != "serial" {
+		return nil, fmt.Errorf("can't list pass registrations by %q", field)
+	}
+	iter := c.fs.Collection("wallet_registrations").Where(field, "==", value).Documents(ctx)
+	defer iter.Stop()
+
+	var out []PassRegistration
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var r PassRegistration
+		if err := doc.DataTo(&r); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
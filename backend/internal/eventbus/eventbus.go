@@ -0,0 +1,138 @@
+// Package eventbus publishes the lifecycle of every generation
+// (events.Lifecycle) to sinks such as Pub/Sub topics, so downstream
+// consumers (analytics, notification services) follow generations without
+// calling the API. Unlike the SSE callbacks of the weather flow, events are
+// published whichever entry point generates.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/progress"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+// publishTimeout bounds each sink's Publish.
+const publishTimeout = 10 * time.Second
+
+// Sink receives lifecycle events. *PubSub implements it.
+type Sink interface {
+	Publish(ctx context.Context, e events.Lifecycle) error
+}
+
+// Bus routes lifecycle events to sinks. A nil *Bus publishes nothing.
+type Bus struct {
+	mu     sync.Mutex
+	routes []route
+	now    func() time.Time
+}
+
+type route struct {
+	name  string
+	types []string // Empty for every type
+	sink  Sink
+}
+
+// New returns an empty Bus.
+func New() *Bus {
+	return &Bus{now: time.Now}
+}
+
+// Add routes the events of types (every type when none are given) to sink.
+// name identifies it in logs.
+func (b *Bus) Add(name string, sink Sink, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.routes = append(b.routes, route{name: name, types: types, sink: sink})
+}
+
+// Publish sends e to the sinks routed its type, setting At and, when ctx
+// has one, the weather flow's ID. Sinks only observe, so their errors are
+// logged: a generation doesn't fail because analytics is down. Events are
+// published even when ctx is cancelled, so a failure caused by a client
+// disconnecting is reported too.
+func (b *Bus) Publish(ctx context.Context, e events.Lifecycle) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	routes := slices.Clone(b.routes)
+	b.mu.Unlock()
+
+	if e.At.IsZero() {
+		e.At = b.now()
+	}
+	if e.FlowID == "" {
+		e.FlowID = progress.FlowID(ctx)
+	}
+	for _, r := range routes {
+		if len(r.types) > 0 && !slices.Contains(r.types, e.Type) {
+			continue
+		}
+		pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+		if err := r.sink.Publish(pctx, e); err != nil {
+			progress.Logf(ctx, "Event sink %s failed on %s: %v", r.name, e.Type, err)
+		}
+		cancel()
+	}
+}
+
+// Open returns the bus of EVENT_TOPICS, or nil when it's empty. Each entry
+// is a Pub/Sub topic, as a full projects/<p>/topics/<t> name or a topic of
+// the GCP project, optionally prefixed with the only lifecycle types it
+// gets: "generation-events" or "video_ready,failed=notifications".
+func Open(ctx context.Context, cfg *config.Config) (*Bus, error) {
+	if len(cfg.EventTopics) == 0 {
+		return nil, nil
+	}
+	client, err := pubsubClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := New()
+	for _, entry := range cfg.EventTopics {
+		types, topic, err := parseTopic(entry, cfg.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("EVENT_TOPICS: %w", err)
+		}
+		b.Add("pubsub "+topic, newPubSub(client, topic), types...)
+	}
+	log.Printf("Publishing generation events to %d Pub/Sub topic(s)", len(cfg.EventTopics))
+	return b, nil
+}
+
+// parseTopic splits an EVENT_TOPICS entry into its types and full topic name.
+func parseTopic(entry, projectID string) ([]string, string, error) {
+	var types []string
+	topic := entry
+	if prefix, t, ok := strings.Cut(entry, "="); ok {
+		topic = t
+		for _, typ := range strings.Split(prefix, ",") {
+			typ = strings.TrimSpace(typ)
+			if !slices.Contains(events.LifecycleTypes, typ) {
+				return nil, "", fmt.Errorf("unknown event type %q (want %s)", typ, strings.Join(events.LifecycleTypes, ", "))
+			}
+			types = append(types, typ)
+		}
+	}
+	topic = strings.TrimSpace(topic)
+	switch {
+	case topic == "":
+		return nil, "", fmt.Errorf("missing topic in %q", entry)
+	case strings.HasPrefix(topic, "projects/"):
+		return types, topic, nil
+	case strings.Contains(topic, "/"):
+		return nil, "", fmt.Errorf("want a topic or projects/<project>/topics/<topic>, got %q", topic)
+	case projectID == "":
+		return nil, "", fmt.Errorf("topic %q needs GOOGLE_CLOUD_PROJECT, or a full projects/<project>/topics/<topic> name", topic)
+	}
+	return types, "projects/" + projectID + "/topics/" + topic, nil
+}
@@ -0,0 +1,130 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/progress"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+type fakeSink struct {
+	got []events.Lifecycle
+	err error
+}
+
+func (f *fakeSink) Publish(ctx context.Context, e events.Lifecycle) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.got = append(f.got, e)
+	return f.err
+}
+
+func TestBus_Publish(t *testing.T) {
+	at := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	b := New()
+	b.now = func() time.Time { return at }
+	all, notify, broken := &fakeSink{}, &fakeSink{}, &fakeSink{err: errors.New("down")}
+	b.Add("broken", broken)
+	b.Add("all", all)
+	b.Add("notify", notify, events.VideoReady, events.Failed)
+
+	// Published after the request is gone, e.g. a failure on disconnect
+	ctx, cancel := context.WithCancel(progress.WithFlowID(context.Background(), "f00d"))
+	cancel()
+	b.Publish(ctx, events.Lifecycle{Type: events.GenerationStarted, City: "Oslo"})
+	b.Publish(ctx, events.Lifecycle{Type: events.Failed, City: "Oslo", Error: "boom"})
+
+	if len(all.got) != 2 || len(broken.got) != 2 {
+		t.Fatalf("Expected every sink to get both events despite the broken one, got %v and %v", all.got, broken.got)
+	}
+	if e := all.got[0]; e.At != at || e.FlowID != "f00d" {
+		t.Errorf("Expected the time and flow ID set, got %+v", e)
+	}
+	if len(notify.got) != 1 || notify.got[0].Type != events.Failed {
+		t.Errorf("Expected only the routed type, got %v", notify.got)
+	}
+
+	var none *Bus
+	none.Publish(ctx, events.Lifecycle{Type: events.Failed}) // No-op
+}
+
+func TestParseTopic(t *testing.T) {
+	tests := []struct {
+		entry, types, topic string
+	}{
+		{"generation-events", "", "projects/demo/topics/generation-events"},
+		{"projects/other/topics/analytics", "", "projects/other/topics/analytics"},
+		{"video_ready, failed = notifications", "video_ready failed", "projects/demo/topics/notifications"},
+	}
+	for _, tt := range tests {
+		types, topic, err := parseTopic(tt.entry, "demo")
+		if err != nil || topic != tt.topic || strings.Join(types, " ") != tt.types {
+			t.Errorf("parseTopic(%q) = %v, %q, %v; want %q, %q", tt.entry, types, topic, err, tt.types, tt.topic)
+		}
+	}
+	for _, entry := range []string{"", "image_done=t", "failed=", "topics/t"} {
+		if _, _, err := parseTopic(entry, "demo"); err == nil {
+			t.Errorf("Expected %q to be rejected", entry)
+		}
+	}
+	if _, _, err := parseTopic("t", ""); err == nil {
+		t.Error("Expected a short topic rejected without a project")
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	var body struct {
+		Messages []struct {
+			Data       string            `json:"data"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"messages"`
+	}
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/demo/topics/gen:publish" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer srv.Close()
+	p := newPubSub(srv.Client(), "projects/demo/topics/gen")
+	p.url = srv.URL
+
+	e := events.Lifecycle{Type: events.ImageReady, LocationID: "oslo", City: "Oslo", ImageURL: "https://example.com/a.png"}
+	if err := p.Publish(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Messages) != 1 {
+		t.Fatalf("Expected one message, got %+v", body)
+	}
+	m := body.Messages[0]
+	if m.Attributes["type"] != events.ImageReady || m.Attributes["location_id"] != "oslo" {
+		t.Errorf("Expected type and location attributes, got %v", m.Attributes)
+	}
+	data, err := base64.StdEncoding.DecodeString(m.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got events.Lifecycle
+	if err := json.Unmarshal(data, &got); err != nil || got.ImageURL != e.ImageURL {
+		t.Errorf("Expected the event as JSON data, got %s (%v)", data, err)
+	}
+
+	status = http.StatusForbidden
+	if err := p.Publish(context.Background(), e); err == nil {
+		t.Error("Expected an error for a rejected publish")
+	}
+}
@@ -0,0 +1,77 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+	"golang.org/x/oauth2/google"
+)
+
+// PubSub publishes lifecycle events to a Pub/Sub topic through its REST API,
+// with Application Default Credentials. Each event is one message whose data
+// is the event as JSON, with "type" and "location_id" attributes.
+type PubSub struct {
+	topic  string // projects/<project>/topics/<topic>
+	client *http.Client
+	url    string
+}
+
+// NewPubSub returns a sink for topic, a full projects/<p>/topics/<t> name.
+func NewPubSub(ctx context.Context, topic string) (*PubSub, error) {
+	client, err := pubsubClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newPubSub(client, topic), nil
+}
+
+func newPubSub(client *http.Client, topic string) *PubSub {
+	return &PubSub{topic: topic, client: client, url: "https://pubsub.googleapis.com"}
+}
+
+func pubsubClient(ctx context.Context) (*http.Client, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/pubsub")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Pub/Sub credentials: %w", err)
+	}
+	return client, nil
+}
+
+// Publish sends e to the topic.
+func (p *PubSub) Publish(ctx context.Context, e events.Lifecycle) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	attrs := map[string]string{"type": e.Type}
+	if e.LocationID != "" {
+		attrs["location_id"] = e.LocationID
+	}
+	// []byte is base64-encoded, as the API wants message data
+	body, err := json.Marshal(map[string]any{
+		"messages": []map[string]any{{"data": data, "attributes": attrs}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/v1/"+p.topic+":publish", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("publish to %s returned %s: %s", p.topic, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package genai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+
+	"google.golang.org/genai"
+)
+
+// MaxAltTextLength caps alt text; screen readers read it whole.
+const MaxAltTextLength = 250
+
+const altTextPromptTemplate = `This is a stylized weather illustration of %s.
+Write alt text for it, for people using screen readers: one or two plain sentences naming the landmarks shown, the weather depicted and the main colors.
+Keep it under %d characters. Don't start with "Image of" or "Illustration of", and don't transcribe text in the image other than the city name.
+Respond in JSON.`
+
+// DescribeImage asks a cheap vision model for the alt text of a generated
+// image of city.
+func (s *Service) DescribeImage(ctx context.Context, imageBase64 string, city string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromBytes(data, "image/png"),
+		genai.NewPartFromText(fmt.Sprintf(altTextPromptTemplate, city, MaxAltTextLength)),
+	}, genai.RoleUser)}
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultTextModel, contents, &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: map[string]*genai.Schema{"alt_text": {Type: genai.TypeString}},
+			Required:   []string{"alt_text"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("genai error: %w", err)
+	}
+	if u := resp.UsageMetadata; u != nil {
+		costs.Record(ctx, database.ModelUsage{Model: DefaultTextModel, PromptTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount})
+	}
+
+	var out struct {
+		AltText string `json:"alt_text"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &out); err != nil {
+		return "", fmt.Errorf("failed to parse alt text: %w", err)
+	}
+	alt := truncateRunes(strings.Join(strings.Fields(out.AltText), " "), MaxAltTextLength)
+	if alt == "" {
+		return "", fmt.Errorf("empty alt text")
+	}
+	log.Printf("Alt text for %s: %s", city, alt)
+	return alt, nil
+}
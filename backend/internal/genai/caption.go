@@ -0,0 +1,71 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+
+	"google.golang.org/genai"
+)
+
+// MaxCaptionLength caps captions, which the frontend shows as one line
+// under the artwork.
+const MaxCaptionLength = 120
+
+const captionPromptTemplate = `Write a one-line caption for an illustration of %s.
+Give its best-known nickname (e.g. "The City of Light" for Paris) or, when it has none, a short and cheerful local fun fact. For a fictional place, draw on its fiction.
+Keep it under %d characters, with no quotes, hashtags or emoji, and don't mention the weather.
+Respond in JSON.`
+
+// Caption asks the text model for a nickname or fun fact about city, for
+// the caption shown under its artwork.
+func (s *Service) Caption(ctx context.Context, city string) (string, error) {
+	prompt := fmt.Sprintf(captionPromptTemplate, city, MaxCaptionLength)
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultTextModel, genai.Text(prompt), &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: map[string]*genai.Schema{"caption": {Type: genai.TypeString}},
+			Required:   []string{"caption"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("genai error: %w", err)
+	}
+	if u := resp.UsageMetadata; u != nil {
+		costs.Record(ctx, database.ModelUsage{Model: DefaultTextModel, PromptTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount})
+	}
+
+	var out struct {
+		Caption string `json:"caption"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &out); err != nil {
+		return "", fmt.Errorf("failed to parse caption: %w", err)
+	}
+	caption := cleanCaption(out.Caption)
+	if caption == "" {
+		return "", fmt.Errorf("empty caption")
+	}
+	log.Printf("Caption for %s: %s", city, caption)
+	return caption, nil
+}
+
+// cleanCaption keeps the first line of a caption, without surrounding
+// quotes, cut to MaxCaptionLength runes.
+func cleanCaption(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return truncateRunes(strings.Trim(strings.TrimSpace(s), `"“”`), MaxCaptionLength)
+}
+
+// truncateRunes cuts s to n runes, ending it with an ellipsis when it's cut.
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n-1])) + "…"
+	}
+	return s
+}
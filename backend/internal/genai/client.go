@@ -0,0 +1,525 @@
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	"banana-weather/internal/branding"
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/quota"
+
+	"google.golang.org/genai"
+)
+
+type Service struct {
+	client     *genai.Client
+	httpClient *http.Client // nil: Application Default Credentials
+	projectID  string
+	location   string
+	bucketName string
+	imageModel string
+	branding   *database.Branding
+	logo       []byte
+	ops        OperationStore
+	cache      ImageCache
+	transport  string
+	videoOut   string // gs:// prefix Veo writes to
+	quota      *quota.Manager
+}
+
+// ImageCache lets GenerateImage reuse images for identical rendered prompts.
+type ImageCache interface {
+	Key(model, prompt string, seed *int32) string
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Put(ctx context.Context, key string, data []byte)
+}
+
+// SetQuota limits image and Veo requests per model, queuing or rejecting
+// those beyond the limits.
+func (s *Service) SetQuota(q *quota.Manager) {
+	s.quota = q
+}
+
+// SetImageCache enables prompt-hash caching of generated images.
+func (s *Service) SetImageCache(c ImageCache) {
+	s.cache = c
+}
+
+func NewService(ctx context.Context, projectID, location, bucketName, imageModel string) (*Service, error) {
+	return NewServiceWithHTTPClient(ctx, projectID, location, bucketName, imageModel, nil)
+}
+
+// NewServiceWithHTTPClient is NewService sending every Vertex AI call, SDK
+// and REST, through hc, which then adds credentials itself. Tests pass a
+// Recorder or Replayer; nil uses Application Default Credentials.
+func NewServiceWithHTTPClient(ctx context.Context, projectID, location, bucketName, imageModel string, hc *http.Client) (*Service, error) {
+	log.Printf("Initializing GenAI Service. Project: %s, Location: %s, Bucket: %s", projectID, location, bucketName)
+
+	// Initialize GenAI Client
+	c, err := genai.NewClient(ctx, &genai.ClientConfig{
+		Backend:    genai.BackendVertexAI,
+		Project:    projectID,
+		Location:   location,
+		HTTPClient: hc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{client: c, httpClient: hc, projectID: projectID, location: location, bucketName: bucketName, imageModel: imageModel}, nil
+}
+
+// SetVideoOutput sets the gs:// prefix Veo writes videos to. The default is
+// videos/ in the bucket passed to NewService.
+func (s *Service) SetVideoOutput(gsURI string) {
+	s.videoOut = gsURI
+}
+
+func (s *Service) videoOutput() string {
+	if s.videoOut != "" {
+		return s.videoOut
+	}
+	return fmt.Sprintf("gs://%s/videos/", s.bucketName)
+}
+
+// SetBranding applies a brand palette/prompt suffix to every prompt and, if
+// logo is set, watermarks every generated image with it.
+func (s *Service) SetBranding(b *database.Branding, logo []byte) {
+	s.branding = b
+	s.logo = logo
+}
+
+// RenderPrompt returns the exact prompt GenerateImage sends, branding included.
+func (s *Service) RenderPrompt(city string, extraContext string, promptMode int, seed *int32) string {
+	return branding.ApplyPrompt(BuildPrompt(city, extraContext, promptMode, seed), s.branding)
+}
+
+// NewSeed returns a random seed suitable for GenerateImage/GenerateVideo.
+// Callers persist it so a good generation can be reproduced later.
+func NewSeed() int32 {
+	return rand.Int32N(math.MaxInt32)
+}
+
+type randomSeedKey struct{}
+
+// WithRandomSeed returns a context whose image generations' seeds were
+// picked at random rather than asked for. The prompt cache ignores random
+// seeds, so the same prompt (city, weather and style) shares one image
+// within the hour; an asked-for seed gets its own.
+func WithRandomSeed(ctx context.Context, random bool) context.Context {
+	return context.WithValue(ctx, randomSeedKey{}, random)
+}
+
+// randomSeed reports whether ctx's seeds were picked at random.
+func randomSeed(ctx context.Context) bool {
+	random, _ := ctx.Value(randomSeedKey{}).(bool)
+	return random
+}
+
+// a clever prompt inspired by @dotey https://x.com/dotey/status/1993729800922341810?s=20
+const basePromptTemplate = `Present a clear, 45° top-down view of a vertical (9:16) isometric miniature 3D cartoon scene, highlighting iconic landmarks centered in the composition to showcase precise and delicate modeling.
+
+The scene features soft, refined textures with realistic PBR materials and gentle, lifelike lighting and shadow effects. Weather elements are creatively integrated into the urban architecture, establishing a dynamic interaction between the city's landscape and atmospheric conditions, creating an immersive weather ambiance.
+
+Use a clean, unified composition with minimalistic aesthetics and a soft, solid-colored background that highlights the main content. The overall visual style is fresh and soothing.
+
+Display a prominent weather icon at the top-center, with the date (x-small text) and temperature range (medium text) beneath it. The city name (large text) is positioned directly above the weather icon. The weather information has no background and can subtly overlap with the buildings.
+
+The text should match the input city's native language.
+Please retrieve current weather conditions for the specified city before rendering.`
+
+const secondaryPromptTemplate = `Present a clear, 45° top-down view of a vertical (9:16) isometric miniature 3D cartoon scene, highlighting iconic landmarks centered in the composition to showcase precise and delicate modeling. 
+
+A close-up of a porcelain [DRINK] cup filled with [DRINK], subtly floating a detailed city of [CITY] occupying most of the composition. Prominently displayed at the scene's center are the city's most iconic landmarks, vividly detailed and illuminated softly. 
+
+Miniature streets feature realistic, tiny vehicles moving seamlessly. With cinematic-quality lighting and depth-of-field blurring, the image creates a magical, dreamlike atmosphere. Exceptionally detailed and highly photorealistic, the scene achieves an 8K cinematic finish. 
+
+Display a prominent weather icon at the top-center, with the date (x-small text) and temperature range (medium text) beneath it. The city name (large text) is positioned directly above the weather icon. The weather information has no background and can subtly overlap with the buildings. The text should match the input city's native language. Please retrieve current weather conditions for the specified city before rendering.`
+
+// PromptVersion identifies the image prompt templates: the first 12 hex
+// digits of their SHA-256. It's recorded with each generation, so a receipt
+// replayed after the prompts changed can tell.
+var PromptVersion = func() string {
+	sum := sha256.Sum256([]byte(basePromptTemplate + "\x00" + secondaryPromptTemplate))
+	return hex.EncodeToString(sum[:])[:12]
+}()
+
+// BuildPrompt renders the image prompt for a city.
+// promptMode: 0=Random, 1=Classic, 2=Drink
+// With a seed, Random mode is deterministic so the rendered prompt matches
+// what GenerateImage will send for the same arguments.
+func BuildPrompt(city string, extraContext string, promptMode int, seed *int32) string {
+	var useSecondary bool
+	switch promptMode {
+	case 1: // Force Classic
+		useSecondary = false
+	case 2: // Force Drink
+		useSecondary = true
+	default: // Random (0 or other)
+		if seed != nil {
+			useSecondary = ResolveStyle(promptMode, seed) == 2
+		} else {
+			useSecondary = rand.IntN(2) == 1
+		}
+	}
+
+	var prompt string
+	if !useSecondary {
+		// Use Base Prompt
+		log.Printf("Selected Base Prompt for %s (Mode: %d)", city, promptMode)
+		prompt = fmt.Sprintf("%s\n\nCity name: %s", basePromptTemplate, city)
+	} else {
+		// Use Secondary Prompt
+		log.Printf("Selected Secondary (Drink) Prompt for %s (Mode: %d)", city, promptMode)
+		// Fill [CITY] placeholder
+		p := strings.Replace(secondaryPromptTemplate, "[CITY]", city, -1)
+		// Instruct model to resolve [DRINK]
+		prompt = fmt.Sprintf("%s\n\nDRINK: the most common AM drink for this location", p)
+	}
+
+	if extraContext != "" {
+		prompt += fmt.Sprintf("\n\nContext/Setting: %s", extraContext)
+	}
+
+	return prompt
+}
+
+// GenerateImage generates a 9:16 image for the given city.
+// promptMode: 0=Random, 1=Classic, 2=Drink
+// seed: optional. When set, the model is asked for deterministic output and
+// Random mode picks the prompt from the seed instead of rolling the dice.
+func (s *Service) GenerateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32) (*ImageResult, error) {
+	return s.generateImage(ctx, city, extraContext, promptMode, seed, ImageOptions{})
+}
+
+// Reference is a user-provided photo that conditions image generation.
+type Reference struct {
+	Data     []byte
+	MIMEType string
+}
+
+// DefaultAspect is the aspect ratio of generated images; Veo animates it as is.
+const DefaultAspect = "9:16"
+
+// ImageOptions are the less common GenerateImageWith settings.
+type ImageOptions struct {
+	Aspect    string        // e.g. "1:1", "16:9"; DefaultAspect when empty
+	Reference *Reference    // Optional photo to condition on, see GenerateImageWithReference
+	Grounding GroundingMode // The context's mode (WithGrounding) when empty, else GroundingSearch
+}
+
+const referencePrompt = `A visitor's photo of this location is attached. Use it as a reference for the landmarks, architecture and colors in the scene, but keep the style described above. Don't reproduce people, faces, license plates or text from the photo.`
+
+// GenerateImageWithReference is GenerateImage conditioned on a reference
+// photo. Results aren't read from or written to the prompt cache, since the
+// cache key doesn't cover the photo.
+func (s *Service) GenerateImageWithReference(ctx context.Context, city string, extraContext string, promptMode int, seed *int32, ref *Reference) (*ImageResult, error) {
+	return s.generateImage(ctx, city, extraContext, promptMode, seed, ImageOptions{Reference: ref})
+}
+
+// GenerateImageWith is GenerateImage with an aspect ratio and/or reference photo.
+func (s *Service) GenerateImageWith(ctx context.Context, city string, extraContext string, promptMode int, seed *int32, opts ImageOptions) (*ImageResult, error) {
+	return s.generateImage(ctx, city, extraContext, promptMode, seed, opts)
+}
+
+func (s *Service) generateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32, opts ImageOptions) (*ImageResult, error) {
+	if opts.Aspect == "" {
+		opts.Aspect = DefaultAspect
+	}
+	if opts.Grounding == "" {
+		opts.Grounding, _ = GroundingFrom(ctx)
+	}
+	if opts.Grounding == "" {
+		opts.Grounding = GroundingSearch
+	}
+	lang := LanguageFrom(ctx)
+	prompt := groundPrompt(languagePrompt(s.RenderPrompt(city, extraContext, promptMode, seed), lang), opts.Grounding, WeatherDataFrom(ctx))
+	if opts.Reference != nil {
+		prompt += "\n\n" + referencePrompt
+	}
+	res, err := s.renderImage(ctx, city, prompt, seed, opts)
+	if res != nil {
+		res.Lang = lang
+		res.Style = StyleName(ResolveStyle(promptMode, seed))
+		res.PromptVersion = PromptVersion
+	}
+	return res, err
+}
+
+// renderImage sends a rendered prompt to the image model, with opts already
+// resolved. subject names the image in logs.
+func (s *Service) renderImage(ctx context.Context, subject, prompt string, seed *int32, opts ImageOptions) (*ImageResult, error) {
+	model := s.imageModel
+	if model == "" {
+		model = "gemini-3.1-flash-image-preview"
+	}
+
+	if seed != nil {
+		log.Printf("Generating image for %s using model: %s (GenerateContent, Seed: %d)", subject, model, *seed)
+	} else {
+		log.Printf("Generating image for %s using model: %s (GenerateContent)", subject, model)
+	}
+
+	cache := s.cache
+	if opts.Reference != nil || opts.Aspect != DefaultAspect || opts.Grounding == GroundingRequired {
+		cache = nil // The key covers only model, seed and prompt, not whether the image was grounded
+	}
+	var cacheKey string
+	if cache != nil {
+		keySeed := seed
+		if randomSeed(ctx) {
+			keySeed = nil
+		}
+		cacheKey = cache.Key(model, prompt, keySeed)
+		if data, ok := cache.Get(ctx, cacheKey); ok {
+			log.Printf("Prompt cache hit for %s (%s)", subject, cacheKey[:12])
+			return &ImageResult{Images: []string{s.finishImage(data)}, Original: s.original(data), Model: model, GroundingMode: opts.Grounding, Cached: true}, nil
+		}
+	}
+
+	release, err := s.quota.Acquire(ctx, quota.KindImage, model)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var raw *rawImageResult
+	if s.transport == TransportREST {
+		raw, err = s.generateImageREST(ctx, model, prompt, seed, opts)
+	} else {
+		raw, err = s.generateImageSDK(ctx, model, prompt, seed, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Billed even if the image is rejected below
+	costs.Record(ctx, database.ModelUsage{Model: model, PromptTokens: raw.usage.Prompt, OutputTokens: raw.usage.Output})
+	if len(raw.images) == 0 {
+		log.Printf("No inline image data found in response. Model said: %q", strings.Join(raw.text, " "))
+		return nil, fmt.Errorf("no image data found in response")
+	}
+	if opts.Grounding == GroundingRequired && !raw.grounding.grounded() {
+		log.Printf("Image for %s wasn't grounded; rejecting it", subject)
+		return nil, ErrUngrounded
+	}
+
+	log.Printf("Image generated successfully. Images: %d, Bytes: %d, Tokens: %d", len(raw.images), len(raw.images[0]), raw.usage.Total)
+	if cache != nil {
+		cache.Put(ctx, cacheKey, raw.images[0])
+	}
+
+	result := &ImageResult{
+		Text:          strings.Join(raw.text, "\n\n"),
+		Grounding:     raw.grounding,
+		GroundingMode: opts.Grounding,
+		Usage:         raw.usage,
+		Model:         model,
+	}
+	for _, data := range raw.images {
+		result.Images = append(result.Images, s.finishImage(data))
+	}
+	result.Original = s.original(raw.images[0])
+	if result.Text != "" {
+		log.Printf("Model commentary for %s: %s", subject, result.Text)
+	}
+	return result, nil
+}
+
+// generateImageSDK calls GenerateContent through the genai SDK.
+func (s *Service) generateImageSDK(ctx context.Context, model, prompt string, seed *int32, opts ImageOptions) (*rawImageResult, error) {
+	contents := genai.Text(prompt)
+	if ref := opts.Reference; ref != nil {
+		contents = []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromBytes(ref.Data, ref.MIMEType),
+			genai.NewPartFromText(prompt),
+		}, genai.RoleUser)}
+	}
+	config := &genai.GenerateContentConfig{
+		ResponseModalities: []string{"IMAGE"},
+		ImageConfig: &genai.ImageConfig{
+			AspectRatio: opts.Aspect,
+		},
+		Seed: seed,
+	}
+	if opts.Grounding != GroundingOff {
+		config.Tools = []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}}
+	}
+	resp, err := s.client.Models.GenerateContent(ctx, model, contents, config)
+	if err != nil {
+		log.Printf("GenAI GenerateContent failed: %v", err)
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		log.Printf("GenAI returned no candidates or parts")
+		return nil, fmt.Errorf("no content generated")
+	}
+	return parseSDKResponse(resp), nil
+}
+
+// finishImage applies post-processing (watermark, AI badge) and base64-encodes the image.
+// The cache stores raw model output so branding changes apply immediately.
+func (s *Service) finishImage(data []byte) string {
+	if len(s.logo) > 0 {
+		marked, err := branding.Watermark(data, s.logo, s.branding)
+		if err != nil {
+			// Better an unbranded image than none at all.
+			log.Printf("Watermarking failed, returning original: %v", err)
+		} else {
+			data = marked
+		}
+	}
+	if s.branding != nil && s.branding.AIBadge {
+		badged, err := branding.Badge(data)
+		if err != nil {
+			log.Printf("AI badge failed, returning image without it: %v", err)
+		} else {
+			data = badged
+		}
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// original returns the base64 model output when finishImage would change it,
+// so callers can retain the unmarked asset; "" otherwise.
+func (s *Service) original(data []byte) string {
+	if len(s.logo) == 0 && (s.branding == nil || !s.branding.AIBadge) {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// VideoSeconds is the length of the clips GenerateVideo asks Veo for, which
+// is what Veo bills.
+const VideoSeconds int32 = 8
+
+// VideoModel is the Veo model GenerateVideo uses.
+const VideoModel = "veo-3.1-lite-generate-001"
+
+const DefaultVideoPrompt = "The camera moves in parallax as the elements in the image move naturally, while the forecast data—the bold title—remains fixed."
+
+// GenerateVideo generates a 9:16 video using Veo 3.1 Fast.
+// seed is optional; when set, Veo is asked for deterministic output.
+// Returns: GS URI (string) or error.
+func (s *Service) GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error) {
+	model := VideoModel
+	
+	if prompt == "" {
+		prompt = DefaultVideoPrompt
+	}
+
+	// Veo reads its input from and writes its output to GCS, so images in an
+	// S3 bucket (STORAGE_BACKEND=s3) can't be animated.
+	if !strings.HasPrefix(inputImageURI, "gs://") {
+		return "", fmt.Errorf("veo requires a gs:// input image, got %s", inputImageURI)
+	}
+
+	// Held while polling, since the operation occupies Veo capacity until done.
+	release, err := s.quota.Acquire(ctx, quota.KindVideo, model)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	log.Printf("Generating video with model %s. Input: %s", model, inputImageURI)
+
+	if s.transport == TransportREST {
+		return s.generateVideoREST(ctx, model, prompt, inputImageURI, seed)
+	}
+	return s.generateVideoSDK(ctx, model, prompt, inputImageURI, seed)
+}
+
+// generateVideoSDK starts and polls a Veo operation through the genai SDK.
+func (s *Service) generateVideoSDK(ctx context.Context, model, prompt, inputImageURI string, seed *int32) (string, error) {
+	// Construct the image object
+	image := &genai.Image{
+		GCSURI: inputImageURI,
+		MIMEType: "image/png",
+	}
+
+	// Config
+	config := &genai.GenerateVideosConfig{
+		AspectRatio: "9:16",
+		DurationSeconds: ptr(VideoSeconds),
+		OutputGCSURI: s.videoOutput(),
+		Seed: seed,
+	}
+
+	// Call GenerateVideos
+	resp, err := s.client.Models.GenerateVideos(ctx, model, prompt, image, config)
+	if err != nil {
+		log.Printf("GenAI GenerateVideos failed: %v", err)
+		return "", fmt.Errorf("veo error: %w", err)
+	}
+
+	progress.Logf(ctx, "Veo operation started. ID: %s", resp.Name)
+	s.trackOperation(ctx, resp.Name, model, inputImageURI)
+	defer s.clearOperation(resp.Name)
+
+	return s.waitForVideo(ctx, model, func(ctx context.Context) (string, map[string]any, bool, error) {
+		// Use native SDK polling
+		op, err := s.client.Operations.GetVideosOperation(ctx, resp, nil)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if !op.Done {
+			return "", op.Metadata, false, nil
+		}
+		if op.Error != nil {
+			return "", nil, true, fmt.Errorf("operation failed: %v", op.Error)
+		}
+		uri, err := sdkVideoURI(op.Response)
+		return uri, nil, true, err
+	})
+}
+
+// sdkVideoURI returns the GCS URI of the first video of a finished operation.
+func sdkVideoURI(resp *genai.GenerateVideosResponse) (string, error) {
+	if resp == nil || len(resp.GeneratedVideos) == 0 {
+		return "", fmt.Errorf("operation done but no videos found")
+	}
+
+	v := resp.GeneratedVideos[0]
+	
+	// Hack: Marshal/Unmarshal to bypass unknown struct field name
+	// The SDK is alpha and field names vary (GcsUri vs VideoUri vs Uri).
+	b, _ := json.Marshal(v)
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	
+	// Top level check
+	uri, _ := m["gcsUri"].(string)
+	if uri == "" { uri, _ = m["videoUri"].(string) }
+	if uri == "" { uri, _ = m["uri"].(string) }
+
+	// Nested check (video.uri) - This matches the logs!
+	if uri == "" {
+		if vid, ok := m["video"].(map[string]interface{}); ok {
+			uri, _ = vid["uri"].(string)
+			if uri == "" { uri, _ = vid["gcsUri"].(string) }
+			if uri == "" { uri, _ = vid["videoUri"].(string) }
+		}
+	}
+
+	if uri != "" {
+		log.Printf("Video generated (GCS URI): %s", uri)
+		return uri, nil
+	}
+
+	return "", fmt.Errorf("video generated but URI is empty (JSON: %s)", string(b))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
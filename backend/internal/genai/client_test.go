@@ -0,0 +1,81 @@
+package genai
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+func BenchmarkBuildPrompt(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	seed := int32(42)
+	for b.Loop() {
+		BuildPrompt("Fort Collins, CO, USA", "Harvest festival weekend", 0, &seed)
+	}
+}
+
+func BenchmarkRenderPrompt_Branded(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	s := &Service{branding: &database.Branding{
+		Palette:      []string{"#FFD400", "#1A1A1A", "#FFFFFF"},
+		PromptSuffix: "in the style of a travel poster",
+	}}
+	seed := int32(7)
+	for b.Loop() {
+		s.RenderPrompt("Kyoto, Japan", "", 2, &seed)
+	}
+}
+
+func TestBuildCoverPrompt(t *testing.T) {
+	cities := []string{"Paris", "Rome", "Berlin", "Madrid", "Vienna", "Prague", "Lisbon"}
+	p := BuildCoverPrompt("Europe", cities)
+	if !strings.Contains(p, `"Europe"`) || !strings.Contains(p, "Paris; Rome") {
+		t.Errorf("prompt = %q", p)
+	}
+	if strings.Contains(p, "Lisbon") {
+		t.Errorf("prompt names more than %d cities: %q", MaxCoverCities, p)
+	}
+}
+
+func TestCleanCaption(t *testing.T) {
+	long := strings.Repeat("a", MaxCaptionLength+10)
+	tests := map[string]string{
+		"The City of Light":             "The City of Light",
+		"  \"The Big Apple\"  \nSecond": "The Big Apple",
+		"“Venice of the North”":         "Venice of the North",
+		"":                              "",
+	}
+	for in, want := range tests {
+		if got := cleanCaption(in); got != want {
+			t.Errorf("cleanCaption(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := []rune(cleanCaption(long)); len(got) != MaxCaptionLength || got[len(got)-1] != '…' {
+		t.Errorf("long caption = %q", string(got))
+	}
+}
+
+func TestWAVFromPCM(t *testing.T) {
+	if got := pcmRate("audio/L16;codec=pcm;rate=16000"); got != 16000 {
+		t.Errorf("pcmRate = %d, want 16000", got)
+	}
+	if got := pcmRate("audio/L16"); got != narrationSampleRate {
+		t.Errorf("pcmRate without rate = %d, want %d", got, narrationSampleRate)
+	}
+
+	wav := wavFromPCM([]byte{1, 2, 3, 4}, 24000)
+	if len(wav) != 48 || string(wav[:4]) != "RIFF" || string(wav[8:16]) != "WAVEfmt " || string(wav[36:40]) != "data" {
+		t.Fatalf("bad header: % x", wav)
+	}
+	le := binary.LittleEndian
+	if le.Uint32(wav[4:]) != 40 || le.Uint32(wav[24:]) != 24000 || le.Uint32(wav[28:]) != 48000 || le.Uint32(wav[40:]) != 4 {
+		t.Errorf("bad sizes or rates: % x", wav)
+	}
+}
@@ -0,0 +1,43 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CoverAspect is the aspect ratio of category covers, which the frontend
+// shows as section headers.
+const CoverAspect = "16:9"
+
+// MaxCoverCities is how many cities a cover prompt names at most; the model
+// crowds the scene beyond that.
+const MaxCoverCities = 6
+
+const coverPromptTemplate = `Present a clear, 45° top-down view of a wide (16:9) isometric miniature 3D cartoon collage, a panoramic banner joining the iconic landmarks of these cities side by side into one continuous miniature landscape: %s.
+
+The scene features soft, refined textures with realistic PBR materials and gentle, lifelike lighting and shadow effects. Each city keeps its own character, blending into its neighbors with mild, pleasant weather.
+
+Use a clean, unified composition with minimalistic aesthetics and a soft, solid-colored background that highlights the main content. The overall visual style is fresh and soothing.
+
+Display the title "%s" (large text) at the top-left, with no background. Don't add city names or any other text.`
+
+// BuildCoverPrompt renders the cover prompt of a category from its member
+// cities, of which the first MaxCoverCities are used.
+func BuildCoverPrompt(category string, cities []string) string {
+	if len(cities) > MaxCoverCities {
+		cities = cities[:MaxCoverCities]
+	}
+	return fmt.Sprintf(coverPromptTemplate, strings.Join(cities, "; "), category)
+}
+
+// GenerateCover generates a CoverAspect collage for a category of presets.
+// The model doesn't search, since covers show no current weather, and
+// covers aren't cached.
+func (s *Service) GenerateCover(ctx context.Context, category string, cities []string, seed *int32) (*ImageResult, error) {
+	if len(cities) == 0 {
+		return nil, fmt.Errorf("category %q has no cities", category)
+	}
+	opts := ImageOptions{Aspect: CoverAspect, Grounding: GroundingOff}
+	return s.renderImage(ctx, "category "+category, BuildCoverPrompt(category, cities), seed, opts)
+}
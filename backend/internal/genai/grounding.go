@@ -0,0 +1,95 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GroundingMode controls whether GenerateImage gives the model the
+// GoogleSearch tool to look up the weather.
+type GroundingMode string
+
+const (
+	GroundingSearch   GroundingMode = "search"   // The model may search (default)
+	GroundingOff      GroundingMode = "off"      // No search; the prompt relies on weather in the context
+	GroundingRequired GroundingMode = "required" // The model must search; ungrounded images fail
+)
+
+// GroundingModes lists every mode, for validation and help text.
+var GroundingModes = []GroundingMode{GroundingSearch, GroundingOff, GroundingRequired}
+
+// ParseGroundingMode reads a mode name; "" is GroundingSearch.
+func ParseGroundingMode(s string) (GroundingMode, error) {
+	if s == "" {
+		return GroundingSearch, nil
+	}
+	for _, m := range GroundingModes {
+		if GroundingMode(s) == m {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown grounding mode %q (use search, off or required)", s)
+}
+
+// ErrUngrounded is returned in GroundingRequired mode when the model
+// answered without searching.
+var ErrUngrounded = errors.New("image isn't grounded in Google Search results")
+
+type groundingKey struct{}
+
+// WithGrounding returns a context whose image generations use mode m.
+func WithGrounding(ctx context.Context, m GroundingMode) context.Context {
+	return context.WithValue(ctx, groundingKey{}, m)
+}
+
+// GroundingFrom returns the mode of ctx, if it has one.
+func GroundingFrom(ctx context.Context) (GroundingMode, bool) {
+	m, ok := ctx.Value(groundingKey{}).(GroundingMode)
+	return m, ok
+}
+
+type weatherDataKey struct{}
+
+// WithWeatherData returns a context whose image prompts carry the observed
+// weather in their context, so the model is told to depict it rather than
+// look the weather up (except in GroundingRequired mode, which must search).
+func WithWeatherData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, weatherDataKey{}, true)
+}
+
+// WeatherDataFrom reports whether ctx was set up by WithWeatherData.
+func WeatherDataFrom(ctx context.Context) bool {
+	data, _ := ctx.Value(weatherDataKey{}).(bool)
+	return data
+}
+
+// searchInstruction ends both prompt templates.
+const searchInstruction = "Please retrieve current weather conditions for the specified city before rendering."
+
+// offlineInstruction replaces searchInstruction when the model can't search.
+const offlineInstruction = "Depict the current weather given in the context below; without one, depict weather typical for the city at this time of year."
+
+// dataInstruction replaces searchInstruction when the context carries the
+// observed weather (see WithWeatherData).
+const dataInstruction = "Depict the current weather and temperatures given in the context below; they are observed data, so don't contradict them."
+
+// groundPrompt adapts a rendered prompt to mode m, and to observed weather in
+// its context when data is set.
+func groundPrompt(prompt string, m GroundingMode, data bool) string {
+	switch {
+	case m == GroundingRequired:
+		return prompt
+	case data:
+		return strings.Replace(prompt, searchInstruction, dataInstruction, 1)
+	case m == GroundingOff:
+		return strings.Replace(prompt, searchInstruction, offlineInstruction, 1)
+	}
+	return prompt
+}
+
+// grounded reports whether the model used the GoogleSearch tool.
+func (g Grounding) grounded() bool {
+	return len(g.SearchQueries) > 0 || len(g.Sources) > 0
+}
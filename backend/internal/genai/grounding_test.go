@@ -0,0 +1,105 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseGroundingMode(t *testing.T) {
+	for in, want := range map[string]GroundingMode{"": GroundingSearch, "search": GroundingSearch, "off": GroundingOff, "required": GroundingRequired} {
+		if got, err := ParseGroundingMode(in); err != nil || got != want {
+			t.Errorf("ParseGroundingMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseGroundingMode("maybe"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestGroundPrompt(t *testing.T) {
+	seed := int32(1)
+	for _, style := range []int{1, 2} {
+		prompt := BuildPrompt("Lisbon", "", style, &seed)
+		if !strings.Contains(prompt, searchInstruction) {
+			t.Fatalf("style %d: prompt doesn't ask the model to search", style)
+		}
+		if got := groundPrompt(prompt, GroundingSearch, false); got != prompt {
+			t.Errorf("style %d: search mode changed the prompt", style)
+		}
+		off := groundPrompt(prompt, GroundingOff, false)
+		if strings.Contains(off, searchInstruction) || !strings.Contains(off, offlineInstruction) {
+			t.Errorf("style %d: off mode prompt = %q", style, off)
+		}
+		for _, m := range []GroundingMode{GroundingSearch, GroundingOff} {
+			if data := groundPrompt(prompt, m, true); strings.Contains(data, searchInstruction) || !strings.Contains(data, dataInstruction) {
+				t.Errorf("style %d: %s mode prompt with weather data = %q", style, m, data)
+			}
+		}
+		if got := groundPrompt(prompt, GroundingRequired, true); got != prompt {
+			t.Errorf("style %d: required mode changed the prompt", style)
+		}
+	}
+}
+
+// ungroundedCassette answers one generateContent call with an image and no
+// grounding metadata, on either transport.
+func ungroundedCassette(transport string) *Cassette {
+	version := "v1beta1"
+	if transport == TransportREST {
+		version = "v1"
+	}
+	return &Cassette{Interactions: []Interaction{{
+		Method:   http.MethodPost,
+		Path:     "/" + version + "/projects/test-project/locations/us-central1/publishers/google/models/gemini-3.1-flash-image-preview:generateContent",
+		Status:   http.StatusOK,
+		Response: json.RawMessage(`{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/png","data":"` + placeholderPNG + `"}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":300,"candidatesTokenCount":1290,"totalTokenCount":1590}}`),
+	}}}
+}
+
+func TestGenerateImage_GroundingModes(t *testing.T) {
+	for _, transport := range []string{TransportSDK, TransportREST} {
+		for _, mode := range GroundingModes {
+			t.Run(transport+"/"+string(mode), func(t *testing.T) {
+				p := NewReplayer(ungroundedCassette(transport))
+				s, err := NewServiceWithHTTPClient(context.Background(), vcrProject, vcrLocation, vcrBucket, "", &http.Client{Transport: p})
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.SetTransport(transport)
+				res, err := s.GenerateImage(WithGrounding(context.Background(), mode), "Lisbon", "", 1, nil)
+
+				if mode == GroundingRequired {
+					if !errors.Is(err, ErrUngrounded) {
+						t.Errorf("err = %v, want ErrUngrounded", err)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				} else if res.GroundingMode != mode || res.Metadata().GroundingMode != string(mode) {
+					t.Errorf("recorded mode = %q, want %q", res.GroundingMode, mode)
+				}
+
+				var req struct {
+					Tools    []json.RawMessage `json:"tools"`
+					Contents []struct {
+						Parts []struct {
+							Text string `json:"text"`
+						} `json:"parts"`
+					} `json:"contents"`
+				}
+				if err := json.Unmarshal(p.Received()[0].Request, &req); err != nil {
+					t.Fatal(err)
+				}
+				if search := len(req.Tools) > 0; search != (mode != GroundingOff) {
+					t.Errorf("sent tools %s", req.Tools)
+				}
+				if offline := strings.Contains(req.Contents[0].Parts[0].Text, offlineInstruction); offline != (mode == GroundingOff) {
+					t.Errorf("prompt offline instruction = %v", offline)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nativeLanguageInstruction is in both prompt templates; languagePrompt
+// replaces it when a language is requested.
+const nativeLanguageInstruction = "The text should match the input city's native language."
+
+// languageTag matches the BCP 47 tags accepted for the image text: a
+// language, then optional script, region or variant subtags ("ja", "pt-BR",
+// "zh-Hant-TW").
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,3}$`)
+
+// ParseLanguage validates a language tag and returns it in canonical case:
+// lowercase language, titlecase script, uppercase region ("pt-BR"). Empty is
+// the city's native language.
+func ParseLanguage(tag string) (string, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return "", nil
+	}
+	if !languageTag.MatchString(tag) {
+		return "", fmt.Errorf("invalid language tag %q (use e.g. ja or pt-BR)", tag)
+	}
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i, p := range parts[1:] {
+		switch len(p) {
+		case 2:
+			parts[i+1] = strings.ToUpper(p)
+		case 4:
+			parts[i+1] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i+1] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a context whose image generations render their text
+// in lang, a tag from ParseLanguage; "" keeps the city's native language.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFrom returns the language of ctx, or "" for the city's native
+// language.
+func LanguageFrom(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// languagePrompt adapts a rendered prompt to render its text in lang.
+func languagePrompt(prompt, lang string) string {
+	if lang == "" {
+		return prompt
+	}
+	return strings.Replace(prompt, nativeLanguageInstruction,
+		fmt.Sprintf("The text should be in the language with BCP 47 tag %q, with the city name as it's written in that language.", lang), 1)
+}
@@ -0,0 +1,36 @@
+package genai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	for in, want := range map[string]string{"": "", "JA": "ja", " pt_br ": "pt-BR", "zh-hant-tw": "zh-Hant-TW", "es-419": "es-419"} {
+		if got, err := ParseLanguage(in); err != nil || got != want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"*", "j", "en-", "en US", "<script>"} {
+		if _, err := ParseLanguage(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestLanguagePrompt(t *testing.T) {
+	for _, style := range []int{1, 2} {
+		prompt := (&Service{}).RenderPrompt("Lisbon, Portugal", "", style, nil)
+		if languagePrompt(prompt, "") != prompt {
+			t.Errorf("Style %d: expected the native-language prompt unchanged", style)
+		}
+		got := languagePrompt(prompt, "ja")
+		if strings.Contains(got, nativeLanguageInstruction) || !strings.Contains(got, `BCP 47 tag "ja"`) {
+			t.Errorf("Style %d: expected the language instruction replaced, got %q", style, got)
+		}
+	}
+	if LanguageFrom(context.Background()) != "" || LanguageFrom(WithLanguage(context.Background(), "ja")) != "ja" {
+		t.Error("Expected the language to round-trip through the context")
+	}
+}
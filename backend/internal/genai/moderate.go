@@ -0,0 +1,70 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Moderation categories returned by ModerateImage. Anything but
+// ModerationOK means the photo must not be used.
+const (
+	ModerationOK        = "ok"
+	ModerationPeople    = "people"      // Identifiable people or faces are the subject
+	ModerationPersonal  = "personal"    // Documents, screens, plates, addresses
+	ModerationUnsafe    = "unsafe"      // Sexual, violent, hateful or otherwise unsafe content
+	ModerationNotAPlace = "not_a_place" // Not a photo of a street, building, landscape or landmark
+)
+
+var moderationCategories = []string{ModerationOK, ModerationPeople, ModerationPersonal, ModerationUnsafe, ModerationNotAPlace}
+
+const moderatePrompt = `A user uploaded this photo as a reference for an illustration of their city.
+Classify it:
+- "ok": a street, building, landscape or landmark, safe for a public gallery
+- "people": identifiable people or faces are the main subject
+- "personal": shows documents, screens, license plates, house numbers or other personal information
+- "unsafe": sexual, violent, hateful, or otherwise unsafe content
+- "not_a_place": anything else
+Respond in JSON.`
+
+// Moderation is the outcome of ModerateImage.
+type Moderation struct {
+	Allowed  bool   `json:"allowed"`
+	Category string `json:"category"`
+	Reason   string `json:"reason"`
+}
+
+// ModerateImage asks a cheap vision model whether a user-provided photo is
+// fit to be used as a reference image.
+func (s *Service) ModerateImage(ctx context.Context, data []byte, mimeType string) (*Moderation, error) {
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromBytes(data, mimeType),
+		genai.NewPartFromText(moderatePrompt),
+	}, genai.RoleUser)}
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultTextModel, contents, &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"category": {Type: genai.TypeString, Enum: moderationCategories},
+				"reason":   {Type: genai.TypeString},
+			},
+			Required: []string{"category", "reason"},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+
+	var m Moderation
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &m); err != nil {
+		return nil, fmt.Errorf("failed to parse moderation: %w", err)
+	}
+	m.Allowed = m.Category == ModerationOK
+	log.Printf("Reference moderation: %s (%s)", m.Category, m.Reason)
+	return &m, nil
+}
@@ -0,0 +1,136 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"strconv"
+	"strings"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+
+	"google.golang.org/genai"
+)
+
+// DefaultSpeechModel reads forecasts aloud for narration clips.
+const DefaultSpeechModel = "gemini-2.5-flash-preview-tts"
+
+// NarrationVoice is the prebuilt voice of narration clips.
+const NarrationVoice = "Kore"
+
+// narrationSampleRate is assumed when the audio MIME type has no rate; it's
+// what the speech models return.
+const narrationSampleRate = 24000
+
+const narrationTranslatePromptTemplate = `Translate this short spoken weather forecast into the language with BCP-47 code %q.
+Use the conventional local name of the place, write numbers the way a native speaker reads them aloud, and keep it to one sentence.
+Respond in JSON.
+
+%s`
+
+const narrationPromptTemplate = "Say in a warm, friendly voice: %s"
+
+// Narrate reads a one-sentence forecast (e.g. "Partly cloudy in Nairobi, 24
+// degrees") aloud in lang, a BCP-47 code, and returns it as a WAV clip. The
+// text is translated first unless lang is English.
+func (s *Service) Narrate(ctx context.Context, text, lang string) ([]byte, error) {
+	if base, _, _ := strings.Cut(lang, "-"); base != "" && base != "en" {
+		translated, err := s.translateNarration(ctx, text, lang)
+		if err != nil {
+			return nil, err
+		}
+		text = translated
+	}
+
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultSpeechModel, genai.Text(fmt.Sprintf(narrationPromptTemplate, text)), &genai.GenerateContentConfig{
+		ResponseModalities: []string{"AUDIO"},
+		SpeechConfig: &genai.SpeechConfig{
+			VoiceConfig: &genai.VoiceConfig{PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: NarrationVoice}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+	if u := resp.UsageMetadata; u != nil {
+		costs.Record(ctx, database.ModelUsage{Model: DefaultSpeechModel, PromptTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount})
+	}
+
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if part.InlineData != nil && strings.HasPrefix(part.InlineData.MIMEType, "audio/") && len(part.InlineData.Data) > 0 {
+				log.Printf("Narrated %q (%s): %d bytes", text, lang, len(part.InlineData.Data))
+				return wavFromPCM(part.InlineData.Data, pcmRate(part.InlineData.MIMEType)), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no audio generated")
+}
+
+// translateNarration translates a forecast line into lang with the text model.
+func (s *Service) translateNarration(ctx context.Context, text, lang string) (string, error) {
+	prompt := fmt.Sprintf(narrationTranslatePromptTemplate, lang, text)
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultTextModel, genai.Text(prompt), &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: map[string]*genai.Schema{"forecast": {Type: genai.TypeString}},
+			Required:   []string{"forecast"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("genai error: %w", err)
+	}
+	if u := resp.UsageMetadata; u != nil {
+		costs.Record(ctx, database.ModelUsage{Model: DefaultTextModel, PromptTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount})
+	}
+
+	var out struct {
+		Forecast string `json:"forecast"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &out); err != nil {
+		return "", fmt.Errorf("failed to parse forecast translation: %w", err)
+	}
+	if out.Forecast = strings.TrimSpace(out.Forecast); out.Forecast == "" {
+		return "", fmt.Errorf("empty forecast translation")
+	}
+	return out.Forecast, nil
+}
+
+// pcmRate reads the sample rate of raw PCM audio from its MIME type, e.g.
+// "audio/L16;codec=pcm;rate=24000".
+func pcmRate(mimeType string) int {
+	if _, params, err := mime.ParseMediaType(mimeType); err == nil {
+		if rate, err := strconv.Atoi(params["rate"]); err == nil && rate > 0 {
+			return rate
+		}
+	}
+	return narrationSampleRate
+}
+
+// wavFromPCM wraps 16-bit mono little-endian PCM in a WAV header, so the
+// clip plays in browsers and signage players.
+func wavFromPCM(pcm []byte, rate int) []byte {
+	const channels, bitsPerSample = 1, 16
+	blockAlign := channels * bitsPerSample / 8
+
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size             uint32
+		Format, Channels uint16
+		Rate, ByteRate   uint32
+		BlockAlign, Bits uint16
+	}{16, 1, channels, uint32(rate), uint32(rate * blockAlign), uint16(blockAlign), bitsPerSample})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
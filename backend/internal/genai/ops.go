@@ -0,0 +1,186 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"banana-weather/internal/database"
+
+	"golang.org/x/oauth2/google"
+)
+
+// OperationStore records in-flight Veo operations so operators can list and
+// cancel them (see `banana admin ops`).
+type OperationStore interface {
+	TrackOperation(ctx context.Context, op database.PendingOperation) error
+	ClearOperation(ctx context.Context, name string) error
+}
+
+// SetOperationStore enables tracking of Veo operations started by GenerateVideo.
+func (s *Service) SetOperationStore(store OperationStore) {
+	s.ops = store
+}
+
+func (s *Service) trackOperation(ctx context.Context, name, model, input string) {
+	if s.ops == nil {
+		return
+	}
+	err := s.ops.TrackOperation(ctx, database.PendingOperation{
+		Name:       name,
+		Model:      model,
+		InputImage: input,
+		StartedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to track operation %s: %v", name, err)
+	}
+}
+
+func (s *Service) clearOperation(name string) {
+	if s.ops == nil {
+		return
+	}
+	// The request context may already be cancelled; clearing must still happen.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.ops.ClearOperation(ctx, name); err != nil {
+		log.Printf("Failed to clear operation %s: %v", name, err)
+	}
+}
+
+// apiHost returns the Vertex AI endpoint for the configured location.
+func (s *Service) apiHost() string {
+	if s.location == "" || s.location == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com", s.location)
+}
+
+// callREST issues an authenticated request with Application Default Credentials
+// (or the Service's HTTP client), JSON-encoding in when it's non-nil. The SDK
+// doesn't cover operation cancellation or quota lookups, and the REST transport
+// uses it for everything.
+func (s *Service) callREST(ctx context.Context, method, endpoint string, in, out any) error {
+	client := s.httpClient
+	if client == nil {
+		var err error
+		client, err = google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return fmt.Errorf("failed to get credentials: %w", err)
+		}
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// CancelOperation asks Vertex AI to cancel a long-running operation by its full
+// name (projects/.../operations/...). Cancellation is best-effort on the server.
+func (s *Service) CancelOperation(ctx context.Context, name string) error {
+	endpoint := fmt.Sprintf("%s/v1/%s:cancel", s.apiHost(), name)
+	if err := s.callREST(ctx, http.MethodPost, endpoint, nil, nil); err != nil {
+		return fmt.Errorf("cancel failed: %w", err)
+	}
+	log.Printf("Cancel requested for operation %s", name)
+	s.clearOperation(name)
+	return nil
+}
+
+// QuotaInfo is one Vertex AI quota limit as reported by the Cloud Quotas API.
+type QuotaInfo struct {
+	QuotaID    string            `json:"quota_id"`
+	Metric     string            `json:"metric"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Limit      string            `json:"limit"`
+}
+
+// GetQuotas lists Vertex AI quota limits whose ID or metric contains filter
+// (case-insensitive). Current usage isn't exposed by the Cloud Quotas API; use
+// Cloud Monitoring for that.
+func (s *Service) GetQuotas(ctx context.Context, filter string) ([]QuotaInfo, error) {
+	type response struct {
+		QuotaInfos []struct {
+			QuotaID         string `json:"quotaId"`
+			Metric          string `json:"metric"`
+			DimensionsInfos []struct {
+				Dimensions map[string]string `json:"dimensions"`
+				Details    struct {
+					Value string `json:"value"`
+				} `json:"details"`
+			} `json:"dimensionsInfos"`
+		} `json:"quotaInfos"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+
+	filter = strings.ToLower(filter)
+	var out []QuotaInfo
+	pageToken := ""
+	for {
+		endpoint := fmt.Sprintf("https://cloudquotas.googleapis.com/v1/projects/%s/locations/global/services/aiplatform.googleapis.com/quotaInfos?pageSize=500", s.projectID)
+		if pageToken != "" {
+			endpoint += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		var resp response
+		if err := s.callREST(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
+			return nil, fmt.Errorf("quota lookup failed: %w", err)
+		}
+
+		for _, q := range resp.QuotaInfos {
+			if filter != "" && !strings.Contains(strings.ToLower(q.QuotaID), filter) && !strings.Contains(strings.ToLower(q.Metric), filter) {
+				continue
+			}
+			for _, d := range q.DimensionsInfos {
+				// Only show limits for our region (or region-less ones)
+				if region, ok := d.Dimensions["region"]; ok && s.location != "" && s.location != "global" && region != s.location {
+					continue
+				}
+				out = append(out, QuotaInfo{
+					QuotaID:    q.QuotaID,
+					Metric:     q.Metric,
+					Dimensions: d.Dimensions,
+					Limit:      d.Details.Value,
+				})
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	return out, nil
+}
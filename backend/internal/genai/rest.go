@@ -0,0 +1,254 @@
+package genai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+
+	"banana-weather/internal/progress"
+)
+
+// Transports for image and video generation. The genai SDK is the default;
+// the REST transport calls the Vertex AI endpoints with the request and
+// response structs below, as an escape hatch when an SDK release renames fields.
+const (
+	TransportSDK  = "sdk"
+	TransportREST = "rest"
+)
+
+// SetTransport selects how GenerateImage and GenerateVideo reach Vertex AI.
+// Anything other than TransportREST uses the SDK.
+func (s *Service) SetTransport(t string) {
+	s.transport = t
+	if t == TransportREST {
+		log.Printf("GenAI using REST transport")
+	}
+}
+
+// modelEndpoint returns the URL for a publisher model method, e.g. ":generateContent".
+func (s *Service) modelEndpoint(model, method string) string {
+	location := s.location
+	if location == "" {
+		location = "global"
+	}
+	return fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models/%s%s", s.apiHost(), s.projectID, location, model, method)
+}
+
+// -- generateContent --
+
+type restPart struct {
+	Text       string          `json:"text,omitempty"`
+	Thought    bool            `json:"thought,omitempty"`
+	InlineData *restInlineData `json:"inlineData,omitempty"`
+}
+
+type restInlineData struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type restContent struct {
+	Role  string     `json:"role,omitempty"`
+	Parts []restPart `json:"parts"`
+}
+
+type restGenerateContentRequest struct {
+	Contents         []restContent `json:"contents"`
+	Tools            []restTool    `json:"tools,omitempty"`
+	GenerationConfig struct {
+		ResponseModalities []string         `json:"responseModalities,omitempty"`
+		ImageConfig        *restImageConfig `json:"imageConfig,omitempty"`
+		Seed               *int32           `json:"seed,omitempty"`
+	} `json:"generationConfig"`
+}
+
+type restImageConfig struct {
+	AspectRatio string `json:"aspectRatio,omitempty"`
+}
+
+type restTool struct {
+	GoogleSearch *struct{} `json:"googleSearch,omitempty"`
+}
+
+type restGenerateContentResponse struct {
+	Candidates []struct {
+		Content           restContent `json:"content"`
+		FinishReason      string      `json:"finishReason"`
+		GroundingMetadata *struct {
+			WebSearchQueries []string `json:"webSearchQueries"`
+			GroundingChunks  []struct {
+				Web *struct {
+					Title  string `json:"title"`
+					URI    string `json:"uri"`
+					Domain string `json:"domain"`
+				} `json:"web"`
+			} `json:"groundingChunks"`
+			GroundingSupports []struct {
+				GroundingChunkIndices []int32 `json:"groundingChunkIndices"`
+				Segment               *struct {
+					Text string `json:"text"`
+				} `json:"segment"`
+			} `json:"groundingSupports"`
+		} `json:"groundingMetadata"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int32 `json:"promptTokenCount"`
+		CandidatesTokenCount int32 `json:"candidatesTokenCount"`
+		TotalTokenCount      int32 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// generateImageREST is generateImageSDK over the REST API.
+func (s *Service) generateImageREST(ctx context.Context, model, prompt string, seed *int32, opts ImageOptions) (*rawImageResult, error) {
+	var req restGenerateContentRequest
+	parts := []restPart{{Text: prompt}}
+	if ref := opts.Reference; ref != nil {
+		parts = append([]restPart{{InlineData: &restInlineData{
+			MIMEType: ref.MIMEType,
+			Data:     base64.StdEncoding.EncodeToString(ref.Data),
+		}}}, parts...)
+	}
+	req.Contents = []restContent{{Role: "user", Parts: parts}}
+	if opts.Grounding != GroundingOff {
+		req.Tools = []restTool{{GoogleSearch: &struct{}{}}}
+	}
+	req.GenerationConfig.ResponseModalities = []string{"IMAGE"}
+	req.GenerationConfig.ImageConfig = &restImageConfig{AspectRatio: opts.Aspect}
+	req.GenerationConfig.Seed = seed
+
+	var resp restGenerateContentResponse
+	if err := s.callREST(ctx, http.MethodPost, s.modelEndpoint(model, ":generateContent"), req, &resp); err != nil {
+		log.Printf("GenAI REST generateContent failed: %v", err)
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		log.Printf("GenAI returned no candidates or parts")
+		return nil, fmt.Errorf("no content generated")
+	}
+	return parseRESTResponse(&resp)
+}
+
+// parseRESTResponse is parseSDKResponse for the REST structs.
+func parseRESTResponse(resp *restGenerateContentResponse) (*rawImageResult, error) {
+	raw := &rawImageResult{usage: TokenUsage{
+		Prompt: resp.UsageMetadata.PromptTokenCount,
+		Output: resp.UsageMetadata.CandidatesTokenCount,
+		Total:  resp.UsageMetadata.TotalTokenCount,
+	}}
+	if len(resp.Candidates) == 0 {
+		return raw, nil
+	}
+	c := resp.Candidates[0]
+	for _, part := range c.Content.Parts {
+		if part.InlineData != nil && part.InlineData.Data != "" {
+			data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image data: %w", err)
+			}
+			raw.images = append(raw.images, data)
+		}
+		raw.addText(part.Text, part.Thought)
+	}
+	if g := c.GroundingMetadata; g != nil {
+		chunks := make([]groundingChunk, len(g.GroundingChunks))
+		for i, chunk := range g.GroundingChunks {
+			if chunk.Web != nil {
+				chunks[i] = groundingChunk{title: chunk.Web.Title, uri: chunk.Web.URI, domain: chunk.Web.Domain}
+			}
+		}
+		var supports []groundingSupport
+		for _, sup := range g.GroundingSupports {
+			if sup.Segment != nil {
+				supports = append(supports, groundingSupport{text: sup.Segment.Text, chunks: sup.GroundingChunkIndices})
+			}
+		}
+		raw.grounding = buildGrounding(g.WebSearchQueries, chunks, supports)
+	}
+	return raw, nil
+}
+
+// -- predictLongRunning (Veo) --
+
+type restGCSFile struct {
+	GCSURI   string `json:"gcsUri"`
+	MIMEType string `json:"mimeType"`
+}
+
+type restVideoInstance struct {
+	Prompt string      `json:"prompt"`
+	Image  restGCSFile `json:"image"`
+}
+
+type restVideoRequest struct {
+	Instances  []restVideoInstance `json:"instances"`
+	Parameters struct {
+		AspectRatio string `json:"aspectRatio"`
+		StorageURI  string `json:"storageUri"`
+		SampleCount int    `json:"sampleCount"`
+		Duration    int32  `json:"durationSeconds"`
+		Seed        *int32 `json:"seed,omitempty"`
+	} `json:"parameters"`
+}
+
+type restOperation struct {
+	Name     string         `json:"name"`
+	Done     bool           `json:"done"`
+	Metadata map[string]any `json:"metadata"`
+	Error    *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Response *struct {
+		Videos []restGCSFile `json:"videos"`
+	} `json:"response"`
+}
+
+// generateVideoREST is generateVideoSDK over the REST API.
+func (s *Service) generateVideoREST(ctx context.Context, model, prompt, inputImageURI string, seed *int32) (string, error) {
+	var req restVideoRequest
+	req.Instances = []restVideoInstance{{
+		Prompt: prompt,
+		Image:  restGCSFile{GCSURI: inputImageURI, MIMEType: "image/png"},
+	}}
+	req.Parameters.AspectRatio = "9:16"
+	req.Parameters.StorageURI = s.videoOutput()
+	req.Parameters.SampleCount = 1
+	req.Parameters.Duration = VideoSeconds
+	req.Parameters.Seed = seed
+
+	var op restOperation
+	if err := s.callREST(ctx, http.MethodPost, s.modelEndpoint(model, ":predictLongRunning"), req, &op); err != nil {
+		log.Printf("GenAI REST predictLongRunning failed: %v", err)
+		return "", fmt.Errorf("veo error: %w", err)
+	}
+
+	progress.Logf(ctx, "Veo operation started. ID: %s", op.Name)
+	s.trackOperation(ctx, op.Name, model, inputImageURI)
+	defer s.clearOperation(op.Name)
+
+	fetch := struct {
+		OperationName string `json:"operationName"`
+	}{op.Name}
+
+	return s.waitForVideo(ctx, model, func(ctx context.Context) (string, map[string]any, bool, error) {
+		var cur restOperation
+		if err := s.callREST(ctx, http.MethodPost, s.modelEndpoint(model, ":fetchPredictOperation"), fetch, &cur); err != nil {
+			return "", nil, false, err
+		}
+		if !cur.Done {
+			return "", cur.Metadata, false, nil
+		}
+		if cur.Error != nil {
+			return "", nil, true, fmt.Errorf("operation failed: %d %s", cur.Error.Code, cur.Error.Message)
+		}
+		if cur.Response == nil || len(cur.Response.Videos) == 0 || cur.Response.Videos[0].GCSURI == "" {
+			return "", nil, true, fmt.Errorf("operation done but no videos found")
+		}
+		uri := cur.Response.Videos[0].GCSURI
+		log.Printf("Video generated (GCS URI): %s", uri)
+		return uri, nil, true, nil
+	})
+}
@@ -0,0 +1,177 @@
+package genai
+
+import (
+	"encoding/base64"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/provenance"
+
+	"google.golang.org/genai"
+)
+
+// ImageResult is everything GenerateImage got back from the model.
+type ImageResult struct {
+	Images    []string // Base64-encoded, watermarked images; Images[0] is the one callers store
+	Original  string   // Base64 model output behind Images[0] when post-processing changed it, to keep privately
+	Text      string   // Model commentary from text parts, e.g. the weather it retrieved
+	Grounding Grounding
+	Usage     TokenUsage
+	Model     string
+	Cached    bool // Served from the prompt cache; Text, Grounding and Usage are empty
+
+	GroundingMode GroundingMode // Whether the model could (or had to) search
+	Lang          string        // Language of the text in the image (see WithLanguage); "" for the city's native language
+	Style         string        // Style the prompt was built with, see ResolveStyle
+	PromptVersion string        // Of the prompt templates, see PromptVersion
+}
+
+// Grounding is what the GoogleSearch tool contributed.
+type Grounding struct {
+	SearchQueries []string
+	Sources       []database.GroundingSource
+}
+
+// groundingChunk and groundingSupport are the transport-neutral form of the
+// SDK and REST grounding metadata.
+type groundingChunk struct {
+	title, uri, domain string // uri is empty for non-web chunks
+}
+
+type groundingSupport struct {
+	text   string
+	chunks []int32
+}
+
+// buildGrounding keeps the web chunks and attaches each supported answer
+// segment to the chunks it cites.
+func buildGrounding(queries []string, chunks []groundingChunk, supports []groundingSupport) Grounding {
+	g := Grounding{SearchQueries: queries}
+	index := make(map[int32]int) // chunk index -> position in g.Sources
+	for i, c := range chunks {
+		if c.uri == "" {
+			continue
+		}
+		index[int32(i)] = len(g.Sources)
+		g.Sources = append(g.Sources, database.GroundingSource{Title: c.title, URI: c.uri, Domain: c.domain})
+	}
+	for _, sup := range supports {
+		text := strings.TrimSpace(sup.text)
+		if text == "" {
+			continue
+		}
+		for _, ci := range sup.chunks {
+			pos, ok := index[ci]
+			if !ok || slices.Contains(g.Sources[pos].Snippets, text) {
+				continue
+			}
+			g.Sources[pos].Snippets = append(g.Sources[pos].Snippets, text)
+		}
+	}
+	return g
+}
+
+type TokenUsage struct {
+	Prompt int32
+	Output int32
+	Total  int32
+}
+
+// Image returns the primary image.
+func (r *ImageResult) Image() string {
+	return r.Images[0]
+}
+
+// Stamp embeds content credentials for locationID into Images[0] (see
+// provenance). A nil signer does nothing; on failure the image is left
+// unlabelled rather than lost.
+func (r *ImageResult) Stamp(s *provenance.Signer, locationID string, now time.Time) {
+	if s == nil || len(r.Images) == 0 {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(r.Images[0])
+	if err == nil {
+		data, err = s.Embed(data, r.Model, locationID, now)
+	}
+	if err != nil {
+		log.Printf("Failed to embed provenance for %s: %v", locationID, err)
+		return
+	}
+	r.Images[0] = base64.StdEncoding.EncodeToString(data)
+}
+
+// Metadata returns the parts of the result worth persisting on the location.
+func (r *ImageResult) Metadata() *database.GenerationMetadata {
+	m := &database.GenerationMetadata{
+		Model:         r.Model,
+		Commentary:    r.Text,
+		SearchQueries: r.Grounding.SearchQueries,
+		Sources:       r.Grounding.Sources,
+		PromptTokens:  r.Usage.Prompt,
+		OutputTokens:  r.Usage.Output,
+		TotalTokens:   r.Usage.Total,
+		Cached:        r.Cached,
+		GroundingMode: string(r.GroundingMode),
+		Lang:          r.Lang,
+		Style:         r.Style,
+		PromptVersion: r.PromptVersion,
+	}
+	return m
+}
+
+// rawImageResult is a parsed model response before watermarking.
+type rawImageResult struct {
+	images    [][]byte
+	text      []string
+	grounding Grounding
+	usage     TokenUsage
+}
+
+func (raw *rawImageResult) addText(text string, thought bool) {
+	if text = strings.TrimSpace(text); text != "" && !thought {
+		raw.text = append(raw.text, text)
+	}
+}
+
+// parseSDKResponse collects every part of the first candidate.
+func parseSDKResponse(resp *genai.GenerateContentResponse) *rawImageResult {
+	raw := &rawImageResult{}
+	if resp.UsageMetadata != nil {
+		raw.usage = TokenUsage{
+			Prompt: resp.UsageMetadata.PromptTokenCount,
+			Output: resp.UsageMetadata.CandidatesTokenCount,
+			Total:  resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+	if len(resp.Candidates) == 0 {
+		return raw
+	}
+	c := resp.Candidates[0]
+	if c.Content != nil {
+		for _, part := range c.Content.Parts {
+			if part.InlineData != nil && len(part.InlineData.Data) > 0 {
+				raw.images = append(raw.images, part.InlineData.Data)
+			}
+			raw.addText(part.Text, part.Thought)
+		}
+	}
+	if g := c.GroundingMetadata; g != nil {
+		chunks := make([]groundingChunk, len(g.GroundingChunks))
+		for i, chunk := range g.GroundingChunks {
+			if chunk != nil && chunk.Web != nil {
+				chunks[i] = groundingChunk{title: chunk.Web.Title, uri: chunk.Web.URI, domain: chunk.Web.Domain}
+			}
+		}
+		var supports []groundingSupport
+		for _, sup := range g.GroundingSupports {
+			if sup != nil && sup.Segment != nil {
+				supports = append(supports, groundingSupport{text: sup.Segment.Text, chunks: sup.GroundingChunkIndices})
+			}
+		}
+		raw.grounding = buildGrounding(g.WebSearchQueries, chunks, supports)
+	}
+	return raw
+}
@@ -0,0 +1,52 @@
+package genai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Styles names the prompt styles by prompt mode (see BuildPrompt). Random
+// picks classic or drink for each generation.
+var Styles = []string{"random", "classic", "drink"}
+
+// ParseStyle accepts a style name or its numeric prompt mode; empty is
+// random.
+func ParseStyle(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	for mode, name := range Styles {
+		if s == name || s == strconv.Itoa(mode) {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown style %q (use %s, or 0-%d)", s, strings.Join(Styles, ", "), len(Styles)-1)
+}
+
+// StyleName returns the name of a prompt mode, or the mode itself when it
+// has none.
+func StyleName(mode int) string {
+	if mode >= 0 && mode < len(Styles) {
+		return Styles[mode]
+	}
+	return strconv.Itoa(mode)
+}
+
+// ResolveStyle returns the style a generation with the prompt mode and seed
+// is built with: random picks classic or drink from the seed, as BuildPrompt
+// does. Without a seed random stays random.
+func ResolveStyle(mode int, seed *int32) int {
+	switch mode {
+	case 1, 2:
+		return mode
+	}
+	if seed == nil {
+		return 0
+	}
+	if *seed%2 == 1 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,46 @@
+package genai
+
+import "testing"
+
+func TestParseStyle(t *testing.T) {
+	for in, want := range map[string]int{"": 0, "random": 0, "Classic": 1, " drink ": 2, "1": 1} {
+		if got, err := ParseStyle(in); err != nil || got != want {
+			t.Errorf("ParseStyle(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"snowglobe", "3", "-1"} {
+		if _, err := ParseStyle(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if StyleName(2) != "drink" || StyleName(7) != "7" {
+		t.Errorf("Unexpected names %q, %q", StyleName(2), StyleName(7))
+	}
+}
+
+func TestResolveStyle(t *testing.T) {
+	even, odd := int32(42), int32(7)
+	tests := []struct {
+		mode int
+		seed *int32
+		want int
+	}{
+		{1, &odd, 1},
+		{2, &even, 2},
+		{0, &even, 1},
+		{0, &odd, 2},
+		{0, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := ResolveStyle(tt.mode, tt.seed); got != tt.want {
+			t.Errorf("ResolveStyle(%d, %v) = %d, want %d", tt.mode, tt.seed, got, tt.want)
+		}
+		if tt.seed == nil {
+			continue
+		}
+		// The prompt BuildPrompt picks is the one of the resolved style
+		if BuildPrompt("Lima", "", tt.mode, tt.seed) != BuildPrompt("Lima", "", tt.want, nil) {
+			t.Errorf("BuildPrompt(%d, %d) isn't the %s prompt", tt.mode, *tt.seed, StyleName(tt.want))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// DefaultTextModel is used for text-only tasks such as translating preset names.
+const DefaultTextModel = "gemini-2.5-flash"
+
+const translatePromptTemplate = `Translate the following place display names into the language with BCP-47 code %q.
+Use the conventional local name for real places (e.g. "Munich" -> "München" for de). Keep fictional names recognizable, transliterating only where the target script requires it.
+Return a JSON array of strings with exactly one translation per input, in the same order.
+
+%s`
+
+// TranslateNames translates display names into lang with a single Gemini call.
+// The result has the same length and order as names.
+func (s *Service) TranslateNames(ctx context.Context, model string, names []string, lang string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if model == "" {
+		model = DefaultTextModel
+	}
+
+	input, _ := json.Marshal(names)
+	prompt := fmt.Sprintf(translatePromptTemplate, lang, input)
+
+	log.Printf("Translating %d names to %s using model: %s", len(names), lang, model)
+	resp, err := s.client.Models.GenerateContent(ctx, model, genai.Text(prompt), &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type:  genai.TypeArray,
+			Items: &genai.Schema{Type: genai.TypeString},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+
+	var out []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse translations: %w", err)
+	}
+	if len(out) != len(names) {
+		return nil, fmt.Errorf("expected %d translations, got %d", len(names), len(out))
+	}
+	return out, nil
+}
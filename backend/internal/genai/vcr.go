@@ -0,0 +1,251 @@
+package genai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Cassette is a recorded sequence of Vertex AI calls: what the SDK or REST
+// transport sent and what came back. Recorder writes them from live calls;
+// Replayer serves them in tests, so response parsing can be regression
+// tested against real payloads without credentials.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and its response. Headers aren't kept, so
+// credentials never end up in a cassette.
+type Interaction struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"` // Host and query are dropped, see Replayer
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// LoadCassette reads a cassette written by Cassette.Save.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// placeholderPNG is a 1x1 PNG standing in for images in cassettes.
+const placeholderPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+// maxInlineData is the largest base64 payload a cassette keeps as is;
+// larger ones (generated images, reference photos) become placeholderPNG.
+const maxInlineData = 1024
+
+// Recorder is an http.RoundTripper that passes requests to Transport and
+// records them, scrubbed, into a Cassette. Pass it to NewServiceWithHTTPClient
+// in an http.Client.
+type Recorder struct {
+	// Transport makes the live calls and must add credentials, e.g. the
+	// Transport of google.DefaultClient.
+	Transport http.RoundTripper
+	// Scrub replaces each key with its value in paths and bodies, e.g. the
+	// project ID with "test-project" and the bucket with "test-bucket".
+	Scrub map[string]string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// RoundTrip makes the call and records it.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:   req.Method,
+		Path:     r.scrub(req.URL.Path),
+		Request:  r.scrubBody(reqBody),
+		Status:   resp.StatusCode,
+		Response: r.scrubBody(respBody),
+	})
+	return resp, nil
+}
+
+// Cassette returns what was recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+func (r *Recorder) scrub(s string) string {
+	for from, to := range r.Scrub {
+		if from != "" {
+			s = strings.ReplaceAll(s, from, to)
+		}
+	}
+	return s
+}
+
+// scrubBody applies Scrub and shrinks inline images. Bodies that aren't
+// JSON are kept as a JSON string.
+func (r *Recorder) scrubBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		s, _ := json.Marshal(r.scrub(string(body)))
+		return s
+	}
+	out, err := json.Marshal(shrinkImages(v))
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(r.scrub(string(out)))
+}
+
+// shrinkImages replaces large base64 payloads in inlineData parts (Gemini)
+// and bytesBase64Encoded fields (Veo) with placeholderPNG.
+func shrinkImages(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok && len(s) > maxInlineData && (k == "data" || k == "bytesBase64Encoded") {
+				v[k] = placeholderPNG
+				continue
+			}
+			v[k] = shrinkImages(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = shrinkImages(child)
+		}
+	}
+	return v
+}
+
+// Replayer is an http.RoundTripper serving a Cassette. Each request gets the
+// first unused interaction with its method and path, so a cassette replays
+// in order while independent calls may interleave. Requests without one
+// fail, naming the request.
+type Replayer struct {
+	cassette *Cassette
+
+	mu       sync.Mutex
+	used     []bool
+	received []Interaction
+}
+
+// NewReplayer returns a Replayer for c.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+// RoundTrip answers req from the cassette.
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sent := Interaction{Method: req.Method, Path: req.URL.Path}
+	if len(body) > 0 {
+		sent.Request = body
+	}
+	p.received = append(p.received, sent)
+	for i, in := range p.cassette.Interactions {
+		if p.used[i] || in.Method != req.Method || in.Path != req.URL.Path {
+			continue
+		}
+		p.used[i] = true
+		status := in.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json; charset=UTF-8"}},
+			Body:          io.NopCloser(bytes.NewReader(responseBody(in.Response))),
+			ContentLength: -1,
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("vcr: no recorded interaction for %s %s", req.Method, req.URL.Path)
+}
+
+// Received returns the requests made so far, bodies unscrubbed, so tests can
+// check what was sent.
+func (p *Replayer) Received() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Interaction(nil), p.received...)
+}
+
+// Unused returns the interactions no request has consumed yet.
+func (p *Replayer) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []Interaction
+	for i, in := range p.cassette.Interactions {
+		if !p.used[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// responseBody undoes the JSON string wrapping of non-JSON bodies.
+func responseBody(raw json.RawMessage) []byte {
+	var s string
+	if len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return []byte(s)
+	}
+	return raw
+}
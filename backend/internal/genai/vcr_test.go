@@ -0,0 +1,276 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/costs"
+
+	"golang.org/x/oauth2/google"
+)
+
+// Cassettes in testdata are re-recorded against Vertex AI with
+//
+//	GOOGLE_CLOUD_PROJECT=... GENMEDIA_BUCKET=... go test ./internal/genai -run VCR -record
+//
+// using Application Default Credentials. Video tests animate
+// gs://$GENMEDIA_BUCKET/vcr/input.png, which must exist. The project and
+// bucket are scrubbed to test-project and test-bucket.
+var record = flag.Bool("record", false, "record testdata cassettes against Vertex AI instead of replaying them")
+
+const (
+	vcrProject  = "test-project"
+	vcrLocation = "us-central1"
+	vcrBucket   = "test-bucket"
+)
+
+// vcrService returns a Service replaying testdata/<name>.json, or recording
+// it with -record. The Replayer is nil when recording.
+func vcrService(t *testing.T, name string) (*Service, *Replayer) {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join("testdata", name+".json")
+
+	if *record {
+		project, bucket := os.Getenv("GOOGLE_CLOUD_PROJECT"), os.Getenv("GENMEDIA_BUCKET")
+		if project == "" || bucket == "" {
+			t.Fatal("-record needs GOOGLE_CLOUD_PROJECT and GENMEDIA_BUCKET")
+		}
+		creds, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := &Recorder{
+			Transport: creds.Transport,
+			Scrub:     map[string]string{project: vcrProject, bucket: vcrBucket},
+		}
+		s, err := NewServiceWithHTTPClient(ctx, project, vcrLocation, bucket, "", &http.Client{Transport: rec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := rec.Cassette().Save(path); err != nil {
+				t.Errorf("failed to save %s: %v", path, err)
+			}
+		})
+		return s, nil
+	}
+
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewReplayer(c)
+	s, err := NewServiceWithHTTPClient(ctx, vcrProject, vcrLocation, vcrBucket, "", &http.Client{Transport: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, in := range p.Unused() {
+			t.Errorf("interaction not replayed: %s %s", in.Method, in.Path)
+		}
+	})
+	return s, p
+}
+
+// fastPolling shortens Veo poll intervals while replaying.
+func fastPolling(t *testing.T) {
+	if *record {
+		return
+	}
+	fast, medium, slow := pollFast, pollMedium, pollSlow
+	pollFast, pollMedium, pollSlow = time.Millisecond, time.Millisecond, time.Millisecond
+	t.Cleanup(func() { pollFast, pollMedium, pollSlow = fast, medium, slow })
+}
+
+// videoInput is the image the video cassettes animate.
+func videoInput() string {
+	if *record {
+		return "gs://" + os.Getenv("GENMEDIA_BUCKET") + "/vcr/input.png"
+	}
+	return "gs://" + vcrBucket + "/vcr/input.png"
+}
+
+func TestVCR_GenerateImage(t *testing.T) {
+	for _, transport := range []string{TransportSDK, TransportREST} {
+		t.Run(transport, func(t *testing.T) {
+			s, p := vcrService(t, "generate_image_"+transport)
+			s.SetTransport(transport)
+			seed := int32(42)
+			ctx, usage := costs.Track(context.Background())
+			res, err := s.GenerateImage(ctx, "Lisbon, Portugal", "", 1, &seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *record {
+				return
+			}
+
+			if len(res.Images) != 1 {
+				t.Fatalf("got %d images", len(res.Images))
+			}
+			data, err := base64.StdEncoding.DecodeString(res.Images[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+				t.Errorf("image isn't a PNG: %v", err)
+			}
+			if res.Model != "gemini-3.1-flash-image-preview" {
+				t.Errorf("model = %q", res.Model)
+			}
+			if !strings.Contains(res.Text, "Lisbon") {
+				t.Errorf("text = %q, want the model's commentary", res.Text)
+			}
+			if len(res.Grounding.SearchQueries) == 0 || len(res.Grounding.Sources) == 0 {
+				t.Errorf("grounding = %+v, want queries and sources", res.Grounding)
+			}
+			if res.Usage.Total == 0 || res.Usage.Total != res.Usage.Prompt+res.Usage.Output {
+				t.Errorf("usage = %+v", res.Usage)
+			}
+			if calls := usage.Calls(); len(calls) != 1 || calls[0].Model != res.Model || calls[0].PromptTokens != res.Usage.Prompt || calls[0].OutputTokens != res.Usage.Output {
+				t.Errorf("tracked %+v", calls)
+			}
+
+			// What was sent: the seed and aspect ratio survive the transport
+			sent := p.Received()
+			if len(sent) != 1 || !strings.HasSuffix(sent[0].Path, "/models/gemini-3.1-flash-image-preview:generateContent") {
+				t.Fatalf("sent %+v", sent)
+			}
+			var req struct {
+				GenerationConfig struct {
+					Seed        int32 `json:"seed"`
+					ImageConfig struct {
+						AspectRatio string `json:"aspectRatio"`
+					} `json:"imageConfig"`
+				} `json:"generationConfig"`
+			}
+			if err := json.Unmarshal(sent[0].Request, &req); err != nil {
+				t.Fatal(err)
+			}
+			if req.GenerationConfig.Seed != seed || req.GenerationConfig.ImageConfig.AspectRatio != DefaultAspect {
+				t.Errorf("generationConfig = %+v", req.GenerationConfig)
+			}
+		})
+	}
+}
+
+func TestVCR_GenerateVideo(t *testing.T) {
+	fastPolling(t)
+	for _, transport := range []string{TransportSDK, TransportREST} {
+		t.Run(transport, func(t *testing.T) {
+			s, p := vcrService(t, "generate_video_"+transport)
+			s.SetTransport(transport)
+			ctx, usage := costs.Track(context.Background())
+			uri, err := s.GenerateVideo(ctx, videoInput(), "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *record {
+				return
+			}
+
+			if !strings.HasPrefix(uri, "gs://"+vcrBucket+"/videos/") || !strings.HasSuffix(uri, ".mp4") {
+				t.Errorf("uri = %q", uri)
+			}
+			sent := p.Received()
+			if len(sent) < 3 {
+				t.Fatalf("sent %d requests, want the start and at least two polls", len(sent))
+			}
+			var req struct {
+				Parameters struct {
+					DurationSeconds int32 `json:"durationSeconds"`
+				} `json:"parameters"`
+			}
+			if err := json.Unmarshal(sent[0].Request, &req); err != nil {
+				t.Fatal(err)
+			}
+			if req.Parameters.DurationSeconds != VideoSeconds {
+				t.Errorf("durationSeconds = %d, want %d", req.Parameters.DurationSeconds, VideoSeconds)
+			}
+			if calls := usage.Calls(); len(calls) != 1 || calls[0].VideoSeconds != VideoSeconds || calls[0].Model != "veo-3.1-lite-generate-001" {
+				t.Errorf("tracked %+v", calls)
+			}
+		})
+	}
+}
+
+func TestVCR_VideoFailed(t *testing.T) {
+	fastPolling(t)
+	s, _ := vcrService(t, "generate_video_failed")
+	ctx, usage := costs.Track(context.Background())
+	_, err := s.GenerateVideo(ctx, videoInput(), "", nil)
+	if *record {
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), "usage guidelines") {
+		t.Errorf("err = %v, want the operation's error", err)
+	}
+	if calls := usage.Calls(); len(calls) != 0 {
+		t.Errorf("failed video tracked as %+v", calls)
+	}
+}
+
+func TestRecorder_Scrubs(t *testing.T) {
+	image := base64.StdEncoding.EncodeToString(make([]byte, 4*maxInlineData))
+	live := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") == "" {
+			t.Error("credentials not passed to the live transport")
+		}
+		body := `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":"` + image + `"}}]}}],"modelVersion":"x","note":"gs://secret-bucket/a.png"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+	rec := &Recorder{Transport: live, Scrub: map[string]string{"secret-project": vcrProject, "secret-bucket": vcrBucket}}
+	req, _ := http.NewRequest(http.MethodPost, "https://us-central1-aiplatform.googleapis.com/v1/projects/secret-project/models/m:generateContent?alt=json", strings.NewReader(`{"contents":[]}`))
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := (&http.Client{Transport: rec}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	c := rec.Cassette()
+	if len(c.Interactions) != 1 {
+		t.Fatalf("recorded %d interactions", len(c.Interactions))
+	}
+	in := c.Interactions[0]
+	if in.Path != "/v1/projects/test-project/models/m:generateContent" {
+		t.Errorf("path = %q", in.Path)
+	}
+	out, _ := json.Marshal(c)
+	for _, secret := range []string{"secret-project", "secret-bucket", "Bearer", image} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("cassette contains %.20q", secret)
+		}
+	}
+	if !strings.Contains(string(in.Response), placeholderPNG) {
+		t.Errorf("response = %s, want the image replaced", in.Response)
+	}
+
+	// Replaying it answers the same request and nothing else
+	p := NewReplayer(c)
+	hc := &http.Client{Transport: p}
+	req, _ = http.NewRequest(http.MethodPost, "https://example.com"+in.Path, strings.NewReader(`{}`))
+	if resp, err := hc.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("replay = %v, %v", resp, err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, "https://example.com"+in.Path, nil)
+	if _, err := hc.Do(req); err == nil {
+		t.Error("second request answered from a single interaction")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,137 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+)
+
+// TimingStore keeps recent Veo durations per model, for progress estimates.
+// Repositories implement it; SetOperationStore picks it up.
+type TimingStore interface {
+	RecordModelTiming(ctx context.Context, model string, d time.Duration) error
+	GetModelTimings(ctx context.Context, model string) (*database.ModelTimings, error)
+}
+
+// veoTypical is roughly how long Veo takes for a clip, the expected duration
+// of models without enough history.
+const veoTypical = 60 * time.Second
+
+// minTimings is how many recorded durations a model needs before they're
+// used instead of veoTypical.
+const minTimings = 3
+
+// Poll intervals: frequent while the video is due, backing off once the
+// operation runs long, so slow ones don't hammer the API. Replay tests
+// shorten them.
+var (
+	pollFast   = 5 * time.Second
+	pollMedium = 15 * time.Second
+	pollSlow   = 30 * time.Second
+)
+
+// veoPoll checks a Veo operation once. Once done, it returns the video's URI
+// or the operation's error; while running, its metadata. Errors while not
+// done are failed polls, which are retried.
+type veoPoll func(ctx context.Context) (uri string, metadata map[string]any, done bool, err error)
+
+// waitForVideo polls a Veo operation of model until it's done, reporting
+// progress with an ETA based on the model's recent durations, and records
+// the duration of successful operations.
+func (s *Service) waitForVideo(ctx context.Context, model string, poll veoPoll) (string, error) {
+	started := time.Now()
+	expected := s.expectedDuration(ctx, model)
+	timer := time.NewTimer(pollInterval(0, expected))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("context cancelled during polling: %w", ctx.Err())
+		case <-timer.C:
+		}
+		uri, metadata, done, err := poll(ctx)
+		elapsed := time.Since(started)
+		switch {
+		case done && err == nil:
+			s.recordTiming(ctx, model, elapsed)
+			costs.Record(ctx, database.ModelUsage{Model: model, VideoSeconds: VideoSeconds, WaitSeconds: elapsed.Seconds()})
+			return uri, nil
+		case done:
+			return "", err
+		case err != nil:
+			progress.Logf(ctx, "Veo polling failed: %v", err)
+		default:
+			progress.Logf(ctx, "Still polling Veo...")
+			reportVeoProgress(ctx, metadata, elapsed, expected)
+		}
+		timer.Reset(pollInterval(elapsed, expected))
+	}
+}
+
+// pollInterval returns the wait before the next poll after elapsed.
+func pollInterval(elapsed, expected time.Duration) time.Duration {
+	switch {
+	case elapsed < expected:
+		return pollFast
+	case elapsed < 2*expected:
+		return pollMedium
+	}
+	return pollSlow
+}
+
+// expectedDuration is the median of the model's recent durations, or
+// veoTypical without enough of them.
+func (s *Service) expectedDuration(ctx context.Context, model string) time.Duration {
+	ts, ok := s.ops.(TimingStore)
+	if !ok {
+		return veoTypical
+	}
+	t, err := ts.GetModelTimings(ctx, model)
+	if err != nil {
+		log.Printf("Failed to load timings of %s: %v", model, err)
+		return veoTypical
+	}
+	if len(t.Durations) < minTimings {
+		return veoTypical
+	}
+	d := slices.Sorted(slices.Values(t.Durations))
+	return time.Duration(d[len(d)/2] * float64(time.Second))
+}
+
+func (s *Service) recordTiming(ctx context.Context, model string, d time.Duration) {
+	ts, ok := s.ops.(TimingStore)
+	if !ok {
+		return
+	}
+	// Like clearOperation, this must happen even if the request went away
+	if err := ts.RecordModelTiming(context.WithoutCancel(ctx), model, d); err != nil {
+		log.Printf("Failed to record timing of %s: %v", model, err)
+	}
+}
+
+// reportVeoProgress emits the progress of a running Veo operation: its
+// progressPercent metadata when set, otherwise an estimate from the expected
+// duration, which slows down past 90% so late videos don't sit at 99%. The
+// ETA is left out once the operation runs past the expected duration.
+func reportVeoProgress(ctx context.Context, metadata map[string]any, elapsed, expected time.Duration) {
+	var pct float64
+	if p, ok := metadata["progressPercent"].(float64); ok {
+		pct = p
+	} else if elapsed < expected {
+		pct = 90 * elapsed.Seconds() / expected.Seconds()
+	} else {
+		pct = 90 + 9*(1-expected.Seconds()/elapsed.Seconds())
+	}
+	u := progress.Update{Stage: "video", Message: "Animating (Veo 3.1)", Percent: min(max(int(pct), 1), 99)}
+	if eta := expected - elapsed; eta > 0 {
+		u.ETASeconds = int(eta.Round(time.Second).Seconds())
+	}
+	progress.Emit(ctx, u)
+}
@@ -0,0 +1,107 @@
+package genai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+)
+
+type fakeTimings struct {
+	durations []float64
+	recorded  []time.Duration
+	ctxErr    error
+}
+
+func (f *fakeTimings) TrackOperation(ctx context.Context, op database.PendingOperation) error {
+	return nil
+}
+func (f *fakeTimings) ClearOperation(ctx context.Context, name string) error {
+	return nil
+}
+func (f *fakeTimings) RecordModelTiming(ctx context.Context, model string, d time.Duration) error {
+	f.recorded = append(f.recorded, d)
+	f.ctxErr = ctx.Err()
+	return nil
+}
+func (f *fakeTimings) GetModelTimings(ctx context.Context, model string) (*database.ModelTimings, error) {
+	return &database.ModelTimings{Model: model, Durations: f.durations}, nil
+}
+
+func TestExpectedDuration(t *testing.T) {
+	tests := []struct {
+		durations []float64
+		want      time.Duration
+	}{
+		{nil, veoTypical},
+		{[]float64{40, 50}, veoTypical}, // Not enough history
+		{[]float64{90, 40, 50, 300}, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		s := &Service{}
+		s.SetOperationStore(&fakeTimings{durations: tt.durations})
+		if got := s.expectedDuration(context.Background(), "veo"); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.durations, tt.want, got)
+		}
+	}
+	if got := (&Service{}).expectedDuration(context.Background(), "veo"); got != veoTypical {
+		t.Errorf("Expected %v without a store, got %v", veoTypical, got)
+	}
+}
+
+func TestRecordTiming(t *testing.T) {
+	f := &fakeTimings{}
+	s := &Service{}
+	s.SetOperationStore(f)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.recordTiming(ctx, "veo", 42*time.Second)
+	if len(f.recorded) != 1 || f.recorded[0] != 42*time.Second || f.ctxErr != nil {
+		t.Errorf("Expected the timing recorded despite the cancelled request, got %v (%v)", f.recorded, f.ctxErr)
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	expected := 60 * time.Second
+	tests := []struct {
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{0, pollFast},
+		{55 * time.Second, pollFast},
+		{90 * time.Second, pollMedium},
+		{5 * time.Minute, pollSlow},
+	}
+	for _, tt := range tests {
+		if got := pollInterval(tt.elapsed, expected); got != tt.want {
+			t.Errorf("After %v: expected %v, got %v", tt.elapsed, tt.want, got)
+		}
+	}
+}
+
+func TestReportVeoProgress(t *testing.T) {
+	var got []progress.Update
+	ctx := progress.WithEmitter(context.Background(), func(u progress.Update) { got = append(got, u) })
+	expected := 60 * time.Second
+	reportVeoProgress(ctx, map[string]any{"progressPercent": 42.0}, 10*time.Second, expected)
+	reportVeoProgress(ctx, nil, 30*time.Second, expected)
+	reportVeoProgress(ctx, nil, 2*time.Minute, expected)
+	reportVeoProgress(ctx, nil, time.Hour, expected)
+
+	want := []progress.Update{
+		{Percent: 42, ETASeconds: 50},
+		{Percent: 45, ETASeconds: 30},
+		{Percent: 94}, // Past the expected duration: slower, without an ETA
+		{Percent: 98},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d updates, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Percent != w.Percent || got[i].ETASeconds != w.ETASeconds || got[i].Stage != "video" {
+			t.Errorf("Update %d: expected %+v, got %+v", i, w, got[i])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package genai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+
+	"google.golang.org/genai"
+)
+
+const verifyPromptTemplate = `This is a stylized weather illustration for a city.
+Which of these weather conditions does the scene depict: %s?
+The observed weather right now is: %s.
+Decide whether the illustration is consistent with the observed weather. Treat "clear" and "cloudy" as compatible with each other, and ignore temperature and time of day unless they are clearly contradicted (e.g. snow at 30°C).
+Respond in JSON.`
+
+// VerifyWeather asks a cheap vision model whether an image depicts weather
+// consistent with actual (see openmeteo.Current.Describe). Actual and
+// CheckedAt are filled in on the result.
+func (s *Service) VerifyWeather(ctx context.Context, imageBase64 string, actual string) (*database.WeatherCheck, error) {
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+
+	conditions := make([]string, len(openmeteo.Conditions))
+	for i, c := range openmeteo.Conditions {
+		conditions[i] = string(c)
+	}
+	prompt := fmt.Sprintf(verifyPromptTemplate, strings.Join(conditions, ", "), actual)
+
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromBytes(data, "image/png"),
+		genai.NewPartFromText(prompt),
+	}, genai.RoleUser)}
+	resp, err := s.client.Models.GenerateContent(ctx, DefaultTextModel, contents, &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"depicted": {Type: genai.TypeString, Enum: conditions},
+				"matches":  {Type: genai.TypeBoolean},
+				"reason":   {Type: genai.TypeString},
+			},
+			Required: []string{"depicted", "matches", "reason"},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("genai error: %w", err)
+	}
+	if u := resp.UsageMetadata; u != nil {
+		costs.Record(ctx, database.ModelUsage{Model: DefaultTextModel, PromptTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount})
+	}
+
+	var out struct {
+		Depicted string `json:"depicted"`
+		Matches  bool   `json:"matches"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Text())), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse verification: %w", err)
+	}
+	log.Printf("Weather check: depicted %s, actual %s, matches: %v", out.Depicted, actual, out.Matches)
+
+	return &database.WeatherCheck{
+		Actual:    actual,
+		Depicted:  out.Depicted,
+		Matches:   out.Matches,
+		Reason:    out.Reason,
+		CheckedAt: time.Now(),
+	}, nil
+}
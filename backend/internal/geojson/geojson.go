@@ -0,0 +1,94 @@
+package geojson
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// FeatureCollection is a GeoJSON (RFC 7946) feature collection of points.
+type FeatureCollection struct {
+	Type     string    `json:"type"` // Always "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON point feature.
+type Feature struct {
+	Type       string         `json:"type"` // Always "Feature"
+	Geometry   Point          `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Point is a GeoJSON point geometry. Coordinates are [lng, lat].
+type Point struct {
+	Type        string     `json:"type"` // Always "Point"
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// NewPoint returns a point feature at lat/lng.
+func NewPoint(lat, lng float64, props map[string]any) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Point{Type: "Point", Coordinates: [2]float64{lng, lat}},
+		Properties: props,
+	}
+}
+
+// NewCollection wraps features, never encoding a null feature list.
+func NewCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// cellsPerTile is how many grid cells span one web map tile; 4 gives ~64px clusters at 256px tiles.
+const cellsPerTile = 4
+
+// Cluster merges points that fall in the same grid cell at the given zoom level
+// (0-20, web map convention). A cell holding a single point keeps it as is;
+// otherwise it becomes one feature at the cell's mean position with
+// properties cluster=true, point_count, and ids (the merged features' "id").
+func Cluster(features []Feature, zoom int) []Feature {
+	zoom = max(0, min(zoom, 20))
+	cell := 360 / (math.Pow(2, float64(zoom)) * cellsPerTile)
+
+	type key struct{ x, y int }
+	cells := make(map[key][]Feature)
+	var order []key // Keep output stable for caching
+	for _, f := range features {
+		lng, lat := f.Geometry.Coordinates[0], f.Geometry.Coordinates[1]
+		k := key{int(math.Floor(lng / cell)), int(math.Floor(lat / cell))}
+		if _, ok := cells[k]; !ok {
+			order = append(order, k)
+		}
+		cells[k] = append(cells[k], f)
+	}
+
+	out := make([]Feature, 0, len(order))
+	for _, k := range order {
+		members := cells[k]
+		if len(members) == 1 {
+			out = append(out, members[0])
+			continue
+		}
+
+		var sumLat, sumLng float64
+		ids := make([]string, 0, len(members))
+		for _, m := range members {
+			sumLng += m.Geometry.Coordinates[0]
+			sumLat += m.Geometry.Coordinates[1]
+			if id, ok := m.Properties["id"]; ok {
+				ids = append(ids, fmt.Sprint(id))
+			}
+		}
+		sort.Strings(ids)
+		n := float64(len(members))
+		out = append(out, NewPoint(sumLat/n, sumLng/n, map[string]any{
+			"cluster":     true,
+			"point_count": len(members),
+			"ids":         ids,
+		}))
+	}
+	return out
+}
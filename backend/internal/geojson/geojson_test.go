@@ -0,0 +1,53 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCluster(t *testing.T) {
+	features := []Feature{
+		NewPoint(48.8566, 2.3522, map[string]any{"id": "paris"}),
+		NewPoint(48.8049, 2.1204, map[string]any{"id": "versailles"}),
+		NewPoint(35.6762, 139.6503, map[string]any{"id": "tokyo"}),
+	}
+
+	// World view: Paris and Versailles merge, Tokyo stays a point
+	out := Cluster(features, 2)
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 features at zoom 2, got %d", len(out))
+	}
+	c := out[0]
+	if c.Properties["cluster"] != true || c.Properties["point_count"] != 2 {
+		t.Errorf("Expected a 2-point cluster first, got %v", c.Properties)
+	}
+	if ids := c.Properties["ids"].([]string); ids[0] != "paris" || ids[1] != "versailles" {
+		t.Errorf("Unexpected cluster ids: %v", ids)
+	}
+	if lat := c.Geometry.Coordinates[1]; lat < 48.80 || lat > 48.86 {
+		t.Errorf("Cluster latitude %f outside its members", lat)
+	}
+	if out[1].Properties["id"] != "tokyo" {
+		t.Errorf("Expected tokyo unclustered, got %v", out[1].Properties)
+	}
+
+	// Street level: everything separate
+	if out := Cluster(features, 14); len(out) != 3 {
+		t.Errorf("Expected 3 features at zoom 14, got %d", len(out))
+	}
+}
+
+func TestNewCollectionEncoding(t *testing.T) {
+	data, err := json.Marshal(NewCollection(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("Unexpected encoding: %s", data)
+	}
+
+	data, _ = json.Marshal(NewPoint(1.5, -2.5, nil))
+	if string(data) != `{"type":"Feature","geometry":{"type":"Point","coordinates":[-2.5,1.5]},"properties":null}` {
+		t.Errorf("Unexpected point encoding: %s", data)
+	}
+}
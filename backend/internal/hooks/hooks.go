@@ -0,0 +1,131 @@
+// Package hooks lets deployments customize generations without forking the
+// weather service: Go plugins and webhooks register functions that the
+// pipeline runs before the prompt, after the image, after the video and on
+// errors, e.g. to add a corporate watermark or extra analytics.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/genai"
+)
+
+// Stage is a point of the generation where hooks run.
+type Stage string
+
+const (
+	BeforePrompt Stage = "before_prompt" // Hooks may change Generation.Context
+	AfterImage   Stage = "after_image"   // Hooks may change Generation.Image before it's stamped and uploaded
+	AfterVideo   Stage = "after_video"   // The media URLs are set
+	OnError      Stage = "on_error"      // Generation.Err is set
+)
+
+// Stages lists every stage, in pipeline order.
+var Stages = []Stage{BeforePrompt, AfterImage, AfterVideo, OnError}
+
+// ParseStage parses a stage name such as "after_video".
+func ParseStage(s string) (Stage, error) {
+	for _, st := range Stages {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hook stage %q", s)
+}
+
+// Generation is what hooks see of a generation.
+type Generation struct {
+	LocationID string
+	City       string
+	Context    string // Extra prompt context
+	Seed       int32
+	Image      *genai.ImageResult // From AfterImage; nil when animating a stored image
+	ImageURL   string             // From AfterVideo
+	VideoURL   string
+	PosterURL  string
+	StreamURL  string
+	Err        error // OnError
+}
+
+// Func is a hook.
+type Func func(ctx context.Context, g *Generation) error
+
+type hook struct {
+	name string
+	fn   Func
+}
+
+// Registry holds the hooks of each stage. It's safe for concurrent use, and
+// a nil Registry has no hooks.
+type Registry struct {
+	mu    sync.RWMutex
+	hooks map[Stage][]hook
+}
+
+func New() *Registry {
+	return &Registry{hooks: map[Stage][]hook{}}
+}
+
+// Open returns a registry with the Go plugins (HOOK_PLUGINS) and webhooks
+// (HOOK_WEBHOOKS) configured in cfg, or nil when there are none.
+func Open(cfg *config.Config) (*Registry, error) {
+	if len(cfg.HookPlugins) == 0 && len(cfg.HookWebhooks) == 0 {
+		return nil, nil
+	}
+	r := New()
+	for _, path := range cfg.HookPlugins {
+		if err := r.LoadPlugin(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, w := range cfg.HookWebhooks {
+		name, url, ok := strings.Cut(w, "=")
+		if !ok {
+			return nil, fmt.Errorf("HOOK_WEBHOOKS: want stage=url, got %q", w)
+		}
+		stage, err := ParseStage(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("HOOK_WEBHOOKS: %w", err)
+		}
+		url = strings.TrimSpace(url)
+		r.Register(stage, "webhook "+url, Webhook(stage, url))
+	}
+	return r, nil
+}
+
+// Register adds fn to the hooks of stage, which run in registration order.
+// name identifies it in logs.
+func (r *Registry) Register(stage Stage, name string, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[stage] = append(r.hooks[stage], hook{name: name, fn: fn})
+}
+
+// Run runs the hooks of stage on g. BeforePrompt and AfterImage hooks shape
+// what's published, so the first error stops the run and is returned: a
+// failed watermark must not publish the image without it. AfterVideo and
+// OnError hooks only observe, so their errors are logged.
+func (r *Registry) Run(ctx context.Context, stage Stage, g *Generation) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	hooks := r.hooks[stage]
+	r.mu.RUnlock()
+	for _, h := range hooks {
+		err := h.fn(ctx, g)
+		if err == nil {
+			continue
+		}
+		if stage == BeforePrompt || stage == AfterImage {
+			return fmt.Errorf("%s hook %s: %w", stage, h.name, err)
+		}
+		log.Printf("%s hook %s failed: %v", stage, h.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"banana-weather/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	r := New()
+	var calls []string
+	record := func(name string, err error) Func {
+		return func(ctx context.Context, g *Generation) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	boom := errors.New("boom")
+	r.Register(AfterImage, "a", record("a", boom))
+	r.Register(AfterImage, "b", record("b", nil))
+	r.Register(AfterVideo, "c", record("c", boom))
+	r.Register(AfterVideo, "d", record("d", nil))
+
+	if err := r.Run(context.Background(), AfterImage, &Generation{}); !errors.Is(err, boom) {
+		t.Errorf("Expected AfterImage errors to stop the run, got %v", err)
+	}
+	if err := r.Run(context.Background(), AfterVideo, &Generation{}); err != nil {
+		t.Errorf("Expected AfterVideo errors to be logged only, got %v", err)
+	}
+	if want := []string{"a", "c", "d"}; !slices.Equal(calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, calls)
+	}
+
+	var none *Registry
+	if err := none.Run(context.Background(), BeforePrompt, &Generation{}); err != nil {
+		t.Errorf("Expected a nil registry to run nothing, got %v", err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"context": "with the company mascot"}`))
+	}))
+	defer srv.Close()
+
+	g := &Generation{LocationID: "oslo", City: "Oslo", Context: "at dusk", Seed: 7}
+	if err := Webhook(BeforePrompt, srv.URL)(context.Background(), g); err != nil {
+		t.Fatal(err)
+	}
+	if got.Stage != BeforePrompt || got.LocationID != "oslo" || got.Context != "at dusk" || got.Seed != 7 {
+		t.Errorf("Unexpected payload %+v", got)
+	}
+	if g.Context != "with the company mascot" {
+		t.Errorf("Expected the response to replace the context, got %q", g.Context)
+	}
+
+	g = &Generation{City: "Oslo", Context: "at dusk", Err: errors.New("veo down")}
+	if err := Webhook(OnError, srv.URL)(context.Background(), g); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error != "veo down" || g.Context != "at dusk" {
+		t.Errorf("Expected the error posted and the context untouched, got %+v, %q", got, g.Context)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if r, err := Open(&config.Config{}); r != nil || err != nil {
+		t.Errorf("Expected no registry without hooks, got %v, %v", r, err)
+	}
+	r, err := Open(&config.Config{HookWebhooks: []string{"after_video=https://example.com/a", "on_error = https://example.com/b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.hooks[AfterVideo]) != 1 || len(r.hooks[OnError]) != 1 {
+		t.Errorf("Expected one webhook per stage, got %v", r.hooks)
+	}
+	for _, bad := range []string{"https://example.com", "after_lunch=https://example.com"} {
+		if _, err := Open(&config.Config{HookWebhooks: []string{bad}}); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
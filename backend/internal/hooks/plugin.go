@@ -0,0 +1,29 @@
+package hooks
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin opens a Go plugin (built with -buildmode=plugin against the
+// same module version) and calls its exported
+//
+//	func Register(r *hooks.Registry)
+//
+// which registers the plugin's hooks.
+func (r *Registry) LoadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("hook plugin %s: %w", path, err)
+	}
+	sym, err := p.Lookup("Register")
+	if err != nil {
+		return fmt.Errorf("hook plugin %s: %w", path, err)
+	}
+	register, ok := sym.(func(*Registry))
+	if !ok {
+		return fmt.Errorf("hook plugin %s: Register is %T, want func(*hooks.Registry)", path, sym)
+	}
+	register(r)
+	return nil
+}
@@ -0,0 +1,89 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// webhookPayload is the JSON posted by Webhook. Images aren't included; they
+// are public at image_url once uploaded.
+type webhookPayload struct {
+	Stage      Stage  `json:"stage"`
+	LocationID string `json:"location_id,omitempty"`
+	City       string `json:"city"`
+	Context    string `json:"context,omitempty"`
+	Seed       int32  `json:"seed"`
+	ImageURL   string `json:"image_url,omitempty"`
+	VideoURL   string `json:"video_url,omitempty"`
+	PosterURL  string `json:"poster_url,omitempty"`
+	StreamURL  string `json:"stream_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// maxWebhookResponse caps the BeforePrompt response read by Webhook.
+const maxWebhookResponse = 64 << 10
+
+// Webhook returns a hook posting the generation as JSON to url. A
+// BeforePrompt webhook may answer {"context": "..."} to replace the prompt
+// context; other responses are ignored. Non-2xx responses are errors.
+func Webhook(stage Stage, url string) Func {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(ctx context.Context, g *Generation) error {
+		p := webhookPayload{
+			Stage:      stage,
+			LocationID: g.LocationID,
+			City:       g.City,
+			Context:    g.Context,
+			Seed:       g.Seed,
+			ImageURL:   g.ImageURL,
+			VideoURL:   g.VideoURL,
+			PosterURL:  g.PosterURL,
+			StreamURL:  g.StreamURL,
+		}
+		if g.Err != nil {
+			p.Error = g.Err.Error()
+		}
+		body, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("webhook failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		if stage != BeforePrompt {
+			return nil
+		}
+		var out struct {
+			Context *string `json:"context"`
+		}
+		b, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(b, &out); err != nil {
+			return fmt.Errorf("webhook response: %w", err)
+		}
+		if out.Context != nil {
+			g.Context = *out.Context
+		}
+		return nil
+	}
+}
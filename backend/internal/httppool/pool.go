@@ -0,0 +1,196 @@
+// Package httppool gives each upstream API the server calls (Maps, GCS and
+// Vertex AI) one explicitly sized connection pool per process, shared by
+// every client of that API, and counts the requests sent through it so pool
+// saturation shows in GET /api/admin/pools.
+package httppool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"banana-weather/internal/config"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// Pools of the server's upstream APIs.
+const (
+	Maps   = "maps"
+	GCS    = "gcs"
+	Vertex = "vertex"
+)
+
+// cloudPlatformScope covers both GCS and Vertex AI.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Config sizes every pool. Zero values keep net/http's defaults.
+type Config struct {
+	MaxConnsPerHost     int // Connections per host, busy or idle; requests beyond it wait for one
+	MaxIdleConnsPerHost int // Idle connections kept for reuse (net/http keeps 2)
+	IdleConnTimeout     time.Duration
+}
+
+// Pools hands out one Pool per upstream API.
+type Pools struct {
+	cfg Config
+
+	mu    sync.Mutex
+	pools map[string]*Pool
+}
+
+// New returns Pools sized by cfg.
+func New(cfg Config) *Pools {
+	return &Pools{cfg: cfg, pools: map[string]*Pool{}}
+}
+
+// Open returns the Pools of HTTP_MAX_CONNS_PER_HOST,
+// HTTP_MAX_IDLE_CONNS_PER_HOST and HTTP_IDLE_CONN_TIMEOUT.
+func Open(cfg *config.Config) *Pools {
+	return New(Config{
+		MaxConnsPerHost:     cfg.HTTPMaxConnsPerHost,
+		MaxIdleConnsPerHost: cfg.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.HTTPIdleConnTimeout,
+	})
+}
+
+// Get returns the pool of name, creating it on first use.
+func (ps *Pools) Get(name string) *Pool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.pools[name]
+	if !ok {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if ps.cfg.MaxConnsPerHost > 0 {
+			t.MaxConnsPerHost = ps.cfg.MaxConnsPerHost
+		}
+		if ps.cfg.MaxIdleConnsPerHost > 0 {
+			t.MaxIdleConnsPerHost = ps.cfg.MaxIdleConnsPerHost
+			t.MaxIdleConns = max(t.MaxIdleConns, ps.cfg.MaxIdleConnsPerHost)
+		}
+		if ps.cfg.IdleConnTimeout > 0 {
+			t.IdleConnTimeout = ps.cfg.IdleConnTimeout
+		}
+		p = &Pool{name: name, transport: t, limit: ps.cfg.MaxConnsPerHost}
+		ps.pools[name] = p
+	}
+	return p
+}
+
+// Stats returns the use of every pool, by name.
+func (ps *Pools) Stats() []Stats {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make([]Stats, 0, len(ps.pools))
+	for _, p := range ps.pools {
+		out = append(out, p.Stats())
+	}
+	slices.SortFunc(out, func(a, b Stats) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Pool is an http.RoundTripper over one shared transport. A request is in
+// flight until its response body is closed.
+type Pool struct {
+	name      string
+	transport http.RoundTripper
+	limit     int // MaxConnsPerHost, 0 for unlimited
+
+	inFlight  atomic.Int64
+	peak      atomic.Int64
+	requests  atomic.Int64
+	saturated atomic.Int64
+	errors    atomic.Int64
+	newConns  atomic.Int64
+}
+
+// Stats is the use of a pool since the process started.
+type Stats struct {
+	Name         string `json:"name"`
+	InFlight     int64  `json:"in_flight"`
+	PeakInFlight int64  `json:"peak_in_flight"`
+	Requests     int64  `json:"requests"`
+	// Requests sent while as many as MaxConnsPerHost were in flight, which
+	// wait for a connection over HTTP/1.1. Most of a pool's requests go to
+	// one host, so this is close to per host.
+	Saturated       int64 `json:"saturated"`
+	Errors          int64 `json:"errors"`    // Requests that got no response
+	NewConns        int64 `json:"new_conns"` // Requests that dialed instead of reusing a connection
+	MaxConnsPerHost int   `json:"max_conns_per_host"`
+}
+
+// Client returns an HTTP client over p, for APIs authenticated by key (Maps).
+func (p *Pool) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+// GoogleClient returns an HTTP client over p that adds Application Default
+// Credentials, for GCS and Vertex AI.
+func (p *Pool) GoogleClient(ctx context.Context) (*http.Client, error) {
+	t, err := htransport.NewTransport(ctx, p, option.WithScopes(cloudPlatformScope))
+	if err != nil {
+		return nil, fmt.Errorf("%s pool: %w", p.name, err)
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// Stats returns the use of p.
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Name:            p.name,
+		InFlight:        p.inFlight.Load(),
+		PeakInFlight:    p.peak.Load(),
+		Requests:        p.requests.Load(),
+		Saturated:       p.saturated.Load(),
+		Errors:          p.errors.Load(),
+		NewConns:        p.newConns.Load(),
+		MaxConnsPerHost: p.limit,
+	}
+}
+
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := p.inFlight.Add(1)
+	p.requests.Add(1)
+	if p.limit > 0 && n > int64(p.limit) {
+		p.saturated.Add(1)
+	}
+	for peak := p.peak.Load(); n > peak && !p.peak.CompareAndSwap(peak, n); peak = p.peak.Load() {
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				p.newConns.Add(1)
+			}
+		},
+	}))
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		p.errors.Add(1)
+		p.inFlight.Add(-1)
+		return nil, err
+	}
+	resp.Body = &body{ReadCloser: resp.Body, done: func() { p.inFlight.Add(-1) }}
+	return resp, nil
+}
+
+// body ends its request when closed.
+type body struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
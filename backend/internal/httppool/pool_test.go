@@ -0,0 +1,86 @@
+package httppool
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	pools := New(Config{MaxConnsPerHost: 1, MaxIdleConnsPerHost: 4})
+	pool := pools.Get(Maps)
+	if pools.Get(Maps) != pool {
+		t.Fatal("Expected one pool per name")
+	}
+	client := pool.Client()
+
+	// The first response is still open when the second request is sent
+	first, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.InFlight != 1 {
+		t.Errorf("Expected the open response in flight, got %+v", s)
+	}
+	io.ReadAll(first.Body)
+	first.Body.Close()
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if _, err := client.Get("http://127.0.0.1:1"); err == nil {
+		t.Error("Expected a connection error")
+	}
+
+	s := pool.Stats()
+	if s.InFlight != 0 || s.PeakInFlight != 1 || s.Requests != 5 || s.Errors != 1 || s.MaxConnsPerHost != 1 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+	if s.NewConns != 1 {
+		t.Errorf("Expected the connection reused, got %d dials", s.NewConns)
+	}
+	if stats := pools.Stats(); len(stats) != 1 || stats[0].Name != Maps {
+		t.Errorf("Unexpected pools %+v", stats)
+	}
+}
+
+func TestPool_Saturated(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	pool := New(Config{MaxConnsPerHost: 1}).Get(Vertex)
+	client := pool.Client()
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			if resp, err := client.Get(srv.URL); err == nil {
+				resp.Body.Close()
+			}
+			done <- struct{}{}
+		}()
+	}
+	// Wait until both requests entered the pool; one waits for the connection
+	for pool.Stats().InFlight < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+	<-done
+	if s := pool.Stats(); s.Saturated != 1 || s.PeakInFlight != 2 || s.InFlight != 0 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+}
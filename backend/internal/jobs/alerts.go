@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/notify"
+)
+
+// AlertStore is the part of the repository the alert evaluator needs.
+type AlertStore interface {
+	GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error)
+	UpdateRuntimeSettings(ctx context.Context, update func(s *database.RuntimeSettings)) (*database.RuntimeSettings, error)
+	ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error)
+}
+
+// Alerts checks generation failure rate and latency over a sliding window
+// against the thresholds in the runtime settings, notifying operators when
+// an alert starts and when it resolves.
+type Alerts struct {
+	DB       AlertStore
+	Notifier notify.Notifier // Log when nil
+	Clock    clock.Clock     // The system clock when nil
+}
+
+func (j *Alerts) now() time.Time {
+	if j.Clock == nil {
+		return time.Now()
+	}
+	return j.Clock.Now()
+}
+
+// Evaluate runs one check, e.g. every few minutes from Cloud Scheduler. With
+// AutoDegrade, a new alert also turns on image-only mode. It stays on after
+// the alert resolves: without Veo calls, video failures stop by themselves,
+// so an operator decides when to turn it off.
+func (j *Alerts) Evaluate(ctx context.Context) (*database.AlertReport, error) {
+	settings, err := j.DB.GetRuntimeSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime settings: %w", err)
+	}
+	th := settings.Alerts
+	report := &database.AlertReport{Reasons: []string{}}
+	if th.Enabled() {
+		since := j.now().Add(-th.Window())
+		samples, err := j.DB.ListLatencySamples(ctx, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read latency stats: %w", err)
+		}
+		report.SLO = database.SummarizeLatency(samples, since)
+		report.Reasons = exceeded(report, th)
+		report.Firing = len(report.Reasons) > 0
+	}
+
+	report.Degraded = settings.DegradeImageOnly
+	if report.Firing == settings.AlertFiring {
+		return report, nil
+	}
+
+	// Decided against the settings as saved, so a concurrent evaluation
+	// notifies once, and saved first, so a failing notifier doesn't repeat
+	// the alert every run. Only the alert fields change; the kill switch and
+	// operator changes meanwhile are kept.
+	subject, message := "", ""
+	settings, err = j.DB.UpdateRuntimeSettings(ctx, func(s *database.RuntimeSettings) {
+		subject, message = "", ""
+		switch {
+		case report.Firing && !s.AlertFiring:
+			s.AlertFiring = true
+			subject, message = "Generation alert", strings.Join(report.Reasons, "\n")
+			if th.AutoDegrade && !s.DegradeImageOnly {
+				s.DegradeImageOnly = true
+				s.DegradeReason = "alert: " + report.Reasons[0]
+				message += "\nImage-only mode is now on; turn it off with `banana admin runtime --degrade-image-only=false`."
+			}
+		case !report.Firing && s.AlertFiring:
+			s.AlertFiring = false
+			subject, message = "Generation alert resolved", "Failure rate and latency are back within thresholds."
+			if s.DegradeImageOnly {
+				message += "\nImage-only mode is still on."
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save runtime settings: %w", err)
+	}
+	report.Degraded = settings.DegradeImageOnly
+	if subject == "" {
+		return report, nil
+	}
+	n := j.Notifier
+	if n == nil {
+		n = notify.Log{}
+	}
+	if err := n.Notify(ctx, subject, message); err != nil {
+		log.Printf("Failed to send %q: %v", subject, err)
+	} else {
+		report.Notified = true
+	}
+	return report, nil
+}
+
+// exceeded fills in the failure rate of report and returns the thresholds
+// its window exceeds, or none with too few generations to tell.
+func exceeded(report *database.AlertReport, th database.AlertThresholds) []string {
+	failures := 0
+	for _, s := range report.SLO.Stages {
+		report.Generations += s.Count + s.Errors
+		failures += s.Errors
+	}
+	if report.Generations == 0 {
+		return []string{}
+	}
+	report.FailureRate = float64(failures) / float64(report.Generations)
+	if report.Generations < th.Samples() {
+		return []string{}
+	}
+
+	reasons := []string{}
+	window := th.Window()
+	if th.MaxFailureRate > 0 && report.FailureRate > th.MaxFailureRate {
+		reasons = append(reasons, fmt.Sprintf("Failure rate %.0f%% of %d generations over %s exceeds %.0f%%",
+			report.FailureRate*100, report.Generations, window, th.MaxFailureRate*100))
+	}
+	for _, s := range report.SLO.Stages {
+		limit := th.MaxImageP95
+		if s.Stage == database.LatencyVideo {
+			limit = th.MaxVideoP95
+		}
+		if limit > 0 && s.Count > 0 && s.P95 > limit {
+			reasons = append(reasons, fmt.Sprintf("P95 %s latency %.1fs over %s exceeds %.0fs", s.Stage, s.P95, window, limit))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+)
+
+type fakeAlertDB struct {
+	settings database.RuntimeSettings
+	samples  []database.LatencySample
+	since    time.Time
+}
+
+func (f *fakeAlertDB) GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error) {
+	s := f.settings
+	return &s, nil
+}
+func (f *fakeAlertDB) UpdateRuntimeSettings(ctx context.Context, update func(s *database.RuntimeSettings)) (*database.RuntimeSettings, error) {
+	update(&f.settings)
+	s := f.settings
+	return &s, nil
+}
+func (f *fakeAlertDB) ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error) {
+	f.since = since
+	return f.samples, nil
+}
+
+type fakeNotifier struct{ subjects []string }
+
+func (f *fakeNotifier) Notify(ctx context.Context, subject, message string) error {
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+// videos returns n video samples, the first failed of them failed.
+func videos(n, failed int) []database.LatencySample {
+	var out []database.LatencySample
+	for i := range n {
+		outcome := database.LatencyOK
+		if i < failed {
+			outcome = database.LatencyError
+		}
+		out = append(out, database.LatencySample{Stage: database.LatencyVideo, Outcome: outcome, DurationMS: 60_000})
+	}
+	return out
+}
+
+func TestAlerts_FireOnceAndResolve(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	db := &fakeAlertDB{
+		settings: database.RuntimeSettings{Alerts: database.AlertThresholds{WindowMinutes: 30, MaxFailureRate: 0.2, AutoDegrade: true}},
+		samples:  videos(20, 10),
+	}
+	n := &fakeNotifier{}
+	job := &Alerts{DB: db, Notifier: n, Clock: clock.NewFake(now)}
+
+	report, err := job.Evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Firing || !report.Notified || !report.Degraded || report.FailureRate != 0.5 {
+		t.Errorf("Expected a notified, degrading alert at 50%%, got %+v", report)
+	}
+	if !db.since.Equal(now.Add(-30 * time.Minute)) {
+		t.Errorf("Expected a 30m window, got samples since %v", db.since)
+	}
+	if !db.settings.AlertFiring || !db.settings.DegradeImageOnly || !strings.HasPrefix(db.settings.DegradeReason, "alert: Failure rate 50%") {
+		t.Errorf("Expected the alert and image-only mode saved, got %+v", db.settings)
+	}
+
+	// Still failing: no second notification
+	if report, _ = job.Evaluate(context.Background()); !report.Firing || report.Notified {
+		t.Errorf("Expected the ongoing alert not to notify again, got %+v", report)
+	}
+
+	db.samples = videos(20, 1)
+	if report, _ = job.Evaluate(context.Background()); report.Firing || !report.Notified {
+		t.Errorf("Expected the resolution to notify, got %+v", report)
+	}
+	if db.settings.AlertFiring || !db.settings.DegradeImageOnly {
+		t.Errorf("Expected the alert cleared and image-only mode kept, got %+v", db.settings)
+	}
+	if want := "Generation alert,Generation alert resolved"; strings.Join(n.subjects, ",") != want {
+		t.Errorf("Expected %q, got %v", want, n.subjects)
+	}
+}
+
+// switchingAlertDB turns the kill switch on right after the evaluator read
+// the runtime settings, as an operator could.
+type switchingAlertDB struct{ *fakeAlertDB }
+
+func (f switchingAlertDB) GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error) {
+	s, err := f.fakeAlertDB.GetRuntimeSettings(ctx)
+	f.settings.KillSwitch = database.KillSwitch{On: true, Reason: "veo quota incident"}
+	return s, err
+}
+
+func TestAlerts_KeepsConcurrentChanges(t *testing.T) {
+	db := &fakeAlertDB{
+		settings: database.RuntimeSettings{Alerts: database.AlertThresholds{MaxFailureRate: 0.2}},
+		samples:  videos(20, 10),
+	}
+	job := &Alerts{DB: switchingAlertDB{db}, Notifier: &fakeNotifier{}}
+	if report, err := job.Evaluate(context.Background()); err != nil || !report.Notified {
+		t.Fatalf("Expected the alert notified, got %+v, %v", report, err)
+	}
+	if !db.settings.AlertFiring || !db.settings.KillSwitch.On {
+		t.Errorf("Expected the alert saved and the kill switch kept, got %+v", db.settings)
+	}
+}
+
+func TestAlerts_Thresholds(t *testing.T) {
+	slow := videos(10, 0)
+	slow[9].DurationMS = 400_000
+	for _, tc := range []struct {
+		name    string
+		th      database.AlertThresholds
+		samples []database.LatencySample
+		firing  bool
+	}{
+		{"off", database.AlertThresholds{}, videos(20, 20), false},
+		{"too few samples", database.AlertThresholds{MaxFailureRate: 0.1}, videos(5, 5), false},
+		{"min samples", database.AlertThresholds{MaxFailureRate: 0.1, MinSamples: 5}, videos(5, 5), true},
+		{"within rate", database.AlertThresholds{MaxFailureRate: 0.2}, videos(20, 4), false},
+		{"video p95", database.AlertThresholds{MaxVideoP95: 120}, slow, true},
+		{"image p95 ignores videos", database.AlertThresholds{MaxImageP95: 120}, slow, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &fakeAlertDB{settings: database.RuntimeSettings{Alerts: tc.th}, samples: tc.samples}
+			report, err := (&Alerts{DB: db, Notifier: &fakeNotifier{}}).Evaluate(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Firing != tc.firing || db.settings.DegradeImageOnly {
+				t.Errorf("Expected firing=%t without degrading, got %+v", tc.firing, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/weather"
+)
+
+// BatchStore is the part of the repository the batch job needs.
+type BatchStore interface {
+	GetLocation(ctx context.Context, id string) (*database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	SetStatus(ctx context.Context, id string, status database.LocationStatus) error
+	ListRunItems(ctx context.Context, runID string) ([]database.RunItem, error)
+	SetRunItem(ctx context.Context, runID string, item database.RunItem) error
+}
+
+// PresetGenerator generates a preset's media. *pipeline.Pipeline implements it.
+type PresetGenerator interface {
+	Generate(ctx context.Context, req pipeline.Request, opts ...pipeline.Option) (*pipeline.Result, error)
+}
+
+// Geocoder resolves a city query. *maps.Service implements it.
+type Geocoder interface {
+	GetCityLocation(ctx context.Context, query string) (*maps.Place, error)
+}
+
+// Preset is a row of a preset CSV.
+type Preset struct {
+	ID, Name, City, Category string
+	Context                  string // Extra image prompt context; optional
+}
+
+// ParsePresetCSV parses a preset CSV, as banana generate --csv reads:
+// id,name,city,category[,context] with a header row. Short rows are
+// skipped, like the CLI does.
+func ParsePresetCSV(r io.Reader) ([]Preset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	var out []Preset
+	for i, row := range records {
+		if i == 0 || len(row) < 4 {
+			continue
+		}
+		p := Preset{ID: strings.TrimSpace(row[0]), Name: row[1], City: row[2], Category: row[3]}
+		if len(row) > 4 {
+			p.Context = row[4]
+		}
+		if p.ID == "" {
+			return nil, fmt.Errorf("row %d has no id", i+1)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Batch generates the presets of a run, as banana generate --csv does, for
+// one task of a sharded job (Cloud Run Jobs or an indexed GKE Job). Each
+// preset's progress is recorded as an item of the run, so a retried task
+// skips the presets an earlier attempt finished.
+type Batch struct {
+	DB        BatchStore
+	Generator PresetGenerator
+	Maps      Geocoder              // Locations are saved without coordinates when nil
+	Runtime   weather.RuntimeSource // Kill switch, checked once per run; not checked when nil
+	Tenant    string                // Whose kill switch applies besides the global one
+	Clock     clock.Clock           // The system clock when nil
+	Task      int                   // Index of this task; it handles presets i with i%Tasks == Task
+	Tasks     int                   // Number of tasks; 0 is 1
+}
+
+// BatchReport counts the presets a task handled.
+type BatchReport struct {
+	Generated int `json:"generated"`
+	Updated   int `json:"updated"` // Existing presets whose metadata was updated without generating
+	Resumed   int `json:"resumed"` // Done by an earlier attempt
+	Failed    int `json:"failed"`
+}
+
+func (j *Batch) now() time.Time {
+	if j.Clock == nil {
+		return time.Now()
+	}
+	return j.Clock.Now()
+}
+
+// Run generates this task's share of presets for run. Existing presets only
+// get their metadata updated unless the run forces them, and locked ones are
+// never overwritten. Presets that fail are recorded and skipped; Run then
+// returns an error with the report, so the task exits as failed and its
+// retry resumes them. It stops early when ctx is cancelled, and doesn't
+// start while the kill switch is on: it returns weather.ErrHalted, so a
+// later retry runs the presets once the switch is off.
+func (j *Batch) Run(ctx context.Context, run database.Run, presets []Preset) (*BatchReport, error) {
+	if err := j.checkHalted(ctx); err != nil {
+		return nil, err
+	}
+	items, err := j.DB.ListRunItems(ctx, run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the progress of run %s: %w", run.ID, err)
+	}
+	previous := map[string]database.RunItem{}
+	for _, item := range items {
+		previous[item.ID] = item
+	}
+	tasks := max(j.Tasks, 1)
+
+	report := &BatchReport{}
+	for i, p := range presets {
+		if i%tasks != j.Task {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		item := previous[p.ID]
+		if item.Status == database.RunItemDone {
+			report.Resumed++
+			continue
+		}
+		item = database.RunItem{ID: p.ID, Status: database.RunItemRunning, Task: j.Task, Attempts: item.Attempts + 1}
+		if err := j.DB.SetRunItem(ctx, run.ID, item); err != nil {
+			return report, fmt.Errorf("failed to record progress of %s: %w", p.ID, err)
+		}
+
+		log.Printf("Run %s task %d: processing [%d/%d] %s (%s)", run.ID, j.Task, i+1, len(presets), p.Name, p.ID)
+		generated, imageURL, err := j.preset(ctx, p, run.Force)
+		switch {
+		case err != nil:
+			log.Printf("Run %s: %s failed: %v", run.ID, p.ID, err)
+			item.Status, item.Error = database.RunItemFailed, err.Error()
+			report.Failed++
+		case generated:
+			item.Status, item.ImageURL = database.RunItemDone, imageURL
+			report.Generated++
+		default:
+			item.Status = database.RunItemDone
+			report.Updated++
+		}
+		if err := j.DB.SetRunItem(ctx, run.ID, item); err != nil {
+			return report, fmt.Errorf("failed to record progress of %s: %w", p.ID, err)
+		}
+	}
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%d presets failed", report.Failed)
+	}
+	return report, nil
+}
+
+// checkHalted returns weather.ErrHalted, with the switch's reason, while the
+// kill switch of the batch's tenant is on.
+func (j *Batch) checkHalted(ctx context.Context) error {
+	if j.Runtime == nil {
+		return nil
+	}
+	rs, err := j.Runtime.GetRuntimeSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read runtime settings: %w", err)
+	}
+	k := rs.Halted(j.Tenant, j.now())
+	switch {
+	case k == nil:
+		return nil
+	case k.Reason == "":
+		return weather.ErrHalted
+	}
+	return fmt.Errorf("%w: %s", weather.ErrHalted, k.Reason)
+}
+
+// preset generates and saves one preset, or only updates the metadata of an
+// existing one it can't overwrite. It returns whether media was generated.
+func (j *Batch) preset(ctx context.Context, p Preset, force bool) (bool, string, error) {
+	existing, err := j.DB.GetLocation(ctx, p.ID)
+	exists := err == nil && existing != nil
+	promptContext := p.Context
+	if promptContext == "" && exists {
+		promptContext = existing.PromptContext
+	}
+
+	if exists && (!force || existing.Locked) {
+		if existing.Locked && force {
+			log.Printf("%s is locked; updating its metadata only.", p.ID)
+		}
+		existing.Name, existing.Category, existing.PromptContext, existing.IsPreset = p.Name, p.Category, promptContext, true
+		if existing.Geo == nil {
+			existing.CityQuery = p.City
+			j.setGeo(ctx, existing)
+		}
+		return false, "", j.DB.UpsertLocation(ctx, *existing)
+	}
+
+	res, err := j.Generator.Generate(ctx, pipeline.Request{
+		ID:       p.ID,
+		City:     p.City,
+		Context:  promptContext,
+		FileName: fmt.Sprintf("preset_%s_image_%d.png", p.ID, j.now().Unix()),
+	}, pipeline.WithStyle(0))
+	if err != nil {
+		if exists {
+			j.DB.SetStatus(ctx, p.ID, database.StatusFailed)
+		}
+		return false, "", err
+	}
+
+	loc := database.Location{
+		ID:            p.ID,
+		Name:          p.Name,
+		Category:      p.Category,
+		CityQuery:     p.City,
+		ImageURL:      res.ImageURL,
+		VideoURL:      res.VideoURL,
+		PosterURL:     res.PosterURL,
+		StreamURL:     res.StreamURL,
+		Checksums:     res.Checksums,
+		AltText:       res.AltText,
+		PromptContext: promptContext,
+		IsPreset:      true,
+		Seed:          &res.Seed,
+		Generation:    res.Metadata(),
+		Status:        database.StatusReady,
+	}
+	j.setGeo(ctx, &loc)
+	if err := j.DB.UpsertLocation(ctx, loc); err != nil {
+		return false, "", fmt.Errorf("failed to save: %w", err)
+	}
+	return true, res.ImageURL, nil
+}
+
+// setGeo geocodes loc's city query, leaving places without a country (like
+// fictional ones) without coordinates.
+func (j *Batch) setGeo(ctx context.Context, loc *database.Location) {
+	if j.Maps == nil {
+		return
+	}
+	place, err := j.Maps.GetCityLocation(ctx, loc.CityQuery)
+	if err != nil || place.CountryCode == "" {
+		log.Printf("No coordinates for %s: no country for %q", loc.ID, loc.CityQuery)
+		return
+	}
+	loc.Geo, loc.CountryCode, loc.Continent = place.LatLng(), place.CountryCode, place.Continent
+}
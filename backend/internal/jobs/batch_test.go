@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/weather"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeBatchDB struct {
+	locations map[string]database.Location
+	items     map[string]database.RunItem
+	statuses  map[string]database.LocationStatus
+}
+
+func newFakeBatchDB() *fakeBatchDB {
+	return &fakeBatchDB{
+		locations: map[string]database.Location{},
+		items:     map[string]database.RunItem{},
+		statuses:  map[string]database.LocationStatus{},
+	}
+}
+
+func (f *fakeBatchDB) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	loc, ok := f.locations[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "location %s not found", id)
+	}
+	return &loc, nil
+}
+func (f *fakeBatchDB) UpsertLocation(ctx context.Context, loc database.Location) error {
+	f.locations[loc.ID] = loc
+	return nil
+}
+func (f *fakeBatchDB) SetStatus(ctx context.Context, id string, s database.LocationStatus) error {
+	f.statuses[id] = s
+	return nil
+}
+func (f *fakeBatchDB) ListRunItems(ctx context.Context, runID string) ([]database.RunItem, error) {
+	var out []database.RunItem
+	for _, item := range f.items {
+		out = append(out, item)
+	}
+	return out, nil
+}
+func (f *fakeBatchDB) SetRunItem(ctx context.Context, runID string, item database.RunItem) error {
+	f.items[item.ID] = item
+	return nil
+}
+
+type fakeGenerator struct {
+	calls []string
+	fail  map[string]bool
+}
+
+func (f *fakeGenerator) Generate(ctx context.Context, req pipeline.Request, opts ...pipeline.Option) (*pipeline.Result, error) {
+	f.calls = append(f.calls, req.ID)
+	if f.fail[req.ID] {
+		return nil, errors.New("image gen failed")
+	}
+	return &pipeline.Result{ImageURL: "https://media.test/" + req.FileName, VideoURL: "https://media.test/" + req.ID + ".mp4"}, nil
+}
+
+func TestParsePresetCSV(t *testing.T) {
+	presets, err := ParsePresetCSV(strings.NewReader("id,name,city,category,context\n" +
+		"porto,Porto,\"Porto, Portugal\",Europe,Ribeira at dusk\n" +
+		"short,row\n" +
+		"lima,Lima,Lima,Americas\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(presets) != 2 || presets[0] != (Preset{ID: "porto", Name: "Porto", City: "Porto, Portugal", Category: "Europe", Context: "Ribeira at dusk"}) ||
+		presets[1].ID != "lima" || presets[1].Context != "" {
+		t.Errorf("Unexpected presets: %+v", presets)
+	}
+	if _, err := ParsePresetCSV(strings.NewReader("id,name,city,category\n,Nowhere,Nowhere,None\n")); err == nil {
+		t.Error("Expected an error for a row without an id")
+	}
+}
+
+func TestBatchRun(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeBatchDB()
+	db.locations["lima"] = database.Location{ID: "lima", Name: "Old Lima", ImageURL: "old.png", PromptContext: "Miraflores"}
+	db.locations["oslo"] = database.Location{ID: "oslo", ImageURL: "locked.png", Locked: true}
+	gen := &fakeGenerator{fail: map[string]bool{"cairo": true}}
+	presets := []Preset{
+		{ID: "porto", Name: "Porto", City: "Porto", Category: "Europe"},
+		{ID: "lima", Name: "Lima", City: "Lima", Category: "Americas"},
+		{ID: "cairo", Name: "Cairo", City: "Cairo", Category: "Africa"},
+		{ID: "oslo", Name: "Oslo", City: "Oslo", Category: "Europe"},
+	}
+	job := &Batch{DB: db, Generator: gen}
+
+	report, err := job.Run(ctx, database.Run{ID: "run-1"}, presets)
+	if err == nil {
+		t.Error("Expected an error when a preset fails")
+	}
+	if *report != (BatchReport{Generated: 1, Updated: 2, Failed: 1}) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if strings.Join(gen.calls, ",") != "porto,cairo" {
+		t.Errorf("Expected only new presets generated, got %v", gen.calls)
+	}
+	if loc := db.locations["porto"]; !loc.IsPreset || loc.Status != database.StatusReady || loc.Seed == nil || loc.Category != "Europe" {
+		t.Errorf("Unexpected porto: %+v", loc)
+	}
+	if loc := db.locations["lima"]; loc.ImageURL != "old.png" || loc.Name != "Lima" || loc.PromptContext != "Miraflores" || !loc.IsPreset {
+		t.Errorf("Expected only lima's metadata updated, got %+v", loc)
+	}
+	if item := db.items["cairo"]; item.Status != database.RunItemFailed || item.Error != "image gen failed" || item.Attempts != 1 {
+		t.Errorf("Unexpected cairo item: %+v", item)
+	}
+	if item := db.items["porto"]; item.Status != database.RunItemDone || !strings.HasPrefix(item.ImageURL, "https://media.test/preset_porto_image_") {
+		t.Errorf("Unexpected porto item: %+v", item)
+	}
+
+	// The retry skips presets already done; lima and oslo are redone to
+	// check that force regenerates existing presets except locked ones.
+	gen.calls, gen.fail = nil, nil
+	delete(db.items, "lima")
+	delete(db.items, "oslo")
+	report, err = job.Run(ctx, database.Run{ID: "run-1", Force: true}, presets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *report != (BatchReport{Generated: 2, Updated: 1, Resumed: 1}) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if strings.Join(gen.calls, ",") != "lima,cairo" {
+		t.Errorf("Unexpected generations: %v", gen.calls)
+	}
+	if item := db.items["cairo"]; item.Status != database.RunItemDone || item.Attempts != 2 || item.Error != "" {
+		t.Errorf("Unexpected cairo item: %+v", item)
+	}
+	if db.locations["oslo"].ImageURL != "locked.png" {
+		t.Error("Expected the locked preset kept")
+	}
+}
+
+func TestBatchRunShards(t *testing.T) {
+	presets := []Preset{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	var handled []string
+	for task := range 2 {
+		gen := &fakeGenerator{}
+		job := &Batch{DB: newFakeBatchDB(), Generator: gen, Task: task, Tasks: 2}
+		if _, err := job.Run(context.Background(), database.Run{ID: "run-2"}, presets); err != nil {
+			t.Fatal(err)
+		}
+		handled = append(handled, strings.Join(gen.calls, ""))
+	}
+	if strings.Join(handled, "|") != "ace|bd" {
+		t.Errorf("Unexpected shards: %v", handled)
+	}
+}
+
+func TestBatchRunHalted(t *testing.T) {
+	ctx := context.Background()
+	db, gen := newFakeBatchDB(), &fakeGenerator{}
+	runtime := &fakeAlertDB{}
+	runtime.settings.SetKillSwitch("acme", database.KillSwitch{On: true, Reason: "Veo incident"})
+	presets := []Preset{{ID: "porto", Name: "Porto", City: "Porto"}}
+
+	job := &Batch{DB: db, Generator: gen, Runtime: runtime, Tenant: "acme"}
+	if _, err := job.Run(ctx, database.Run{ID: "run-3"}, presets); !errors.Is(err, weather.ErrHalted) || !strings.Contains(err.Error(), "Veo incident") {
+		t.Fatalf("Expected ErrHalted with the reason, got %v", err)
+	}
+	if len(gen.calls) != 0 || len(db.items) != 0 {
+		t.Errorf("Expected nothing generated or recorded, got %v and %v", gen.calls, db.items)
+	}
+
+	job.Tenant = "other"
+	if _, err := job.Run(ctx, database.Run{ID: "run-3"}, presets); err != nil {
+		t.Fatalf("Expected another tenant's switch to be ignored, got %v", err)
+	}
+	if len(gen.calls) != 1 {
+		t.Errorf("Expected porto generated, got %v", gen.calls)
+	}
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+)
+
+// CategoryCoverStore is the part of the repository the cover job needs.
+type CategoryCoverStore interface {
+	GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error)
+	ListCategories(ctx context.Context) ([]database.Category, error)
+	SetCategories(ctx context.Context, categories []database.Category) error
+}
+
+// CoverGenerator renders a category's cover. *genai.Service implements it.
+type CoverGenerator interface {
+	GenerateCover(ctx context.Context, category string, cities []string, seed *int32) (*genai.ImageResult, error)
+}
+
+// ImageUploader stores an image and returns (objectURI, publicURL). storage.Store implements it.
+type ImageUploader interface {
+	UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error)
+}
+
+// CategoryCovers generates a cover per category: a collage of its presets'
+// cities, saved as the category's cover_url for the gallery's section
+// headers.
+type CategoryCovers struct {
+	DB      CategoryCoverStore
+	GenAI   CoverGenerator
+	Storage ImageUploader
+	Clock   clock.Clock // The system clock when nil
+}
+
+func (j *CategoryCovers) now() time.Time {
+	if j.Clock == nil {
+		return time.Now()
+	}
+	return j.Clock.Now()
+}
+
+// Run generates covers for the named categories, or for every category
+// with presets when names is empty. A category without an entry gets one
+// at the end of the display order. Covers that fail are logged and
+// skipped; the others are saved, and the failures returned together.
+func (j *CategoryCovers) Run(ctx context.Context, names ...string) ([]database.Category, error) {
+	presets, err := j.DB.GetPresetSummaries(ctx, database.PresetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+	categories, err := j.DB.ListCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	cities := map[string][]string{}
+	var found []string
+	for _, p := range presets {
+		if p.Category == "" {
+			continue
+		}
+		if _, ok := cities[p.Category]; !ok {
+			found = append(found, p.Category)
+		}
+		cities[p.Category] = append(cities[p.Category], p.Name)
+	}
+	if len(names) == 0 {
+		names = found
+	}
+
+	var errs []error
+	var done int
+	for _, name := range names {
+		if len(cities[name]) == 0 {
+			errs = append(errs, fmt.Errorf("category %q has no presets", name))
+			continue
+		}
+		cover, err := j.cover(ctx, name, cities[name])
+		if err != nil {
+			log.Printf("Cover of %s failed: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		categories = setCover(categories, name, cover)
+		done++
+	}
+	if done > 0 {
+		if err := j.DB.SetCategories(ctx, categories); err != nil {
+			return nil, fmt.Errorf("failed to save categories: %w", err)
+		}
+	}
+	return categories, errors.Join(errs...)
+}
+
+// cover generates and uploads the cover of a category. Only the cover
+// fields of the result are set.
+func (j *CategoryCovers) cover(ctx context.Context, name string, cities []string) (database.Category, error) {
+	if len(cities) > genai.MaxCoverCities {
+		cities = cities[:genai.MaxCoverCities]
+	}
+	img, err := j.GenAI.GenerateCover(ctx, name, cities, nil)
+	if err != nil {
+		return database.Category{}, err
+	}
+	now := j.now()
+	fileName := fmt.Sprintf("covers/%s_%d.png", coverSlug(name), now.Unix())
+	_, url, err := j.Storage.UploadImage(ctx, img.Image(), fileName)
+	if err != nil {
+		return database.Category{}, fmt.Errorf("upload failed: %w", err)
+	}
+	log.Printf("Cover of %s (%s): %s", name, strings.Join(cities, ", "), url)
+	return database.Category{CoverURL: url, CoverCities: cities, CoverUpdated: now}, nil
+}
+
+// setCover copies the cover fields of c to the category called name,
+// appending it if it isn't listed.
+func setCover(categories []database.Category, name string, c database.Category) []database.Category {
+	for i := range categories {
+		if categories[i].Name == name {
+			categories[i].CoverURL, categories[i].CoverCities, categories[i].CoverUpdated = c.CoverURL, c.CoverCities, c.CoverUpdated
+			return categories
+		}
+	}
+	order := 0
+	for _, cat := range categories {
+		order = max(order, cat.Order+1)
+	}
+	c.Name, c.Order = name, order
+	return append(categories, c)
+}
+
+// coverSlug makes a category name usable in an object name.
+func coverSlug(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+)
+
+type fakeCoverDB struct {
+	presets    []database.PresetSummary
+	categories []database.Category
+	saved      int
+}
+
+func (f *fakeCoverDB) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	return f.presets, nil
+}
+func (f *fakeCoverDB) ListCategories(ctx context.Context) ([]database.Category, error) {
+	return append([]database.Category(nil), f.categories...), nil
+}
+func (f *fakeCoverDB) SetCategories(ctx context.Context, categories []database.Category) error {
+	f.categories = categories
+	f.saved++
+	return nil
+}
+
+type fakeCovers struct {
+	cities map[string][]string
+	fail   string
+}
+
+func (f *fakeCovers) GenerateCover(ctx context.Context, category string, cities []string, seed *int32) (*genai.ImageResult, error) {
+	if category == f.fail {
+		return nil, errors.New("blocked")
+	}
+	f.cities[category] = cities
+	return &genai.ImageResult{Images: []string{"aW1n"}}, nil
+}
+
+type fakeImageUploads struct{ names []string }
+
+func (f *fakeImageUploads) UploadImage(ctx context.Context, data, name string) (string, string, error) {
+	f.names = append(f.names, name)
+	return "gs://bucket/" + name, "https://storage.googleapis.com/bucket/" + name, nil
+}
+
+func TestCategoryCovers(t *testing.T) {
+	var presets []database.PresetSummary
+	for i := range genai.MaxCoverCities + 2 {
+		presets = append(presets, database.PresetSummary{Name: "Euro " + string(rune('A'+i)), Category: "Europe"})
+	}
+	presets = append(presets,
+		database.PresetSummary{Name: "Arrakis", Category: "Fictional"},
+		database.PresetSummary{Name: "Kyoto", Category: "Asia / Pacific"},
+		database.PresetSummary{Name: "Nowhere"},
+	)
+	db := &fakeCoverDB{presets: presets, categories: []database.Category{{Name: "Fictional", Order: 0}, {Name: "Europe", Order: 1}}}
+	gen := &fakeCovers{cities: map[string][]string{}}
+	store := &fakeImageUploads{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	j := &CategoryCovers{DB: db, GenAI: gen, Storage: store, Clock: clock.NewFake(now)}
+
+	categories, err := j.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.cities["Europe"]) != genai.MaxCoverCities || gen.cities["Europe"][0] != "Euro A" {
+		t.Errorf("Europe cover cities = %v", gen.cities["Europe"])
+	}
+	if len(gen.cities) != 3 {
+		t.Errorf("covers generated for %v, want every category with presets", gen.cities)
+	}
+	if db.saved != 1 || len(categories) != 3 {
+		t.Fatalf("saved %d times: %+v", db.saved, db.categories)
+	}
+	// Existing entries keep their order; new ones go last
+	want := map[string]int{"Fictional": 0, "Europe": 1, "Asia / Pacific": 2}
+	for _, c := range db.categories {
+		if c.Order != want[c.Name] || !strings.HasPrefix(c.CoverURL, "https://") || !c.CoverUpdated.Equal(now) || len(c.CoverCities) == 0 {
+			t.Errorf("category = %+v", c)
+		}
+	}
+	if !strings.Contains(strings.Join(store.names, " "), "covers/asia___pacific_") {
+		t.Errorf("uploaded %v", store.names)
+	}
+
+	// Failures don't lose the others
+	gen.fail = "Fictional"
+	db.saved = 0
+	_, err = j.Run(context.Background(), "Europe", "Fictional", "Atlantis")
+	if err == nil || !strings.Contains(err.Error(), "blocked") || !strings.Contains(err.Error(), "Atlantis") {
+		t.Errorf("err = %v", err)
+	}
+	if db.saved != 1 {
+		t.Errorf("saved %d times, want Europe's cover saved", db.saved)
+	}
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+)
+
+// CityOfTheDayStore is the part of the repository the city of the day job needs.
+type CityOfTheDayStore interface {
+	GetLocation(ctx context.Context, id string) (*database.Location, error)
+	GetPresets(ctx context.Context) ([]database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	SetCityOfTheDay(ctx context.Context, f database.FeaturedCity) error
+	ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error)
+}
+
+// Refresher regenerates a location's media. *weather.Service implements it.
+type Refresher interface {
+	RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error)
+}
+
+// CityOfTheDay picks a preset as the city of the day, regenerates it with the
+// classic style (unless its media was attached by hand or it's locked),
+// marks it featured and records it in the history.
+type CityOfTheDay struct {
+	DB        CityOfTheDayStore
+	Refresher Refresher
+	AvoidDays int             // Picks aren't repeated within this many days
+	Clock     clock.Clock     // The system clock when nil
+	Rand      func(n int) int // Picks an index in [0, n); math/rand when nil
+	Push      *Fanout         // Optional: announces the pick to devices
+}
+
+func (j *CityOfTheDay) now() time.Time {
+	if j.Clock == nil {
+		return time.Now()
+	}
+	return j.Clock.Now()
+}
+
+// Run selects today's city. With an empty id it picks one, and returns the
+// existing entry when today already has one (so a retried schedule doesn't
+// regenerate twice). A given id is used even if it was featured recently.
+func (j *CityOfTheDay) Run(ctx context.Context, id string) (*database.FeaturedCity, error) {
+	today := database.FeaturedDate(j.now())
+	history, err := j.DB.ListCityOfTheDay(ctx, j.AvoidDays+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read city of the day history: %w", err)
+	}
+
+	var loc *database.Location
+	if id == "" {
+		if len(history) > 0 && history[0].Date == today {
+			log.Printf("City of the day for %s is already %s", today, history[0].LocationID)
+			return &history[0], nil
+		}
+		if loc, err = j.pick(ctx, history); err != nil {
+			return nil, err
+		}
+		id = loc.ID
+	} else if loc, err = j.DB.GetLocation(ctx, id); err != nil {
+		return nil, fmt.Errorf("location %s not found: %w", id, err)
+	}
+
+	log.Printf("City of the day for %s: %s", today, id)
+	if loc == nil || !loc.KeepsMedia() {
+		loc, err = j.Refresher.RefreshLocation(ctx, id, weather.RefreshOptions{Style: 1})
+		if err != nil {
+			return nil, fmt.Errorf("failed to regenerate %s: %w", id, err)
+		}
+	}
+	loc.FeaturedOn = today
+	if err := j.DB.UpsertLocation(ctx, *loc); err != nil {
+		return nil, fmt.Errorf("failed to mark %s featured: %w", id, err)
+	}
+
+	f := database.FeaturedCity{
+		Date:       today,
+		LocationID: loc.ID,
+		Name:       loc.Name,
+		ImageURL:   loc.ImageURL,
+		VideoURL:   loc.VideoURL,
+		SelectedAt: j.now(),
+	}
+	if err := j.DB.SetCityOfTheDay(ctx, f); err != nil {
+		return nil, fmt.Errorf("failed to record city of the day: %w", err)
+	}
+	if err := j.Push.CityOfTheDay(ctx, loc); err != nil {
+		log.Printf("Failed to announce city of the day: %v", err)
+	}
+	return &f, nil
+}
+
+// pick chooses a ready preset not featured within AvoidDays. When every
+// preset was, the one featured longest ago is reused.
+func (j *CityOfTheDay) pick(ctx context.Context, history []database.FeaturedCity) (*database.Location, error) {
+	presets, err := j.DB.GetPresets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+	cutoff := database.FeaturedDate(j.now().AddDate(0, 0, -j.AvoidDays))
+	recent := map[string]bool{}
+	for _, f := range history {
+		if f.Date > cutoff {
+			recent[f.LocationID] = true
+		}
+	}
+
+	var candidates, ready []*database.Location
+	for i := range presets {
+		p := &presets[i]
+		if p.EffectiveStatus() != database.StatusReady {
+			continue
+		}
+		ready = append(ready, p)
+		if !recent[p.ID] {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, fmt.Errorf("no ready presets to feature")
+	}
+	if len(candidates) == 0 {
+		oldest := ready[0]
+		for _, p := range ready[1:] {
+			if p.FeaturedOn < oldest.FeaturedOn {
+				oldest = p
+			}
+		}
+		return oldest, nil
+	}
+
+	n := rand.IntN
+	if j.Rand != nil {
+		n = j.Rand
+	}
+	return candidates[n(len(candidates))], nil
+}
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/push"
+	"banana-weather/internal/weather"
+)
+
+type fakeFeaturedDB struct {
+	CityOfTheDayStore
+	presets []database.Location
+	history []database.FeaturedCity // Newest first
+	marked  []database.Location
+}
+
+func (f *fakeFeaturedDB) GetPresets(ctx context.Context) ([]database.Location, error) {
+	return f.presets, nil
+}
+func (f *fakeFeaturedDB) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	for _, p := range f.presets {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, context.Canceled
+}
+func (f *fakeFeaturedDB) UpsertLocation(ctx context.Context, loc database.Location) error {
+	f.marked = append(f.marked, loc)
+	return nil
+}
+func (f *fakeFeaturedDB) SetCityOfTheDay(ctx context.Context, c database.FeaturedCity) error {
+	f.history = append([]database.FeaturedCity{c}, f.history...)
+	return nil
+}
+func (f *fakeFeaturedDB) ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error) {
+	if limit > 0 && len(f.history) > limit {
+		return f.history[:limit], nil
+	}
+	return f.history, nil
+}
+
+type fakeRefresher struct {
+	db    *fakeFeaturedDB
+	calls []string
+	style int
+}
+
+func (f *fakeRefresher) RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error) {
+	f.calls = append(f.calls, id)
+	f.style = opts.Style
+	loc, err := f.db.GetLocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	loc.ImageURL = "https://storage.googleapis.com/media/" + id + "_new.png"
+	return loc, nil
+}
+
+func TestCityOfTheDay(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC))
+	db := &fakeFeaturedDB{
+		presets: []database.Location{
+			{ID: "paris", Name: "Paris", IsPreset: true},
+			{ID: "tokyo", Name: "Tokyo", IsPreset: true, Status: database.StatusReady},
+			{ID: "oslo", Name: "Oslo", IsPreset: true, Status: database.StatusHidden},
+		},
+		history: []database.FeaturedCity{{Date: "2026-10-10", LocationID: "paris"}},
+	}
+	ref := &fakeRefresher{db: db}
+	job := &CityOfTheDay{DB: db, Refresher: ref, AvoidDays: 7, Clock: clk, Rand: func(int) int { return 0 }}
+
+	f, err := job.Run(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// paris was featured 5 days ago and oslo is hidden
+	if f.Date != "2026-10-15" || f.LocationID != "tokyo" || f.ImageURL != "https://storage.googleapis.com/media/tokyo_new.png" {
+		t.Errorf("Unexpected pick %+v", f)
+	}
+	if ref.style != 1 || len(db.marked) != 1 || db.marked[0].FeaturedOn != "2026-10-15" {
+		t.Errorf("Expected a classic regeneration marked featured, got style %d, marked %+v", ref.style, db.marked)
+	}
+
+	// A retried schedule keeps today's pick
+	if f, _ := job.Run(context.Background(), ""); f.LocationID != "tokyo" || len(ref.calls) != 1 {
+		t.Errorf("Expected today's pick reused without regenerating, got %+v after %d refreshes", f, len(ref.calls))
+	}
+
+	// An explicit id replaces it, even if featured recently
+	if f, err := job.Run(context.Background(), "paris"); err != nil || f.LocationID != "paris" || db.history[0].LocationID != "paris" {
+		t.Errorf("Expected paris to replace today's pick, got %+v, %v", f, err)
+	}
+	if _, err := job.Run(context.Background(), "atlantis"); err == nil {
+		t.Error("Expected an unknown id to fail")
+	}
+
+	// Everything featured recently: the one featured longest ago is reused
+	clk.Set(clk.Now().AddDate(0, 0, 1))
+	db.presets[0].FeaturedOn, db.presets[1].FeaturedOn = "2026-10-15", "2026-10-14"
+	if f, _ := job.Run(context.Background(), ""); f == nil || f.LocationID != "tokyo" {
+		t.Errorf("Expected the least recently featured preset, got %+v", f)
+	}
+}
+
+type fakeSender struct{ sent []push.Message }
+
+func (f *fakeSender) Send(ctx context.Context, m push.Message) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func TestCityOfTheDay_Push(t *testing.T) {
+	db := &fakeFeaturedDB{presets: []database.Location{{ID: "paris", Name: "Paris", Category: "Classic Cities", IsPreset: true}}}
+	sender := &fakeSender{}
+	job := &CityOfTheDay{DB: db, Refresher: &fakeRefresher{db: db}, Clock: clock.NewFake(time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)), Push: &Fanout{Sender: sender}}
+
+	if _, err := job.Run(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("Expected one notification, got %+v", sender.sent)
+	}
+	m := sender.sent[0]
+	if strings.Join(m.Topics, ",") != "city_of_the_day,preset_paris,category_classic_cities" || m.Data["featured_on"] != "2026-10-15" {
+		t.Errorf("Unexpected notification %+v", m)
+	}
+
+	// Only presets have followers
+	if err := job.Push.Refreshed(context.Background(), &database.Location{ID: "u1"}); err != nil || len(sender.sent) != 1 {
+		t.Errorf("Expected no notification for a user location, got %+v, %v", sender.sent, err)
+	}
+	var none *Fanout
+	if err := none.Refreshed(context.Background(), &db.presets[0]); err != nil {
+		t.Errorf("A nil fan-out should be a no-op, got %v", err)
+	}
+}
+
+func TestCityOfTheDay_Curated(t *testing.T) {
+	db := &fakeFeaturedDB{presets: []database.Location{
+		{ID: "tokyo", Name: "Tokyo", IsPreset: true, ManuallyCurated: true, ImageURL: "https://storage.googleapis.com/media/curated/tokyo.png"},
+	}}
+	ref := &fakeRefresher{db: db}
+	job := &CityOfTheDay{DB: db, Refresher: ref, Clock: clock.NewFake(time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC))}
+
+	for _, id := range []string{"", "tokyo"} {
+		f, err := job.Run(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ref.calls) != 0 || f.ImageURL != "https://storage.googleapis.com/media/curated/tokyo.png" {
+			t.Errorf("Expected the attached media featured as is, got %+v after refreshes %v", f, ref.calls)
+		}
+	}
+}
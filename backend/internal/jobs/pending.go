@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+)
+
+// PendingRefreshStore is the part of the repository the pending refresh job
+// needs.
+type PendingRefreshStore interface {
+	ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error)
+	SetStatus(ctx context.Context, id string, status database.LocationStatus) error
+}
+
+// PendingRefreshReport is what a PendingRefreshes run did, by location ID.
+type PendingRefreshReport struct {
+	Refreshed []string `json:"refreshed"`
+	Failed    []string `json:"failed,omitempty"`   // Marked failed by RefreshLocation
+	Restored  []string `json:"restored,omitempty"` // Refused (locked or withdrawn meanwhile), back to ready
+	Halted    bool     `json:"halted,omitempty"`   // Stopped by the kill switch; the rest stay pending
+}
+
+// PendingRefreshes refreshes the locations left refresh_pending. The web
+// flow marks a location it served stale and refreshes it in the background
+// after a delay (see weather.Service.StaleRetryDelay), which an instance
+// scaled in or restarted meanwhile never gets to; run from a scheduler, the
+// job is the durable retry. While the kill switch is on, it stops and
+// leaves them pending for the next run.
+type PendingRefreshes struct {
+	DB        PendingRefreshStore
+	Refresher Refresher
+}
+
+// Run refreshes each pending location in turn.
+func (j *PendingRefreshes) Run(ctx context.Context) (*PendingRefreshReport, error) {
+	locs, err := j.DB.ListLocations(ctx, database.ListOptions{Type: "all", Status: database.StatusRefreshPending})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending locations: %w", err)
+	}
+	report := &PendingRefreshReport{Refreshed: []string{}}
+	for _, loc := range locs {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		_, err := j.Refresher.RefreshLocation(ctx, loc.ID, weather.RefreshOptions{})
+		switch {
+		case err == nil:
+			report.Refreshed = append(report.Refreshed, loc.ID)
+		case errors.Is(err, weather.ErrHalted):
+			report.Halted = true
+			return report, nil
+		case errors.Is(err, weather.ErrLocked) || errors.Is(err, weather.ErrWithdrawn):
+			if err := j.DB.SetStatus(ctx, loc.ID, database.StatusReady); err != nil {
+				return report, fmt.Errorf("failed to restore %s: %w", loc.ID, err)
+			}
+			report.Restored = append(report.Restored, loc.ID)
+		default:
+			log.Printf("Pending refresh of %s failed: %v", loc.ID, err)
+			report.Failed = append(report.Failed, loc.ID)
+		}
+	}
+	return report, nil
+}
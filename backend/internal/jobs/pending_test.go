@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+)
+
+type fakePendingDB struct {
+	locs     []database.Location
+	statuses map[string]database.LocationStatus
+}
+
+func (f *fakePendingDB) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	var out []database.Location
+	for _, l := range f.locs {
+		if opts.Status == "" || l.Status == opts.Status {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePendingDB) SetStatus(ctx context.Context, id string, status database.LocationStatus) error {
+	f.statuses[id] = status
+	return nil
+}
+
+// errRefresher refreshes locations, failing those in errs.
+type errRefresher struct {
+	errs  map[string]error
+	calls []string
+}
+
+func (r *errRefresher) RefreshLocation(ctx context.Context, id string, opts weather.RefreshOptions) (*database.Location, error) {
+	r.calls = append(r.calls, id)
+	if err := r.errs[id]; err != nil {
+		return nil, err
+	}
+	return &database.Location{ID: id}, nil
+}
+
+func TestPendingRefreshes(t *testing.T) {
+	db := &fakePendingDB{
+		locs: []database.Location{
+			{ID: "oslo", Status: database.StatusRefreshPending},
+			{ID: "paris", Status: database.StatusReady},
+			{ID: "lima", Status: database.StatusRefreshPending},
+			{ID: "rome", Status: database.StatusRefreshPending},
+			{ID: "kyiv", Status: database.StatusRefreshPending},
+		},
+		statuses: map[string]database.LocationStatus{},
+	}
+	ref := &errRefresher{errs: map[string]error{
+		"lima": fmt.Errorf("lima: %w", weather.ErrLocked),
+		"rome": errors.New("vertex unavailable"),
+		"kyiv": weather.ErrHalted,
+	}}
+	report, err := (&PendingRefreshes{DB: db, Refresher: ref}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ref.calls, []string{"oslo", "lima", "rome", "kyiv"}) {
+		t.Errorf("Refreshed %v, want only the pending locations", ref.calls)
+	}
+	if !slices.Equal(report.Refreshed, []string{"oslo"}) || !slices.Equal(report.Failed, []string{"rome"}) || !report.Halted {
+		t.Errorf("report = %+v", report)
+	}
+	// A refused refresh puts the location back to ready; failures and the
+	// kill switch leave the status to RefreshLocation and the next run
+	if !slices.Equal(report.Restored, []string{"lima"}) || len(db.statuses) != 1 || db.statuses["lima"] != database.StatusReady {
+		t.Errorf("Expected only lima restored, got %+v (%v)", report.Restored, db.statuses)
+	}
+}
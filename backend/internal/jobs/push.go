@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/push"
+)
+
+// Fanout notifies devices following a location when its art changes. A nil
+// Fanout sends nothing.
+type Fanout struct {
+	Sender push.Sender
+}
+
+// locationTopics are the topics of devices following loc: the preset itself
+// and its category.
+func locationTopics(loc *database.Location) []string {
+	topics := []string{push.PresetTopic(loc.ID)}
+	if loc.Category != "" {
+		topics = append(topics, push.CategoryTopic(loc.Category))
+	}
+	return topics
+}
+
+func locationData(loc *database.Location) map[string]string {
+	return map[string]string{"location_id": loc.ID, "image_url": loc.ImageURL, "video_url": loc.VideoURL}
+}
+
+// Refreshed notifies followers of a preset after its media was regenerated.
+// User locations have no followers and are skipped.
+func (f *Fanout) Refreshed(ctx context.Context, loc *database.Location) error {
+	if f == nil || f.Sender == nil || !loc.IsPreset {
+		return nil
+	}
+	return f.Sender.Send(ctx, push.Message{
+		Topics:   locationTopics(loc),
+		Title:    loc.Name,
+		Body:     fmt.Sprintf("New weather art for %s", loc.Name),
+		ImageURL: loc.ImageURL,
+		Data:     locationData(loc),
+	})
+}
+
+// CityOfTheDay announces the day's pick to the city of the day topic and the
+// city's followers, in one message so nobody gets it twice.
+func (f *Fanout) CityOfTheDay(ctx context.Context, loc *database.Location) error {
+	if f == nil || f.Sender == nil {
+		return nil
+	}
+	data := locationData(loc)
+	data["featured_on"] = loc.FeaturedOn
+	return f.Sender.Send(ctx, push.Message{
+		Topics:   append([]string{push.CityOfTheDayTopic}, locationTopics(loc)...),
+		Title:    "City of the day",
+		Body:     loc.Name,
+		ImageURL: loc.ImageURL,
+		Data:     data,
+	})
+}
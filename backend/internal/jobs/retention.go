@@ -0,0 +1,343 @@
+// Package jobs holds maintenance jobs that run over the whole location
+// collection, from the CLI or a scheduler.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/media"
+	"banana-weather/internal/storage"
+)
+
+// RetentionAction is what happens to media past a rule's age.
+type RetentionAction string
+
+const (
+	ActionColdline RetentionAction = "coldline" // Rewrite the object as COLDLINE; its URL keeps working
+	ActionDelete   RetentionAction = "delete"   // Delete the object (an image delete purges the location)
+	ActionExpire   RetentionAction = "expire"   // Set the location's expire_at (Firestore TTL), see LocationTTL
+)
+
+// RetentionRule applies Action to one kind of media of user locations not
+// updated for After.
+type RetentionRule struct {
+	Kind   storage.Kind    `json:"kind"`
+	Action RetentionAction `json:"action"`
+	After  time.Duration   `json:"after"`
+}
+
+// ParseRetentionPolicy parses comma-separated kind:action:age rules, e.g.
+// "image:coldline:30d,video:delete:90d". Kinds are image or video; ages are
+// days ("30d") or Go durations ("720h").
+func ParseRetentionPolicy(s string) ([]RetentionRule, error) {
+	var rules []RetentionRule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("retention rule %q: want kind:action:age", part)
+		}
+		r := RetentionRule{Kind: storage.Kind(fields[0]), Action: RetentionAction(fields[1])}
+		if r.Kind != storage.KindImage && r.Kind != storage.KindVideo {
+			return nil, fmt.Errorf("retention rule %q: kind must be image or video", part)
+		}
+		if r.Action != ActionColdline && r.Action != ActionDelete {
+			return nil, fmt.Errorf("retention rule %q: action must be coldline or delete", part)
+		}
+		after, err := parseAge(fields[2])
+		if err != nil || after <= 0 {
+			return nil, fmt.Errorf("retention rule %q: invalid age %q", part, fields[2])
+		}
+		r.After = after
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// TTLGrace is how long after an image delete rule applies a user location's
+// record expires. Firestore TTL only deletes the document, so the retention
+// job, run daily, gets to purge the media first; the TTL is the backstop that
+// keeps the collection bounded.
+const TTLGrace = 7 * 24 * time.Hour
+
+// LocationTTL returns how long after their last update user locations expire
+// under rules: TTLGrace after the earliest image delete rule. It's 0 (no
+// expiry) without an image delete rule.
+func LocationTTL(rules []RetentionRule) time.Duration {
+	var purge time.Duration
+	for _, r := range rules {
+		if r.Kind == storage.KindImage && r.Action == ActionDelete && (purge == 0 || r.After < purge) {
+			purge = r.After
+		}
+	}
+	if purge == 0 {
+		return 0
+	}
+	return purge + TTLGrace
+}
+
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
+
+// RetentionStore is the part of the repository the retention job needs.
+type RetentionStore interface {
+	ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	DeleteLocation(ctx context.Context, id string) error
+}
+
+// Expirer is implemented by stores whose location records expire through
+// expire_at (*database.Client, with Firestore TTL). The retention job keeps
+// expire_at in line with the policy, which UpsertLocation only does on write.
+type Expirer interface {
+	SetLocationExpiry(ctx context.Context, id string, at *time.Time) error
+}
+
+// KindLocation marks the step deleting the location record itself.
+const KindLocation storage.Kind = "location"
+
+// RetentionStep is one action taken (or, in a dry run, planned) on a location.
+type RetentionStep struct {
+	Location string          `json:"location"`
+	Kind     storage.Kind    `json:"kind"`
+	Action   RetentionAction `json:"action"`
+	Object   string          `json:"object"`
+	Age      time.Duration   `json:"age"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Retention applies a retention policy to the media of user-generated
+// locations. Presets, and locations with generation in progress, are never
+// touched.
+type Retention struct {
+	DB     RetentionStore
+	Media  map[storage.Kind]storage.Store // Image and video stores; Original is optional
+	Rules  []RetentionRule
+	Clock  clock.Clock // The system clock when nil
+	DryRun bool        // Report the steps without changing anything
+}
+
+// rule returns the rule for kind that applies at age: a delete over a
+// coldline, otherwise the one with the longest age.
+func (r *Retention) rule(kind storage.Kind, age time.Duration) *RetentionRule {
+	var best *RetentionRule
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Kind != kind || age < rule.After {
+			continue
+		}
+		switch {
+		case best == nil,
+			rule.Action == ActionDelete && best.Action != ActionDelete,
+			rule.Action == best.Action && rule.After > best.After:
+			best = rule
+		}
+	}
+	return best
+}
+
+// Run applies the rules and returns the steps taken. Failed steps are
+// reported in their Error field; Run only fails when locations can't be
+// listed.
+//
+// Deleting an image purges the location (record, image, video and original),
+// as does an expire_at that has passed, whatever the rules: the record is
+// about to be deleted by Firestore TTL, which would leave its media behind.
+// Deleting only the video clears it from the location, which rewrites the
+// record and so restarts its age. Objects already in Coldline are skipped, as
+// is Coldline on stores without storage classes (S3).
+//
+// When DB is an Expirer, the expire_at of the remaining locations is set to
+// LocationTTL after their last update (or removed, without an image delete
+// rule), so records written before the policy, or under another one, expire.
+func (r *Retention) Run(ctx context.Context) ([]RetentionStep, error) {
+	locs, err := r.DB.ListLocations(ctx, database.ListOptions{Type: "user"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user locations: %w", err)
+	}
+	now := time.Now()
+	if r.Clock != nil {
+		now = r.Clock.Now()
+	}
+
+	expirer, _ := r.DB.(Expirer)
+	ttl := LocationTTL(r.Rules)
+
+	var steps []RetentionStep
+	for _, loc := range locs {
+		if loc.IsPreset || loc.Status == database.StatusGenerating || loc.Status == database.StatusRefreshPending {
+			continue
+		}
+		age := now.Sub(loc.LastUpdated)
+		expired := loc.ExpireAt != nil && !now.Before(*loc.ExpireAt)
+		if rule := r.rule(storage.KindImage, age); expired || rule != nil && rule.Action == ActionDelete {
+			steps = append(steps, r.purge(ctx, loc, age)...)
+			continue
+		}
+		rewritten := false
+		for _, m := range []struct {
+			kind storage.Kind
+			url  string
+		}{{storage.KindImage, loc.ImageURL}, {storage.KindVideo, loc.VideoURL}} {
+			rule := r.rule(m.kind, age)
+			store := r.Media[m.kind]
+			if rule == nil || store == nil || m.url == "" {
+				continue
+			}
+			name := store.ObjectName(m.url)
+			if name == "" {
+				continue // Not in our bucket
+			}
+			step := RetentionStep{Location: loc.ID, Kind: m.kind, Action: rule.Action, Object: name, Age: age}
+			switch rule.Action {
+			case ActionColdline:
+				archiver := storage.AsArchiver(store)
+				if archiver == nil {
+					continue
+				}
+				class, err := archiver.StorageClass(ctx, name)
+				if err != nil {
+					step.Error = err.Error()
+				} else if class == "COLDLINE" || class == "ARCHIVE" {
+					continue
+				} else if !r.DryRun {
+					if err := archiver.SetStorageClass(ctx, name, "COLDLINE"); err != nil {
+						step.Error = err.Error()
+					}
+				}
+			case ActionDelete: // Video only; an image delete purged above
+				streamSteps, streamFailed := r.deleteStream(ctx, loc, age)
+				steps = append(steps, streamSteps...)
+				if !r.DryRun {
+					if err := store.DeleteObject(ctx, name); err != nil {
+						step.Error = err.Error()
+					} else {
+						loc.VideoURL = ""
+						if !streamFailed {
+							loc.StreamURL = ""
+						}
+						if err := r.DB.UpsertLocation(ctx, loc); err != nil {
+							step.Error = err.Error()
+						}
+						rewritten = true // With a new expire_at
+					}
+				}
+			}
+			steps = append(steps, step)
+		}
+		if expirer != nil && !rewritten {
+			if step := r.expire(ctx, expirer, loc, ttl, age); step != nil {
+				steps = append(steps, *step)
+			}
+		}
+	}
+	return steps, nil
+}
+
+// expire sets the expire_at of loc for ttl when it differs, and returns the
+// step, or nil when it's already right.
+func (r *Retention) expire(ctx context.Context, expirer Expirer, loc database.Location, ttl, age time.Duration) *RetentionStep {
+	want := loc
+	want.Expire(ttl)
+	if want.ExpireAt == nil && loc.ExpireAt == nil ||
+		want.ExpireAt != nil && loc.ExpireAt != nil && want.ExpireAt.Equal(*loc.ExpireAt) {
+		return nil
+	}
+	step := &RetentionStep{Location: loc.ID, Kind: KindLocation, Action: ActionExpire, Object: loc.ID, Age: age}
+	if !r.DryRun {
+		if err := expirer.SetLocationExpiry(ctx, loc.ID, want.ExpireAt); err != nil {
+			step.Error = err.Error()
+		}
+	}
+	return step
+}
+
+// purge deletes all media of loc, then the location itself.
+func (r *Retention) purge(ctx context.Context, loc database.Location, age time.Duration) []RetentionStep {
+	images := r.Media[storage.KindImage]
+	steps, failed := r.deleteStream(ctx, loc, age)
+	for _, m := range []struct {
+		kind  storage.Kind
+		url   string
+		names storage.Store // Resolves the object name
+	}{
+		{storage.KindImage, loc.ImageURL, images},
+		{storage.KindImage, loc.PosterURL, images},
+		{storage.KindVideo, loc.VideoURL, r.Media[storage.KindVideo]},
+		{storage.KindOriginal, loc.ImageURL, images}, // Kept under the public image's name
+	} {
+		store := r.Media[m.kind]
+		if store == nil || m.names == nil || m.url == "" {
+			continue
+		}
+		name := m.names.ObjectName(m.url)
+		if name == "" {
+			continue
+		}
+		step := RetentionStep{Location: loc.ID, Kind: m.kind, Action: ActionDelete, Object: name, Age: age}
+		if !r.DryRun {
+			if err := store.DeleteObject(ctx, name); err != nil {
+				step.Error = err.Error()
+				failed = true
+			}
+		}
+		steps = append(steps, step)
+	}
+	if failed {
+		// Keep the record so the next run retries the media
+		return steps
+	}
+
+	step := RetentionStep{Location: loc.ID, Kind: KindLocation, Action: ActionDelete, Object: loc.ID, Age: age}
+	if !r.DryRun {
+		if err := r.DB.DeleteLocation(ctx, loc.ID); err != nil {
+			step.Error = err.Error()
+		}
+	}
+	return append(steps, step)
+}
+
+// deleteStream deletes the HLS stream of loc from the video store: segments
+// and variant playlists first, then the master playlist, so a failed run can
+// still find what's left.
+func (r *Retention) deleteStream(ctx context.Context, loc database.Location, age time.Duration) (steps []RetentionStep, failed bool) {
+	videos := r.Media[storage.KindVideo]
+	if videos == nil || loc.StreamURL == "" {
+		return nil, false
+	}
+	master := videos.ObjectName(loc.StreamURL)
+	if master == "" {
+		return nil, false // Not in our bucket
+	}
+	names, err := media.StreamObjects(ctx, videos.ReadObject, master)
+	if err != nil {
+		return []RetentionStep{{Location: loc.ID, Kind: storage.KindVideo, Action: ActionDelete, Object: master, Age: age, Error: err.Error()}}, true
+	}
+	slices.Reverse(names)
+	for _, name := range names {
+		step := RetentionStep{Location: loc.ID, Kind: storage.KindVideo, Action: ActionDelete, Object: name, Age: age}
+		if !r.DryRun {
+			if err := videos.DeleteObject(ctx, name); err != nil {
+				step.Error = err.Error()
+				failed = true
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, failed
+}
@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/storage"
+)
+
+type fakeDB struct {
+	RetentionStore
+	locs     []database.Location
+	deleted  []string
+	upserted []database.Location
+}
+
+func (f *fakeDB) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	return f.locs, nil
+}
+func (f *fakeDB) UpsertLocation(ctx context.Context, loc database.Location) error {
+	f.upserted = append(f.upserted, loc)
+	return nil
+}
+func (f *fakeDB) DeleteLocation(ctx context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeStore struct {
+	storage.Store
+	bucket  string
+	classes map[string]string
+	deleted []string
+	objects map[string]string
+}
+
+func (f *fakeStore) ObjectName(url string) string {
+	name, _ := strings.CutPrefix(url, "https://storage.googleapis.com/"+f.bucket+"/")
+	if name == url {
+		return ""
+	}
+	return name
+}
+func (f *fakeStore) DeleteObject(ctx context.Context, name string) error {
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+func (f *fakeStore) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	if b, ok := f.objects[name]; ok {
+		return []byte(b), nil
+	}
+	return nil, fmt.Errorf("object %s not found", name)
+}
+func (f *fakeStore) StorageClass(ctx context.Context, name string) (string, error) {
+	if c := f.classes[name]; c != "" {
+		return c, nil
+	}
+	return "STANDARD", nil
+}
+func (f *fakeStore) SetStorageClass(ctx context.Context, name, class string) error {
+	f.classes[name] = class
+	return nil
+}
+
+func TestParseRetentionPolicy(t *testing.T) {
+	rules, err := ParseRetentionPolicy("image:coldline:30d, video:delete:36h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].After != 30*24*time.Hour || rules[1].Kind != storage.KindVideo || rules[1].After != 36*time.Hour {
+		t.Errorf("Unexpected rules %+v", rules)
+	}
+	for _, bad := range []string{"image:coldline", "upload:delete:1d", "image:archive:1d", "image:delete:soon", "image:delete:0d"} {
+		if _, err := ParseRetentionPolicy(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	url := func(bucket, name string) string { return "https://storage.googleapis.com/" + bucket + "/" + name }
+	loc := func(id string, days int) database.Location {
+		return database.Location{ID: id, ImageURL: url("media", id+".png"), VideoURL: url("media", "videos/"+id+".mp4"), LastUpdated: now.AddDate(0, 0, -days)}
+	}
+	generating := loc("busy", 400)
+	generating.Status = database.StatusGenerating
+	preset := loc("preset", 400)
+	preset.IsPreset = true
+
+	newRun := func(dryRun bool) (*Retention, *fakeDB, *fakeStore, *fakeStore) {
+		db := &fakeDB{locs: []database.Location{loc("fresh", 5), loc("old", 40), loc("older", 100), loc("oldest", 400), generating, preset}}
+		media := &fakeStore{bucket: "media", classes: map[string]string{}}
+		originals := &fakeStore{bucket: "originals", classes: map[string]string{}}
+		rules, _ := ParseRetentionPolicy("image:coldline:30d,video:coldline:30d,video:delete:90d,image:delete:365d")
+		return &Retention{
+			DB:     db,
+			Media:  map[storage.Kind]storage.Store{storage.KindImage: media, storage.KindVideo: media, storage.KindOriginal: originals},
+			Rules:  rules,
+			Clock:  clock.NewFake(now),
+			DryRun: dryRun,
+		}, db, media, originals
+	}
+
+	job, db, media, originals := newRun(false)
+	steps, err := job.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, s := range steps {
+		if s.Error != "" {
+			t.Errorf("Unexpected error in %+v", s)
+		}
+		got[s.Location+":"+string(s.Kind)+":"+string(s.Action)] = true
+	}
+	for _, want := range []string{
+		"old:image:coldline", "old:video:coldline",
+		"older:image:coldline", "older:video:delete",
+		"oldest:image:delete", "oldest:video:delete", "oldest:original:delete", "oldest:location:delete",
+	} {
+		if !got[want] {
+			t.Errorf("Missing step %s in %v", want, got)
+		}
+	}
+	if len(steps) != 8 {
+		t.Errorf("Expected 8 steps (fresh, in-progress and preset untouched), got %d: %v", len(steps), got)
+	}
+	if media.classes["old.png"] != "COLDLINE" || len(db.deleted) != 1 || db.deleted[0] != "oldest" {
+		t.Errorf("Unexpected changes: classes %v, deleted locations %v", media.classes, db.deleted)
+	}
+	if len(originals.deleted) != 1 || originals.deleted[0] != "oldest.png" {
+		t.Errorf("Expected the original purged with its location, got %v", originals.deleted)
+	}
+	if len(db.upserted) != 1 || db.upserted[0].ID != "older" || db.upserted[0].VideoURL != "" {
+		t.Errorf("Expected the deleted video cleared from its location, got %+v", db.upserted)
+	}
+
+	// Already archived objects aren't rewritten again
+	steps, _ = job.Run(context.Background())
+	for _, s := range steps {
+		if s.Location == "old" {
+			t.Errorf("Expected no step for an object already in Coldline, got %+v", s)
+		}
+	}
+
+	job, db, media, _ = newRun(true)
+	if steps, _ := job.Run(context.Background()); len(steps) != 8 {
+		t.Errorf("Expected the dry run to plan the same 8 steps, got %d", len(steps))
+	}
+	if len(media.classes) != 0 || len(media.deleted) != 0 || len(db.deleted) != 0 || len(db.upserted) != 0 {
+		t.Error("Dry run changed something")
+	}
+}
+
+func TestRetention_Stream(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	videos := &fakeStore{bucket: "media", objects: map[string]string{
+		"videos/older_hls/master.m3u8":  "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=600000\n0/index.m3u8\n",
+		"videos/older_hls/0/index.m3u8": "#EXTM3U\n#EXTINF:2.0,\nsegment_000.ts\n#EXTINF:2.0,\nsegment_001.ts\n#EXT-X-ENDLIST\n",
+	}}
+	db := &fakeDB{locs: []database.Location{{
+		ID:          "older",
+		VideoURL:    "https://storage.googleapis.com/media/videos/older.mp4",
+		StreamURL:   "https://storage.googleapis.com/media/videos/older_hls/master.m3u8",
+		LastUpdated: now.AddDate(0, 0, -100),
+	}}}
+	rules, _ := ParseRetentionPolicy("video:delete:90d")
+	job := &Retention{DB: db, Media: map[storage.Kind]storage.Store{storage.KindVideo: videos}, Rules: rules, Clock: clock.NewFake(now)}
+
+	if _, err := job.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"videos/older_hls/0/segment_001.ts", "videos/older_hls/0/segment_000.ts", "videos/older_hls/0/index.m3u8",
+		"videos/older_hls/master.m3u8", "videos/older.mp4",
+	}
+	if !slices.Equal(videos.deleted, want) {
+		t.Errorf("Expected the stream deleted before its master playlist and the MP4, got %v", videos.deleted)
+	}
+	if len(db.upserted) != 1 || db.upserted[0].StreamURL != "" || db.upserted[0].VideoURL != "" {
+		t.Errorf("Expected the video and stream cleared, got %+v", db.upserted)
+	}
+}
+
+func TestLocationTTL(t *testing.T) {
+	for policy, want := range map[string]time.Duration{
+		"image:coldline:30d,video:delete:90d":                  0,
+		"image:delete:365d,image:delete:180d,video:delete:30d": 180*24*time.Hour + TTLGrace,
+	} {
+		rules, err := ParseRetentionPolicy(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := LocationTTL(rules); got != want {
+			t.Errorf("LocationTTL(%s) = %v, want %v", policy, got, want)
+		}
+	}
+}
+
+type expiringDB struct {
+	fakeDB
+	expiry map[string]*time.Time
+}
+
+func (e *expiringDB) SetLocationExpiry(ctx context.Context, id string, at *time.Time) error {
+	e.expiry[id] = at
+	return nil
+}
+
+func TestRetention_Expiry(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	rules, _ := ParseRetentionPolicy("image:delete:30d")
+	ttl := LocationTTL(rules)
+	at := func(t time.Time) *time.Time { return &t }
+
+	fresh := database.Location{ID: "fresh", LastUpdated: now.AddDate(0, 0, -5)}
+	current := database.Location{ID: "current", LastUpdated: now.AddDate(0, 0, -5)}
+	current.Expire(ttl)
+	// Expired under an older, shorter policy: purged before TTL drops the record
+	expired := database.Location{ID: "expired", ImageURL: "https://storage.googleapis.com/media/expired.png", LastUpdated: now.AddDate(0, 0, -10), ExpireAt: at(now.Add(-time.Hour))}
+	db := &expiringDB{fakeDB: fakeDB{locs: []database.Location{fresh, current, expired}}, expiry: map[string]*time.Time{}}
+	media := &fakeStore{bucket: "media"}
+	job := &Retention{DB: db, Media: map[storage.Kind]storage.Store{storage.KindImage: media}, Rules: rules, Clock: clock.NewFake(now)}
+
+	steps, err := job.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.Location+":"+string(s.Kind)+":"+string(s.Action))
+	}
+	want := []string{"fresh:location:expire", "expired:image:delete", "expired:location:delete"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected steps %v, got %v", want, got)
+	}
+	if e := db.expiry["fresh"]; e == nil || !e.Equal(fresh.LastUpdated.Add(ttl)) || len(db.expiry) != 1 {
+		t.Errorf("Expected only fresh given an expiry, got %v", db.expiry)
+	}
+	if len(media.deleted) != 1 || media.deleted[0] != "expired.png" {
+		t.Errorf("Expected the expired location's media purged, got %v", media.deleted)
+	}
+
+	// Without an image delete rule, expiries are removed
+	job.Rules, _ = ParseRetentionPolicy("image:coldline:30d")
+	db.locs, db.expiry = []database.Location{current}, map[string]*time.Time{}
+	if steps, _ := job.Run(context.Background()); len(steps) != 1 || steps[0].Action != ActionExpire {
+		t.Fatalf("Expected the expiry removed, got %+v", steps)
+	}
+	if e, ok := db.expiry["current"]; !ok || e != nil {
+		t.Errorf("Expected expire_at deleted, got %v", db.expiry)
+	}
+}
@@ -4,37 +4,79 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 
+	"google.golang.org/genproto/googleapis/type/latlng"
 	"googlemaps.github.io/maps"
 )
 
+// Place is a resolved geocoding result.
+type Place struct {
+	Name        string // Friendly or formatted address, used for display and location IDs
+	Lat, Lng    float64
+	CountryCode string // ISO 3166-1 alpha-2, e.g. "US"; empty for unresolvable places
+	Continent   string // Derived from CountryCode, see ContinentForCountry
+}
+
+func newPlace(name string, r maps.GeocodingResult) *Place {
+	p := &Place{
+		Name: name,
+		Lat:  r.Geometry.Location.Lat,
+		Lng:  r.Geometry.Location.Lng,
+	}
+	for _, component := range r.AddressComponents {
+		for _, t := range component.Types {
+			if t == "country" {
+				p.CountryCode = component.ShortName
+			}
+		}
+	}
+	p.Continent = ContinentForCountry(p.CountryCode)
+	return p
+}
+
+// LatLng returns the coordinates in the form Firestore stores as a geopoint.
+func (p *Place) LatLng() *latlng.LatLng {
+	return &latlng.LatLng{Latitude: p.Lat, Longitude: p.Lng}
+}
+
 type Service struct {
 	client *maps.Client
 }
 
 func NewService(apiKey string) (*Service, error) {
+	return NewServiceWithHTTPClient(apiKey, nil)
+}
+
+// NewServiceWithHTTPClient is NewService sending requests through hc, e.g. a
+// shared pool (see httppool); nil uses the default client.
+func NewServiceWithHTTPClient(apiKey string, hc *http.Client) (*Service, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("GOOGLE_MAPS_API_KEY is empty")
 	}
 
-	c, err := maps.NewClient(maps.WithAPIKey(apiKey))
+	opts := []maps.ClientOption{maps.WithAPIKey(apiKey)}
+	if hc != nil {
+		opts = append(opts, maps.WithHTTPClient(hc))
+	}
+	c, err := maps.NewClient(opts...)
 	if err != nil {
 		return nil, err
 	}
 	return &Service{client: c}, nil
 }
 
-func (s *Service) GetReverseGeocoding(ctx context.Context, lat, lng float64) (string, error) {
+func (s *Service) GetReverseGeocoding(ctx context.Context, lat, lng float64) (*Place, error) {
 	log.Printf("Reverse geocoding lat: %f, lng: %f", lat, lng)
 	r, err := s.client.Geocode(ctx, &maps.GeocodingRequest{
 		LatLng: &maps.LatLng{Lat: lat, Lng: lng},
 	})
 	if err != nil {
 		log.Printf("Reverse geocoding failed: %v", err)
-		return "", err
+		return nil, err
 	}
 	if len(r) == 0 {
-		return "", fmt.Errorf("location not found")
+		return nil, fmt.Errorf("location not found")
 	}
 
 	// Extract city and state from address components of the first result
@@ -84,28 +126,27 @@ func (s *Service) GetReverseGeocoding(ctx context.Context, lat, lng float64) (st
 	}
 	
 	log.Printf("Reverse geocoding success: %s", friendlyName)
-	return friendlyName, nil
+	p := newPlace(friendlyName, r[0])
+	p.Lat, p.Lng = lat, lng // Keep the user's coordinates rather than the result's centroid
+	return p, nil
 }
 
-func (s *Service) GetCityLocation(ctx context.Context, city string) (string, float64, float64, error) {
+func (s *Service) GetCityLocation(ctx context.Context, city string) (*Place, error) {
 	log.Printf("Geocoding city: %s", city)
 	r, err := s.client.Geocode(ctx, &maps.GeocodingRequest{
 		Address: city,
 	})
 	if err != nil {
 		log.Printf("Geocoding failed: %v", err)
-		return "", 0, 0, err
+		return nil, err
 	}
 	if len(r) == 0 {
 		log.Printf("Geocoding found no results for: %s", city)
-		return "", 0, 0, fmt.Errorf("city not found")
+		return nil, fmt.Errorf("city not found")
 	}
 
-	formattedAddress := r[0].FormattedAddress
-	lat := r[0].Geometry.Location.Lat
-	lng := r[0].Geometry.Location.Lng
-	
-	log.Printf("Geocoding success: %s (Lat: %f, Lng: %f)", formattedAddress, lat, lng)
+	p := newPlace(r[0].FormattedAddress, r[0])
+	log.Printf("Geocoding success: %s (Lat: %f, Lng: %f, Country: %s)", p.Name, p.Lat, p.Lng, p.CountryCode)
 
-	return formattedAddress, lat, lng, nil
+	return p, nil
 }
@@ -0,0 +1,41 @@
+package maps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+
+	"googlemaps.github.io/maps"
+)
+
+// Static map size: 9:16 like the generated art, at twice the density
+// (720x1280 pixels; the Static Maps API caps sizes at 640).
+const (
+	staticMapSize  = "360x640"
+	staticMapScale = 2
+	staticMapZoom  = 11 // City level
+)
+
+// StaticMap renders a PNG map of the area around the coordinates with a
+// marker on them, used as the image when AI generation fails. It needs the
+// Maps Static API enabled for the key.
+func (s *Service) StaticMap(ctx context.Context, lat, lng float64) ([]byte, error) {
+	center := maps.LatLng{Lat: lat, Lng: lng}
+	img, err := s.client.StaticMap(ctx, &maps.StaticMapRequest{
+		Center:  center.String(),
+		Zoom:    staticMapZoom,
+		Size:    staticMapSize,
+		Scale:   staticMapScale,
+		MapType: maps.Terrain,
+		Markers: []maps.Marker{{Color: "yellow", Location: []maps.LatLng{center}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("static map failed: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,215 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/storage"
+)
+
+// MasterPlaylist is the object name of an HLS stream's master playlist,
+// relative to the stream's directory.
+const MasterPlaylist = "master.m3u8"
+
+// Rendition is one bitrate of an HLS stream. Heights follow from the video's
+// aspect ratio.
+type Rendition struct {
+	Width   int
+	Bitrate int // Video bitrate in kbit/s
+}
+
+// DefaultRenditions suit 9:16 Veo clips on anything from a phone on 3G to a
+// signage screen.
+var DefaultRenditions = []Rendition{
+	{Width: 720, Bitrate: 2800},
+	{Width: 540, Bitrate: 1400},
+	{Width: 360, Bitrate: 600},
+}
+
+// hlsSegmentSeconds is the target segment length: short, so playback starts
+// quickly and can switch renditions within an 8s clip.
+const hlsSegmentSeconds = 2
+
+// Uploader stores the files of a stream. storage.Store implements it.
+type Uploader interface {
+	UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error)
+}
+
+// HLS transcodes videos to multi-bitrate HLS and uploads the playlists and
+// segments. Raw Veo MP4s are a single high bitrate, which stutters on slow
+// connections; players switch between HLS renditions as bandwidth allows.
+type HLS struct {
+	FFmpeg     string // Path of the ffmpeg binary
+	Renditions []Rendition
+	Store      Uploader // Where streams are written, typically the video store
+
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewHLS finds ffmpeg on the PATH and writes streams to store.
+func NewHLS(store Uploader) (*HLS, error) {
+	bin, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	return &HLS{FFmpeg: bin, Renditions: DefaultRenditions, Store: store}, nil
+}
+
+// OpenHLS returns the transcoder configured in cfg, writing streams next to
+// the videos (VIDEO_BUCKET), or nil when HLS_TRANSCODE is off.
+func OpenHLS(ctx context.Context, cfg *config.Config) (*HLS, error) {
+	if !cfg.HLSTranscode {
+		return nil, nil
+	}
+	videos, err := storage.OpenKind(ctx, cfg, storage.KindVideo)
+	if err != nil {
+		return nil, err
+	}
+	return NewHLS(videos)
+}
+
+// args returns the ffmpeg arguments writing the stream of url into dir, one
+// subdirectory per rendition plus the master playlist. Audio is dropped: the
+// app plays videos muted.
+func (h *HLS) args(url, dir string) []string {
+	n := len(h.Renditions)
+	filter := fmt.Sprintf("[0:v]split=%d", n)
+	for i := range h.Renditions {
+		filter += fmt.Sprintf("[s%d]", i)
+	}
+	streams := make([]string, n)
+	for i, r := range h.Renditions {
+		filter += fmt.Sprintf(";[s%d]scale=w=%d:h=-2[v%d]", i, r.Width, i)
+		streams[i] = fmt.Sprintf("v:%d", i)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", url, "-filter_complex", filter}
+	for i, r := range h.Renditions {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i),
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", r.Bitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", r.Bitrate*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", r.Bitrate*2))
+	}
+	return append(args,
+		"-an", "-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+		// Fixed keyframes every segment, so renditions switch cleanly
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds), "-sc_threshold", "0",
+		"-f", "hls", "-hls_time", fmt.Sprint(hlsSegmentSeconds), "-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-master_pl_name", MasterPlaylist, "-var_stream_map", strings.Join(streams, " "),
+		"-hls_segment_filename", filepath.Join(dir, "%v", "segment_%03d.ts"),
+		filepath.Join(dir, "%v", "index.m3u8"),
+	)
+}
+
+// Transcode packages the video at url (anything ffmpeg can read) as HLS under
+// name/ in the store and returns the public URL of the master playlist.
+// Playlists reference their files relatively, so the stream plays from
+// wherever the store serves it.
+func (h *HLS) Transcode(ctx context.Context, url, name string) (string, error) {
+	dir, err := os.MkdirTemp("", "hls-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	run := h.run
+	if run == nil {
+		run = runCommand
+	}
+	if _, err := run(ctx, h.FFmpeg, h.args(url, dir)...); err != nil {
+		return "", fmt.Errorf("failed to transcode to HLS: %w", err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(files, MasterPlaylist) {
+		return "", fmt.Errorf("failed to transcode to HLS: ffmpeg wrote no master playlist")
+	}
+	// The master goes last, so it never points at files that aren't there yet
+	files = append(slices.DeleteFunc(files, func(f string) bool { return f == MasterPlaylist }), MasterPlaylist)
+
+	var masterURL string
+	for i, f := range files {
+		progress.Emit(ctx, progress.Update{Stage: "stream", Message: "Packaging stream", Percent: 1 + i*99/len(files)})
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f)))
+		if err != nil {
+			return "", err
+		}
+		u, err := h.Store.UploadBytes(ctx, data, name+"/"+f, contentType(f))
+		if err != nil {
+			return "", fmt.Errorf("failed to upload %s: %w", f, err)
+		}
+		masterURL = u
+	}
+	return masterURL, nil
+}
+
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	}
+	return "application/octet-stream"
+}
+
+// StreamObjects lists the objects of the stream whose master playlist is the
+// object master, by following its playlists: the master itself, the variant
+// playlists and their segments. read returns an object's contents.
+func StreamObjects(ctx context.Context, read func(ctx context.Context, name string) ([]byte, error), master string) ([]string, error) {
+	objects := []string{master}
+	variants, err := playlistEntries(ctx, read, master)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		segments, err := playlistEntries(ctx, read, v)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, v)
+		objects = append(objects, segments...)
+	}
+	return objects, nil
+}
+
+// playlistEntries returns the object names a playlist references, resolved
+// against its directory.
+func playlistEntries(ctx context.Context, read func(ctx context.Context, name string) ([]byte, error), name string) ([]string, error) {
+	b, err := read(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playlist %s: %w", name, err)
+	}
+	var entries []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, "://") {
+			continue
+		}
+		entries = append(entries, path.Join(path.Dir(name), line))
+	}
+	return entries, sc.Err()
+}
@@ -0,0 +1,92 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeUploader struct {
+	names []string
+	types []string
+}
+
+func (f *fakeUploader) UploadBytes(ctx context.Context, data []byte, name, mimeType string) (string, error) {
+	f.names = append(f.names, name)
+	f.types = append(f.types, mimeType)
+	return "https://storage.googleapis.com/bucket/videos/" + name, nil
+}
+
+// fakeFFmpeg writes what ffmpeg would for two renditions into the output
+// directory taken from the last argument.
+func fakeFFmpeg(ctx context.Context, name string, args ...string) ([]byte, error) {
+	dir := filepath.Dir(filepath.Dir(args[len(args)-1]))
+	files := map[string]string{
+		"master.m3u8":      "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=2996000\n0/index.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=642000\n1/index.m3u8\n",
+		"0/index.m3u8":     "#EXTM3U\n#EXTINF:2.0,\nsegment_000.ts\n#EXT-X-ENDLIST\n",
+		"0/segment_000.ts": "ts",
+		"1/index.m3u8":     "#EXTM3U\n#EXTINF:2.0,\nsegment_000.ts\n#EXT-X-ENDLIST\n",
+		"1/segment_000.ts": "ts",
+	}
+	for f, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func TestTranscode(t *testing.T) {
+	store := &fakeUploader{}
+	h := &HLS{FFmpeg: "ffmpeg", Renditions: DefaultRenditions[1:], Store: store, run: fakeFFmpeg}
+	url, err := h.Transcode(context.Background(), "https://example.com/v.mp4", "paris_hls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://storage.googleapis.com/bucket/videos/paris_hls/master.m3u8" {
+		t.Errorf("Expected the master playlist URL, got %s", url)
+	}
+	if len(store.names) != 5 || store.names[4] != "paris_hls/master.m3u8" {
+		t.Errorf("Expected every file uploaded, the master last, got %v", store.names)
+	}
+	for i, name := range store.names {
+		if strings.HasSuffix(name, ".ts") != (store.types[i] == "video/mp2t") {
+			t.Errorf("Unexpected content type %s for %s", store.types[i], name)
+		}
+	}
+}
+
+func TestHLSArgs(t *testing.T) {
+	h := &HLS{Renditions: DefaultRenditions}
+	args := h.args("in.mp4", "/tmp/out")
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"split=3", "scale=w=720:h=-2", "-b:v:2 600k", "-var_stream_map v:0 v:1 v:2", "-master_pl_name master.m3u8", "-an"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in %s", want, joined)
+		}
+	}
+	if args[len(args)-1] != filepath.Join("/tmp/out", "%v", "index.m3u8") {
+		t.Errorf("Expected per-rendition playlists, got %s", args[len(args)-1])
+	}
+}
+
+func TestStreamObjects(t *testing.T) {
+	objects := map[string]string{
+		"v/p_hls/master.m3u8":  "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n0/index.m3u8\n",
+		"v/p_hls/0/index.m3u8": "#EXTM3U\n#EXTINF:2.0,\nsegment_000.ts\n#EXTINF:2.0,\nsegment_001.ts\n",
+	}
+	read := func(ctx context.Context, name string) ([]byte, error) { return []byte(objects[name]), nil }
+	got, err := StreamObjects(context.Background(), read, "v/p_hls/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v/p_hls/master.m3u8", "v/p_hls/0/index.m3u8", "v/p_hls/0/segment_000.ts", "v/p_hls/0/segment_001.ts"}
+	if !slices.Equal(got, want) {
+		t.Errorf("StreamObjects() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+// Package media derives assets from generated videos with ffmpeg.
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Poster frame selections, see Posters.Frame.
+const (
+	FrameFirst = "first" // The first frame, what the video shows before playing
+	FrameBest  = "best"  // ffmpeg's most representative frame of the clip
+	FrameOff   = "off"
+)
+
+// bestFrameWindow is how many frames the thumbnail filter compares; a Veo
+// clip is 8s at 24fps.
+const bestFrameWindow = 200
+
+// Posters extracts a poster frame from videos. Veo doesn't always start on
+// the input image exactly, so a frame of the video itself avoids a visual pop
+// when playback starts.
+type Posters struct {
+	Frame  string // FrameFirst or FrameBest
+	FFmpeg string // Path of the ffmpeg binary
+
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewPosters finds ffmpeg on the PATH. It returns nil for FrameOff.
+func NewPosters(frame string) (*Posters, error) {
+	switch frame {
+	case FrameOff:
+		return nil, nil
+	case FrameFirst, FrameBest:
+	default:
+		return nil, fmt.Errorf("poster frame must be first, best or off, got %q", frame)
+	}
+	bin, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	return &Posters{Frame: frame, FFmpeg: bin}, nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Extract returns the poster frame of the video at url (anything ffmpeg can
+// read, e.g. a public HTTPS URL) as a PNG at the video's resolution.
+func (p *Posters) Extract(ctx context.Context, url string) ([]byte, error) {
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", url}
+	if p.Frame == FrameBest {
+		args = append(args, "-vf", fmt.Sprintf("thumbnail=%d", bestFrameWindow))
+	}
+	args = append(args, "-frames:v", "1", "-f", "image2pipe", "-vcodec", "png", "-")
+
+	run := p.run
+	if run == nil {
+		run = runCommand
+	}
+	png, err := run(ctx, p.FFmpeg, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract poster: %w", err)
+	}
+	if len(png) == 0 {
+		return nil, fmt.Errorf("failed to extract poster: ffmpeg returned no frame")
+	}
+	return png, nil
+}
@@ -0,0 +1,59 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		frame  string
+		filter bool
+	}{
+		{FrameFirst, false},
+		{FrameBest, true},
+	}
+	for _, tt := range tests {
+		var got []string
+		p := &Posters{Frame: tt.frame, FFmpeg: "ffmpeg", run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			got = append([]string{name}, args...)
+			return []byte("png"), nil
+		}}
+		b, err := p.Extract(context.Background(), "https://example.com/v.mp4")
+		if err != nil || string(b) != "png" {
+			t.Fatalf("%s: Extract() = %q, %v", tt.frame, b, err)
+		}
+		if i := slices.Index(got, "-i"); i < 0 || got[i+1] != "https://example.com/v.mp4" {
+			t.Errorf("%s: expected the video as input, got %v", tt.frame, got)
+		}
+		if slices.Contains(got, "-vf") != tt.filter {
+			t.Errorf("%s: thumbnail filter = %v, want %v in %v", tt.frame, !tt.filter, tt.filter, got)
+		}
+		if i := slices.Index(got, "-frames:v"); i < 0 || got[i+1] != "1" {
+			t.Errorf("%s: expected a single frame, got %v", tt.frame, got)
+		}
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	for name, run := range map[string]func(context.Context, string, ...string) ([]byte, error){
+		"failed": func(context.Context, string, ...string) ([]byte, error) { return nil, errors.New("exit status 1") },
+		"empty":  func(context.Context, string, ...string) ([]byte, error) { return nil, nil },
+	} {
+		p := &Posters{Frame: FrameFirst, FFmpeg: "ffmpeg", run: run}
+		if _, err := p.Extract(context.Background(), "v.mp4"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNewPosters(t *testing.T) {
+	if p, err := NewPosters(FrameOff); p != nil || err != nil {
+		t.Errorf("NewPosters(off) = %v, %v; want nil, nil", p, err)
+	}
+	if _, err := NewPosters("middle"); err == nil {
+		t.Error("Expected an error for an unknown frame")
+	}
+}
@@ -0,0 +1,809 @@
+package mock
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/repo"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ repo.Repository = (*DB)(nil)
+
+// DB is an in-memory repo.Repository that behaves like the Firestore store,
+// except that feedback and reports only update the location's counters.
+// Nothing survives a restart.
+type DB struct {
+	mu         sync.Mutex
+	locations  map[string]database.Location
+	categories []database.Category
+	settings   map[string]any // Branding, location policies and runtime settings by doc ID
+	featured   map[string]database.FeaturedCity
+	prompts    map[string]database.PromptCacheEntry
+	operations map[string]database.PendingOperation
+	timings    map[string]database.ModelTimings
+	latency    []database.LatencySample
+	traces     map[string]database.FlowTrace
+	passes     map[string]database.PassRegistration
+	history    map[string][]database.LocationRevision // By location, oldest first
+	audit      []database.AuditEntry
+	candidates []database.Candidate
+	runs       map[string]database.Run
+	runItems   map[string]map[string]database.RunItem // By run, then preset
+	reporters  map[string]bool                        // Location ID + "/" + reporter, of counted reports
+}
+
+// NewDB returns an empty store.
+func NewDB() *DB {
+	return &DB{
+		locations:  map[string]database.Location{},
+		settings:   map[string]any{},
+		featured:   map[string]database.FeaturedCity{},
+		prompts:    map[string]database.PromptCacheEntry{},
+		operations: map[string]database.PendingOperation{},
+		timings:    map[string]database.ModelTimings{},
+		traces:     map[string]database.FlowTrace{},
+		passes:     map[string]database.PassRegistration{},
+		history:    map[string][]database.LocationRevision{},
+		runs:       map[string]database.Run{},
+		runItems:   map[string]map[string]database.RunItem{},
+		reporters:  map[string]bool{},
+	}
+}
+
+// Fixture is the JSON the mock server is seeded from.
+type Fixture struct {
+	Locations  []database.Location `json:"locations"`
+	Categories []database.Category `json:"categories,omitempty"`
+}
+
+// Load adds a fixture's locations and categories. Locations keep their
+// last_updated, so a fixture can include stale ones that regenerate.
+func (d *DB) Load(r io.Reader) (*Fixture, error) {
+	var f Fixture
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, loc := range f.Locations {
+		if loc.ID == "" {
+			return nil, fmt.Errorf("fixture location %q has no id", loc.Name)
+		}
+		if loc.LastUpdated.IsZero() {
+			loc.LastUpdated = time.Now()
+		}
+		d.locations[loc.ID] = loc
+	}
+	d.categories = append(d.categories, f.Categories...)
+	return &f, nil
+}
+
+func notFound(what, id string) error {
+	return status.Errorf(codes.NotFound, "%s %q not found", what, id)
+}
+
+// all returns every location, by ID.
+func (d *DB) all() []database.Location {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.SortedFunc(maps.Values(d.locations), func(a, b database.Location) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+// filter returns the locations keep accepts.
+func (d *DB) filter(keep func(l *database.Location) bool) []database.Location {
+	var out []database.Location
+	for _, l := range d.all() {
+		if keep(&l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// update applies fn to an existing location.
+func (d *DB) update(id string, fn func(l *database.Location)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l, ok := d.locations[id]
+	if !ok {
+		return notFound("location", id)
+	}
+	fn(&l)
+	d.locations[id] = l
+	return nil
+}
+
+func (d *DB) Close() error { return nil }
+
+// -- Locations --
+
+func (d *DB) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l, ok := d.locations[id]
+	if !ok {
+		return nil, notFound("location", id)
+	}
+	return &l, nil
+}
+
+func (d *DB) UpsertLocation(ctx context.Context, loc database.Location) error {
+	if loc.ID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	loc.LastUpdated = time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.locations[loc.ID] = loc
+	d.history[loc.ID] = append(d.history[loc.ID], database.LocationRevision{Location: loc, CreatedAt: loc.LastUpdated})
+	return nil
+}
+
+// PutLocation stores loc as given, keeping its LastUpdated.
+func (d *DB) PutLocation(ctx context.Context, loc database.Location) error {
+	if loc.ID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.locations[loc.ID] = loc
+	return nil
+}
+
+func (d *DB) DeleteLocation(ctx context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.locations, id)
+	return nil
+}
+
+func (d *DB) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	locs := d.filter(func(l *database.Location) bool {
+		switch opts.Type {
+		case "preset":
+			if !l.IsPreset {
+				return false
+			}
+		case "user":
+			if l.IsPreset {
+				return false
+			}
+		case "hidden":
+			if !l.IsHidden() {
+				return false
+			}
+		}
+		return opts.Status == "" || l.Status == opts.Status
+	})
+	if opts.Sort == "feedback" {
+		slices.SortStableFunc(locs, func(a, b database.Location) int { return cmp.Compare(a.FeedbackScore, b.FeedbackScore) })
+	} else {
+		slices.SortStableFunc(locs, func(a, b database.Location) int { return b.LastUpdated.Compare(a.LastUpdated) })
+	}
+	if opts.Limit > 0 && len(locs) > opts.Limit {
+		locs = locs[:opts.Limit]
+	}
+	return locs, nil
+}
+
+func (d *DB) GetPresets(ctx context.Context) ([]database.Location, error) {
+	return d.filter(func(l *database.Location) bool {
+		return l.IsPreset && !l.IsHidden() && l.Status != database.StatusArchived
+	}), nil
+}
+
+func (d *DB) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	var presets []database.PresetSummary
+	for _, l := range d.all() {
+		p := database.PresetSummary{
+			ID: l.ID, Name: l.Name, NameI18n: l.NameI18n, Category: l.Category,
+			ImageURL: l.ImageURL, VideoURL: l.VideoURL, PosterURL: l.PosterURL, StreamURL: l.StreamURL,
+			AltText: l.AltText, AudioURL: l.AudioURL, AudioI18n: l.AudioI18n, Continent: l.Continent, Status: l.Status, LastUpdated: l.LastUpdated,
+		}
+		if l.IsPreset && p.Visible() {
+			presets = append(presets, p)
+		}
+	}
+
+	slices.SortStableFunc(presets, func(a, b database.PresetSummary) int {
+		var c int
+		switch opts.Sort {
+		case database.SortName:
+			c = cmp.Compare(a.Name, b.Name)
+		case database.SortUpdated:
+			c = a.LastUpdated.Compare(b.LastUpdated)
+		default:
+			c = cmp.Compare(a.Category, b.Category)
+		}
+		if opts.Desc {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.Name, b.Name))
+	})
+	if opts.Sort == "" || opts.Sort == database.SortCategory {
+		categories, _ := d.ListCategories(ctx)
+		database.SortByCategoryOrder(presets, categories, opts.Desc)
+	}
+	return presets, nil
+}
+
+func (d *DB) GetLocationsByCountry(ctx context.Context, code string, limit int) ([]database.Location, error) {
+	locs := d.filter(func(l *database.Location) bool {
+		return strings.EqualFold(l.CountryCode, code) && !l.IsHidden() && l.Status != database.StatusArchived
+	})
+	slices.SortStableFunc(locs, func(a, b database.Location) int { return b.LastUpdated.Compare(a.LastUpdated) })
+	if limit > 0 && len(locs) > limit {
+		locs = locs[:limit]
+	}
+	return locs, nil
+}
+
+func (d *DB) GetStats(ctx context.Context) (*database.Stats, error) {
+	var s database.Stats
+	for _, l := range d.all() {
+		s.TotalLocations++
+		if l.IsPreset {
+			s.Presets++
+		}
+		if l.WeatherMismatch {
+			s.WeatherMismatches++
+		}
+		if l.LastUpdated.After(s.LastUpdated) {
+			s.LastUpdated = l.LastUpdated
+		}
+	}
+	s.UserGenerated = s.TotalLocations - s.Presets
+	return &s, nil
+}
+
+// SetStatus creates a stub location when id is new, like Firestore's merge.
+func (d *DB) SetStatus(ctx context.Context, id string, st database.LocationStatus) error {
+	if id == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l, ok := d.locations[id]
+	if !ok {
+		l.ID = id
+	}
+	l.Status = st
+	d.locations[id] = l
+	return nil
+}
+
+func (d *DB) SetGeo(ctx context.Context, id string, geo *latlng.LatLng, countryCode, continent string) error {
+	return d.update(id, func(l *database.Location) {
+		l.Geo, l.CountryCode, l.Continent = geo, countryCode, continent
+	})
+}
+
+func (d *DB) SetChecksums(ctx context.Context, id string, sums map[string]string) error {
+	return d.update(id, func(l *database.Location) { l.Checksums = sums })
+}
+
+func (d *DB) SetNameI18n(ctx context.Context, id string, names map[string]string) error {
+	return d.update(id, func(l *database.Location) { l.NameI18n = names })
+}
+
+func (d *DB) UpdateLocations(ctx context.Context, edits []database.LocationEdit) error {
+	for _, e := range edits {
+		for field := range e.Fields {
+			if !slices.Contains(database.EditableFields, field) {
+				return fmt.Errorf("field %q is not editable", field)
+			}
+		}
+	}
+	var firstErr error
+	for _, e := range edits {
+		err := d.update(e.ID, func(l *database.Location) {
+			for field, value := range e.Fields {
+				switch field {
+				case "name":
+					l.Name, _ = value.(string)
+				case "category":
+					l.Category, _ = value.(string)
+				case "city_query":
+					l.CityQuery, _ = value.(string)
+				case "status":
+					l.Status, _ = value.(database.LocationStatus)
+				case "is_preset":
+					l.IsPreset, _ = value.(bool)
+					if l.IsPreset {
+						l.ExpireAt = nil
+					}
+				case "country_code":
+					l.CountryCode, _ = value.(string)
+				case "continent":
+					l.Continent, _ = value.(string)
+				case "video_prompt":
+					l.VideoPrompt, _ = value.(string)
+				case "prompt_context":
+					l.PromptContext, _ = value.(string)
+				case "locked":
+					l.Locked, _ = value.(bool)
+				}
+			}
+		})
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to update %s: %w", e.ID, err)
+		}
+	}
+	return firstErr
+}
+
+// -- Moderation --
+
+func (d *DB) AddFeedback(ctx context.Context, id string, fb database.Feedback) error {
+	return d.update(id, func(l *database.Location) {
+		if fb.Up {
+			l.FeedbackUp++
+			l.FeedbackScore++
+		} else {
+			l.FeedbackDown++
+			l.FeedbackScore--
+		}
+		if fb.Reason != "" {
+			if l.FeedbackReasons == nil {
+				l.FeedbackReasons = map[string]int{}
+			}
+			l.FeedbackReasons[fb.Reason]++
+		}
+	})
+}
+
+func (d *DB) AddReport(ctx context.Context, id string, r database.Report, threshold int) (hidden bool, err error) {
+	err = d.update(id, func(l *database.Location) {
+		if r.Reporter != "" {
+			if d.reporters[id+"/"+r.Reporter] {
+				return
+			}
+			d.reporters[id+"/"+r.Reporter] = true
+		}
+		l.Reports++
+		if !l.IsHidden() && threshold > 0 && l.Reports >= threshold {
+			l.Status = database.StatusHiddenPendingReview
+			hidden = true
+		}
+	})
+	return hidden, err
+}
+
+func (d *DB) ResolveReview(ctx context.Context, id string) error {
+	return d.update(id, func(l *database.Location) {
+		l.Status, l.Reports = database.StatusReady, 0
+	})
+}
+
+// -- Settings --
+
+func tenantDoc(name, tenant string) string {
+	if tenant == "" {
+		return name
+	}
+	return name + "_" + tenant
+}
+
+// getSetting returns a copy of a settings doc, or a NotFound error.
+func getSetting[T any](d *DB, id string) (*T, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, ok := d.settings[id].(T)
+	if !ok {
+		return nil, notFound("settings", id)
+	}
+	return &v, nil
+}
+
+func (d *DB) setSetting(id string, v any) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.settings[id] = v
+	return nil
+}
+
+func (d *DB) GetBranding(ctx context.Context, tenant string) (*database.Branding, error) {
+	return getSetting[database.Branding](d, tenantDoc("branding", tenant))
+}
+
+func (d *DB) SetBranding(ctx context.Context, tenant string, b database.Branding) error {
+	return d.setSetting(tenantDoc("branding", tenant), b)
+}
+
+func (d *DB) GetLocationPolicy(ctx context.Context, tenant string) (*database.LocationPolicy, error) {
+	return getSetting[database.LocationPolicy](d, tenantDoc("location_policy", tenant))
+}
+
+func (d *DB) SetLocationPolicy(ctx context.Context, tenant string, p database.LocationPolicy) error {
+	return d.setSetting(tenantDoc("location_policy", tenant), p)
+}
+
+func (d *DB) GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error) {
+	s, err := getSetting[database.RuntimeSettings](d, "runtime")
+	if err != nil {
+		return &database.RuntimeSettings{}, nil
+	}
+	return s, nil
+}
+
+func (d *DB) UpdateRuntimeSettings(ctx context.Context, update func(s *database.RuntimeSettings)) (*database.RuntimeSettings, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, _ := d.settings["runtime"].(database.RuntimeSettings)
+	s.TenantKillSwitches = maps.Clone(s.TenantKillSwitches)
+	update(&s)
+	d.settings["runtime"] = s
+	return &s, nil
+}
+
+func (d *DB) ListCategories(ctx context.Context) ([]database.Category, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	categories := slices.Clone(d.categories)
+	slices.SortStableFunc(categories, func(a, b database.Category) int { return cmp.Compare(a.Order, b.Order) })
+	return categories, nil
+}
+
+func (d *DB) SetCategories(ctx context.Context, categories []database.Category) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.categories = slices.Clone(categories)
+	return nil
+}
+
+// -- Logs --
+
+func (d *DB) AddAuditEntry(ctx context.Context, e database.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.audit = append(d.audit, e)
+	return nil
+}
+
+func (d *DB) ListAuditEntries(ctx context.Context, location string, since, until time.Time) ([]database.AuditEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []database.AuditEntry
+	for _, e := range d.audit {
+		if e.Location == location && !e.CreatedAt.Before(since) && !e.CreatedAt.After(until) {
+			out = append(out, e)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b database.AuditEntry) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
+
+// AddRevision records a revision as if UpsertLocation had written it at
+// rev.CreatedAt (tests).
+func (d *DB) AddRevision(rev database.LocationRevision) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.history[rev.Location.ID] = append(d.history[rev.Location.ID], rev)
+}
+
+func (d *DB) GetLocationAsOf(ctx context.Context, id string, at time.Time) (*database.LocationRevision, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var found *database.LocationRevision
+	for _, rev := range d.history[id] {
+		if !rev.CreatedAt.After(at) && (found == nil || !rev.CreatedAt.Before(found.CreatedAt)) {
+			found = &rev
+		}
+	}
+	if found == nil {
+		return nil, notFound("revision of location", id)
+	}
+	return found, nil
+}
+
+func (d *DB) AddLatencySample(ctx context.Context, s database.LatencySample) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.latency = append(d.latency, s)
+	return nil
+}
+
+func (d *DB) ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []database.LatencySample
+	for _, s := range d.latency {
+		if !s.CreatedAt.Before(since) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (d *DB) SaveFlowTrace(ctx context.Context, t database.FlowTrace) error {
+	if t.ID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.traces[t.ID] = t
+	return nil
+}
+
+func (d *DB) GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.traces[id]
+	if !ok {
+		return nil, notFound("flow trace", id)
+	}
+	return &t, nil
+}
+
+func (d *DB) ListFlowTraces(ctx context.Context, limit int) ([]database.FlowTrace, error) {
+	d.mu.Lock()
+	traces := slices.Collect(maps.Values(d.traces))
+	d.mu.Unlock()
+	slices.SortFunc(traces, func(a, b database.FlowTrace) int { return b.StartedAt.Compare(a.StartedAt) })
+	if limit > 0 && len(traces) > limit {
+		traces = traces[:limit]
+	}
+	return traces, nil
+}
+
+// -- Candidates --
+
+func (d *DB) AddCandidate(ctx context.Context, cand database.Candidate) (string, error) {
+	if cand.LocationID == "" {
+		return "", fmt.Errorf("location ID is required")
+	}
+	if cand.CreatedAt.IsZero() {
+		cand.CreatedAt = time.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cand.ID = strconv.Itoa(len(d.candidates) + 1)
+	d.candidates = append(d.candidates, cand)
+	return cand.ID, nil
+}
+
+func (d *DB) ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []database.Candidate
+	for _, c := range d.candidates {
+		if c.LocationID == locationID {
+			out = append(out, c)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b database.Candidate) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return out, nil
+}
+
+func (d *DB) GetCandidate(ctx context.Context, id string) (*database.Candidate, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.candidates {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, notFound("candidate", id)
+}
+
+func (d *DB) SetCandidateStatus(ctx context.Context, id, status string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.candidates {
+		if d.candidates[i].ID == id {
+			now := time.Now()
+			d.candidates[i].Status, d.candidates[i].ReviewedAt = status, &now
+			return nil
+		}
+	}
+	return notFound("candidate", id)
+}
+
+func (d *DB) TransitionCandidate(ctx context.Context, id string, from []string, to string) (*database.Candidate, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.candidates {
+		if d.candidates[i].ID != id {
+			continue
+		}
+		cand := d.candidates[i]
+		if !slices.Contains(from, cand.Status) {
+			return nil, status.Errorf(codes.FailedPrecondition, "candidate %q is %s", id, cand.Status)
+		}
+		now := time.Now()
+		d.candidates[i].Status, d.candidates[i].ReviewedAt = to, &now
+		return &cand, nil
+	}
+	return nil, notFound("candidate", id)
+}
+
+// -- Runs --
+
+func (d *DB) GetRun(ctx context.Context, id string) (*database.Run, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	run, ok := d.runs[id]
+	if !ok {
+		return nil, notFound("run", id)
+	}
+	return &run, nil
+}
+
+func (d *DB) SaveRun(ctx context.Context, run database.Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("run ID is required")
+	}
+	run.UpdatedAt = time.Now()
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = run.UpdatedAt
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.runs[run.ID] = run
+	return nil
+}
+
+func (d *DB) SetRunItem(ctx context.Context, runID string, item database.RunItem) error {
+	if item.ID == "" {
+		return fmt.Errorf("item ID is required")
+	}
+	item.UpdatedAt = time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.runItems[runID] == nil {
+		d.runItems[runID] = map[string]database.RunItem{}
+	}
+	d.runItems[runID][item.ID] = item
+	return nil
+}
+
+func (d *DB) ListRunItems(ctx context.Context, runID string) ([]database.RunItem, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []database.RunItem
+	for _, item := range d.runItems[runID] {
+		out = append(out, item)
+	}
+	slices.SortFunc(out, func(a, b database.RunItem) int { return strings.Compare(a.ID, b.ID) })
+	return out, nil
+}
+
+// -- Veo Operations --
+
+func (d *DB) TrackOperation(ctx context.Context, op database.PendingOperation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.operations[op.Name] = op
+	return nil
+}
+
+func (d *DB) ClearOperation(ctx context.Context, name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.operations, name)
+	return nil
+}
+
+func (d *DB) ListOperations(ctx context.Context) ([]database.PendingOperation, error) {
+	d.mu.Lock()
+	ops := slices.Collect(maps.Values(d.operations))
+	d.mu.Unlock()
+	slices.SortFunc(ops, func(a, b database.PendingOperation) int { return a.StartedAt.Compare(b.StartedAt) })
+	return ops, nil
+}
+
+func (d *DB) RecordModelTiming(ctx context.Context, model string, dur time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.timings[model]
+	t.Model = model
+	t.Durations = append(t.Durations, dur.Seconds())
+	if n := len(t.Durations); n > database.MaxModelTimings {
+		t.Durations = t.Durations[n-database.MaxModelTimings:]
+	}
+	t.UpdatedAt = time.Now()
+	d.timings[model] = t
+	return nil
+}
+
+func (d *DB) GetModelTimings(ctx context.Context, model string) (*database.ModelTimings, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.timings[model]
+	t.Model = model
+	return &t, nil
+}
+
+// -- Prompt Cache --
+
+func (d *DB) GetPromptCache(ctx context.Context, key string) (*database.PromptCacheEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.prompts[key]
+	if !ok {
+		return nil, notFound("prompt cache entry", key)
+	}
+	return &e, nil
+}
+
+func (d *DB) PutPromptCache(ctx context.Context, e database.PromptCacheEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prompts[e.Key] = e
+	return nil
+}
+
+// -- City of the Day --
+
+func (d *DB) SetCityOfTheDay(ctx context.Context, f database.FeaturedCity) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.featured[f.Date] = f
+	return nil
+}
+
+func (d *DB) ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error) {
+	d.mu.Lock()
+	out := slices.Collect(maps.Values(d.featured))
+	d.mu.Unlock()
+	slices.SortFunc(out, func(a, b database.FeaturedCity) int { return cmp.Compare(b.Date, a.Date) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// -- Wallet Passes --
+
+func (d *DB) RegisterPass(ctx context.Context, r database.PassRegistration) (created bool, err error) {
+	if r.DeviceID == "" || r.Serial == "" {
+		return false, fmt.Errorf("device ID and serial are required")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := r.DeviceID + ":" + r.Serial
+	_, exists := d.passes[key]
+	d.passes[key] = r
+	return !exists, nil
+}
+
+func (d *DB) UnregisterPass(ctx context.Context, deviceID, serial string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.passes, deviceID+":"+serial)
+	return nil
+}
+
+func (d *DB) ListPassRegistrations(ctx context.Context, field, value string) ([]database.PassRegistration, error) {
+	if field != "device_id" && field != "serial" {
+		return nil, fmt.Errorf("can't list pass registrations by %q", field)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []database.PassRegistration
+	for _, r := range d.passes {
+		if (field == "device_id" && r.DeviceID == value) || (field == "serial" && r.Serial == value) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
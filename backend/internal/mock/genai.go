@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"time"
+
+	"banana-weather/internal/genai"
+	"banana-weather/internal/progress"
+)
+
+// GenAI stands in for Gemini and Veo. Images are gradients in colors derived
+// from the city and seed; videos are a configured clip. Both take their
+// configured latency, and video reports progress like Veo polling does.
+type GenAI struct {
+	ImageLatency time.Duration
+	VideoLatency time.Duration
+	// Video is the clip every generation returns: an object in Storage
+	// ("videos/sample.mp4") or a URL. Videos fail when it's empty.
+	Video string
+}
+
+// GenerateImage returns a 9:16 gradient PNG.
+func (g *GenAI) GenerateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32) (*genai.ImageResult, error) {
+	if err := sleep(ctx, g.ImageLatency); err != nil {
+		return nil, err
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", city, promptMode)
+	if seed != nil {
+		fmt.Fprintf(h, "/%d", *seed)
+	}
+	data, err := gradient(h.Sum32(), 360, 640)
+	if err != nil {
+		return nil, err
+	}
+	return &genai.ImageResult{
+		Images: []string{base64.StdEncoding.EncodeToString(data)},
+		Text:   fmt.Sprintf("Mock weather for %s.", city),
+		Model:  "mock-image",
+	}, nil
+}
+
+// GenerateVideo waits VideoLatency, emitting progress every second, and
+// returns the configured clip.
+func (g *GenAI) GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error) {
+	start := time.Now()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	done := time.After(g.VideoLatency)
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-done:
+			break wait
+		case <-tick.C:
+			elapsed := time.Since(start)
+			progress.Emit(ctx, progress.Update{
+				Stage:      "video",
+				Message:    "Animating (mock)",
+				Percent:    min(max(int(100*elapsed/g.VideoLatency), 1), 99),
+				ETASeconds: int((g.VideoLatency - elapsed).Round(time.Second).Seconds()),
+			})
+		}
+	}
+
+	if g.Video == "" {
+		return "", fmt.Errorf("mock: no video configured")
+	}
+	if strings.Contains(g.Video, "://") {
+		return g.Video, nil
+	}
+	return "gs://" + Bucket + "/" + g.Video, nil
+}
+
+// gradient renders a vertical two-color gradient picked from hash.
+func gradient(hash uint32, w, h int) ([]byte, error) {
+	top := color.RGBA{uint8(hash), uint8(hash >> 8), uint8(hash >> 16), 255}
+	bottom := color.RGBA{255 - top.R/2, 255 - top.G/2, 255 - top.B/2, 255}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		t := float64(y) / float64(h-1)
+		c := color.RGBA{
+			R: uint8(float64(top.R)*(1-t) + float64(bottom.R)*t),
+			G: uint8(float64(top.G)*(1-t) + float64(bottom.G)*t),
+			B: uint8(float64(top.B)*(1-t) + float64(bottom.B)*t),
+			A: 255,
+		}
+		for x := range w {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
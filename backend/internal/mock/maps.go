@@ -0,0 +1,99 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"banana-weather/internal/maps"
+)
+
+// Maps geocodes against the fixture: a query matching a location's name, ID
+// or city query resolves to it, anything else to made-up but stable
+// coordinates, so every city "exists".
+type Maps struct {
+	DB      *DB
+	Latency time.Duration // Added to every lookup
+}
+
+// GetCityLocation resolves a city name.
+func (m *Maps) GetCityLocation(ctx context.Context, city string) (*maps.Place, error) {
+	if err := sleep(ctx, m.Latency); err != nil {
+		return nil, err
+	}
+	q := strings.ToLower(strings.TrimSpace(city))
+	for _, loc := range m.DB.all() {
+		if strings.ToLower(loc.Name) == q || loc.ID == q || strings.ToLower(loc.CityQuery) == q {
+			return placeOf(loc.Name, loc.CountryCode, loc.Geo.GetLatitude(), loc.Geo.GetLongitude()), nil
+		}
+	}
+
+	// Stable coordinates from the name, away from the poles
+	h := fnv.New64a()
+	h.Write([]byte(q))
+	sum := h.Sum64()
+	lat := float64(sum%12000)/100 - 60
+	lng := float64((sum>>16)%36000)/100 - 180
+	return placeOf(titleCase(city), "", lat, lng), nil
+}
+
+// GetReverseGeocoding returns the nearest fixture location, or a place named
+// after the coordinates when there are none.
+func (m *Maps) GetReverseGeocoding(ctx context.Context, lat, lng float64) (*maps.Place, error) {
+	if err := sleep(ctx, m.Latency); err != nil {
+		return nil, err
+	}
+	best := math.Inf(1)
+	var place *maps.Place
+	for _, loc := range m.DB.all() {
+		if loc.Geo == nil {
+			continue
+		}
+		dLat, dLng := loc.Geo.Latitude-lat, loc.Geo.Longitude-lng
+		if d := dLat*dLat + dLng*dLng; d < best {
+			best = d
+			place = placeOf(loc.Name, loc.CountryCode, loc.Geo.Latitude, loc.Geo.Longitude)
+		}
+	}
+	if place == nil {
+		place = placeOf(fmt.Sprintf("%.2f, %.2f", lat, lng), "", lat, lng)
+	}
+	return place, nil
+}
+
+func placeOf(name, country string, lat, lng float64) *maps.Place {
+	return &maps.Place{
+		Name:        name,
+		Lat:         lat,
+		Lng:         lng,
+		CountryCode: country,
+		Continent:   maps.ContinentForCountry(country),
+	}
+}
+
+// titleCase capitalizes each word, as the geocoder's formatted names are.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// sleep waits d, or returns early when ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package mock
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"banana-weather/api"
+	"banana-weather/internal/database"
+	"banana-weather/internal/weather"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/client"
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDefaultFixture(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	fx, err := db.LoadDefault()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fx.Locations) == 0 || len(fx.Categories) == 0 {
+		t.Fatalf("fixture = %d locations, %d categories", len(fx.Locations), len(fx.Categories))
+	}
+
+	presets, err := db.GetPresetSummaries(ctx, database.PresetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range presets {
+		if p.ID == "seattle" {
+			t.Error("user location listed as a preset")
+		}
+	}
+	if len(presets) == 0 {
+		t.Fatal("no presets")
+	}
+
+	if _, err := db.GetLocation(ctx, "atlantis"); err == nil {
+		t.Error("GetLocation(atlantis) succeeded")
+	}
+}
+
+func TestMaps(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	if _, err := db.LoadDefault(); err != nil {
+		t.Fatal(err)
+	}
+	m := &Maps{DB: db}
+
+	p, err := m.GetCityLocation(ctx, "  paris ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Paris" || p.CountryCode != "FR" || p.Continent != "Europe" {
+		t.Errorf("paris = %+v", p)
+	}
+
+	a, _ := m.GetCityLocation(ctx, "new gotham")
+	b, _ := m.GetCityLocation(ctx, "New Gotham")
+	if a.Name != "New Gotham" || a.Lat != b.Lat || a.Lng != b.Lng {
+		t.Errorf("unknown city = %+v and %+v, want stable coordinates", a, b)
+	}
+
+	r, err := m.GetReverseGeocoding(ctx, 48.9, 2.4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "Paris" {
+		t.Errorf("reverse geocoding = %q, want the nearest fixture location", r.Name)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewStorage(t.TempDir(), "http://localhost:8080/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := s.UploadBytes(ctx, []byte("clip"), "videos/a.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "http://localhost:8080/media/videos/a.mp4" {
+		t.Errorf("url = %q", url)
+	}
+	if got, _ := s.PublicURL("gs://mock/videos/a.mp4"); got != url {
+		t.Errorf("PublicURL = %q, want %q", got, url)
+	}
+	if data, err := s.ReadObject(ctx, "videos/a.mp4"); err != nil || string(data) != "clip" {
+		t.Errorf("ReadObject = %q, %v", data, err)
+	}
+	if _, err := s.UploadBytes(ctx, nil, "../escape", "text/plain"); err == nil {
+		t.Error("upload outside Dir succeeded")
+	}
+}
+
+// TestWeatherFlow runs a city the fixture doesn't know through the real
+// handler and expects an image and the configured video, served from Storage.
+func TestWeatherFlow(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	if _, err := db.LoadDefault(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(nil)
+	store, err := NewStorage(t.TempDir(), "http://"+srv.Listener.Addr().String()+"/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UploadBytes(ctx, []byte("clip"), "videos/sample.mp4", "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	gen := &GenAI{Video: "videos/sample.mp4"}
+	if err := Seed(ctx, db, gen, store); err != nil {
+		t.Fatal(err)
+	}
+	if loc, _ := db.GetLocation(ctx, "arrakis_carthag"); loc.ImageURL == "" || loc.VideoURL == "" {
+		t.Errorf("seeded location = %+v, want media", loc)
+	}
+
+	svc := weather.NewService(&Maps{DB: db}, gen, store, db)
+	h := &api.Handler{DB: db, Weather: svc}
+	r := chi.NewRouter()
+	r.Get("/api/weather", h.HandleGetWeather)
+	srv.Config.Handler = r
+	srv.Start()
+	defer srv.Close()
+
+	stream, err := client.New(srv.URL).Weather(ctx, client.WeatherRequest{City: "Lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var result *events.ResultData
+	var video string
+	for ev := range stream.Events {
+		switch ev.Type {
+		case events.Result:
+			if result, err = ev.Result(); err != nil {
+				t.Fatal(err)
+			}
+		case events.Video:
+			video = ev.Data
+		case events.Error:
+			t.Errorf("error event: %s", ev.Data)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.City != "Lisbon" {
+		t.Fatalf("result = %+v", result)
+	}
+	if want := store.BaseURL + "/videos/sample.mp4"; video != want {
+		t.Errorf("video = %q, want %q", video, want)
+	}
+}
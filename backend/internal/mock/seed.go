@@ -0,0 +1,60 @@
+package mock
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+
+	"banana-weather/internal/database"
+)
+
+// DefaultFixture seeds the mock server when no fixture is given: presets in a
+// few categories (one without coordinates) and a stale user location, which
+// regenerates on its first lookup.
+//
+//go:embed fixture.json
+var DefaultFixture []byte
+
+// LoadDefault loads DefaultFixture.
+func (d *DB) LoadDefault() (*Fixture, error) {
+	return d.Load(bytes.NewReader(DefaultFixture))
+}
+
+// Seed gives locations without an image a generated one, and without a video
+// g's clip, so fixtures don't need media files. It doesn't wait for g's
+// latencies.
+func Seed(ctx context.Context, d *DB, g *GenAI, s *Storage) error {
+	quick := *g
+	quick.ImageLatency, quick.VideoLatency = 0, 0
+	for _, loc := range d.all() {
+		if loc.ImageURL != "" && (loc.VideoURL != "" || g.Video == "") {
+			continue
+		}
+		var imageURL, videoURL string
+		if loc.ImageURL == "" {
+			img, err := quick.GenerateImage(ctx, loc.Name, "", 0, loc.Seed)
+			if err != nil {
+				return err
+			}
+			if _, imageURL, err = s.UploadImage(ctx, img.Image(), "seed/"+loc.ID+".png"); err != nil {
+				return fmt.Errorf("failed to store image of %s: %w", loc.ID, err)
+			}
+		}
+		if loc.VideoURL == "" && g.Video != "" {
+			uri, err := quick.GenerateVideo(ctx, "", "", nil)
+			if err != nil {
+				return err
+			}
+			if videoURL, err = s.PublicURL(uri); err != nil {
+				return err
+			}
+		}
+		d.update(loc.ID, func(l *database.Location) {
+			l.ImageURL = cmp.Or(l.ImageURL, imageURL)
+			l.VideoURL = cmp.Or(l.VideoURL, videoURL)
+		})
+	}
+	return nil
+}
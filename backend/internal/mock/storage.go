@@ -0,0 +1,93 @@
+package mock
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Bucket is the bucket name in the gs:// URIs of Storage objects, so they
+// look like GCS to the pipeline and Veo.
+const Bucket = "mock"
+
+// Storage keeps media in a local directory, served by the mock server under
+// BaseURL (see http.FileServer).
+type Storage struct {
+	Dir     string
+	BaseURL string // e.g. "http://localhost:8080/media"
+}
+
+// NewStorage returns a store in dir, creating it.
+func NewStorage(dir, baseURL string) (*Storage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Storage{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *Storage) path(fileName string) (string, error) {
+	p := filepath.Join(s.Dir, filepath.FromSlash(fileName))
+	if !strings.HasPrefix(p, filepath.Clean(s.Dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object name %q", fileName)
+	}
+	return p, nil
+}
+
+// UploadImage stores a base64 image and returns its (gs://mock/ URI, URL).
+func (s *Storage) UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	url, err := s.UploadBytes(ctx, data, fileName, "image/png")
+	if err != nil {
+		return "", "", err
+	}
+	return "gs://" + Bucket + "/" + fileName, url, nil
+}
+
+// UploadBytes stores data and returns its URL. The type follows from the
+// file extension when served.
+func (s *Storage) UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error) {
+	p, err := s.path(fileName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return "", err
+	}
+	return s.BaseURL + "/" + fileName, nil
+}
+
+// ReadObject returns a stored object.
+func (s *Storage) ReadObject(ctx context.Context, fileName string) ([]byte, error) {
+	p, err := s.path(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+// ObjectName returns the object name of a URL served from BaseURL, or "".
+func (s *Storage) ObjectName(url string) string {
+	name, _ := strings.CutPrefix(url, s.BaseURL+"/")
+	if name == url {
+		return ""
+	}
+	return name
+}
+
+// PublicURL maps gs://mock/ URIs to their URL, for the pipeline's video
+// results; other URIs (fixture videos) are already URLs.
+func (s *Storage) PublicURL(objectURI string) (string, error) {
+	if name, ok := strings.CutPrefix(objectURI, "gs://"+Bucket+"/"); ok {
+		return s.BaseURL + "/" + name, nil
+	}
+	return objectURI, nil
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"banana-weather/internal/config"
+)
+
+// Email sends notifications as plain text mail through an SMTP server.
+type Email struct {
+	Addr string // host:port
+	From string
+	To   []string
+	Auth smtp.Auth // Optional
+
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmail returns an Email notifier for cfg, authenticating with PLAIN when
+// a username is set.
+func NewEmail(cfg config.EmailConfig) *Email {
+	e := &Email{Addr: cfg.SMTPAddr, From: cfg.From, To: cfg.To}
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		e.Auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return e
+}
+
+// message renders the mail. Headers can't contain line breaks.
+func (e *Email) message(subject, message string) []byte {
+	subject = strings.Join(strings.Fields(subject), " ")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+func (e *Email) Notify(ctx context.Context, subject, message string) error {
+	send := e.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(e.Addr, e.Auth, e.From, e.To, e.message(subject, message)); err != nil {
+		return fmt.Errorf("email failed: %w", err)
+	}
+	return nil
+}
+
+// Multi sends each notification to all of its notifiers.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, subject, message string) error {
+	var errs []error
+	for _, n := range m {
+		errs = append(errs, n.Notify(ctx, subject, message))
+	}
+	return errors.Join(errs...)
+}
+
+// Open returns the admin notifier configured in cfg: the ADMIN_WEBHOOK_URL
+// one (or Log), plus email when SMTP_ADDR and ADMIN_EMAILS are set.
+func Open(cfg *config.Config) Notifier {
+	n := New(cfg.AdminWebhookURL)
+	if cfg.Email.SMTPAddr == "" || len(cfg.Email.To) == 0 {
+		return n
+	}
+	return Multi{n, NewEmail(cfg.Email)}
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestEmail_Notify(t *testing.T) {
+	var got string
+	var to []string
+	e := &Email{Addr: "smtp.example.com:587", From: "banana@example.com", To: []string{"ops@example.com", "oncall@example.com"},
+		send: func(addr string, a smtp.Auth, from string, rcpt []string, msg []byte) error {
+			to, got = rcpt, string(msg)
+			return nil
+		}}
+	if err := e.Notify(context.Background(), "Generation\nalert", "Line one\nLine two"); err != nil {
+		t.Fatal(err)
+	}
+	if len(to) != 2 {
+		t.Errorf("Expected both recipients, got %v", to)
+	}
+	for _, want := range []string{"Subject: Generation alert\r\n", "To: ops@example.com, oncall@example.com\r\n", "\r\n\r\nLine one\r\nLine two\r\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in message:\n%s", want, got)
+		}
+	}
+}
+
+type failing struct{ calls *int }
+
+func (f failing) Notify(ctx context.Context, subject, message string) error {
+	*f.calls++
+	return fmt.Errorf("down")
+}
+
+func TestMulti_NotifiesAll(t *testing.T) {
+	calls := 0
+	if err := (Multi{failing{&calls}, failing{&calls}}).Notify(context.Background(), "s", "m"); err == nil || calls != 2 {
+		t.Errorf("Expected both notifiers called and the errors returned, got %d calls, %v", calls, err)
+	}
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier delivers operator-facing messages (takedowns, alerts, etc).
+type Notifier interface {
+	Notify(ctx context.Context, subject, message string) error
+}
+
+// Webhook posts {"subject": ..., "text": ...} to a URL. The "text" field makes
+// it work as-is with Slack and Google Chat incoming webhooks.
+type Webhook struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *Webhook) Notify(ctx context.Context, subject, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": subject,
+		"text":    fmt.Sprintf("*%s*\n%s", subject, message),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Log writes notifications to the server log. Used when no webhook is configured.
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, subject, message string) error {
+	log.Printf("NOTIFY: %s: %s", subject, message)
+	return nil
+}
+
+// New returns a Webhook notifier for url, or Log if url is empty.
+func New(url string) Notifier {
+	if url == "" {
+		return Log{}
+	}
+	return NewWebhook(url)
+}
@@ -0,0 +1,46 @@
+// Package openmeteo reads the observed weather from Open-Meteo.
+package openmeteo
+
+import "github.com/ghchinoy/banana-weather/backend/pkg/weatherdata"
+
+// The weather model and providers are shared with API clients, in package
+// weatherdata; these aliases keep the server's code in terms of this package.
+type (
+	Condition = weatherdata.Condition
+	Current   = weatherdata.Current
+	Day       = weatherdata.Day
+	Mood      = weatherdata.Mood
+	Client    = weatherdata.OpenMeteo
+)
+
+const (
+	ConditionClear        = weatherdata.ConditionClear
+	ConditionCloudy       = weatherdata.ConditionCloudy
+	ConditionFog          = weatherdata.ConditionFog
+	ConditionRain         = weatherdata.ConditionRain
+	ConditionSnow         = weatherdata.ConditionSnow
+	ConditionThunderstorm = weatherdata.ConditionThunderstorm
+
+	MoodGoldenSun    = weatherdata.MoodGoldenSun
+	MoodStarryNight  = weatherdata.MoodStarryNight
+	MoodSoftOvercast = weatherdata.MoodSoftOvercast
+	MoodMisty        = weatherdata.MoodMisty
+	MoodCozyRain     = weatherdata.MoodCozyRain
+	MoodCrispSnow    = weatherdata.MoodCrispSnow
+	MoodStormy       = weatherdata.MoodStormy
+)
+
+var (
+	Conditions = weatherdata.Conditions
+	Moods      = weatherdata.Moods
+)
+
+// NewClient returns an Open-Meteo client with a 10s timeout.
+func NewClient() *Client {
+	return weatherdata.NewOpenMeteo(nil)
+}
+
+// ConditionForCode maps a WMO weather interpretation code to a Condition.
+func ConditionForCode(code int) Condition {
+	return weatherdata.ConditionForCode(code)
+}
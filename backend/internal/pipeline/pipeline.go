@@ -0,0 +1,478 @@
+// Package pipeline runs the image -> upload -> Veo sequence shared by the web
+// flow, cache warming, admin refresh and the CLI generators.
+package pipeline
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/storage"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+// Generator produces the image and video. *genai.Service implements it.
+type Generator interface {
+	GenerateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32) (*genai.ImageResult, error)
+	GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error)
+}
+
+// ImageGenerator is implemented by generators that support WithAspect and
+// WithReference (*genai.Service).
+type ImageGenerator interface {
+	GenerateImageWith(ctx context.Context, city string, extraContext string, promptMode int, seed *int32, opts genai.ImageOptions) (*genai.ImageResult, error)
+}
+
+// Uploader stores an image and returns (objectURI, publicURL). storage.Store implements it.
+type Uploader interface {
+	UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error)
+}
+
+// URLResolver is implemented by uploaders that serve video output
+// themselves (mock.Storage); Veo's gs:// output gets storage.PublicURL
+// otherwise.
+type URLResolver interface {
+	PublicURL(objectURI string) (string, error)
+}
+
+// PosterExtractor returns a frame of a video as a PNG. *media.Posters implements it.
+type PosterExtractor interface {
+	Extract(ctx context.Context, videoURL string) ([]byte, error)
+}
+
+// StreamTranscoder packages a video for adaptive streaming under name and
+// returns the URL of its master playlist. *media.HLS implements it.
+type StreamTranscoder interface {
+	Transcode(ctx context.Context, videoURL, name string) (string, error)
+}
+
+// Describer writes the alt text of an image. *genai.Service implements it.
+type Describer interface {
+	DescribeImage(ctx context.Context, imageBase64 string, city string) (string, error)
+}
+
+// Errors wrapped by Generate, identifying the stage that failed.
+var (
+	ErrImage  = errors.New("image gen failed")
+	ErrUpload = errors.New("image upload failed")
+	ErrVideo  = errors.New("video gen failed")
+)
+
+// Pipeline holds the services a generation runs against.
+type Pipeline struct {
+	GenAI      Generator
+	Storage    Uploader           // Optional: without it Generate stops after the image
+	Originals  Uploader           // Optional: private store for images before watermark/AI badge
+	Provenance *provenance.Signer // Optional: content credentials embedded before upload
+	Clock      clock.Clock        // Credential timestamps; the system clock when nil
+	Posters    PosterExtractor    // Optional: uploads a frame of the video as its poster
+	Streams    StreamTranscoder   // Optional: transcodes the video to HLS
+	Describer  Describer          // Optional: writes alt text for each uploaded image
+	Hooks      *hooks.Registry    // Optional: deployment hooks around the generation
+	Events     *eventbus.Bus      // Optional: lifecycle events for downstream consumers
+	Quota      *quota.Manager     // Optional: caps concurrent generations (quota.KindGeneration)
+}
+
+// Request is what to generate.
+type Request struct {
+	ID       string // Location ID, recorded in the content credentials
+	City     string // City query passed to the prompt
+	Context  string // Optional extra prompt context
+	FileName string // Object name for the image (the poster adds _poster, the stream _hls/); image_<unixnano>.png when empty
+}
+
+// Result is the outcome of Generate. Seed is the seed actually used, which
+// an image func may have changed (e.g. when regenerating).
+type Result struct {
+	Image     *genai.ImageResult
+	ImageURI  string // gs:// or s3:// URI; empty without Storage
+	ImageURL  string // Public URL; empty without Storage
+	VideoURL  string // Empty with SkipVideo
+	PosterURL string // Frame of the video; empty without Posters or when extraction failed
+	StreamURL string // HLS master playlist; empty without Streams or when transcoding failed
+	AltText   string // Description of the image for screen readers; empty without Describer or when it failed
+	Seed      int32
+	Usage     []database.ModelUsage // Billed model calls, including earlier ones tracked on the context (see costs.Track)
+	Checksums map[string]string     // CRC32C of the image and poster uploaded, by URL (see Location.RecordChecksums)
+}
+
+// addChecksum records the checksum of the media uploaded to url.
+func (r *Result) addChecksum(url, sum string) {
+	if url == "" || sum == "" {
+		return
+	}
+	if r.Checksums == nil {
+		r.Checksums = map[string]string{}
+	}
+	r.Checksums[url] = sum
+}
+
+// Metadata returns the image's generation metadata with Usage attached, for
+// saving on the location. It's nil without an image.
+func (r *Result) Metadata() *database.GenerationMetadata {
+	if r.Image == nil {
+		return nil
+	}
+	m := r.Image.Metadata()
+	m.Usage = r.Usage
+	return m
+}
+
+// ImageFunc replaces the image step, see WithImageFunc. req.Context includes
+// changes by BeforePrompt hooks.
+type ImageFunc func(ctx context.Context, req Request, seed *int32) (*genai.ImageResult, error)
+
+type options struct {
+	style       int
+	aspect      string
+	seed        *int32
+	videoPrompt string
+	skipVideo   bool
+	skipUpload  bool
+	reference   *genai.Reference
+	imageFunc   ImageFunc
+	fromImage   string
+	onImage     func(*genai.ImageResult)
+	onUpload    func(*Result)
+}
+
+// Option configures a single Generate call.
+type Option func(*options)
+
+// WithStyle sets the prompt style: 0=Random, 1=Classic, 2=Drink.
+func WithStyle(style int) Option {
+	return func(o *options) { o.style = style }
+}
+
+// WithAspect sets the image aspect ratio. Veo only animates genai.DefaultAspect,
+// so other ratios need SkipVideo.
+func WithAspect(aspect string) Option {
+	return func(o *options) { o.aspect = aspect }
+}
+
+// WithSeed fixes the seed; a random one is picked otherwise, which the
+// prompt cache ignores (see genai.WithRandomSeed).
+func WithSeed(seed int32) Option {
+	return func(o *options) { o.seed = &seed }
+}
+
+// WithVideoPrompt sets the Veo motion prompt; "" is genai.DefaultVideoPrompt.
+func WithVideoPrompt(prompt string) Option {
+	return func(o *options) { o.videoPrompt = prompt }
+}
+
+// SkipVideo stops after the image upload.
+func SkipVideo() Option {
+	return func(o *options) { o.skipVideo = true }
+}
+
+// SkipUpload stops after the image (and OnImage), for callers that upload it
+// themselves with Upload.
+func SkipUpload() Option {
+	return func(o *options) { o.skipUpload = true }
+}
+
+// WithReference conditions the image on a user photo.
+func WithReference(ref *genai.Reference) Option {
+	return func(o *options) { o.reference = ref }
+}
+
+// WithImageFunc replaces the image step, e.g. to verify the depicted weather
+// and regenerate. Style, aspect and reference options don't apply to it.
+func WithImageFunc(fn ImageFunc) Option {
+	return func(o *options) { o.imageFunc = fn }
+}
+
+// WithImage uses an image that was already generated (and previewed).
+func WithImage(img *genai.ImageResult) Option {
+	return WithImageFunc(func(context.Context, Request, *int32) (*genai.ImageResult, error) { return img, nil })
+}
+
+// FromImage skips the image and animates one already in the bucket, given by
+// its public or gs:// URL.
+func FromImage(url string) Option {
+	return func(o *options) { o.fromImage = url }
+}
+
+// OnImage is called with the stamped image before it's uploaded.
+func OnImage(fn func(*genai.ImageResult)) Option {
+	return func(o *options) { o.onImage = fn }
+}
+
+// OnUpload is called once the image is uploaded, before Veo starts.
+func OnUpload(fn func(*Result)) Option {
+	return func(o *options) { o.onUpload = fn }
+}
+
+func (p *Pipeline) now() time.Time {
+	if p.Clock == nil {
+		return time.Now()
+	}
+	return p.Clock.Now()
+}
+
+// Generate runs the pipeline for req. Errors wrap ErrImage, ErrUpload or
+// ErrVideo; after ErrUpload or ErrVideo the partial Result is returned too,
+// so callers can keep the image. OnError hooks and a failed event see every
+// error.
+func (p *Pipeline) Generate(ctx context.Context, req Request, opts ...Option) (*Result, error) {
+	ctx, usage := costs.Track(ctx)
+	p.publish(ctx, events.GenerationStarted, req, nil, nil)
+	res, err := p.generate(ctx, req, opts...)
+	if err != nil {
+		p.Hooks.Run(ctx, hooks.OnError, &hooks.Generation{LocationID: req.ID, City: req.City, Context: req.Context, Err: err})
+		p.publish(ctx, events.Failed, req, res, err)
+	}
+	if res != nil {
+		res.Usage = usage.Calls()
+	}
+	return res, err
+}
+
+func (p *Pipeline) generate(ctx context.Context, req Request, opts ...Option) (*Result, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.aspect != "" && o.aspect != genai.DefaultAspect && !o.skipVideo {
+		return nil, fmt.Errorf("aspect %s can't be animated, use SkipVideo", o.aspect)
+	}
+	ctx = genai.WithRandomSeed(ctx, o.seed == nil)
+	if o.seed == nil {
+		v := rand.Int32N(math.MaxInt32)
+		o.seed = &v
+	}
+
+	// Without capacity, the generation fails at the stage it starts with
+	release, err := p.Quota.Acquire(ctx, quota.KindGeneration, "")
+	if err != nil {
+		if o.fromImage != "" {
+			return nil, fmt.Errorf("%w: %w", ErrVideo, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrImage, err)
+	}
+	defer release()
+
+	res := &Result{}
+	fileName := req.FileName
+	if fileName == "" {
+		fileName = fmt.Sprintf("image_%d.png", time.Now().UnixNano())
+	}
+
+	if o.fromImage != "" {
+		uri, err := storage.GSURI(o.fromImage)
+		if err != nil {
+			return nil, fmt.Errorf("can't animate stored image: %w", err)
+		}
+		res.ImageURL = o.fromImage
+		res.ImageURI = uri
+		res.Seed = *o.seed
+	} else {
+		g := &hooks.Generation{LocationID: req.ID, City: req.City, Context: req.Context, Seed: *o.seed}
+		if err := p.Hooks.Run(ctx, hooks.BeforePrompt, g); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImage, err)
+		}
+		req.Context = g.Context
+		img, err := p.image(ctx, req, &o)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImage, err)
+		}
+		// Before the stamp, so the content credentials cover hook changes
+		g.Image, g.Seed = img, *o.seed
+		if err := p.Hooks.Run(ctx, hooks.AfterImage, g); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImage, err)
+		}
+		img = g.Image
+		img.Stamp(p.Provenance, req.ID, p.now())
+		res.Image = img
+		res.Seed = *o.seed
+		if o.onImage != nil {
+			o.onImage(img)
+		}
+		if p.Storage == nil || o.skipUpload {
+			return res, nil
+		}
+		// Alongside the upload and Veo; done by the time Generate returns
+		described := p.describeAsync(ctx, img, req.City)
+		defer func() { res.AltText = <-described }()
+		if res.ImageURI, res.ImageURL, err = p.Upload(ctx, img, fileName); err != nil {
+			return res, err
+		}
+		res.addChecksum(res.ImageURL, storage.ImageCRC32C(img.Image()))
+		p.publish(ctx, events.ImageReady, req, res, nil)
+	}
+	if o.onUpload != nil {
+		o.onUpload(res)
+	}
+	if o.skipVideo {
+		return res, nil
+	}
+
+	progress.Logf(ctx, "Generating video (Veo)...")
+	videoGsURI, err := p.GenAI.GenerateVideo(ctx, res.ImageURI, o.videoPrompt, o.seed)
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrVideo, err)
+	}
+	res.VideoURL, err = p.publicURL(videoGsURI)
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrVideo, err)
+	}
+	progress.Logf(ctx, "Video generated: %s", res.VideoURL)
+	res.PosterURL = p.poster(ctx, res, fileName)
+	res.StreamURL = p.stream(ctx, res, fileName)
+	p.Hooks.Run(ctx, hooks.AfterVideo, &hooks.Generation{
+		LocationID: req.ID,
+		City:       req.City,
+		Context:    req.Context,
+		Seed:       res.Seed,
+		Image:      res.Image,
+		ImageURL:   res.ImageURL,
+		VideoURL:   res.VideoURL,
+		PosterURL:  res.PosterURL,
+		StreamURL:  res.StreamURL,
+	})
+	p.publish(ctx, events.VideoReady, req, res, nil)
+	return res, nil
+}
+
+// publish sends a lifecycle event of req's generation to p.Events, with the
+// media of res so far.
+func (p *Pipeline) publish(ctx context.Context, typ string, req Request, res *Result, err error) {
+	if p.Events == nil {
+		return
+	}
+	e := events.Lifecycle{Type: typ, LocationID: req.ID, City: req.City}
+	if res != nil {
+		e.ImageURL, e.VideoURL, e.PosterURL, e.StreamURL = res.ImageURL, res.VideoURL, res.PosterURL, res.StreamURL
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.Events.Publish(ctx, e)
+}
+
+// stream transcodes the generated video to HLS, named after the image. The
+// MP4 stays the fallback, so failures are only logged.
+func (p *Pipeline) stream(ctx context.Context, res *Result, fileName string) string {
+	if p.Streams == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fileName, ".png") + "_hls"
+	url, err := p.Streams.Transcode(ctx, res.VideoURL, name)
+	if err != nil {
+		progress.Logf(ctx, "No HLS stream for %s: %v", res.VideoURL, err)
+		return ""
+	}
+	progress.Logf(ctx, "HLS stream: %s", url)
+	return url
+}
+
+// Upload stores a generated image as fileName, plus its original, and returns
+// (objectURI, publicURL). It's the step of Generate between the image and Veo,
+// for callers running the steps separately. Errors wrap ErrUpload.
+func (p *Pipeline) Upload(ctx context.Context, img *genai.ImageResult, fileName string) (string, string, error) {
+	if p.Storage == nil {
+		return "", "", fmt.Errorf("%w: no storage", ErrUpload)
+	}
+	uri, url, err := p.Storage.UploadImage(ctx, img.Image(), fileName)
+	p.keepOriginal(ctx, img, fileName)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrUpload, err)
+	}
+	progress.Logf(ctx, "Image uploaded: %s", url)
+	return uri, url, nil
+}
+
+// Describe returns the alt text of a generated image, or "" without a
+// Describer. It's the step Generate runs alongside the upload, for callers
+// running the steps separately. Alt text is optional, so failures are only
+// logged.
+func (p *Pipeline) Describe(ctx context.Context, img *genai.ImageResult, city string) string {
+	if p.Describer == nil {
+		return ""
+	}
+	alt, err := p.Describer.DescribeImage(ctx, img.Image(), city)
+	if err != nil {
+		progress.Logf(ctx, "No alt text for %s: %v", city, err)
+		return ""
+	}
+	return alt
+}
+
+// describeAsync runs Describe in the background; the channel gets its result.
+func (p *Pipeline) describeAsync(ctx context.Context, img *genai.ImageResult, city string) <-chan string {
+	out := make(chan string, 1)
+	go func() { out <- p.Describe(ctx, img, city) }()
+	return out
+}
+
+// publicURL resolves Veo's output URI through the storage when it can.
+func (p *Pipeline) publicURL(uri string) (string, error) {
+	if r, ok := p.Storage.(URLResolver); ok {
+		return r.PublicURL(uri)
+	}
+	return storage.PublicURL(uri)
+}
+
+// poster uploads a frame of the generated video next to the image, named
+// after it. The poster is optional, so failures are only logged.
+func (p *Pipeline) poster(ctx context.Context, res *Result, fileName string) string {
+	if p.Posters == nil || p.Storage == nil {
+		return ""
+	}
+	frame, err := p.Posters.Extract(ctx, res.VideoURL)
+	if err != nil {
+		progress.Logf(ctx, "No poster for %s: %v", res.VideoURL, err)
+		return ""
+	}
+	name := strings.TrimSuffix(fileName, ".png") + "_poster.png"
+	_, url, err := p.Storage.UploadImage(ctx, base64.StdEncoding.EncodeToString(frame), name)
+	if err != nil {
+		progress.Logf(ctx, "Failed to upload poster %s: %v", name, err)
+		return ""
+	}
+	res.addChecksum(url, storage.CRC32C(frame))
+	return url
+}
+
+// image runs the image step selected by the options.
+func (p *Pipeline) image(ctx context.Context, req Request, o *options) (*genai.ImageResult, error) {
+	if o.imageFunc != nil {
+		return o.imageFunc(ctx, req, o.seed)
+	}
+	progress.Logf(ctx, "Generating image for '%s' (Style: %d, Seed: %d)...", req.City, o.style, *o.seed)
+	if o.aspect == "" && o.reference == nil {
+		return p.GenAI.GenerateImage(ctx, req.City, req.Context, o.style, o.seed)
+	}
+	g, ok := p.GenAI.(ImageGenerator)
+	if !ok {
+		return nil, fmt.Errorf("generator doesn't support aspect or reference options")
+	}
+	return g.GenerateImageWith(ctx, req.City, req.Context, o.style, o.seed, genai.ImageOptions{Aspect: o.aspect, Reference: o.reference})
+}
+
+// keepOriginal uploads the unmarked model output, when there is one, to the
+// originals store under the public image's file name.
+func (p *Pipeline) keepOriginal(ctx context.Context, img *genai.ImageResult, fileName string) {
+	if p.Originals == nil || img.Original == "" {
+		return
+	}
+	if _, _, err := p.Originals.UploadImage(ctx, img.Original, fileName); err != nil {
+		progress.Logf(ctx, "Failed to keep original %s: %v", fileName, err)
+	}
+}
@@ -0,0 +1,465 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/storage"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+type fakeGenAI struct {
+	imageErr, videoErr error
+	videoInput         string
+	videoPrompt        string
+	extra              string
+	opts               *genai.ImageOptions
+}
+
+func (f *fakeGenAI) GenerateImage(ctx context.Context, city, extra string, mode int, seed *int32) (*genai.ImageResult, error) {
+	f.extra = extra
+	if f.imageErr != nil {
+		return nil, f.imageErr
+	}
+	costs.Record(ctx, database.ModelUsage{Model: "test-model", PromptTokens: 100, OutputTokens: 1000})
+	return &genai.ImageResult{Images: []string{"aW1n"}, Original: "b3JpZw==", Model: "test-model"}, nil
+}
+func (f *fakeGenAI) GenerateImageWith(ctx context.Context, city, extra string, mode int, seed *int32, opts genai.ImageOptions) (*genai.ImageResult, error) {
+	f.opts = &opts
+	return f.GenerateImage(ctx, city, extra, mode, seed)
+}
+func (f *fakeGenAI) GenerateVideo(ctx context.Context, inputURI, prompt string, seed *int32) (string, error) {
+	f.videoInput = inputURI
+	f.videoPrompt = prompt
+	if f.videoErr == nil {
+		costs.Record(ctx, database.ModelUsage{Model: "test-veo", VideoSeconds: 8})
+	}
+	return "gs://bucket/videos/v.mp4", f.videoErr
+}
+
+type fakeUploader struct {
+	uploaded []string
+	err      error
+}
+
+func (f *fakeUploader) UploadImage(ctx context.Context, data, name string) (string, string, error) {
+	f.uploaded = append(f.uploaded, name)
+	return "gs://bucket/" + name, "https://storage.googleapis.com/bucket/" + name, f.err
+}
+
+func TestGenerate(t *testing.T) {
+	g := &fakeGenAI{}
+	store, originals := &fakeUploader{}, &fakeUploader{}
+	p := &Pipeline{GenAI: g, Storage: store, Originals: originals}
+
+	var events []string
+	res, err := p.Generate(context.Background(), Request{ID: "paris", City: "Paris", FileName: "p.png"},
+		WithSeed(7),
+		OnImage(func(*genai.ImageResult) { events = append(events, "image") }),
+		OnUpload(func(*Result) { events = append(events, "upload") }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Seed != 7 || res.ImageURI != "gs://bucket/p.png" || res.VideoURL != "https://storage.googleapis.com/bucket/videos/v.mp4" {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if len(events) != 2 || events[0] != "image" || events[1] != "upload" {
+		t.Errorf("Expected image then upload callbacks, got %v", events)
+	}
+	if len(originals.uploaded) != 1 || originals.uploaded[0] != "p.png" {
+		t.Errorf("Expected the original kept under the same name, got %v", originals.uploaded)
+	}
+}
+
+// TestGenerate_Usage runs the image and video as separate generations, like
+// the web flow, under one tracker.
+func TestGenerate_Usage(t *testing.T) {
+	p := &Pipeline{GenAI: &fakeGenAI{}, Storage: &fakeUploader{}}
+	ctx, _ := costs.Track(context.Background())
+
+	img, err := p.Generate(ctx, Request{City: "Paris"}, SkipVideo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := img.Metadata(); len(m.Usage) != 1 || m.Usage[0].Model != "test-model" || m.Model != "test-model" {
+		t.Errorf("image metadata = %+v", m)
+	}
+
+	video, err := p.Generate(ctx, Request{City: "Paris"}, FromImage(img.ImageURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(video.Usage) != 2 || video.Usage[1].VideoSeconds != 8 {
+		t.Errorf("usage = %+v, want the image and the video", video.Usage)
+	}
+	if video.Metadata() != nil {
+		t.Error("metadata without an image")
+	}
+
+	// Without a tracker on the context, each generation has its own
+	res, err := p.Generate(context.Background(), Request{City: "Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Usage) != 2 {
+		t.Errorf("usage = %+v", res.Usage)
+	}
+}
+
+func TestGenerate_Stages(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name      string
+		g         *fakeGenAI
+		uploadErr error
+		want      error
+		partial   bool
+	}{
+		{"image", &fakeGenAI{imageErr: boom}, nil, ErrImage, false},
+		{"upload", &fakeGenAI{}, boom, ErrUpload, true},
+		{"video", &fakeGenAI{videoErr: boom}, nil, ErrVideo, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{GenAI: tt.g, Storage: &fakeUploader{err: tt.uploadErr}}
+			res, err := p.Generate(context.Background(), Request{City: "Paris"})
+			if !errors.Is(err, tt.want) || !errors.Is(err, boom) {
+				t.Errorf("Expected %v wrapping boom, got %v", tt.want, err)
+			}
+			if (res != nil) != tt.partial {
+				t.Errorf("partial result = %+v, want %v", res, tt.partial)
+			}
+		})
+	}
+}
+
+func TestGenerate_Capacity(t *testing.T) {
+	q := quota.New(map[string]quota.Limit{quota.KindGeneration: {InFlight: 1}})
+	release, err := q.Acquire(context.Background(), quota.KindGeneration, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &fakeGenAI{}
+	p := &Pipeline{GenAI: g, Storage: &fakeUploader{}, Quota: q}
+
+	// The stage a generation starts with fails
+	if _, err := p.Generate(context.Background(), Request{City: "Paris"}); !errors.Is(err, ErrImage) || !errors.Is(err, quota.ErrExhausted) {
+		t.Errorf("Expected ErrImage wrapping ErrExhausted, got %v", err)
+	}
+	_, err = p.Generate(context.Background(), Request{City: "Paris"}, FromImage("https://storage.googleapis.com/bucket/p.png"))
+	if !errors.Is(err, ErrVideo) || !errors.Is(err, quota.ErrExhausted) || g.videoInput != "" {
+		t.Errorf("Expected ErrVideo wrapping ErrExhausted before Veo, got %v", err)
+	}
+
+	release()
+	if _, err := p.Generate(context.Background(), Request{City: "Paris"}); err != nil {
+		t.Fatal(err)
+	}
+	if u := q.Usage(); u[0].InFlight != 0 || u[0].Rejected != 2 {
+		t.Errorf("Expected the slot released after the generation, got %+v", u)
+	}
+}
+
+func TestGenerate_Options(t *testing.T) {
+	g := &fakeGenAI{}
+	p := &Pipeline{GenAI: g, Storage: &fakeUploader{}}
+	ctx := context.Background()
+
+	if _, err := p.Generate(ctx, Request{City: "Paris"}, WithAspect("1:1")); err == nil {
+		t.Error("Expected a non-9:16 aspect to require SkipVideo")
+	}
+
+	res, err := p.Generate(ctx, Request{City: "Paris"}, WithAspect("1:1"), SkipVideo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.opts == nil || g.opts.Aspect != "1:1" || res.VideoURL != "" || g.videoInput != "" {
+		t.Errorf("Expected a 1:1 image and no video, got opts %+v, result %+v", g.opts, res)
+	}
+
+	store := &fakeUploader{}
+	p.Storage = store
+	res, err = p.Generate(ctx, Request{City: "Paris"}, FromImage("https://storage.googleapis.com/bucket/old.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.uploaded) != 0 || g.videoInput != "gs://bucket/old.png" || res.Image != nil {
+		t.Errorf("Expected the stored image animated without a new upload, got input %q, uploads %v", g.videoInput, store.uploaded)
+	}
+	if g.videoPrompt != "" {
+		t.Errorf("Expected the default video prompt, got %q", g.videoPrompt)
+	}
+
+	if _, err := p.Generate(ctx, Request{City: "Venice"}, WithVideoPrompt("Gondolas glide along the canal")); err != nil {
+		t.Fatal(err)
+	}
+	if g.videoPrompt != "Gondolas glide along the canal" {
+		t.Errorf("Expected the custom video prompt, got %q", g.videoPrompt)
+	}
+}
+
+type fakePosters struct {
+	video string
+	err   error
+}
+
+func (f *fakePosters) Extract(ctx context.Context, videoURL string) ([]byte, error) {
+	f.video = videoURL
+	return []byte("png"), f.err
+}
+
+func TestGenerate_Poster(t *testing.T) {
+	store, posters := &fakeUploader{}, &fakePosters{}
+	p := &Pipeline{GenAI: &fakeGenAI{}, Storage: store, Posters: posters}
+	res, err := p.Generate(context.Background(), Request{City: "Paris", FileName: "paris.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posters.video != res.VideoURL {
+		t.Errorf("Expected a frame of %s, got %s", res.VideoURL, posters.video)
+	}
+	if res.PosterURL != "https://storage.googleapis.com/bucket/paris_poster.png" {
+		t.Errorf("Unexpected poster URL %q (uploaded %v)", res.PosterURL, store.uploaded)
+	}
+	if res.Checksums[res.PosterURL] != storage.CRC32C([]byte("png")) || res.Checksums[res.ImageURL] == "" {
+		t.Errorf("Expected the image and poster checksums, got %v", res.Checksums)
+	}
+
+	// A failed extraction leaves the generation successful, without a poster
+	posters.err = errors.New("no ffmpeg")
+	res, err = p.Generate(context.Background(), Request{City: "Paris", FileName: "paris.png"})
+	if err != nil || res.PosterURL != "" || res.VideoURL == "" {
+		t.Errorf("Expected video without poster, got %+v, %v", res, err)
+	}
+}
+
+type fakeStreams struct {
+	video, name string
+}
+
+func (f *fakeStreams) Transcode(ctx context.Context, videoURL, name string) (string, error) {
+	f.video, f.name = videoURL, name
+	return "https://storage.googleapis.com/bucket/videos/" + name + "/master.m3u8", nil
+}
+
+func TestGenerate_Stream(t *testing.T) {
+	streams := &fakeStreams{}
+	p := &Pipeline{GenAI: &fakeGenAI{}, Storage: &fakeUploader{}, Streams: streams}
+	res, err := p.Generate(context.Background(), Request{City: "Paris", FileName: "paris.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streams.video != res.VideoURL || streams.name != "paris_hls" {
+		t.Errorf("Expected %s transcoded as paris_hls, got %s as %s", res.VideoURL, streams.video, streams.name)
+	}
+	if res.StreamURL != "https://storage.googleapis.com/bucket/videos/paris_hls/master.m3u8" {
+		t.Errorf("Unexpected stream URL %q", res.StreamURL)
+	}
+}
+
+type fakeDescriber struct {
+	calls int
+	err   error
+}
+
+func (f *fakeDescriber) DescribeImage(ctx context.Context, imageBase64, city string) (string, error) {
+	f.calls++
+	costs.Record(ctx, database.ModelUsage{Model: "test-text", PromptTokens: 300, OutputTokens: 40})
+	return "The Eiffel Tower under soft rain, in blues and greys", f.err
+}
+
+func TestGenerate_AltText(t *testing.T) {
+	d := &fakeDescriber{}
+	p := &Pipeline{GenAI: &fakeGenAI{}, Storage: &fakeUploader{}, Describer: d}
+	res, err := p.Generate(context.Background(), Request{City: "Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AltText != "The Eiffel Tower under soft rain, in blues and greys" {
+		t.Errorf("alt text = %q", res.AltText)
+	}
+	if len(res.Usage) != 3 {
+		t.Errorf("usage = %+v, want the image, alt text and video", res.Usage)
+	}
+
+	// Callers uploading themselves describe with Describe; videos of stored images have none
+	if res, _ := p.Generate(context.Background(), Request{City: "Paris"}, SkipUpload()); res.AltText != "" {
+		t.Errorf("alt text with SkipUpload = %q", res.AltText)
+	}
+	if res, _ := p.Generate(context.Background(), Request{City: "Paris"}, FromImage(res.ImageURL)); res.AltText != "" {
+		t.Errorf("alt text of a stored image = %q", res.AltText)
+	}
+	if d.calls != 1 {
+		t.Errorf("described %d times", d.calls)
+	}
+
+	// Alt text is optional
+	d.err = errors.New("blocked")
+	res, err = p.Generate(context.Background(), Request{City: "Paris"})
+	if err != nil || res.AltText != "" || res.VideoURL == "" {
+		t.Errorf("res = %+v, %v", res, err)
+	}
+}
+
+func TestGenerate_Hooks(t *testing.T) {
+	g := &fakeGenAI{videoErr: errors.New("boom")}
+	reg := hooks.New()
+	reg.Register(hooks.BeforePrompt, "brand", func(ctx context.Context, gen *hooks.Generation) error {
+		gen.Context += " with a corporate umbrella"
+		return nil
+	})
+	reg.Register(hooks.AfterImage, "watermark", func(ctx context.Context, gen *hooks.Generation) error {
+		gen.Image.Images[0] = "bWFya2Vk"
+		return nil
+	})
+	var failed error
+	reg.Register(hooks.OnError, "alert", func(ctx context.Context, gen *hooks.Generation) error {
+		failed = gen.Err
+		return nil
+	})
+	p := &Pipeline{GenAI: g, Storage: &fakeUploader{}, Hooks: reg}
+
+	res, err := p.Generate(context.Background(), Request{City: "Oslo", Context: "at dusk"})
+	if !errors.Is(err, ErrVideo) {
+		t.Fatalf("Expected ErrVideo, got %v", err)
+	}
+	if g.extra != "at dusk with a corporate umbrella" {
+		t.Errorf("Expected the BeforePrompt context in the prompt, got %q", g.extra)
+	}
+	if res.Image.Image() != "bWFya2Vk" {
+		t.Errorf("Expected the AfterImage change to be kept, got %q", res.Image.Image())
+	}
+	if !errors.Is(failed, ErrVideo) {
+		t.Errorf("Expected OnError to see the video error, got %v", failed)
+	}
+}
+
+func TestGenerate_HookAborts(t *testing.T) {
+	store := &fakeUploader{}
+	reg := hooks.New()
+	reg.Register(hooks.AfterImage, "watermark", func(ctx context.Context, gen *hooks.Generation) error {
+		return errors.New("no logo")
+	})
+	var after bool
+	reg.Register(hooks.AfterVideo, "analytics", func(ctx context.Context, gen *hooks.Generation) error {
+		after = true
+		return nil
+	})
+	p := &Pipeline{GenAI: &fakeGenAI{}, Storage: store, Hooks: reg}
+
+	if _, err := p.Generate(context.Background(), Request{City: "Oslo"}); !errors.Is(err, ErrImage) {
+		t.Fatalf("Expected ErrImage, got %v", err)
+	}
+	if len(store.uploaded) != 0 || after {
+		t.Errorf("Expected nothing uploaded after a failed AfterImage hook, got %v", store.uploaded)
+	}
+}
+
+type recordingSink struct{ got []events.Lifecycle }
+
+func (r *recordingSink) Publish(ctx context.Context, e events.Lifecycle) error {
+	r.got = append(r.got, e)
+	return nil
+}
+
+func TestGenerate_Events(t *testing.T) {
+	sink := &recordingSink{}
+	bus := eventbus.New()
+	bus.Add("test", sink)
+	g := &fakeGenAI{}
+	p := &Pipeline{GenAI: g, Storage: &fakeUploader{}, Events: bus}
+
+	if _, err := p.Generate(context.Background(), Request{ID: "oslo", City: "Oslo", FileName: "o.png"}); err != nil {
+		t.Fatal(err)
+	}
+	g.videoErr = errors.New("boom")
+	if _, err := p.Generate(context.Background(), Request{ID: "oslo", City: "Oslo", FileName: "o.png"}); !errors.Is(err, ErrVideo) {
+		t.Fatalf("Expected ErrVideo, got %v", err)
+	}
+
+	want := []string{
+		events.GenerationStarted, events.ImageReady, events.VideoReady,
+		events.GenerationStarted, events.ImageReady, events.Failed,
+	}
+	if len(sink.got) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, sink.got)
+	}
+	for i, e := range sink.got {
+		if e.Type != want[i] || e.LocationID != "oslo" {
+			t.Errorf("Event %d: expected %s for oslo, got %+v", i, want[i], e)
+		}
+	}
+	if e := sink.got[2]; e.ImageURL != "https://storage.googleapis.com/bucket/o.png" || e.VideoURL == "" {
+		t.Errorf("Expected the media URLs on video_ready, got %+v", e)
+	}
+	if e := sink.got[5]; e.ImageURL == "" || e.Error == "" {
+		t.Errorf("Expected the kept image and the error on failed, got %+v", e)
+	}
+}
+
+// mapCache is an in-memory genai.ImageCache.
+type mapCache map[string][]byte
+
+func (c mapCache) Key(model, prompt string, seed *int32) string {
+	if seed == nil {
+		return model + "/-/" + prompt
+	}
+	return fmt.Sprintf("%s/%d/%s", model, *seed, prompt)
+}
+func (c mapCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	data, ok := c[key]
+	return data, ok
+}
+func (c mapCache) Put(ctx context.Context, key string, data []byte) { c[key] = data }
+
+func TestGenerate_PromptCacheIgnoresRandomSeeds(t *testing.T) {
+	const png = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+	answer := genai.Interaction{
+		Method:   http.MethodPost,
+		Path:     "/v1/projects/test-project/locations/us-central1/publishers/google/models/gemini-3.1-flash-image-preview:generateContent",
+		Status:   http.StatusOK,
+		Response: json.RawMessage(`{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/png","data":"` + png + `"}}]},"finishReason":"STOP"}]}`),
+	}
+	replayer := genai.NewReplayer(&genai.Cassette{Interactions: []genai.Interaction{answer, answer}})
+	gs, err := genai.NewServiceWithHTTPClient(context.Background(), "test-project", "us-central1", "test-bucket", "", &http.Client{Transport: replayer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.SetTransport(genai.TransportREST)
+	gs.SetImageCache(mapCache{})
+	p := &Pipeline{GenAI: gs, Storage: &fakeUploader{}}
+	generate := func(opts ...Option) *Result {
+		t.Helper()
+		res, err := p.Generate(context.Background(), Request{ID: "lisbon", City: "Lisbon"}, append(opts, WithStyle(1), SkipVideo())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// Unseeded generations get different random seeds but share the image
+	first, second := generate(), generate()
+	if n := len(replayer.Received()); n != 1 || !second.Image.Cached {
+		t.Errorf("Expected one model call for two unseeded generations, got %d (second cached: %v)", n, second.Image.Cached)
+	}
+	if first.Seed == second.Seed {
+		t.Errorf("Expected each generation to record its own seed, got %d twice", first.Seed)
+	}
+
+	// A seed asked for gets its own image
+	if res := generate(WithSeed(7)); res.Image.Cached || len(replayer.Received()) != 2 {
+		t.Errorf("Expected a model call for an explicit seed, got %d calls (cached: %v)", len(replayer.Received()), res.Image.Cached)
+	}
+}
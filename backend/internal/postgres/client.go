@@ -0,0 +1,1244 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Client is the Postgres implementation of the repository, for deployments outside GCP.
+// Models are shared with the Firestore client in internal/database.
+type Client struct {
+	pool  *pgxpool.Pool
+	clock clock.Clock
+}
+
+// NewClient connects to Postgres and applies pending schema migrations.
+func NewClient(ctx context.Context, url string) (*Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required for the postgres backend")
+	}
+	log.Printf("Initializing Postgres.")
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := migrateUp(pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Client{pool: pool, clock: clock.Real{}}, nil
+}
+
+// SetClock replaces the clock used for write timestamps (tests).
+func (c *Client) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+func migrateUp(pool *pgxpool.Pool) error {
+	src, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	// Closing this *sql.DB leaves the pool open
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to init migration driver: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		return fmt.Errorf("failed to init migrations: %w", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	version, _, _ := m.Version()
+	log.Printf("Postgres schema at version %d", version)
+	return nil
+}
+
+// Close closes the connection pool.
+func (c *Client) Close() error {
+	c.pool.Close()
+	return nil
+}
+
+// notFound matches the Firestore client's error for missing documents, so
+// callers checking status.Code(err) == codes.NotFound work with either backend.
+func notFound(what, id string) error {
+	return status.Errorf(codes.NotFound, "%s %q not found", what, id)
+}
+
+// jsonb encodes a map for a JSONB column, storing empty maps as NULL.
+func jsonb[M ~map[string]V, V any](m M) any {
+	if len(m) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// jsonValue encodes a struct for a JSONB column, storing nil as NULL.
+func jsonValue[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// -- Locations --
+
+const locationColumns = `id, name, name_i18n, category, city_query, image_url, video_url, is_preset, seed,
+	country_code, continent, lat, lng, feedback_up, feedback_down, feedback_score, feedback_reasons,
+	status, reports, generation, weather_check, weather_mismatch, featured_on, poster_url, stream_url, mood, caption, alt_text, audio_url, audio_i18n, temperature_c, video_prompt, prompt_context, manually_curated, locked, checksums, last_conditions, last_updated`
+
+func scanLocation(row pgx.Row) (*database.Location, error) {
+	var l database.Location
+	var nameI18n, reasons, generation, weatherCheck, audioI18n, checksums, lastConditions []byte
+	var lat, lng *float64
+	err := row.Scan(&l.ID, &l.Name, &nameI18n, &l.Category, &l.CityQuery, &l.ImageURL, &l.VideoURL, &l.IsPreset, &l.Seed,
+		&l.CountryCode, &l.Continent, &lat, &lng, &l.FeedbackUp, &l.FeedbackDown, &l.FeedbackScore, &reasons,
+		&l.Status, &l.Reports, &generation, &weatherCheck, &l.WeatherMismatch, &l.FeaturedOn, &l.PosterURL, &l.StreamURL, &l.Mood, &l.Caption, &l.AltText, &l.AudioURL, &audioI18n, &l.TemperatureC, &l.VideoPrompt, &l.PromptContext, &l.ManuallyCurated, &l.Locked, &checksums, &lastConditions, &l.LastUpdated)
+	if err != nil {
+		return nil, err
+	}
+	if len(nameI18n) > 0 {
+		if err := json.Unmarshal(nameI18n, &l.NameI18n); err != nil {
+			return nil, fmt.Errorf("bad name_i18n on %s: %w", l.ID, err)
+		}
+	}
+	if len(audioI18n) > 0 {
+		if err := json.Unmarshal(audioI18n, &l.AudioI18n); err != nil {
+			return nil, fmt.Errorf("bad audio_i18n on %s: %w", l.ID, err)
+		}
+	}
+	if len(checksums) > 0 {
+		if err := json.Unmarshal(checksums, &l.Checksums); err != nil {
+			return nil, fmt.Errorf("bad checksums on %s: %w", l.ID, err)
+		}
+	}
+	if len(reasons) > 0 {
+		if err := json.Unmarshal(reasons, &l.FeedbackReasons); err != nil {
+			return nil, fmt.Errorf("bad feedback_reasons on %s: %w", l.ID, err)
+		}
+	}
+	if len(generation) > 0 {
+		if err := json.Unmarshal(generation, &l.Generation); err != nil {
+			return nil, fmt.Errorf("bad generation on %s: %w", l.ID, err)
+		}
+	}
+	if len(weatherCheck) > 0 {
+		if err := json.Unmarshal(weatherCheck, &l.WeatherCheck); err != nil {
+			return nil, fmt.Errorf("bad weather_check on %s: %w", l.ID, err)
+		}
+	}
+	if len(lastConditions) > 0 {
+		if err := json.Unmarshal(lastConditions, &l.LastConditions); err != nil {
+			return nil, fmt.Errorf("bad last_conditions on %s: %w", l.ID, err)
+		}
+	}
+	if lat != nil && lng != nil {
+		l.Geo = &latlng.LatLng{Latitude: *lat, Longitude: *lng}
+	}
+	return &l, nil
+}
+
+func (c *Client) queryLocations(ctx context.Context, sql string, args ...any) ([]database.Location, error) {
+	rows, err := c.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locs []database.Location
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, *l)
+	}
+	return locs, rows.Err()
+}
+
+// GetLocation retrieves a location by ID.
+func (c *Client) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	l, err := scanLocation(c.pool.QueryRow(ctx, `SELECT `+locationColumns+` FROM locations WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("location", id)
+	}
+	return l, err
+}
+
+// UpsertLocation creates or replaces a location, and records the version
+// written in location_history (see database.LocationRevision).
+func (c *Client) UpsertLocation(ctx context.Context, loc database.Location) error {
+	if loc.ID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	var lat, lng *float64
+	if loc.Geo != nil {
+		lat, lng = &loc.Geo.Latitude, &loc.Geo.Longitude
+	}
+
+	_, err := c.pool.Exec(ctx, `
+		WITH upserted AS (
+		INSERT INTO locations (`+locationColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, now())
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, name_i18n = EXCLUDED.name_i18n, category = EXCLUDED.category,
+			city_query = EXCLUDED.city_query, image_url = EXCLUDED.image_url, video_url = EXCLUDED.video_url,
+			is_preset = EXCLUDED.is_preset, seed = EXCLUDED.seed, country_code = EXCLUDED.country_code,
+			continent = EXCLUDED.continent, lat = EXCLUDED.lat, lng = EXCLUDED.lng,
+			feedback_up = EXCLUDED.feedback_up, feedback_down = EXCLUDED.feedback_down,
+			feedback_score = EXCLUDED.feedback_score, feedback_reasons = EXCLUDED.feedback_reasons,
+			status = EXCLUDED.status, reports = EXCLUDED.reports, generation = EXCLUDED.generation,
+			weather_check = EXCLUDED.weather_check, weather_mismatch = EXCLUDED.weather_mismatch,
+			featured_on = EXCLUDED.featured_on, poster_url = EXCLUDED.poster_url,
+			stream_url = EXCLUDED.stream_url, mood = EXCLUDED.mood, caption = EXCLUDED.caption,
+			alt_text = EXCLUDED.alt_text, audio_url = EXCLUDED.audio_url, audio_i18n = EXCLUDED.audio_i18n,
+			temperature_c = EXCLUDED.temperature_c, video_prompt = EXCLUDED.video_prompt,
+			prompt_context = EXCLUDED.prompt_context, manually_curated = EXCLUDED.manually_curated, locked = EXCLUDED.locked, checksums = EXCLUDED.checksums,
+			last_conditions = EXCLUDED.last_conditions, last_updated = EXCLUDED.last_updated
+		RETURNING last_updated)
+		INSERT INTO location_history (location_id, data, created_at) SELECT $1, $38, last_updated FROM upserted`,
+		loc.ID, loc.Name, jsonb(loc.NameI18n), loc.Category, loc.CityQuery, loc.ImageURL, loc.VideoURL, loc.IsPreset, loc.Seed,
+		loc.CountryCode, loc.Continent, lat, lng, loc.FeedbackUp, loc.FeedbackDown, loc.FeedbackScore, jsonb(loc.FeedbackReasons),
+		string(loc.Status), loc.Reports, jsonValue(loc.Generation), jsonValue(loc.WeatherCheck), loc.WeatherMismatch, loc.FeaturedOn, loc.PosterURL, loc.StreamURL, loc.Mood, loc.Caption, loc.AltText, loc.AudioURL, jsonb(loc.AudioI18n), loc.TemperatureC, loc.VideoPrompt, loc.PromptContext, loc.ManuallyCurated, loc.Locked,
+		jsonb(loc.Checksums), jsonValue(loc.LastConditions), jsonValue(&loc))
+	return err
+}
+
+// DeleteLocation removes a location along with its feedback and reports. Media is left untouched.
+func (c *Client) DeleteLocation(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	_, err := c.pool.Exec(ctx, `DELETE FROM locations WHERE id = $1`, id)
+	return err
+}
+
+// buildListQuery returns the SQL and args for ListLocations.
+func buildListQuery(opts database.ListOptions) (string, []any) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	switch opts.Type {
+	case "preset":
+		where = append(where, "is_preset")
+	case "user":
+		where = append(where, "NOT is_preset")
+	case "hidden":
+		where = append(where, "status IN ("+arg(string(database.StatusHidden))+", "+arg(string(database.StatusHiddenPendingReview))+")")
+	}
+	if opts.Status != "" {
+		where = append(where, "status = "+arg(string(opts.Status)))
+	}
+
+	sql := `SELECT ` + locationColumns + ` FROM locations`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	if opts.Sort == "feedback" {
+		sql += " ORDER BY feedback_score ASC"
+	} else {
+		sql += " ORDER BY last_updated DESC"
+	}
+	if opts.Limit > 0 {
+		sql += " LIMIT " + arg(opts.Limit)
+	}
+	return sql, args
+}
+
+// ListLocations returns locations, optionally filtered and limited (see database.ListOptions).
+func (c *Client) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	sql, args := buildListQuery(opts)
+	return c.queryLocations(ctx, sql, args...)
+}
+
+// Statuses kept out of presets and region listings
+var invisibleStatuses = []string{string(database.StatusHidden), string(database.StatusHiddenPendingReview), string(database.StatusArchived)}
+
+// GetPresets returns all visible presets.
+func (c *Client) GetPresets(ctx context.Context) ([]database.Location, error) {
+	return c.queryLocations(ctx, `SELECT `+locationColumns+` FROM locations
+		WHERE is_preset AND status <> ALL($1)`, invisibleStatuses)
+}
+
+// presetOrder returns the ORDER BY clause for opts, matching the Firestore
+// client's order (see database.SortByCategoryOrder).
+func presetOrder(opts database.PresetOptions) string {
+	dir := "ASC"
+	if opts.Desc {
+		dir = "DESC"
+	}
+	switch opts.Sort {
+	case database.SortName:
+		return "l.name " + dir
+	case database.SortUpdated:
+		return "l.last_updated " + dir
+	default:
+		// NULLs (unlisted categories) sort last ascending and first descending
+		return "c.display_order " + dir + ", l.category " + dir + ", l.name ASC"
+	}
+}
+
+// GetPresetSummaries returns the gallery fields of all visible presets, in the order given by opts.
+func (c *Client) GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error) {
+	rows, err := c.pool.Query(ctx, `SELECT l.id, l.name, l.name_i18n, l.category, l.image_url, l.video_url,
+		l.poster_url, l.stream_url, l.alt_text, l.audio_url, l.audio_i18n, l.continent, l.status, l.last_updated
+		FROM locations l LEFT JOIN categories c ON c.name = l.category
+		WHERE l.is_preset AND l.status <> ALL($1)
+		ORDER BY `+presetOrder(opts), invisibleStatuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []database.PresetSummary
+	for rows.Next() {
+		var p database.PresetSummary
+		var i18n, audioI18n []byte
+		if err := rows.Scan(&p.ID, &p.Name, &i18n, &p.Category, &p.ImageURL, &p.VideoURL,
+			&p.PosterURL, &p.StreamURL, &p.AltText, &p.AudioURL, &audioI18n, &p.Continent, &p.Status, &p.LastUpdated); err != nil {
+			return nil, err
+		}
+		if len(i18n) > 0 {
+			if err := json.Unmarshal(i18n, &p.NameI18n); err != nil {
+				return nil, fmt.Errorf("bad name_i18n on %s: %w", p.ID, err)
+			}
+		}
+		if len(audioI18n) > 0 {
+			if err := json.Unmarshal(audioI18n, &p.AudioI18n); err != nil {
+				return nil, fmt.Errorf("bad audio_i18n on %s: %w", p.ID, err)
+			}
+		}
+		presets = append(presets, p)
+	}
+	return presets, rows.Err()
+}
+
+// ListCategories returns the categories in display order.
+func (c *Client) ListCategories(ctx context.Context) ([]database.Category, error) {
+	rows, err := c.pool.Query(ctx, `SELECT name, display_order, cover_url, cover_cities, cover_updated FROM categories ORDER BY display_order`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []database.Category
+	for rows.Next() {
+		var cat database.Category
+		var updated *time.Time
+		if err := rows.Scan(&cat.Name, &cat.Order, &cat.CoverURL, &cat.CoverCities, &updated); err != nil {
+			return nil, err
+		}
+		if updated != nil {
+			cat.CoverUpdated = *updated
+		}
+		categories = append(categories, cat)
+	}
+	return categories, rows.Err()
+}
+
+// SetCategories replaces the categories table.
+func (c *Client) SetCategories(ctx context.Context, categories []database.Category) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM categories`); err != nil {
+		return err
+	}
+	for _, cat := range categories {
+		if _, err := tx.Exec(ctx, `INSERT INTO categories (name, display_order, cover_url, cover_cities, cover_updated) VALUES ($1, $2, $3, $4, $5)`,
+			cat.Name, cat.Order, cat.CoverURL, cat.CoverCities, nullTime(cat.CoverUpdated)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetLocationsByCountry returns visible locations in a country, most recently updated first.
+func (c *Client) GetLocationsByCountry(ctx context.Context, code string, limit int) ([]database.Location, error) {
+	sql := `SELECT ` + locationColumns + ` FROM locations
+		WHERE country_code = $1 AND status <> ALL($2) ORDER BY last_updated DESC`
+	args := []any{strings.ToUpper(code), invisibleStatuses}
+	if limit > 0 {
+		sql += " LIMIT $3"
+		args = append(args, limit)
+	}
+	return c.queryLocations(ctx, sql, args...)
+}
+
+// GetStats returns aggregate statistics about locations.
+func (c *Client) GetStats(ctx context.Context) (*database.Stats, error) {
+	var s database.Stats
+	var last *time.Time
+	err := c.pool.QueryRow(ctx, `
+		SELECT count(*), count(*) FILTER (WHERE is_preset), count(*) FILTER (WHERE weather_mismatch), max(last_updated)
+		FROM locations`,
+	).Scan(&s.TotalLocations, &s.Presets, &s.WeatherMismatches, &last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count locations: %w", err)
+	}
+	s.UserGenerated = s.TotalLocations - s.Presets
+	if last != nil {
+		s.LastUpdated = *last
+	}
+	return &s, nil
+}
+
+// SetStatus updates only the status of a location, creating a stub row if it doesn't exist yet.
+func (c *Client) SetStatus(ctx context.Context, id string, st database.LocationStatus) error {
+	if id == "" {
+		return fmt.Errorf("location ID is required")
+	}
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO locations (id, status) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status`, id, string(st))
+	return err
+}
+
+// SetGeo stores the coordinates, country and continent derived from geocoding.
+func (c *Client) SetGeo(ctx context.Context, id string, geo *latlng.LatLng, countryCode, continent string) error {
+	var lat, lng *float64
+	if geo != nil {
+		lat, lng = &geo.Latitude, &geo.Longitude
+	}
+	_, err := c.pool.Exec(ctx, `UPDATE locations SET lat = $2, lng = $3, country_code = $4, continent = $5 WHERE id = $1`,
+		id, lat, lng, countryCode, continent)
+	return err
+}
+
+// SetChecksums replaces the media checksums of a location, leaving
+// last_updated alone.
+func (c *Client) SetChecksums(ctx context.Context, id string, sums map[string]string) error {
+	tag, err := c.pool.Exec(ctx, `UPDATE locations SET checksums = $2 WHERE id = $1`, id, jsonb(sums))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("location", id)
+	}
+	return nil
+}
+
+// SetNameI18n merges localized display names (language code -> name) into a location.
+func (c *Client) SetNameI18n(ctx context.Context, id string, names map[string]string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	_, err := c.pool.Exec(ctx, `UPDATE locations SET name_i18n = COALESCE(name_i18n, '{}'::jsonb) || $2 WHERE id = $1`,
+		id, jsonb(names))
+	return err
+}
+
+// UpdateLocations applies edits in one transaction, so either all or none
+// are written. last_updated is left alone, since it dates the media. Field
+// names are checked against database.EditableFields before they reach SQL.
+func (c *Client) UpdateLocations(ctx context.Context, edits []database.LocationEdit) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, e := range edits {
+		if e.ID == "" {
+			return fmt.Errorf("location ID is required")
+		}
+		var sets []string
+		args := []any{e.ID}
+		for field, value := range e.Fields {
+			if !slices.Contains(database.EditableFields, field) {
+				return fmt.Errorf("field %q is not editable", field)
+			}
+			if st, ok := value.(database.LocationStatus); ok {
+				value = string(st)
+			}
+			args = append(args, value)
+			sets = append(sets, fmt.Sprintf("%s = $%d", field, len(args)))
+		}
+		if len(sets) == 0 {
+			continue
+		}
+		tag, err := tx.Exec(ctx, `UPDATE locations SET `+strings.Join(sets, ", ")+` WHERE id = $1`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", e.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("failed to update %s: %w", e.ID, pgx.ErrNoRows)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// -- Moderation --
+
+// AddFeedback records a vote and atomically updates the aggregates on the location.
+// Returns a NotFound status error if the location doesn't exist.
+func (c *Client) AddFeedback(ctx context.Context, id string, fb database.Feedback) error {
+	if fb.CreatedAt.IsZero() {
+		fb.CreatedAt = c.clock.Now()
+	}
+	up, down, score := 0, 1, -1
+	if fb.Up {
+		up, down, score = 1, 0, 1
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE locations SET
+			feedback_up = feedback_up + $2,
+			feedback_down = feedback_down + $3,
+			feedback_score = feedback_score + $4,
+			feedback_reasons = CASE WHEN $5 = '' THEN feedback_reasons
+				ELSE jsonb_set(COALESCE(feedback_reasons, '{}'::jsonb), ARRAY[$5::text],
+					to_jsonb(COALESCE((feedback_reasons ->> $5)::int, 0) + 1))
+			END
+		WHERE id = $1`, id, up, down, score, fb.Reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("location", id)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO location_feedback (location_id, up, reason, image_url, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		id, fb.Up, fb.Reason, fb.ImageURL, fb.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AddReport records an abuse report. Once reports reach threshold the location
+// is hidden pending review. hidden is true only for the report that caused the
+// takedown, so callers notify admins exactly once. A reporter's report is
+// counted once; later ones are ignored.
+func (c *Client) AddReport(ctx context.Context, id string, r database.Report, threshold int) (hidden bool, err error) {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = c.clock.Now()
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var loc database.Location
+	err = tx.QueryRow(ctx, `SELECT status, reports FROM locations WHERE id = $1 FOR UPDATE`, id).Scan(&loc.Status, &loc.Reports)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, notFound("location", id)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO location_reports (location_id, reporter, reason, image_url, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (location_id, reporter) WHERE reporter <> '' DO NOTHING`,
+		id, r.Reporter, r.Reason, r.ImageURL, r.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil // Already reported by this client
+	}
+
+	newStatus := loc.Status
+	if !loc.IsHidden() && threshold > 0 && loc.Reports+1 >= threshold {
+		newStatus = database.StatusHiddenPendingReview
+		hidden = true
+	}
+	if _, err := tx.Exec(ctx, `UPDATE locations SET reports = reports + 1, status = $2 WHERE id = $1`, id, string(newStatus)); err != nil {
+		return false, err
+	}
+	return hidden, tx.Commit(ctx)
+}
+
+// ResolveReview clears a takedown after an admin approves the location.
+func (c *Client) ResolveReview(ctx context.Context, id string) error {
+	tag, err := c.pool.Exec(ctx, `UPDATE locations SET status = $2, reports = 0 WHERE id = $1`, id, string(database.StatusReady))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("location", id)
+	}
+	return nil
+}
+
+// -- Settings --
+
+func settingsName(name, tenant string) string {
+	if tenant == "" {
+		return name
+	}
+	return name + "_" + tenant
+}
+
+func (c *Client) getSetting(ctx context.Context, name string, v any) error {
+	var data []byte
+	err := c.pool.QueryRow(ctx, `SELECT data FROM settings WHERE name = $1`, name).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notFound("settings", name)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) setSetting(ctx context.Context, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.pool.Exec(ctx, `
+		INSERT INTO settings (name, data) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET data = EXCLUDED.data`, name, data)
+	return err
+}
+
+// GetBranding returns the branding settings for a tenant ("" = deployment default).
+func (c *Client) GetBranding(ctx context.Context, tenant string) (*database.Branding, error) {
+	var b database.Branding
+	if err := c.getSetting(ctx, settingsName("branding", tenant), &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SetBranding replaces the branding settings for a tenant.
+func (c *Client) SetBranding(ctx context.Context, tenant string, b database.Branding) error {
+	return c.setSetting(ctx, settingsName("branding", tenant), b)
+}
+
+// GetLocationPolicy returns the location policy for a tenant ("" = deployment default).
+func (c *Client) GetLocationPolicy(ctx context.Context, tenant string) (*database.LocationPolicy, error) {
+	var p database.LocationPolicy
+	if err := c.getSetting(ctx, settingsName("location_policy", tenant), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetLocationPolicy replaces the location policy for a tenant.
+func (c *Client) SetLocationPolicy(ctx context.Context, tenant string, p database.LocationPolicy) error {
+	return c.setSetting(ctx, settingsName("location_policy", tenant), p)
+}
+
+// GetRuntimeSettings returns the runtime settings, all off when never set.
+func (c *Client) GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error) {
+	var s database.RuntimeSettings
+	if err := c.getSetting(ctx, "runtime", &s); err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateRuntimeSettings applies update to the current runtime settings and
+// saves them, with the row locked, so concurrent writers don't undo each
+// other's changes, and returns the settings saved.
+func (c *Client) UpdateRuntimeSettings(ctx context.Context, update func(s *database.RuntimeSettings)) (*database.RuntimeSettings, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Created first, so there's a row to lock
+	if _, err := tx.Exec(ctx, `INSERT INTO settings (name, data) VALUES ('runtime', '{}') ON CONFLICT (name) DO NOTHING`); err != nil {
+		return nil, err
+	}
+	var data []byte
+	if err := tx.QueryRow(ctx, `SELECT data FROM settings WHERE name = 'runtime' FOR UPDATE`).Scan(&data); err != nil {
+		return nil, err
+	}
+	var s database.RuntimeSettings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	update(&s)
+	if data, err = json.Marshal(s); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE settings SET data = $1 WHERE name = 'runtime'`, data); err != nil {
+		return nil, err
+	}
+	return &s, tx.Commit(ctx)
+}
+
+// -- Audit Log --
+
+// AddAuditEntry appends an entry to the audit log.
+func (c *Client) AddAuditEntry(ctx context.Context, e database.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = c.clock.Now()
+	}
+	_, err := c.pool.Exec(ctx, `INSERT INTO audit_log (event, query, location, reason, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		e.Event, e.Query, e.Location, e.Reason, e.CreatedAt)
+	return err
+}
+
+// -- Operation Tracking --
+
+// TrackOperation records an in-flight Veo operation.
+func (c *Client) TrackOperation(ctx context.Context, op database.PendingOperation) error {
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO pending_operations (name, model, input_image, started_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET model = EXCLUDED.model, input_image = EXCLUDED.input_image, started_at = EXCLUDED.started_at`,
+		op.Name, op.Model, op.InputImage, op.StartedAt)
+	return err
+}
+
+// ClearOperation removes a finished or cancelled operation.
+func (c *Client) ClearOperation(ctx context.Context, name string) error {
+	_, err := c.pool.Exec(ctx, `DELETE FROM pending_operations WHERE name = $1`, name)
+	return err
+}
+
+// RecordModelTiming adds a finished operation's duration to the model's
+// timings, keeping the last database.MaxModelTimings.
+func (c *Client) RecordModelTiming(ctx context.Context, model string, d time.Duration) error {
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO model_timings (model, durations, updated_at) VALUES ($1, ARRAY[$2::double precision], $3)
+		ON CONFLICT (model) DO UPDATE SET
+			durations = (model_timings.durations || $2::double precision)[greatest(cardinality(model_timings.durations) + 2 - $4, 1):],
+			updated_at = EXCLUDED.updated_at`,
+		model, d.Seconds(), c.clock.Now(), database.MaxModelTimings)
+	return err
+}
+
+// GetModelTimings returns the model's recent durations; empty for models
+// without any yet.
+func (c *Client) GetModelTimings(ctx context.Context, model string) (*database.ModelTimings, error) {
+	t := database.ModelTimings{Model: model}
+	err := c.pool.QueryRow(ctx, `SELECT durations, updated_at FROM model_timings WHERE model = $1`, model).Scan(&t.Durations, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListOperations returns tracked operations, oldest first.
+func (c *Client) ListOperations(ctx context.Context) ([]database.PendingOperation, error) {
+	rows, err := c.pool.Query(ctx, `SELECT name, model, input_image, started_at FROM pending_operations ORDER BY started_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ops []database.PendingOperation
+	for rows.Next() {
+		var op database.PendingOperation
+		if err := rows.Scan(&op.Name, &op.Model, &op.InputImage, &op.StartedAt); err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+// -- Latency --
+
+// AddLatencySample records a stage duration.
+func (c *Client) AddLatencySample(ctx context.Context, s database.LatencySample) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = c.clock.Now()
+	}
+	_, err := c.pool.Exec(ctx, `INSERT INTO latency_stats (stage, outcome, duration_ms, location_id, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		s.Stage, s.Outcome, s.DurationMS, s.LocationID, s.CreatedAt)
+	return err
+}
+
+// ListLatencySamples returns the samples recorded since since.
+func (c *Client) ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error) {
+	rows, err := c.pool.Query(ctx, `SELECT stage, outcome, duration_ms, location_id, created_at FROM latency_stats WHERE created_at >= $1`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.LatencySample
+	for rows.Next() {
+		var s database.LatencySample
+		if err := rows.Scan(&s.Stage, &s.Outcome, &s.DurationMS, &s.LocationID, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// -- Location History --
+
+// GetLocationAsOf returns the latest revision of location id written at or
+// before at, or a NotFound error when there's none.
+func (c *Client) GetLocationAsOf(ctx context.Context, id string, at time.Time) (*database.LocationRevision, error) {
+	var rev database.LocationRevision
+	var data []byte
+	err := c.pool.QueryRow(ctx, `
+		SELECT data, created_at FROM location_history
+		WHERE location_id = $1 AND created_at <= $2 ORDER BY created_at DESC LIMIT 1`, id, at).Scan(&data, &rev.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("revision of location", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &rev.Location); err != nil {
+		return nil, fmt.Errorf("bad revision of %s: %w", id, err)
+	}
+	rev.Location.LastUpdated = rev.CreatedAt
+	return &rev, nil
+}
+
+// ListAuditEntries returns the audit entries recorded for location between
+// since and until, oldest first.
+func (c *Client) ListAuditEntries(ctx context.Context, location string, since, until time.Time) ([]database.AuditEntry, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT event, query, location, reason, created_at FROM audit_log
+		WHERE location = $1 AND created_at BETWEEN $2 AND $3 ORDER BY created_at`, location, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.AuditEntry
+	for rows.Next() {
+		var e database.AuditEntry
+		if err := rows.Scan(&e.Event, &e.Query, &e.Location, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// -- Flow Traces --
+
+// SaveFlowTrace stores a trace under its ID.
+func (c *Client) SaveFlowTrace(ctx context.Context, t database.FlowTrace) error {
+	if t.ID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	events, _ := json.Marshal(t.Events)
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO flow_traces (id, query, lat, lng, reference, started_at, finished_at, error, events) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET finished_at = EXCLUDED.finished_at, error = EXCLUDED.error, events = EXCLUDED.events`,
+		t.ID, t.Query, t.Lat, t.Lng, t.Reference, t.StartedAt, t.FinishedAt, t.Error, events)
+	return err
+}
+
+const flowTraceColumns = `id, query, lat, lng, reference, started_at, finished_at, error, events`
+
+func scanFlowTrace(row pgx.Row) (*database.FlowTrace, error) {
+	var t database.FlowTrace
+	var events []byte
+	if err := row.Scan(&t.ID, &t.Query, &t.Lat, &t.Lng, &t.Reference, &t.StartedAt, &t.FinishedAt, &t.Error, &events); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(events, &t.Events); err != nil {
+		return nil, fmt.Errorf("failed to decode events of trace %s: %w", t.ID, err)
+	}
+	return &t, nil
+}
+
+// GetFlowTrace returns a trace, or a NotFound error.
+func (c *Client) GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error) {
+	t, err := scanFlowTrace(c.pool.QueryRow(ctx, `SELECT `+flowTraceColumns+` FROM flow_traces WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("flow trace", id)
+	}
+	return t, err
+}
+
+// ListFlowTraces returns the latest traces, newest first.
+func (c *Client) ListFlowTraces(ctx context.Context, limit int) ([]database.FlowTrace, error) {
+	rows, err := c.pool.Query(ctx, `SELECT `+flowTraceColumns+` FROM flow_traces ORDER BY started_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.FlowTrace
+	for rows.Next() {
+		t, err := scanFlowTrace(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// -- Candidates --
+
+// AddCandidate stores a candidate under a new ID, which it returns.
+func (c *Client) AddCandidate(ctx context.Context, cand database.Candidate) (string, error) {
+	if cand.LocationID == "" {
+		return "", fmt.Errorf("location ID is required")
+	}
+	if cand.CreatedAt.IsZero() {
+		cand.CreatedAt = c.clock.Now()
+	}
+	var id int64
+	err := c.pool.QueryRow(ctx, `
+		INSERT INTO candidates (location_id, city, source, style, seed, image_url, generation, status, alt_text, mood, temperature_c, last_conditions, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`,
+		cand.LocationID, cand.City, cand.Source, cand.Style, cand.Seed, cand.ImageURL, jsonValue(cand.Generation),
+		cand.Status, cand.AltText, cand.Mood, cand.TemperatureC, jsonValue(cand.LastConditions), cand.CreatedAt).Scan(&id)
+	return strconv.FormatInt(id, 10), err
+}
+
+const candidateColumns = `id, location_id, city, source, style, seed, image_url, generation, status, alt_text, mood, temperature_c, last_conditions, created_at, reviewed_at`
+
+func scanCandidate(row pgx.Row) (*database.Candidate, error) {
+	var cand database.Candidate
+	var id int64
+	var generation, lastConditions []byte
+	if err := row.Scan(&id, &cand.LocationID, &cand.City, &cand.Source, &cand.Style, &cand.Seed, &cand.ImageURL, &generation,
+		&cand.Status, &cand.AltText, &cand.Mood, &cand.TemperatureC, &lastConditions, &cand.CreatedAt, &cand.ReviewedAt); err != nil {
+		return nil, err
+	}
+	cand.ID = strconv.FormatInt(id, 10)
+	if len(generation) > 0 {
+		if err := json.Unmarshal(generation, &cand.Generation); err != nil {
+			return nil, fmt.Errorf("bad generation on candidate %s: %w", cand.ID, err)
+		}
+	}
+	if len(lastConditions) > 0 {
+		if err := json.Unmarshal(lastConditions, &cand.LastConditions); err != nil {
+			return nil, fmt.Errorf("bad last_conditions on candidate %s: %w", cand.ID, err)
+		}
+	}
+	return &cand, nil
+}
+
+// ListCandidates returns the candidates of a location, newest first.
+func (c *Client) ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error) {
+	rows, err := c.pool.Query(ctx, `SELECT `+candidateColumns+` FROM candidates
+		WHERE location_id = $1 ORDER BY created_at DESC`, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.Candidate
+	for rows.Next() {
+		cand, err := scanCandidate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *cand)
+	}
+	return out, rows.Err()
+}
+
+// GetCandidate returns a candidate by ID.
+func (c *Client) GetCandidate(ctx context.Context, id string) (*database.Candidate, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, notFound("candidate", id)
+	}
+	cand, err := scanCandidate(c.pool.QueryRow(ctx, `SELECT `+candidateColumns+` FROM candidates WHERE id = $1`, n))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("candidate", id)
+	}
+	return cand, err
+}
+
+// SetCandidateStatus records the review of a candidate.
+func (c *Client) SetCandidateStatus(ctx context.Context, id, status string) error {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return notFound("candidate", id)
+	}
+	tag, err := c.pool.Exec(ctx, `UPDATE candidates SET status = $2, reviewed_at = $3 WHERE id = $1`, n, status, c.clock.Now())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("candidate", id)
+	}
+	return nil
+}
+
+// TransitionCandidate changes the status of a candidate to to if it's one of
+// from, and returns the candidate as it was. It fails with
+// FailedPrecondition otherwise, e.g. when a concurrent review claimed it
+// first.
+func (c *Client) TransitionCandidate(ctx context.Context, id string, from []string, to string) (*database.Candidate, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, notFound("candidate", id)
+	}
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	cand, err := scanCandidate(tx.QueryRow(ctx, `SELECT `+candidateColumns+` FROM candidates WHERE id = $1 FOR UPDATE`, n))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("candidate", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, cand.Status) {
+		return nil, status.Errorf(codes.FailedPrecondition, "candidate %q is %s", id, cand.Status)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE candidates SET status = $2, reviewed_at = $3 WHERE id = $1`, n, to, c.clock.Now()); err != nil {
+		return nil, err
+	}
+	return cand, tx.Commit(ctx)
+}
+
+// -- Runs --
+
+// GetRun returns a run by ID.
+func (c *Client) GetRun(ctx context.Context, id string) (*database.Run, error) {
+	run := database.Run{ID: id}
+	err := c.pool.QueryRow(ctx, `SELECT source, force, total, created_at, updated_at FROM runs WHERE id = $1`, id).
+		Scan(&run.Source, &run.Force, &run.Total, &run.CreatedAt, &run.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("run", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// SaveRun creates or replaces a run, stamping its update time. Its items
+// are kept.
+func (c *Client) SaveRun(ctx context.Context, run database.Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("run ID is required")
+	}
+	run.UpdatedAt = c.clock.Now()
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = run.UpdatedAt
+	}
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO runs (id, source, force, total, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET source = EXCLUDED.source, force = EXCLUDED.force, total = EXCLUDED.total,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		run.ID, run.Source, run.Force, run.Total, run.CreatedAt, run.UpdatedAt)
+	return err
+}
+
+// SetRunItem records the progress of a preset of a run.
+func (c *Client) SetRunItem(ctx context.Context, runID string, item database.RunItem) error {
+	if item.ID == "" {
+		return fmt.Errorf("item ID is required")
+	}
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO run_items (run_id, id, status, task, attempts, error, image_url, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (run_id, id) DO UPDATE SET status = EXCLUDED.status, task = EXCLUDED.task, attempts = EXCLUDED.attempts,
+			error = EXCLUDED.error, image_url = EXCLUDED.image_url, updated_at = EXCLUDED.updated_at`,
+		runID, item.ID, item.Status, item.Task, item.Attempts, item.Error, item.ImageURL, c.clock.Now())
+	return err
+}
+
+// ListRunItems returns the items of a run.
+func (c *Client) ListRunItems(ctx context.Context, runID string) ([]database.RunItem, error) {
+	rows, err := c.pool.Query(ctx, `SELECT id, status, task, attempts, error, image_url, updated_at FROM run_items
+		WHERE run_id = $1 ORDER BY id`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.RunItem
+	for rows.Next() {
+		var item database.RunItem
+		if err := rows.Scan(&item.ID, &item.Status, &item.Task, &item.Attempts, &item.Error, &item.ImageURL, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+// -- City of the Day --
+
+// SetCityOfTheDay records the city of the day, replacing an earlier pick for the same date.
+func (c *Client) SetCityOfTheDay(ctx context.Context, f database.FeaturedCity) error {
+	if f.Date == "" {
+		return fmt.Errorf("date is required")
+	}
+	if f.SelectedAt.IsZero() {
+		f.SelectedAt = c.clock.Now()
+	}
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO city_of_the_day (date, location_id, name, image_url, video_url, selected_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (date) DO UPDATE SET location_id = EXCLUDED.location_id, name = EXCLUDED.name,
+			image_url = EXCLUDED.image_url, video_url = EXCLUDED.video_url, selected_at = EXCLUDED.selected_at`,
+		f.Date, f.LocationID, f.Name, f.ImageURL, f.VideoURL, f.SelectedAt)
+	return err
+}
+
+// ListCityOfTheDay returns past selections, newest first.
+func (c *Client) ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error) {
+	sql := `SELECT date, location_id, name, image_url, video_url, selected_at FROM city_of_the_day ORDER BY date DESC`
+	var args []any
+	if limit > 0 {
+		sql += " LIMIT $1"
+		args = append(args, limit)
+	}
+	rows, err := c.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.FeaturedCity
+	for rows.Next() {
+		var f database.FeaturedCity
+		if err := rows.Scan(&f.Date, &f.LocationID, &f.Name, &f.ImageURL, &f.VideoURL, &f.SelectedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// -- Wallet Passes --
+
+// RegisterPass records a registration. created is false when the device
+// already had one for the pass (its push token is updated).
+func (c *Client) RegisterPass(ctx context.Context, r database.PassRegistration) (created bool, err error) {
+	if r.DeviceID == "" || r.Serial == "" {
+		return false, fmt.Errorf("device ID and serial are required")
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = c.clock.Now()
+	}
+	// xmax is 0 for a freshly inserted row
+	err = c.pool.QueryRow(ctx, `
+		INSERT INTO wallet_registrations (device_id, serial, push_token, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (device_id, serial) DO UPDATE SET push_token = EXCLUDED.push_token
+		RETURNING xmax = 0`,
+		r.DeviceID, r.Serial, r.PushToken, r.CreatedAt).Scan(&created)
+	return created, err
+}
+
+// UnregisterPass removes a registration; a missing one is not an error.
+func (c *Client) UnregisterPass(ctx context.Context, deviceID, serial string) error {
+	_, err := c.pool.Exec(ctx, `DELETE FROM wallet_registrations WHERE device_id = $1 AND serial = $2`, deviceID, serial)
+	return err
+}
+
+// ListPassRegistrations returns the registrations of a device (field
+// "device_id") or of a pass (field "serial").
+func (c *Client) ListPassRegistrations(ctx context.Context, field, value string) ([]database.PassRegistration, error) {
+	if field != "device_id" && field != "serial" {
+		return nil, fmt.Errorf("can't list pass registrations by %q", field)
+	}
+	rows, err := c.pool.Query(ctx, `SELECT device_id, serial, push_token, created_at FROM wallet_registrations WHERE `+field+` = $1`, value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []database.PassRegistration
+	for rows.Next() {
+		var r database.PassRegistration
+		if err := rows.Scan(&r.DeviceID, &r.Serial, &r.PushToken, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// -- Prompt Cache --
+
+// GetPromptCache returns the cache entry for a prompt hash.
+func (c *Client) GetPromptCache(ctx context.Context, key string) (*database.PromptCacheEntry, error) {
+	var e database.PromptCacheEntry
+	err := c.pool.QueryRow(ctx, `SELECT key, object, created_at, expires_at FROM prompt_cache WHERE key = $1`, key).
+		Scan(&e.Key, &e.Object, &e.CreatedAt, &e.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound("prompt cache entry", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// PutPromptCache stores a cache entry. Expired rows are pruned on write, since
+// Postgres has no TTL policy like Firestore's.
+func (c *Client) PutPromptCache(ctx context.Context, e database.PromptCacheEntry) error {
+	_, err := c.pool.Exec(ctx, `
+		INSERT INTO prompt_cache (key, object, created_at, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET object = EXCLUDED.object, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`,
+		e.Key, e.Object, e.CreatedAt, e.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if _, err := c.pool.Exec(ctx, `DELETE FROM prompt_cache WHERE expires_at < now()`); err != nil {
+		log.Printf("Failed to prune prompt cache: %v", err)
+	}
+	return nil
+}
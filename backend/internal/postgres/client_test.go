@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+)
+
+func TestBuildListQuery(t *testing.T) {
+	tests := []struct {
+		opts  database.ListOptions
+		where string
+		order string
+		args  []any
+	}{
+		{database.ListOptions{}, "", "ORDER BY last_updated DESC", nil},
+		{database.ListOptions{Type: "preset", Limit: 10}, "WHERE is_preset", "ORDER BY last_updated DESC LIMIT $1", []any{10}},
+		{
+			database.ListOptions{Type: "hidden", Sort: "feedback"},
+			"WHERE status IN ($1, $2)", "ORDER BY feedback_score ASC",
+			[]any{"hidden", "hidden_pending_review"},
+		},
+		{
+			database.ListOptions{Type: "user", Status: database.StatusFailed, Limit: 5},
+			"WHERE NOT is_preset AND status = $1", "ORDER BY last_updated DESC LIMIT $2",
+			[]any{"failed", 5},
+		},
+	}
+	for _, tt := range tests {
+		sql, args := buildListQuery(tt.opts)
+		if tt.where != "" && !strings.Contains(sql, tt.where) {
+			t.Errorf("%+v: expected %q in %q", tt.opts, tt.where, sql)
+		}
+		if tt.where == "" && strings.Contains(sql, "WHERE") {
+			t.Errorf("%+v: expected no WHERE in %q", tt.opts, sql)
+		}
+		if !strings.HasSuffix(sql, tt.order) {
+			t.Errorf("%+v: expected %q to end with %q", tt.opts, sql, tt.order)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%+v: args = %v, want %v", tt.opts, args, tt.args)
+		}
+	}
+}
+
+func TestPresetOrder(t *testing.T) {
+	tests := []struct {
+		opts database.PresetOptions
+		want string
+	}{
+		{database.PresetOptions{}, "c.display_order ASC, l.category ASC, l.name ASC"},
+		{database.PresetOptions{Sort: database.SortCategory, Desc: true}, "c.display_order DESC, l.category DESC, l.name ASC"},
+		{database.PresetOptions{Sort: database.SortName}, "l.name ASC"},
+		{database.PresetOptions{Sort: database.SortUpdated, Desc: true}, "l.last_updated DESC"},
+	}
+	for _, tt := range tests {
+		if got := presetOrder(tt.opts); got != tt.want {
+			t.Errorf("%+v: expected %q, got %q", tt.opts, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+DROP TABLE prompt_cache;
+DROP TABLE pending_operations;
+DROP TABLE audit_log;
+DROP TABLE settings;
+DROP TABLE location_reports;
+DROP TABLE location_feedback;
+DROP TABLE locations;
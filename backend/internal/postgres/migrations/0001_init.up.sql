@@ -0,0 +1,77 @@
+-- Mirrors the Firestore collections; see docs/firestore_setup.md for field meanings.
+
+CREATE TABLE locations (
+    id               TEXT PRIMARY KEY,
+    name             TEXT NOT NULL DEFAULT '',
+    name_i18n        JSONB,
+    category         TEXT NOT NULL DEFAULT '',
+    city_query       TEXT NOT NULL DEFAULT '',
+    image_url        TEXT NOT NULL DEFAULT '',
+    video_url        TEXT NOT NULL DEFAULT '',
+    is_preset        BOOLEAN NOT NULL DEFAULT FALSE,
+    seed             INTEGER,
+    country_code     TEXT NOT NULL DEFAULT '',
+    continent        TEXT NOT NULL DEFAULT '',
+    lat              DOUBLE PRECISION,
+    lng              DOUBLE PRECISION,
+    feedback_up      INTEGER NOT NULL DEFAULT 0,
+    feedback_down    INTEGER NOT NULL DEFAULT 0,
+    feedback_score   INTEGER NOT NULL DEFAULT 0,
+    feedback_reasons JSONB,
+    status           TEXT NOT NULL DEFAULT '',
+    reports          INTEGER NOT NULL DEFAULT 0,
+    last_updated     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX locations_preset_updated ON locations (is_preset, last_updated DESC);
+CREATE INDEX locations_status_updated ON locations (status, last_updated DESC);
+CREATE INDEX locations_country_updated ON locations (country_code, last_updated DESC);
+CREATE INDEX locations_feedback_score ON locations (feedback_score);
+
+CREATE TABLE location_feedback (
+    id          BIGSERIAL PRIMARY KEY,
+    location_id TEXT NOT NULL REFERENCES locations (id) ON DELETE CASCADE,
+    up          BOOLEAN NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    image_url   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE location_reports (
+    id          BIGSERIAL PRIMARY KEY,
+    location_id TEXT NOT NULL REFERENCES locations (id) ON DELETE CASCADE,
+    reason      TEXT NOT NULL DEFAULT '',
+    image_url   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+-- Singleton settings docs (branding, location_policy, with _<tenant> variants) as JSON
+CREATE TABLE settings (
+    name TEXT PRIMARY KEY,
+    data JSONB NOT NULL
+);
+
+CREATE TABLE audit_log (
+    id         BIGSERIAL PRIMARY KEY,
+    event      TEXT NOT NULL,
+    query      TEXT NOT NULL DEFAULT '',
+    location   TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE pending_operations (
+    name        TEXT PRIMARY KEY,
+    model       TEXT NOT NULL DEFAULT '',
+    input_image TEXT NOT NULL DEFAULT '',
+    started_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE prompt_cache (
+    key        TEXT PRIMARY KEY,
+    object     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX prompt_cache_expires ON prompt_cache (expires_at);
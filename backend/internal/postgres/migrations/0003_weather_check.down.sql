@@ -0,0 +1,2 @@
+ALTER TABLE locations DROP COLUMN weather_mismatch;
+ALTER TABLE locations DROP COLUMN weather_check;
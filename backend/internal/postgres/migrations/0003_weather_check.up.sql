@@ -0,0 +1,2 @@
+ALTER TABLE locations ADD COLUMN weather_check JSONB;
+ALTER TABLE locations ADD COLUMN weather_mismatch BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Gallery display order of preset categories; unlisted categories sort last
+CREATE TABLE categories (
+    name          TEXT PRIMARY KEY,
+    display_order INTEGER NOT NULL DEFAULT 0
+);
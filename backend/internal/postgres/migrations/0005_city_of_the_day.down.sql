@@ -0,0 +1,2 @@
+DROP TABLE city_of_the_day;
+ALTER TABLE locations DROP COLUMN featured_on;
@@ -0,0 +1,12 @@
+-- Date a location was last city of the day
+ALTER TABLE locations ADD COLUMN featured_on TEXT NOT NULL DEFAULT '';
+
+-- City of the day history, one row per date (YYYY-MM-DD, UTC)
+CREATE TABLE city_of_the_day (
+    date        TEXT PRIMARY KEY,
+    location_id TEXT NOT NULL,
+    name        TEXT NOT NULL DEFAULT '',
+    image_url   TEXT NOT NULL DEFAULT '',
+    video_url   TEXT NOT NULL DEFAULT '',
+    selected_at TIMESTAMPTZ NOT NULL
+);
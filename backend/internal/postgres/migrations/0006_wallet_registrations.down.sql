@@ -0,0 +1 @@
+DROP TABLE wallet_registrations;
@@ -0,0 +1,9 @@
+-- Apple Wallet devices holding a location's pass, pushed when its media changes
+CREATE TABLE wallet_registrations (
+    device_id  TEXT NOT NULL,
+    serial     TEXT NOT NULL,
+    push_token TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (device_id, serial)
+);
+CREATE INDEX wallet_registrations_serial ON wallet_registrations (serial);
@@ -0,0 +1,2 @@
+-- First frame of the video, shown as its poster
+ALTER TABLE locations ADD COLUMN poster_url TEXT NOT NULL DEFAULT '';
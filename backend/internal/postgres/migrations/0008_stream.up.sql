@@ -0,0 +1,2 @@
+-- HLS master playlist of the video
+ALTER TABLE locations ADD COLUMN stream_url TEXT NOT NULL DEFAULT '';
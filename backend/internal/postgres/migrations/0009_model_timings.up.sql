@@ -0,0 +1,6 @@
+-- Recent Veo durations per model, for progress estimates
+CREATE TABLE model_timings (
+    model      TEXT PRIMARY KEY,
+    durations  DOUBLE PRECISION[] NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
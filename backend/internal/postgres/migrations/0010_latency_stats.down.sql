@@ -0,0 +1 @@
+DROP TABLE latency_stats;
@@ -0,0 +1,10 @@
+-- Web flow stage durations, for SLO reports
+CREATE TABLE latency_stats (
+    id          BIGSERIAL PRIMARY KEY,
+    stage       TEXT NOT NULL,
+    outcome     TEXT NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    location_id TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX latency_stats_created_at ON latency_stats (created_at);
@@ -0,0 +1 @@
+DROP TABLE flow_traces;
@@ -0,0 +1,13 @@
+-- Sampled weather flow event streams, for debugging
+CREATE TABLE flow_traces (
+    id          TEXT PRIMARY KEY,
+    query       TEXT NOT NULL,
+    lat         TEXT NOT NULL,
+    lng         TEXT NOT NULL,
+    reference   TEXT NOT NULL,
+    started_at  TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    error       TEXT NOT NULL,
+    events      JSONB NOT NULL
+);
+CREATE INDEX flow_traces_started_at ON flow_traces (started_at);
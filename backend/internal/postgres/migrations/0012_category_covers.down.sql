@@ -0,0 +1,3 @@
+ALTER TABLE categories DROP COLUMN cover_updated;
+ALTER TABLE categories DROP COLUMN cover_cities;
+ALTER TABLE categories DROP COLUMN cover_url;
@@ -0,0 +1,4 @@
+-- Cover art of a category, see jobs.CategoryCovers
+ALTER TABLE categories ADD COLUMN cover_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN cover_cities TEXT[];
+ALTER TABLE categories ADD COLUMN cover_updated TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Theme of the observed weather when the media was generated (openmeteo.Mood)
+ALTER TABLE locations ADD COLUMN mood TEXT NOT NULL DEFAULT '';
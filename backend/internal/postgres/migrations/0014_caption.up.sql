@@ -0,0 +1,2 @@
+-- Nickname or fun fact shown under the artwork
+ALTER TABLE locations ADD COLUMN caption TEXT NOT NULL DEFAULT '';
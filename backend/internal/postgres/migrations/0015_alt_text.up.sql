@@ -0,0 +1,2 @@
+-- Description of the image for screen readers
+ALTER TABLE locations ADD COLUMN alt_text TEXT NOT NULL DEFAULT '';
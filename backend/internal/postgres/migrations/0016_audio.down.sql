@@ -0,0 +1,2 @@
+ALTER TABLE locations DROP COLUMN audio_i18n;
+ALTER TABLE locations DROP COLUMN audio_url;
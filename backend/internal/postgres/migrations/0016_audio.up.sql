@@ -0,0 +1,3 @@
+-- Narrated forecast clips: audio_url in the first NARRATION_LANGS language, audio_i18n the others
+ALTER TABLE locations ADD COLUMN audio_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE locations ADD COLUMN audio_i18n JSONB;
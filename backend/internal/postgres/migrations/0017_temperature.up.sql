@@ -0,0 +1,2 @@
+-- Temperature observed when the media was generated, for share cards
+ALTER TABLE locations ADD COLUMN temperature_c DOUBLE PRECISION;
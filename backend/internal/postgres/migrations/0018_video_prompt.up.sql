@@ -0,0 +1,2 @@
+-- Admin-set Veo motion prompt; the default prompt when empty
+ALTER TABLE locations ADD COLUMN video_prompt TEXT NOT NULL DEFAULT '';
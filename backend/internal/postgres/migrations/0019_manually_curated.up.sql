@@ -0,0 +1,2 @@
+-- Media attached by an admin, which automatic refreshes leave alone
+ALTER TABLE locations ADD COLUMN manually_curated BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Hand-approved media, regenerated only with an explicit override
+ALTER TABLE locations ADD COLUMN locked BOOLEAN NOT NULL DEFAULT false;
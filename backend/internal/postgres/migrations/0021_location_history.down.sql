@@ -0,0 +1,2 @@
+DROP INDEX audit_log_location;
+DROP TABLE location_history;
@@ -0,0 +1,10 @@
+-- Every version of a location written by UpsertLocation, for time-travel debugging.
+-- Kept when the location is deleted.
+CREATE TABLE location_history (
+    id          BIGSERIAL PRIMARY KEY,
+    location_id TEXT NOT NULL,
+    data        JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX location_history_location_created_at ON location_history (location_id, created_at);
+CREATE INDEX audit_log_location ON audit_log (location);
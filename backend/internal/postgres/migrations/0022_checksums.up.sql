@@ -0,0 +1,2 @@
+-- CRC32C of the stored media by URL, checked by banana admin verify-media
+ALTER TABLE locations ADD COLUMN checksums JSONB;
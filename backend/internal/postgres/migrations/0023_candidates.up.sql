@@ -0,0 +1,13 @@
+-- Media generated for review without replacing a location's live media (banana admin compare)
+CREATE TABLE candidates (
+    id          BIGSERIAL PRIMARY KEY,
+    location_id TEXT NOT NULL,
+    city        TEXT NOT NULL,
+    source      TEXT NOT NULL,
+    style       TEXT NOT NULL,
+    seed        INTEGER NOT NULL,
+    image_url   TEXT NOT NULL,
+    generation  JSONB,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX candidates_location ON candidates (location_id, created_at DESC);
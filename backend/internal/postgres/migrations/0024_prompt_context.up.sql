@@ -0,0 +1,2 @@
+-- Extra image prompt context, reused by every regeneration of the location
+ALTER TABLE locations ADD COLUMN prompt_context TEXT NOT NULL DEFAULT '';
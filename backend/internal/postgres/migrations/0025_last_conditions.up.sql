@@ -0,0 +1,2 @@
+-- Observed weather the media was generated for, see model.Conditions
+ALTER TABLE locations ADD COLUMN last_conditions JSONB;
@@ -0,0 +1,6 @@
+ALTER TABLE candidates DROP COLUMN reviewed_at;
+ALTER TABLE candidates DROP COLUMN last_conditions;
+ALTER TABLE candidates DROP COLUMN temperature_c;
+ALTER TABLE candidates DROP COLUMN mood;
+ALTER TABLE candidates DROP COLUMN alt_text;
+ALTER TABLE candidates DROP COLUMN status;
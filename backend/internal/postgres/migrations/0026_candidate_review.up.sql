@@ -0,0 +1,8 @@
+-- Review of candidates: preset refreshes stage their image as a pending
+-- candidate, swapped in once approved
+ALTER TABLE candidates ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE candidates ADD COLUMN alt_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE candidates ADD COLUMN mood TEXT NOT NULL DEFAULT '';
+ALTER TABLE candidates ADD COLUMN temperature_c DOUBLE PRECISION;
+ALTER TABLE candidates ADD COLUMN last_conditions JSONB;
+ALTER TABLE candidates ADD COLUMN reviewed_at TIMESTAMPTZ;
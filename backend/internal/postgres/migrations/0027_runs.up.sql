@@ -0,0 +1,20 @@
+-- Batch generation runs of cmd/worker, with each preset's progress as a checkpoint
+CREATE TABLE runs (
+    id         TEXT PRIMARY KEY,
+    source     TEXT NOT NULL,
+    force      BOOLEAN NOT NULL DEFAULT FALSE,
+    total      INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE run_items (
+    run_id     TEXT NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    id         TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    task       INTEGER NOT NULL DEFAULT 0,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    error      TEXT NOT NULL DEFAULT '',
+    image_url  TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (run_id, id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX location_reports_reporter;
+ALTER TABLE location_reports DROP COLUMN reporter;
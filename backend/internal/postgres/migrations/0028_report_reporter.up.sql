@@ -0,0 +1,3 @@
+-- One counted report per reporter (a hashed client) and location
+ALTER TABLE location_reports ADD COLUMN reporter TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX location_reports_reporter ON location_reports (location_id, reporter) WHERE reporter <> '';
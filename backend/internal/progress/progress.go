@@ -0,0 +1,85 @@
+// Package progress carries a request-scoped logger and a user-visible
+// progress emitter through contexts, so deep layers (Veo polling, stream
+// uploads) can report progress, e.g. "Animating (Veo 3.1) 40% – attempt 2",
+// without their callers wiring messages through.
+package progress
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+// Update is a progress report. It's the "progress" SSE event of the web
+// flow, as JSON.
+type Update = events.ProgressData
+
+// Emitter receives the updates of a request.
+type Emitter func(Update)
+
+type emitterKey struct{}
+type loggerKey struct{}
+
+// WithEmitter returns a context whose updates go to e.
+func WithEmitter(ctx context.Context, e Emitter) context.Context {
+	return context.WithValue(ctx, emitterKey{}, e)
+}
+
+// Emit reports u to the context's emitter, if it has one.
+func Emit(ctx context.Context, u Update) {
+	if e, ok := ctx.Value(emitterKey{}).(Emitter); ok && e != nil {
+		e(u)
+	}
+}
+
+// WithLogger returns a context logging to l.
+func WithLogger(ctx context.Context, l *log.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the context's logger, or the standard logger.
+func Logger(ctx context.Context) *log.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*log.Logger); ok && l != nil {
+		return l
+	}
+	return log.Default()
+}
+
+// Logf logs to the context's logger.
+func Logf(ctx context.Context, format string, v ...any) {
+	Logger(ctx).Printf(format, v...)
+}
+
+// NewLogger returns a logger writing where the standard logger does, with
+// lines prefixed by a request ID.
+func NewLogger(requestID string) *log.Logger {
+	return log.New(log.Writer(), "["+requestID+"] ", log.Flags()|log.Lmsgprefix)
+}
+
+type flowIDKey struct{}
+
+// NewFlowID returns a random weather flow ID: 16 hex digits, short enough
+// for a user to read out in a report.
+func NewFlowID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// WithFlowID returns a context carrying the weather flow ID id, whose logger
+// also prefixes lines with it, so a flow's lines can be found among
+// concurrent ones.
+func WithFlowID(ctx context.Context, id string) context.Context {
+	l := Logger(ctx)
+	ctx = WithLogger(ctx, log.New(l.Writer(), l.Prefix()+"[flow "+id+"] ", l.Flags()|log.Lmsgprefix))
+	return context.WithValue(ctx, flowIDKey{}, id)
+}
+
+// FlowID returns the context's weather flow ID, or "".
+func FlowID(ctx context.Context) string {
+	id, _ := ctx.Value(flowIDKey{}).(string)
+	return id
+}
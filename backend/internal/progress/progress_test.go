@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestEmit(t *testing.T) {
+	// No emitter: a no-op
+	Emit(context.Background(), Update{Message: "lost"})
+
+	var got []Update
+	ctx := WithEmitter(context.Background(), func(u Update) { got = append(got, u) })
+	Emit(ctx, Update{Stage: "video", Percent: 40})
+	if len(got) != 1 || got[0].Percent != 40 {
+		t.Errorf("Expected the update emitted, got %+v", got)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	if Logger(context.Background()) != log.Default() {
+		t.Error("Expected the standard logger without one in the context")
+	}
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), log.New(&buf, "[req-1] ", log.Lmsgprefix))
+	Logf(ctx, "Still polling Veo...")
+	if !strings.Contains(buf.String(), "[req-1] Still polling Veo...") {
+		t.Errorf("Expected the prefixed line, got %q", buf.String())
+	}
+}
+
+func TestWithFlowID(t *testing.T) {
+	if FlowID(context.Background()) != "" {
+		t.Error("Expected no flow ID by default")
+	}
+	id := NewFlowID()
+	if len(id) != 16 || id == NewFlowID() {
+		t.Fatalf("Expected distinct 16-digit IDs, got %q", id)
+	}
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), log.New(&buf, "[req-1] ", log.Lmsgprefix))
+	ctx = WithFlowID(ctx, id)
+	if FlowID(ctx) != id {
+		t.Errorf("Expected flow ID %q, got %q", id, FlowID(ctx))
+	}
+	Logf(ctx, "Resolved location")
+	if want := "[req-1] [flow " + id + "] Resolved location"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,93 @@
+package promptcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+// Index is the Firestore side of the cache (prompt_cache collection).
+type Index interface {
+	GetPromptCache(ctx context.Context, key string) (*database.PromptCacheEntry, error)
+	PutPromptCache(ctx context.Context, entry database.PromptCacheEntry) error
+}
+
+// Blobs stores the cached image bytes in GCS.
+type Blobs interface {
+	ReadObject(ctx context.Context, fileName string) ([]byte, error)
+	UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error)
+}
+
+// Cache reuses generated images for identical rendered prompts within the
+// same hour. The model looks up current weather itself, so the hour bucket
+// stands in for "same weather conditions".
+type Cache struct {
+	index Index
+	blobs Blobs
+	now   func() time.Time
+}
+
+func New(index Index, blobs Blobs) *Cache {
+	return &Cache{index: index, blobs: blobs, now: time.Now}
+}
+
+// bucket returns the start of the weather bucket containing t.
+func bucket(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// Key hashes the model, seed, rendered prompt and current weather bucket.
+// Images of different seeds get different keys, so a seed always reproduces
+// the image generated with it; a nil seed (the model picks one) is a key of
+// its own.
+func (c *Cache) Key(model, prompt string, seed *int32) string {
+	s := "-"
+	if seed != nil {
+		s = fmt.Sprint(*seed)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s", model, s, bucket(c.now()).Format(time.RFC3339), prompt)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached image for key, if present and not expired.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	entry, err := c.index.GetPromptCache(ctx, key)
+	if err != nil || entry == nil {
+		return nil, false
+	}
+	// Firestore TTL deletion is lazy, so check expiry ourselves.
+	if c.now().After(entry.ExpiresAt) {
+		return nil, false
+	}
+	data, err := c.blobs.ReadObject(ctx, entry.Object)
+	if err != nil {
+		log.Printf("Prompt cache blob %s unreadable: %v", entry.Object, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores an image under key until the end of the current weather bucket.
+func (c *Cache) Put(ctx context.Context, key string, data []byte) {
+	object := fmt.Sprintf("cache/%s.png", key)
+	if _, err := c.blobs.UploadBytes(ctx, data, object, "image/png"); err != nil {
+		log.Printf("Failed to store prompt cache blob: %v", err)
+		return
+	}
+	now := c.now()
+	err := c.index.PutPromptCache(ctx, database.PromptCacheEntry{
+		Key:       key,
+		Object:    object,
+		CreatedAt: now,
+		ExpiresAt: bucket(now).Add(time.Hour),
+	})
+	if err != nil {
+		log.Printf("Failed to index prompt cache entry: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package promptcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+type memIndex map[string]database.PromptCacheEntry
+
+func (m memIndex) GetPromptCache(ctx context.Context, key string) (*database.PromptCacheEntry, error) {
+	e, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return &e, nil
+}
+func (m memIndex) PutPromptCache(ctx context.Context, e database.PromptCacheEntry) error {
+	m[e.Key] = e
+	return nil
+}
+
+type memBlobs map[string][]byte
+
+func (m memBlobs) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	b, ok := m[name]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return b, nil
+}
+func (m memBlobs) UploadBytes(ctx context.Context, data []byte, name, mime string) (string, error) {
+	m[name] = data
+	return "https://example/" + name, nil
+}
+
+func TestCache_HourBucket(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 12, 1, 10, 15, 0, 0, time.UTC)
+
+	c := New(memIndex{}, memBlobs{})
+	c.now = func() time.Time { return now }
+
+	key := c.Key("model", "prompt", nil)
+	c.Put(ctx, key, []byte("png"))
+
+	// Same hour: same key, hit
+	now = now.Add(30 * time.Minute)
+	if c.Key("model", "prompt", nil) != key {
+		t.Error("Expected the same key within the hour")
+	}
+	if data, ok := c.Get(ctx, key); !ok || string(data) != "png" {
+		t.Error("Expected cache hit within the hour")
+	}
+
+	// Next hour: new key, and the old entry has expired
+	now = now.Add(time.Hour)
+	if c.Key("model", "prompt", nil) == key {
+		t.Error("Expected a new key in the next hour")
+	}
+	if _, ok := c.Get(ctx, key); ok {
+		t.Error("Expected the old entry to be expired")
+	}
+}
+
+func TestCache_Seed(t *testing.T) {
+	ctx := context.Background()
+	c := New(memIndex{}, memBlobs{})
+	seed1, seed2 := int32(1), int32(2)
+
+	key1 := c.Key("model", "prompt", &seed1)
+	c.Put(ctx, key1, []byte("png-1"))
+	key2 := c.Key("model", "prompt", &seed2)
+	if key2 == key1 || key1 == c.Key("model", "prompt", nil) {
+		t.Fatal("Expected a key per seed")
+	}
+	if _, ok := c.Get(ctx, key2); ok {
+		t.Error("Expected a miss for another seed of the same prompt")
+	}
+	c.Put(ctx, key2, []byte("png-2"))
+	if data, ok := c.Get(ctx, key1); !ok || string(data) != "png-1" {
+		t.Errorf("Expected the first seed's image, got %q", data)
+	}
+	if data, ok := c.Get(ctx, key2); !ok || string(data) != "png-2" {
+		t.Errorf("Expected the second seed's image, got %q", data)
+	}
+	if c.Key("model", "prompt", &seed1) != key1 {
+		t.Error("Expected the same key for the same seed")
+	}
+}
@@ -0,0 +1,110 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// -- PNG: the packet is an iTXt chunk with the XMP keyword, right after IHDR --
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// iTXt header: keyword, NUL, no compression, method 0, empty language and translated keyword
+var pngXMPHeader = []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00")
+
+func isPNG(img []byte) bool { return bytes.HasPrefix(img, pngSignature) }
+
+func embedPNG(img, packet []byte) ([]byte, error) {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(img) < ihdrEnd || string(img[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("invalid PNG: missing IHDR")
+	}
+	data := append(append([]byte{}, pngXMPHeader...), packet...)
+
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(data)))
+	chunk.WriteString("iTXt")
+	chunk.Write(data)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(append([]byte("iTXt"), data...)))
+
+	out := make([]byte, 0, len(img)+chunk.Len())
+	out = append(out, img[:ihdrEnd]...)
+	out = append(out, chunk.Bytes()...)
+	return append(out, img[ihdrEnd:]...), nil
+}
+
+// stripPNG returns img without our iTXt chunk, and the packet it held.
+func stripPNG(img []byte) ([]byte, []byte, error) {
+	for pos := len(pngSignature); pos+12 <= len(img); {
+		n := int(binary.BigEndian.Uint32(img[pos:]))
+		end := pos + 12 + n
+		if n < 0 || end > len(img) {
+			return nil, nil, fmt.Errorf("invalid PNG: truncated chunk")
+		}
+		typ, data := string(img[pos+4:pos+8]), img[pos+8:pos+8+n]
+		if typ == "iTXt" && bytes.HasPrefix(data, pngXMPHeader) && ours(data) {
+			original := append(append([]byte{}, img[:pos]...), img[end:]...)
+			return original, data[len(pngXMPHeader):], nil
+		}
+		if typ == "IEND" {
+			break
+		}
+		pos = end
+	}
+	return img, nil, nil
+}
+
+// -- JPEG: the packet is an APP1 segment with the XMP namespace header, after SOI/APP0 --
+
+var jpegXMPHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+func isJPEG(img []byte) bool { return len(img) > 3 && img[0] == 0xFF && img[1] == 0xD8 }
+
+func embedJPEG(img, packet []byte) ([]byte, error) {
+	n := 2 + len(jpegXMPHeader) + len(packet)
+	if n > 0xFFFF {
+		return nil, fmt.Errorf("XMP packet too large for a JPEG segment")
+	}
+	// Keep a JFIF APP0 segment first, as decoders expect
+	at := 2
+	if len(img) > 6 && img[2] == 0xFF && img[3] == 0xE0 {
+		at = 4 + int(binary.BigEndian.Uint16(img[4:]))
+		if at > len(img) {
+			return nil, fmt.Errorf("invalid JPEG: truncated APP0")
+		}
+	}
+
+	var seg bytes.Buffer
+	seg.Write([]byte{0xFF, 0xE1})
+	binary.Write(&seg, binary.BigEndian, uint16(n))
+	seg.Write(jpegXMPHeader)
+	seg.Write(packet)
+
+	out := make([]byte, 0, len(img)+seg.Len())
+	out = append(out, img[:at]...)
+	out = append(out, seg.Bytes()...)
+	return append(out, img[at:]...), nil
+}
+
+// stripJPEG returns img without our APP1 segment, and the packet it held.
+func stripJPEG(img []byte) ([]byte, []byte, error) {
+	for pos := 2; pos+4 <= len(img) && img[pos] == 0xFF; {
+		marker := img[pos+1]
+		if marker == 0xDA { // Start of scan: no metadata after this
+			break
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(img[pos+2:]))
+		if end > len(img) {
+			return nil, nil, fmt.Errorf("invalid JPEG: truncated segment")
+		}
+		data := img[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(data, jpegXMPHeader) && ours(data) {
+			original := append(append([]byte{}, img[:pos]...), img[end:]...)
+			return original, data[len(jpegXMPHeader):], nil
+		}
+		pos = end
+	}
+	return img, nil, nil
+}
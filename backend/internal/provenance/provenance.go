@@ -0,0 +1,203 @@
+// Package provenance labels generated images as AI-generated by embedding an
+// XMP packet (IPTC digital source type, creator tool, model, timestamp and
+// location ID), and verifies it later.
+//
+// This is not a C2PA manifest: there's no certificate chain. Instead the packet
+// records a SHA-256 digest of the image without the packet, optionally signed
+// with an HMAC key, so a deployment can tell its own unmodified images apart.
+package provenance
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tool is the xmp:CreatorTool written into every image.
+const Tool = "banana-weather"
+
+// DigitalSourceType is the IPTC term for media created by a generative model.
+const DigitalSourceType = "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia"
+
+const (
+	nsXMP    = "http://ns.adobe.com/xap/1.0/"
+	nsIPTC   = "http://iptc.org/std/Iptc4xmpExt/2008-02-29/"
+	nsRDF    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsBanana = "https://github.com/ghchinoy/banana-weather/ns/provenance/1.0/"
+)
+
+// ErrNoCredentials is returned by Verify for images without a banana-weather packet.
+var ErrNoCredentials = errors.New("no content credentials found")
+
+// Info is the provenance recorded in an image.
+type Info struct {
+	Tool              string    `json:"tool"`
+	DigitalSourceType string    `json:"digital_source_type"`
+	Model             string    `json:"model"`
+	LocationID        string    `json:"location_id"`
+	Created           time.Time `json:"created"`
+	Digest            string    `json:"digest"`              // "sha256:<hex>" of the image without the packet
+	Signature         string    `json:"signature,omitempty"` // HMAC-SHA256 of the fields above, when a key is set
+}
+
+// Verification is the result of checking an image's credentials.
+type Verification struct {
+	Info           Info `json:"info"`
+	AIGenerated    bool `json:"ai_generated"`
+	DigestValid    bool `json:"digest_valid"` // Pixels and other metadata are unchanged
+	Signed         bool `json:"signed"`
+	SignatureValid bool `json:"signature_valid"` // Signed with this deployment's key
+}
+
+// Signer embeds and verifies credentials. Key is optional; without one images
+// are labelled but not signed.
+type Signer struct {
+	Key []byte
+}
+
+func NewSigner(key string) *Signer {
+	return &Signer{Key: []byte(key)}
+}
+
+// Embed returns img (PNG or JPEG) with a provenance packet added.
+func (s *Signer) Embed(img []byte, model, locationID string, created time.Time) ([]byte, error) {
+	sum := sha256.Sum256(img)
+	info := Info{
+		Tool:              Tool,
+		DigitalSourceType: DigitalSourceType,
+		Model:             model,
+		LocationID:        locationID,
+		Created:           created.UTC().Truncate(time.Second),
+		Digest:            "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if len(s.Key) > 0 {
+		info.Signature = s.sign(info)
+	}
+	packet := buildPacket(info)
+
+	switch {
+	case isPNG(img):
+		return embedPNG(img, packet)
+	case isJPEG(img):
+		return embedJPEG(img, packet)
+	}
+	return nil, fmt.Errorf("unsupported image format")
+}
+
+// Verify reads the provenance packet from img and checks its digest and,
+// when both the image and the signer have one, its signature.
+func (s *Signer) Verify(img []byte) (*Verification, error) {
+	var original, packet []byte
+	var err error
+	switch {
+	case isPNG(img):
+		original, packet, err = stripPNG(img)
+	case isJPEG(img):
+		original, packet, err = stripJPEG(img)
+	default:
+		return nil, fmt.Errorf("unsupported image format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if packet == nil {
+		return nil, ErrNoCredentials
+	}
+
+	info, err := parsePacket(packet)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(original)
+	v := &Verification{
+		Info:        *info,
+		AIGenerated: info.DigitalSourceType == DigitalSourceType,
+		DigestValid: info.Digest == "sha256:"+hex.EncodeToString(sum[:]),
+		Signed:      info.Signature != "",
+	}
+	if v.Signed && len(s.Key) > 0 {
+		v.SignatureValid = hmac.Equal([]byte(info.Signature), []byte(s.sign(*info)))
+	}
+	return v, nil
+}
+
+func (s *Signer) sign(info Info) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(strings.Join([]string{
+		info.Tool, info.DigitalSourceType, info.Model, info.LocationID,
+		info.Created.Format(time.RFC3339), info.Digest,
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// -- XMP --
+
+func buildPacket(info Info) []byte {
+	attr := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return strings.ReplaceAll(b.String(), `"`, "&#34;")
+	}
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(` <rdf:RDF xmlns:rdf="` + nsRDF + `">` + "\n")
+	b.WriteString(`  <rdf:Description rdf:about=""` + "\n")
+	b.WriteString(`    xmlns:xmp="` + nsXMP + `"` + "\n")
+	b.WriteString(`    xmlns:Iptc4xmpExt="` + nsIPTC + `"` + "\n")
+	b.WriteString(`    xmlns:banana="` + nsBanana + `"` + "\n")
+	fmt.Fprintf(&b, "    xmp:CreatorTool=\"%s\"\n", attr(info.Tool))
+	fmt.Fprintf(&b, "    xmp:CreateDate=\"%s\"\n", info.Created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "    Iptc4xmpExt:DigitalSourceType=\"%s\"\n", attr(info.DigitalSourceType))
+	fmt.Fprintf(&b, "    banana:Model=\"%s\"\n", attr(info.Model))
+	fmt.Fprintf(&b, "    banana:LocationID=\"%s\"\n", attr(info.LocationID))
+	fmt.Fprintf(&b, "    banana:Digest=\"%s\"\n", attr(info.Digest))
+	fmt.Fprintf(&b, "    banana:Signature=\"%s\"/>\n", attr(info.Signature))
+	b.WriteString(" </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"r\"?>")
+	return b.Bytes()
+}
+
+type xmpMeta struct {
+	Description struct {
+		CreatorTool       string `xml:"http://ns.adobe.com/xap/1.0/ CreatorTool,attr"`
+		CreateDate        string `xml:"http://ns.adobe.com/xap/1.0/ CreateDate,attr"`
+		DigitalSourceType string `xml:"http://iptc.org/std/Iptc4xmpExt/2008-02-29/ DigitalSourceType,attr"`
+		Model             string `xml:"https://github.com/ghchinoy/banana-weather/ns/provenance/1.0/ Model,attr"`
+		LocationID        string `xml:"https://github.com/ghchinoy/banana-weather/ns/provenance/1.0/ LocationID,attr"`
+		Digest            string `xml:"https://github.com/ghchinoy/banana-weather/ns/provenance/1.0/ Digest,attr"`
+		Signature         string `xml:"https://github.com/ghchinoy/banana-weather/ns/provenance/1.0/ Signature,attr"`
+	} `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF>Description"`
+}
+
+func parsePacket(packet []byte) (*Info, error) {
+	var m xmpMeta
+	if err := xml.Unmarshal(packet, &m); err != nil {
+		return nil, fmt.Errorf("invalid XMP packet: %w", err)
+	}
+	d := m.Description
+	created, err := time.Parse(time.RFC3339, d.CreateDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xmp:CreateDate %q", d.CreateDate)
+	}
+	return &Info{
+		Tool:              d.CreatorTool,
+		DigitalSourceType: d.DigitalSourceType,
+		Model:             d.Model,
+		LocationID:        d.LocationID,
+		Created:           created,
+		Digest:            d.Digest,
+		Signature:         d.Signature,
+	}, nil
+}
+
+// ours reports whether an XMP packet was written by Embed, so packets from
+// other tools are left in place.
+func ours(packet []byte) bool {
+	return bytes.Contains(packet, []byte(nsBanana))
+}
@@ -0,0 +1,87 @@
+package provenance
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func testImage(t *testing.T, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEmbedVerify(t *testing.T) {
+	created := time.Date(2025, 12, 1, 12, 30, 0, 0, time.UTC)
+	for _, format := range []string{"png", "jpeg"} {
+		t.Run(format, func(t *testing.T) {
+			orig := testImage(t, format)
+			signer := NewSigner("secret")
+			stamped, err := signer.Embed(orig, "gemini-3.1-flash-image-preview", `paris_"france"`, created)
+			if err != nil {
+				t.Fatalf("Embed failed: %v", err)
+			}
+
+			// Still a valid image
+			if _, _, err := image.Decode(bytes.NewReader(stamped)); err != nil {
+				t.Fatalf("Stamped image doesn't decode: %v", err)
+			}
+
+			v, err := signer.Verify(stamped)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if !v.AIGenerated || !v.DigestValid || !v.Signed || !v.SignatureValid {
+				t.Errorf("Expected a valid signed credential, got %+v", v)
+			}
+			if v.Info.Model != "gemini-3.1-flash-image-preview" || v.Info.LocationID != `paris_"france"` || !v.Info.Created.Equal(created) {
+				t.Errorf("Unexpected info: %+v", v.Info)
+			}
+
+			// Another deployment can read it but not vouch for it
+			v, _ = NewSigner("other").Verify(stamped)
+			if !v.DigestValid || v.SignatureValid {
+				t.Errorf("Expected digest valid, signature invalid with another key, got %+v", v)
+			}
+
+			if _, err := signer.Verify(orig); !errors.Is(err, ErrNoCredentials) {
+				t.Errorf("Expected ErrNoCredentials for an unstamped image, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	signer := NewSigner("")
+	stamped, err := signer.Embed(testImage(t, "png"), "model", "tokyo", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte in the IEND CRC: the packet survives, the digest no longer matches
+	stamped[len(stamped)-1] ^= 0xFF
+
+	v, err := signer.Verify(stamped)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if v.DigestValid || v.Signed {
+		t.Errorf("Expected an invalid, unsigned credential, got %+v", v)
+	}
+}
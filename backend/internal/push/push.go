@@ -0,0 +1,184 @@
+// Package push sends mobile/web push notifications through Firebase Cloud
+// Messaging (FCM) topics. Devices follow presets, categories and the city of
+// the day by subscribing their registration token to the matching topics.
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"banana-weather/internal/config"
+
+	"golang.org/x/oauth2/google"
+)
+
+// CityOfTheDayTopic is followed by devices that want the daily pick.
+const CityOfTheDayTopic = "city_of_the_day"
+
+// maxConditionTopics is the most topics FCM accepts in one condition.
+const maxConditionTopics = 5
+
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9\-_.~%]+`)
+
+// PresetTopic is the topic of one preset location.
+func PresetTopic(id string) string {
+	return "preset_" + invalidTopicChars.ReplaceAllString(id, "_")
+}
+
+// CategoryTopic is the topic of a preset category ("Dune Universe" ->
+// category_dune_universe).
+func CategoryTopic(category string) string {
+	return "category_" + strings.Trim(invalidTopicChars.ReplaceAllString(strings.ToLower(category), "_"), "_")
+}
+
+// ParseTopic converts what a client follows to a topic: "preset:<id>",
+// "category:<name>" or "city_of_the_day".
+func ParseTopic(s string) (string, error) {
+	kind, value, _ := strings.Cut(s, ":")
+	switch {
+	case s == CityOfTheDayTopic:
+		return CityOfTheDayTopic, nil
+	case kind == "preset" && value != "":
+		return PresetTopic(value), nil
+	case kind == "category" && value != "":
+		return CategoryTopic(value), nil
+	}
+	return "", fmt.Errorf("unknown topic %q (want preset:<id>, category:<name> or %s)", s, CityOfTheDayTopic)
+}
+
+// Message is a notification sent to every device subscribed to any of Topics.
+// A device following several of them gets it once.
+type Message struct {
+	Topics   []string
+	Title    string
+	Body     string
+	ImageURL string            // Optional: shown in the expanded notification
+	Data     map[string]string // Optional: app payload, e.g. the location ID
+}
+
+// Sender delivers messages. *FCM implements it.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// FCM talks to the FCM HTTP v1 API and the Instance ID API (topic
+// subscriptions) with Application Default Credentials.
+type FCM struct {
+	projectID string
+	client    *http.Client
+	fcmURL    string
+	iidURL    string
+}
+
+// NewFCM returns a client for the Firebase project (usually the GCP project).
+func NewFCM(ctx context.Context, projectID string) (*FCM, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/firebase.messaging")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FCM credentials: %w", err)
+	}
+	return &FCM{
+		projectID: projectID,
+		client:    client,
+		fcmURL:    "https://fcm.googleapis.com",
+		iidURL:    "https://iid.googleapis.com",
+	}, nil
+}
+
+// Open returns the FCM client for cfg.FCMProjectID, or nil when
+// PUSH_NOTIFICATIONS is off.
+func Open(ctx context.Context, cfg *config.Config) (*FCM, error) {
+	if !cfg.PushNotifications {
+		return nil, nil
+	}
+	return NewFCM(ctx, cfg.FCMProjectID)
+}
+
+// condition builds an FCM condition matching devices in any of topics.
+func condition(topics []string) string {
+	parts := make([]string, len(topics))
+	for i, t := range topics {
+		parts[i] = fmt.Sprintf("'%s' in topics", t)
+	}
+	return strings.Join(parts, " || ")
+}
+
+// Send delivers m to its topics.
+func (f *FCM) Send(ctx context.Context, m Message) error {
+	if len(m.Topics) == 0 || len(m.Topics) > maxConditionTopics {
+		return fmt.Errorf("a message needs 1 to %d topics, got %d", maxConditionTopics, len(m.Topics))
+	}
+	msg := map[string]any{
+		"notification": map[string]string{"title": m.Title, "body": m.Body, "image": m.ImageURL},
+	}
+	if len(m.Topics) == 1 {
+		msg["topic"] = m.Topics[0]
+	} else {
+		msg["condition"] = condition(m.Topics)
+	}
+	if len(m.Data) > 0 {
+		msg["data"] = m.Data
+	}
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", f.fcmURL, f.projectID)
+	return f.post(ctx, endpoint, map[string]any{"message": msg}, nil)
+}
+
+// Subscribe adds a device token to topics.
+func (f *FCM) Subscribe(ctx context.Context, token string, topics []string) error {
+	return f.batch(ctx, "batchAdd", token, topics)
+}
+
+// Unsubscribe removes a device token from topics.
+func (f *FCM) Unsubscribe(ctx context.Context, token string, topics []string) error {
+	return f.batch(ctx, "batchRemove", token, topics)
+}
+
+func (f *FCM) batch(ctx context.Context, op, token string, topics []string) error {
+	for _, topic := range topics {
+		var out struct {
+			Results []struct {
+				Error string `json:"error"`
+			} `json:"results"`
+		}
+		body := map[string]any{"to": "/topics/" + topic, "registration_tokens": []string{token}}
+		if err := f.post(ctx, f.iidURL+"/iid/v1:"+op, body, &out); err != nil {
+			return err
+		}
+		if len(out.Results) > 0 && out.Results[0].Error != "" {
+			return fmt.Errorf("%s %s: %s", op, topic, out.Results[0].Error)
+		}
+	}
+	return nil
+}
+
+func (f *FCM) post(ctx context.Context, endpoint string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("access_token_auth", "true") // Lets the Instance ID API accept OAuth tokens
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s: %s", endpoint, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTopics(t *testing.T) {
+	for in, want := range map[string]string{
+		"city_of_the_day":        "city_of_the_day",
+		"preset:paris_fr":        "preset_paris_fr",
+		"category:Dune Universe": "category_dune_universe",
+		"category:Sci-Fi & More": "category_sci-fi_more",
+	} {
+		if got, err := ParseTopic(in); err != nil || got != want {
+			t.Errorf("ParseTopic(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "preset:", "weather:paris", "paris"} {
+		if _, err := ParseTopic(in); err == nil {
+			t.Errorf("Expected %q to be rejected", in)
+		}
+	}
+}
+
+func TestFCM(t *testing.T) {
+	var sent map[string]map[string]any
+	var iid []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/projects/demo/messages:send":
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+		case strings.HasPrefix(r.URL.Path, "/iid/v1:"):
+			var body struct {
+				To string `json:"to"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			iid = append(iid, r.URL.Path+" "+body.To)
+			if body.To == "/topics/preset_bad" {
+				w.Write([]byte(`{"results":[{"error":"INVALID_ARGUMENT"}]}`))
+				return
+			}
+			w.Write([]byte(`{"results":[{}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	f := &FCM{projectID: "demo", client: srv.Client(), fcmURL: srv.URL, iidURL: srv.URL}
+	ctx := context.Background()
+
+	if err := f.Send(ctx, Message{Topics: []string{"preset_paris"}, Title: "Paris", Data: map[string]string{"location_id": "paris"}}); err != nil {
+		t.Fatal(err)
+	}
+	if sent["message"]["topic"] != "preset_paris" || sent["message"]["condition"] != nil {
+		t.Errorf("Expected a single-topic message, got %v", sent)
+	}
+	if err := f.Send(ctx, Message{Topics: []string{"city_of_the_day", "preset_paris"}}); err != nil {
+		t.Fatal(err)
+	}
+	if sent["message"]["condition"] != "'city_of_the_day' in topics || 'preset_paris' in topics" {
+		t.Errorf("Unexpected condition %v", sent["message"]["condition"])
+	}
+	if err := f.Send(ctx, Message{Topics: make([]string, 6)}); err == nil {
+		t.Error("Expected more than 5 topics to be rejected")
+	}
+
+	if err := f.Subscribe(ctx, "tok", []string{"preset_paris", "city_of_the_day"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(iid) != 2 || iid[0] != "/iid/v1:batchAdd /topics/preset_paris" {
+		t.Errorf("Unexpected IID calls %v", iid)
+	}
+	if err := f.Unsubscribe(ctx, "tok", []string{"preset_bad"}); err == nil || !strings.Contains(err.Error(), "INVALID_ARGUMENT") {
+		t.Errorf("Expected the per-token error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest city query accepted, in characters.
+const MaxLength = 100
+
+// Error codes returned in Error.Code.
+const (
+	CodeEmpty           = "empty"
+	CodeTooLong         = "too_long"
+	CodeURL             = "url"
+	CodeNoLetters       = "no_letters"
+	CodePromptInjection = "prompt_injection"
+)
+
+// Error is a rejected query. Message is safe to show to users.
+type Error struct {
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query (%s): %s", e.Code, e.Message)
+}
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)([a-z][a-z0-9+.-]*://|^www\.|\.(com|net|org|io|ly|xyz)(/|$))`)
+
+	// Obvious attempts to steer the image prompt rather than name a place
+	injectionPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\b.{0,20}\b(previous|prior|above|earlier|all)\b.{0,20}\b(instructions?|prompts?|rules?)\b`),
+		regexp.MustCompile(`(?i)\b(system|developer)\s+prompt\b`),
+		regexp.MustCompile(`(?i)\byou\s+are\s+now\b`),
+		regexp.MustCompile(`(?i)\bnew\s+instructions?\b`),
+		regexp.MustCompile(`(?i)\bact\s+as\b`),
+	}
+)
+
+// Normalize cleans a city query and rejects anything that isn't plausibly a
+// place name before it reaches geocoding or prompt assembly. Control characters
+// are stripped and whitespace is collapsed. An empty query is returned as-is,
+// since callers apply their own default.
+func Normalize(q string) (string, error) {
+	q = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, q)
+	q = strings.Join(strings.Fields(q), " ")
+
+	if q == "" {
+		return "", nil
+	}
+	if utf8.RuneCountInString(q) > MaxLength {
+		return "", &Error{CodeTooLong, fmt.Sprintf("Location must be %d characters or fewer.", MaxLength)}
+	}
+	if urlPattern.MatchString(q) {
+		return "", &Error{CodeURL, "Please enter a place name, not a link."}
+	}
+	// Digits alone are fine (postal codes), emoji and punctuation alone aren't
+	if strings.IndexFunc(q, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return "", &Error{CodeNoLetters, "Please enter a place name."}
+	}
+	for _, p := range injectionPatterns {
+		if p.MatchString(q) {
+			return "", &Error{CodePromptInjection, "Please enter a place name."}
+		}
+	}
+	return q, nil
+}
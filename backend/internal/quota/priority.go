@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+)
+
+// Priority orders requests waiting for the same partition: a waiting request
+// goes ahead of every lower-priority one.
+type Priority int
+
+const (
+	PriorityBatch       Priority = iota // Backfills: banana warmup, batch generate
+	PriorityScheduled                   // Refreshes: admin, city of the day
+	PriorityInteractive                 // Live web requests
+	priorities
+)
+
+var priorityNames = [priorities]string{"batch", "scheduled", "interactive"}
+
+func (p Priority) String() string {
+	if p < 0 || p >= priorities {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority reads a priority name.
+func ParsePriority(s string) (Priority, error) {
+	for p, name := range priorityNames {
+		if s == name {
+			return Priority(p), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q (use interactive, scheduled or batch)", s)
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose generation requests queue at p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// DefaultPriority returns a context at p, unless ctx already has a priority.
+// Entry points use it so callers can override the priority they imply.
+func DefaultPriority(ctx context.Context, p Priority) context.Context {
+	if _, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return ctx
+	}
+	return WithPriority(ctx, p)
+}
+
+// PriorityFrom returns the priority of ctx, PriorityInteractive by default.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
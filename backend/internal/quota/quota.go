@@ -0,0 +1,268 @@
+// Package quota partitions model capacity: image and video generation each
+// get their own in-flight and per-minute limits, so a burst of Veo jobs
+// can't take the capacity interactive image requests need. Within a
+// partition, waiting requests are served by priority. Limits are per
+// process.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/progress"
+)
+
+// Kinds of generation a limit can apply to, besides a model name.
+const (
+	KindImage      = "image"
+	KindVideo      = "video"
+	KindGeneration = "generation" // A whole pipeline run, see MAX_CONCURRENT_GENERATIONS
+)
+
+// generationWait is how long a generation queues for MAX_CONCURRENT_GENERATIONS.
+const generationWait = time.Minute
+
+// ErrExhausted is returned when a partition has no capacity left within its
+// wait.
+var ErrExhausted = errors.New("quota exhausted")
+
+// Limit bounds one partition. Zero values are unlimited.
+type Limit struct {
+	InFlight  int           // Concurrent requests
+	PerMinute int           // Requests started per rolling minute
+	MaxWait   time.Duration // How long a request queues for capacity before ErrExhausted
+	Reserve   int           // In-flight slots only interactive requests may use
+}
+
+// Usage is the current state of a partition.
+type Usage struct {
+	Key        string         `json:"key"`
+	InFlight   int            `json:"in_flight"`
+	LastMinute int            `json:"last_minute"` // Requests started
+	Waiting    int            `json:"waiting"`
+	WaitingBy  map[string]int `json:"waiting_by_priority,omitempty"`
+	Rejected   int            `json:"rejected"` // Since the process started
+	Limit      Limit          `json:"limit"`
+}
+
+type partition struct {
+	limit    Limit
+	inFlight int
+	starts   []time.Time // Within the last minute, oldest first
+	waiting  [priorities]int
+	rejected int
+	freed    chan struct{} // Closed when capacity may have freed up
+}
+
+// Manager hands out capacity per partition. A nil Manager is unlimited.
+type Manager struct {
+	mu     sync.Mutex
+	limits map[string]Limit
+	parts  map[string]*partition
+	now    func() time.Time
+}
+
+// New returns a Manager enforcing limits, keyed by model name or kind.
+func New(limits map[string]Limit) *Manager {
+	return &Manager{limits: limits, parts: map[string]*partition{}, now: time.Now}
+}
+
+// Open returns the Manager of QUOTA_LIMITS and MAX_CONCURRENT_GENERATIONS,
+// or nil when neither is set. MAX_CONCURRENT_GENERATIONS=N is short for
+// generation=inflight:N,wait:1m; a generation entry in QUOTA_LIMITS wins.
+func Open(cfg *config.Config) (*Manager, error) {
+	if len(cfg.QuotaLimits) == 0 && cfg.MaxGenerations <= 0 {
+		return nil, nil
+	}
+	limits, err := Parse(cfg.QuotaLimits)
+	if err != nil {
+		return nil, fmt.Errorf("QUOTA_LIMITS: %w", err)
+	}
+	if _, ok := limits[KindGeneration]; !ok && cfg.MaxGenerations > 0 {
+		limits[KindGeneration] = Limit{InFlight: cfg.MaxGenerations, MaxWait: generationWait}
+	}
+	return New(limits), nil
+}
+
+// Parse reads limits written as key=setting,..., where key is a model name
+// or a kind and settings are inflight:N, rpm:N, wait:DURATION and
+// reserve:N, e.g. "video=inflight:2,rpm:6,wait:0s".
+func Parse(entries []string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, e := range entries {
+		key, settings, ok := strings.Cut(e, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("want key=settings, got %q", e)
+		}
+		var l Limit
+		for _, s := range strings.Split(settings, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(s), ":")
+			var err error
+			switch name {
+			case "inflight":
+				l.InFlight, err = strconv.Atoi(value)
+			case "rpm":
+				l.PerMinute, err = strconv.Atoi(value)
+			case "wait":
+				l.MaxWait, err = time.ParseDuration(value)
+			case "reserve":
+				l.Reserve, err = strconv.Atoi(value)
+			default:
+				err = fmt.Errorf("unknown setting %q (use inflight, rpm, wait or reserve)", name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		limits[key] = l
+	}
+	return limits, nil
+}
+
+// Acquire takes capacity for a request to model, of kind KindImage,
+// KindVideo or KindGeneration (with no model), waiting up to the limit's MaxWait. The limit of the model
+// applies if there is one, else the one of its kind; requests with neither
+// are unlimited. Requests queue at the priority of ctx (see WithPriority).
+// Call release when the request finishes.
+func (m *Manager) Acquire(ctx context.Context, kind, model string) (release func(), err error) {
+	if m == nil {
+		return func() {}, nil
+	}
+	key := model
+	if _, ok := m.limits[key]; !ok {
+		key = kind
+	}
+	limit, ok := m.limits[key]
+	if !ok {
+		return func() {}, nil
+	}
+
+	m.mu.Lock()
+	p := m.parts[key]
+	if p == nil {
+		p = &partition{limit: limit, freed: make(chan struct{})}
+		m.parts[key] = p
+	}
+	prio := PriorityFrom(ctx)
+	deadline := m.now().Add(limit.MaxWait)
+	announced := false
+	for {
+		now := m.now()
+		p.prune(now)
+		retry, ok := p.admit(now, prio)
+		if ok {
+			p.inFlight++
+			p.starts = append(p.starts, now)
+			m.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(func() { m.release(p) }) }, nil
+		}
+		if !now.Before(deadline) {
+			p.rejected++
+			err := fmt.Errorf("%w for %s (%d in flight, %d in the last minute)", ErrExhausted, key, p.inFlight, len(p.starts))
+			m.mu.Unlock()
+			return nil, err
+		}
+
+		if !announced {
+			log.Printf("Quota %s is full, queuing %s request for %s", key, prio, model)
+			progress.Emit(ctx, progress.Update{Stage: kind, Message: "Waiting for capacity"})
+			announced = true
+		}
+		freed := p.freed
+		p.waiting[prio]++
+		m.mu.Unlock()
+		timer := time.NewTimer(min(retry, deadline.Sub(now)))
+		select {
+		case <-freed:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		m.mu.Lock()
+		p.waiting[prio]--
+		if ctx.Err() != nil {
+			m.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (m *Manager) release(p *partition) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p.inFlight--
+	close(p.freed)
+	p.freed = make(chan struct{})
+}
+
+// prune forgets starts older than a minute.
+func (p *partition) prune(now time.Time) {
+	i := 0
+	for i < len(p.starts) && now.Sub(p.starts[i]) >= time.Minute {
+		i++
+	}
+	p.starts = p.starts[i:]
+}
+
+// admit reports whether a request at prio may start now, and otherwise when
+// to check again: when the oldest start leaves the minute, or at the latest
+// in a second (a release wakes waiters earlier). Higher-priority waiters go
+// first, and only interactive requests use the reserved slots.
+func (p *partition) admit(now time.Time, prio Priority) (retry time.Duration, ok bool) {
+	retry = time.Second
+	ok = true
+	for q := prio + 1; q < priorities; q++ {
+		if p.waiting[q] > 0 {
+			ok = false
+		}
+	}
+	inFlight := p.limit.InFlight
+	if prio < PriorityInteractive {
+		inFlight -= p.limit.Reserve
+	}
+	if p.limit.InFlight > 0 && p.inFlight >= max(inFlight, 0) {
+		ok = false
+	}
+	if p.limit.PerMinute > 0 && len(p.starts) >= p.limit.PerMinute {
+		ok = false
+		retry = min(retry, time.Minute-now.Sub(p.starts[0]))
+	}
+	return retry, ok
+}
+
+// Usage returns the state of each partition used so far, sorted by key.
+func (m *Manager) Usage() []Usage {
+	if m == nil {
+		return []Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Usage{}
+	now := m.now()
+	for key, p := range m.parts {
+		p.prune(now)
+		u := Usage{Key: key, InFlight: p.inFlight, LastMinute: len(p.starts), Rejected: p.rejected, Limit: p.limit}
+		for prio, n := range p.waiting {
+			if n > 0 {
+				u.Waiting += n
+				if u.WaitingBy == nil {
+					u.WaitingBy = map[string]int{}
+				}
+				u.WaitingBy[Priority(prio).String()] = n
+			}
+		}
+		out = append(out, u)
+	}
+	slices.SortFunc(out, func(a, b Usage) int { return strings.Compare(a.Key, b.Key) })
+	return out
+}
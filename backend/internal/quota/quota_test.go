@@ -0,0 +1,205 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	limits, err := Parse([]string{"image=inflight:4,rpm:30,wait:20s,reserve:1", "veo-3.1-lite-generate-001=inflight:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := limits[KindImage]; l != (Limit{InFlight: 4, PerMinute: 30, MaxWait: 20 * time.Second, Reserve: 1}) {
+		t.Errorf("Unexpected image limit %+v", l)
+	}
+	if l := limits["veo-3.1-lite-generate-001"]; l.InFlight != 1 {
+		t.Errorf("Unexpected model limit %+v", l)
+	}
+	for _, bad := range []string{"image", "image=burst:3", "video=rpm:x"} {
+		if _, err := Parse([]string{bad}); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestOpen_MaxGenerations(t *testing.T) {
+	if m, err := Open(&config.Config{}); m != nil || err != nil {
+		t.Errorf("Expected no manager without limits, got %v, %v", m, err)
+	}
+	m, err := Open(&config.Config{MaxGenerations: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.limits[KindGeneration]; l != (Limit{InFlight: 3, MaxWait: time.Minute}) {
+		t.Errorf("Unexpected generation limit %+v", l)
+	}
+	m, _ = Open(&config.Config{MaxGenerations: 3, QuotaLimits: []string{"generation=inflight:8,wait:5s"}})
+	if l := m.limits[KindGeneration]; l.InFlight != 8 || l.MaxWait != 5*time.Second {
+		t.Errorf("Expected QUOTA_LIMITS to win, got %+v", l)
+	}
+}
+
+func TestAcquire_PartitionsByKind(t *testing.T) {
+	m := New(map[string]Limit{KindVideo: {InFlight: 1}})
+	ctx := context.Background()
+
+	release, err := m.Acquire(ctx, KindVideo, "veo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Acquire(ctx, KindVideo, "veo"); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected the second video to be rejected, got %v", err)
+	}
+	// Video jobs don't hold image capacity.
+	for range 5 {
+		if _, err := m.Acquire(ctx, KindImage, "gemini"); err != nil {
+			t.Errorf("Expected images to be unlimited, got %v", err)
+		}
+	}
+
+	release()
+	release() // Idempotent
+	if _, err := m.Acquire(ctx, KindVideo, "veo"); err != nil {
+		t.Errorf("Expected capacity after release, got %v", err)
+	}
+	if u := m.Usage(); len(u) != 1 || u[0].Key != KindVideo || u[0].InFlight != 1 || u[0].Rejected != 1 {
+		t.Errorf("Unexpected usage %+v", u)
+	}
+}
+
+func TestAcquire_ModelOverridesKind(t *testing.T) {
+	m := New(map[string]Limit{KindImage: {InFlight: 1}, "gemini-pro": {InFlight: 2}})
+	ctx := context.Background()
+	for range 2 {
+		if _, err := m.Acquire(ctx, KindImage, "gemini-pro"); err != nil {
+			t.Fatalf("Expected the model limit to apply, got %v", err)
+		}
+	}
+	if _, err := m.Acquire(ctx, KindImage, "gemini-flash"); err != nil {
+		t.Errorf("Expected the kind partition to be separate, got %v", err)
+	}
+}
+
+func TestAcquire_PerMinute(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	m := New(map[string]Limit{KindImage: {PerMinute: 2}})
+	m.now = c.Now
+	ctx := context.Background()
+
+	for range 2 {
+		release, err := m.Acquire(ctx, KindImage, "gemini")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if _, err := m.Acquire(ctx, KindImage, "gemini"); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected the third request in a minute to be rejected, got %v", err)
+	}
+	c.Advance(time.Minute)
+	if _, err := m.Acquire(ctx, KindImage, "gemini"); err != nil {
+		t.Errorf("Expected capacity a minute later, got %v", err)
+	}
+}
+
+func TestAcquire_QueuesUntilRelease(t *testing.T) {
+	m := New(map[string]Limit{KindImage: {InFlight: 1, MaxWait: 5 * time.Second}})
+	ctx := context.Background()
+	release, err := m.Acquire(ctx, KindImage, "gemini")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := m.Acquire(ctx, KindImage, "gemini")
+		done <- err
+	}()
+	for m.Usage()[0].Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release()
+	if err := <-done; err != nil {
+		t.Errorf("Expected the queued request to run after release, got %v", err)
+	}
+}
+
+func TestAcquire_Priority(t *testing.T) {
+	m := New(map[string]Limit{KindImage: {InFlight: 1, MaxWait: 5 * time.Second}})
+	ctx := context.Background()
+	release, err := m.Acquire(ctx, KindImage, "gemini")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Priority, 2)
+	queue := func(p Priority) {
+		release, err := m.Acquire(WithPriority(ctx, p), KindImage, "gemini")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		order <- p
+		release()
+	}
+	waiting := func(n int) {
+		for m.Usage()[0].Waiting < n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	go queue(PriorityBatch)
+	waiting(1)
+	go queue(PriorityInteractive)
+	waiting(2)
+
+	release()
+	if first, second := <-order, <-order; first != PriorityInteractive || second != PriorityBatch {
+		t.Errorf("Expected interactive before batch, got %s then %s", first, second)
+	}
+}
+
+func TestAcquire_Reserve(t *testing.T) {
+	m := New(map[string]Limit{KindImage: {InFlight: 2, Reserve: 1}})
+	batch := WithPriority(context.Background(), PriorityBatch)
+	if _, err := m.Acquire(batch, KindImage, "gemini"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Acquire(batch, KindImage, "gemini"); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected batch to stay out of the reserved slot, got %v", err)
+	}
+	if _, err := m.Acquire(context.Background(), KindImage, "gemini"); err != nil {
+		t.Errorf("Expected interactive to use the reserved slot, got %v", err)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, p := range []Priority{PriorityBatch, PriorityScheduled, PriorityInteractive} {
+		if got, err := ParsePriority(p.String()); err != nil || got != p {
+			t.Errorf("ParsePriority(%q) = %s, %v", p, got, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("Expected an error for an unknown priority")
+	}
+	if p := PriorityFrom(DefaultPriority(WithPriority(context.Background(), PriorityBatch), PriorityScheduled)); p != PriorityBatch {
+		t.Errorf("Expected DefaultPriority to keep the set priority, got %s", p)
+	}
+}
+
+func TestAcquire_Nil(t *testing.T) {
+	var m *Manager
+	release, err := m.Acquire(context.Background(), KindVideo, "veo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if u := m.Usage(); len(u) != 0 {
+		t.Errorf("Expected no usage, got %+v", u)
+	}
+}
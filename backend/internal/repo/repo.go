@@ -0,0 +1,209 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/postgres"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// LocationStore reads and writes locations.
+type LocationStore interface {
+	GetLocation(ctx context.Context, id string) (*database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	DeleteLocation(ctx context.Context, id string) error
+	ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error)
+	GetPresets(ctx context.Context) ([]database.Location, error)
+	GetPresetSummaries(ctx context.Context, opts database.PresetOptions) ([]database.PresetSummary, error)
+	GetLocationsByCountry(ctx context.Context, code string, limit int) ([]database.Location, error)
+	GetStats(ctx context.Context) (*database.Stats, error)
+
+	// Partial updates
+	SetStatus(ctx context.Context, id string, status database.LocationStatus) error
+	SetGeo(ctx context.Context, id string, geo *latlng.LatLng, countryCode, continent string) error
+	SetNameI18n(ctx context.Context, id string, names map[string]string) error
+	SetChecksums(ctx context.Context, id string, sums map[string]string) error
+	UpdateLocations(ctx context.Context, edits []database.LocationEdit) error
+}
+
+// ModerationStore records user feedback and abuse reports.
+type ModerationStore interface {
+	AddFeedback(ctx context.Context, id string, fb database.Feedback) error
+	AddReport(ctx context.Context, id string, r database.Report, threshold int) (hidden bool, err error)
+	ResolveReview(ctx context.Context, id string) error
+}
+
+// SettingsStore holds the per-tenant settings docs.
+type SettingsStore interface {
+	GetBranding(ctx context.Context, tenant string) (*database.Branding, error)
+	SetBranding(ctx context.Context, tenant string, b database.Branding) error
+	GetLocationPolicy(ctx context.Context, tenant string) (*database.LocationPolicy, error)
+	SetLocationPolicy(ctx context.Context, tenant string, p database.LocationPolicy) error
+	ListCategories(ctx context.Context) ([]database.Category, error)
+	SetCategories(ctx context.Context, categories []database.Category) error
+	// Deployment-wide switches, incl. alert thresholds and degrade mode
+	GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error)
+	UpdateRuntimeSettings(ctx context.Context, update func(s *database.RuntimeSettings)) (*database.RuntimeSettings, error)
+}
+
+// AuditStore appends to the audit log.
+type AuditStore interface {
+	AddAuditEntry(ctx context.Context, e database.AuditEntry) error
+}
+
+// HistoryStore reads past versions of locations and the audit log, to see
+// what a location looked like at a given time.
+type HistoryStore interface {
+	GetLocationAsOf(ctx context.Context, id string, at time.Time) (*database.LocationRevision, error)
+	ListAuditEntries(ctx context.Context, location string, since, until time.Time) ([]database.AuditEntry, error)
+}
+
+// OperationStore tracks in-flight Veo operations.
+type OperationStore interface {
+	TrackOperation(ctx context.Context, op database.PendingOperation) error
+	ClearOperation(ctx context.Context, name string) error
+	ListOperations(ctx context.Context) ([]database.PendingOperation, error)
+	// Recent durations per model, for progress estimates
+	RecordModelTiming(ctx context.Context, model string, d time.Duration) error
+	GetModelTimings(ctx context.Context, model string) (*database.ModelTimings, error)
+}
+
+// LatencyStore keeps web flow stage durations for SLO reports.
+type LatencyStore interface {
+	AddLatencySample(ctx context.Context, s database.LatencySample) error
+	ListLatencySamples(ctx context.Context, since time.Time) ([]database.LatencySample, error)
+}
+
+// TraceStore keeps sampled weather flow event streams for debugging.
+type TraceStore interface {
+	SaveFlowTrace(ctx context.Context, t database.FlowTrace) error
+	GetFlowTrace(ctx context.Context, id string) (*database.FlowTrace, error)
+	ListFlowTraces(ctx context.Context, limit int) ([]database.FlowTrace, error)
+}
+
+// PromptCacheStore indexes cached images by prompt hash.
+type PromptCacheStore interface {
+	GetPromptCache(ctx context.Context, key string) (*database.PromptCacheEntry, error)
+	PutPromptCache(ctx context.Context, e database.PromptCacheEntry) error
+}
+
+// FeaturedStore keeps the city of the day history.
+type FeaturedStore interface {
+	SetCityOfTheDay(ctx context.Context, f database.FeaturedCity) error
+	ListCityOfTheDay(ctx context.Context, limit int) ([]database.FeaturedCity, error)
+}
+
+// WalletStore keeps the Apple Wallet devices registered for location passes.
+type WalletStore interface {
+	RegisterPass(ctx context.Context, r database.PassRegistration) (created bool, err error)
+	UnregisterPass(ctx context.Context, deviceID, serial string) error
+	ListPassRegistrations(ctx context.Context, field, value string) ([]database.PassRegistration, error)
+}
+
+// CandidateStore keeps media generated for review without replacing a
+// location's live media.
+type CandidateStore interface {
+	AddCandidate(ctx context.Context, c database.Candidate) (string, error)
+	ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error)
+	GetCandidate(ctx context.Context, id string) (*database.Candidate, error)
+	SetCandidateStatus(ctx context.Context, id, status string) error
+	TransitionCandidate(ctx context.Context, id string, from []string, to string) (*database.Candidate, error)
+}
+
+// RunStore keeps batch generation runs (cmd/worker) and their per-preset
+// progress.
+type RunStore interface {
+	GetRun(ctx context.Context, id string) (*database.Run, error)
+	SaveRun(ctx context.Context, run database.Run) error
+	SetRunItem(ctx context.Context, runID string, item database.RunItem) error
+	ListRunItems(ctx context.Context, runID string) ([]database.RunItem, error)
+}
+
+// PresetWatcher streams preset changes. Only the Firestore store implements it
+// (with snapshot listeners); callers should type-assert a Repository.
+type PresetWatcher interface {
+	WatchPresets(ctx context.Context, fn func([]database.PresetChange) error) error
+}
+
+// Repository is the full persistence layer shared by the API server, CLI and jobs.
+// Code that only needs part of it should accept the narrower interface.
+type Repository interface {
+	LocationStore
+	ModerationStore
+	SettingsStore
+	AuditStore
+	HistoryStore
+	OperationStore
+	LatencyStore
+	TraceStore
+	PromptCacheStore
+	FeaturedStore
+	WalletStore
+	CandidateStore
+	RunStore
+	Close() error
+}
+
+// Firestore is the default implementation; Postgres is for deployments outside GCP.
+var (
+	_ Repository = (*database.Client)(nil)
+	_ Repository = (*postgres.Client)(nil)
+
+	_ PresetWatcher = (*database.Client)(nil)
+	_ PresetWatcher = (*Shadow)(nil)
+)
+
+// Open connects to the store selected by cfg.DBBackend.
+func Open(ctx context.Context, cfg *config.Config) (Repository, error) {
+	switch cfg.DBBackend {
+	case "postgres":
+		db, err := postgres.NewClient(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open postgres repository: %w", err)
+		}
+		return db, nil
+	case "", "firestore":
+		db, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open firestore repository: %w", err)
+		}
+		if cfg.LocationsCollection != "" {
+			db.SetLocationsCollection(cfg.LocationsCollection)
+		}
+		ttl := locationTTL(cfg)
+		db.SetLocationTTL(ttl)
+		if cfg.ShadowCollection == "" {
+			return db, nil
+		}
+		shadow, err := database.NewClient(ctx, cfg.ProjectID, cfg.DatabaseID)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open shadow repository: %w", err)
+		}
+		shadow.SetLocationsCollection(cfg.ShadowCollection)
+		shadow.SetLocationTTL(ttl)
+		log.Printf("Shadow mode: mirroring %s into %s", db.LocationsCollection(), cfg.ShadowCollection)
+		return NewShadow(db, shadow), nil
+	default:
+		return nil, fmt.Errorf("unknown DB_BACKEND %q", cfg.DBBackend)
+	}
+}
+
+// locationTTL is the expiry of user locations under RETENTION_POLICY, see
+// jobs.LocationTTL. An invalid policy sets none; banana admin retention run
+// reports it.
+func locationTTL(cfg *config.Config) time.Duration {
+	rules, err := jobs.ParseRetentionPolicy(cfg.RetentionPolicy)
+	if err != nil {
+		log.Printf("Warning: user locations won't expire, invalid RETENTION_POLICY: %v", err)
+		return 0
+	}
+	return jobs.LocationTTL(rules)
+}
@@ -0,0 +1,306 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"banana-weather/internal/database"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ShadowStore is the new side of a migration. PutLocation writes a location
+// as given, so copies keep their last_updated.
+type ShadowStore interface {
+	LocationStore
+	PutLocation(ctx context.Context, loc database.Location) error
+	Close() error
+}
+
+var _ ShadowStore = (*database.Client)(nil)
+
+// ShadowDiff is a location that differs between the primary and the shadow.
+// Fields are the JSON names of the differing fields; a location on one side
+// only has Missing (not in the shadow) or Extra (not in the primary) set.
+type ShadowDiff struct {
+	ID      string   `json:"id"`
+	Fields  []string `json:"fields,omitempty"`
+	Missing bool     `json:"missing,omitempty"`
+	Extra   bool     `json:"extra,omitempty"`
+}
+
+// Shadow serves a Repository while mirroring its locations into a second
+// store, so a locations collection can be migrated (to a new name, or
+// rewritten with the current schema) without downtime. After every location
+// write, including feedback and reports, the primary's document is copied to
+// the shadow, or deleted there when it's gone; the shadow never fails a
+// request, its errors are logged. GetLocation, ListLocations and GetPresets
+// also read the shadow, concurrently, and report differences to OnDiff.
+type Shadow struct {
+	Repository
+	shadow ShadowStore
+
+	// OnDiff receives the differences found by a read (logged when nil).
+	OnDiff func(op string, diffs []ShadowDiff)
+}
+
+// NewShadow mirrors the locations of primary into shadow.
+func NewShadow(primary Repository, shadow ShadowStore) *Shadow {
+	return &Shadow{Repository: primary, shadow: shadow}
+}
+
+// WatchPresets streams the primary's presets, when it can.
+func (s *Shadow) WatchPresets(ctx context.Context, fn func([]database.PresetChange) error) error {
+	w, ok := s.Repository.(PresetWatcher)
+	if !ok {
+		return errors.New("the primary store can't stream presets")
+	}
+	return w.WatchPresets(ctx, fn)
+}
+
+// Close closes both stores.
+func (s *Shadow) Close() error {
+	return errors.Join(s.Repository.Close(), s.shadow.Close())
+}
+
+// -- Reads --
+
+func (s *Shadow) GetLocation(ctx context.Context, id string) (*database.Location, error) {
+	shadowed := s.read(func() ([]database.Location, error) {
+		l, err := s.shadow.GetLocation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return []database.Location{*l}, nil
+	})
+	loc, err := s.Repository.GetLocation(ctx, id)
+	switch {
+	case err == nil:
+		s.compare("GetLocation", []database.Location{*loc}, shadowed)
+	case isNotFound(err):
+		s.compare("GetLocation", nil, shadowed)
+	}
+	return loc, err
+}
+
+func (s *Shadow) ListLocations(ctx context.Context, opts database.ListOptions) ([]database.Location, error) {
+	shadowed := s.read(func() ([]database.Location, error) { return s.shadow.ListLocations(ctx, opts) })
+	locs, err := s.Repository.ListLocations(ctx, opts)
+	if err == nil {
+		s.compare("ListLocations", locs, shadowed)
+	}
+	return locs, err
+}
+
+func (s *Shadow) GetPresets(ctx context.Context) ([]database.Location, error) {
+	shadowed := s.read(func() ([]database.Location, error) { return s.shadow.GetPresets(ctx) })
+	locs, err := s.Repository.GetPresets(ctx)
+	if err == nil {
+		s.compare("GetPresets", locs, shadowed)
+	}
+	return locs, err
+}
+
+// shadowRead is the result of a read from the shadow.
+type shadowRead struct {
+	locs []database.Location
+	err  error
+}
+
+// read starts fn (a read from the shadow) and returns where its result will
+// be sent.
+func (s *Shadow) read(fn func() ([]database.Location, error)) <-chan shadowRead {
+	ch := make(chan shadowRead, 1)
+	go func() {
+		locs, err := fn()
+		ch <- shadowRead{locs, err}
+	}()
+	return ch
+}
+
+// compare waits for the shadow's side of op and reports how it differs from
+// the primary's locs. A location the shadow doesn't have is a difference.
+func (s *Shadow) compare(op string, locs []database.Location, shadowed <-chan shadowRead) {
+	r := <-shadowed
+	if r.err != nil && !isNotFound(r.err) {
+		log.Printf("Shadow %s failed: %v", op, r.err)
+		return
+	}
+	diffs := CompareLocations(locs, r.locs)
+	if len(diffs) == 0 {
+		return
+	}
+	if s.OnDiff != nil {
+		s.OnDiff(op, diffs)
+		return
+	}
+	for _, d := range diffs {
+		log.Printf("Shadow %s differs: %s", op, d)
+	}
+}
+
+func (d ShadowDiff) String() string {
+	switch {
+	case d.Missing:
+		return d.ID + " is missing from the shadow"
+	case d.Extra:
+		return d.ID + " is only in the shadow"
+	}
+	return d.ID + ": " + strings.Join(d.Fields, ", ")
+}
+
+// -- Writes --
+
+func (s *Shadow) UpsertLocation(ctx context.Context, loc database.Location) error {
+	if err := s.Repository.UpsertLocation(ctx, loc); err != nil {
+		return err
+	}
+	s.mirror(ctx, loc.ID)
+	return nil
+}
+
+func (s *Shadow) DeleteLocation(ctx context.Context, id string) error {
+	if err := s.Repository.DeleteLocation(ctx, id); err != nil {
+		return err
+	}
+	s.mirror(ctx, id)
+	return nil
+}
+
+func (s *Shadow) SetStatus(ctx context.Context, id string, st database.LocationStatus) error {
+	return s.mirrorAfter(ctx, s.Repository.SetStatus(ctx, id, st), id)
+}
+
+func (s *Shadow) SetGeo(ctx context.Context, id string, geo *latlng.LatLng, countryCode, continent string) error {
+	return s.mirrorAfter(ctx, s.Repository.SetGeo(ctx, id, geo, countryCode, continent), id)
+}
+
+func (s *Shadow) SetNameI18n(ctx context.Context, id string, names map[string]string) error {
+	return s.mirrorAfter(ctx, s.Repository.SetNameI18n(ctx, id, names), id)
+}
+
+func (s *Shadow) SetChecksums(ctx context.Context, id string, sums map[string]string) error {
+	return s.mirrorAfter(ctx, s.Repository.SetChecksums(ctx, id, sums), id)
+}
+
+func (s *Shadow) UpdateLocations(ctx context.Context, edits []database.LocationEdit) error {
+	if err := s.Repository.UpdateLocations(ctx, edits); err != nil {
+		return err
+	}
+	for _, e := range edits {
+		s.mirror(ctx, e.ID)
+	}
+	return nil
+}
+
+func (s *Shadow) AddFeedback(ctx context.Context, id string, fb database.Feedback) error {
+	return s.mirrorAfter(ctx, s.Repository.AddFeedback(ctx, id, fb), id)
+}
+
+func (s *Shadow) AddReport(ctx context.Context, id string, r database.Report, threshold int) (bool, error) {
+	hidden, err := s.Repository.AddReport(ctx, id, r, threshold)
+	return hidden, s.mirrorAfter(ctx, err, id)
+}
+
+func (s *Shadow) ResolveReview(ctx context.Context, id string) error {
+	return s.mirrorAfter(ctx, s.Repository.ResolveReview(ctx, id), id)
+}
+
+// mirrorAfter mirrors id when the primary's write succeeded, and returns
+// the write's error.
+func (s *Shadow) mirrorAfter(ctx context.Context, err error, id string) error {
+	if err == nil {
+		s.mirror(ctx, id)
+	}
+	return err
+}
+
+// mirror copies location id from the primary to the shadow, or deletes it
+// there when the primary no longer has it. Reading the document back,
+// rather than repeating the write, keeps partial updates exact even when
+// the shadow hasn't been backfilled yet.
+func (s *Shadow) mirror(ctx context.Context, id string) {
+	loc, err := s.Repository.GetLocation(ctx, id)
+	switch {
+	case isNotFound(err):
+		err = s.shadow.DeleteLocation(ctx, id)
+	case err == nil:
+		err = s.shadow.PutLocation(ctx, *loc)
+	}
+	if err != nil {
+		log.Printf("Shadow: failed to mirror %s: %v", id, err)
+	}
+}
+
+// CompareLocations matches primary and shadow locations by ID and returns
+// how they differ, sorted by ID.
+func CompareLocations(primary, shadow []database.Location) []ShadowDiff {
+	byID := make(map[string]database.Location, len(shadow))
+	for _, l := range shadow {
+		byID[l.ID] = l
+	}
+
+	var diffs []ShadowDiff
+	for _, p := range primary {
+		sh, ok := byID[p.ID]
+		if !ok {
+			diffs = append(diffs, ShadowDiff{ID: p.ID, Missing: true})
+			continue
+		}
+		delete(byID, p.ID)
+		if fields := diffFields(p, sh); len(fields) > 0 {
+			diffs = append(diffs, ShadowDiff{ID: p.ID, Fields: fields})
+		}
+	}
+	for id := range byID {
+		diffs = append(diffs, ShadowDiff{ID: id, Extra: true})
+	}
+
+	slices.SortFunc(diffs, func(a, b ShadowDiff) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return diffs
+}
+
+// diffFields returns the JSON names of the fields that differ between a and
+// b, sorted. Comparing the JSON form treats nil and empty the same way the
+// API does.
+func diffFields(a, b database.Location) []string {
+	ma, mb := jsonFields(a), jsonFields(b)
+	var fields []string
+	for k, v := range ma {
+		if !reflect.DeepEqual(v, mb[k]) {
+			fields = append(fields, k)
+		}
+	}
+	for k := range mb {
+		if _, ok := ma[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func jsonFields(l database.Location) map[string]any {
+	m := map[string]any{}
+	data, err := json.Marshal(l)
+	if err == nil {
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		log.Printf("Shadow: failed to compare %s: %v", l.ID, err)
+	}
+	return m
+}
+
+func isNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
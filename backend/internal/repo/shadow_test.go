@@ -0,0 +1,100 @@
+package repo_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/mock"
+	"banana-weather/internal/repo"
+)
+
+func TestShadow_MirrorsWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, shadowDB := mock.NewDB(), mock.NewDB()
+	s := repo.NewShadow(primary, shadowDB)
+	s.OnDiff = func(op string, diffs []repo.ShadowDiff) {}
+
+	if err := s.UpsertLocation(ctx, database.Location{ID: "paris", Name: "Paris", IsPreset: true}); err != nil {
+		t.Fatal(err)
+	}
+	// Not backfilled yet: partial updates still reach the shadow whole
+	primary.PutLocation(ctx, database.Location{ID: "tokyo", Name: "Tokyo", LastUpdated: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)})
+	if err := s.SetStatus(ctx, "tokyo", database.StatusHidden); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddFeedback(ctx, "paris", database.Feedback{Up: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"paris", "tokyo"} {
+		want, _ := primary.GetLocation(ctx, id)
+		got, err := shadowDB.GetLocation(ctx, id)
+		if err != nil {
+			t.Fatalf("%s wasn't mirrored: %v", id, err)
+		}
+		if diffs := repo.CompareLocations([]database.Location{*want}, []database.Location{*got}); len(diffs) > 0 {
+			t.Errorf("Mirror of %s differs: %v", id, diffs)
+		}
+	}
+
+	if err := s.DeleteLocation(ctx, "tokyo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shadowDB.GetLocation(ctx, "tokyo"); err == nil {
+		t.Error("Expected the delete mirrored")
+	}
+
+	// A failed primary write leaves the shadow alone
+	if err := s.AddFeedback(ctx, "nowhere", database.Feedback{Up: true}); err == nil {
+		t.Error("Expected the primary's error")
+	}
+	if _, err := shadowDB.GetLocation(ctx, "nowhere"); err == nil {
+		t.Error("Expected nothing mirrored for a failed write")
+	}
+}
+
+func TestShadow_ComparesReads(t *testing.T) {
+	ctx := context.Background()
+	primary, shadowDB := mock.NewDB(), mock.NewDB()
+	updated := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	primary.PutLocation(ctx, database.Location{ID: "paris", Name: "Paris", IsPreset: true, LastUpdated: updated})
+	primary.PutLocation(ctx, database.Location{ID: "tokyo", Name: "Tokyo", IsPreset: true, LastUpdated: updated})
+	shadowDB.PutLocation(ctx, database.Location{ID: "paris", Name: "Paris", IsPreset: true, LastUpdated: updated})
+	shadowDB.PutLocation(ctx, database.Location{ID: "oslo", Name: "Oslo", IsPreset: true, Mood: "snow", LastUpdated: updated})
+
+	s := repo.NewShadow(primary, shadowDB)
+	got := map[string][]repo.ShadowDiff{}
+	s.OnDiff = func(op string, diffs []repo.ShadowDiff) { got[op] = diffs }
+
+	if loc, err := s.GetLocation(ctx, "paris"); err != nil || loc.Name != "Paris" {
+		t.Fatalf("GetLocation: %v, %v", loc, err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected identical locations to match, got %v", got)
+	}
+
+	locs, err := s.GetPresets(ctx)
+	if err != nil || len(locs) != 2 {
+		t.Fatalf("Expected the primary's presets, got %d, %v", len(locs), err)
+	}
+	want := []repo.ShadowDiff{{ID: "oslo", Extra: true}, {ID: "tokyo", Missing: true}}
+	if !slices.EqualFunc(got["GetPresets"], want, func(a, b repo.ShadowDiff) bool { return a.String() == b.String() }) {
+		t.Errorf("GetPresets diffs = %v, want %v", got["GetPresets"], want)
+	}
+
+	if _, err := s.GetLocation(ctx, "oslo"); err == nil {
+		t.Error("Expected the primary's not found")
+	}
+	if d := got["GetLocation"]; len(d) != 1 || !d[0].Extra {
+		t.Errorf("Expected oslo reported as shadow-only, got %v", d)
+	}
+
+	shadowDB.PutLocation(ctx, database.Location{ID: "tokyo", Name: "Tokio", IsPreset: true, Mood: "rain", LastUpdated: updated})
+	s.GetLocation(ctx, "tokyo")
+	if d := got["GetLocation"]; len(d) != 1 || !slices.Equal(d[0].Fields, []string{"mood", "name"}) {
+		t.Errorf("Expected mood and name to differ, got %v", d)
+	}
+}
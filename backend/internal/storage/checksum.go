@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// CRC32C returns the CRC32C checksum of data as GCS reports it in object
+// metadata (base64 of the big-endian value), for Location.Checksums.
+func CRC32C(data []byte) string {
+	return formatCRC32C(crc32.Checksum(data, castagnoli))
+}
+
+// ImageCRC32C returns the CRC32C checksum (see CRC32C) of a base64 image as
+// UploadImage stores it, or "" when it isn't valid base64.
+func ImageCRC32C(imageBase64 string) string {
+	sum, err := base64CRC32C(imageBase64)
+	if err != nil {
+		return ""
+	}
+	return formatCRC32C(sum)
+}
+
+// base64CRC32C decodes data into the checksum without holding the decoded
+// image in memory.
+func base64CRC32C(data string) (uint32, error) {
+	h := crc32.New(castagnoli)
+	if _, err := io.Copy(h, base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+func formatCRC32C(sum uint32) string {
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, sum))
+}
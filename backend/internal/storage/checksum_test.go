@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestCRC32C(t *testing.T) {
+	// The CRC-32C check value, in the big-endian base64 form GCS reports
+	if got := CRC32C([]byte("123456789")); got != "4waSgw==" {
+		t.Errorf("CRC32C = %q, want 4waSgw==", got)
+	}
+	if got := ImageCRC32C(base64.StdEncoding.EncodeToString([]byte("123456789"))); got != "4waSgw==" {
+		t.Errorf("ImageCRC32C = %q, want 4waSgw==", got)
+	}
+	if got := ImageCRC32C("not base64!"); got != "" {
+		t.Errorf("Expected no checksum for invalid base64, got %q", got)
+	}
+}
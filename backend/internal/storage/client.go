@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+type Service struct {
+	client     *storage.Client
+	bucketName string
+	projectID  string
+}
+
+// shared is the GCS client of every Service once SetHTTPClient was called,
+// so all buckets share one connection pool instead of one each.
+var shared struct {
+	sync.Mutex
+	hc     *http.Client
+	client *storage.Client
+}
+
+// SetHTTPClient makes Services created afterwards share one GCS client that
+// sends requests through hc, which must add credentials itself (see
+// httppool.Pool.GoogleClient). URLs are still signed with Application
+// Default Credentials.
+func SetHTTPClient(hc *http.Client) {
+	shared.Lock()
+	defer shared.Unlock()
+	shared.hc, shared.client = hc, nil
+}
+
+func newClient(ctx context.Context) (*storage.Client, error) {
+	shared.Lock()
+	defer shared.Unlock()
+	if shared.hc == nil {
+		return storage.NewClient(ctx)
+	}
+	if shared.client == nil {
+		c, err := storage.NewClient(ctx, option.WithHTTPClient(shared.hc))
+		if err != nil {
+			return nil, err
+		}
+		shared.client = c
+	}
+	return shared.client, nil
+}
+
+func NewService(ctx context.Context, bucketName string) (*Service, error) {
+	if bucketName == "" {
+		return nil, fmt.Errorf("GENMEDIA_BUCKET is empty")
+	}
+
+	client, err := newClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &Service{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+// ReadObject reads the content of a file from GCS.
+func (s *Service) ReadObject(ctx context.Context, fileName string) ([]byte, error) {
+	bucket := s.client.Bucket(s.bucketName)
+	obj := bucket.Object(fileName)
+	
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// DeleteObject removes a file from the bucket. Missing objects are not an error.
+func (s *Service) DeleteObject(ctx context.Context, fileName string) error {
+	err := s.client.Bucket(s.bucketName).Object(fileName).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+// StorageClass returns the object's storage class, e.g. "STANDARD".
+func (s *Service) StorageClass(ctx context.Context, fileName string) (string, error) {
+	attrs, err := s.client.Bucket(s.bucketName).Object(fileName).Attrs(ctx)
+	if err != nil {
+		return "", err
+	}
+	return attrs.StorageClass, nil
+}
+
+// SetStorageClass rewrites the object in place with a new storage class
+// (e.g. "COLDLINE"). Its content type, caching and custom metadata are kept;
+// its URL doesn't change.
+func (s *Service) SetStorageClass(ctx context.Context, fileName, class string) error {
+	obj := s.client.Bucket(s.bucketName).Object(fileName)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	c := obj.CopierFrom(obj)
+	c.StorageClass = class
+	c.ContentType = attrs.ContentType
+	c.CacheControl = attrs.CacheControl
+	c.Metadata = attrs.Metadata
+	_, err = c.Run(ctx)
+	return err
+}
+
+// Stat returns the object's attributes.
+func (s *Service) Stat(ctx context.Context, fileName string) (*ObjectInfo, error) {
+	attrs, err := s.client.Bucket(s.bucketName).Object(fileName).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%s: %w", fileName, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objectInfo(attrs), nil
+}
+
+func objectInfo(attrs *storage.ObjectAttrs) *ObjectInfo {
+	return &ObjectInfo{
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		ETag:        attrs.Etag,
+		CRC32C:      formatCRC32C(attrs.CRC32C),
+		Updated:     attrs.Updated,
+	}
+}
+
+// StatURI returns the attributes of the object at a gs:// URI, in this or
+// any other bucket the service account can read.
+func (s *Service) StatURI(ctx context.Context, uri string) (*ObjectInfo, error) {
+	bucket, object, err := ParseGSURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.client.Bucket(bucket).Object(object).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%s: %w", uri, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objectInfo(attrs), nil
+}
+
+// ReadURI reads the content of the object at a gs:// URI, in this or any
+// other bucket the service account can read.
+func (s *Service) ReadURI(ctx context.Context, uri string) ([]byte, error) {
+	bucket, object, err := ParseGSURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.client.Bucket(bucket).Object(object).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%s: %w", uri, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CopyFrom copies the object at a gs:// URI into this bucket as fileName
+// with a server-side rewrite, so large videos aren't downloaded. It returns
+// (gsURI, publicURL) of the copy.
+func (s *Service) CopyFrom(ctx context.Context, uri, fileName string) (string, string, error) {
+	bucket, object, err := ParseGSURI(uri)
+	if err != nil {
+		return "", "", err
+	}
+	dst := s.client.Bucket(s.bucketName).Object(fileName)
+	if _, err := dst.CopierFrom(s.client.Bucket(bucket).Object(object)).Run(ctx); err != nil {
+		return "", "", fmt.Errorf("failed to copy %s: %w", uri, err)
+	}
+	gsURI := fmt.Sprintf("gs://%s/%s", s.bucketName, fileName)
+	log.Printf("Copied %s to %s", uri, gsURI)
+	return gsURI, publicURL(s.bucketName, fileName), nil
+}
+
+// NewRangeReader reads part of an object; a negative length reads to the end.
+func (s *Service) NewRangeReader(ctx context.Context, fileName string, offset, length int64) (io.ReadCloser, error) {
+	return s.client.Bucket(s.bucketName).Object(fileName).NewRangeReader(ctx, offset, length)
+}
+
+// ObjectName returns the object path for a public or gs:// URL in this bucket,
+// or "" if the URL points somewhere else.
+func (s *Service) ObjectName(url string) string {
+	for _, prefix := range []string{
+		publicURL(s.bucketName, ""),
+		fmt.Sprintf("gs://%s/", s.bucketName),
+	} {
+		if strings.HasPrefix(url, prefix) {
+			return strings.TrimPrefix(url, prefix)
+		}
+	}
+	return ""
+}
+
+// SignedUploadURL returns a V4 signed PUT URL for fileName. The content type
+// is part of the signature, and GCS rejects bodies over maxBytes through the
+// x-goog-content-length-range header, which the client must send as given.
+func (s *Service) SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*SignedUpload, error) {
+	lengthRange := fmt.Sprintf("0,%d", maxBytes)
+	expires := time.Now().Add(expiry)
+	u, err := s.client.Bucket(s.bucketName).SignedURL(fileName, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPut,
+		ContentType: contentType,
+		Headers:     []string{"x-goog-content-length-range:" + lengthRange},
+		Expires:     expires,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload for %s: %w", fileName, err)
+	}
+	return &SignedUpload{
+		URL:    u,
+		Method: http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":                contentType,
+			"x-goog-content-length-range": lengthRange,
+		},
+		ExpiresAt: expires,
+	}, nil
+}
+
+// SignedURL returns a V4 signed GET URL for fileName.
+func (s *Service) SignedURL(ctx context.Context, fileName string, expiry time.Duration) (string, error) {
+	u, err := s.client.Bucket(s.bucketName).SignedURL(fileName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", fileName, err)
+	}
+	return u, nil
+}
+
+// UploadImage streams a base64 image to GCS and returns (gsURI, publicURL).
+// gsURI is what Veo reads; publicURL is what the frontend shows. The image's
+// CRC32C is sent along, so GCS rejects an upload corrupted on the way.
+func (s *Service) UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error) {
+	sum, err := base64CRC32C(imageBase64)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base64: %w", err)
+	}
+	// Cancelling the writer's context abandons a partial upload
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := s.client.Bucket(s.bucketName).Object(fileName).NewWriter(wctx)
+	w.ContentType = "image/png"
+	w.CRC32C, w.SendCRC32C = sum, true
+	if _, err := decodeBase64To(w, imageBase64); err != nil {
+		cancel()
+		return "", "", fmt.Errorf("failed to write to bucket: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	gsURI := fmt.Sprintf("gs://%s/%s", s.bucketName, fileName)
+	log.Printf("Uploaded %s to %s", fileName, gsURI)
+	return gsURI, publicURL(s.bucketName, fileName), nil
+}
+
+// decodeBase64To decodes into w without holding the decoded image in memory.
+func decodeBase64To(w io.Writer, data string) (int64, error) {
+	return io.Copy(w, base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+}
+
+// UploadBytes uploads raw bytes to GCS and returns the public URL. Like
+// UploadImage, it has GCS verify the CRC32C.
+func (s *Service) UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error) {
+	bucket := s.client.Bucket(s.bucketName)
+	obj := bucket.Object(fileName)
+	
+	w := obj.NewWriter(ctx)
+	w.ContentType = mimeType
+	w.CRC32C, w.SendCRC32C = crc32.Checksum(data, castagnoli), true
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write to bucket: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	u := publicURL(s.bucketName, fileName)
+	log.Printf("Uploaded %d bytes to %s", len(data), u)
+	return u, nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+)
+
+// A generated 9:16 PNG is typically 1-2 MB.
+func sampleImage(tb testing.TB) ([]byte, string) {
+	data := make([]byte, 1536*1024)
+	if _, err := rand.Read(data); err != nil {
+		tb.Fatal(err)
+	}
+	return data, base64.StdEncoding.EncodeToString(data)
+}
+
+func TestDecodeBase64To(t *testing.T) {
+	data, encoded := sampleImage(t)
+
+	var buf bytes.Buffer
+	n, err := decodeBase64To(&buf, encoded)
+	if err != nil {
+		t.Fatalf("decodeBase64To failed: %v", err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Error("Decoded bytes don't match the original")
+	}
+
+	if _, err := decodeBase64To(io.Discard, "not base64!"); err == nil {
+		t.Error("Expected error for invalid base64, got nil")
+	}
+}
+
+func BenchmarkDecodeBase64_Buffered(b *testing.B) {
+	_, encoded := sampleImage(b)
+	b.SetBytes(int64(len(encoded)))
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Discard.Write(data)
+	}
+}
+
+func BenchmarkDecodeBase64_Streamed(b *testing.B) {
+	_, encoded := sampleImage(b)
+	b.SetBytes(int64(len(encoded)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := decodeBase64To(io.Discard, encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Object reads a stored object through range requests. It's an io.ReadSeeker
+// for http.ServeContent, which seeks to the requested range and reads only
+// that, so a player can stream a video without the server downloading it
+// whole.
+type Object struct {
+	ObjectInfo
+
+	ctx  context.Context
+	r    RangeReader
+	name string
+	off  int64
+	body io.ReadCloser // Open from off to the end; nil until the next Read
+}
+
+// OpenObject stats fileName in s. It fails when s can't read ranges, and with
+// an error wrapping ErrNotFound when the object doesn't exist.
+func OpenObject(ctx context.Context, s Store, fileName string) (*Object, error) {
+	r := AsRangeReader(s)
+	if r == nil {
+		return nil, fmt.Errorf("storage backend can't read ranges")
+	}
+	info, err := r.Stat(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{ObjectInfo: *info, ctx: ctx, r: r, name: fileName}, nil
+}
+
+func (o *Object) Read(p []byte) (int, error) {
+	if o.off >= o.Size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		body, err := o.r.NewRangeReader(o.ctx, o.name, o.off, -1)
+		if err != nil {
+			return 0, err
+		}
+		o.body = body
+	}
+	n, err := o.body.Read(p)
+	o.off += int64(n)
+	return n, err
+}
+
+// Seek moves the read position; the next Read starts a new range request
+// there.
+func (o *Object) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = o.off + offset
+	case io.SeekEnd:
+		pos = o.Size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	if pos != o.off && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.off = pos
+	return pos, nil
+}
+
+// Close releases the current range request, if any.
+func (o *Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
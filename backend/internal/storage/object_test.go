@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memStore is a Store and RangeReader over in-memory objects, recording the
+// ranges read.
+type memStore struct {
+	Store
+	objects map[string]string
+	ranges  []string
+}
+
+func (m *memStore) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	data, ok := m.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return &ObjectInfo{Size: int64(len(data)), ContentType: "video/mp4", ETag: "e1", Updated: time.Unix(0, 0)}, nil
+}
+
+func (m *memStore) NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	m.ranges = append(m.ranges, fmt.Sprintf("%s@%d", name, offset))
+	return io.NopCloser(strings.NewReader(m.objects[name][offset:])), nil
+}
+
+func TestObject_ServeContent(t *testing.T) {
+	store := &memStore{objects: map[string]string{"v.mp4": "0123456789"}}
+	obj, err := OpenObject(context.Background(), store, "v.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/v.mp4", nil)
+	req.Header.Set("Range", "bytes=4-6")
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", obj.ContentType) // Otherwise ServeContent sniffs the first bytes
+	http.ServeContent(rec, req, "", obj.Updated, obj)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "456" {
+		t.Errorf("Range response: %d %q", rec.Code, rec.Body.String())
+	}
+	if len(store.ranges) != 1 || store.ranges[0] != "v.mp4@4" {
+		t.Errorf("Expected one read from offset 4, got %v", store.ranges)
+	}
+}
+
+func TestOpenObject_Prefixed(t *testing.T) {
+	inner := &memStore{objects: map[string]string{"videos/v.mp4": "abc"}}
+	obj, err := OpenObject(context.Background(), &prefixed{Store: inner, prefix: "videos/"}, "v.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(obj)
+	if string(b) != "abc" {
+		t.Errorf("ReadAll() = %q", b)
+	}
+
+	if _, err := OpenObject(context.Background(), inner, "missing.mp4"); err == nil || !strings.Contains(err.Error(), ErrNotFound.Error()) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"banana-weather/internal/config"
+)
+
+// Kind is a class of media with its own bucket/prefix (see Routes).
+type Kind string
+
+const (
+	KindImage     Kind = "image"     // Generated images (GENMEDIA_BUCKET)
+	KindVideo     Kind = "video"     // Veo output (VIDEO_BUCKET)
+	KindThumbnail Kind = "thumbnail" // Derived thumbnails (THUMBNAILS_BUCKET)
+	KindOriginal  Kind = "original"  // Images before watermark/AI badge (ORIGINALS_BUCKET)
+	KindUpload    Kind = "upload"    // User reference photos (UPLOADS_BUCKET)
+	KindExport    Kind = "export"    // Exports (EXPORTS_BUCKET)
+)
+
+// Kinds lists every media kind in display order.
+var Kinds = []Kind{KindImage, KindVideo, KindThumbnail, KindOriginal, KindUpload, KindExport}
+
+// Public reports whether media of this kind is served to browsers, i.e. may
+// live in a public (CDN) bucket.
+func (k Kind) Public() bool {
+	return k == KindImage || k == KindVideo || k == KindThumbnail
+}
+
+// Route is where one kind of media is stored: a bucket and an optional
+// object prefix ending in "/".
+type Route struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ParseRoute parses "bucket" or "bucket/prefix".
+func ParseRoute(s string) Route {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(s, "gs://"), "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return Route{Bucket: bucket, Prefix: prefix}
+}
+
+// GSURI returns the gs:// URI of the route's prefix.
+func (r Route) GSURI() string {
+	return "gs://" + r.Bucket + "/" + r.Prefix
+}
+
+// Routes returns the configured route for each kind. Public kinds default to
+// GENMEDIA_BUCKET (videos under videos/, thumbnails under thumbnails/).
+// Private kinds have no default, so they're never written to the public
+// bucket by accident; a missing kind means the feature is off.
+func Routes(cfg *config.Config) map[Kind]Route {
+	routeOr := func(s string, def Route) Route {
+		if s == "" {
+			return def
+		}
+		return ParseRoute(s)
+	}
+	routes := map[Kind]Route{
+		KindImage:     {Bucket: cfg.BucketName},
+		KindVideo:     routeOr(cfg.VideoBucket, Route{Bucket: cfg.BucketName, Prefix: "videos/"}),
+		KindThumbnail: routeOr(cfg.ThumbnailsBucket, Route{Bucket: cfg.BucketName, Prefix: "thumbnails/"}),
+	}
+	for kind, s := range map[Kind]string{
+		KindOriginal: cfg.OriginalsBucket,
+		KindUpload:   cfg.UploadsBucket,
+		KindExport:   cfg.ExportsBucket,
+	} {
+		if s != "" {
+			routes[kind] = ParseRoute(s)
+		}
+	}
+	return routes
+}
+
+// OpenKind opens the store for one kind of media. Object names passed to the
+// returned store are relative to the route's prefix. It returns nil when the
+// kind has no route.
+func OpenKind(ctx context.Context, cfg *config.Config, kind Kind) (Store, error) {
+	route, ok := Routes(cfg)[kind]
+	if !ok {
+		return nil, nil
+	}
+	c := *cfg
+	c.BucketName = route.Bucket
+	s, err := Open(ctx, &c)
+	if err != nil {
+		return nil, fmt.Errorf("%s bucket %s: %w", kind, route.Bucket, err)
+	}
+	if route.Prefix == "" {
+		return s, nil
+	}
+	return &prefixed{Store: s, prefix: route.Prefix}, nil
+}
+
+// prefixed stores objects under a fixed prefix of another Store.
+type prefixed struct {
+	Store
+	prefix string
+}
+
+func (p *prefixed) UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error) {
+	return p.Store.UploadImage(ctx, imageBase64, p.prefix+fileName)
+}
+
+func (p *prefixed) UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error) {
+	return p.Store.UploadBytes(ctx, data, p.prefix+fileName, mimeType)
+}
+
+func (p *prefixed) ReadObject(ctx context.Context, fileName string) ([]byte, error) {
+	return p.Store.ReadObject(ctx, p.prefix+fileName)
+}
+
+func (p *prefixed) DeleteObject(ctx context.Context, fileName string) error {
+	return p.Store.DeleteObject(ctx, p.prefix+fileName)
+}
+
+func (p *prefixed) SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*SignedUpload, error) {
+	return p.Store.SignedUploadURL(ctx, p.prefix+fileName, contentType, maxBytes, expiry)
+}
+
+// ObjectName returns the name relative to the prefix, or "" for URLs outside it.
+func (p *prefixed) ObjectName(url string) string {
+	name, ok := strings.CutPrefix(p.Store.ObjectName(url), p.prefix)
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+type prefixedArchiver struct {
+	a      Archiver
+	prefix string
+}
+
+func (p *prefixedArchiver) StorageClass(ctx context.Context, fileName string) (string, error) {
+	return p.a.StorageClass(ctx, p.prefix+fileName)
+}
+
+func (p *prefixedArchiver) SetStorageClass(ctx context.Context, fileName, class string) error {
+	return p.a.SetStorageClass(ctx, p.prefix+fileName, class)
+}
+
+type prefixedRangeReader struct {
+	r      RangeReader
+	prefix string
+}
+
+func (p *prefixedRangeReader) Stat(ctx context.Context, fileName string) (*ObjectInfo, error) {
+	return p.r.Stat(ctx, p.prefix+fileName)
+}
+
+func (p *prefixedRangeReader) NewRangeReader(ctx context.Context, fileName string, offset, length int64) (io.ReadCloser, error) {
+	return p.r.NewRangeReader(ctx, p.prefix+fileName, offset, length)
+}
+
+type prefixedCopier struct {
+	c      Copier
+	prefix string
+}
+
+func (p *prefixedCopier) StatURI(ctx context.Context, uri string) (*ObjectInfo, error) {
+	return p.c.StatURI(ctx, uri)
+}
+
+func (p *prefixedCopier) CopyFrom(ctx context.Context, uri, fileName string) (string, string, error) {
+	return p.c.CopyFrom(ctx, uri, p.prefix+fileName)
+}
+
+type prefixedURLSigner struct {
+	u      URLSigner
+	prefix string
+}
+
+func (p *prefixedURLSigner) SignedURL(ctx context.Context, fileName string, expiry time.Duration) (string, error) {
+	return p.u.SignedURL(ctx, p.prefix+fileName, expiry)
+}
+
+// -- Lifecycle plan --
+
+// Lifecycle is a GCS bucket lifecycle configuration, in the JSON format
+// accepted by `gcloud storage buckets update --lifecycle-file`.
+type Lifecycle struct {
+	Rule []LifecycleRule `json:"rule"`
+}
+
+type LifecycleRule struct {
+	Action struct {
+		Type         string `json:"type"`
+		StorageClass string `json:"storageClass,omitempty"`
+	} `json:"action"`
+	Condition struct {
+		Age           int      `json:"age"`
+		MatchesPrefix []string `json:"matchesPrefix,omitempty"`
+	} `json:"condition"`
+}
+
+// BucketPlan is the suggested setup of one bucket.
+type BucketPlan struct {
+	Bucket    string    `json:"bucket"`
+	Kinds     []Kind    `json:"kinds"`
+	Public    bool      `json:"public"` // Serves browser-facing media
+	Lifecycle Lifecycle `json:"lifecycle"`
+	Warnings  []string  `json:"warnings,omitempty"`
+}
+
+// objectPrefixes are prefixes the code itself puts object names under,
+// within a route.
+var objectPrefixes = map[Kind]string{KindUpload: "uploads/"}
+
+// lifecycleRules are the suggested rules per kind. Images and videos are
+// referenced by locations until purged, so they get none.
+func lifecycleRules(kind Kind) []LifecycleRule {
+	rule := func(action, class string, age int) LifecycleRule {
+		var r LifecycleRule
+		r.Action.Type = action
+		r.Action.StorageClass = class
+		r.Condition.Age = age
+		return r
+	}
+	switch kind {
+	case KindUpload:
+		// Used or rejected uploads are deleted right away; this catches abandoned ones
+		return []LifecycleRule{rule("Delete", "", 1)}
+	case KindOriginal:
+		return []LifecycleRule{rule("SetStorageClass", "COLDLINE", 30), rule("SetStorageClass", "ARCHIVE", 365)}
+	case KindExport:
+		return []LifecycleRule{rule("Delete", "", 30)}
+	case KindThumbnail:
+		return []LifecycleRule{rule("Delete", "", 90)} // Derived from images, so they can be rebuilt
+	}
+	return nil
+}
+
+// Plan groups routes by bucket and suggests lifecycle rules for each. Rules
+// are scoped to the kind's prefix; a kind without one that shares its bucket
+// gets no rule (it would apply to everything) and a warning instead.
+func Plan(routes map[Kind]Route) []BucketPlan {
+	byBucket := map[string]*BucketPlan{}
+	var order []string
+	for _, kind := range Kinds {
+		route, ok := routes[kind]
+		if !ok {
+			continue
+		}
+		bp := byBucket[route.Bucket]
+		if bp == nil {
+			bp = &BucketPlan{Bucket: route.Bucket, Lifecycle: Lifecycle{Rule: []LifecycleRule{}}}
+			byBucket[route.Bucket] = bp
+			order = append(order, route.Bucket)
+		}
+		bp.Kinds = append(bp.Kinds, kind)
+		bp.Public = bp.Public || kind.Public()
+	}
+
+	plans := make([]BucketPlan, 0, len(order))
+	for _, bucket := range order {
+		bp := byBucket[bucket]
+		for _, kind := range bp.Kinds {
+			if !kind.Public() && bp.Public {
+				bp.Warnings = append(bp.Warnings, fmt.Sprintf("%s media shares a bucket with public media; use a private bucket", kind))
+			}
+			rules := lifecycleRules(kind)
+			if len(rules) == 0 {
+				continue
+			}
+			prefix := routes[kind].Prefix + objectPrefixes[kind]
+			if prefix == "" && len(bp.Kinds) > 1 {
+				bp.Warnings = append(bp.Warnings, fmt.Sprintf("no lifecycle rule for %s media: it has no prefix of its own in this bucket", kind))
+				continue
+			}
+			for _, r := range rules {
+				if prefix != "" {
+					r.Condition.MatchesPrefix = []string{prefix}
+				}
+				bp.Lifecycle.Rule = append(bp.Lifecycle.Rule, r)
+			}
+		}
+		plans = append(plans, *bp)
+	}
+	return plans
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/config"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Route
+	}{
+		{"media", Route{Bucket: "media"}},
+		{"media/videos", Route{Bucket: "media", Prefix: "videos/"}},
+		{"gs://archive/originals/", Route{Bucket: "archive", Prefix: "originals/"}},
+	}
+	for _, tt := range tests {
+		if got := ParseRoute(tt.in); got != tt.want {
+			t.Errorf("ParseRoute(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	routes := Routes(&config.Config{BucketName: "media", OriginalsBucket: "archive/originals"})
+
+	if got := routes[KindVideo]; got != (Route{Bucket: "media", Prefix: "videos/"}) {
+		t.Errorf("video route = %+v", got)
+	}
+	if got := routes[KindOriginal]; got != (Route{Bucket: "archive", Prefix: "originals/"}) {
+		t.Errorf("original route = %+v", got)
+	}
+	// Private kinds must not fall back to the public bucket
+	for _, kind := range []Kind{KindUpload, KindExport} {
+		if r, ok := routes[kind]; ok {
+			t.Errorf("Expected no %s route, got %+v", kind, r)
+		}
+	}
+
+	if got := Routes(&config.Config{BucketName: "media", VideoBucket: "cdn-video"})[KindVideo]; got != (Route{Bucket: "cdn-video"}) {
+		t.Errorf("VIDEO_BUCKET route = %+v", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	plans := Plan(map[Kind]Route{
+		KindImage:     {Bucket: "media"},
+		KindThumbnail: {Bucket: "media", Prefix: "thumbnails/"},
+		KindUpload:    {Bucket: "media"},
+		KindOriginal:  {Bucket: "archive"},
+	})
+	if len(plans) != 2 || plans[0].Bucket != "media" || plans[1].Bucket != "archive" {
+		t.Fatalf("Unexpected plans %+v", plans)
+	}
+
+	media := plans[0]
+	if !media.Public || len(media.Warnings) != 1 || !strings.Contains(media.Warnings[0], "upload") {
+		t.Errorf("Expected a warning about uploads in the public bucket, got %+v", media)
+	}
+	prefixes := map[string]bool{}
+	for _, r := range media.Lifecycle.Rule {
+		if len(r.Condition.MatchesPrefix) != 1 {
+			t.Fatalf("Shared bucket rule without a prefix: %+v", r)
+		}
+		prefixes[r.Condition.MatchesPrefix[0]] = true
+	}
+	if !prefixes["thumbnails/"] || !prefixes["uploads/"] {
+		t.Errorf("Expected rules scoped to thumbnails/ and uploads/, got %v", prefixes)
+	}
+
+	// A bucket of its own gets bucket-wide rules
+	archive := plans[1]
+	if archive.Public || len(archive.Warnings) != 0 || len(archive.Lifecycle.Rule) != 2 || archive.Lifecycle.Rule[0].Condition.MatchesPrefix != nil {
+		t.Errorf("Unexpected originals plan %+v", archive)
+	}
+}
+
+type nameStore struct{ Store }
+
+func (nameStore) ObjectName(url string) string {
+	return strings.TrimPrefix(url, "https://storage.googleapis.com/media/")
+}
+
+func TestPrefixedObjectName(t *testing.T) {
+	p := &prefixed{Store: nameStore{}, prefix: "videos/"}
+	if got := p.ObjectName("https://storage.googleapis.com/media/videos/1/sample_0.mp4"); got != "1/sample_0.mp4" {
+		t.Errorf("ObjectName = %q", got)
+	}
+	// Outside the prefix, so not this store's object
+	if got := p.ObjectName("https://storage.googleapis.com/media/paris.png"); got != "" {
+		t.Errorf("ObjectName = %q, want empty", got)
+	}
+}
+
+func TestOpenKind_Unrouted(t *testing.T) {
+	s, err := OpenKind(context.Background(), &config.Config{BucketName: "media"}, KindExport)
+	if s != nil || err != nil {
+		t.Errorf("Expected no store for an unrouted kind, got %v, %v", s, err)
+	}
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"banana-weather/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// presignExpiry is the longest lifetime SigV4 allows. Presigned URLs are
+// stored on locations, so deployments that keep media longer than this
+// should set S3_PUBLIC_URL.
+const presignExpiry = 7 * 24 * time.Hour
+
+// S3Service stores media in an S3-compatible bucket (AWS S3, MinIO, R2).
+type S3Service struct {
+	client     *minio.Client
+	bucketName string
+	publicURL  string
+}
+
+func NewS3Service(ctx context.Context, bucketName string, cfg config.S3Config) (*S3Service, error) {
+	if bucketName == "" {
+		return nil, fmt.Errorf("GENMEDIA_BUCKET is empty")
+	}
+
+	var creds *credentials.Credentials
+	if cfg.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	} else {
+		creds = credentials.NewIAM("") // Instance or task role
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach s3 bucket %s: %w", bucketName, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("s3 bucket %s does not exist", bucketName)
+	}
+
+	return &S3Service{
+		client:     client,
+		bucketName: bucketName,
+		publicURL:  cfg.PublicURL,
+	}, nil
+}
+
+// ReadObject reads the content of a file from the bucket.
+func (s *S3Service) ReadObject(ctx context.Context, fileName string) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, s.bucketName, fileName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(obj)
+}
+
+// Stat returns the object's attributes.
+func (s *S3Service) Stat(ctx context.Context, fileName string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucketName, fileName, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return nil, fmt.Errorf("%s: %w", fileName, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Size: info.Size, ContentType: info.ContentType, ETag: info.ETag, CRC32C: info.ChecksumCRC32C, Updated: info.LastModified}, nil
+}
+
+// NewRangeReader reads part of an object; a negative length reads to the end.
+func (s *S3Service) NewRangeReader(ctx context.Context, fileName string, offset, length int64) (io.ReadCloser, error) {
+	var opts minio.GetObjectOptions
+	var err error
+	switch {
+	case length == 0:
+		return io.NopCloser(strings.NewReader("")), nil
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0) // bytes=offset-
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.client.GetObject(ctx, s.bucketName, fileName, opts)
+}
+
+// DeleteObject removes a file from the bucket. S3 doesn't report missing objects.
+func (s *S3Service) DeleteObject(ctx context.Context, fileName string) error {
+	return s.client.RemoveObject(ctx, s.bucketName, fileName, minio.RemoveObjectOptions{})
+}
+
+// ObjectName returns the object path for a public, presigned or s3:// URL in
+// this bucket, or "" if the URL points somewhere else.
+func (s *S3Service) ObjectName(u string) string {
+	prefix := fmt.Sprintf("s3://%s/", s.bucketName)
+	if strings.HasPrefix(u, prefix) {
+		return strings.TrimPrefix(u, prefix)
+	}
+	if s.publicURL != "" && strings.HasPrefix(u, s.publicURL+"/") {
+		return strings.TrimPrefix(u, s.publicURL+"/")
+	}
+
+	// Presigned URLs use path-style (endpoint/bucket/key) or
+	// virtual-hosted-style (bucket.endpoint/key) addressing.
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	endpoint := s.client.EndpointURL().Host
+	path := strings.TrimPrefix(parsed.Path, "/")
+	switch parsed.Host {
+	case endpoint:
+		if strings.HasPrefix(path, s.bucketName+"/") {
+			return strings.TrimPrefix(path, s.bucketName+"/")
+		}
+	case s.bucketName + "." + endpoint:
+		return path
+	}
+	return ""
+}
+
+// URL returns the public URL of an object, or a presigned GET URL when
+// no S3_PUBLIC_URL is configured.
+func (s *S3Service) URL(ctx context.Context, fileName string) (string, error) {
+	if s.publicURL != "" {
+		return s.publicURL + "/" + fileName, nil
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.bucketName, fileName, presignExpiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %w", fileName, err)
+	}
+	return u.String(), nil
+}
+
+// SignedURL returns a presigned GET URL for fileName; SigV4 caps expiry at
+// presignExpiry.
+func (s *S3Service) SignedURL(ctx context.Context, fileName string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucketName, fileName, min(expiry, presignExpiry), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %w", fileName, err)
+	}
+	return u.String(), nil
+}
+
+// SignedUploadURL returns a presigned PUT URL for fileName. SigV4 presigned
+// PUTs can't limit the body size, so readers must check it (maxBytes is unused).
+func (s *S3Service) SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*SignedUpload, error) {
+	expires := time.Now().Add(expiry)
+	u, err := s.client.PresignedPutObject(ctx, s.bucketName, fileName, expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload for %s: %w", fileName, err)
+	}
+	return &SignedUpload{
+		URL:       u.String(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expires,
+	}, nil
+}
+
+// UploadImage uploads a base64 image and returns (s3URI, publicURL).
+func (s *S3Service) UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base64: %w", err)
+	}
+	publicURL, err := s.UploadBytes(ctx, data, fileName, "image/png")
+	if err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", s.bucketName, fileName), publicURL, nil
+}
+
+// UploadBytes uploads raw bytes and returns the public (or presigned) URL.
+func (s *S3Service) UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error) {
+	_, err := s.client.PutObject(ctx, s.bucketName, fileName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:    mimeType,
+		SendContentMd5: true, // The server rejects a corrupted upload
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write to bucket: %w", err)
+	}
+
+	publicURL, err := s.URL(ctx, fileName)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Uploaded %d bytes to s3://%s/%s", len(data), s.bucketName, fileName)
+	return publicURL, nil
+}
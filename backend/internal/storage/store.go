@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"banana-weather/internal/config"
+)
+
+// Store is the media bucket used by the server and CLI. Service (GCS) and
+// S3Service (S3/MinIO) implement it.
+type Store interface {
+	// UploadImage stores a base64 PNG and returns (objectURI, publicURL).
+	// objectURI is gs:// or s3://; only gs:// URIs can be passed to Veo.
+	UploadImage(ctx context.Context, imageBase64 string, fileName string) (string, string, error)
+	UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error)
+	ReadObject(ctx context.Context, fileName string) ([]byte, error)
+	DeleteObject(ctx context.Context, fileName string) error
+	ObjectName(url string) string
+	// SignedUploadURL lets a client PUT one object directly to the bucket.
+	SignedUploadURL(ctx context.Context, fileName, contentType string, maxBytes int64, expiry time.Duration) (*SignedUpload, error)
+}
+
+// SignedUpload describes a direct upload: the client sends Method to URL with
+// Headers set, before ExpiresAt.
+type SignedUpload struct {
+	URL       string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Archiver is implemented by stores whose objects can move to a colder
+// storage class (GCS). Use AsArchiver rather than a type assertion, so
+// prefixed stores from OpenKind are handled.
+type Archiver interface {
+	StorageClass(ctx context.Context, fileName string) (string, error)
+	SetStorageClass(ctx context.Context, fileName, class string) error
+}
+
+// RangeReader is implemented by stores that can read part of an object (GCS
+// and S3). Use AsRangeReader rather than a type assertion, so prefixed stores
+// from OpenKind are handled.
+type RangeReader interface {
+	// Stat returns the object's attributes, or an error wrapping ErrNotFound.
+	Stat(ctx context.Context, fileName string) (*ObjectInfo, error)
+	// NewRangeReader reads length bytes from offset; a negative length reads
+	// to the end.
+	NewRangeReader(ctx context.Context, fileName string, offset, length int64) (io.ReadCloser, error)
+}
+
+// Copier is implemented by stores that can copy objects in from other
+// buckets (GCS), e.g. media made outside the pipeline. Use AsCopier rather
+// than a type assertion, so prefixed stores from OpenKind are handled.
+type Copier interface {
+	// StatURI returns the attributes of the object at a gs:// URI in any
+	// readable bucket, or an error wrapping ErrNotFound.
+	StatURI(ctx context.Context, uri string) (*ObjectInfo, error)
+	// CopyFrom copies the object at a gs:// URI to fileName, keeping its
+	// content type, and returns (objectURI, publicURL) of the copy.
+	CopyFrom(ctx context.Context, uri, fileName string) (string, string, error)
+}
+
+// URLSigner is implemented by stores that can sign GET URLs (GCS and S3), so
+// clients can read a private bucket directly. Use AsURLSigner rather than a
+// type assertion, so prefixed stores from OpenKind are handled.
+type URLSigner interface {
+	SignedURL(ctx context.Context, fileName string, expiry time.Duration) (string, error)
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+	ETag        string
+	CRC32C      string // Base64, see CRC32C; empty when the backend doesn't report it
+	Updated     time.Time
+}
+
+// ErrNotFound is wrapped by RangeReader.Stat for missing objects.
+var ErrNotFound = errors.New("object not found")
+
+var (
+	_ Store = (*Service)(nil)
+	_ Store = (*S3Service)(nil)
+
+	_ Archiver = (*Service)(nil)
+
+	_ RangeReader = (*Service)(nil)
+	_ RangeReader = (*S3Service)(nil)
+
+	_ Copier = (*Service)(nil)
+
+	_ URLSigner = (*Service)(nil)
+	_ URLSigner = (*S3Service)(nil)
+)
+
+// AsArchiver returns s as an Archiver, or nil when its backend has no storage
+// classes.
+func AsArchiver(s Store) Archiver {
+	if p, ok := s.(*prefixed); ok {
+		a := AsArchiver(p.Store)
+		if a == nil {
+			return nil
+		}
+		return &prefixedArchiver{a: a, prefix: p.prefix}
+	}
+	a, _ := s.(Archiver)
+	return a
+}
+
+// AsRangeReader returns s as a RangeReader, or nil when its backend can't read
+// ranges.
+func AsRangeReader(s Store) RangeReader {
+	if p, ok := s.(*prefixed); ok {
+		r := AsRangeReader(p.Store)
+		if r == nil {
+			return nil
+		}
+		return &prefixedRangeReader{r: r, prefix: p.prefix}
+	}
+	r, _ := s.(RangeReader)
+	return r
+}
+
+// AsCopier returns s as a Copier, or nil when its backend can't copy from
+// gs:// URIs.
+func AsCopier(s Store) Copier {
+	if p, ok := s.(*prefixed); ok {
+		c := AsCopier(p.Store)
+		if c == nil {
+			return nil
+		}
+		return &prefixedCopier{c: c, prefix: p.prefix}
+	}
+	c, _ := s.(Copier)
+	return c
+}
+
+// AsURLSigner returns s as a URLSigner, or nil when its backend can't sign
+// URLs.
+func AsURLSigner(s Store) URLSigner {
+	if p, ok := s.(*prefixed); ok {
+		u := AsURLSigner(p.Store)
+		if u == nil {
+			return nil
+		}
+		return &prefixedURLSigner{u: u, prefix: p.prefix}
+	}
+	u, _ := s.(URLSigner)
+	return u
+}
+
+// OpenOriginals connects to the private bucket (ORIGINALS_BUCKET) that keeps
+// images as generated, before the watermark and AI badge. It returns nil when
+// no originals bucket is configured.
+func OpenOriginals(ctx context.Context, cfg *config.Config) (Store, error) {
+	return OpenKind(ctx, cfg, KindOriginal)
+}
+
+// OpenUploads connects to the private bucket (UPLOADS_BUCKET) that receives
+// user reference photos. It returns nil when no uploads bucket is configured.
+func OpenUploads(ctx context.Context, cfg *config.Config) (Store, error) {
+	return OpenKind(ctx, cfg, KindUpload)
+}
+
+// Open connects to the media bucket selected by cfg.StorageBackend.
+func Open(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch cfg.StorageBackend {
+	case "s3":
+		s, err := NewS3Service(ctx, cfg.BucketName, cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "", "gcs":
+		s, err := NewService(ctx, cfg.BucketName)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", cfg.StorageBackend)
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gcsPublicHost serves public objects of any GCS bucket.
+const gcsPublicHost = "https://storage.googleapis.com/"
+
+// ParseGSURI splits gs://bucket/object into its bucket and object name.
+func ParseGSURI(uri string) (bucket, object string, err error) {
+	rest, ok := strings.CutPrefix(uri, "gs://")
+	if !ok {
+		return "", "", fmt.Errorf("not a gs:// URI: %q", uri)
+	}
+	bucket, object, ok = strings.Cut(rest, "/")
+	if !ok || bucket == "" || object == "" {
+		return "", "", fmt.Errorf("invalid gs:// URI: %q", uri)
+	}
+	return bucket, object, nil
+}
+
+// PublicURL returns the public HTTPS URL of a gs:// URI. The bucket comes
+// from the URI, so objects outside GENMEDIA_BUCKET (e.g. Veo output written
+// to another bucket) resolve too.
+func PublicURL(gsURI string) (string, error) {
+	bucket, object, err := ParseGSURI(gsURI)
+	if err != nil {
+		return "", err
+	}
+	return publicURL(bucket, object), nil
+}
+
+// GSURI is the inverse of PublicURL: it returns the gs:// URI of a public GCS
+// URL. gs:// URIs are returned as is.
+func GSURI(url string) (string, error) {
+	if _, _, err := ParseGSURI(url); err == nil {
+		return url, nil
+	}
+	rest, ok := strings.CutPrefix(url, gcsPublicHost)
+	if !ok {
+		return "", fmt.Errorf("%q is not in GCS", url)
+	}
+	uri := "gs://" + rest
+	if _, _, err := ParseGSURI(uri); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+func publicURL(bucket, object string) string {
+	return gcsPublicHost + bucket + "/" + object
+}
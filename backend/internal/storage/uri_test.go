@@ -0,0 +1,46 @@
+package storage
+
+import "testing"
+
+func TestParseGSURI(t *testing.T) {
+	tests := []struct {
+		uri, bucket, object string
+		wantErr             bool
+	}{
+		{"gs://media/videos/123/sample_0.mp4", "media", "videos/123/sample_0.mp4", false},
+		{"gs://veo-output/a.mp4", "veo-output", "a.mp4", false},
+		{"gs://media", "", "", true},
+		{"gs://media/", "", "", true},
+		{"gs:///a.png", "", "", true},
+		{"https://storage.googleapis.com/media/a.png", "", "", true},
+		{"s3://media/a.png", "", "", true},
+	}
+	for _, tt := range tests {
+		bucket, object, err := ParseGSURI(tt.uri)
+		if (err != nil) != tt.wantErr || bucket != tt.bucket || object != tt.object {
+			t.Errorf("ParseGSURI(%q) = %q, %q, %v", tt.uri, bucket, object, err)
+		}
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	// Veo may write to a bucket other than GENMEDIA_BUCKET
+	got, err := PublicURL("gs://veo-output/videos/1/sample_0.mp4")
+	if err != nil || got != "https://storage.googleapis.com/veo-output/videos/1/sample_0.mp4" {
+		t.Errorf("PublicURL = %q, %v", got, err)
+	}
+	if _, err := PublicURL("s3://media/a.png"); err == nil {
+		t.Error("Expected an error for a non-GCS URI")
+	}
+
+	for _, u := range []string{"https://storage.googleapis.com/media/a.png", "gs://media/a.png"} {
+		if got, err := GSURI(u); err != nil || got != "gs://media/a.png" {
+			t.Errorf("GSURI(%q) = %q, %v", u, got, err)
+		}
+	}
+	for _, u := range []string{"https://cdn.example.com/a.png", "https://storage.googleapis.com/media"} {
+		if _, err := GSURI(u); err == nil {
+			t.Errorf("GSURI(%q): expected an error", u)
+		}
+	}
+}
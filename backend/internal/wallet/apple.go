@@ -0,0 +1,257 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"banana-weather/internal/config"
+)
+
+// Apple builds signed .pkpass files and pushes pass updates through APNs with
+// the Pass Type ID certificate.
+type Apple struct {
+	PassTypeID    string
+	TeamID        string
+	WebServiceURL string // Base URL of the PassKit web service (…/api/wallet)
+
+	cert   *x509.Certificate
+	key    crypto.Signer
+	wwdr   *x509.Certificate
+	secret []byte
+	now    func() time.Time
+
+	apnsURL string
+	apns    *http.Client
+}
+
+// NewApple loads the pass signing certificates named in cfg.
+func NewApple(cfg config.WalletConfig) (*Apple, error) {
+	if cfg.AppleTeamID == "" || cfg.AuthSecret == "" || cfg.BaseURL == "" {
+		return nil, fmt.Errorf("APPLE_TEAM_ID, WALLET_AUTH_SECRET and PUBLIC_BASE_URL are required with APPLE_PASS_TYPE_ID")
+	}
+	cert, err := readCertificate(cfg.AppleCert)
+	if err != nil {
+		return nil, fmt.Errorf("APPLE_PASS_CERT: %w", err)
+	}
+	wwdr, err := readCertificate(cfg.AppleWWDRCert)
+	if err != nil {
+		return nil, fmt.Errorf("APPLE_WWDR_CERT: %w", err)
+	}
+	key, err := readPrivateKey(cfg.AppleKey)
+	if err != nil {
+		return nil, fmt.Errorf("APPLE_PASS_KEY: %w", err)
+	}
+
+	tlsCert := tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+	return &Apple{
+		PassTypeID:    cfg.ApplePassTypeID,
+		TeamID:        cfg.AppleTeamID,
+		WebServiceURL: cfg.BaseURL + "/api/wallet",
+		cert:          cert,
+		key:           key,
+		wwdr:          wwdr,
+		secret:        []byte(cfg.AuthSecret),
+		now:           time.Now,
+		apnsURL:       "https://api.push.apple.com",
+		apns: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{tlsCert}},
+				ForceAttemptHTTP2: true, // APNs only speaks HTTP/2
+			},
+		},
+	}, nil
+}
+
+// readCertificate reads the first certificate of a PEM or DER file.
+func readCertificate(path string) (*x509.Certificate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	return x509.ParseCertificate(b)
+}
+
+// readPrivateKey reads a PKCS#8, PKCS#1 or SEC 1 PEM key.
+func readPrivateKey(path string) (crypto.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if s, ok := k.(crypto.Signer); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unsupported key type %T", k)
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// AuthToken is the web service token of a pass. It's derived from the serial,
+// so nothing needs to be stored to check it.
+func (a *Apple) AuthToken(serial string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(a.PassTypeID + "/" + serial))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// CheckAuth reports whether an Authorization header ("ApplePass <token>")
+// carries the token of serial.
+func (a *Apple) CheckAuth(header, serial string) bool {
+	token, ok := strings.CutPrefix(header, "ApplePass ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.AuthToken(serial))) == 1
+}
+
+type passField struct {
+	Key       string `json:"key"`
+	Label     string `json:"label,omitempty"`
+	Value     string `json:"value"`
+	DateStyle string `json:"dateStyle,omitempty"`
+}
+
+type passJSON struct {
+	FormatVersion       int    `json:"formatVersion"`
+	PassTypeIdentifier  string `json:"passTypeIdentifier"`
+	SerialNumber        string `json:"serialNumber"`
+	TeamIdentifier      string `json:"teamIdentifier"`
+	OrganizationName    string `json:"organizationName"`
+	Description         string `json:"description"`
+	LogoText            string `json:"logoText"`
+	ForegroundColor     string `json:"foregroundColor"`
+	BackgroundColor     string `json:"backgroundColor"`
+	LabelColor          string `json:"labelColor"`
+	WebServiceURL       string `json:"webServiceURL"`
+	AuthenticationToken string `json:"authenticationToken"`
+	Generic             struct {
+		PrimaryFields   []passField `json:"primaryFields"`
+		SecondaryFields []passField `json:"secondaryFields,omitempty"`
+		BackFields      []passField `json:"backFields,omitempty"`
+	} `json:"generic"`
+}
+
+// Build returns the signed .pkpass for p.
+func (a *Apple) Build(p *Pass) ([]byte, error) {
+	loc := p.Location
+	pj := passJSON{
+		FormatVersion:       1,
+		PassTypeIdentifier:  a.PassTypeID,
+		SerialNumber:        loc.ID,
+		TeamIdentifier:      a.TeamID,
+		OrganizationName:    organization,
+		Description:         fmt.Sprintf("%s weather art for %s", organization, loc.Name),
+		LogoText:            organization,
+		ForegroundColor:     "rgb(255, 255, 255)",
+		BackgroundColor:     "rgb(24, 24, 40)",
+		LabelColor:          "rgb(255, 214, 10)",
+		WebServiceURL:       a.WebServiceURL,
+		AuthenticationToken: a.AuthToken(loc.ID),
+	}
+	pj.Generic.PrimaryFields = []passField{{Key: "city", Label: "CITY", Value: loc.Name}}
+	if t := p.Temperature(); t != "" {
+		pj.Generic.SecondaryFields = append(pj.Generic.SecondaryFields, passField{Key: "temperature", Label: "NOW", Value: t})
+	}
+	if p.Weather != nil {
+		pj.Generic.SecondaryFields = append(pj.Generic.SecondaryFields, passField{Key: "conditions", Label: "WEATHER", Value: string(p.Weather.Condition)})
+	}
+	if !loc.LastUpdated.IsZero() {
+		pj.Generic.BackFields = append(pj.Generic.BackFields, passField{Key: "updated", Label: "Art updated", Value: loc.LastUpdated.UTC().Format(time.RFC3339), DateStyle: "PKDateStyleMedium"})
+	}
+
+	files := map[string][]byte{}
+	var err error
+	if files["pass.json"], err = json.Marshal(pj); err != nil {
+		return nil, err
+	}
+	for name, size := range map[string]int{"icon.png": 29, "icon@2x.png": 58, "thumbnail.png": 90, "thumbnail@2x.png": 180} {
+		if files[name], err = p.thumbnailPNG(size); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := map[string]string{}
+	for name, b := range files {
+		sum := sha1.Sum(b)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	if files["manifest.json"], err = json.Marshal(manifest); err != nil {
+		return nil, err
+	}
+	if files["signature"], err = signDetached(files["manifest.json"], a.cert, a.key, []*x509.Certificate{a.wwdr}, a.now()); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Notify asks devices to fetch updated passes. APNs wallet pushes carry no
+// payload; the device calls the web service for what changed.
+func (a *Apple) Notify(ctx context.Context, pushTokens []string) error {
+	var errs []error
+	for _, token := range pushTokens {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.apnsURL+"/3/device/"+token, strings.NewReader("{}"))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("apns-topic", a.PassTypeID)
+		resp, err := a.apns.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// 410: the device removed the pass; it unregisters itself
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusGone {
+			errs = append(errs, fmt.Errorf("APNs %s: %s", resp.Status, strings.TrimSpace(string(body))))
+		}
+	}
+	return errors.Join(errs...)
+}
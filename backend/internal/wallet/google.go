@@ -0,0 +1,204 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"banana-weather/internal/config"
+
+	"golang.org/x/oauth2/google"
+)
+
+const walletScope = "https://www.googleapis.com/auth/wallet_object.issuer"
+
+var invalidObjectChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Google issues Google Wallet generic passes: a signed "save" link creates
+// the object, and the Wallet REST API updates it.
+type Google struct {
+	IssuerID string
+
+	email   string
+	key     *rsa.PrivateKey
+	client  *http.Client
+	baseURL string
+}
+
+// NewGoogle loads the service account key that signs save links and calls
+// the Wallet API.
+func NewGoogle(ctx context.Context, cfg config.WalletConfig) (*Google, error) {
+	b, err := os.ReadFile(cfg.GoogleKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("GOOGLE_WALLET_KEY_FILE: %w", err)
+	}
+	jwtCfg, err := google.JWTConfigFromJSON(b, walletScope)
+	if err != nil {
+		return nil, fmt.Errorf("GOOGLE_WALLET_KEY_FILE: %w", err)
+	}
+	block, _ := pem.Decode(jwtCfg.PrivateKey)
+	if block == nil {
+		return nil, fmt.Errorf("GOOGLE_WALLET_KEY_FILE: no private key")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("GOOGLE_WALLET_KEY_FILE: %w", err)
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GOOGLE_WALLET_KEY_FILE: want an RSA key, got %T", k)
+	}
+	return &Google{
+		IssuerID: cfg.GoogleIssuerID,
+		email:    jwtCfg.Email,
+		key:      key,
+		client:   jwtCfg.Client(ctx),
+		baseURL:  "https://walletobjects.googleapis.com/walletobjects/v1",
+	}, nil
+}
+
+func (g *Google) classID() string {
+	return g.IssuerID + ".banana_weather"
+}
+
+func (g *Google) objectID(locationID string) string {
+	return g.IssuerID + "." + invalidObjectChars.ReplaceAllString(locationID, "_")
+}
+
+type localizedString struct {
+	DefaultValue struct {
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	} `json:"defaultValue"`
+}
+
+func localized(s string) *localizedString {
+	l := &localizedString{}
+	l.DefaultValue.Language = "en-US"
+	l.DefaultValue.Value = s
+	return l
+}
+
+type textModule struct {
+	ID     string `json:"id"`
+	Header string `json:"header"`
+	Body   string `json:"body"`
+}
+
+type walletImage struct {
+	SourceURI struct {
+		URI string `json:"uri"`
+	} `json:"sourceUri"`
+}
+
+type genericObject struct {
+	ID                 string           `json:"id"`
+	ClassID            string           `json:"classId"`
+	State              string           `json:"state"`
+	CardTitle          *localizedString `json:"cardTitle"`
+	Header             *localizedString `json:"header"`
+	Subheader          *localizedString `json:"subheader,omitempty"`
+	HexBackgroundColor string           `json:"hexBackgroundColor"`
+	HeroImage          *walletImage     `json:"heroImage,omitempty"`
+	TextModulesData    []textModule     `json:"textModulesData,omitempty"`
+}
+
+func (g *Google) object(p *Pass) genericObject {
+	loc := p.Location
+	obj := genericObject{
+		ID:                 g.objectID(loc.ID),
+		ClassID:            g.classID(),
+		State:              "ACTIVE",
+		CardTitle:          localized(organization),
+		Header:             localized(loc.Name),
+		HexBackgroundColor: "#181828",
+	}
+	if p.Weather != nil {
+		obj.Subheader = localized(string(p.Weather.Condition))
+	}
+	if loc.ImageURL != "" {
+		obj.HeroImage = &walletImage{}
+		obj.HeroImage.SourceURI.URI = loc.ImageURL
+	}
+	if t := p.Temperature(); t != "" {
+		obj.TextModulesData = append(obj.TextModulesData, textModule{ID: "temperature", Header: "Now", Body: t})
+	}
+	return obj
+}
+
+// SaveURL returns the "Add to Google Wallet" link for p. The link embeds the
+// object (and class), which Google creates when the user saves it.
+func (g *Google) SaveURL(p *Pass) (string, error) {
+	claims := map[string]any{
+		"iss":     g.email,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"origins": []string{},
+		"payload": map[string]any{
+			"genericClasses": []map[string]string{{"id": g.classID()}},
+			"genericObjects": []genericObject{g.object(p)},
+		},
+	}
+	token, err := signJWT(g.key, claims)
+	if err != nil {
+		return "", err
+	}
+	return "https://pay.google.com/gp/v/save/" + token, nil
+}
+
+// Update patches the object of p. Objects nobody saved yet don't exist, which
+// is not an error.
+func (g *Google) Update(ctx context.Context, p *Pass) error {
+	obj := g.object(p)
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, g.baseURL+"/genericObject/"+obj.ID, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Google Wallet update of %s: %s: %s", obj.ID, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// signJWT returns an RS256 JSON Web Token.
+func signJWT(key *rsa.PrivateKey, claims any) (string, error) {
+	enc := base64.RawURLEncoding
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
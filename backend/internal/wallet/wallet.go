@@ -0,0 +1,185 @@
+// Package wallet issues Apple Wallet and Google Wallet passes for locations,
+// showing the latest art and temperature, and updates them when the media is
+// refreshed.
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"time"
+
+	"banana-weather/internal/config"
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/weather"
+)
+
+const organization = "Banana Weather"
+
+// maxImageBytes caps the art downloaded for a pass thumbnail.
+const maxImageBytes = 20 << 20
+
+// Store is the part of the repository the wallet needs.
+type Store interface {
+	ListPassRegistrations(ctx context.Context, field, value string) ([]database.PassRegistration, error)
+}
+
+// Pass is what a wallet pass shows for a location.
+type Pass struct {
+	Location *database.Location
+	Weather  *openmeteo.Current // Optional: current conditions
+	Art      image.Image        // Optional: the latest art, for Apple thumbnails
+}
+
+// Temperature renders the current temperature, or "" when unknown.
+func (p *Pass) Temperature() string {
+	if p.Weather == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f°C", p.Weather.TemperatureC)
+}
+
+// thumbnailPNG crops the art to a centred square of size pixels. Without art
+// it's a plain banana-yellow square.
+func (p *Pass) thumbnailPNG(size int) ([]byte, error) {
+	out := image.NewRGBA(image.Rect(0, 0, size, size))
+	if p.Art == nil {
+		draw.Draw(out, out.Bounds(), image.NewUniform(color.RGBA{255, 214, 10, 255}), image.Point{}, draw.Src)
+	} else {
+		// Nearest-neighbour sampling, as in branding; plenty at thumbnail size
+		b := p.Art.Bounds()
+		side := min(b.Dx(), b.Dy())
+		x0, y0 := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				out.Set(x, y, p.Art.At(x0+x*side/size, y0+y*side/size))
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Service builds passes and keeps them current. Either wallet may be nil.
+type Service struct {
+	Apple      *Apple
+	Google     *Google
+	DB         Store
+	Conditions weather.ConditionsService // Optional: live temperature on passes
+	HTTP       *http.Client              // Downloads the art for thumbnails
+}
+
+// Open returns the wallet service configured in cfg, or nil when neither
+// Apple nor Google Wallet is set up.
+func Open(ctx context.Context, cfg *config.Config, db Store) (*Service, error) {
+	s := &Service{DB: db, HTTP: &http.Client{Timeout: 30 * time.Second}}
+	var err error
+	if cfg.Wallet.ApplePassTypeID != "" {
+		if s.Apple, err = NewApple(cfg.Wallet); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Wallet.GoogleIssuerID != "" {
+		if s.Google, err = NewGoogle(ctx, cfg.Wallet); err != nil {
+			return nil, err
+		}
+	}
+	if s.Apple == nil && s.Google == nil {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// pass gathers what a pass shows. Weather and art are best effort: a pass
+// without them is better than none.
+func (s *Service) pass(ctx context.Context, loc *database.Location, withArt bool) *Pass {
+	p := &Pass{Location: loc}
+	if s.Conditions != nil && loc.Geo != nil {
+		cur, err := s.Conditions.Current(ctx, loc.Geo.Latitude, loc.Geo.Longitude)
+		if err != nil {
+			log.Printf("Wallet pass for %s: weather unavailable: %v", loc.ID, err)
+		} else {
+			p.Weather = cur
+		}
+	}
+	if withArt && loc.ImageURL != "" {
+		art, err := s.fetchArt(ctx, loc.ImageURL)
+		if err != nil {
+			log.Printf("Wallet pass for %s: art unavailable: %v", loc.ID, err)
+		} else {
+			p.Art = art
+		}
+	}
+	return p
+}
+
+func (s *Service) fetchArt(ctx context.Context, url string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	img, _, err := image.Decode(http.MaxBytesReader(nil, resp.Body, maxImageBytes))
+	return img, err
+}
+
+// ApplePass returns the signed .pkpass of a location.
+func (s *Service) ApplePass(ctx context.Context, loc *database.Location) ([]byte, error) {
+	if s.Apple == nil {
+		return nil, fmt.Errorf("Apple Wallet is not configured")
+	}
+	return s.Apple.Build(s.pass(ctx, loc, true))
+}
+
+// GoogleSaveURL returns the "Add to Google Wallet" link of a location.
+func (s *Service) GoogleSaveURL(ctx context.Context, loc *database.Location) (string, error) {
+	if s.Google == nil {
+		return "", fmt.Errorf("Google Wallet is not configured")
+	}
+	return s.Google.SaveURL(s.pass(ctx, loc, false))
+}
+
+// Refreshed updates the passes of a location after its media changed: Apple
+// devices holding it are pushed, and the Google Wallet object is patched. A
+// nil Service does nothing.
+func (s *Service) Refreshed(ctx context.Context, loc *database.Location) error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	if s.Apple != nil {
+		regs, err := s.DB.ListPassRegistrations(ctx, "serial", loc.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list pass registrations: %w", err))
+		} else if len(regs) > 0 {
+			tokens := make([]string, len(regs))
+			for i, r := range regs {
+				tokens[i] = r.PushToken
+			}
+			errs = append(errs, s.Apple.Notify(ctx, tokens))
+		}
+	}
+	if s.Google != nil {
+		errs = append(errs, s.Google.Update(ctx, s.pass(ctx, loc, false)))
+	}
+	return errors.Join(errs...)
+}
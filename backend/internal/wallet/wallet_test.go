@@ -0,0 +1,219 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+)
+
+func testCert(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func testApple(t *testing.T) *Apple {
+	cert, key := testCert(t)
+	return &Apple{
+		PassTypeID:    "pass.test",
+		TeamID:        "TEAM123",
+		WebServiceURL: "https://example.com/api/wallet",
+		cert:          cert,
+		key:           key,
+		wwdr:          cert,
+		secret:        []byte("secret"),
+		now:           time.Now,
+	}
+}
+
+func TestAppleBuild(t *testing.T) {
+	a := testApple(t)
+	art := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	art.Set(150, 100, color.RGBA{255, 0, 0, 255})
+	p := &Pass{
+		Location: &database.Location{ID: "paris", Name: "Paris", LastUpdated: time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)},
+		Weather:  &openmeteo.Current{Condition: openmeteo.ConditionRain, TemperatureC: 12.4},
+		Art:      art,
+	}
+	b, err := a.Build(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pass.json", "icon.png", "thumbnail@2x.png"} {
+		sum := sha1.Sum(files[name])
+		if manifest[name] != hex.EncodeToString(sum[:]) {
+			t.Errorf("Manifest hash of %s doesn't match", name)
+		}
+	}
+	var pj passJSON
+	if err := json.Unmarshal(files["pass.json"], &pj); err != nil {
+		t.Fatal(err)
+	}
+	if pj.SerialNumber != "paris" || pj.AuthenticationToken != a.AuthToken("paris") || len(pj.Generic.SecondaryFields) != 2 || pj.Generic.SecondaryFields[0].Value != "12°C" {
+		t.Errorf("Unexpected pass.json %+v", pj)
+	}
+	if thumb, _, err := image.Decode(bytes.NewReader(files["thumbnail.png"])); err != nil || thumb.Bounds().Dx() != 90 {
+		t.Errorf("Expected a 90px thumbnail, got %v (%v)", thumb, err)
+	}
+
+	// Check the signature the way Wallet does (openssl cms -verify agrees)
+	var ci contentInfo
+	var sd signedData
+	if _, err := asn1.Unmarshal(files["signature"], &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		t.Fatalf("Expected PKCS#7 signed data, got %v", err)
+	}
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+	si := sd.SignerInfos[0]
+	signed, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: si.AuthenticatedAttributes.Bytes})
+	digest := sha256.Sum256(signed)
+	if err := rsa.VerifyPKCS1v15(&a.key.(*rsa.PrivateKey).PublicKey, crypto.SHA256, digest[:], si.EncryptedDigest); err != nil {
+		t.Errorf("Signature doesn't verify: %v", err)
+	}
+	manifestDigest := sha256.Sum256(files["manifest.json"])
+	if !bytes.Contains(si.AuthenticatedAttributes.Bytes, manifestDigest[:]) {
+		t.Error("Signed attributes don't carry the manifest digest")
+	}
+}
+
+func TestAppleAuth(t *testing.T) {
+	a := testApple(t)
+	if !a.CheckAuth("ApplePass "+a.AuthToken("paris"), "paris") {
+		t.Error("Expected the pass's own token to be accepted")
+	}
+	if a.CheckAuth("ApplePass "+a.AuthToken("paris"), "tokyo") || a.CheckAuth(a.AuthToken("paris"), "paris") {
+		t.Error("Expected another pass's token, or a missing scheme, to be rejected")
+	}
+}
+
+func TestAppleNotify(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+" "+r.Header.Get("apns-topic"))
+		switch r.URL.Path {
+		case "/3/device/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/3/device/bad":
+			http.Error(w, `{"reason":"BadDeviceToken"}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	a := testApple(t)
+	a.apnsURL, a.apns = srv.URL, srv.Client()
+
+	if err := a.Notify(context.Background(), []string{"tok", "gone"}); err != nil {
+		t.Errorf("Expected removed passes to be ignored, got %v", err)
+	}
+	if paths[0] != "/3/device/tok pass.test" {
+		t.Errorf("Unexpected APNs request %v", paths)
+	}
+	if err := a.Notify(context.Background(), []string{"bad", "tok"}); err == nil || !strings.Contains(err.Error(), "BadDeviceToken") || len(paths) != 4 {
+		t.Errorf("Expected the error after trying every device, got %v (%v)", err, paths)
+	}
+}
+
+func TestGoogle(t *testing.T) {
+	_, key := testCert(t)
+	var patched genericObject
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/genericObject/123.unsaved" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&patched)
+	}))
+	defer srv.Close()
+	g := &Google{IssuerID: "123", email: "wallet@example.iam.gserviceaccount.com", key: key, client: srv.Client(), baseURL: srv.URL}
+	p := &Pass{
+		Location: &database.Location{ID: "paris fr", Name: "Paris", ImageURL: "https://example.com/paris.png"},
+		Weather:  &openmeteo.Current{Condition: openmeteo.ConditionClear, TemperatureC: 21},
+	}
+
+	url, err := g.SaveURL(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(url, "https://pay.google.com/gp/v/save/"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT in %s", url)
+	}
+	if err := verifyJWT(&key.PublicKey, parts); err != nil {
+		t.Errorf("Invalid JWT signature: %v", err)
+	}
+
+	if err := g.Update(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if patched.ID != "123.paris_fr" || patched.HeroImage.SourceURI.URI != p.Location.ImageURL || patched.TextModulesData[0].Body != "21°C" {
+		t.Errorf("Unexpected object %+v", patched)
+	}
+	p.Location.ID = "unsaved"
+	if err := g.Update(context.Background(), p); err != nil {
+		t.Errorf("Expected an unsaved object to be skipped, got %v", err)
+	}
+}
+
+func verifyJWT(pub *rsa.PublicKey, parts []string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+}
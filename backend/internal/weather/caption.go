@@ -0,0 +1,44 @@
+package weather
+
+import (
+	"context"
+
+	"banana-weather/internal/progress"
+)
+
+// Captioner writes the caption shown under a place's artwork: a nickname or
+// fun fact. *genai.Service implements it.
+type Captioner interface {
+	Caption(ctx context.Context, city string) (string, error)
+}
+
+// captionStage sends the place's caption as a "caption" event, running
+// alongside the image stage: the caption stored on the location, or a new
+// one from Captioner, since nicknames don't change with the weather. The
+// channel gets the caption ("" when there's none) once it's sent. A failed
+// caption is logged and never fails the flow.
+func (s *Service) captionStage(ctx context.Context, r *resolvedPlace, send StatusCallback) <-chan string {
+	out := make(chan string, 1)
+	if s.Captioner == nil {
+		out <- ""
+		return out
+	}
+	go func() {
+		var caption string
+		if loc, err := s.DB.GetLocation(ctx, r.ID); err == nil && loc != nil {
+			caption = loc.Caption
+		}
+		if caption == "" {
+			c, err := s.Captioner.Caption(ctx, r.Place.Name)
+			if err != nil {
+				progress.Logf(ctx, "Caption for %s failed: %v", r.Place.Name, err)
+			}
+			caption = c
+		}
+		if caption != "" {
+			send("caption", caption)
+		}
+		out <- caption
+	}()
+	return out
+}
@@ -0,0 +1,58 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"banana-weather/internal/genai"
+	"banana-weather/internal/progress"
+)
+
+// ErrChaos is returned by GenerateImage calls that ChaosGenAI chose to fail.
+var ErrChaos = errors.New("chaos: injected image generation failure")
+
+// ChaosGenAI wraps a GenAIService to inject image failures and Veo latency,
+// for exercising the flow's partial-failure handling. Development only.
+type ChaosGenAI struct {
+	GenAIService
+	ImageFailRate float64       // Fraction of GenerateImage calls that fail, 0-1
+	VeoDelay      time.Duration // Added before every GenerateVideo call
+
+	roll func() float64 // Defaults to rand.Float64; replaced in tests
+}
+
+// WithChaos returns g wrapped in a ChaosGenAI, or g itself when no chaos is configured.
+func WithChaos(g GenAIService, imageFailRate float64, veoDelay time.Duration) GenAIService {
+	if imageFailRate <= 0 && veoDelay <= 0 {
+		return g
+	}
+	log.Printf("WARNING: Chaos enabled (image fail rate: %.2f, Veo delay: %s). Do not use in production.", imageFailRate, veoDelay)
+	return &ChaosGenAI{GenAIService: g, ImageFailRate: imageFailRate, VeoDelay: veoDelay}
+}
+
+func (c *ChaosGenAI) GenerateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32) (*genai.ImageResult, error) {
+	roll := c.roll
+	if roll == nil {
+		roll = rand.Float64
+	}
+	if c.ImageFailRate > 0 && roll() < c.ImageFailRate {
+		progress.Logf(ctx, "Chaos: failing image generation for %s", city)
+		return nil, ErrChaos
+	}
+	return c.GenAIService.GenerateImage(ctx, city, extraContext, promptMode, seed)
+}
+
+func (c *ChaosGenAI) GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error) {
+	if c.VeoDelay > 0 {
+		progress.Logf(ctx, "Chaos: delaying Veo by %s", c.VeoDelay)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(c.VeoDelay):
+		}
+	}
+	return c.GenAIService.GenerateVideo(ctx, inputImageURI, prompt, seed)
+}
@@ -0,0 +1,115 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+)
+
+// rolls returns a roll func yielding values in order.
+func rolls(values ...float64) func() float64 {
+	return func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+}
+
+func TestWithChaos_Disabled(t *testing.T) {
+	g := &MockGenAI{}
+	if WithChaos(g, 0, 0) != GenAIService(g) {
+		t.Error("Expected the service to be returned unwrapped when chaos is off")
+	}
+}
+
+func TestGetWeatherFlow_ChaosImageFailure(t *testing.T) {
+	genai := &ChaosGenAI{GenAIService: &MockGenAI{ImageBase64: "base64data"}, ImageFailRate: 1}
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, genai, &MockStorage{}, db)
+
+	var events []string
+	err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		events = append(events, event)
+	})
+	if !errors.Is(err, ErrChaos) {
+		t.Fatalf("Expected ErrChaos, got %v", err)
+	}
+	if events[len(events)-1] != "error" {
+		t.Errorf("Expected final SSE error event, got %v", events)
+	}
+	for _, e := range events {
+		if e == "result" {
+			t.Error("No result may be sent when the image fails")
+		}
+	}
+	if db.LastStatus != database.StatusFailed {
+		t.Errorf("Expected status %q, got %q", database.StatusFailed, db.LastStatus)
+	}
+}
+
+func TestGetWeatherFlow_ChaosVeoTimeout(t *testing.T) {
+	genai := &ChaosGenAI{GenAIService: &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}, VeoDelay: time.Hour}
+	storage := &MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, genai, storage, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var events []string
+	err := svc.GetWeatherFlow(ctx, "London", "", "", func(event, data string) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("A Veo failure must not fail the flow, got %v", err)
+	}
+
+	// The image goes out first, then the video failure is reported
+	resultAt, errorAt := -1, -1
+	for i, e := range events {
+		switch e {
+		case "result":
+			resultAt = i
+		case "error":
+			errorAt = i
+		case "video":
+			t.Error("No video event expected")
+		}
+	}
+	if resultAt < 0 || errorAt < resultAt {
+		t.Errorf("Expected result then error events, got %v", events)
+	}
+	if db.Saved == nil || db.Saved.Status != database.StatusReady || db.Saved.ImageURL != "http://storage/image.png" || db.Saved.VideoURL != "" {
+		t.Errorf("Expected the image to be saved as ready without video, got %+v", db.Saved)
+	}
+	if db.SaveCtxErr != nil {
+		t.Errorf("Final save used a cancelled context: %v", db.SaveCtxErr)
+	}
+}
+
+func TestGetWeatherFlow_ChaosRegenerationFailure(t *testing.T) {
+	mock := &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}
+	genai := &ChaosGenAI{GenAIService: mock, ImageFailRate: 0.5, roll: rolls(0.9, 0.1)} // First call passes, retry fails
+	storage := &MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}
+	db := &MockDB{Err: fmt.Errorf("not found")}
+
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, genai, storage, db)
+	svc.Conditions = &MockConditions{Observed: &openmeteo.Current{Condition: openmeteo.ConditionSnow, IsDay: true}}
+	svc.Verifier = &MockVerifier{Matches: []bool{false}}
+	svc.RegenerateOnMismatch = true
+
+	if err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {}); err != nil {
+		t.Fatalf("A failed regeneration must keep the first image, got %v", err)
+	}
+	if db.Saved == nil || !db.Saved.WeatherMismatch || db.Saved.WeatherCheck.Regenerated {
+		t.Errorf("Expected the first image to be kept and flagged, got %+v", db.Saved)
+	}
+	if *db.Saved.Seed != *mock.LastSeed {
+		t.Error("Expected the saved seed to be the first image's")
+	}
+}
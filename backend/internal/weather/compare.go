@@ -0,0 +1,142 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"sync"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/pipeline"
+)
+
+// CandidateStore keeps generations offered for review instead of replacing a
+// location's media.
+type CandidateStore interface {
+	AddCandidate(ctx context.Context, cand database.Candidate) (string, error)
+	ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error)
+	GetCandidate(ctx context.Context, id string) (*database.Candidate, error)
+	SetCandidateStatus(ctx context.Context, id, status string) error
+	TransitionCandidate(ctx context.Context, id string, from []string, to string) (*database.Candidate, error)
+}
+
+// CompareRequest asks Compare for one image of a city per style.
+type CompareRequest struct {
+	City       string
+	LocationID string // Candidates are recorded under it; LocationID(City) when empty
+	Styles     []int  // Prompt modes, see genai.Styles
+	Seed       *int32 // Shared by every style; random when nil
+	Record     bool   // Upload the images and record them as candidates
+}
+
+// Comparison is the outcome of Compare, one result per requested style in
+// order.
+type Comparison struct {
+	LocationID string          `json:"location_id"`
+	City       string          `json:"city"`
+	Seed       int32           `json:"seed"`
+	Results    []CompareResult `json:"results"`
+}
+
+// CompareResult is one style's image: inline, and when recorded also as a
+// URL and candidate ID. A failed style has only Error.
+type CompareResult struct {
+	Style       string `json:"style"`
+	ImageBase64 string `json:"image_base64,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Candidate   string `json:"candidate,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// LocationID is the ID a city query is stored under.
+func LocationID(city string) string {
+	return sanitizeID(city)
+}
+
+// Compare generates the city once per style, concurrently and with the same
+// seed, for prompt and style tuning. Images skip Veo, and nothing replaces
+// the location's media: with Record they're uploaded under candidates/ and
+// stored as candidates (Source "compare"). It fails only on a bad request;
+// a style that fails to generate reports its error in its result.
+func (s *Service) Compare(ctx context.Context, req CompareRequest) (*Comparison, error) {
+	if req.City == "" {
+		return nil, fmt.Errorf("city is required")
+	}
+	if len(req.Styles) == 0 {
+		return nil, fmt.Errorf("at least one style is required")
+	}
+	if err := s.checkHalted(ctx); err != nil {
+		return nil, err
+	}
+	if req.Record && (s.Storage == nil || s.Candidates == nil) {
+		return nil, fmt.Errorf("recording candidates needs storage and a candidate store")
+	}
+	id := req.LocationID
+	if id == "" {
+		id = LocationID(req.City)
+	}
+	seed := rand.Int32N(math.MaxInt32)
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	out := &Comparison{LocationID: id, City: req.City, Seed: seed, Results: make([]CompareResult, len(req.Styles))}
+	var wg sync.WaitGroup
+	for i, style := range req.Styles {
+		wg.Go(func() {
+			out.Results[i] = s.compareStyle(ctx, id, req, style, seed)
+		})
+	}
+	wg.Wait()
+	return out, nil
+}
+
+func (s *Service) compareStyle(ctx context.Context, id string, req CompareRequest, style int, seed int32) CompareResult {
+	name := genai.StyleName(style)
+	result := CompareResult{Style: name}
+	opts := []pipeline.Option{
+		pipeline.WithSeed(seed),
+		pipeline.WithImageFunc(func(ctx context.Context, preq pipeline.Request, seed *int32) (*genai.ImageResult, error) {
+			img, _, err := s.generateImage(ctx, req.City, preq.Context, style, seed, nil)
+			return img, err
+		}),
+		pipeline.SkipVideo(),
+	}
+	if !req.Record {
+		opts = append(opts, pipeline.SkipUpload())
+	}
+	preq := pipeline.Request{
+		ID:       id,
+		City:     req.City,
+		FileName: fmt.Sprintf("candidates/%s_%s_%d.png", id, name, s.now().UnixNano()),
+	}
+	res, err := s.pipeline().Generate(ctx, preq, opts...)
+	if err != nil {
+		log.Printf("Compare: %s in style %s failed: %v", req.City, name, err)
+		result.Error = err.Error()
+		return result
+	}
+	result.ImageBase64 = res.Image.Images[0]
+	if !req.Record {
+		return result
+	}
+
+	result.ImageURL = res.ImageURL
+	result.Candidate, err = s.Candidates.AddCandidate(ctx, database.Candidate{
+		LocationID: id,
+		City:       req.City,
+		Source:     "compare",
+		Style:      name,
+		Seed:       res.Seed,
+		ImageURL:   res.ImageURL,
+		Generation: res.Metadata(),
+	})
+	if err != nil {
+		log.Printf("Compare: failed to record the %s candidate of %s: %v", name, id, err)
+		result.Error = fmt.Sprintf("failed to record candidate: %v", err)
+	}
+	return result
+}
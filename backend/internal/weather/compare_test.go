@@ -0,0 +1,159 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// styleGenAI returns an image named after the prompt style, and fails the
+// styles in fail.
+type styleGenAI struct {
+	MockGenAI
+	mu    sync.Mutex
+	seeds []int32
+	fail  map[int]bool
+}
+
+func (m *styleGenAI) GenerateImage(ctx context.Context, city, extra string, mode int, seed *int32) (*genai.ImageResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seeds = append(m.seeds, *seed)
+	if m.fail[mode] {
+		return nil, errors.New("blocked by safety filter")
+	}
+	return &genai.ImageResult{Images: []string{"style" + strconv.Itoa(mode)}, Model: "test-model"}, nil
+}
+
+type fakeCandidates struct {
+	mu    sync.Mutex
+	added []database.Candidate
+}
+
+func (f *fakeCandidates) AddCandidate(ctx context.Context, cand database.Candidate) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cand.ID = strconv.Itoa(len(f.added) + 1)
+	if cand.CreatedAt.IsZero() {
+		cand.CreatedAt = time.Unix(int64(len(f.added)), 0) // Ordered as added
+	}
+	f.added = append(f.added, cand)
+	return cand.ID, nil
+}
+
+func (f *fakeCandidates) ListCandidates(ctx context.Context, locationID string) ([]database.Candidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []database.Candidate
+	for _, c := range slices.Backward(f.added) {
+		if c.LocationID == locationID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCandidates) GetCandidate(ctx context.Context, id string) (*database.Candidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.added {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("candidate %s not found", id)
+}
+
+func (f *fakeCandidates) SetCandidateStatus(ctx context.Context, id, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.added {
+		if f.added[i].ID == id {
+			f.added[i].Status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("candidate %s not found", id)
+}
+
+func (f *fakeCandidates) TransitionCandidate(ctx context.Context, id string, from []string, to string) (*database.Candidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.added {
+		if f.added[i].ID == id {
+			cand := f.added[i]
+			if !slices.Contains(from, cand.Status) {
+				return nil, status.Errorf(codes.FailedPrecondition, "candidate %q is %s", id, cand.Status)
+			}
+			f.added[i].Status = to
+			return &cand, nil
+		}
+	}
+	return nil, fmt.Errorf("candidate %s not found", id)
+}
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	gen := &styleGenAI{fail: map[int]bool{0: true}}
+	storage := &MockStorage{PublicURL: "http://storage/candidate.png", GsURI: "gs://bucket/candidate.png"}
+	svc := NewService(nil, gen, storage, &MockDB{})
+
+	seed := int32(7)
+	out, err := svc.Compare(ctx, CompareRequest{City: "Istanbul", Styles: []int{1, 2, 0}, Seed: &seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.LocationID != "istanbul" || out.Seed != 7 || len(out.Results) != 3 {
+		t.Fatalf("Unexpected comparison: %+v", out)
+	}
+	for i, want := range []CompareResult{
+		{Style: "classic", ImageBase64: "style1"},
+		{Style: "drink", ImageBase64: "style2"},
+		{Style: "random"},
+	} {
+		got := out.Results[i]
+		if got.Style != want.Style || got.ImageBase64 != want.ImageBase64 || got.ImageURL != "" {
+			t.Errorf("Result %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if out.Results[2].Error == "" {
+		t.Error("Expected the failed style to report its error")
+	}
+	for _, s := range gen.seeds {
+		if s != seed {
+			t.Errorf("Expected every style generated with seed %d, got %v", seed, gen.seeds)
+		}
+	}
+
+	// Recording needs a candidate store
+	if _, err := svc.Compare(ctx, CompareRequest{City: "Istanbul", Styles: []int{1}, Record: true}); err == nil {
+		t.Error("Expected an error recording without a candidate store")
+	}
+	cands := &fakeCandidates{}
+	svc.Candidates = cands
+	out, err = svc.Compare(ctx, CompareRequest{City: "Istanbul", LocationID: "istanbul_tr", Styles: []int{2}, Record: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := out.Results[0]; r.ImageURL != "http://storage/candidate.png" || r.Candidate != "1" {
+		t.Errorf("Expected the image recorded as a candidate, got %+v", r)
+	}
+	if len(cands.added) != 1 || cands.added[0].LocationID != "istanbul_tr" || cands.added[0].Source != "compare" || cands.added[0].Style != "drink" {
+		t.Errorf("Unexpected candidates: %+v", cands.added)
+	}
+
+	if _, err := svc.Compare(ctx, CompareRequest{City: "Istanbul"}); err == nil {
+		t.Error("Expected an error without styles")
+	}
+}
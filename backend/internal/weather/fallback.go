@@ -0,0 +1,152 @@
+package weather
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+)
+
+// fallbackCandidates caps the cached locations of a country considered for
+// fallback art.
+const fallbackCandidates = 50
+
+// FallbackStore finds previously generated art. The repository implements it.
+type FallbackStore interface {
+	GetLocationsByCountry(ctx context.Context, code string, limit int) ([]database.Location, error)
+	GetPresets(ctx context.Context) ([]database.Location, error)
+}
+
+// FallbackMedia is what the web flow shows when it can't generate an image,
+// besides a static map (Service.StaticMaps).
+type FallbackMedia struct {
+	Store    FallbackStore // Optional: previously generated art of nearby places
+	ImageURL string        // Optional: generic placeholder image
+	VideoURL string        // Optional: generic placeholder animation
+}
+
+// sendFallback sends something to look at when no image could be generated,
+// flagged as a fallback result, trying in order: the location's previous
+// art, the nearest cached location in the same country, the nearest preset
+// on the same continent, a static map of the place, then the placeholder.
+// Nothing is stored and the location stays failed. It returns what was
+// shown, for the error message, or "" when nothing was.
+func (s *Service) sendFallback(ctx context.Context, r *resolvedPlace, send StatusCallback) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	if loc := s.fallbackArt(ctx, r); loc != nil {
+		s.sendFallbackResult(r, WeatherResponse{ImageURL: loc.ImageURL, FallbackFrom: loc.Name}, send)
+		if loc.PosterURL != "" {
+			send("poster", loc.PosterURL)
+		}
+		if loc.StreamURL != "" {
+			send("stream", loc.StreamURL)
+		}
+		if loc.VideoURL != "" {
+			send("video", loc.VideoURL)
+		}
+		if loc.ID == r.ID {
+			return "the previous image"
+		}
+		return loc.Name
+	}
+
+	if s.StaticMaps != nil {
+		b, err := s.StaticMaps.StaticMap(ctx, r.Place.Lat, r.Place.Lng)
+		if err == nil {
+			s.sendFallbackResult(r, WeatherResponse{ImageBase64: base64.StdEncoding.EncodeToString(b)}, send)
+			return "a map"
+		}
+		progress.Logf(ctx, "Static map fallback for '%s' failed: %v", r.Place.Name, err)
+	}
+
+	if f := s.Fallback; f != nil && f.ImageURL != "" {
+		s.sendFallbackResult(r, WeatherResponse{ImageURL: f.ImageURL}, send)
+		if f.VideoURL != "" {
+			send("video", f.VideoURL)
+		}
+		return "a placeholder"
+	}
+	return ""
+}
+
+func (s *Service) sendFallbackResult(r *resolvedPlace, resp WeatherResponse, send StatusCallback) {
+	resp.City = r.Place.Name
+	resp.LastUpdated = s.now()
+	resp.Fallback = true
+	jsonData, _ := json.Marshal(resp)
+	send("result", string(jsonData))
+}
+
+// fallbackArt returns the location whose art to show instead, or nil.
+func (s *Service) fallbackArt(ctx context.Context, r *resolvedPlace) *database.Location {
+	f := s.Fallback
+	if f == nil || f.Store == nil {
+		return nil
+	}
+	// A stale location being regenerated still has its previous art
+	if loc, err := s.DB.GetLocation(ctx, r.ID); err == nil && loc != nil && servableArt(loc) {
+		return loc
+	}
+	if r.Place.CountryCode != "" {
+		locs, err := f.Store.GetLocationsByCountry(ctx, r.Place.CountryCode, fallbackCandidates)
+		if err != nil {
+			progress.Logf(ctx, "Fallback art for '%s' unavailable: %v", r.Place.Name, err)
+		} else if loc := nearest(locs, r); loc != nil {
+			return loc
+		}
+	}
+	if r.Place.Continent == "" {
+		return nil
+	}
+	presets, err := f.Store.GetPresets(ctx)
+	if err != nil {
+		progress.Logf(ctx, "Fallback presets for '%s' unavailable: %v", r.Place.Name, err)
+		return nil
+	}
+	var sameContinent []database.Location
+	for _, p := range presets {
+		if p.Continent == r.Place.Continent {
+			sameContinent = append(sameContinent, p)
+		}
+	}
+	return nearest(sameContinent, r)
+}
+
+// nearest returns the location with servable art closest to the place,
+// preferring ones with coordinates, or nil.
+func nearest(locs []database.Location, r *resolvedPlace) *database.Location {
+	var best *database.Location
+	bestKm := math.Inf(1)
+	for i := range locs {
+		l := &locs[i]
+		if l.ID == r.ID || !servableArt(l) {
+			continue
+		}
+		km := math.MaxFloat64 // Without coordinates, only better than nothing
+		if l.Geo != nil {
+			km = distanceKm(r.Place.Lat, r.Place.Lng, l.Geo.Latitude, l.Geo.Longitude)
+		}
+		if km < bestKm {
+			best, bestKm = l, km
+		}
+	}
+	return best
+}
+
+func servableArt(l *database.Location) bool {
+	return l.ImageURL != "" && !l.IsHidden() && l.Status != database.StatusArchived
+}
+
+// distanceKm is the great-circle distance between two coordinates.
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat, dLng := (lat2-lat1)*rad, (lng2-lng1)*rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,93 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/maps"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+type fakeFallbackStore struct {
+	byCountry map[string][]database.Location
+	presets   []database.Location
+}
+
+func (f *fakeFallbackStore) GetLocationsByCountry(ctx context.Context, code string, limit int) ([]database.Location, error) {
+	return f.byCountry[code], nil
+}
+func (f *fakeFallbackStore) GetPresets(ctx context.Context) ([]database.Location, error) {
+	return f.presets, nil
+}
+
+func at(id string, lat, lng float64) database.Location {
+	return database.Location{ID: id, Name: id, ImageURL: "https://img/" + id + ".png", VideoURL: "https://vid/" + id + ".mp4", Geo: &latlng.LatLng{Latitude: lat, Longitude: lng}}
+}
+
+func TestSendFallback(t *testing.T) {
+	// Lyon
+	place := &resolvedPlace{ID: "lyon_france", Place: &maps.Place{Name: "Lyon, France", Lat: 45.76, Lng: 4.84, CountryCode: "FR", Continent: maps.Europe}}
+	hidden := at("marseille", 43.30, 5.37)
+	hidden.Status = database.StatusHiddenPendingReview
+	store := &fakeFallbackStore{
+		byCountry: map[string][]database.Location{"FR": {at("paris", 48.86, 2.35), hidden, at("nice", 43.70, 7.27)}},
+		presets:   []database.Location{{ID: "tokyo", Name: "tokyo", ImageURL: "https://img/tokyo.png", Continent: maps.Asia}, {ID: "berlin", Name: "berlin", ImageURL: "https://img/berlin.png", Continent: maps.Europe}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		previous *database.Location
+		place    *maps.Place
+		fallback *FallbackMedia
+		shown    string
+		image    string
+	}{
+		{"previous art", &database.Location{ID: "lyon_france", ImageURL: "https://img/old.png"}, place.Place, &FallbackMedia{Store: store}, "the previous image", "https://img/old.png"},
+		{"nearest in country", nil, place.Place, &FallbackMedia{Store: store}, "nice", "https://img/nice.png"},
+		{"preset on continent", nil, &maps.Place{Name: "Bern", CountryCode: "CH", Continent: maps.Europe}, &FallbackMedia{Store: store}, "berlin", "https://img/berlin.png"},
+		{"placeholder", nil, &maps.Place{Name: "Nowhere"}, &FallbackMedia{Store: store, ImageURL: "https://img/banana.png"}, "a placeholder", "https://img/banana.png"},
+		{"nothing", nil, place.Place, nil, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &MockDB{Loc: tc.previous}
+			if tc.previous == nil {
+				db.Err = fmt.Errorf("not found")
+			}
+			svc := NewService(nil, nil, nil, db)
+			svc.Fallback = tc.fallback
+
+			var events []string
+			shown := svc.sendFallback(context.Background(), &resolvedPlace{ID: place.ID, Place: tc.place}, func(event, data string) {
+				events = append(events, event+":"+data)
+			})
+			if shown != tc.shown {
+				t.Errorf("Expected %q shown, got %q", tc.shown, shown)
+			}
+			if tc.image == "" {
+				if len(events) > 0 {
+					t.Errorf("Expected no events, got %v", events)
+				}
+				return
+			}
+			var resp WeatherResponse
+			if len(events) == 0 || json.Unmarshal([]byte(strings.TrimPrefix(events[0], "result:")), &resp) != nil {
+				t.Fatalf("Expected a result first, got %v", events)
+			}
+			if !resp.Fallback || resp.ImageURL != tc.image || resp.ID != "" || resp.City != tc.place.Name {
+				t.Errorf("Unexpected fallback result %+v", resp)
+			}
+		})
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	// Paris to London is about 344km
+	if d := distanceKm(48.8566, 2.3522, 51.5074, -0.1278); d < 340 || d > 348 {
+		t.Errorf("Expected about 344km, got %.0f", d)
+	}
+}
@@ -0,0 +1,429 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/storage"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// GetWeatherFlow runs the web flow as stages, each with an explicit result:
+// resolve (geocode and policy), cache (serve a fresh location), image, then
+// upload (with the partial save) concurrently with sending the image to the
+// client, and finally video, unless image-only mode is on. While the kill
+// switch is on, it stops after the cache stage, see sendMaintenance. The caption runs
+// alongside the image stage. Events go to sendStatus, which is only called
+// from one goroutine at a time; progress reported by deeper layers through
+// ctx is sent too, see withProgress. The first event is "flow", see
+// startFlow.
+func (s *Service) GetWeatherFlow(ctx context.Context, cityQuery, latStr, lngStr string, sendStatus StatusCallback) error {
+	ctx = startFlow(ctx, sendStatus)
+	progress.Logf(ctx, "Weather Flow Started. City: %s, Lat: %s, Lng: %s", cityQuery, latStr, lngStr)
+	send := serialized(sendStatus)
+	ctx = withProgress(ctx, send)
+	// The image and video stages run separate pipelines; record both
+	ctx, _ = costs.Track(ctx)
+
+	r, err := s.resolveStage(ctx, cityQuery, latStr, lngStr, send)
+	if err != nil {
+		return err
+	}
+	halted := s.halted(ctx)
+	if halted != nil {
+		send("status", MaintenanceMessage)
+	}
+	if hit, err := s.cacheStage(ctx, r, send); hit || err != nil {
+		return err
+	}
+	if halted != nil {
+		return s.sendMaintenance(ctx, r, halted, send)
+	}
+	captioned := s.captionStage(ctx, r, send)
+	img, err := s.imageStage(ctx, r, send)
+	r.Caption = <-captioned // Also before returning, so nothing is sent after the flow ends
+	if errors.Is(err, errServedStale) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.Storage == nil {
+		progress.Logf(ctx, "Storage service not available, skipping video generation.")
+		s.sendResult(r, img, send)
+		return nil
+	}
+
+	// The result is a large base64 event to a possibly slow client; upload
+	// and save meanwhile
+	var up *uploadedImage
+	var g errgroup.Group
+	g.Go(func() error {
+		s.sendResult(r, img, send)
+		send("status", "Preparing for animation...")
+		return nil
+	})
+	g.Go(func() error {
+		var err error
+		up, err = s.uploadStage(ctx, r, img)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		progress.Logf(ctx, "Failed to upload image for video gen: %v", err)
+		// We don't error out the user here, they have the image. just log it.
+		s.DB.SetStatus(ctx, r.ID, database.StatusFailed)
+		return nil
+	}
+	if up.Location.AltText != "" {
+		send("alt_text", up.Location.AltText)
+	}
+
+	if s.degraded(ctx) {
+		progress.Logf(ctx, "Image-only mode is on, skipping video generation.")
+		send("status", "Videos are paused for now. Enjoy the image!")
+		up.Location.Status = database.StatusReady
+		s.DB.UpsertLocation(context.WithoutCancel(ctx), up.Location)
+		return nil
+	}
+	send("status", "Animating (Veo 3.1)... this may take a minute.")
+	return s.videoStage(ctx, img, up, send)
+}
+
+// runtime returns the runtime settings, or nil without a source or when
+// they can't be read, in which case generation goes on as usual.
+func (s *Service) runtime(ctx context.Context) *database.RuntimeSettings {
+	if s.Runtime == nil {
+		return nil
+	}
+	rs, err := s.Runtime.GetRuntimeSettings(ctx)
+	if err != nil {
+		progress.Logf(ctx, "Failed to read runtime settings: %v", err)
+		return nil
+	}
+	return rs
+}
+
+// degraded reports whether the runtime settings turned on image-only mode.
+func (s *Service) degraded(ctx context.Context) bool {
+	rs := s.runtime(ctx)
+	return rs != nil && rs.DegradeImageOnly
+}
+
+// startFlow sends the flow's ID as its "flow" event, so a user report can
+// name the flow, and returns ctx carrying it for the flow's logs and stored
+// generation metadata. The ID is the caller's (the server sets one per
+// request, see progress.WithFlowID), or a new one.
+func startFlow(ctx context.Context, send StatusCallback) context.Context {
+	id := progress.FlowID(ctx)
+	if id == "" {
+		id = progress.NewFlowID()
+		ctx = progress.WithFlowID(ctx, id)
+	}
+	send("flow", id)
+	return ctx
+}
+
+// serialized guards send with a mutex, for stages running concurrently.
+func serialized(send StatusCallback) StatusCallback {
+	var mu sync.Mutex
+	return func(event, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		send(event, data)
+	}
+}
+
+// withProgress sends the progress updates emitted through ctx as a "status"
+// event with the rendered text, for clients showing status lines, followed by
+// a "progress" event with the progress.Update as JSON.
+func withProgress(ctx context.Context, send StatusCallback) context.Context {
+	return progress.WithEmitter(ctx, func(u progress.Update) {
+		send("status", u.String())
+		b, _ := json.Marshal(u)
+		send("progress", string(b))
+	})
+}
+
+// resolvedPlace is the result of the resolve stage.
+type resolvedPlace struct {
+	ID            string // Location ID
+	Place         *maps.Place
+	Caption       string             // Set once the caption stage is done
+	VideoPrompt   string             // Of the stored location, kept when it's regenerated
+	PromptContext string             // Of the stored location, used and kept when it's regenerated
+	Stale         *database.Location // The stored location when it's being regenerated, served if that fails
+}
+
+// resolveStage geocodes the query and applies the location policy.
+func (s *Service) resolveStage(ctx context.Context, cityQuery, latStr, lngStr string, send StatusCallback) (*resolvedPlace, error) {
+	place, err := s.resolvePlace(ctx, cityQuery, latStr, lngStr, send)
+	if err != nil {
+		return nil, err
+	}
+	return &resolvedPlace{ID: sanitizeID(place.Name), Place: place}, nil
+}
+
+// cacheStage serves the stored location when it's fresh, and reports whether
+// it did. Locations hidden by user reports are an error: they're neither
+// served nor regenerated over before review.
+func (s *Service) cacheStage(ctx context.Context, r *resolvedPlace, send StatusCallback) (bool, error) {
+	start := time.Now()
+	cachedLoc, err := s.DB.GetLocation(ctx, r.ID)
+	if err != nil || cachedLoc == nil {
+		s.recordLatency(ctx, database.LatencyCache, database.LatencyMiss, r.ID, start)
+		return false, nil
+	}
+	if cachedLoc.IsHidden() {
+		progress.Logf(ctx, "Location %s is hidden pending review", r.ID)
+		send("error", "This location is temporarily unavailable.")
+		return false, fmt.Errorf("location %s is hidden pending review", r.ID)
+	}
+	// Hand-made and locked media never goes stale
+	if !cachedLoc.KeepsMedia() && !s.fresh(cachedLoc.LastUpdated) {
+		r.VideoPrompt, r.PromptContext = cachedLoc.VideoPrompt, cachedLoc.PromptContext
+		r.Stale = cachedLoc
+		s.recordLatency(ctx, database.LatencyCache, database.LatencyMiss, r.ID, start)
+		return false, nil
+	}
+
+	progress.Logf(ctx, "Cache Hit for %s", r.Place.Name)
+	send("status", "Loading cached forecast...")
+	resp := WeatherResponse{
+		ID:          r.ID,
+		City:        model.Localize(r.Place.Name, cachedLoc.NameI18n, genai.LanguageFrom(ctx)),
+		ImageURL:    cachedLoc.ImageURL,
+		LastUpdated: cachedLoc.LastUpdated,
+		AltText:     cachedLoc.AltText,
+	}
+	resp.observedAt(cachedLoc)
+	if cachedLoc.Generation != nil {
+		// The cached image keeps the language it was rendered in
+		resp.Lang = cachedLoc.Generation.Lang
+	}
+	jsonData, _ := json.Marshal(resp)
+	send("result", string(jsonData))
+	if cachedLoc.Caption != "" && s.Captioner != nil {
+		send("caption", cachedLoc.Caption)
+	}
+	if cachedLoc.VideoURL != "" {
+		send("video", cachedLoc.VideoURL)
+	}
+	s.recordLatency(ctx, database.LatencyCache, database.LatencyHit, r.ID, start)
+	return true, nil
+}
+
+// generatedImage is the result of the image stage.
+type generatedImage struct {
+	Image    *genai.ImageResult // Stamped with content credentials
+	Seed     int32
+	Current  *openmeteo.Current     // Observed weather, when known
+	Check    *database.WeatherCheck // With WEATHER_CHECK, see generateImage
+	FileName string                 // Object name of the image; the poster and stream are named after it
+	At       time.Time
+	Usage    []database.ModelUsage // Billed model calls, including rejected images
+}
+
+type styleKey struct{}
+
+// WithStyle returns a context whose web flows use prompt style (see
+// RefreshOptions.Style) instead of a random one, to reproduce a generation.
+func WithStyle(ctx context.Context, style int) context.Context {
+	return context.WithValue(ctx, styleKey{}, style)
+}
+
+func styleFrom(ctx context.Context) int {
+	style, _ := ctx.Value(styleKey{}).(int)
+	return style
+}
+
+// imageStage generates the image, with the random prompt style unless ctx
+// sets one (WithStyle). The seed is picked up front so the generation can be
+// reproduced from the DB record.
+// The observed weather, when known, is sent first as the "forecast" event.
+func (s *Service) imageStage(ctx context.Context, r *resolvedPlace, send StatusCallback) (*generatedImage, error) {
+	send("status", fmt.Sprintf("Getting a banana image of the weather for %s...", r.Place.Name))
+	// Nothing is persisted without storage, so only track status when it's available
+	if s.Storage != nil {
+		s.DB.SetStatus(ctx, r.ID, database.StatusGenerating)
+	}
+
+	out := &generatedImage{FileName: fmt.Sprintf("image_%d.png", s.now().UnixNano())}
+	out.Current = s.observe(ctx, r.Place.Name, r.Place.LatLng())
+	if out.Current != nil {
+		jsonData, _ := json.Marshal(newForecast(out.Current))
+		send("forecast", string(jsonData))
+	}
+	start := time.Now()
+	var genErr error
+	res, err := s.pipeline().Generate(ctx, pipeline.Request{ID: r.ID, City: r.Place.Name, Context: r.PromptContext, FileName: out.FileName},
+		pipeline.SkipUpload(),
+		pipeline.WithImageFunc(func(ctx context.Context, req pipeline.Request, seed *int32) (*genai.ImageResult, error) {
+			// Use the formatted name to ensure the AI gets the full context
+			img, c, err := s.generateImage(ctx, r.Place.Name, req.Context, styleFrom(ctx), seed, out.Current)
+			out.Check, genErr = c, err
+			return img, err
+		}),
+	)
+	if err != nil {
+		progress.Logf(ctx, "Error generating image for '%s': %v", r.Place.Name, err)
+		s.recordLatency(ctx, database.LatencyImage, database.LatencyError, r.ID, start)
+		if s.sendStale(ctx, r, send) {
+			return nil, errServedStale
+		}
+		msg := err.Error()
+		if genErr != nil {
+			msg = genErr.Error()
+		}
+		if shown := s.sendFallback(ctx, r, send); shown != "" {
+			msg += ". Showing " + shown + " instead"
+		}
+		send("error", "Failed to generate image: "+msg)
+		if s.Storage != nil {
+			s.DB.SetStatus(ctx, r.ID, database.StatusFailed)
+		}
+		return nil, err
+	}
+	progress.Logf(ctx, "Successfully generated image for: %s", r.Place.Name)
+	s.recordLatency(ctx, database.LatencyImage, database.LatencyOK, r.ID, start)
+	out.Image, out.Seed, out.At, out.Usage = res.Image, res.Seed, s.now(), res.Usage
+	return out, nil
+}
+
+// sendResult sends the image to the client as base64, so it shows before
+// the upload finishes.
+func (s *Service) sendResult(r *resolvedPlace, img *generatedImage, send StatusCallback) {
+	resp := WeatherResponse{
+		ID:          r.ID,
+		City:        r.Place.Name,
+		ImageBase64: img.Image.Image(),
+		LastUpdated: img.At,
+		Lang:        img.Image.Lang,
+	}
+	if cur := img.Current; cur != nil {
+		temp := cur.TemperatureC
+		resp.Mood, resp.Condition, resp.TemperatureC, resp.Forecast = string(cur.Mood()), string(cur.Condition), &temp, cur.Days
+	}
+	jsonData, _ := json.Marshal(resp)
+	send("result", string(jsonData))
+}
+
+// uploadedImage is the result of the upload stage.
+type uploadedImage struct {
+	Location database.Location // As saved, generating until the video stage
+	ImageURI string            // gs:// URI Veo reads
+}
+
+// uploadStage uploads the image and saves the location with it (the partial
+// save), so it's servable while Veo runs. The alt text is written meanwhile.
+func (s *Service) uploadStage(ctx context.Context, r *resolvedPlace, img *generatedImage) (*uploadedImage, error) {
+	described := make(chan string, 1)
+	go func() { described <- s.pipeline().Describe(ctx, img.Image, r.Place.Name) }()
+	uri, url, err := s.pipeline().Upload(ctx, img.Image, img.FileName)
+	altText := <-described
+	if err != nil {
+		return nil, err
+	}
+	loc := database.Location{
+		ID:            r.ID,
+		Name:          r.Place.Name,
+		CityQuery:     r.Place.Name,
+		ImageURL:      url,
+		IsPreset:      false,
+		Seed:          &img.Seed,
+		Generation:    img.Image.Metadata(),
+		CountryCode:   r.Place.CountryCode,
+		Continent:     r.Place.Continent,
+		Geo:           r.Place.LatLng(),
+		Caption:       r.Caption,
+		AltText:       altText,
+		VideoPrompt:   r.VideoPrompt,
+		PromptContext: r.PromptContext,
+		Status:        database.StatusGenerating, // Video still pending
+		LastUpdated:   s.now(),
+	}
+	loc.Generation.Usage = img.Usage
+	loc.Generation.FlowID = progress.FlowID(ctx)
+	loc.RecordChecksums(map[string]string{url: storage.ImageCRC32C(img.Image.Image())})
+	applyWeather(&loc, img.Current, img.Check)
+	s.DB.UpsertLocation(ctx, loc)
+	return &uploadedImage{Location: loc, ImageURI: uri}, nil
+}
+
+// videoStage animates the uploaded image and saves the location as ready,
+// with the video when Veo succeeded. With DetachVideo it runs to completion
+// even if the client goes away, so the video isn't wasted.
+func (s *Service) videoStage(ctx context.Context, img *generatedImage, up *uploadedImage, send StatusCallback) error {
+	if s.DetachVideo {
+		ctx = context.WithoutCancel(ctx)
+	}
+	loc := up.Location
+	start := time.Now()
+	res, err := s.pipeline().Generate(ctx, pipeline.Request{ID: loc.ID, City: loc.Name, FileName: img.FileName},
+		pipeline.FromImage(up.ImageURI), pipeline.WithSeed(img.Seed), pipeline.WithVideoPrompt(loc.VideoPrompt))
+	if errors.Is(err, pipeline.ErrVideo) {
+		progress.Logf(ctx, "Veo generation failed: %v", err)
+		s.recordLatency(ctx, database.LatencyVideo, database.LatencyError, loc.ID, start)
+		send("error", "Video generation failed (Beta). Enjoy the image!")
+		// The image alone is still servable. Veo may have failed because the
+		// client went away, so don't let the request context cancel the write.
+		loc.Status = database.StatusReady
+		s.DB.UpsertLocation(context.WithoutCancel(ctx), loc)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.recordLatency(ctx, database.LatencyVideo, database.LatencyOK, loc.ID, start)
+	send("status", "Finalizing video...")
+	progress.Logf(ctx, "Video available at: %s", res.VideoURL)
+	if res.PosterURL != "" {
+		send("poster", res.PosterURL) // Before the video, so the client can show it while loading
+	}
+	if res.StreamURL != "" {
+		send("stream", res.StreamURL)
+	}
+	send("video", res.VideoURL)
+
+	loc.VideoURL = res.VideoURL
+	loc.PosterURL = res.PosterURL
+	loc.StreamURL = res.StreamURL
+	loc.RecordChecksums(res.Checksums)
+	if loc.Generation != nil {
+		loc.Generation.Usage = res.Usage // The image's calls plus Veo
+	}
+	loc.Status = database.StatusReady
+	s.DB.UpsertLocation(ctx, loc)
+	return nil
+}
+
+// recordLatency saves how long a stage took, for SLO reports, when Latency
+// is set. It's best effort, and written even if the client went away.
+func (s *Service) recordLatency(ctx context.Context, stage, outcome, id string, start time.Time) {
+	if s.Latency == nil {
+		return
+	}
+	err := s.Latency.AddLatencySample(context.WithoutCancel(ctx), database.LatencySample{
+		Stage:      stage,
+		Outcome:    outcome,
+		DurationMS: time.Since(start).Milliseconds(),
+		LocationID: id,
+		CreatedAt:  s.now(),
+	})
+	if err != nil {
+		progress.Logf(ctx, "Failed to record %s latency: %v", stage, err)
+	}
+}
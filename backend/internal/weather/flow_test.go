@@ -0,0 +1,529 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/progress"
+)
+
+func TestUploadStage_PartialSave(t *testing.T) {
+	ctx := context.Background()
+	db := &MockDB{}
+	svc := NewService(&MockMapService{}, &MockGenAI{ImageBase64: "base64data"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+
+	r := &resolvedPlace{ID: "oslo_norway", Place: &maps.Place{Name: "Oslo, Norway", CountryCode: "NO"}}
+	img, err := svc.imageStage(ctx, r, func(event, data string) {})
+	if err != nil {
+		t.Fatalf("imageStage: %v", err)
+	}
+	if db.Saved != nil {
+		t.Fatal("Expected nothing saved before the upload stage")
+	}
+
+	up, err := svc.uploadStage(ctx, r, img)
+	if err != nil {
+		t.Fatalf("uploadStage: %v", err)
+	}
+	if up.ImageURI != "gs://bucket/image.png" {
+		t.Errorf("Expected the gs:// URI for Veo, got %q", up.ImageURI)
+	}
+	if db.Saved == nil || db.Saved.Status != database.StatusGenerating || db.Saved.ImageURL != "http://storage/image.png" {
+		t.Fatalf("Expected a partial save with the image, got %+v", db.Saved)
+	}
+	if db.Saved.Seed == nil || *db.Saved.Seed != img.Seed || db.Saved.CountryCode != "NO" {
+		t.Errorf("Expected the seed and place on the partial save, got %+v", db.Saved)
+	}
+}
+
+func TestImageStage_Style(t *testing.T) {
+	gen := &MockGenAI{ImageBase64: "base64data"}
+	svc := NewService(&MockMapService{}, gen, nil, &MockDB{})
+	r := &resolvedPlace{ID: "lagos_nigeria", Place: &maps.Place{Name: "Lagos, Nigeria"}}
+
+	if _, err := svc.imageStage(context.Background(), r, func(event, data string) {}); err != nil || gen.LastStyle != 0 {
+		t.Fatalf("Expected the random style by default, got %d (%v)", gen.LastStyle, err)
+	}
+	if _, err := svc.imageStage(WithStyle(context.Background(), 1), r, func(event, data string) {}); err != nil || gen.LastStyle != 1 {
+		t.Errorf("Expected the classic style from the context, got %d (%v)", gen.LastStyle, err)
+	}
+}
+
+func TestResolvePlace_DefaultCity(t *testing.T) {
+	m := &MockMapService{}
+	svc := NewService(m, &MockGenAI{}, nil, &MockDB{})
+	noop := func(event, data string) {}
+
+	if _, err := svc.resolvePlace(context.Background(), "", "", "", noop); err != nil || m.LastQuery != DefaultCity {
+		t.Errorf("Expected %q looked up, got %q (%v)", DefaultCity, m.LastQuery, err)
+	}
+	svc.DefaultCity = "Lagos"
+	if _, err := svc.resolvePlace(context.Background(), "", "", "", noop); err != nil || m.LastQuery != "Lagos" {
+		t.Errorf("Expected the configured default city looked up, got %q (%v)", m.LastQuery, err)
+	}
+	if svc.resolvePlace(context.Background(), "Oslo", "", "", noop); m.LastQuery != "Oslo" {
+		t.Errorf("Expected the query looked up, got %q", m.LastQuery)
+	}
+}
+
+func TestUploadStage_Error(t *testing.T) {
+	db := &MockDB{}
+	svc := NewService(&MockMapService{}, &MockGenAI{ImageBase64: "base64data"}, &MockStorage{Err: fmt.Errorf("bucket gone")}, db)
+
+	r := &resolvedPlace{ID: "oslo_norway", Place: &maps.Place{Name: "Oslo, Norway"}}
+	img, err := svc.imageStage(context.Background(), r, func(event, data string) {})
+	if err != nil {
+		t.Fatalf("imageStage: %v", err)
+	}
+	if _, err := svc.uploadStage(context.Background(), r, img); err == nil {
+		t.Fatal("Expected the upload error")
+	}
+	if db.Saved != nil {
+		t.Error("Expected no partial save without an image URL")
+	}
+}
+
+func TestVideoStage_Detached(t *testing.T) {
+	for _, detach := range []bool{false, true} {
+		t.Run(fmt.Sprint("detach=", detach), func(t *testing.T) {
+			db := &MockDB{}
+			svc := NewService(&MockMapService{}, &MockGenAI{VideoURI: "gs://bucket/video.mp4"}, &MockStorage{}, db)
+			svc.DetachVideo = detach
+
+			// The client went away while the image was uploading
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			img := &generatedImage{Seed: 7, FileName: "image_1.png"}
+			up := &uploadedImage{
+				Location: database.Location{ID: "oslo_norway", Name: "Oslo, Norway", Status: database.StatusGenerating},
+				ImageURI: "gs://bucket/image_1.png",
+			}
+			if err := svc.videoStage(ctx, img, up, func(event, data string) {}); err != nil {
+				t.Fatalf("videoStage: %v", err)
+			}
+			if db.Saved == nil || db.Saved.Status != database.StatusReady {
+				t.Fatalf("Expected the location saved as ready, got %+v", db.Saved)
+			}
+			if detach && db.SaveCtxErr != nil {
+				t.Errorf("Expected a detached save, got ctx error %v", db.SaveCtxErr)
+			}
+			if !detach && db.SaveCtxErr == nil {
+				t.Error("Expected the save to see the cancelled request context")
+			}
+		})
+	}
+}
+
+func TestGetWeatherFlow_ResultBeforeAnimating(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+
+	var events []string
+	err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result := slices.IndexFunc(events, func(e string) bool { return len(e) > 7 && e[:7] == "result:" })
+	animating := slices.Index(events, "status:Animating (Veo 3.1)... this may take a minute.")
+	video := slices.Index(events, "video:https://storage.googleapis.com/bucket/video.mp4")
+	if result < 0 || animating < result || video < animating {
+		t.Errorf("Expected result, then animating, then video; got %v", events)
+	}
+}
+
+func TestGetWeatherFlow_FlowID(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+
+	// The caller's ID is kept
+	var events []string
+	ctx := progress.WithFlowID(context.Background(), "f00d")
+	if err := svc.GetWeatherFlow(ctx, "London", "", "", func(event, data string) { events = append(events, event+":"+data) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || events[0] != "flow:f00d" {
+		t.Errorf("Expected the flow ID first, got %v", events)
+	}
+	if db.Saved == nil || db.Saved.Generation == nil || db.Saved.Generation.FlowID != "f00d" {
+		t.Errorf("Expected the flow ID in the saved generation, got %+v", db.Saved)
+	}
+
+	// Otherwise the flow gets one
+	var first []string
+	svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		if first == nil {
+			first = []string{event, data}
+		}
+	})
+	if first[0] != "flow" || len(first[1]) != 16 {
+		t.Errorf("Expected a new flow ID first, got %v", first)
+	}
+}
+
+// MockForecaster is MockConditions with a forecast of up to len(Days) days.
+type MockForecaster struct {
+	MockConditions
+	Days []openmeteo.Day
+}
+
+func (m *MockForecaster) Forecast(ctx context.Context, lat, lng float64, days int) (*openmeteo.Current, error) {
+	m.Calls++
+	cur := *m.Observed
+	cur.Days = m.Days[:min(days, len(m.Days))]
+	return &cur, nil
+}
+
+func TestGetWeatherFlow_WeatherInPrompt(t *testing.T) {
+	days := []openmeteo.Day{
+		{Date: "2026-10-15", Condition: openmeteo.ConditionRain, HighC: 14.2, LowC: 7.6, PrecipitationChance: 80},
+		{Date: "2026-10-16", Condition: openmeteo.ConditionCloudy, HighC: 15, LowC: 9},
+		{Date: "2026-10-17", Condition: openmeteo.ConditionClear, HighC: 18, LowC: 8},
+		{Date: "2026-10-18", Condition: openmeteo.ConditionClear, HighC: 19, LowC: 9},
+	}
+	conditions := &MockForecaster{MockConditions: MockConditions{Observed: &openmeteo.Current{Condition: openmeteo.ConditionRain, TemperatureC: 9.4, IsDay: true}}, Days: days}
+	gen := &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, gen, &MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	svc.Conditions = conditions
+	svc.WeatherInPrompt = true
+
+	var forecast Forecast
+	var result WeatherResponse
+	err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		switch event {
+		case "forecast":
+			json.Unmarshal([]byte(data), &forecast)
+		case "result":
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Current weather: rain, 9°C, day. Today: rain, 8°C to 14°C, 80% chance of precipitation. Mood: " + openmeteo.MoodCozyRain.Adjectives()
+	if gen.LastExtra != want || !gen.LastWeatherData || gen.LastMode != genai.GroundingSearch {
+		t.Errorf("extra = %q (weather data %v, mode %q), want %q", gen.LastExtra, gen.LastWeatherData, gen.LastMode, want)
+	}
+	if len(forecast.Days) != forecastDays || !reflect.DeepEqual(result.Forecast, days[:forecastDays]) {
+		t.Errorf("Expected %d forecast days, got %+v and %+v", forecastDays, forecast.Days, result.Forecast)
+	}
+	if result.Condition != "rain" || result.TemperatureC == nil || *result.TemperatureC != 9.4 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// A cache hit serves the weather its media was generated for, without a forecast
+	cached := *db.Saved
+	cached.LastUpdated = time.Now()
+	svc.DB = &MockDB{Loc: &cached}
+	result = WeatherResponse{}
+	if err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		if event == "result" {
+			json.Unmarshal([]byte(data), &result)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if result.Condition != "rain" || result.TemperatureC == nil || *result.TemperatureC != 9.4 || result.Forecast != nil || result.Mood != "cozy-rain" {
+		t.Errorf("cached result = %+v", result)
+	}
+}
+
+func TestGetWeatherFlow_Mood(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	svc.Conditions = &MockConditions{Observed: &openmeteo.Current{Condition: openmeteo.ConditionSnow, TemperatureC: -4, IsDay: true}}
+
+	var events []string
+	var forecast Forecast
+	var result WeatherResponse
+	err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		events = append(events, event)
+		switch event {
+		case "forecast":
+			json.Unmarshal([]byte(data), &forecast)
+		case "result":
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, r := slices.Index(events, "forecast"), slices.Index(events, "result"); f < 0 || r < f {
+		t.Errorf("Expected the forecast before the result, got %v", events)
+	}
+	if !reflect.DeepEqual(forecast, Forecast{Condition: openmeteo.ConditionSnow, TemperatureC: -4, IsDay: true, Mood: openmeteo.MoodCrispSnow}) {
+		t.Errorf("forecast = %+v", forecast)
+	}
+	if result.Mood != "crisp-snow" || db.Saved == nil || db.Saved.Mood != "crisp-snow" {
+		t.Errorf("result mood = %q, saved %+v", result.Mood, db.Saved)
+	}
+	if db.Saved.TemperatureC == nil || *db.Saved.TemperatureC != -4 {
+		t.Errorf("saved temperature = %v, want -4", db.Saved.TemperatureC)
+	}
+
+	// Without observed weather there's no forecast, and no stale mood
+	svc.Conditions = nil
+	events = nil
+	db.Saved.Mood = "stormy"
+	if err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) { events = append(events, event) }); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(events, "forecast") || db.Saved.Mood != "" || db.Saved.TemperatureC != nil {
+		t.Errorf("events %v, saved mood %q", events, db.Saved.Mood)
+	}
+}
+
+type mockCaptioner struct {
+	caption string
+	err     error
+	calls   int
+}
+
+func (m *mockCaptioner) Caption(ctx context.Context, city string) (string, error) {
+	m.calls++
+	return m.caption, m.err
+}
+
+func TestGetWeatherFlow_Caption(t *testing.T) {
+	stale := &database.Location{ID: "paris_france", Caption: "The City of Light"}
+	tests := []struct {
+		name      string
+		db        *MockDB
+		captioner *mockCaptioner
+		want      string
+		wantCalls int
+	}{
+		{"new", &MockDB{Err: fmt.Errorf("not found")}, &mockCaptioner{caption: "The City of Light"}, "The City of Light", 1},
+		{"kept across regenerations", &MockDB{Loc: stale}, &mockCaptioner{caption: "Other"}, "The City of Light", 0},
+		{"failed", &MockDB{Err: fmt.Errorf("not found")}, &mockCaptioner{err: fmt.Errorf("quota")}, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&MockMapService{ResolvedCity: "Paris, France"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+				&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, tt.db)
+			svc.Captioner = tt.captioner
+
+			var captions []string
+			err := svc.GetWeatherFlow(context.Background(), "Paris", "", "", func(event, data string) {
+				if event == "caption" {
+					captions = append(captions, data)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.captioner.calls != tt.wantCalls {
+				t.Errorf("captioned %d times, want %d", tt.captioner.calls, tt.wantCalls)
+			}
+			if tt.want == "" && len(captions) > 0 || tt.want != "" && !slices.Equal(captions, []string{tt.want}) {
+				t.Errorf("caption events = %q, want %q", captions, tt.want)
+			}
+			if tt.db.Saved == nil || tt.db.Saved.Caption != tt.want {
+				t.Errorf("saved %+v", tt.db.Saved)
+			}
+		})
+	}
+
+	// Cache hits send the stored caption
+	db := &MockDB{Loc: &database.Location{ID: "paris_france", Caption: "The City of Light", LastUpdated: time.Now()}}
+	svc := NewService(&MockMapService{ResolvedCity: "Paris, France"}, &MockGenAI{}, &MockStorage{}, db)
+	svc.Captioner = &mockCaptioner{}
+	var events []string
+	if err := svc.GetWeatherFlow(context.Background(), "Paris", "", "", func(event, data string) { events = append(events, event+":"+data) }); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(events, "caption:The City of Light") {
+		t.Errorf("events = %v", events)
+	}
+}
+
+type mockDescriber struct{}
+
+func (mockDescriber) DescribeImage(ctx context.Context, imageBase64, city string) (string, error) {
+	return "Snowy rooftops of " + city, nil
+}
+
+func TestGetWeatherFlow_AltText(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	svc.Describer = mockDescriber{}
+
+	var events []string
+	if err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) { events = append(events, event+":"+data) }); err != nil {
+		t.Fatal(err)
+	}
+	alt := slices.Index(events, "alt_text:Snowy rooftops of Oslo, Norway")
+	video := slices.IndexFunc(events, func(e string) bool { return strings.HasPrefix(e, "video:") })
+	if alt < 0 || video < alt {
+		t.Errorf("Expected the alt text before the video, got %v", events)
+	}
+	if db.Saved == nil || db.Saved.AltText != "Snowy rooftops of Oslo, Norway" {
+		t.Errorf("saved %+v", db.Saved)
+	}
+
+	// Cache hits have it in the result
+	db = &MockDB{Loc: &database.Location{ID: "oslo_norway", AltText: "Snowy rooftops", LastUpdated: time.Now()}}
+	svc = NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{}, &MockStorage{}, db)
+	var result WeatherResponse
+	svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		if event == "result" {
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if result.AltText != "Snowy rooftops" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// progressGenAI reports Veo progress through the context, like genai's polling.
+type progressGenAI struct {
+	*MockGenAI
+}
+
+func (m progressGenAI) GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error) {
+	progress.Emit(ctx, progress.Update{Stage: "video", Message: "Animating (Veo 3.1)", Percent: 40})
+	return m.MockGenAI.GenerateVideo(ctx, inputImageURI, prompt, seed)
+}
+
+func TestGetWeatherFlow_Progress(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	g := progressGenAI{&MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, g,
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+
+	var events []string
+	err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	status := slices.Index(events, "status:Animating (Veo 3.1) 40%")
+	if status < 0 || status+1 >= len(events) || events[status+1] != `progress:{"stage":"video","message":"Animating (Veo 3.1)","percent":40}` {
+		t.Errorf("Expected a status line followed by the progress event, got %v", events)
+	}
+}
+
+type fakeLatency struct {
+	mu      sync.Mutex
+	samples []database.LatencySample
+}
+
+func (f *fakeLatency) AddLatencySample(ctx context.Context, s database.LatencySample) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.samples = append(f.samples, s)
+	return nil
+}
+
+func TestGetWeatherFlow_RecordsLatency(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	lat := &fakeLatency{}
+	svc.Latency = lat
+
+	if err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got []string
+	for _, s := range lat.samples {
+		got = append(got, s.Stage+":"+s.Outcome)
+	}
+	if want := []string{"cache:miss", "image:ok", "video:ok"}; !slices.Equal(got, want) {
+		t.Errorf("Expected samples %v, got %v", want, got)
+	}
+}
+
+type fakeRuntime struct{ database.RuntimeSettings }
+
+func (f *fakeRuntime) GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error) {
+	return &f.RuntimeSettings, nil
+}
+
+func TestGetWeatherFlow_ImageOnly(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	g := &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, g,
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	svc.Runtime = &fakeRuntime{database.RuntimeSettings{DegradeImageOnly: true}}
+
+	var events []string
+	err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if slices.ContainsFunc(events, func(e string) bool { return len(e) > 6 && e[:6] == "video:" }) {
+		t.Errorf("Expected no video in image-only mode, got %v", events)
+	}
+	if db.Saved == nil || db.Saved.Status != database.StatusReady || db.Saved.VideoURL != "" {
+		t.Errorf("Expected the image saved as ready, got %+v", db.Saved)
+	}
+}
+
+type fakeStaticMaps struct{}
+
+func (fakeStaticMaps) StaticMap(ctx context.Context, lat, lng float64) ([]byte, error) {
+	return []byte("png"), nil
+}
+
+func TestGetWeatherFlow_StaticMapFallback(t *testing.T) {
+	db := &MockDB{Err: fmt.Errorf("not found")}
+	svc := NewService(&MockMapService{ResolvedCity: "London, UK"}, &MockGenAI{Err: fmt.Errorf("quota exceeded")},
+		&MockStorage{PublicURL: "http://storage/image.png", GsURI: "gs://bucket/image.png"}, db)
+	svc.StaticMaps = fakeStaticMaps{}
+
+	var events []string
+	if err := svc.GetWeatherFlow(context.Background(), "London", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	}); err == nil {
+		t.Fatal("Expected the image error")
+	}
+	if len(events) < 2 || events[len(events)-1] != "error:Failed to generate image: quota exceeded. Showing a map instead" {
+		t.Fatalf("Expected the error after the map, got %v", events)
+	}
+	var resp WeatherResponse
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(events[len(events)-2], "result:")), &resp); err != nil || !resp.Fallback || resp.ImageBase64 != "cG5n" || resp.ID != "" {
+		t.Errorf("Expected an unstored fallback result, got %+v (%v)", resp, err)
+	}
+	if db.Saved != nil && db.Saved.ImageURL != "" {
+		t.Errorf("Expected the map not to be saved, got %+v", db.Saved)
+	}
+}
+
+func TestGetWeatherFlow_Lang(t *testing.T) {
+	// Cache hits localize the city and report the cached image's language
+	db := &MockDB{Loc: &database.Location{ID: "oslo_norway", LastUpdated: time.Now(),
+		NameI18n:   map[string]string{"ja": "オスロ"},
+		Generation: &database.GenerationMetadata{Lang: "en"}}}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{}, &MockStorage{}, db)
+	var result WeatherResponse
+	svc.GetWeatherFlow(genai.WithLanguage(context.Background(), "ja-JP"), "Oslo", "", "", func(event, data string) {
+		if event == "result" {
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if result.City != "オスロ" || result.Lang != "en" {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -0,0 +1,57 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/progress"
+)
+
+// MaintenanceMessage is the status line of web flows while the kill switch
+// is on.
+const MaintenanceMessage = "New forecasts are paused for maintenance, so you're seeing the latest one we have."
+
+// ErrHalted is returned by generations refused because the kill switch is
+// on.
+var ErrHalted = errors.New("generation is paused by the kill switch")
+
+// halted returns the active kill switch of the service's tenant, or nil.
+func (s *Service) halted(ctx context.Context) *database.KillSwitch {
+	rs := s.runtime(ctx)
+	if rs == nil {
+		return nil
+	}
+	return rs.Halted(s.Tenant, s.now())
+}
+
+// checkHalted returns ErrHalted, with the switch's reason, while the kill
+// switch is on.
+func (s *Service) checkHalted(ctx context.Context) error {
+	k := s.halted(ctx)
+	if k == nil {
+		return nil
+	}
+	if k.Reason == "" {
+		return ErrHalted
+	}
+	return fmt.Errorf("%w: %s", ErrHalted, k.Reason)
+}
+
+// sendMaintenance ends a web flow that missed the cache while the kill switch
+// is on: it serves the stale location as it is, without scheduling a
+// refresh, or else the fallback media. Nothing is generated or stored. With
+// nothing to show, the flow fails.
+func (s *Service) sendMaintenance(ctx context.Context, r *resolvedPlace, k *database.KillSwitch, send StatusCallback) error {
+	progress.Logf(ctx, "Kill switch is on (%s), not generating %s", k.Reason, r.ID)
+	if s.sendStaleMedia(ctx, r, MaintenanceMessage, send) {
+		return nil
+	}
+	if shown := s.sendFallback(ctx, r, send); shown != "" {
+		send("status", MaintenanceMessage)
+		return nil
+	}
+	send("error", "New forecasts are paused for maintenance. Please try again later.")
+	return ErrHalted
+}
@@ -0,0 +1,102 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+func haltedService(db LocationRepo, settings database.RuntimeSettings) *Service {
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{Err: errors.New("generation must not run")}, &MockStorage{}, db)
+	svc.Runtime = &fakeRuntime{settings}
+	return svc
+}
+
+func TestGetWeatherFlow_KillSwitch(t *testing.T) {
+	on := database.RuntimeSettings{KillSwitch: database.KillSwitch{On: true, Reason: "veo quota incident"}}
+
+	// A stale location is served as it is, without a background refresh
+	stale := &database.Location{ID: "oslo__norway", Name: "Oslo, Norway", ImageURL: "https://img/old.png", VideoURL: "https://vid/old.mp4",
+		LastUpdated: time.Now().Add(-5 * time.Hour)}
+	db := &MockDB{Loc: stale}
+	var events []string
+	var result WeatherResponse
+	err := haltedService(db, on).GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+		if event == "result" {
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected the stale media served, got %v", err)
+	}
+	if !slices.Contains(events, "status:"+MaintenanceMessage) || !slices.Contains(events, "video:https://vid/old.mp4") ||
+		slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "error:") }) {
+		t.Errorf("Unexpected events: %v", events)
+	}
+	if !result.Stale || result.ImageURL != stale.ImageURL {
+		t.Errorf("result = %+v", result)
+	}
+	if db.LastStatus != "" || db.Saved != nil {
+		t.Errorf("Expected nothing written, got status %q and %+v", db.LastStatus, db.Saved)
+	}
+
+	// A fresh location is a cache hit, annotated
+	fresh := *stale
+	fresh.LastUpdated = time.Now()
+	events = nil
+	if err := haltedService(&MockDB{Loc: &fresh}, on).GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(events, "status:"+MaintenanceMessage) || !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "result:") }) {
+		t.Errorf("Unexpected events: %v", events)
+	}
+
+	// Without anything to show, the flow fails
+	events = nil
+	err = haltedService(&MockDB{Err: errors.New("not found")}, on).GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		events = append(events, event+":"+data)
+	})
+	if !errors.Is(err, ErrHalted) || !strings.HasPrefix(events[len(events)-1], "error:New forecasts are paused") {
+		t.Errorf("Expected ErrHalted after an error event, got %v (%v)", err, events)
+	}
+}
+
+func TestKillSwitchScope(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		tenant   string
+		settings database.RuntimeSettings
+		halted   bool
+	}{
+		{"off", "", database.RuntimeSettings{}, false},
+		{"global", "acme", database.RuntimeSettings{KillSwitch: database.KillSwitch{On: true}}, true},
+		{"expired", "", database.RuntimeSettings{KillSwitch: database.KillSwitch{On: true, ExpiresAt: &expired}}, false},
+		{"tenant", "acme", database.RuntimeSettings{TenantKillSwitches: map[string]database.KillSwitch{"acme": {On: true}}}, true},
+		{"other tenant", "globex", database.RuntimeSettings{TenantKillSwitches: map[string]database.KillSwitch{"acme": {On: true}}}, false},
+		{"default tenant", "", database.RuntimeSettings{TenantKillSwitches: map[string]database.KillSwitch{database.DefaultTenant: {On: true}}}, true},
+	} {
+		svc := haltedService(&MockDB{Loc: &database.Location{ID: "oslo"}}, tc.settings)
+		svc.Tenant = tc.tenant
+		_, err := svc.RefreshLocation(ctx, "oslo", RefreshOptions{})
+		if got := errors.Is(err, ErrHalted); got != tc.halted {
+			t.Errorf("%s: refresh error %v, want halted %v", tc.name, err, tc.halted)
+		}
+	}
+
+	svc := haltedService(&MockDB{}, database.RuntimeSettings{KillSwitch: database.KillSwitch{On: true, Reason: "veo quota incident"}})
+	_, err := svc.Compare(ctx, CompareRequest{City: "Oslo", Styles: []int{1}})
+	if !errors.Is(err, ErrHalted) || !strings.Contains(err.Error(), "veo quota incident") {
+		t.Errorf("Expected Compare halted with the reason, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/storage"
+)
+
+// Narrator reads a forecast aloud in a language and returns a WAV clip.
+// *genai.Service implements it.
+type Narrator interface {
+	Narrate(ctx context.Context, text, lang string) ([]byte, error)
+}
+
+// AudioStore keeps narration clips. storage.Store implements it.
+type AudioStore interface {
+	UploadBytes(ctx context.Context, data []byte, fileName string, mimeType string) (string, error)
+}
+
+var conditionPhrases = map[openmeteo.Condition]string{
+	openmeteo.ConditionCloudy:       "Cloudy",
+	openmeteo.ConditionFog:          "Foggy",
+	openmeteo.ConditionRain:         "Rainy",
+	openmeteo.ConditionSnow:         "Snowing",
+	openmeteo.ConditionThunderstorm: "Thunderstorms",
+}
+
+// forecastLine is the English narration of the observed weather, e.g.
+// "Rainy in Nairobi, 24 degrees".
+func forecastLine(city string, c *openmeteo.Current) string {
+	phrase, ok := conditionPhrases[c.Condition]
+	switch {
+	case c.Condition == openmeteo.ConditionClear && c.IsDay:
+		phrase = "Sunny"
+	case c.Condition == openmeteo.ConditionClear:
+		phrase = "Clear skies"
+	case !ok:
+		return fmt.Sprintf("%s: %d degrees", city, int(math.Round(c.TemperatureC)))
+	}
+	return fmt.Sprintf("%s in %s, %d degrees", phrase, city, int(math.Round(c.TemperatureC)))
+}
+
+// narrationLangs are the languages of narration clips; the first one is
+// AudioURL's.
+func (s *Service) narrationLangs() []string {
+	if len(s.NarrationLangs) == 0 {
+		return []string{"en"}
+	}
+	return s.NarrationLangs
+}
+
+// narrate reads the observed weather aloud in each narration language, for
+// signage and widgets: the first language's clip becomes loc.AudioURL, the
+// others go in loc.AudioI18n. A clip that fails is logged and cleared
+// rather than kept, since it would read out the old weather. Nothing
+// happens without a Narrator or observed weather.
+func (s *Service) narrate(ctx context.Context, loc *database.Location, current *openmeteo.Current) {
+	if s.Narrator == nil || s.Audio == nil || current == nil {
+		return
+	}
+	line := forecastLine(loc.Name, current)
+	for i, lang := range s.narrationLangs() {
+		url, sum, err := s.narration(ctx, loc.ID, line, lang)
+		if err != nil {
+			log.Printf("Narration of %s (%s) failed: %v", loc.ID, lang, err)
+		}
+		if i == 0 {
+			loc.AudioURL = url
+			loc.RecordChecksums(map[string]string{url: sum})
+			continue
+		}
+		if url == "" {
+			delete(loc.AudioI18n, lang)
+			continue
+		}
+		if loc.AudioI18n == nil {
+			loc.AudioI18n = map[string]string{}
+		}
+		loc.AudioI18n[lang] = url
+	}
+}
+
+// narration speaks line in lang and uploads the clip, returning its URL and
+// checksum (see storage.CRC32C).
+func (s *Service) narration(ctx context.Context, id, line, lang string) (string, string, error) {
+	clip, err := s.Narrator.Narrate(ctx, line, lang)
+	if err != nil {
+		return "", "", err
+	}
+	fileName := fmt.Sprintf("audio/%s_%s_%d.wav", id, lang, s.now().Unix())
+	url, err := s.Audio.UploadBytes(ctx, clip, fileName, "audio/wav")
+	if err != nil {
+		return "", "", fmt.Errorf("upload failed: %w", err)
+	}
+	return url, storage.CRC32C(clip), nil
+}
@@ -0,0 +1,71 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"banana-weather/internal/database"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PolicyRepo is the subset of the database client needed to load the location policy.
+type PolicyRepo interface {
+	GetLocationPolicy(ctx context.Context, tenant string) (*database.LocationPolicy, error)
+}
+
+// LoadLocationPolicy returns the tenant's policy from Firestore, falling back
+// to the config-provided policy when no settings doc exists.
+func LoadLocationPolicy(ctx context.Context, repo PolicyRepo, tenant string, fallback *database.LocationPolicy) (*database.LocationPolicy, error) {
+	p, err := repo.GetLocationPolicy(ctx, tenant)
+	if status.Code(err) == codes.NotFound {
+		return fallback, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location policy: %w", err)
+	}
+	log.Printf("Loaded location policy (tenant: %q, blocked: %d, allowed: %d, allowlist only: %v)", tenant, len(p.Blocked), len(p.Allowed), p.AllowlistOnly)
+	return p, nil
+}
+
+// checkPolicy returns why a formatted address is not allowed, or "" if it is.
+func checkPolicy(p *database.LocationPolicy, formattedCity string) string {
+	if p == nil {
+		return ""
+	}
+	if entry := matchLocation(p.Blocked, formattedCity); entry != "" {
+		return "blocked: " + entry
+	}
+	if p.AllowlistOnly && matchLocation(p.Allowed, formattedCity) == "" {
+		return "not in allowlist"
+	}
+	return ""
+}
+
+// matchLocation returns the first entry matching the whole address or one of its components.
+func matchLocation(entries []string, formattedCity string) string {
+	full := strings.ToLower(strings.TrimSpace(formattedCity))
+	parts := strings.Split(full, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	for _, e := range entries {
+		want := strings.ToLower(strings.TrimSpace(e))
+		if want == "" {
+			continue
+		}
+		if want == full {
+			return e
+		}
+		for _, part := range parts {
+			if want == part {
+				return e
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/progress"
+)
+
+// ReferencePrefix is where POST /api/uploads puts user photos in the uploads
+// bucket. GetReferenceFlow only reads objects under it.
+const ReferencePrefix = "uploads/"
+
+// MaxReferenceBytes caps the size of a reference photo.
+const MaxReferenceBytes = 10 << 20
+
+// ReferenceTypes are the accepted reference photo content types.
+var ReferenceTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// UploadStore is the private bucket holding user reference photos.
+type UploadStore interface {
+	ReadObject(ctx context.Context, fileName string) ([]byte, error)
+	DeleteObject(ctx context.Context, fileName string) error
+}
+
+// ReferenceService screens reference photos and generates from them.
+type ReferenceService interface {
+	ModerateImage(ctx context.Context, data []byte, mimeType string) (*genai.Moderation, error)
+	GenerateImageWithReference(ctx context.Context, city string, extraContext string, promptMode int, seed *int32, ref *genai.Reference) (*genai.ImageResult, error)
+}
+
+// GetReferenceFlow is GetWeatherFlow conditioned on a user's photo of the
+// location, uploaded to object in the uploads bucket. The photo goes through
+// a moderation pass first and is deleted once it's been used or rejected.
+// The result is personal: it's sent as base64 and not stored, cached or
+// animated.
+func (s *Service) GetReferenceFlow(ctx context.Context, cityQuery, latStr, lngStr, object string, sendStatus StatusCallback) error {
+	ctx = startFlow(ctx, sendStatus)
+	if s.Uploads == nil || s.References == nil {
+		sendStatus("error", "Reference photos aren't enabled here.")
+		return fmt.Errorf("reference photos not configured")
+	}
+	if !strings.HasPrefix(object, ReferencePrefix) || strings.Contains(object, "..") {
+		sendStatus("error", "Invalid reference photo.")
+		return fmt.Errorf("invalid reference object %q", object)
+	}
+	if err := s.checkHalted(ctx); err != nil {
+		sendStatus("error", "New forecasts are paused for maintenance. Please try again later.")
+		return err
+	}
+	progress.Logf(ctx, "Reference Flow Started. City: %s, Lat: %s, Lng: %s, Photo: %s", cityQuery, latStr, lngStr, object)
+
+	place, err := s.resolvePlace(ctx, cityQuery, latStr, lngStr, sendStatus)
+	if err != nil {
+		return err
+	}
+	formattedCity := place.Name
+
+	sendStatus("status", "Checking your photo...")
+	data, err := s.Uploads.ReadObject(ctx, object)
+	if err != nil {
+		progress.Logf(ctx, "Failed to read reference %s: %v", object, err)
+		sendStatus("error", "Couldn't find your photo. Try uploading it again.")
+		return err
+	}
+	// The upload is single-use, whatever happens next
+	defer func() {
+		if err := s.Uploads.DeleteObject(context.WithoutCancel(ctx), object); err != nil {
+			progress.Logf(ctx, "Failed to delete reference %s: %v", object, err)
+		}
+	}()
+
+	mimeType := http.DetectContentType(data)
+	if len(data) > MaxReferenceBytes || !slices.Contains(ReferenceTypes, mimeType) {
+		sendStatus("error", "Photos must be JPEG, PNG or WebP and under 10 MB.")
+		return fmt.Errorf("reference %s rejected: %s, %d bytes", object, mimeType, len(data))
+	}
+
+	m, err := s.References.ModerateImage(ctx, data, mimeType)
+	if err != nil {
+		// Fail closed: an unchecked photo is never used
+		progress.Logf(ctx, "Moderation failed for %s: %v", object, err)
+		sendStatus("error", "Couldn't check your photo right now. Please try again.")
+		return err
+	}
+	if !m.Allowed {
+		err := s.DB.AddAuditEntry(ctx, database.AuditEntry{
+			Event:    "reference_rejected",
+			Query:    object,
+			Location: formattedCity,
+			Reason:   m.Category + ": " + m.Reason,
+		})
+		if err != nil {
+			progress.Logf(ctx, "Failed to write audit entry: %v", err)
+		}
+		sendStatus("error", "This photo can't be used: "+m.Reason)
+		return fmt.Errorf("reference %s rejected by moderation (%s)", object, m.Category)
+	}
+
+	sendStatus("status", fmt.Sprintf("Getting a banana image of the weather for %s from your photo...", formattedCity))
+	seed := rand.Int32N(math.MaxInt32)
+	img, err := s.References.GenerateImageWithReference(ctx, formattedCity, "", 0, &seed, &genai.Reference{Data: data, MIMEType: mimeType})
+	if err != nil {
+		progress.Logf(ctx, "Error generating reference image for '%s': %v", formattedCity, err)
+		sendStatus("error", "Failed to generate image: "+err.Error())
+		return err
+	}
+	img.Stamp(s.Provenance, "", s.now())
+
+	resp := WeatherResponse{
+		City:        formattedCity,
+		ImageBase64: img.Image(),
+		LastUpdated: s.now(),
+		Lang:        img.Lang,
+	}
+	jsonData, _ := json.Marshal(resp)
+	sendStatus("result", string(jsonData))
+	return nil
+}
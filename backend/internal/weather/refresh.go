@@ -0,0 +1,230 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/quota"
+)
+
+// ErrLocked is returned by RefreshLocation for a locked location without
+// RefreshOptions.OverrideLock.
+var ErrLocked = errors.New("location is locked")
+
+// ErrWithdrawn is returned by RefreshLocation for a hidden (e.g. taken down
+// by reports) or archived location without RefreshOptions.OverrideHidden.
+var ErrWithdrawn = errors.New("location is hidden or archived")
+
+// RefreshOptions controls which media RefreshLocation regenerates.
+type RefreshOptions struct {
+	Style        int    // Prompt style: 0=Random, 1=Classic, 2=Drink
+	Seed         *int32 // Overrides the stored seed
+	ImageOnly    bool   // Regenerate the image, keep the current video
+	VideoOnly    bool   // Regenerate the video from the stored image
+	Priority     string // Quota priority (see quota.ParsePriority), default scheduled
+	Grounding    string // GoogleSearch mode (see genai.ParseGroundingMode), default Service.Grounding
+	OverrideLock bool   // Regenerate a locked location; the lock is kept
+
+	// OverrideHidden regenerates a hidden or archived location. Its status
+	// is kept, so a takedown is only lifted by banana admin review.
+	OverrideHidden bool
+
+	// SkipUnchanged keeps the media when the observed weather is in the same
+	// bucket as when it was generated (see database.Conditions): only the
+	// temperature and LastUpdated are bumped, and the result is Unchanged.
+	// Video-only refreshes, and locations without recorded conditions, are
+	// always regenerated.
+	SkipUnchanged bool
+
+	// RequireApproval stages the new image of a preset as a pending
+	// candidate instead of replacing its media: the live media and status
+	// are left alone, no video is generated, and the result is the live
+	// location with Staged set. See ApproveCandidate. Other locations are
+	// refreshed as usual.
+	RequireApproval bool
+}
+
+// RefreshLocation regenerates the image and/or video for an existing location.
+// The stored seed is reused unless opts.Seed is set, so only the weather changes.
+// Locked locations fail with ErrLocked unless opts.OverrideLock is set,
+// hidden and archived ones with ErrWithdrawn unless opts.OverrideHidden is,
+// and every refresh fails with ErrHalted while the kill switch is on.
+func (s *Service) RefreshLocation(ctx context.Context, id string, opts RefreshOptions) (*database.Location, error) {
+	if s.Storage == nil {
+		return nil, fmt.Errorf("storage service not available")
+	}
+	if err := s.checkHalted(ctx); err != nil {
+		return nil, err
+	}
+	if opts.ImageOnly && opts.VideoOnly {
+		return nil, fmt.Errorf("image-only and video-only are mutually exclusive")
+	}
+	if opts.Priority != "" {
+		prio, err := quota.ParsePriority(opts.Priority)
+		if err != nil {
+			return nil, err
+		}
+		ctx = quota.WithPriority(ctx, prio)
+	}
+	ctx = quota.DefaultPriority(ctx, quota.PriorityScheduled)
+	if opts.Grounding != "" {
+		mode, err := genai.ParseGroundingMode(opts.Grounding)
+		if err != nil {
+			return nil, err
+		}
+		ctx = genai.WithGrounding(ctx, mode)
+	}
+
+	log.Printf("Refreshing location: %s (Style: %d, ImageOnly: %v, VideoOnly: %v)", id, opts.Style, opts.ImageOnly, opts.VideoOnly)
+	loc, err := s.DB.GetLocation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("location not found: %w", err)
+	}
+	if loc.Locked && !opts.OverrideLock {
+		return nil, fmt.Errorf("%s: %w (override the lock to regenerate it)", id, ErrLocked)
+	}
+	keepStatus := withdrawn(loc)
+	if keepStatus && !opts.OverrideHidden {
+		return nil, fmt.Errorf("%s is %s: %w (review it first, or override to regenerate it as it is)", id, loc.Status, ErrWithdrawn)
+	}
+	opts.RequireApproval = opts.RequireApproval && loc.IsPreset
+	if opts.RequireApproval {
+		if opts.VideoOnly {
+			return nil, fmt.Errorf("video-only refreshes can't be staged for approval")
+		}
+		if s.Candidates == nil {
+			return nil, fmt.Errorf("staging for approval needs a candidate store")
+		}
+		return s.refresh(ctx, loc, opts)
+	}
+
+	if keepStatus {
+		return s.refresh(ctx, loc, opts) // Its status stays as it is throughout
+	}
+	s.DB.SetStatus(ctx, id, database.StatusGenerating)
+	loc, err = s.refresh(ctx, loc, opts)
+	if err != nil {
+		s.DB.SetStatus(ctx, id, database.StatusFailed)
+		return nil, err
+	}
+	return loc, nil
+}
+
+// withdrawn reports whether loc is kept from users by an admin or by
+// reports: hidden, pending review or archived.
+func withdrawn(loc *database.Location) bool {
+	return loc.IsHidden() || loc.Status == database.StatusArchived
+}
+
+// refreshedStatus is the status of loc after a refresh: ready, unless it's
+// withdrawn, which only an admin lifts.
+func refreshedStatus(loc *database.Location) database.LocationStatus {
+	if withdrawn(loc) {
+		return loc.Status
+	}
+	return database.StatusReady
+}
+
+func (s *Service) refresh(ctx context.Context, loc *database.Location, opts RefreshOptions) (*database.Location, error) {
+	id := loc.ID
+
+	// Without a seed, the pipeline picks one and the new seed is stored
+	seed := opts.Seed
+	if seed == nil {
+		seed = loc.Seed
+	}
+	var current *openmeteo.Current
+	var check *database.WeatherCheck
+	steps := []pipeline.Option{pipeline.WithVideoPrompt(loc.VideoPrompt)}
+	if seed != nil {
+		log.Printf("Using seed: %d", *seed)
+		steps = append(steps, pipeline.WithSeed(*seed))
+	}
+	if opts.VideoOnly {
+		// Reuse the stored image as Veo input
+		steps = append(steps, pipeline.FromImage(loc.ImageURL))
+	} else {
+		current = s.observe(ctx, loc.CityQuery, loc.Geo)
+		if opts.SkipUnchanged && loc.ImageURL != "" && weatherUnchanged(loc, current) {
+			return s.keepMedia(ctx, loc, current)
+		}
+		steps = append(steps, pipeline.WithImageFunc(func(ctx context.Context, req pipeline.Request, seed *int32) (*genai.ImageResult, error) {
+			log.Printf("Generating image for '%s'...", loc.CityQuery)
+			img, c, err := s.generateImage(ctx, loc.CityQuery, req.Context, opts.Style, seed, current)
+			check = c
+			return img, err
+		}))
+	}
+	if opts.ImageOnly || opts.RequireApproval {
+		steps = append(steps, pipeline.SkipVideo())
+	}
+
+	req := pipeline.Request{
+		ID:       id,
+		City:     loc.CityQuery,
+		Context:  loc.PromptContext,
+		FileName: fmt.Sprintf("refresh_%s_image_%d.png", id, time.Now().Unix()),
+	}
+	if opts.RequireApproval {
+		req.FileName = "candidates/" + req.FileName
+	}
+	res, err := s.pipeline().Generate(ctx, req, steps...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RequireApproval {
+		return s.stage(ctx, loc, opts, res, current, check)
+	}
+	if res.Image != nil {
+		loc.Generation = res.Metadata()
+		applyWeather(loc, current, check)
+		loc.ImageURL = res.ImageURL
+		loc.AltText = res.AltText
+		s.narrate(ctx, loc, current)
+	} else if loc.Generation != nil {
+		// The image is kept, so the new Veo call adds to its usage
+		loc.Generation.Usage = append(loc.Generation.Usage, res.Usage...)
+	}
+	if !opts.ImageOnly {
+		loc.VideoURL = res.VideoURL
+		loc.PosterURL = res.PosterURL
+		loc.StreamURL = res.StreamURL
+	}
+	loc.RecordChecksums(res.Checksums)
+
+	loc.Seed = &res.Seed
+	loc.Status = refreshedStatus(loc)
+	loc.ManuallyCurated = false // An explicit refresh replaces attached media
+	loc.LastUpdated = s.now()
+	// Feedback applied to the old media
+	loc.FeedbackUp, loc.FeedbackDown, loc.FeedbackScore, loc.FeedbackReasons = 0, 0, 0, nil
+
+	if err := s.DB.UpsertLocation(ctx, *loc); err != nil {
+		return nil, fmt.Errorf("failed to update DB: %w", err)
+	}
+	log.Printf("Refresh complete for %s", id)
+	return loc, nil
+}
+
+// keepMedia ends a refresh whose weather hasn't changed without generating
+// anything: the location is saved ready (unless withdrawn), with the new temperature, as if
+// refreshed.
+func (s *Service) keepMedia(ctx context.Context, loc *database.Location, current *openmeteo.Current) (*database.Location, error) {
+	log.Printf("Weather unchanged for %s (%s), keeping its media", loc.ID, current.Describe())
+	temp := current.TemperatureC
+	loc.TemperatureC = &temp
+	loc.Status = refreshedStatus(loc)
+	loc.LastUpdated = s.now()
+	if err := s.DB.UpsertLocation(ctx, *loc); err != nil {
+		return nil, fmt.Errorf("failed to update DB: %w", err)
+	}
+	loc.Unchanged = true
+	return loc, nil
+}
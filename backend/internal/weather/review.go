@@ -0,0 +1,159 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/quota"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotReviewable is returned when approving or rejecting a candidate that
+// was already reviewed (or is being approved), isn't one of the location's,
+// or, for approvals, was superseded by a newer staged or approved one.
+var ErrNotReviewable = errors.New("candidate can't be reviewed")
+
+// stage records the image of a refresh awaiting approval (see
+// RefreshOptions.RequireApproval) as a pending candidate, with the weather it
+// was generated for, and returns the live location with Staged set.
+func (s *Service) stage(ctx context.Context, loc *database.Location, opts RefreshOptions, res *pipeline.Result, current *openmeteo.Current, check *database.WeatherCheck) (*database.Location, error) {
+	var observed database.Location
+	applyWeather(&observed, current, check)
+	id, err := s.Candidates.AddCandidate(ctx, database.Candidate{
+		LocationID:     loc.ID,
+		City:           loc.CityQuery,
+		Source:         "refresh",
+		Style:          genai.StyleName(opts.Style),
+		Seed:           res.Seed,
+		ImageURL:       res.ImageURL,
+		Generation:     res.Metadata(),
+		Status:         database.CandidatePending,
+		AltText:        res.AltText,
+		Mood:           observed.Mood,
+		TemperatureC:   observed.TemperatureC,
+		LastConditions: observed.LastConditions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage the new image: %w", err)
+	}
+	log.Printf("Staged candidate %s of %s for approval: %s", id, loc.ID, res.ImageURL)
+	loc.Staged = id
+	return loc, nil
+}
+
+// reviewable returns the candidate for a review of the location's media.
+func (s *Service) reviewable(ctx context.Context, locationID, candidateID string) (*database.Candidate, error) {
+	if s.Candidates == nil {
+		return nil, fmt.Errorf("no candidate store")
+	}
+	cand, err := s.Candidates.GetCandidate(ctx, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	if cand.LocationID != locationID {
+		return nil, fmt.Errorf("candidate %s is of %s, not %s: %w", candidateID, cand.LocationID, locationID, ErrNotReviewable)
+	}
+	if !cand.Reviewable() {
+		return nil, fmt.Errorf("candidate %s is already %s: %w", candidateID, cand.Status, ErrNotReviewable)
+	}
+	return cand, nil
+}
+
+// claim moves a reviewable candidate of the location to the status to, so
+// only one of concurrent reviews goes ahead, and returns the candidate as it
+// was.
+func (s *Service) claim(ctx context.Context, locationID, candidateID, to string) (*database.Candidate, error) {
+	if _, err := s.reviewable(ctx, locationID, candidateID); err != nil {
+		return nil, err
+	}
+	cand, err := s.Candidates.TransitionCandidate(ctx, candidateID, []string{"", database.CandidatePending}, to)
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, fmt.Errorf("candidate %s was reviewed meanwhile: %w", candidateID, ErrNotReviewable)
+	}
+	return cand, err
+}
+
+// supersededBy returns the ID of a candidate of the location newer than cand
+// that is staged or approved, or "" when cand is the latest.
+func (s *Service) supersededBy(ctx context.Context, cand *database.Candidate) (string, error) {
+	cands, err := s.Candidates.ListCandidates(ctx, cand.LocationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list candidates: %w", err)
+	}
+	for _, c := range cands {
+		switch c.Status {
+		case database.CandidatePending, database.CandidateApproving, database.CandidateApproved:
+			if c.ID != cand.ID && c.CreatedAt.After(cand.CreatedAt) {
+				return c.ID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// ApproveCandidate swaps a candidate's image in as the location's live media
+// and animates it, like a video-only refresh, then marks it approved. The
+// candidate is claimed as approving first, so concurrent reviews of it fail
+// with ErrNotReviewable, as does approving one superseded by a newer staged
+// or approved candidate. The live media is only replaced once the video is
+// ready; if Veo fails, nothing changes and the candidate stays reviewable.
+func (s *Service) ApproveCandidate(ctx context.Context, locationID, candidateID string) (*database.Location, error) {
+	if s.Storage == nil {
+		return nil, fmt.Errorf("storage service not available")
+	}
+	if err := s.checkHalted(ctx); err != nil {
+		return nil, err
+	}
+	cand, err := s.claim(ctx, locationID, candidateID, database.CandidateApproving)
+	if err != nil {
+		return nil, err
+	}
+	release := func() {
+		if _, err := s.Candidates.TransitionCandidate(ctx, candidateID, []string{database.CandidateApproving}, cand.Status); err != nil {
+			log.Printf("Failed to release candidate %s: %v", candidateID, err)
+		}
+	}
+	newer, err := s.supersededBy(ctx, cand)
+	if err == nil && newer != "" {
+		err = fmt.Errorf("candidate %s is superseded by %s: %w", candidateID, newer, ErrNotReviewable)
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+	loc, err := s.DB.GetLocation(ctx, locationID)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("location not found: %w", err)
+	}
+	ctx = quota.DefaultPriority(ctx, quota.PriorityScheduled)
+
+	log.Printf("Approving candidate %s of %s", candidateID, locationID)
+	loc.ImageURL, loc.Generation, loc.AltText, loc.Seed = cand.ImageURL, cand.Generation, cand.AltText, &cand.Seed
+	loc.Mood, loc.TemperatureC, loc.LastConditions = cand.Mood, cand.TemperatureC, cand.LastConditions
+	loc.WeatherCheck, loc.WeatherMismatch = nil, false
+	loc, err = s.refresh(ctx, loc, RefreshOptions{VideoOnly: true})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if err := s.Candidates.SetCandidateStatus(ctx, candidateID, database.CandidateApproved); err != nil {
+		log.Printf("Failed to mark candidate %s approved: %v", candidateID, err)
+	}
+	return loc, nil
+}
+
+// RejectCandidate marks a candidate of the location rejected; its image is
+// kept for reference.
+func (s *Service) RejectCandidate(ctx context.Context, locationID, candidateID string) error {
+	_, err := s.claim(ctx, locationID, candidateID, database.CandidateRejected)
+	return err
+}
@@ -0,0 +1,119 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/openmeteo"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestRefreshLocation_RequireApproval(t *testing.T) {
+	ctx := context.Background()
+	loc := database.Location{
+		ID: "tokyo", Name: "Tokyo", CityQuery: "Tokyo", IsPreset: true, Status: database.StatusReady,
+		ImageURL: "https://img/old.png", VideoURL: "https://vid/old.mp4",
+		Geo: &latlng.LatLng{Latitude: 35.68, Longitude: 139.69},
+	}
+	db := &MockDB{Loc: &loc}
+	genai := &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}
+	svc := NewService(nil, genai, &MockStorage{PublicURL: "https://storage.googleapis.com/bucket/new.png", GsURI: "gs://bucket/new.png"}, db)
+	svc.Conditions = &MockConditions{Observed: &openmeteo.Current{Condition: openmeteo.ConditionSnow, TemperatureC: -2}}
+	if _, err := svc.RefreshLocation(ctx, "tokyo", RefreshOptions{RequireApproval: true}); err == nil {
+		t.Error("Expected an error staging without a candidate store")
+	}
+	cands := &fakeCandidates{}
+	svc.Candidates = cands
+
+	// A preset's new image is staged; its media and status are left alone
+	got, err := svc.RefreshLocation(ctx, "tokyo", RefreshOptions{RequireApproval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Staged != "1" || got.ImageURL != "https://img/old.png" || db.Saved != nil || db.LastStatus != "" {
+		t.Fatalf("Expected the image staged, got %+v (saved %+v, status %q)", got, db.Saved, db.LastStatus)
+	}
+	cand := cands.added[0]
+	if cand.LocationID != "tokyo" || cand.Source != "refresh" || cand.Status != database.CandidatePending ||
+		cand.ImageURL != "https://storage.googleapis.com/bucket/new.png" || cand.TemperatureC == nil || *cand.TemperatureC != -2 || cand.LastConditions == nil {
+		t.Errorf("Unexpected candidate %+v", cand)
+	}
+	if _, err := svc.RefreshLocation(ctx, "tokyo", RefreshOptions{RequireApproval: true, VideoOnly: true}); err == nil {
+		t.Error("Expected an error staging a video-only refresh")
+	}
+
+	// Approving animates the candidate and swaps it in
+	if _, err := svc.ApproveCandidate(ctx, "osaka", "1"); !errors.Is(err, ErrNotReviewable) {
+		t.Errorf("Expected ErrNotReviewable for another location's candidate, got %v", err)
+	}
+	approved, err := svc.ApproveCandidate(ctx, "tokyo", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.ImageURL != "https://storage.googleapis.com/bucket/new.png" || db.Saved == nil || db.Saved.VideoURL == "https://vid/old.mp4" || db.Saved.ImageURL != "https://storage.googleapis.com/bucket/new.png" ||
+		db.Saved.TemperatureC == nil || *db.Saved.TemperatureC != -2 {
+		t.Errorf("Expected the candidate swapped in, got %+v (saved %+v)", approved, db.Saved)
+	}
+	if cands.added[0].Status != database.CandidateApproved {
+		t.Errorf("Expected the candidate approved, got %q", cands.added[0].Status)
+	}
+	if err := svc.RejectCandidate(ctx, "tokyo", "1"); !errors.Is(err, ErrNotReviewable) {
+		t.Errorf("Expected ErrNotReviewable for a reviewed candidate, got %v", err)
+	}
+
+	// Other locations are refreshed as usual
+	loc = database.Location{ID: "tokyo", CityQuery: "Tokyo", Geo: loc.Geo}
+	db.Loc, db.Saved = &loc, nil
+	if got, err := svc.RefreshLocation(ctx, "tokyo", RefreshOptions{RequireApproval: true}); err != nil || got.Staged != "" || db.Saved == nil {
+		t.Errorf("Expected a user location refreshed in place, got %+v, %v", got, err)
+	}
+}
+
+func TestApproveCandidate_Claim(t *testing.T) {
+	ctx := context.Background()
+	loc := database.Location{ID: "tokyo", Name: "Tokyo", CityQuery: "Tokyo", IsPreset: true, ImageURL: "https://img/old.png"}
+	db := &MockDB{Loc: &loc}
+	gen := &MockGenAI{ImageBase64: "base64data", VideoURI: "gs://bucket/video.mp4"}
+	svc := NewService(nil, gen, &MockStorage{PublicURL: "https://storage.googleapis.com/bucket/new.png", GsURI: "gs://bucket/new.png"}, db)
+	cands := &fakeCandidates{}
+	svc.Candidates = cands
+	for range 2 {
+		cands.AddCandidate(ctx, database.Candidate{LocationID: "tokyo", Source: "refresh", Status: database.CandidatePending, ImageURL: "https://storage.googleapis.com/bucket/new.png"})
+	}
+
+	// An older staged image can't be approved over a newer one, and stays
+	// pending for a rejection
+	if _, err := svc.ApproveCandidate(ctx, "tokyo", "1"); !errors.Is(err, ErrNotReviewable) || !strings.Contains(err.Error(), "superseded by 2") {
+		t.Errorf("Expected the older candidate refused, got %v", err)
+	}
+	if cands.added[0].Status != database.CandidatePending {
+		t.Errorf("Expected the older candidate released, got %q", cands.added[0].Status)
+	}
+
+	// A candidate being approved can't be reviewed again meanwhile
+	cands.added[1].Status = database.CandidateApproving
+	if _, err := svc.ApproveCandidate(ctx, "tokyo", "2"); !errors.Is(err, ErrNotReviewable) {
+		t.Errorf("Expected a concurrent approval refused, got %v", err)
+	}
+	if err := svc.RejectCandidate(ctx, "tokyo", "2"); !errors.Is(err, ErrNotReviewable) {
+		t.Errorf("Expected a concurrent rejection refused, got %v", err)
+	}
+
+	// A failed video releases the claim
+	cands.added[1].Status = database.CandidatePending
+	gen.Err = errors.New("veo unavailable")
+	if _, err := svc.ApproveCandidate(ctx, "tokyo", "2"); err == nil || errors.Is(err, ErrNotReviewable) {
+		t.Errorf("Expected the video failure, got %v", err)
+	}
+	if cands.added[1].Status != database.CandidatePending {
+		t.Errorf("Expected the candidate reviewable after a failure, got %q", cands.added[1].Status)
+	}
+	gen.Err = nil
+	if _, err := svc.ApproveCandidate(ctx, "tokyo", "2"); err != nil || cands.added[1].Status != database.CandidateApproved {
+		t.Errorf("Expected the latest candidate approved, got %v (%q)", err, cands.added[1].Status)
+	}
+}
@@ -0,0 +1,297 @@
+package weather
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"banana-weather/internal/clock"
+	"banana-weather/internal/database"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/progress"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/query"
+	"banana-weather/internal/quota"
+)
+
+// -- Interfaces --
+
+type MapService interface {
+	GetReverseGeocoding(ctx context.Context, lat, lng float64) (*maps.Place, error)
+	GetCityLocation(ctx context.Context, city string) (*maps.Place, error)
+}
+
+type GenAIService interface {
+	GenerateImage(ctx context.Context, city string, extraContext string, promptMode int, seed *int32) (*genai.ImageResult, error)
+	GenerateVideo(ctx context.Context, inputImageURI string, prompt string, seed *int32) (string, error)
+}
+
+type StorageService interface {
+	UploadImage(ctx context.Context, base64Data string, fileName string) (string, string, error)
+}
+
+// LatencyRecorder keeps web flow stage durations, see database.SummarizeLatency.
+type LatencyRecorder interface {
+	AddLatencySample(ctx context.Context, s database.LatencySample) error
+}
+
+// StaticMapper renders a map of coordinates as PNG. *maps.Service implements it.
+type StaticMapper interface {
+	StaticMap(ctx context.Context, lat, lng float64) ([]byte, error)
+}
+
+// RuntimeSource reads the runtime settings, for image-only degrade mode and
+// the kill switch.
+type RuntimeSource interface {
+	GetRuntimeSettings(ctx context.Context) (*database.RuntimeSettings, error)
+}
+
+type LocationRepo interface {
+	GetLocation(ctx context.Context, id string) (*database.Location, error)
+	UpsertLocation(ctx context.Context, loc database.Location) error
+	SetStatus(ctx context.Context, id string, status database.LocationStatus) error
+	AddAuditEntry(ctx context.Context, e database.AuditEntry) error
+}
+
+// -- Service --
+
+type Service struct {
+	Maps    MapService
+	GenAI   GenAIService
+	Storage StorageService
+	DB      LocationRepo
+	Policy  *database.LocationPolicy // Optional blocklist/allowlist, enforced after geocoding
+
+	// DefaultCity is looked up when a flow gets neither a city nor
+	// coordinates (the web app's first load); DefaultCity when empty.
+	DefaultCity string
+
+	// Optional check of generated images against the observed weather, see generateImage
+	Conditions           ConditionsService
+	Verifier             WeatherVerifier
+	RegenerateOnMismatch bool
+
+	// Grounding is the default GoogleSearch mode of generated images;
+	// requests override it with genai.WithGrounding. With GroundingOff, the
+	// observed weather (from Conditions) goes into the prompt instead.
+	Grounding genai.GroundingMode
+	// WeatherInPrompt puts the observed weather into the prompt in every
+	// grounding mode, and tells the model to depict it instead of searching.
+	WeatherInPrompt bool
+
+	Captioner Captioner // Optional: captions web flow locations, see captionStage
+
+	// Optional narrated forecasts of refreshed and warmed locations, see narrate
+	Narrator       Narrator
+	Audio          AudioStore
+	NarrationLangs []string // Language codes; the first is AudioURL's, default "en"
+
+	Clock       clock.Clock               // Cache freshness and timestamps; the system clock when nil
+	Provenance  *provenance.Signer        // Optional: content credentials embedded before upload
+	Originals   StorageService            // Optional: private store for images before watermark/AI badge
+	Posters     pipeline.PosterExtractor  // Optional: extracts a frame of each video as its poster
+	Streams     pipeline.StreamTranscoder // Optional: transcodes each video to HLS
+	Describer   pipeline.Describer        // Optional: writes alt text for each image
+	DetachVideo bool                      // Finish web flow videos after the client disconnects
+	Hooks       *hooks.Registry           // Optional: deployment hooks, run by the pipeline
+	Events      *eventbus.Bus             // Optional: lifecycle events, published by the pipeline
+	Quota       *quota.Manager            // Optional: caps concurrent generations, see pipeline.Pipeline
+	Latency     LatencyRecorder           // Optional: web flow stage durations, for SLO reports
+	Runtime     RuntimeSource             // Optional: image-only degrade mode and the kill switch
+	Tenant      string                    // Whose kill switch applies besides the global one; empty for the default
+	StaticMaps  StaticMapper              // Optional: a map is shown when the web flow's image fails
+	Fallback    *FallbackMedia            // Optional: other media shown when the web flow's image fails
+	Candidates  CandidateStore            // Optional: records the images of Compare
+
+	// StaleRetryDelay is the wait before refreshing a stale location the web
+	// flow served because its image failed (see sendStale); 5 minutes when 0.
+	StaleRetryDelay time.Duration
+	staleRefreshes  sync.Map // Location IDs with a pending background refresh
+
+	// Optional reference-photo generation, see GetReferenceFlow
+	Uploads    UploadStore
+	References ReferenceService
+}
+
+// pipeline returns the generation pipeline over the service's dependencies.
+func (s *Service) pipeline() *pipeline.Pipeline {
+	return &pipeline.Pipeline{
+		GenAI:      s.GenAI,
+		Storage:    s.Storage,
+		Originals:  s.Originals,
+		Provenance: s.Provenance,
+		Clock:      s.Clock,
+		Posters:    s.Posters,
+		Streams:    s.Streams,
+		Describer:  s.Describer,
+		Hooks:      s.Hooks,
+		Events:     s.Events,
+		Quota:      s.Quota,
+	}
+}
+
+// DefaultCity is the default of Service.DefaultCity (and DEFAULT_CITY).
+const DefaultCity = "San Francisco"
+
+func NewService(m MapService, g GenAIService, s StorageService, db LocationRepo) *Service {
+	return &Service{
+		Maps:    m,
+		GenAI:   g,
+		Storage: s,
+		DB:      db,
+	}
+}
+
+// WeatherResponse mirrors the JSON response expected by the frontend
+type WeatherResponse struct {
+	ID           string          `json:"id,omitempty"` // Location ID, for feedback; empty for reference-photo and fallback results, which aren't stored
+	City         string          `json:"city"`
+	ImageBase64  string          `json:"image_base64,omitempty"`
+	ImageURL     string          `json:"image_url,omitempty"`
+	LastUpdated  time.Time       `json:"last_updated"`
+	Fallback     bool            `json:"fallback,omitempty"`      // Not generated for this request (other art, a map or a placeholder); not stored
+	Stale        bool            `json:"stale,omitempty"`         // The location's previous media, served because its image failed; refreshed in the background
+	FallbackFrom string          `json:"fallback_from,omitempty"` // Name of the location whose art is shown
+	Mood         string          `json:"mood,omitempty"`          // Theme of the observed weather, see openmeteo.Mood
+	Condition    string          `json:"condition,omitempty"`     // Observed weather when the image was generated, e.g. "rain"
+	TemperatureC *float64        `json:"temperature_c,omitempty"` // Observed temperature when the image was generated
+	Forecast     []openmeteo.Day `json:"forecast,omitempty"`      // Daily forecast from today; new images only
+	AltText      string          `json:"alt_text,omitempty"`      // Cached images only; new ones get an "alt_text" event once described
+	Lang         string          `json:"lang,omitempty"`          // Language of the image's text (BCP 47); empty for the city's native language
+}
+
+// Forecast is the observed weather, sent as the "forecast" event before the
+// image is generated so the client can theme its background by Mood.
+type Forecast struct {
+	Condition    openmeteo.Condition `json:"condition"`
+	TemperatureC float64             `json:"temperature_c"`
+	IsDay        bool                `json:"is_day"`
+	Mood         openmeteo.Mood      `json:"mood"`
+	Days         []openmeteo.Day     `json:"days,omitempty"` // Daily forecast from today, when the provider has one
+}
+
+func newForecast(cur *openmeteo.Current) Forecast {
+	return Forecast{Condition: cur.Condition, TemperatureC: cur.TemperatureC, IsDay: cur.IsDay, Mood: cur.Mood(), Days: cur.Days}
+}
+
+// observedAt sets the observed weather of r from a location: its mood,
+// condition and temperature when its media was generated.
+func (r *WeatherResponse) observedAt(loc *database.Location) {
+	r.Mood, r.TemperatureC = loc.Mood, loc.TemperatureC
+	if loc.LastConditions != nil {
+		r.Condition = loc.LastConditions.Condition
+	}
+}
+
+// cacheTTL is how long a generated location is served before it's regenerated.
+const cacheTTL = 3 * time.Hour
+
+func (s *Service) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// fresh reports whether a location updated at t can still be served from cache.
+func (s *Service) fresh(t time.Time) bool {
+	return s.now().Sub(t) < cacheTTL
+}
+
+// StatusCallback is a function that sends real-time updates to the client
+type StatusCallback func(event string, data string)
+
+func sanitizeID(s string) string {
+	var result []rune
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			result = append(result, r)
+		} else {
+			result = append(result, '_')
+		}
+	}
+	return string(result)
+}
+
+// denied checks the location policy and records rejections in the audit log.
+func (s *Service) denied(ctx context.Context, query, formattedCity string) string {
+	reason := checkPolicy(s.Policy, formattedCity)
+	if reason == "" {
+		return ""
+	}
+	progress.Logf(ctx, "Location %s denied by policy (%s)", formattedCity, reason)
+	err := s.DB.AddAuditEntry(ctx, database.AuditEntry{
+		Event:    "location_blocked",
+		Query:    query,
+		Location: formattedCity,
+		Reason:   reason,
+	})
+	if err != nil {
+		progress.Logf(ctx, "Failed to write audit entry: %v", err)
+	}
+	return reason
+}
+
+// resolvePlace validates the query, geocodes it (or lat/lng when both are
+// set) and applies the location policy, reporting progress via sendStatus.
+func (s *Service) resolvePlace(ctx context.Context, cityQuery, latStr, lngStr string, sendStatus StatusCallback) (*maps.Place, error) {
+	var place *maps.Place
+	var err error
+
+	// Validate before anything reaches geocoding or prompt assembly
+	cityQuery, err = query.Normalize(cityQuery)
+	var qe *query.Error
+	if errors.As(err, &qe) {
+		progress.Logf(ctx, "Rejected city query: %v", err)
+		sendStatus("error", qe.Message)
+		return nil, err
+	}
+	sendStatus("status", "Identifying location...")
+
+	// 1. Resolve Location
+	if latStr != "" && lngStr != "" {
+		// Handle Coordinates
+		var lat, lng float64
+		fmt.Sscanf(latStr, "%f", &lat)
+		fmt.Sscanf(lngStr, "%f", &lng)
+
+		place, err = s.Maps.GetReverseGeocoding(ctx, lat, lng)
+		if err != nil {
+			progress.Logf(ctx, "Error reverse geocoding: %v", err)
+			sendStatus("error", "Failed to resolve location: "+err.Error())
+			return nil, err
+		}
+	} else {
+		// Handle City Name (or default)
+		if cityQuery == "" {
+			cityQuery = cmp.Or(s.DefaultCity, DefaultCity)
+		}
+
+		// Resolve City
+		place, err = s.Maps.GetCityLocation(ctx, cityQuery)
+		if err != nil {
+			progress.Logf(ctx, "Error resolving location for city '%s': %v", cityQuery, err)
+			sendStatus("error", "Failed to find city: "+err.Error())
+			return nil, err
+		}
+	}
+
+	formattedCity := place.Name
+	progress.Logf(ctx, "Resolved location to: %s", formattedCity)
+
+	if reason := s.denied(ctx, cityQuery, formattedCity); reason != "" {
+		sendStatus("error", "Sorry, "+formattedCity+" isn't available here.")
+		return nil, fmt.Errorf("location %s denied by policy: %s", formattedCity, reason)
+	}
+	sendStatus("status", "Found location: "+formattedCity)
+	return place, nil
+}
@@ -0,0 +1,103 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/progress"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/model"
+)
+
+// staleRetryDelay is the default wait before a stale location served in
+// place of a failed image is refreshed, so a model outage has time to clear.
+const staleRetryDelay = 5 * time.Minute
+
+// errServedStale ends the image stage when the stale media was served
+// instead; the flow succeeds.
+var errServedStale = errors.New("served the stale location")
+
+// sendStale serves the location's previous media when its image couldn't be
+// regenerated: the result is flagged stale, with a status line rather than
+// an error, and the location is refreshed in the background after
+// StaleRetryDelay (see scheduleRefresh). It reports whether it served
+// anything.
+func (s *Service) sendStale(ctx context.Context, r *resolvedPlace, send StatusCallback) bool {
+	if !s.sendStaleMedia(ctx, r, "We couldn't paint a fresh forecast just now, so here's the latest one. It'll be updated shortly.", send) {
+		return false
+	}
+	if s.Storage != nil {
+		s.scheduleRefresh(ctx, r.ID, r.Stale.EffectiveStatus())
+	}
+	return true
+}
+
+// sendStaleMedia sends the stale location's media as a result flagged
+// stale, with status as the line explaining why, and reports whether it had
+// any to send.
+func (s *Service) sendStaleMedia(ctx context.Context, r *resolvedPlace, status string, send StatusCallback) bool {
+	loc := r.Stale
+	if loc == nil || !servableArt(loc) || ctx.Err() != nil {
+		return false
+	}
+	progress.Logf(ctx, "Serving stale media of %s (updated %s)", r.ID, loc.LastUpdated.Format(time.RFC3339))
+	resp := WeatherResponse{
+		ID:          r.ID,
+		City:        model.Localize(r.Place.Name, loc.NameI18n, genai.LanguageFrom(ctx)),
+		ImageURL:    loc.ImageURL,
+		LastUpdated: loc.LastUpdated,
+		Stale:       true,
+		AltText:     loc.AltText,
+	}
+	resp.observedAt(loc)
+	if loc.Generation != nil {
+		resp.Lang = loc.Generation.Lang
+	}
+	jsonData, _ := json.Marshal(resp)
+	send("result", string(jsonData))
+	send("status", status)
+	if loc.PosterURL != "" {
+		send("poster", loc.PosterURL)
+	}
+	if loc.StreamURL != "" {
+		send("stream", loc.StreamURL)
+	}
+	if loc.VideoURL != "" {
+		send("video", loc.VideoURL)
+	}
+	return true
+}
+
+// scheduleRefresh marks a location refresh_pending and refreshes it in the
+// background after StaleRetryDelay, at most once at a time per location. A
+// refresh refused before it starts (kill switch, lock) puts the location
+// back to prev, its status before the flow. The refresh outlives the flow;
+// it keeps only the flow ID, for its logs. An instance can stop before the
+// delay is up, so jobs.PendingRefreshes retries what's left pending.
+func (s *Service) scheduleRefresh(ctx context.Context, id string, prev database.LocationStatus) {
+	s.DB.SetStatus(ctx, id, database.StatusRefreshPending)
+	if _, pending := s.staleRefreshes.LoadOrStore(id, struct{}{}); pending {
+		return
+	}
+	delay := s.StaleRetryDelay
+	if delay <= 0 {
+		delay = staleRetryDelay
+	}
+	ctx = progress.WithFlowID(context.Background(), progress.FlowID(ctx))
+	go func() {
+		defer s.staleRefreshes.Delete(id)
+		time.Sleep(delay)
+		_, err := s.RefreshLocation(ctx, id, RefreshOptions{})
+		if err == nil {
+			return
+		}
+		progress.Logf(ctx, "Background refresh of stale %s failed: %v", id, err)
+		if loc, gerr := s.DB.GetLocation(ctx, id); gerr == nil && loc.Status == database.StatusRefreshPending {
+			s.DB.SetStatus(ctx, id, prev)
+		}
+	}()
+}
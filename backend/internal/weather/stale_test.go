@@ -0,0 +1,118 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"banana-weather/internal/database"
+)
+
+// statusDB records status changes on a channel, for flows that refresh in
+// the background.
+type statusDB struct {
+	MockDB
+	statuses chan database.LocationStatus
+}
+
+func (m *statusDB) SetStatus(ctx context.Context, id string, status database.LocationStatus) error {
+	loc := *m.Loc // Copied, as the flow holds the location it read
+	loc.Status = status
+	m.Loc = &loc
+	m.statuses <- status
+	return nil
+}
+
+// expectStatuses waits for the status changes of db, in order.
+func expectStatuses(t *testing.T, db *statusDB, want ...database.LocationStatus) {
+	t.Helper()
+	for i, w := range want {
+		select {
+		case got := <-db.statuses:
+			if got != w {
+				t.Fatalf("Status %d = %s, want %s", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for status %s", w)
+		}
+	}
+}
+
+func TestGetWeatherFlow_Stale(t *testing.T) {
+	stale := &database.Location{ID: "oslo__norway", Name: "Oslo, Norway", ImageURL: "https://img/old.png", VideoURL: "https://vid/old.mp4",
+		LastUpdated: time.Now().Add(-5 * time.Hour), Mood: "cozy-snow"}
+	db := &statusDB{MockDB: MockDB{Loc: stale}, statuses: make(chan database.LocationStatus, 8)}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{Err: errors.New("vertex unavailable")}, &MockStorage{}, db)
+	svc.StaleRetryDelay = time.Millisecond
+
+	var events []string
+	var result WeatherResponse
+	err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {
+		events = append(events, event)
+		if event == "result" {
+			json.Unmarshal([]byte(data), &result)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected the stale media to be served without an error, got %v", err)
+	}
+	if slices.Contains(events, "error") || !slices.Contains(events, "video") {
+		t.Errorf("Unexpected events: %v", events)
+	}
+	if !result.Stale || result.ImageURL != stale.ImageURL || result.ID != "oslo__norway" || !result.LastUpdated.Equal(stale.LastUpdated) || result.Mood != "cozy-snow" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// The flow marks it pending, then the background refresh runs (and
+	// fails again here)
+	expectStatuses(t, db, database.StatusGenerating, database.StatusRefreshPending, database.StatusGenerating, database.StatusFailed)
+}
+
+// haltOnPending turns the kill switch on once a location is marked
+// refresh_pending, before its background refresh starts.
+type haltOnPending struct {
+	*statusDB
+	runtime *fakeRuntime
+}
+
+func (h haltOnPending) SetStatus(ctx context.Context, id string, status database.LocationStatus) error {
+	if status == database.StatusRefreshPending {
+		h.runtime.KillSwitch.On = true
+	}
+	return h.statusDB.SetStatus(ctx, id, status)
+}
+
+func TestGetWeatherFlow_StaleRefreshRefused(t *testing.T) {
+	// The kill switch goes on before the background refresh: the location
+	// goes back to its status instead of staying pending
+	stale := &database.Location{ID: "oslo__norway", Name: "Oslo, Norway", ImageURL: "https://img/old.png", Status: database.StatusReady,
+		LastUpdated: time.Now().Add(-5 * time.Hour)}
+	db := &statusDB{MockDB: MockDB{Loc: stale}, statuses: make(chan database.LocationStatus, 8)}
+	rt := &fakeRuntime{}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{Err: errors.New("vertex unavailable")}, &MockStorage{}, haltOnPending{db, rt})
+	svc.Runtime = rt
+	svc.StaleRetryDelay = time.Millisecond
+
+	if err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) {}); err != nil {
+		t.Fatalf("Expected the stale media to be served without an error, got %v", err)
+	}
+	expectStatuses(t, db, database.StatusGenerating, database.StatusRefreshPending, database.StatusReady)
+}
+
+func TestGetWeatherFlow_StaleWithoutMedia(t *testing.T) {
+	// Without previous art the failure is reported as before
+	db := &MockDB{Loc: &database.Location{ID: "oslo__norway", LastUpdated: time.Now().Add(-5 * time.Hour)}}
+	svc := NewService(&MockMapService{ResolvedCity: "Oslo, Norway"}, &MockGenAI{Err: errors.New("vertex unavailable")}, &MockStorage{}, db)
+	var events []string
+	err := svc.GetWeatherFlow(context.Background(), "Oslo", "", "", func(event, data string) { events = append(events, event+":"+data) })
+	if err == nil || !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "error:") }) {
+		t.Errorf("Expected an error, got %v (%v)", err, events)
+	}
+	if db.LastStatus != database.StatusFailed {
+		t.Errorf("Expected the location failed, got %s", db.LastStatus)
+	}
+}
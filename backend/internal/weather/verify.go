@@ -0,0 +1,172 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strings"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/openmeteo"
+	"banana-weather/internal/progress"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// ConditionsService looks up the observed weather to check images against.
+type ConditionsService interface {
+	Current(ctx context.Context, lat, lng float64) (*openmeteo.Current, error)
+}
+
+// ForecastService is a ConditionsService that also forecasts the next days,
+// like the weatherdata providers.
+type ForecastService interface {
+	Forecast(ctx context.Context, lat, lng float64, days int) (*openmeteo.Current, error)
+}
+
+// forecastDays is how many days observe forecasts, from today.
+const forecastDays = 3
+
+// WeatherVerifier asks a vision model whether an image matches the observed weather.
+type WeatherVerifier interface {
+	VerifyWeather(ctx context.Context, imageBase64 string, actual string) (*database.WeatherCheck, error)
+}
+
+// observe looks up the observed weather at geo, with the forecast of the
+// next days when Conditions is a ForecastService, for the mood, prompts,
+// results and the weather check. It's nil when Conditions isn't set, geo is
+// unknown or the lookup failed; generation goes on without it.
+func (s *Service) observe(ctx context.Context, city string, geo *latlng.LatLng) *openmeteo.Current {
+	if s.Conditions == nil || geo == nil {
+		return nil
+	}
+	var current *openmeteo.Current
+	var err error
+	if f, ok := s.Conditions.(ForecastService); ok {
+		current, err = f.Forecast(ctx, geo.Latitude, geo.Longitude, forecastDays)
+	} else {
+		current, err = s.Conditions.Current(ctx, geo.Latitude, geo.Longitude)
+	}
+	if err != nil {
+		progress.Logf(ctx, "Observed weather unavailable for %s: %v", city, err)
+		return nil
+	}
+	return current
+}
+
+// generateImage generates an image and, when Verifier is set and the
+// observed weather (see observe) is known, checks it against it. With
+// RegenerateOnMismatch, a mismatched image is regenerated once with a new
+// seed (written back through seed). The check is nil when it was skipped or
+// failed; verification never fails generation.
+//
+// In GroundingOff mode (see Service.Grounding), the observed weather and
+// its mood are added to the prompt's context, as the model can't look it up.
+// With WeatherInPrompt they are in every mode, and the prompt tells the
+// model to depict them (see genai.WithWeatherData).
+func (s *Service) generateImage(ctx context.Context, city, extra string, mode int, seed *int32, current *openmeteo.Current) (*genai.ImageResult, *database.WeatherCheck, error) {
+	grounding := s.groundingMode(ctx)
+	ctx = genai.WithGrounding(ctx, grounding)
+
+	if current != nil && (grounding == genai.GroundingOff || s.WeatherInPrompt) {
+		extra = WithObservedWeather(extra, current)
+		if s.WeatherInPrompt {
+			ctx = genai.WithWeatherData(ctx)
+		}
+	}
+
+	img, err := s.GenAI.GenerateImage(ctx, city, extra, mode, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Verifier == nil || current == nil {
+		return img, nil, nil
+	}
+
+	check, err := s.Verifier.VerifyWeather(ctx, img.Image(), current.Describe())
+	if err != nil {
+		progress.Logf(ctx, "Weather check failed for %s: %v", city, err)
+		return img, nil, nil
+	}
+	if check.Matches || !s.RegenerateOnMismatch {
+		return img, check, nil
+	}
+
+	progress.Logf(ctx, "Image for %s depicts %s but it's %s; regenerating", city, check.Depicted, check.Actual)
+	progress.Emit(ctx, progress.Update{Stage: "image", Message: "Regenerating to match the weather", Attempt: 2})
+	retrySeed := rand.Int32N(math.MaxInt32)
+	// Keyed by its seed, so the prompt cache doesn't serve the mismatched image again
+	retry, err := s.GenAI.GenerateImage(genai.WithRandomSeed(ctx, false), city, extra, mode, &retrySeed)
+	if err != nil {
+		progress.Logf(ctx, "Regeneration failed for %s, keeping the first image: %v", city, err)
+		return img, check, nil
+	}
+	retryCheck, err := s.Verifier.VerifyWeather(ctx, retry.Image(), check.Actual)
+	if err != nil {
+		progress.Logf(ctx, "Weather re-check failed for %s: %v", city, err)
+		retryCheck = nil
+	} else {
+		retryCheck.Regenerated = true
+	}
+	if seed != nil {
+		*seed = retrySeed
+	}
+	return retry, retryCheck, nil
+}
+
+// groundingMode is the mode of ctx, else Grounding, else GroundingSearch.
+func (s *Service) groundingMode(ctx context.Context) genai.GroundingMode {
+	if m, ok := genai.GroundingFrom(ctx); ok {
+		return m
+	}
+	if s.Grounding != "" {
+		return s.Grounding
+	}
+	return genai.GroundingSearch
+}
+
+// WithObservedWeather adds the observed weather, today's forecast when
+// known and the mood's adjectives to a prompt context.
+func WithObservedWeather(extra string, cur *openmeteo.Current) string {
+	observed := "Current weather: " + cur.Describe()
+	if today, ok := cur.Today(); ok {
+		observed += ". Today: " + today.Describe()
+	}
+	if adjectives := cur.Mood().Adjectives(); adjectives != "" {
+		observed += ". Mood: " + adjectives
+	}
+	if extra == "" {
+		return observed
+	}
+	return strings.TrimRight(extra, ". ") + ". " + observed
+}
+
+// applyWeather records the observed weather's mood, temperature and
+// conditions and the check result on a location. Either may be nil.
+func applyWeather(loc *database.Location, current *openmeteo.Current, check *database.WeatherCheck) {
+	loc.Mood, loc.TemperatureC, loc.LastConditions = "", nil, nil
+	if current != nil {
+		loc.Mood = string(current.Mood())
+		temp := current.TemperatureC
+		loc.TemperatureC = &temp
+		loc.LastConditions = conditionsOf(current)
+	}
+	loc.WeatherCheck = check
+	loc.WeatherMismatch = check != nil && !check.Matches
+}
+
+// conditionsOf buckets the observed weather, see database.Conditions.
+func conditionsOf(cur *openmeteo.Current) *database.Conditions {
+	return &database.Conditions{
+		Condition: string(cur.Condition),
+		IsDay:     cur.IsDay,
+		TempBandC: int(math.Floor(cur.TemperatureC/5)) * 5,
+	}
+}
+
+// weatherUnchanged reports whether current is in the same bucket as the
+// weather loc's media was generated for. Unknown weather counts as changed.
+func weatherUnchanged(loc *database.Location, current *openmeteo.Current) bool {
+	return current != nil && loc.LastConditions != nil && *loc.LastConditions == *conditionsOf(current)
+}
@@ -0,0 +1,128 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"banana-weather/internal/database"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/pipeline"
+	"banana-weather/internal/query"
+)
+
+// WarmTarget is a resolved city and the state of its cache entry.
+type WarmTarget struct {
+	Query string
+	ID    string
+	City  string // Formatted address from Maps
+	Place *maps.Place
+	Skip  string // Non-empty when the entry shouldn't be generated: "blocked", "fresh", "hidden", "preset", "curated" or "locked"
+}
+
+// PlanWarm resolves a city the same way the web flow does and reports whether
+// its cache entry still needs generating. Nothing is generated here, so callers
+// can apply a budget before calling Warm.
+func (s *Service) PlanWarm(ctx context.Context, cityQuery string) (*WarmTarget, error) {
+	cityQuery, err := query.Normalize(cityQuery)
+	if err != nil {
+		return nil, err
+	}
+	if cityQuery == "" {
+		return nil, fmt.Errorf("empty city query")
+	}
+	place, err := s.Maps.GetCityLocation(ctx, cityQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find city: %w", err)
+	}
+
+	t := &WarmTarget{Query: cityQuery, ID: sanitizeID(place.Name), City: place.Name, Place: place}
+	if s.denied(ctx, cityQuery, place.Name) != "" {
+		t.Skip = "blocked"
+		return t, nil
+	}
+	loc, err := s.DB.GetLocation(ctx, t.ID)
+	if err == nil && loc != nil {
+		switch {
+		case loc.IsHidden():
+			t.Skip = "hidden"
+		case loc.IsPreset:
+			t.Skip = "preset"
+		case loc.ManuallyCurated:
+			t.Skip = "curated"
+		case loc.Locked:
+			t.Skip = "locked"
+		case s.fresh(loc.LastUpdated):
+			t.Skip = "fresh"
+		}
+	}
+	return t, nil
+}
+
+// Warm generates and stores media for a planned target so the next web request is a cache hit.
+func (s *Service) Warm(ctx context.Context, t *WarmTarget, imageOnly bool) (*database.Location, error) {
+	if s.Storage == nil {
+		return nil, fmt.Errorf("storage service not available")
+	}
+	if err := s.checkHalted(ctx); err != nil {
+		return nil, err
+	}
+
+	s.DB.SetStatus(ctx, t.ID, database.StatusGenerating)
+	loc, err := s.warm(ctx, t, imageOnly)
+	if err != nil {
+		s.DB.SetStatus(ctx, t.ID, database.StatusFailed)
+		return nil, err
+	}
+	return loc, nil
+}
+
+func (s *Service) warm(ctx context.Context, t *WarmTarget, imageOnly bool) (*database.Location, error) {
+	current := s.observe(ctx, t.City, t.Place.LatLng())
+	var check *database.WeatherCheck
+	opts := []pipeline.Option{
+		pipeline.WithImageFunc(func(ctx context.Context, req pipeline.Request, seed *int32) (*genai.ImageResult, error) {
+			img, c, err := s.generateImage(ctx, t.City, req.Context, 0, seed, current)
+			check = c
+			return img, err
+		}),
+	}
+	if imageOnly {
+		opts = append(opts, pipeline.SkipVideo())
+	}
+	res, err := s.pipeline().Generate(ctx, pipeline.Request{ID: t.ID, City: t.City}, opts...)
+	if errors.Is(err, pipeline.ErrVideo) {
+		// Same as the web flow: the image alone is still servable
+		log.Printf("Veo generation failed for %s, keeping image only: %v", t.ID, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	loc := database.Location{
+		ID:          t.ID,
+		Name:        t.City,
+		CityQuery:   t.City,
+		ImageURL:    res.ImageURL,
+		VideoURL:    res.VideoURL,
+		PosterURL:   res.PosterURL,
+		StreamURL:   res.StreamURL,
+		Checksums:   res.Checksums,
+		AltText:     res.AltText,
+		IsPreset:    false,
+		Seed:        &res.Seed,
+		Generation:  res.Metadata(),
+		CountryCode: t.Place.CountryCode,
+		Continent:   t.Place.Continent,
+		Geo:         t.Place.LatLng(),
+		Status:      database.StatusReady,
+	}
+	applyWeather(&loc, current, check)
+	s.narrate(ctx, &loc, current)
+
+	if err := s.DB.UpsertLocation(ctx, loc); err != nil {
+		return nil, fmt.Errorf("failed to update DB: %w", err)
+	}
+	return &loc, nil
+}
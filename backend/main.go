@@ -7,14 +7,30 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"banana-weather/api"
-	"banana-weather/pkg/config"
-	"banana-weather/pkg/database"
-	"banana-weather/pkg/genai"
-	"banana-weather/pkg/maps"
-	"banana-weather/pkg/storage"
-	"banana-weather/pkg/weather"
+	"banana-weather/internal/branding"
+	"banana-weather/internal/cards"
+	"banana-weather/internal/config"
+	"banana-weather/internal/costs"
+	"banana-weather/internal/database"
+	"banana-weather/internal/eventbus"
+	"banana-weather/internal/genai"
+	"banana-weather/internal/hooks"
+	"banana-weather/internal/httppool"
+	"banana-weather/internal/jobs"
+	"banana-weather/internal/maps"
+	"banana-weather/internal/media"
+	"banana-weather/internal/notify"
+	"banana-weather/internal/promptcache"
+	"banana-weather/internal/provenance"
+	"banana-weather/internal/push"
+	"banana-weather/internal/quota"
+	"banana-weather/internal/repo"
+	"banana-weather/internal/storage"
+	"banana-weather/internal/wallet"
+	"banana-weather/internal/weather"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -27,47 +43,281 @@ func main() {
 		log.Fatalf("FATAL: Failed to load configuration: %v", err)
 	}
 
+	// One connection pool per upstream API, shared by all of its clients
+	pools := httppool.Open(cfg)
+
 	// Initialize Services
-	mapsService, err := maps.NewService(cfg.GoogleMapsKey)
+	mapsService, err := maps.NewServiceWithHTTPClient(cfg.GoogleMapsKey, pools.Get(httppool.Maps).Client())
 	if err != nil {
 		log.Fatalf("FATAL: Maps service failed to initialize. Error: %v", err)
 	}
 
 	// GenAI Service
-	genaiService, err := genai.NewService(context.Background(), cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel)
+	vertexHTTP, err := pools.Get(httppool.Vertex).GoogleClient(context.Background())
+	if err != nil {
+		log.Printf("Warning: Vertex AI calls won't use the shared connection pool: %v", err)
+	}
+	genaiService, err := genai.NewServiceWithHTTPClient(context.Background(), cfg.ProjectID, cfg.Location, cfg.BucketName, cfg.GeminiImageModel, vertexHTTP)
 	if err != nil {
 		log.Fatalf("FATAL: GenAI service failed to initialize. Error: %v", err)
 	}
 
 	// Storage Service
-	storageService, err := storage.NewService(context.Background(), cfg.BucketName)
+	if gcsHTTP, err := pools.Get(httppool.GCS).GoogleClient(context.Background()); err != nil {
+		log.Printf("Warning: GCS calls won't use the shared connection pool: %v", err)
+	} else {
+		storage.SetHTTPClient(gcsHTTP)
+	}
+	storageService, err := storage.Open(context.Background(), cfg)
 	if err != nil {
 		log.Printf("Warning: Storage service failed to initialize: %v", err)
 	}
 
 	// Database Service
-	dbService, err := database.NewClient(context.Background(), cfg.ProjectID, cfg.DatabaseID)
+	dbService, err := repo.Open(context.Background(), cfg)
 	if err != nil {
 		log.Fatalf("FATAL: Database service failed to initialize. Error: %v", err)
 	}
 	defer dbService.Close()
 
+	// Queries fail at request time without their composite indexes; catch that at deploy instead
+	if cfg.IndexCheck && cfg.DBBackend == "firestore" {
+		checkIndexes(cfg)
+	}
+
+	// Branding (optional prompt suffix, palette, and watermark)
+	var objects branding.ObjectReader
+	if storageService != nil {
+		objects = storageService
+	}
+	brand, logo, err := branding.Load(context.Background(), dbService, objects, cfg.TenantID)
+	if err != nil {
+		log.Printf("Warning: Branding failed to load, continuing without it: %v", err)
+	} else if brand != nil {
+		genaiService.SetBranding(brand, logo)
+	}
+	genaiService.SetOperationStore(dbService)
+	genaiService.SetTransport(cfg.GenAITransport)
+	genaiService.SetVideoOutput(storage.Routes(cfg)[storage.KindVideo].GSURI())
+	quotaManager, err := quota.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load quota limits: %v", err)
+	}
+	genaiService.SetQuota(quotaManager)
+	costRates, err := costs.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load cost rates: %v", err)
+	}
+	if cfg.PromptCache && storageService != nil {
+		genaiService.SetImageCache(promptcache.New(dbService, storageService))
+	}
+
 	// Weather Orchestrator
-	weatherService := weather.NewService(mapsService, genaiService, storageService, dbService)
+	weatherService := weather.NewService(mapsService, weather.WithChaos(genaiService, cfg.ChaosImageFail, cfg.ChaosVeoDelay), storageService, dbService)
+	policy, err := weather.LoadLocationPolicy(context.Background(), dbService, cfg.TenantID, cfg.LocationPolicy())
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	weatherService.Policy = policy
+	weatherService.Provenance = cfg.Signer()
+	weatherService.DetachVideo = cfg.DetachVideo
+	weatherService.DefaultCity = cfg.DefaultCity
+	weatherService.Latency = dbService
+	weatherService.Runtime = dbService
+	weatherService.Tenant = cfg.TenantID
+	weatherService.Candidates = dbService
+	weatherService.Quota = quotaManager
+	if cfg.MapFallback {
+		weatherService.StaticMaps = mapsService
+	}
+	weatherService.Fallback = &weather.FallbackMedia{ImageURL: cfg.FallbackImageURL, VideoURL: cfg.FallbackVideoURL}
+	if cfg.FallbackMedia {
+		weatherService.Fallback.Store = dbService
+	}
+	if originals, err := storage.OpenOriginals(context.Background(), cfg); err != nil {
+		log.Printf("Warning: originals bucket unavailable, unbadged images won't be kept: %v", err)
+	} else if originals != nil {
+		weatherService.Originals = originals
+	}
+	if posters, err := media.NewPosters(cfg.PosterFrame); err != nil {
+		log.Printf("Warning: video posters disabled: %v", err)
+	} else if posters != nil {
+		weatherService.Posters = posters
+	}
+	if streams, err := media.OpenHLS(context.Background(), cfg); err != nil {
+		log.Printf("Warning: HLS transcoding disabled: %v", err)
+	} else if streams != nil {
+		weatherService.Streams = streams
+	}
+	if weatherService.Hooks, err = hooks.Open(cfg); err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	if weatherService.Events, err = eventbus.Open(context.Background(), cfg); err != nil {
+		log.Printf("Warning: generation events disabled: %v", err)
+	}
+	uploads, err := storage.OpenUploads(context.Background(), cfg)
+	if err != nil {
+		log.Printf("Warning: uploads bucket unavailable, reference photos disabled: %v", err)
+	} else if uploads != nil {
+		weatherService.Uploads = uploads
+		weatherService.References = genaiService
+	}
+	weatherService.Grounding = genai.GroundingMode(cfg.GroundingMode)
+	weatherService.Conditions = cfg.Weather() // Observed weather and forecast for prompts, results and the weather check
+	weatherService.WeatherInPrompt = cfg.WeatherInPrompt
+	if cfg.Captions {
+		weatherService.Captioner = genaiService
+	}
+	if cfg.AltText {
+		weatherService.Describer = genaiService
+	}
+	if cfg.Narration {
+		weatherService.Narrator, weatherService.Audio, weatherService.NarrationLangs = genaiService, storageService, cfg.NarrationLangs
+	}
+	if cfg.WeatherCheck {
+		weatherService.Verifier = genaiService
+		weatherService.RegenerateOnMismatch = cfg.WeatherRegen
+	}
 
+	notifier := notify.Open(cfg)
 	handler := &api.Handler{
-		DB:      dbService,
-		Weather: weatherService,
+		DB:              dbService,
+		Weather:         weatherService,
+		ReportThreshold: cfg.ReportThreshold,
+		Notifier:        notifier,
+		PreloadImages:   cfg.PreloadImages,
+		TraceSample:     cfg.TraceSample,
+		Costs:           costRates,
+		Provenance:      provenance.NewSigner(cfg.ProvenanceKey), // Verification works even when embedding is off
+		Flows:           api.NewFlowBuffer(),
+		TrustedProxies:  cfg.TrustedProxies,
+	}
+	if uploads != nil {
+		handler.Uploads = uploads
+	}
+	featured := &jobs.CityOfTheDay{DB: dbService, Refresher: weatherService, AvoidDays: cfg.CityOfTheDayDays}
+	if fcm, err := push.Open(context.Background(), cfg); err != nil {
+		log.Printf("Warning: FCM unavailable, push notifications disabled: %v", err)
+	} else if fcm != nil {
+		fanout := &jobs.Fanout{Sender: fcm}
+		handler.Devices = fcm
+		handler.Push = fanout
+		featured.Push = fanout
+	}
+	handler.CityOfTheDay = featured
+	handler.Quota = quotaManager
+	handler.Pools = pools
+	handler.Alerts = &jobs.Alerts{DB: dbService, Notifier: notifier}
+	handler.RefreshPending = &jobs.PendingRefreshes{DB: dbService, Refresher: weatherService}
+	if passes, err := wallet.Open(context.Background(), cfg, dbService); err != nil {
+		log.Printf("Warning: wallet passes disabled: %v", err)
+	} else if passes != nil {
+		passes.Conditions = cfg.Weather()
+		handler.Wallet = passes
+	}
+	if storageService != nil {
+		handler.Cards = &cards.Service{Store: storageService, HTTP: &http.Client{Timeout: 30 * time.Second}}
+	}
+	if cfg.MediaProxy {
+		if stores, err := openMediaStores(context.Background(), cfg); err != nil {
+			log.Printf("Warning: media proxy disabled: %v", err)
+		} else {
+			handler.Media = stores
+			handler.AdminAPIKey = cfg.AdminAPIKey
+		}
+	}
+	if cfg.PresetsCacheTTL > 0 {
+		handler.Presets = api.NewPresetCache(dbService, cfg.PresetsCacheTTL)
+		if cfg.PresetsSignURLs > 0 {
+			stores, err := handler.Media, error(nil)
+			if stores == nil {
+				stores, err = openMediaStores(context.Background(), cfg)
+			}
+			if err != nil {
+				log.Printf("Warning: preset URLs won't be signed: %v", err)
+			} else {
+				handler.Presets.SignURLs(stores, cfg.PresetsSignURLs)
+			}
+		}
+		if cfg.PresetsWarmup {
+			handler.Ready = api.NewReadiness()
+			handler.Ready.Timeout = cfg.PresetsWarmupTimeout
+			go handler.Ready.Warm(context.Background(), handler.Presets)
+		}
 	}
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(api.RequestLogger)
+	r.Use(api.Compress(cfg.CompressLevel, cfg.CompressBrotli))
+
+	// Startup probe: 503 until presets are warmed
+	r.Get("/readyz", handler.HandleReadyz)
+
+	// Stable media URLs for private buckets, see api.HandleMediaProxy
+	r.Get("/media/proxy/{locationID}/{kind}", handler.HandleMediaProxy)
 
 	// API Routes
 	r.Route("/api", func(r chi.Router) {
 		r.Get("/weather", handler.HandleGetWeather)
+		r.With(api.RateLimitFlowStarts(api.PolledFlowsPerHour, time.Hour, cfg.TrustedProxies)).Get("/weather/poll", handler.HandleWeatherPoll)
+		r.Get("/dashboard", handler.HandleDashboard)
 		r.Get("/presets", handler.HandleGetPresets)
+		r.Get("/presets/stream", handler.HandlePresetStream)
+		r.Get("/categories", handler.HandleGetCategories)
+		r.Post("/locations/{id}/feedback", handler.HandleLocationFeedback)
+		r.With(api.RateLimit(api.ReportsPerHour, time.Hour, cfg.TrustedProxies)).Post("/locations/{id}/report", handler.HandleLocationReport)
+		r.Get("/locations/by-country/{code}", handler.HandleLocationsByCountry)
+		r.Get("/map.geojson", handler.HandleMapGeoJSON)
+		r.Post("/provenance/verify", handler.HandleVerifyProvenance)
+		r.Post("/uploads", handler.HandleCreateUpload)
+		r.Get("/city-of-the-day", handler.HandleCityOfTheDay)
+		r.Post("/devices", handler.HandleRegisterDevice)
+		r.Get("/locations/{id}/playlist", handler.HandleLocationPlaylist)
+		r.Get("/locations/{id}/card.png", handler.HandleLocationCard)
+		r.Get("/locations/{id}/receipt", handler.HandleLocationReceipt)
+		r.Get("/locations/{id}/pass.pkpass", handler.HandleApplePass)
+		r.Get("/locations/{id}/wallet/google", handler.HandleGoogleWalletPass)
+
+		// PassKit web service, called by Apple Wallet on devices holding a pass
+		r.Route("/wallet/v1", func(r chi.Router) {
+			r.Post("/devices/{device}/registrations/{passType}/{serial}", handler.HandlePassRegister)
+			r.Delete("/devices/{device}/registrations/{passType}/{serial}", handler.HandlePassUnregister)
+			r.Get("/devices/{device}/registrations/{passType}", handler.HandlePassSerials)
+			r.Get("/passes/{passType}/{serial}", handler.HandlePassLatest)
+			r.Post("/log", handler.HandlePassLog)
+		})
+
+		// Admin API (used by `banana --remote`), disabled unless ADMIN_API_KEY is set
+		if cfg.AdminAPIKey != "" {
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(api.RequireAPIKey(cfg.AdminAPIKey))
+				r.Get("/stats", handler.HandleAdminStats)
+				r.Get("/slo", handler.HandleAdminSLO)
+				r.Mount("/debug", middleware.Profiler()) // net/http/pprof under /api/admin/debug/pprof/
+				r.Get("/locations", handler.HandleAdminListLocations)
+				r.Get("/locations/{id}", handler.HandleAdminGetLocation)
+				r.Post("/locations/{id}/refresh", handler.HandleAdminRefreshLocation)
+				r.Get("/locations/{id}/generation", handler.HandleAdminLocationGeneration)
+				r.Put("/locations/{id}/video-prompt", handler.HandleAdminSetVideoPrompt)
+				r.Put("/locations/{id}/prompt-context", handler.HandleAdminSetPromptContext)
+				r.Delete("/locations/{id}", handler.HandleAdminDeleteLocation)
+				r.Get("/locations/{id}/candidates", handler.HandleAdminListCandidates)
+				r.Get("/locations/{id}/candidates/{cid}/preview.png", handler.HandleAdminCandidatePreview)
+				r.Post("/locations/{id}/candidates/{cid}/approve", handler.HandleAdminApproveCandidate)
+				r.Post("/locations/{id}/candidates/{cid}/reject", handler.HandleAdminRejectCandidate)
+				r.Post("/compare", handler.HandleAdminCompare)
+				r.Post("/city-of-the-day", handler.HandleAdminCityOfTheDay)
+				r.Post("/alerts/evaluate", handler.HandleAdminEvaluateAlerts)
+				r.Post("/refresh-pending", handler.HandleAdminRefreshPending)
+				r.Get("/quota", handler.HandleAdminQuota)
+				r.Get("/pools", handler.HandleAdminPools)
+				r.Get("/traces", handler.HandleAdminListFlowTraces)
+				r.Get("/traces/{id}", handler.HandleAdminFlowTrace)
+			})
+		}
 	})
 
 	// Static Files (Frontend)
@@ -84,14 +334,58 @@ func main() {
 	log.Printf("Serving static files from: %s", filesDir)
 	FileServer(r, "/", http.Dir(filesDir))
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	if cfg.HTTP2 {
+		// TLS is terminated by the load balancer (Cloud Run), so HTTP/2 reaches
+		// us as cleartext h2c; HTTP/1.1 keeps working alongside it.
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	log.Printf("Server starting on port %s (h2c: %v)", cfg.Port, cfg.HTTP2)
+	if err := srv.ListenAndServe(); err != nil {
 		log.Fatal(err)
 	}
 }
 
 // FileServer conveniently sets up a http.FileServer handler to serve
 // static files from a http.FileSystem.
+// checkIndexes exits when a required composite index is missing. If the
+// check itself can't run (e.g. no datastore.indexes.list permission) it only warns.
+func checkIndexes(cfg *config.Config) {
+	statuses, err := database.CheckIndexes(context.Background(), cfg.ProjectID, cfg.DatabaseID)
+	if err != nil {
+		log.Printf("Warning: Could not verify Firestore indexes (set INDEX_CHECK=false to skip): %v", err)
+		return
+	}
+	var missing []string
+	for _, s := range statuses {
+		switch s.State {
+		case database.IndexMissing:
+			missing = append(missing, "  "+s.GcloudCommand(cfg.DatabaseID))
+		case database.IndexBuilding:
+			log.Printf("Warning: Firestore index %s is still building; %s will fail until it's ready", s.Index, s.Query)
+		}
+	}
+	if len(missing) > 0 {
+		log.Fatalf("FATAL: %d Firestore composite indexes are missing. Deploy firestore.indexes.json (`banana admin indexes generate`) or run:\n%s", len(missing), strings.Join(missing, "\n"))
+	}
+}
+
+// openMediaStores opens the image and video stores the media proxy reads.
+func openMediaStores(ctx context.Context, cfg *config.Config) (map[storage.Kind]storage.Store, error) {
+	stores := map[storage.Kind]storage.Store{}
+	for _, kind := range []storage.Kind{storage.KindImage, storage.KindVideo} {
+		s, err := storage.OpenKind(ctx, cfg, kind)
+		if err != nil {
+			return nil, err
+		}
+		stores[kind] = s
+	}
+	return stores, nil
+}
+
 func FileServer(r chi.Router, path string, root http.FileSystem) {
 	if strings.ContainsAny(path, "{}*") {
 		panic("FileServer does not permit any URL parameters.")
@@ -0,0 +1,219 @@
+// Package client is a Go SDK for the Banana Weather HTTP API, for services
+// (bots, signage controllers) that show presets or run the weather flow
+// without parsing SSE themselves. It mirrors the API's JSON with its own
+// types and those of package events, so importing it doesn't pull in
+// Firestore or GenAI.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls a Banana Weather server. It's safe for concurrent use.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sends key as a bearer token, for the admin API or a gateway in
+// front of the server.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces the default HTTP client. It must not have a
+// Timeout shorter than a weather flow (a few minutes with Veo); use the
+// request context instead.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries sets how many times a failed request is retried (default 2),
+// waiting backoff, then twice as long, and so on, in between.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = n, backoff }
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://weather.example.com".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{},
+		retries: 2,
+		backoff: 500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a response the server rejected.
+type APIError struct {
+	StatusCode int
+	Code       string // Machine-readable reason, when the server gave one (e.g. a rejected city query)
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// retryable reports whether a failed attempt is worth repeating: network
+// errors, rate limiting and server errors.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return err != nil
+}
+
+// get sends a GET for path with retries and returns the successful response,
+// whose body the caller closes.
+func (c *Client) get(ctx context.Context, path string, q url.Values, accept string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(ctx, u, accept)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.retries || !retryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+// readError turns an error response into an APIError. Most handlers reply
+// with plain text; rejected queries are JSON with a code.
+func readError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	e := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	var qe struct {
+		Code    string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &qe) == nil && qe.Message != "" {
+		e.Code, e.Message = qe.Code, qe.Message
+	}
+	return e
+}
+
+// Preset is a gallery entry, as served by GET /api/presets.
+type Preset struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	ImageURL    string    `json:"image_url"`
+	VideoURL    string    `json:"video_url"`
+	PosterURL   string    `json:"poster_url,omitempty"`
+	StreamURL   string    `json:"stream_url,omitempty"` // HLS master playlist, when transcoded
+	AltText     string    `json:"alt_text,omitempty"`   // Description of the image for screen readers
+	AudioURL    string    `json:"audio_url,omitempty"`  // Narrated forecast (WAV) in the Lang of the request, when narration is on
+	Continent   string    `json:"continent,omitempty"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// PresetOptions filters and orders Presets.
+type PresetOptions struct {
+	Sort string // "category" (default), "name" or "updated"
+	Desc bool
+	Lang string // Localizes names and narration, e.g. "ja"
+}
+
+// Presets returns the preset gallery.
+func (c *Client) Presets(ctx context.Context, opts PresetOptions) ([]Preset, error) {
+	q := url.Values{}
+	if opts.Sort != "" {
+		q.Set("sort", opts.Sort)
+	}
+	if opts.Desc {
+		q.Set("order", "desc")
+	}
+	if opts.Lang != "" {
+		q.Set("lang", opts.Lang)
+	}
+	resp, err := c.get(ctx, "/api/presets", q, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var presets []Preset
+	if err := json.NewDecoder(resp.Body).Decode(&presets); err != nil {
+		return nil, fmt.Errorf("failed to decode presets: %w", err)
+	}
+	return presets, nil
+}
+
+// Category is a gallery section, as served by GET /api/categories.
+type Category struct {
+	Name     string `json:"name"`
+	Order    int    `json:"order"`
+	CoverURL string `json:"cover_url,omitempty"` // Section header art, when generated
+}
+
+// Categories returns the gallery categories in display order.
+func (c *Client) Categories(ctx context.Context) ([]Category, error) {
+	resp, err := c.get(ctx, "/api/categories", nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var categories []Category
+	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+	return categories, nil
+}
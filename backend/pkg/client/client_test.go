@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+func TestPresetsRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Expected the API key as a bearer token, got %q", got)
+		}
+		if calls == 1 {
+			http.Error(w, "Failed to fetch presets", http.StatusServiceUnavailable)
+			return
+		}
+		if got := r.URL.Query().Get("lang"); got != "ja" {
+			t.Errorf("Expected lang=ja, got %q", got)
+		}
+		fmt.Fprint(w, `[{"id":"paris","name":"パリ","category":"Europe","image_url":"https://example.com/paris.png"}]`)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithAPIKey("secret"), WithRetries(2, time.Millisecond))
+	presets, err := c.Presets(context.Background(), PresetOptions{Lang: "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(presets) != 1 || presets[0].Name != "パリ" {
+		t.Errorf("Expected one preset after a retry, got %+v after %d calls", presets, calls)
+	}
+}
+
+func TestWeatherRejected(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"empty","message":"Enter a city"}`)
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, WithRetries(2, time.Millisecond)).Weather(context.Background(), WeatherRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "empty" {
+		t.Fatalf("Expected a 400 APIError with the query code, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retries of a rejected query, got %d calls", calls)
+	}
+}
+
+func TestWeatherStream(t *testing.T) {
+	image := strings.Repeat("A", 1<<20) // Larger than a bufio.Scanner line
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("city"); got != "Paris" {
+			t.Errorf("Expected city=Paris, got %q", got)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Flow-ID", "f00d")
+		fmt.Fprint(w, "event: flow\ndata: f00d\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: status\ndata: Identifying location...\n\n")
+		fmt.Fprint(w, "event: forecast\ndata: {\"condition\":\"rain\",\"temperature_c\":9,\"is_day\":true,\"mood\":\"cozy-rain\",\"days\":[{\"date\":\"2026-10-15\",\"condition\":\"rain\",\"high_c\":14,\"low_c\":8,\"precipitation_chance\":80}]}\n\n")
+		fmt.Fprint(w, "event: progress\ndata: {\"stage\":\"video\",\"message\":\"Animating\",\"percent\":40}\n\n")
+		fmt.Fprintf(w, "event: result\ndata: {\"id\":\"paris\",\"city\":\"Paris\",\"image_base64\":\"%s\",\"condition\":\"rain\",\"temperature_c\":9.4}\n\n", image)
+		fmt.Fprint(w, "event: video\ndata: https://example.com/paris.mp4\n\n")
+	}))
+	defer srv.Close()
+
+	s, err := New(srv.URL).Weather(context.Background(), WeatherRequest{City: "Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for e := range s.Events {
+		types = append(types, e.Type)
+		switch e.Type {
+		case events.Forecast:
+			if f, err := e.Forecast(); err != nil || f.Mood != "cozy-rain" || f.TemperatureC != 9 || len(f.Days) != 1 || f.Days[0].HighC != 14 {
+				t.Errorf("Unexpected forecast %+v (%v)", f, err)
+			}
+		case events.Progress:
+			if u, err := e.Progress(); err != nil || u.Percent != 40 {
+				t.Errorf("Unexpected progress %+v (%v)", u, err)
+			}
+		case events.Result:
+			r, err := e.Result()
+			if err != nil || r.ID != "paris" || len(r.ImageBase64) != len(image) || r.TemperatureC == nil || *r.TemperatureC != 9.4 {
+				t.Errorf("Unexpected result for %+v (%v)", r.ID, err)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+	if got := strings.Join(types, ","); got != "flow,status,forecast,progress,result,video" || s.FlowID != "f00d" {
+		t.Errorf("Unexpected events %s of flow %q", got, s.FlowID)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	var got []Event
+	err := readSSE(strings.NewReader("data: line one\r\ndata: line two\r\n\r\nevent: ignored\n\nevent: status\ndata: done"), func(e Event) bool {
+		got = append(got, e)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Type != "message" || got[0].Data != "line one\nline two" {
+		t.Errorf("Unexpected events %+v", got)
+	}
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/events"
+)
+
+// Event is one server-sent event of the weather flow. Type is one of the
+// names in package events.
+type Event struct {
+	Type string
+	Data string
+}
+
+// Result decodes a result event.
+func (e Event) Result() (*events.ResultData, error) {
+	if e.Type != events.Result {
+		return nil, fmt.Errorf("not a result event: %s", e.Type)
+	}
+	var r events.ResultData
+	if err := json.Unmarshal([]byte(e.Data), &r); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	return &r, nil
+}
+
+// Forecast decodes a forecast event.
+func (e Event) Forecast() (*events.ForecastData, error) {
+	if e.Type != events.Forecast {
+		return nil, fmt.Errorf("not a forecast event: %s", e.Type)
+	}
+	var f events.ForecastData
+	if err := json.Unmarshal([]byte(e.Data), &f); err != nil {
+		return nil, fmt.Errorf("failed to decode forecast: %w", err)
+	}
+	return &f, nil
+}
+
+// Progress decodes a progress event.
+func (e Event) Progress() (*events.ProgressData, error) {
+	if e.Type != events.Progress {
+		return nil, fmt.Errorf("not a progress event: %s", e.Type)
+	}
+	var u events.ProgressData
+	if err := json.Unmarshal([]byte(e.Data), &u); err != nil {
+		return nil, fmt.Errorf("failed to decode progress: %w", err)
+	}
+	return &u, nil
+}
+
+// WeatherRequest is a weather flow to run. City is required; Lat and Lng
+// pin the place when the caller already knows it.
+type WeatherRequest struct {
+	City      string
+	Lat, Lng  string
+	Reference string // Object name from POST /api/uploads, to restyle a user photo
+}
+
+// Stream is a running weather flow.
+type Stream struct {
+	// Events delivers the flow's events in order, and is closed when the
+	// server ends the stream, the context is done or reading fails (see Err).
+	Events <-chan Event
+	FlowID string // From X-Flow-ID, for bug reports (banana admin trace --flow)
+
+	body io.Closer
+	err  error
+}
+
+// Err returns why the stream ended early, once Events is closed: nil when
+// the server finished it.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close stops reading the stream, which ends the flow on the server unless
+// it's configured to finish videos anyway (DETACH_VIDEO).
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+// Weather starts a weather flow. Connecting is retried like other requests,
+// but a stream that breaks once events arrive isn't: that would start the
+// generation over. Callers read Events until it's closed, then check Err.
+func (c *Client) Weather(ctx context.Context, req WeatherRequest) (*Stream, error) {
+	q := url.Values{}
+	q.Set("city", req.City)
+	if req.Lat != "" || req.Lng != "" {
+		q.Set("lat", req.Lat)
+		q.Set("lng", req.Lng)
+	}
+	if req.Reference != "" {
+		q.Set("reference", req.Reference)
+	}
+	resp, err := c.get(ctx, "/api/weather", q, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	s := &Stream{Events: events, FlowID: resp.Header.Get("X-Flow-ID"), body: resp.Body}
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		err := readSSE(resp.Body, func(e Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		s.err = err
+	}()
+	return s, nil
+}
+
+// readSSE parses server-sent events from r until EOF or emit returns false.
+// Data lines of one event are joined with newlines; comments (keep-alives)
+// and fields other than event and data are skipped. A bufio.Reader is used
+// rather than a Scanner since result events carry whole images.
+func readSSE(r io.Reader, emit func(Event) bool) error {
+	br := bufio.NewReader(r)
+	var e Event
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("stream interrupted: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if e.Type == "" {
+					e.Type = "message"
+				}
+				e.Data = strings.Join(data, "\n")
+				if !emit(e) {
+					return nil
+				}
+			}
+			e, data = Event{}, nil
+		case field == "event":
+			e.Type = value
+		case field == "data":
+			data = append(data, value)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package events names the server-sent events of the weather flow
+// (GET /api/weather, and the batches of GET /api/weather/poll) and defines
+// the data of those sent as JSON, as well as the lifecycle messages
+// published for every generation (see Lifecycle).
+package events
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ghchinoy/banana-weather/backend/pkg/weatherdata"
+)
+
+// Event names. Every flow starts with Flow; Status and Progress come at any
+// point.
+const (
+	Flow     = "flow"     // Flow ID, for bug reports
+	Status   = "status"   // Status line for the user
+	Progress = "progress" // ProgressData as JSON
+	Forecast = "forecast" // Observed weather, before the image, see ForecastData
+	Result   = "result"   // The image, see ResultData
+	Caption  = "caption"  // Nickname or fun fact to show under the image
+	AltText  = "alt_text" // Description of a new image for screen readers, sent after Result
+	Poster   = "poster"   // URL of the video's first frame, sent before Video
+	Stream   = "stream"   // URL of the video's HLS playlist
+	Video    = "video"    // URL of the MP4; the flow is done after it
+	Error    = "error"    // Message for the user; the flow may still send a result or finish without video
+	Trace    = "trace"    // Flow ID of a sampled flow, from servers older than Flow
+)
+
+// ProgressData is the data of a progress event: a step's progress, which the
+// flow also sends rendered (String) as a status event for older clients.
+type ProgressData struct {
+	Stage   string `json:"stage,omitempty"` // Step reporting, e.g. "image" or "video"
+	Message string `json:"message"`
+	Percent int    `json:"percent,omitempty"` // 1-100; 0 when unknown
+	Attempt int    `json:"attempt,omitempty"` // From 1, when the step is retried
+	// Estimated seconds left; 0 when unknown
+	ETASeconds int `json:"eta_seconds,omitempty"`
+}
+
+// String renders the update for a plain status line.
+func (u ProgressData) String() string {
+	s := u.Message
+	if u.Percent > 0 {
+		s += fmt.Sprintf(" %d%%", u.Percent)
+	}
+	if u.ETASeconds > 0 {
+		s += fmt.Sprintf(" – about %ds left", u.ETASeconds)
+	}
+	if u.Attempt > 1 {
+		s += fmt.Sprintf(" – attempt %d", u.Attempt)
+	}
+	return s
+}
+
+// ForecastData is the data of a forecast event.
+type ForecastData struct {
+	Condition    string            `json:"condition"` // clear, cloudy, fog, rain, snow or thunderstorm
+	TemperatureC float64           `json:"temperature_c"`
+	IsDay        bool              `json:"is_day"`
+	Mood         string            `json:"mood"`           // golden-sun, starry-night, soft-overcast, misty, cozy-rain, crisp-snow or stormy
+	Days         []weatherdata.Day `json:"days,omitempty"` // Daily forecast from today, when the server's weather provider has one
+}
+
+// ResultData is the data of a result event.
+type ResultData struct {
+	ID           string            `json:"id,omitempty"` // Location ID; empty for reference-photo and fallback results
+	City         string            `json:"city"`
+	ImageBase64  string            `json:"image_base64,omitempty"` // Freshly generated images
+	ImageURL     string            `json:"image_url,omitempty"`    // Cached images
+	LastUpdated  time.Time         `json:"last_updated"`
+	Fallback     bool              `json:"fallback,omitempty"`      // Other art, a map or a placeholder shown because generation failed
+	Stale        bool              `json:"stale,omitempty"`         // The location's previous media, shown because generation failed
+	FallbackFrom string            `json:"fallback_from,omitempty"` // Name of the location whose art is shown
+	Mood         string            `json:"mood,omitempty"`          // Theme of the observed weather, e.g. "cozy-rain"
+	Condition    string            `json:"condition,omitempty"`     // Observed weather when the image was generated, e.g. "rain"
+	TemperatureC *float64          `json:"temperature_c,omitempty"` // Observed temperature when the image was generated
+	Forecast     []weatherdata.Day `json:"forecast,omitempty"`      // Daily forecast from today; freshly generated images only
+	AltText      string            `json:"alt_text,omitempty"`      // Cached images; new ones get an AltText event
+	Lang         string            `json:"lang,omitempty"`          // Language of the image's text (BCP 47); empty for the city's native language
+}
+
+// Image decodes ImageBase64; it's nil when the result has an ImageURL instead.
+func (r *ResultData) Image() ([]byte, error) {
+	if r.ImageBase64 == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(r.ImageBase64)
+}
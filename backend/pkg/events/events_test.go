@@ -0,0 +1,20 @@
+package events
+
+import "testing"
+
+func TestProgressData_String(t *testing.T) {
+	tests := []struct {
+		u    ProgressData
+		want string
+	}{
+		{ProgressData{Message: "Animating (Veo 3.1)"}, "Animating (Veo 3.1)"},
+		{ProgressData{Message: "Animating (Veo 3.1)", Percent: 40, Attempt: 2}, "Animating (Veo 3.1) 40% – attempt 2"},
+		{ProgressData{Message: "Getting a banana image", Attempt: 1}, "Getting a banana image"},
+		{ProgressData{Message: "Animating (Veo 3.1)", Percent: 45, ETASeconds: 30}, "Animating (Veo 3.1) 45% – about 30s left"},
+	}
+	for _, tt := range tests {
+		if got := tt.u.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package events
+
+import "time"
+
+// Lifecycle types. Unlike the flow events above, which go to the client of
+// one flow, these are published for every generation (web flow, warming,
+// admin refresh and CLI) to the Pub/Sub topics of EVENT_TOPICS.
+const (
+	GenerationStarted = "generation_started" // Before the image
+	ImageReady        = "image_ready"        // Image uploaded; the last event of an image-only generation
+	VideoReady        = "video_ready"        // Video done; the generation is complete
+	Failed            = "failed"             // The generation failed, see Error; an image may be ready
+)
+
+// LifecycleTypes lists the lifecycle types in the order a generation goes
+// through them.
+var LifecycleTypes = []string{GenerationStarted, ImageReady, VideoReady, Failed}
+
+// Lifecycle is the JSON data of a lifecycle message. Its Pub/Sub attributes
+// repeat Type and LocationID, so subscriptions can filter on them.
+type Lifecycle struct {
+	Type       string    `json:"type"`
+	LocationID string    `json:"location_id,omitempty"`
+	City       string    `json:"city"`
+	FlowID     string    `json:"flow_id,omitempty"` // The weather flow's, when one started the generation
+	ImageURL   string    `json:"image_url,omitempty"`
+	VideoURL   string    `json:"video_url,omitempty"`
+	PosterURL  string    `json:"poster_url,omitempty"`
+	StreamURL  string    `json:"stream_url,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
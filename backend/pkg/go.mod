@@ -0,0 +1,7 @@
+module github.com/ghchinoy/banana-weather/backend/pkg
+
+go 1.25.11
+
+require google.golang.org/genproto v0.0.0-20260630182238-925bb5da69e7
+
+require google.golang.org/protobuf v1.36.11 // indirect
@@ -0,0 +1,270 @@
+// Package model is the location model of Banana Weather, as stored and as
+// served by the API (GET /api/admin/locations and friends), for clients that
+// decode it without depending on the server.
+package model
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Location is a place with generated media: a preset, or a city looked up by
+// a user.
+type Location struct {
+	ID              string              `firestore:"id" json:"id"`
+	Name            string              `firestore:"name" json:"name"`                               // Display Name
+	NameI18n        map[string]string   `firestore:"name_i18n,omitempty" json:"name_i18n,omitempty"` // Localized display names by language code
+	Category        string              `firestore:"category" json:"category"`                       // Grouping
+	CityQuery       string              `firestore:"city_query" json:"city_query"`                   // Original input
+	ImageURL        string              `firestore:"image_url" json:"image_url"`
+	VideoURL        string              `firestore:"video_url" json:"video_url"`
+	PosterURL       string              `firestore:"poster_url,omitempty" json:"poster_url,omitempty"`       // A frame of the video, shown while it loads
+	StreamURL       string              `firestore:"stream_url,omitempty" json:"stream_url,omitempty"`       // HLS master playlist of the video
+	IsPreset        bool                `firestore:"is_preset" json:"is_preset"`                             // Admin managed?
+	Seed            *int32              `firestore:"seed,omitempty" json:"seed,omitempty"`                   // Generation seed for reproducibility
+	CountryCode     string              `firestore:"country_code,omitempty" json:"country_code,omitempty"`   // ISO 3166-1 alpha-2, from geocoding
+	Continent       string              `firestore:"continent,omitempty" json:"continent,omitempty"`         // e.g. "Europe"
+	Geo             *latlng.LatLng      `firestore:"geo,omitempty" json:"geo,omitempty"`                     // Geocoded coordinates, for the map view
+	Generation      *GenerationMetadata `firestore:"generation,omitempty" json:"generation,omitempty"`       // How the current image was produced
+	WeatherCheck    *WeatherCheck       `firestore:"weather_check,omitempty" json:"weather_check,omitempty"` // Depicted vs. actual weather, when WEATHER_CHECK is on
+	WeatherMismatch bool                `firestore:"weather_mismatch,omitempty" json:"weather_mismatch,omitempty"`
+	Mood            string              `firestore:"mood,omitempty" json:"mood,omitempty"`                         // Theme of the observed weather when generated, see weatherdata.Mood
+	TemperatureC    *float64            `firestore:"temperature_c,omitempty" json:"temperature_c,omitempty"`       // Observed when generated, shown on share cards
+	LastConditions  *Conditions         `firestore:"last_conditions,omitempty" json:"last_conditions,omitempty"`   // Observed weather the media was generated for, to skip refreshes while it holds
+	Caption         string              `firestore:"caption,omitempty" json:"caption,omitempty"`                   // Nickname or fun fact shown under the artwork, kept across regenerations
+	AltText         string              `firestore:"alt_text,omitempty" json:"alt_text,omitempty"`                 // Description of the image for screen readers
+	AudioURL        string              `firestore:"audio_url,omitempty" json:"audio_url,omitempty"`               // Narrated forecast clip in the first NARRATION_LANGS language
+	AudioI18n       map[string]string   `firestore:"audio_i18n,omitempty" json:"audio_i18n,omitempty"`             // Narrated forecast clips in the other languages, by language code
+	VideoPrompt     string              `firestore:"video_prompt,omitempty" json:"video_prompt,omitempty"`         // Admin-set Veo motion prompt; genai.DefaultVideoPrompt when empty
+	PromptContext   string              `firestore:"prompt_context,omitempty" json:"prompt_context,omitempty"`     // Extra image prompt context (e.g. "focus on the old town"), reused by every regeneration
+	ManuallyCurated bool                `firestore:"manually_curated,omitempty" json:"manually_curated,omitempty"` // Media attached by an admin (banana admin attach); never regenerated automatically
+	Locked          bool                `firestore:"locked,omitempty" json:"locked,omitempty"`                     // Hand-approved media; not regenerated, even on request, without an override
+	Checksums       map[string]string   `firestore:"checksums,omitempty" json:"checksums,omitempty"`               // CRC32C of the media as stored (base64, see storage.CRC32C), by URL; checked by banana admin verify-media
+
+	// User feedback on the current media, maintained by AddFeedback
+	FeedbackUp      int            `firestore:"feedback_up" json:"feedback_up"`
+	FeedbackDown    int            `firestore:"feedback_down" json:"feedback_down"`
+	FeedbackScore   int            `firestore:"feedback_score" json:"feedback_score"` // up - down, for sorting
+	FeedbackReasons map[string]int `firestore:"feedback_reasons,omitempty" json:"feedback_reasons,omitempty"`
+
+	Status      LocationStatus `firestore:"status,omitempty" json:"status,omitempty"`           // Lifecycle state, see LocationStatus
+	Reports     int            `firestore:"reports" json:"reports"`                             // Abuse reports since last review
+	FeaturedOn  string         `firestore:"featured_on,omitempty" json:"featured_on,omitempty"` // Date it was last city of the day
+	Usage       *UsageSummary  `firestore:"-" json:"usage,omitempty"`                           // Filled in by admin listings, see costs.Rates.Summarize
+	Unchanged   bool           `firestore:"-" json:"unchanged,omitempty"`                       // Set on a refresh result that kept the media because the weather hadn't changed
+	Staged      string         `firestore:"-" json:"staged,omitempty"`                          // Set on a refresh result that staged its image for approval: the candidate's ID
+	LastUpdated time.Time      `firestore:"last_updated" json:"last_updated"`
+	ExpireAt    *time.Time     `firestore:"expire_at,omitempty" json:"expire_at,omitempty"` // Firestore TTL field: user locations not updated since are deleted, see Expire
+}
+
+// GenerationMetadata records what the image model reported for the current
+// image: its commentary (e.g. the weather it looked up), the Google Search
+// grounding behind it, and token usage.
+type GenerationMetadata struct {
+	Model         string            `firestore:"model" json:"model"`
+	Commentary    string            `firestore:"commentary,omitempty" json:"commentary,omitempty"`
+	SearchQueries []string          `firestore:"search_queries,omitempty" json:"search_queries,omitempty"`
+	Sources       []GroundingSource `firestore:"sources,omitempty" json:"sources,omitempty"`
+	PromptTokens  int32             `firestore:"prompt_tokens" json:"prompt_tokens"`
+	OutputTokens  int32             `firestore:"output_tokens" json:"output_tokens"`
+	TotalTokens   int32             `firestore:"total_tokens" json:"total_tokens"`
+	Cached        bool              `firestore:"cached,omitempty" json:"cached,omitempty"`                 // Served from the prompt cache; only Model and GroundingMode are set
+	GroundingMode string            `firestore:"grounding_mode,omitempty" json:"grounding_mode,omitempty"` // search, off or required (genai.GroundingMode); empty before modes were recorded
+	Usage         []ModelUsage      `firestore:"usage,omitempty" json:"usage,omitempty"`                   // Every billed model call behind the media, including rejected images and Veo
+	FlowID        string            `firestore:"flow_id,omitempty" json:"flow_id,omitempty"`               // Web flow that generated the media (the "flow" event); empty for other generators
+	Lang          string            `firestore:"lang,omitempty" json:"lang,omitempty"`                     // Language of the text in the image (BCP 47); empty for the city's native language
+	Style         string            `firestore:"style,omitempty" json:"style,omitempty"`                   // Prompt style the image was built with, e.g. "classic"; empty before styles were recorded
+	PromptVersion string            `firestore:"prompt_version,omitempty" json:"prompt_version,omitempty"` // Hash of the prompt templates (genai.PromptVersion); empty before versions were recorded
+}
+
+// ModelUsage is the billing dimensions of one model call: tokens for Gemini,
+// seconds of video for Veo. See costs.Track.
+type ModelUsage struct {
+	Model        string  `firestore:"model" json:"model"`
+	PromptTokens int32   `firestore:"prompt_tokens,omitempty" json:"prompt_tokens,omitempty"`
+	OutputTokens int32   `firestore:"output_tokens,omitempty" json:"output_tokens,omitempty"`
+	VideoSeconds int32   `firestore:"video_seconds,omitempty" json:"video_seconds,omitempty"` // Length of the clip, which Veo bills
+	WaitSeconds  float64 `firestore:"wait_seconds,omitempty" json:"wait_seconds,omitempty"`   // How long the Veo operation ran
+}
+
+// UsageSummary totals a location's ModelUsage for admin listings. It isn't
+// stored.
+type UsageSummary struct {
+	Tokens       int32   `json:"tokens"`
+	VideoSeconds int32   `json:"video_seconds,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"` // Estimate from COST_RATES; 0 for models without a rate
+}
+
+// WeatherCheck compares the weather an image depicts with the observed
+// weather at generation time.
+type WeatherCheck struct {
+	Actual      string    `firestore:"actual" json:"actual"`     // e.g. "rain, 12°C, night"
+	Depicted    string    `firestore:"depicted" json:"depicted"` // Condition the vision model saw
+	Matches     bool      `firestore:"matches" json:"matches"`
+	Reason      string    `firestore:"reason,omitempty" json:"reason,omitempty"`
+	Regenerated bool      `firestore:"regenerated,omitempty" json:"regenerated,omitempty"` // The first image mismatched and was replaced
+	CheckedAt   time.Time `firestore:"checked_at" json:"checked_at"`
+}
+
+// Conditions is observed weather, bucketed so that only changes a picture
+// would show count: the condition, day or night, and the 5°C band of the
+// temperature. Two observations in the same bucket compare equal.
+type Conditions struct {
+	Condition string `firestore:"condition" json:"condition"` // e.g. "rain", see weatherdata.Condition
+	IsDay     bool   `firestore:"is_day" json:"is_day"`
+	TempBandC int    `firestore:"temp_band_c" json:"temp_band_c"` // Lower bound of the band, e.g. 10 for 10-14.9°C
+}
+
+// GroundingSource is a web page the GoogleSearch tool retrieved, with the
+// parts of the model's answer it supports. Used to audit the depicted weather.
+type GroundingSource struct {
+	Title    string   `firestore:"title" json:"title"`
+	URI      string   `firestore:"uri" json:"uri"`
+	Domain   string   `firestore:"domain,omitempty" json:"domain,omitempty"`
+	Snippets []string `firestore:"snippets,omitempty" json:"snippets,omitempty"` // Answer segments attributed to this source
+}
+
+// LocationStatus is the explicit lifecycle state of a location.
+// Documents written before the field existed have no status and count as ready.
+type LocationStatus string
+
+const (
+	StatusGenerating     LocationStatus = "generating"      // Media generation in progress
+	StatusReady          LocationStatus = "ready"           // Media available
+	StatusRefreshPending LocationStatus = "refresh_pending" // Queued for regeneration
+	StatusFailed         LocationStatus = "failed"          // Last generation attempt failed
+	StatusHidden         LocationStatus = "hidden"          // Hidden by an admin
+	StatusArchived       LocationStatus = "archived"        // Retired; kept out of presets but not blocked
+
+	// StatusHiddenPendingReview marks a location taken down by user reports.
+	// It is excluded from presets and user lookups until an admin reviews it.
+	StatusHiddenPendingReview LocationStatus = "hidden_pending_review"
+)
+
+// LocationStatuses lists every valid status, for validation and completion.
+var LocationStatuses = []LocationStatus{
+	StatusGenerating, StatusReady, StatusRefreshPending, StatusFailed,
+	StatusHidden, StatusArchived, StatusHiddenPendingReview,
+}
+
+// EffectiveStatus returns the status, treating legacy (empty) documents as ready.
+func (l *Location) EffectiveStatus() LocationStatus {
+	if l.Status == "" {
+		return StatusReady
+	}
+	return l.Status
+}
+
+// IsHidden reports whether the location must not be served to users.
+func (l *Location) IsHidden() bool {
+	return l.Status == StatusHidden || l.Status == StatusHiddenPendingReview
+}
+
+// KeepsMedia reports whether automatic regeneration (stale refreshes, cache
+// warm-up, the web flow's cache TTL) must leave the media alone: it was
+// attached by hand or the location is locked.
+func (l *Location) KeepsMedia() bool {
+	return l.ManuallyCurated || l.Locked
+}
+
+// Expire sets ExpireAt to ttl after LastUpdated, for the retention policy
+// to delete user locations through Firestore TTL. Presets never expire, and
+// a ttl of 0 clears ExpireAt.
+func (l *Location) Expire(ttl time.Duration) {
+	if l.IsPreset || ttl <= 0 || l.LastUpdated.IsZero() {
+		l.ExpireAt = nil
+		return
+	}
+	at := l.LastUpdated.Add(ttl)
+	l.ExpireAt = &at
+}
+
+// MediaURLs returns the location's media URLs that are set: image, video,
+// poster and narration.
+func (l *Location) MediaURLs() []string {
+	var urls []string
+	for _, u := range []string{l.ImageURL, l.VideoURL, l.PosterURL, l.AudioURL} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// RecordChecksums adds sums (by URL) to Checksums, and drops the checksums
+// of media the location no longer uses. Call it after setting the URLs.
+func (l *Location) RecordChecksums(sums map[string]string) {
+	kept := map[string]string{}
+	for _, u := range l.MediaURLs() {
+		if sum := cmp.Or(sums[u], l.Checksums[u]); sum != "" {
+			kept[u] = sum
+		}
+	}
+	l.Checksums = kept
+	if len(kept) == 0 {
+		l.Checksums = nil
+	}
+}
+
+// LocalizedName returns the display name for lang (e.g. "ja" or "pt-BR"),
+// falling back to the base language and then to Name.
+func (l *Location) LocalizedName(lang string) string {
+	return Localize(l.Name, l.NameI18n, lang)
+}
+
+// LocalizedAudioURL returns the narrated forecast for lang, falling back
+// like LocalizedName.
+func (l *Location) LocalizedAudioURL(lang string) string {
+	return Localize(l.AudioURL, l.AudioI18n, lang)
+}
+
+// Localize returns the value of i18n for lang (e.g. "ja" or "pt-BR"), falling
+// back to the base language and then to name.
+func Localize(name string, i18n map[string]string, lang string) string {
+	if lang == "" || len(i18n) == 0 {
+		return name
+	}
+	if n, ok := i18n[lang]; ok && n != "" {
+		return n
+	}
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		if n := i18n[base]; n != "" {
+			return n
+		}
+	}
+	return name
+}
+
+// Field returns the text value of an editable field, or of id, for filtering
+// and previews. ok is false for other fields.
+func (l *Location) Field(field string) (value string, ok bool) {
+	switch field {
+	case "id":
+		return l.ID, true
+	case "name":
+		return l.Name, true
+	case "category":
+		return l.Category, true
+	case "city_query":
+		return l.CityQuery, true
+	case "status":
+		return string(l.Status), true
+	case "is_preset":
+		return strconv.FormatBool(l.IsPreset), true
+	case "country_code":
+		return l.CountryCode, true
+	case "continent":
+		return l.Continent, true
+	case "video_prompt":
+		return l.VideoPrompt, true
+	case "prompt_context":
+		return l.PromptContext, true
+	case "locked":
+		return strconv.FormatBool(l.Locked), true
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordChecksums(t *testing.T) {
+	l := Location{ImageURL: "img-1", Checksums: map[string]string{"img-0": "old", "poster": "p"}, PosterURL: "poster"}
+	l.RecordChecksums(map[string]string{"img-1": "new", "elsewhere": "x"})
+	if len(l.Checksums) != 2 || l.Checksums["img-1"] != "new" || l.Checksums["poster"] != "p" {
+		t.Errorf("Expected replaced media pruned and kept media kept, got %v", l.Checksums)
+	}
+
+	l = Location{Checksums: map[string]string{"gone": "x"}}
+	l.RecordChecksums(nil)
+	if l.Checksums != nil {
+		t.Errorf("Expected no checksums without media, got %v", l.Checksums)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	names := map[string]string{"ja": "東京", "pt": "Tóquio"}
+	tests := []struct{ lang, want string }{
+		{"", "Tokyo"},
+		{"ja", "東京"},
+		{"pt-BR", "Tóquio"},
+		{"fr", "Tokyo"},
+	}
+	for _, tt := range tests {
+		if got := Localize("Tokyo", names, tt.lang); got != tt.want {
+			t.Errorf("Localize(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestExpire(t *testing.T) {
+	updated := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	loc := Location{LastUpdated: updated}
+	loc.Expire(48 * time.Hour)
+	if loc.ExpireAt == nil || !loc.ExpireAt.Equal(updated.Add(48*time.Hour)) {
+		t.Fatalf("Expected expiry 48h after the update, got %v", loc.ExpireAt)
+	}
+	loc.Expire(0)
+	if loc.ExpireAt != nil {
+		t.Errorf("Expected no expiry without a TTL, got %v", loc.ExpireAt)
+	}
+	preset := Location{IsPreset: true, LastUpdated: updated}
+	preset.Expire(48 * time.Hour)
+	if preset.ExpireAt != nil {
+		t.Errorf("Expected presets never to expire, got %v", preset.ExpireAt)
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Receipt is the recipe behind a location's media, served by GET
+// /api/locations/{id}/receipt: the settings that reproduce it, here or in
+// another environment (banana generate --from-receipt), and the weather it
+// was generated for.
+type Receipt struct {
+	LocationID    string `json:"location_id"`
+	Name          string `json:"name"`
+	Category      string `json:"category,omitempty"`
+	City          string `json:"city"` // City query the prompt was built from
+	PromptContext string `json:"prompt_context,omitempty"`
+	Style         string `json:"style,omitempty"`          // Prompt style, e.g. "classic"; empty before styles were recorded, when random with Seed rebuilds the same prompt
+	PromptVersion string `json:"prompt_version,omitempty"` // Hash of the prompt templates; empty before versions were recorded
+	Seed          *int32 `json:"seed,omitempty"`
+	ImageModel    string `json:"image_model,omitempty"`
+	VideoModel    string `json:"video_model,omitempty"`
+	VideoPrompt   string `json:"video_prompt,omitempty"`   // Empty for the default motion prompt
+	GroundingMode string `json:"grounding_mode,omitempty"` // search, off or required
+	Lang          string `json:"lang,omitempty"`           // Language of the text in the image; empty for the city's native language
+
+	// Weather observed when the media was generated
+	Conditions   *Conditions `json:"conditions,omitempty"`
+	TemperatureC *float64    `json:"temperature_c,omitempty"`
+	Mood         string      `json:"mood,omitempty"`
+
+	ImageURL    string    `json:"image_url"`
+	VideoURL    string    `json:"video_url,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Receipt returns the recipe behind the location's current media.
+func (l *Location) Receipt() *Receipt {
+	r := &Receipt{
+		LocationID:    l.ID,
+		Name:          l.Name,
+		Category:      l.Category,
+		City:          l.CityQuery,
+		PromptContext: l.PromptContext,
+		Seed:          l.Seed,
+		VideoPrompt:   l.VideoPrompt,
+		Conditions:    l.LastConditions,
+		TemperatureC:  l.TemperatureC,
+		Mood:          l.Mood,
+		ImageURL:      l.ImageURL,
+		VideoURL:      l.VideoURL,
+		GeneratedAt:   l.LastUpdated,
+	}
+	if g := l.Generation; g != nil {
+		r.Style, r.PromptVersion = g.Style, g.PromptVersion
+		r.ImageModel, r.GroundingMode, r.Lang = g.Model, g.GroundingMode, g.Lang
+		for _, u := range g.Usage {
+			if u.VideoSeconds > 0 {
+				r.VideoModel = u.Model // The last Veo call made the current video
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReceipt(t *testing.T) {
+	seed, temp := int32(42), 18.5
+	updated := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	loc := Location{
+		ID: "lima", Name: "Lima", CityQuery: "Lima, Peru", PromptContext: "Miraflores cliffs", Seed: &seed,
+		ImageURL: "img", VideoURL: "vid", LastConditions: &Conditions{Condition: "fog", IsDay: true, TempBandC: 15},
+		TemperatureC: &temp, Mood: "misty", LastUpdated: updated,
+		Generation: &GenerationMetadata{Model: "image-model", Style: "drink", PromptVersion: "abc123", GroundingMode: "off", Lang: "es",
+			Usage: []ModelUsage{{Model: "image-model", PromptTokens: 10}, {Model: "veo-old", VideoSeconds: 8}, {Model: "veo-new", VideoSeconds: 8}}},
+	}
+	r := loc.Receipt()
+	if r.LocationID != "lima" || r.City != "Lima, Peru" || r.PromptContext != "Miraflores cliffs" || *r.Seed != 42 ||
+		r.Style != "drink" || r.PromptVersion != "abc123" || r.ImageModel != "image-model" || r.VideoModel != "veo-new" ||
+		r.GroundingMode != "off" || r.Lang != "es" || r.Conditions.Condition != "fog" || *r.TemperatureC != 18.5 ||
+		r.Mood != "misty" || !r.GeneratedAt.Equal(updated) {
+		t.Errorf("Unexpected receipt %+v", r)
+	}
+
+	if r := (&Location{ID: "old", CityQuery: "Old"}).Receipt(); r.Style != "" || r.ImageModel != "" || r.Seed != nil {
+		t.Errorf("Expected a bare receipt without generation metadata, got %+v", r)
+	}
+}
@@ -0,0 +1,58 @@
+package weatherdata
+
+// Mood is a theme token for the observed weather: the frontend themes
+// backgrounds with it, and prompts reuse its adjectives so images of the
+// same weather read alike.
+type Mood string
+
+const (
+	MoodGoldenSun    Mood = "golden-sun"
+	MoodStarryNight  Mood = "starry-night"
+	MoodSoftOvercast Mood = "soft-overcast"
+	MoodMisty        Mood = "misty"
+	MoodCozyRain     Mood = "cozy-rain"
+	MoodCrispSnow    Mood = "crisp-snow"
+	MoodStormy       Mood = "stormy"
+)
+
+// Moods lists every Mood, for clients and validation.
+var Moods = []Mood{MoodGoldenSun, MoodStarryNight, MoodSoftOvercast, MoodMisty, MoodCozyRain, MoodCrispSnow, MoodStormy}
+
+var moodAdjectives = map[Mood]string{
+	MoodGoldenSun:    "warm golden light, crisp shadows, bright and cheerful",
+	MoodStarryNight:  "clear starry sky, cool moonlight, calm and quiet",
+	MoodSoftOvercast: "soft diffuse light, muted colors, gentle and mellow",
+	MoodMisty:        "hazy veiled distances, pale light, hushed and mysterious",
+	MoodCozyRain:     "glistening wet streets, warm glowing windows, cozy",
+	MoodCrispSnow:    "fresh powdery snow, cold clear air, crisp and serene",
+	MoodStormy:       "dark brooding clouds, dramatic lightning, wild and moody",
+}
+
+// Mood classifies the conditions. Clear skies are golden by day and starry
+// by night; the other conditions map one to one.
+func (c *Current) Mood() Mood {
+	switch c.Condition {
+	case ConditionClear:
+		if c.IsDay {
+			return MoodGoldenSun
+		}
+		return MoodStarryNight
+	case ConditionCloudy:
+		return MoodSoftOvercast
+	case ConditionFog:
+		return MoodMisty
+	case ConditionRain:
+		return MoodCozyRain
+	case ConditionSnow:
+		return MoodCrispSnow
+	case ConditionThunderstorm:
+		return MoodStormy
+	}
+	return ""
+}
+
+// Adjectives are the words prompts use for the mood, e.g. "glistening wet
+// streets, warm glowing windows, cozy". Unknown moods have none.
+func (m Mood) Adjectives() string {
+	return moodAdjectives[m]
+}
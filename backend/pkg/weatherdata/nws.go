@@ -0,0 +1,218 @@
+package weatherdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultNWSUserAgent identifies NWS requests, which the API requires.
+const DefaultNWSUserAgent = "banana-weather (https://github.com/ghchinoy/banana-weather)"
+
+// ErrNotCovered is returned for points a provider has no data for, e.g. NWS
+// outside the US.
+var ErrNotCovered = errors.New("point not covered by the weather provider")
+
+// NWS reads the hourly forecast of the US National Weather Service API,
+// which needs no API key but only covers the US. The current conditions
+// are those of the current hour; days are summarized from the hours.
+type NWS struct {
+	UserAgent string // Sent with every request; DefaultNWSUserAgent when empty
+
+	baseURL string
+	http    *http.Client
+}
+
+// NewNWS returns an NWS provider sending requests with hc.
+func NewNWS(hc *http.Client) *NWS {
+	if hc == nil {
+		hc = &http.Client{Timeout: timeout}
+	}
+	return &NWS{baseURL: "https://api.weather.gov", http: hc}
+}
+
+// nwsPeriod is an hour of an NWS hourly forecast.
+type nwsPeriod struct {
+	StartTime                  string  `json:"startTime"` // RFC 3339, in the point's offset
+	IsDaytime                  bool    `json:"isDaytime"`
+	Temperature                float64 `json:"temperature"`
+	TemperatureUnit            string  `json:"temperatureUnit"` // "F" or "C"
+	ShortForecast              string  `json:"shortForecast"`   // e.g. "Chance Rain Showers"
+	ProbabilityOfPrecipitation struct {
+		Value *float64 `json:"value"`
+	} `json:"probabilityOfPrecipitation"`
+}
+
+func (p nwsPeriod) celsius() float64 {
+	if p.TemperatureUnit == "F" {
+		return (p.Temperature - 32) * 5 / 9
+	}
+	return p.Temperature
+}
+
+// Current returns the conditions of the current hour at lat/lng.
+func (c *NWS) Current(ctx context.Context, lat, lng float64) (*Current, error) {
+	return c.Forecast(ctx, lat, lng, 0)
+}
+
+// Forecast returns the conditions of the current hour at lat/lng and the
+// next days (about a week at most). Today covers the hours left.
+func (c *NWS) Forecast(ctx context.Context, lat, lng float64, days int) (*Current, error) {
+	var point struct {
+		Properties struct {
+			ForecastHourly string `json:"forecastHourly"`
+		} `json:"properties"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("%s/points/%.4f,%.4f", c.baseURL, lat, lng), &point); err != nil {
+		return nil, err
+	}
+	if point.Properties.ForecastHourly == "" {
+		return nil, fmt.Errorf("nws: %w", ErrNotCovered)
+	}
+	var forecast struct {
+		Properties struct {
+			Periods []nwsPeriod `json:"periods"`
+		} `json:"properties"`
+	}
+	if err := c.get(ctx, point.Properties.ForecastHourly, &forecast); err != nil {
+		return nil, err
+	}
+	periods := forecast.Properties.Periods
+	if len(periods) == 0 {
+		return nil, errors.New("nws returned no forecast periods")
+	}
+
+	now := periods[0]
+	cur := &Current{Condition: nwsCondition(now.ShortForecast), TemperatureC: now.celsius(), IsDay: now.IsDaytime}
+	if cur.Condition == "" {
+		return nil, fmt.Errorf("unknown NWS forecast %q", now.ShortForecast)
+	}
+	cur.WeatherCode = wmoCodes[cur.Condition]
+	cur.Days = nwsDays(periods, days)
+	return cur, nil
+}
+
+func (c *NWS) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	ua := c.UserAgent
+	if ua == "" {
+		ua = DefaultNWSUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept", "application/geo+json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("nws request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("nws: %w", ErrNotCovered)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("nws returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode nws response: %w", err)
+	}
+	return nil
+}
+
+// wmoCodes are representative WMO codes of conditions, for providers that
+// report conditions in words.
+var wmoCodes = map[Condition]int{
+	ConditionClear:        0,
+	ConditionCloudy:       3,
+	ConditionFog:          45,
+	ConditionRain:         61,
+	ConditionSnow:         71,
+	ConditionThunderstorm: 95,
+}
+
+// nwsCondition maps an NWS short forecast, e.g. "Slight Chance Rain
+// Showers", to a Condition, the most severe weather it mentions first.
+// Unknown forecasts map to "".
+func nwsCondition(forecast string) Condition {
+	f := strings.ToLower(forecast)
+	has := func(words ...string) bool {
+		return slices.ContainsFunc(words, func(w string) bool { return strings.Contains(f, w) })
+	}
+	switch {
+	case has("thunder", "t-storm", "tstorm"):
+		return ConditionThunderstorm
+	case has("snow", "flurr", "sleet", "blizzard", "ice pellets", "wintry"):
+		return ConditionSnow
+	case has("rain", "shower", "drizzle"):
+		return ConditionRain
+	case has("fog", "haze", "smoke", "mist"):
+		return ConditionFog
+	case has("cloud", "overcast", "partly sunny"):
+		return ConditionCloudy
+	case has("sunny", "clear", "fair"):
+		return ConditionClear
+	}
+	return ""
+}
+
+// nwsDays summarizes hourly periods into at most n days: the range of their
+// temperatures, their highest chance of precipitation and their dominant
+// condition by daylight (by night for days with only night hours left).
+func nwsDays(periods []nwsPeriod, n int) []Day {
+	var out []Day
+	var day, night map[Condition]int // Hours of each condition of the last day
+	flush := func() {
+		if len(out) > 0 {
+			if len(day) == 0 {
+				day = night
+			}
+			out[len(out)-1].Condition = dominant(day)
+		}
+	}
+	for _, p := range periods {
+		if len(p.StartTime) < len(time.DateOnly) {
+			continue
+		}
+		date, temp := p.StartTime[:len(time.DateOnly)], p.celsius()
+		if len(out) == 0 || out[len(out)-1].Date != date {
+			flush()
+			if len(out) == n {
+				return out
+			}
+			out = append(out, Day{Date: date, HighC: temp, LowC: temp})
+			day, night = map[Condition]int{}, map[Condition]int{}
+		}
+		d := &out[len(out)-1]
+		d.HighC, d.LowC = max(d.HighC, temp), min(d.LowC, temp)
+		if v := p.ProbabilityOfPrecipitation.Value; v != nil {
+			d.PrecipitationChance = max(d.PrecipitationChance, int(*v))
+		}
+		if c := nwsCondition(p.ShortForecast); c != "" {
+			if p.IsDaytime {
+				day[c]++
+			} else {
+				night[c]++
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+// dominant returns the most frequent condition of counts, the more severe
+// one (later in Conditions) on ties.
+func dominant(counts map[Condition]int) Condition {
+	var best Condition
+	for _, c := range Conditions {
+		if counts[c] > 0 && counts[c] >= counts[best] {
+			best = c
+		}
+	}
+	return best
+}
@@ -0,0 +1,92 @@
+package weatherdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// nwsServer serves an NWS point at 47.6062,-122.3321 with three hours of
+// forecast over two days; other points aren't covered.
+func nwsServer(t *testing.T) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") == "" {
+			t.Error("Expected a User-Agent")
+		}
+		switch r.URL.Path {
+		case "/points/47.6062,-122.3321":
+			fmt.Fprintf(w, `{"properties":{"forecastHourly":"%s/gridpoints/SEW/125,68/forecast/hourly"}}`, srv.URL)
+		case "/gridpoints/SEW/125,68/forecast/hourly":
+			fmt.Fprint(w, `{"properties":{"periods":[
+				{"startTime":"2026-10-15T22:00:00-07:00","isDaytime":false,"temperature":50,"temperatureUnit":"F","shortForecast":"Light Rain","probabilityOfPrecipitation":{"value":70}},
+				{"startTime":"2026-10-15T23:00:00-07:00","isDaytime":false,"temperature":48,"temperatureUnit":"F","shortForecast":"Chance Light Rain","probabilityOfPrecipitation":{"value":40}},
+				{"startTime":"2026-10-16T10:00:00-07:00","isDaytime":true,"temperature":59,"temperatureUnit":"F","shortForecast":"Mostly Sunny","probabilityOfPrecipitation":{"value":null}}]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return srv
+}
+
+func TestNWS_Forecast(t *testing.T) {
+	srv := nwsServer(t)
+	defer srv.Close()
+
+	c := NewNWS(nil)
+	c.baseURL = srv.URL
+	cur, err := c.Forecast(context.Background(), 47.6062, -122.3321, 7)
+	if err != nil {
+		t.Fatalf("Forecast() failed: %v", err)
+	}
+	if cur.Condition != ConditionRain || cur.WeatherCode != 61 || cur.IsDay || math.Round(cur.TemperatureC) != 10 {
+		t.Errorf("Unexpected conditions: %+v", cur)
+	}
+	if len(cur.Days) != 2 {
+		t.Fatalf("Days = %+v", cur.Days)
+	}
+	if d := cur.Days[0]; d.Date != "2026-10-15" || d.Condition != ConditionRain || d.PrecipitationChance != 70 || math.Round(d.LowC) != 9 {
+		t.Errorf("today = %+v", d)
+	}
+	if d := cur.Days[1]; d.Condition != ConditionClear || math.Round(d.HighC) != 15 || d.PrecipitationChance != 0 {
+		t.Errorf("tomorrow = %+v", d)
+	}
+
+	cur, err = c.Current(context.Background(), 47.6062, -122.3321)
+	if err != nil || cur.Days != nil {
+		t.Errorf("Current() = %+v, %v", cur, err)
+	}
+}
+
+func TestNWS_NotCovered(t *testing.T) {
+	srv := nwsServer(t)
+	defer srv.Close()
+
+	c := NewNWS(nil)
+	c.baseURL = srv.URL
+	if _, err := c.Current(context.Background(), 51.5, -0.12); !errors.Is(err, ErrNotCovered) {
+		t.Errorf("Expected ErrNotCovered outside the US, got %v", err)
+	}
+}
+
+func TestNWSCondition(t *testing.T) {
+	for forecast, want := range map[string]Condition{
+		"Sunny":                            ConditionClear,
+		"Mostly Clear":                     ConditionClear,
+		"Partly Sunny":                     ConditionCloudy,
+		"Mostly Cloudy":                    ConditionCloudy,
+		"Patchy Fog":                       ConditionFog,
+		"Slight Chance Rain Showers":       ConditionRain,
+		"Rain And Snow":                    ConditionSnow,
+		"Chance Showers And Thunderstorms": ConditionThunderstorm,
+		"Something Unheard Of":             "",
+	} {
+		if got := nwsCondition(forecast); got != want {
+			t.Errorf("nwsCondition(%q) = %q, want %q", forecast, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package weatherdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+)
+
+// maxOpenMeteoDays is the longest forecast Open-Meteo serves.
+const maxOpenMeteoDays = 16
+
+// OpenMeteo reads current conditions and daily forecasts from the Open-Meteo
+// forecast API, which covers the world and needs no API key.
+type OpenMeteo struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewOpenMeteo returns an Open-Meteo provider sending requests with hc.
+func NewOpenMeteo(hc *http.Client) *OpenMeteo {
+	if hc == nil {
+		hc = &http.Client{Timeout: timeout}
+	}
+	return &OpenMeteo{baseURL: "https://api.open-meteo.com/v1/forecast", http: hc}
+}
+
+// openMeteoResponse is the part of a forecast response the provider reads.
+type openMeteoResponse struct {
+	Current struct {
+		WeatherCode   int     `json:"weather_code"`
+		Temperature2m float64 `json:"temperature_2m"`
+		IsDay         int     `json:"is_day"`
+	} `json:"current"`
+	Daily struct {
+		Time                        []string   `json:"time"`
+		WeatherCode                 []int      `json:"weather_code"`
+		Temperature2mMax            []float64  `json:"temperature_2m_max"`
+		Temperature2mMin            []float64  `json:"temperature_2m_min"`
+		PrecipitationProbabilityMax []*float64 `json:"precipitation_probability_max"` // null where unknown
+	} `json:"daily"`
+}
+
+// Current returns the current conditions at lat/lng.
+func (c *OpenMeteo) Current(ctx context.Context, lat, lng float64) (*Current, error) {
+	return c.Forecast(ctx, lat, lng, 0)
+}
+
+// Forecast returns the current conditions at lat/lng and the daily forecast
+// of the next days (at most 16), in the point's time zone.
+func (c *OpenMeteo) Forecast(ctx context.Context, lat, lng float64, days int) (*Current, error) {
+	q := url.Values{}
+	q.Set("latitude", fmt.Sprintf("%.4f", lat))
+	q.Set("longitude", fmt.Sprintf("%.4f", lng))
+	q.Set("current", "weather_code,temperature_2m,is_day")
+	if days > 0 {
+		q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max")
+		q.Set("timezone", "auto")
+		q.Set("forecast_days", fmt.Sprint(min(days, maxOpenMeteoDays)))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("open-meteo request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open-meteo returned %s", resp.Status)
+	}
+
+	var body openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode open-meteo response: %w", err)
+	}
+
+	cur := &Current{
+		WeatherCode:  body.Current.WeatherCode,
+		Condition:    ConditionForCode(body.Current.WeatherCode),
+		TemperatureC: body.Current.Temperature2m,
+		IsDay:        body.Current.IsDay == 1,
+	}
+	if cur.Condition == "" {
+		return nil, fmt.Errorf("unknown weather code %d", cur.WeatherCode)
+	}
+	d := body.Daily
+	n := min(len(d.Time), len(d.WeatherCode), len(d.Temperature2mMax), len(d.Temperature2mMin))
+	for i := range n {
+		day := Day{
+			Date:      d.Time[i],
+			Condition: ConditionForCode(d.WeatherCode[i]),
+			HighC:     d.Temperature2mMax[i],
+			LowC:      d.Temperature2mMin[i],
+		}
+		if i < len(d.PrecipitationProbabilityMax) && d.PrecipitationProbabilityMax[i] != nil {
+			day.PrecipitationChance = int(math.Round(*d.PrecipitationProbabilityMax[i]))
+		}
+		cur.Days = append(cur.Days, day)
+	}
+	return cur, nil
+}
@@ -0,0 +1,67 @@
+package weatherdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenMeteo_Current(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("current") == "" {
+			t.Error("Expected current= parameter")
+		}
+		if r.URL.Query().Has("daily") {
+			t.Error("Expected no daily forecast requested")
+		}
+		fmt.Fprint(w, `{"current":{"weather_code":61,"temperature_2m":11.6,"is_day":0}}`)
+	}))
+	defer srv.Close()
+
+	c := NewOpenMeteo(nil)
+	c.baseURL = srv.URL
+	cur, err := c.Current(context.Background(), 51.5, -0.12)
+	if err != nil {
+		t.Fatalf("Current() failed: %v", err)
+	}
+	if cur.Condition != ConditionRain || cur.IsDay || cur.Days != nil {
+		t.Errorf("Unexpected conditions: %+v", cur)
+	}
+	if got := cur.Describe(); got != "rain, 12°C, night" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+func TestOpenMeteo_Forecast(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("daily") == "" || q.Get("forecast_days") != "2" || q.Get("timezone") != "auto" {
+			t.Errorf("Unexpected query %v", q)
+		}
+		fmt.Fprint(w, `{"current":{"weather_code":2,"temperature_2m":17.2,"is_day":1},
+			"daily":{"time":["2026-10-15","2026-10-16"],"weather_code":[3,63],
+			"temperature_2m_max":[19.4,15.1],"temperature_2m_min":[9.8,10.3],"precipitation_probability_max":[12,null]}}`)
+	}))
+	defer srv.Close()
+
+	c := NewOpenMeteo(nil)
+	c.baseURL = srv.URL
+	cur, err := c.Forecast(context.Background(), 51.5, -0.12, 2)
+	if err != nil {
+		t.Fatalf("Forecast() failed: %v", err)
+	}
+	want := []Day{
+		{Date: "2026-10-15", Condition: ConditionCloudy, HighC: 19.4, LowC: 9.8, PrecipitationChance: 12},
+		{Date: "2026-10-16", Condition: ConditionRain, HighC: 15.1, LowC: 10.3},
+	}
+	if cur.Condition != ConditionCloudy || len(cur.Days) != len(want) {
+		t.Fatalf("Unexpected forecast: %+v", cur)
+	}
+	for i := range want {
+		if cur.Days[i] != want[i] {
+			t.Errorf("day %d = %+v, want %+v", i, cur.Days[i], want[i])
+		}
+	}
+}
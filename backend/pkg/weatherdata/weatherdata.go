@@ -0,0 +1,163 @@
+// Package weatherdata looks up observed weather and daily forecasts from
+// public weather APIs (Open-Meteo and the US National Weather Service), for
+// image prompts and for clients that show the numbers next to the image.
+package weatherdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Condition is a coarse weather category, as it would show up in an illustration.
+type Condition string
+
+const (
+	ConditionClear        Condition = "clear"
+	ConditionCloudy       Condition = "cloudy"
+	ConditionFog          Condition = "fog"
+	ConditionRain         Condition = "rain"
+	ConditionSnow         Condition = "snow"
+	ConditionThunderstorm Condition = "thunderstorm"
+)
+
+// Conditions lists every Condition, for prompts and validation.
+var Conditions = []Condition{ConditionClear, ConditionCloudy, ConditionFog, ConditionRain, ConditionSnow, ConditionThunderstorm}
+
+// ConditionForCode maps a WMO weather interpretation code to a Condition.
+// Unknown codes map to "".
+func ConditionForCode(code int) Condition {
+	switch {
+	case code == 0 || code == 1:
+		return ConditionClear
+	case code == 2 || code == 3:
+		return ConditionCloudy
+	case code == 45 || code == 48:
+		return ConditionFog
+	case code >= 51 && code <= 67, code >= 80 && code <= 82:
+		return ConditionRain
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return ConditionSnow
+	case code >= 95 && code <= 99:
+		return ConditionThunderstorm
+	}
+	return ""
+}
+
+// Current is the observed weather at a point.
+type Current struct {
+	Condition    Condition `json:"condition"`
+	WeatherCode  int       `json:"weather_code"` // WMO code; representative of Condition for providers without codes
+	TemperatureC float64   `json:"temperature_c"`
+	IsDay        bool      `json:"is_day"`
+	Days         []Day     `json:"days,omitempty"` // Daily forecast from today, see Provider.Forecast
+}
+
+// Describe renders the conditions for a prompt, e.g. "rain, 12°C, night".
+func (c *Current) Describe() string {
+	timeOfDay := "day"
+	if !c.IsDay {
+		timeOfDay = "night"
+	}
+	return fmt.Sprintf("%s, %.0f°C, %s", c.Condition, c.TemperatureC, timeOfDay)
+}
+
+// Today returns the forecast of the first day, if there is one.
+func (c *Current) Today() (Day, bool) {
+	if len(c.Days) == 0 {
+		return Day{}, false
+	}
+	return c.Days[0], true
+}
+
+// Day is the forecast of one day at a point.
+type Day struct {
+	Date                string    `json:"date"` // YYYY-MM-DD, local to the point
+	Condition           Condition `json:"condition"`
+	HighC               float64   `json:"high_c"`
+	LowC                float64   `json:"low_c"`
+	PrecipitationChance int       `json:"precipitation_chance"` // Percent
+}
+
+// Describe renders the day for a prompt, e.g. "rain, 8°C to 14°C, 80%
+// chance of precipitation".
+func (d Day) Describe() string {
+	s := fmt.Sprintf("%s, %.0f°C to %.0f°C", d.Condition, d.LowC, d.HighC)
+	if d.PrecipitationChance > 0 {
+		s += fmt.Sprintf(", %d%% chance of precipitation", d.PrecipitationChance)
+	}
+	return s
+}
+
+// Provider looks up the weather at a point.
+type Provider interface {
+	// Current returns the current conditions at lat/lng.
+	Current(ctx context.Context, lat, lng float64) (*Current, error)
+	// Forecast returns the current conditions with Days set to today and
+	// up to days-1 following days.
+	Forecast(ctx context.Context, lat, lng float64, days int) (*Current, error)
+}
+
+// Provider names, for Open.
+const (
+	NameOpenMeteo = "open-meteo"
+	NameNWS       = "nws"
+)
+
+// timeout bounds every request of the providers Open returns.
+const timeout = 10 * time.Second
+
+// Open returns the provider of names, in order of preference: a Chain when
+// there are several. hc sends their requests; nil uses an HTTP client with a
+// 10s timeout.
+func Open(names []string, hc *http.Client) (Provider, error) {
+	if hc == nil {
+		hc = &http.Client{Timeout: timeout}
+	}
+	var chain Chain
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case NameOpenMeteo:
+			chain = append(chain, NewOpenMeteo(hc))
+		case NameNWS:
+			chain = append(chain, NewNWS(hc))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown weather provider %q (use %s or %s)", name, NameOpenMeteo, NameNWS)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil, errors.New("no weather provider")
+	case 1:
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+// Chain is a Provider that asks its providers in order and returns the first
+// answer, e.g. NWS for US points, falling back to Open-Meteo elsewhere.
+type Chain []Provider
+
+func (c Chain) Current(ctx context.Context, lat, lng float64) (*Current, error) {
+	return c.first(func(p Provider) (*Current, error) { return p.Current(ctx, lat, lng) })
+}
+
+func (c Chain) Forecast(ctx context.Context, lat, lng float64, days int) (*Current, error) {
+	return c.first(func(p Provider) (*Current, error) { return p.Forecast(ctx, lat, lng, days) })
+}
+
+func (c Chain) first(lookup func(Provider) (*Current, error)) (*Current, error) {
+	var errs []error
+	for _, p := range c {
+		cur, err := lookup(p)
+		if err == nil {
+			return cur, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
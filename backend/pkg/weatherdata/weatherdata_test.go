@@ -0,0 +1,124 @@
+package weatherdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConditionForCode(t *testing.T) {
+	tests := []struct {
+		code int
+		want Condition
+	}{
+		{0, ConditionClear},
+		{3, ConditionCloudy},
+		{48, ConditionFog},
+		{53, ConditionRain}, // Drizzle
+		{81, ConditionRain}, // Showers
+		{75, ConditionSnow},
+		{86, ConditionSnow}, // Snow showers
+		{96, ConditionThunderstorm},
+		{42, ""},
+	}
+	for _, tt := range tests {
+		if got := ConditionForCode(tt.code); got != tt.want {
+			t.Errorf("ConditionForCode(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestMood(t *testing.T) {
+	tests := []struct {
+		cur  Current
+		want Mood
+	}{
+		{Current{Condition: ConditionClear, IsDay: true}, MoodGoldenSun},
+		{Current{Condition: ConditionClear}, MoodStarryNight},
+		{Current{Condition: ConditionCloudy, IsDay: true}, MoodSoftOvercast},
+		{Current{Condition: ConditionFog}, MoodMisty},
+		{Current{Condition: ConditionRain, IsDay: true}, MoodCozyRain},
+		{Current{Condition: ConditionSnow}, MoodCrispSnow},
+		{Current{Condition: ConditionThunderstorm}, MoodStormy},
+		{Current{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.cur.Mood(); got != tt.want {
+			t.Errorf("%+v: Mood() = %q, want %q", tt.cur, got, tt.want)
+		}
+	}
+	for _, m := range Moods {
+		if m.Adjectives() == "" {
+			t.Errorf("%s has no adjectives", m)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	cur := &Current{Condition: ConditionRain, TemperatureC: 11.6, Days: []Day{{Condition: ConditionRain, HighC: 14.2, LowC: 7.6, PrecipitationChance: 80}}}
+	if got := cur.Describe(); got != "rain, 12°C, night" {
+		t.Errorf("Describe() = %q", got)
+	}
+	today, ok := cur.Today()
+	if !ok || today.Describe() != "rain, 8°C to 14°C, 80% chance of precipitation" {
+		t.Errorf("Today() = %+v, %v", today, ok)
+	}
+	if got := (Day{Condition: ConditionClear, HighC: 21, LowC: 12}).Describe(); got != "clear, 12°C to 21°C" {
+		t.Errorf("Describe() without precipitation = %q", got)
+	}
+	if _, ok := (&Current{}).Today(); ok {
+		t.Error("Expected no day without a forecast")
+	}
+}
+
+// fakeProvider answers with cur or fails with err, counting calls.
+type fakeProvider struct {
+	cur   *Current
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) Current(ctx context.Context, lat, lng float64) (*Current, error) {
+	return f.Forecast(ctx, lat, lng, 0)
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, lat, lng float64, days int) (*Current, error) {
+	f.calls++
+	return f.cur, f.err
+}
+
+func TestChain(t *testing.T) {
+	nws := &fakeProvider{err: ErrNotCovered}
+	om := &fakeProvider{cur: &Current{Condition: ConditionFog}}
+	last := &fakeProvider{cur: &Current{Condition: ConditionSnow}}
+	cur, err := Chain{nws, om, last}.Forecast(context.Background(), 48.85, 2.35, 3)
+	if err != nil || cur.Condition != ConditionFog {
+		t.Fatalf("Forecast() = %+v, %v; want the second provider's", cur, err)
+	}
+	if nws.calls != 1 || om.calls != 1 || last.calls != 0 {
+		t.Errorf("calls = %d, %d, %d", nws.calls, om.calls, last.calls)
+	}
+
+	down := errors.New("unavailable")
+	_, err = Chain{nws, &fakeProvider{err: down}}.Current(context.Background(), 48.85, 2.35)
+	if !errors.Is(err, ErrNotCovered) || !errors.Is(err, down) {
+		t.Errorf("Expected every provider's error, got %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	p, err := Open([]string{"open-meteo"}, nil)
+	if _, ok := p.(*OpenMeteo); err != nil || !ok {
+		t.Errorf("Open(open-meteo) = %T, %v", p, err)
+	}
+	p, err = Open([]string{"nws", " open-meteo"}, nil)
+	if c, ok := p.(Chain); err != nil || !ok || len(c) != 2 {
+		t.Errorf("Open(nws, open-meteo) = %#v, %v", p, err)
+	}
+	if _, err := Open([]string{"met-office"}, nil); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+	if _, err := Open(nil, nil); err == nil {
+		t.Error("Expected an error without providers")
+	}
+}
@@ -4,6 +4,9 @@ class Preset {
   final String? category;
   final String imageUrl;
   final String videoUrl;
+  final String? posterUrl; // First frame of the video, matches it exactly
+  final String? streamUrl; // HLS master playlist of the video
+  final String? altText; // Description of the image for screen readers
   final DateTime? lastUpdated;
 
   Preset({
@@ -12,6 +15,9 @@ class Preset {
     this.category,
     required this.imageUrl,
     required this.videoUrl,
+    this.posterUrl,
+    this.streamUrl,
+    this.altText,
     this.lastUpdated,
   });
 
@@ -22,6 +28,9 @@ class Preset {
       category: json['category'],
       imageUrl: json['image_url'],
       videoUrl: json['video_url'],
+      posterUrl: json['poster_url'],
+      streamUrl: json['stream_url'],
+      altText: json['alt_text'],
       lastUpdated: json['last_updated'] != null 
           ? DateTime.parse(json['last_updated']) 
           : null,
@@ -12,7 +12,14 @@ class WeatherProvider with ChangeNotifier {
   bool _isLoading = false;
   String? _error;
   String? _statusMessage;
+  double? _progress; // 0-1 from "progress" events; null when unknown
   String? _videoUrl;
+  String? _posterUrl;
+  String? _streamUrl;
+  String? _mood; // Theme of the observed weather, e.g. "cozy-rain"; null when unknown
+  String? _caption; // Nickname or fun fact shown under the artwork
+  String? _altText; // Description of the image for screen readers
+  String? _flowId; // From the "flow" event; quoted in bug reports
   List<Preset> _presets = [];
   bool _isPresetLoaded = false;
   DateTime? _lastUpdated;
@@ -23,10 +30,19 @@ class WeatherProvider with ChangeNotifier {
   bool get isLoading => _isLoading;
   String? get error => _error;
   String? get statusMessage => _statusMessage;
+  double? get progress => _statusMessage == null ? null : _progress;
   String? get videoUrl => _videoUrl;
+  String? get posterUrl => _posterUrl;
+  // What the player loads: HLS adapts to slow connections, but browsers other
+  // than Safari can't play it natively, so the web app keeps the MP4.
+  String? get playbackUrl => (!kIsWeb && _streamUrl != null) ? _streamUrl : _videoUrl;
   List<Preset> get presets => _presets;
   bool get isPresetLoaded => _isPresetLoaded;
   DateTime? get lastUpdated => _lastUpdated;
+  String? get mood => _mood;
+  String? get caption => _caption;
+  String? get altText => _altText;
+  String? get flowId => _flowId;
 
   void clearError() {
     _error = null;
@@ -37,6 +53,12 @@ class WeatherProvider with ChangeNotifier {
     _city = p.name;
     _imageUrl = p.imageUrl;
     _videoUrl = p.videoUrl;
+    _posterUrl = p.posterUrl;
+    _streamUrl = p.streamUrl;
+    _mood = null;
+    _caption = null;
+    _altText = p.altText;
+    _flowId = null;
     _lastUpdated = p.lastUpdated;
     _imageBase64 = null; // Clear generated image
     _error = null;
@@ -66,7 +88,7 @@ class WeatherProvider with ChangeNotifier {
 
       serviceEnabled = await Geolocator.isLocationServiceEnabled();
       if (!serviceEnabled) {
-        await fetchWeather(city: 'San Francisco');
+        await fetchWeather();
         return;
       }
 
@@ -74,20 +96,20 @@ class WeatherProvider with ChangeNotifier {
       if (permission == LocationPermission.denied) {
         permission = await Geolocator.requestPermission();
         if (permission == LocationPermission.denied) {
-          await fetchWeather(city: 'San Francisco');
+          await fetchWeather();
           return;
         }
       }
       
       if (permission == LocationPermission.deniedForever) {
-        await fetchWeather(city: 'San Francisco');
+        await fetchWeather();
         return;
       } 
 
       Position position = await Geolocator.getCurrentPosition();
       await fetchWeather(lat: position.latitude, lng: position.longitude);
     } catch (e) {
-       await fetchWeather(city: 'San Francisco');
+       await fetchWeather();
     }
   }
 
@@ -96,6 +118,12 @@ class WeatherProvider with ChangeNotifier {
     _error = null;
     _statusMessage = "Connecting...";
     _videoUrl = null;
+    _posterUrl = null;
+    _streamUrl = null;
+    _mood = null;
+    _caption = null;
+    _altText = null;
+    _flowId = null;
     _imageUrl = null; // Clear preset image
     _imageBase64 = null;
     _isPresetLoaded = false;
@@ -110,7 +138,7 @@ class WeatherProvider with ChangeNotifier {
       } else if (city != null && city.isNotEmpty) {
         uri = Uri.parse('$baseUrl/api/weather?city=$city');
       } else {
-         uri = Uri.parse('$baseUrl/api/weather?city=San Francisco');
+         uri = Uri.parse('$baseUrl/api/weather'); // The server picks its DEFAULT_CITY
       }
       
       final request = http.Request('GET', uri);
@@ -160,8 +188,39 @@ class WeatherProvider with ChangeNotifier {
 
   void _handleEvent(String event, String data) {
     switch (event) {
+      case 'flow':
+        _flowId = data;
+        break;
       case 'status':
         _statusMessage = data;
+        _progress = null; // A following "progress" event sets it
+        notifyListeners();
+        break;
+      case 'progress':
+        try {
+          final percent = json.decode(data)['percent'];
+          _progress = percent == null ? null : (percent as num) / 100;
+          notifyListeners();
+        } catch (e) {
+          // Progress is cosmetic; the status line already went through
+        }
+        break;
+      case 'forecast':
+        // The observed weather, before the image; its mood themes the background
+        try {
+          _mood = json.decode(data)['mood'];
+          notifyListeners();
+        } catch (e) {
+          // Theming is cosmetic
+        }
+        break;
+      case 'caption':
+        _caption = data;
+        notifyListeners();
+        break;
+      case 'alt_text':
+        // Sent once the new image is described, after the result
+        _altText = data;
         notifyListeners();
         break;
       case 'error':
@@ -176,6 +235,8 @@ class WeatherProvider with ChangeNotifier {
           _city = jsonData['city'];
           _imageBase64 = jsonData['image_base64']; // Null if missing
           _imageUrl = jsonData['image_url'];       // Null if missing
+          _mood = jsonData['mood'] ?? _mood;
+          _altText = jsonData['alt_text']; // Cached images only
           
           if (jsonData['last_updated'] != null) {
             _lastUpdated = DateTime.parse(jsonData['last_updated']);
@@ -192,6 +253,15 @@ class WeatherProvider with ChangeNotifier {
           notifyListeners();
         }
         break;
+      case 'poster':
+        // Sent just before the video; shown while it loads
+        _posterUrl = data;
+        notifyListeners();
+        break;
+      case 'stream':
+        // Sent just before the video, like the poster
+        _streamUrl = data;
+        break;
       case 'video':
         _videoUrl = data;
         // If we receive a video, we are effectively "done" with the heavy lifting for this session
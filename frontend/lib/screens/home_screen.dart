@@ -111,13 +111,13 @@ class _HomeScreenState extends State<HomeScreen> {
     final colorScheme = Theme.of(context).colorScheme;
 
     // Check for video update
-    if (weatherProvider.videoUrl == null && _videoController != null) {
+    if (weatherProvider.playbackUrl == null && _videoController != null) {
        _disposeVideo();
     }
-    if (weatherProvider.videoUrl != null && weatherProvider.videoUrl != _currentVideoUrl) {
-      _currentVideoUrl = weatherProvider.videoUrl;
+    if (weatherProvider.playbackUrl != null && weatherProvider.playbackUrl != _currentVideoUrl) {
+      _currentVideoUrl = weatherProvider.playbackUrl;
       WidgetsBinding.instance.addPostFrameCallback((_) {
-        _initializeVideo(weatherProvider.videoUrl!);
+        _initializeVideo(weatherProvider.playbackUrl!);
       });
     }
 
@@ -168,6 +168,7 @@ class _HomeScreenState extends State<HomeScreen> {
                     child: Image.memory(
                       base64Decode(weatherProvider.imageBase64!),
                       fit: BoxFit.cover,
+                      semanticLabel: weatherProvider.altText,
                     ),
                   )
                 else if (weatherProvider.imageUrl != null)
@@ -175,6 +176,7 @@ class _HomeScreenState extends State<HomeScreen> {
                     child: Image.network(
                       weatherProvider.imageUrl!,
                       fit: BoxFit.cover,
+                      semanticLabel: weatherProvider.altText,
                     ),
                   )
                 else
@@ -185,6 +187,19 @@ class _HomeScreenState extends State<HomeScreen> {
                     ),
                   ),
 
+                // Poster Layer: the video's own first frame, so starting playback doesn't pop
+                if (weatherProvider.videoUrl != null &&
+                    weatherProvider.posterUrl != null &&
+                    !(_videoController?.value.isInitialized ?? false))
+                  Positioned.fill(
+                    child: Image.network(
+                      weatherProvider.posterUrl!,
+                      fit: BoxFit.cover,
+                      gaplessPlayback: true,
+                      errorBuilder: (context, error, stackTrace) => const SizedBox.shrink(),
+                    ),
+                  ),
+
                 // Video Layer
                 if (_videoController != null && _videoController!.value.isInitialized)
                   Positioned.fill(
@@ -215,10 +230,11 @@ class _HomeScreenState extends State<HomeScreen> {
                         child: Row(
                           mainAxisSize: MainAxisSize.min,
                           children: [
-                            const SizedBox(
+                            SizedBox(
                               width: 16, 
                               height: 16, 
                               child: CircularProgressIndicator(
+                                value: weatherProvider.progress, // Indeterminate without a percentage
                                 color: Colors.yellowAccent, 
                                 strokeWidth: 2,
                               ),
@@ -257,6 +273,25 @@ class _HomeScreenState extends State<HomeScreen> {
                     ),
                   ),
 
+                // Caption (Bottom Center, between the buttons)
+                if (weatherProvider.caption != null && !weatherProvider.isLoading)
+                  Positioned(
+                    bottom: 38,
+                    left: 80,
+                    right: 80,
+                    child: Text(
+                      weatherProvider.caption!,
+                      textAlign: TextAlign.center,
+                      maxLines: 2,
+                      overflow: TextOverflow.ellipsis,
+                      style: const TextStyle(
+                        color: Colors.white,
+                        fontStyle: FontStyle.italic,
+                        shadows: [Shadow(blurRadius: 6, color: Colors.black54)],
+                      ),
+                    ),
+                  ),
+
                 // Download Button (Bottom Left)
                 if (weatherProvider.videoUrl != null)
                   Positioned(
@@ -306,8 +341,10 @@ class _HomeScreenState extends State<HomeScreen> {
                       child: Row(
                         children: [
                           Expanded(
-                            child: Text(
-                              weatherProvider.error!,
+                            child: SelectableText(
+                              weatherProvider.flowId == null
+                                  ? weatherProvider.error!
+                                  : '${weatherProvider.error!}\nRef: ${weatherProvider.flowId}',
                               style: TextStyle(color: colorScheme.onErrorContainer),
                               textAlign: TextAlign.center,
                             ),